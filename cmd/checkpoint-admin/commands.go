@@ -0,0 +1,160 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aaechain/go-aaechain/aaeclient"
+	"github.com/aaechain/go-aaechain/accounts/abi/bind"
+	"github.com/aaechain/go-aaechain/cmd/utils"
+	"github.com/aaechain/go-aaechain/common/hexutil"
+	"github.com/aaechain/go-aaechain/contracts/checkpointoracle"
+	"gopkg.in/urfave/cli.v1"
+)
+
+type outputSign struct {
+	Checkpoint *checkpointoracle.Checkpoint
+	Signature  string
+}
+
+var commandStatus = cli.Command{
+	Name:  "status",
+	Usage: "show the signers and the latest checkpoint of the oracle",
+	Description: `
+Show the admins of the checkpoint oracle and its latest registered checkpoint
+along with the number of admins who signed it.
+`,
+	Flags: []cli.Flag{
+		rpcFlag,
+		oracleFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		address := getOracleAddress(ctx)
+		oracle, err := checkpointoracle.NewCheckpointOracle(address, aaeclient.NewClient(dialRPC(ctx)))
+		if err != nil {
+			utils.Fatalf("Failed to bind the oracle: %v", err)
+		}
+		admins, err := oracle.Contract().GetAllAdmin(nil)
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the admins: %v", err)
+		}
+		for i, admin := range admins {
+			fmt.Printf("Admin %d: %s\n", i+1, admin.Hex())
+		}
+		cp, height, err := oracle.LatestCheckpoint(nil, admins, 0)
+		if err == checkpointoracle.ErrNoCheckpoint {
+			fmt.Println("No checkpoint registered")
+			return nil
+		}
+		if err != nil {
+			utils.Fatalf("Failed to retrieve the latest checkpoint: %v", err)
+		}
+		fmt.Println("Section:", cp.SectionIndex)
+		fmt.Println("Section head:", cp.SectionHead.Hex())
+		fmt.Println("CHT root:", cp.ChtRoot.Hex())
+		fmt.Println("BloomTrie root:", cp.BloomTrieRoot.Hex())
+		fmt.Println("Registered at:", height)
+		return nil
+	},
+}
+
+var commandSign = cli.Command{
+	Name:      "sign",
+	Usage:     "sign the latest checkpoint of a server",
+	ArgsUsage: "<keyfile>",
+	Description: `
+Retrieve the latest section processed by the light server at the --rpc endpoint
+and sign it with the keyfile for the oracle at the --oracle address. The
+signature is printed to be collected by the admin publishing the checkpoint.
+`,
+	Flags: []cli.Flag{
+		rpcFlag,
+		oracleFlag,
+		passphraseFlag,
+		jsonFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		address := getOracleAddress(ctx)
+		cp := getLatestCheckpoint(dialRPC(ctx))
+		key := getKey(ctx)
+
+		sig, err := cp.Sign(address, key.PrivateKey)
+		if err != nil {
+			utils.Fatalf("Failed to sign checkpoint: %v", err)
+		}
+		out := outputSign{Checkpoint: cp, Signature: hexutil.Encode(sig)}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			fmt.Println("Section:", cp.SectionIndex)
+			fmt.Println("Signer:", key.Address.Hex())
+			fmt.Println("Signature:", out.Signature)
+		}
+		return nil
+	},
+}
+
+var commandPublish = cli.Command{
+	Name:      "publish",
+	Usage:     "publish the latest checkpoint of a server with the collected signatures",
+	ArgsUsage: "<keyfile>",
+	Description: `
+Register the latest section processed by the light server at the --rpc endpoint
+in the oracle, along with the signatures given by --signatures. The transaction
+is sent from the account of the keyfile, which must be an admin of the oracle.
+`,
+	Flags: []cli.Flag{
+		rpcFlag,
+		oracleFlag,
+		passphraseFlag,
+		signaturesFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		address := getOracleAddress(ctx)
+		client := dialRPC(ctx)
+		cp := getLatestCheckpoint(client)
+
+		var sigs [][]byte
+		for _, s := range strings.Split(ctx.String(signaturesFlag.Name), ",") {
+			sig, err := hexutil.Decode(strings.TrimSpace(s))
+			if err != nil || len(sig) != 65 {
+				utils.Fatalf("Invalid signature %q", s)
+			}
+			sigs = append(sigs, sig)
+		}
+		signers, err := cp.Signers(address, sigs)
+		if err != nil {
+			utils.Fatalf("Failed to recover signers: %v", err)
+		}
+		for _, signer := range signers {
+			fmt.Println("Signed by:", signer.Hex())
+		}
+		key := getKey(ctx)
+		oracle, err := checkpointoracle.NewCheckpointOracle(address, aaeclient.NewClient(client))
+		if err != nil {
+			utils.Fatalf("Failed to bind the oracle: %v", err)
+		}
+		tx, err := oracle.SetCheckpoint(bind.NewKeyedTransactor(key.PrivateKey), cp, sigs)
+		if err != nil {
+			utils.Fatalf("Failed to publish checkpoint: %v", err)
+		}
+		fmt.Println("Published section", cp.SectionIndex, "in transaction", tx.Hash().Hex())
+		return nil
+	},
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+// checkpoint-admin is a utility for the signers of a checkpoint oracle to
+// build, sign and publish checkpoints for light clients.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aaechain/go-aaechain/cmd/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Git SHA1 commit hash of the release (set via linker flags)
+var gitCommit = ""
+
+var app *cli.App
+
+func init() {
+	app = utils.NewApp(gitCommit, "a checkpoint oracle administration tool")
+	app.Commands = []cli.Command{
+		commandStatus,
+		commandSign,
+		commandPublish,
+	}
+}
+
+// Commonly used command line flags.
+var (
+	rpcFlag = cli.StringFlag{
+		Name:  "rpc",
+		Value: "http://localhost:8545",
+		Usage: "the rpc endpoint of a full node serving light clients",
+	}
+	oracleFlag = cli.StringFlag{
+		Name:  "oracle",
+		Usage: "the address of the checkpoint oracle contract",
+	}
+	passphraseFlag = cli.StringFlag{
+		Name:  "passwordfile",
+		Usage: "the file that contains the passphrase for the keyfile",
+	}
+	signaturesFlag = cli.StringFlag{
+		Name:  "signatures",
+		Usage: "comma separated hex signatures of the checkpoint",
+	}
+	jsonFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "output JSON instead of human-readable format",
+	}
+)
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aaechain/go-aaechain/accounts/keystore"
+	"github.com/aaechain/go-aaechain/cmd/utils"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/console"
+	"github.com/aaechain/go-aaechain/contracts/checkpointoracle"
+	"github.com/aaechain/go-aaechain/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// getPassPhrase obtains a passphrase given by the user. It first checks the
+// --passwordfile command line flag and ultimately prompts the user for a
+// passphrase.
+func getPassPhrase(ctx *cli.Context) string {
+	passphraseFile := ctx.String(passphraseFlag.Name)
+	if passphraseFile != "" {
+		content, err := ioutil.ReadFile(passphraseFile)
+		if err != nil {
+			utils.Fatalf("Failed to read passphrase file '%s': %v", passphraseFile, err)
+		}
+		return strings.TrimRight(string(content), "\r\n")
+	}
+	passphrase, err := console.Stdin.PromptPassword("Passphrase: ")
+	if err != nil {
+		utils.Fatalf("Failed to read passphrase: %v", err)
+	}
+	return passphrase
+}
+
+// getKey loads and decrypts the keyfile given as the first argument.
+func getKey(ctx *cli.Context) *keystore.Key {
+	keyfilepath := ctx.Args().First()
+	if keyfilepath == "" {
+		utils.Fatalf("No keyfile specified")
+	}
+	keyjson, err := ioutil.ReadFile(keyfilepath)
+	if err != nil {
+		utils.Fatalf("Failed to read the keyfile at '%s': %v", keyfilepath, err)
+	}
+	key, err := keystore.DecryptKey(keyjson, getPassPhrase(ctx))
+	if err != nil {
+		utils.Fatalf("Error decrypting key: %v", err)
+	}
+	return key
+}
+
+// getOracleAddress returns the address given by the --oracle flag.
+func getOracleAddress(ctx *cli.Context) common.Address {
+	if !common.IsHexAddress(ctx.String(oracleFlag.Name)) {
+		utils.Fatalf("Invalid or missing oracle address (--%s)", oracleFlag.Name)
+	}
+	return common.HexToAddress(ctx.String(oracleFlag.Name))
+}
+
+// dialRPC connects to the node given by the --rpc flag.
+func dialRPC(ctx *cli.Context) *rpc.Client {
+	client, err := rpc.Dial(ctx.String(rpcFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to connect to %s: %v", ctx.String(rpcFlag.Name), err)
+	}
+	return client
+}
+
+// getLatestCheckpoint retrieves the latest section processed by the server.
+func getLatestCheckpoint(client *rpc.Client) *checkpointoracle.Checkpoint {
+	var cp checkpointoracle.Checkpoint
+	if err := client.Call(&cp, "les_latestCheckpoint"); err != nil {
+		utils.Fatalf("Failed to retrieve latest checkpoint: %v", err)
+	}
+	return &cp
+}
+
+// mustPrintJSON prints the JSON encoding of the given object and
+// exits the program with an error message when the marshaling fails.
+func mustPrintJSON(jsonObject interface{}) {
+	str, err := json.MarshalIndent(jsonObject, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to marshal JSON object: %v", err)
+	}
+	fmt.Println(string(str))
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var commandCrawl = cli.Command{
+	Name:      "crawl",
+	Usage:     "crawl the discovery network and update a node set",
+	ArgsUsage: "<nodes.json>",
+	Description: `
+Crawl runs random lookups in the discovery network and retrieves the node records
+of all nodes it finds. The records are added to the given node set file. Nodes
+which have not been seen for longer than --maxage are removed from the set.
+`,
+	Flags: []cli.Flag{
+		bootnodesFlag,
+		listenAddrFlag,
+		crawlTimeoutFlag,
+		maxAgeFlag,
+	},
+	Action: crawl,
+}
+
+var (
+	bootnodesFlag = cli.StringFlag{
+		Name:  "bootnodes",
+		Usage: "comma separated enode URLs to start the crawl from (defaults to the mainnet bootnodes)",
+	}
+	listenAddrFlag = cli.StringFlag{
+		Name:  "addr",
+		Usage: "UDP listen address",
+		Value: "0.0.0.0:0",
+	}
+	crawlTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "time limit for the crawl",
+		Value: 30 * time.Minute,
+	}
+	maxAgeFlag = cli.DurationFlag{
+		Name:  "maxage",
+		Usage: "remove nodes not seen within this time span",
+		Value: 24 * time.Hour,
+	}
+)
+
+func crawl(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need node set file as argument")
+	}
+	file := ctx.Args().First()
+	ns, err := loadNodesJSON(file)
+	if err != nil {
+		return err
+	}
+	tab, err := startDiscovery(ctx, ns)
+	if err != nil {
+		return err
+	}
+	defer tab.Close()
+
+	var (
+		deadline = time.Now().Add(ctx.Duration(crawlTimeoutFlag.Name))
+		seen     = make(map[discover.NodeID]bool)
+		added    int
+	)
+	for time.Now().Before(deadline) {
+		var target discover.NodeID
+		rand.Read(target[:])
+		for _, n := range tab.Lookup(target) {
+			if seen[n.ID] {
+				continue
+			}
+			seen[n.ID] = true
+			rec, err := tab.RequestENR(n)
+			if err != nil {
+				log.Debug("Can't retrieve node record", "id", n.ID, "err", err)
+				continue
+			}
+			key := n.ID.String()
+			if _, ok := ns[key]; !ok {
+				added++
+			}
+			ns[key] = nodeJSON{Record: (*textRecord)(rec), LastSeen: time.Now()}
+		}
+		log.Info("Crawling", "seen", len(seen), "added", added, "total", len(ns))
+	}
+
+	cutoff := time.Now().Add(-ctx.Duration(maxAgeFlag.Name))
+	for key, n := range ns {
+		if n.LastSeen.Before(cutoff) {
+			delete(ns, key)
+		}
+	}
+	return writeNodesJSON(file, ns)
+}
+
+// startDiscovery starts a discovery table with a temporary key. Nodes of the
+// existing node set are used as additional bootstrap nodes.
+func startDiscovery(ctx *cli.Context, ns nodeSet) (*discover.Table, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	urls := params.MainnetBootnodes
+	if ctx.IsSet(bootnodesFlag.Name) {
+		urls = strings.Split(ctx.String(bootnodesFlag.Name), ",")
+	}
+	var bootnodes []*discover.Node
+	for _, url := range urls {
+		n, err := discover.ParseNode(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bootnode %q: %v", url, err)
+		}
+		bootnodes = append(bootnodes, n)
+	}
+	for _, rec := range ns.records() {
+		if n, err := discover.NodeFromRecord(rec); err == nil {
+			bootnodes = append(bootnodes, n)
+		}
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", ctx.String(listenAddrFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	tab, err := discover.ListenUDP(conn, discover.Config{PrivateKey: key, Bootnodes: bootnodes})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tab, nil
+}
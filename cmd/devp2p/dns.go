@@ -0,0 +1,157 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/dnsdisc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var commandDNS = cli.Command{
+	Name:  "dns",
+	Usage: "DNS node list commands",
+	Subcommands: []cli.Command{
+		commandDNSZone,
+		commandDNSSync,
+	},
+}
+
+var commandDNSZone = cli.Command{
+	Name:      "zone",
+	Usage:     "create a signed DNS zone file from a node set",
+	ArgsUsage: "<nodes.json>",
+	Description: `
+Zone builds a node tree containing all records of the node set, signs its root
+with the key in --keyfile and prints the TXT records of the tree as a zone file.
+The URL of the tree is printed to stderr.
+`,
+	Flags: []cli.Flag{
+		domainFlag,
+		keyFileFlag,
+		seqFlag,
+		linkFlag,
+		outputFlag,
+	},
+	Action: dnsZone,
+}
+
+var commandDNSSync = cli.Command{
+	Name:      "sync",
+	Usage:     "download a DNS node tree into a node set",
+	ArgsUsage: "<enrtree-url> <nodes.json>",
+	Action:    dnsSync,
+}
+
+var (
+	domainFlag = cli.StringFlag{
+		Name:  "domain",
+		Usage: "domain name at which the tree is published",
+	}
+	keyFileFlag = cli.StringFlag{
+		Name:  "keyfile",
+		Usage: "file containing the hex private key used to sign the tree",
+	}
+	seqFlag = cli.UintFlag{
+		Name:  "seq",
+		Usage: "sequence number of the tree (defaults to the current unix time)",
+	}
+	linkFlag = cli.StringFlag{
+		Name:  "links",
+		Usage: "comma separated enrtree:// URLs of trees linked from the tree",
+	}
+	outputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "write the zone file to this file instead of stdout",
+	}
+)
+
+func dnsZone(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need node set file as argument")
+	}
+	domain := ctx.String(domainFlag.Name)
+	if domain == "" {
+		return fmt.Errorf("missing --%s", domainFlag.Name)
+	}
+	if !ctx.IsSet(keyFileFlag.Name) {
+		return fmt.Errorf("missing --%s", keyFileFlag.Name)
+	}
+	key, err := crypto.LoadECDSA(ctx.String(keyFileFlag.Name))
+	if err != nil {
+		return fmt.Errorf("can't load key: %v", err)
+	}
+	ns, err := loadNodesJSON(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	seq := ctx.Uint(seqFlag.Name)
+	if !ctx.IsSet(seqFlag.Name) {
+		seq = uint(time.Now().Unix())
+	}
+	var links []string
+	if ctx.IsSet(linkFlag.Name) {
+		links = strings.Split(ctx.String(linkFlag.Name), ",")
+	}
+
+	tree, err := dnsdisc.MakeTree(seq, ns.records(), links)
+	if err != nil {
+		return err
+	}
+	url, err := tree.Sign(key, domain)
+	if err != nil {
+		return err
+	}
+	out := os.Stdout
+	if file := ctx.String(outputFlag.Name); file != "" {
+		if out, err = os.Create(file); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	if err := tree.WriteZone(out, domain); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, url)
+	return nil
+}
+
+func dnsSync(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("need tree URL and node set file as arguments")
+	}
+	client := dnsdisc.NewClient(dnsdisc.Config{})
+	tree, err := client.SyncTree(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	ns := make(nodeSet)
+	for _, rec := range tree.Nodes() {
+		n, err := discover.NodeFromRecord(rec)
+		if err != nil {
+			continue
+		}
+		ns[n.ID.String()] = nodeJSON{Record: (*textRecord)(rec), LastSeen: time.Now()}
+	}
+	return writeNodesJSON(ctx.Args().Get(1), ns)
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+// devp2p is a utility for node discovery. It crawls the discovery network and
+// publishes the nodes it finds as DNS node lists.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aaechain/go-aaechain/cmd/utils"
+	"github.com/aaechain/go-aaechain/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Git SHA1 commit hash of the release (set via linker flags)
+var gitCommit = ""
+
+var app *cli.App
+
+func init() {
+	app = utils.NewApp(gitCommit, "aaechain p2p tool")
+	app.Flags = []cli.Flag{
+		verbosityFlag,
+	}
+	app.Before = func(ctx *cli.Context) error {
+		glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+		glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
+		log.Root().SetHandler(glogger)
+		return nil
+	}
+	app.Commands = []cli.Command{
+		commandCrawl,
+		commandDNS,
+	}
+}
+
+var verbosityFlag = cli.IntFlag{
+	Name:  "verbosity",
+	Usage: "log verbosity (0-9)",
+	Value: int(log.LvlInfo),
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aaechain/go-aaechain/p2p/enr"
+	"github.com/aaechain/go-aaechain/rlp"
+)
+
+// nodeSet is the content of a nodes.json file, keyed by node ID.
+type nodeSet map[string]nodeJSON
+
+type nodeJSON struct {
+	Record   *textRecord `json:"record"`
+	LastSeen time.Time   `json:"lastSeen"`
+}
+
+// textRecord is a node record which is encoded as "enr:<base64 RLP>" in JSON.
+type textRecord enr.Record
+
+func (r *textRecord) MarshalText() ([]byte, error) {
+	enc, err := rlp.EncodeToBytes((*enr.Record)(r))
+	if err != nil {
+		return nil, err
+	}
+	return []byte("enr:" + base64.RawURLEncoding.EncodeToString(enc)), nil
+}
+
+func (r *textRecord) UnmarshalText(text []byte) error {
+	s := string(text)
+	if !strings.HasPrefix(s, "enr:") {
+		return fmt.Errorf("missing 'enr:' prefix")
+	}
+	enc, err := base64.RawURLEncoding.DecodeString(s[4:])
+	if err != nil {
+		return err
+	}
+	return rlp.Decode(bytes.NewReader(enc), (*enr.Record)(r))
+}
+
+// records returns the node records in the set.
+func (ns nodeSet) records() []*enr.Record {
+	records := make([]*enr.Record, 0, len(ns))
+	for _, n := range ns {
+		records = append(records, (*enr.Record)(n.Record))
+	}
+	return records
+}
+
+func loadNodesJSON(file string) (nodeSet, error) {
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return make(nodeSet), nil
+	} else if err != nil {
+		return nil, err
+	}
+	ns := make(nodeSet)
+	if err := json.Unmarshal(content, &ns); err != nil {
+		return nil, fmt.Errorf("invalid node set in %s: %v", file, err)
+	}
+	return ns, nil
+}
+
+func writeNodesJSON(file string, ns nodeSet) error {
+	content, err := json.MarshalIndent(ns, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(content, '\n'), 0644)
+}
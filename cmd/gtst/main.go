@@ -58,6 +58,7 @@ var (
 		utils.BootnodesFlag,
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
+		utils.DNSDiscoveryFlag,
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
@@ -88,6 +89,9 @@ var (
 		utils.GCModeFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.LightPriorityFlag,
+		utils.UltraLightServersFlag,
+		utils.UltraLightFractionFlag,
 		utils.LightKDFFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
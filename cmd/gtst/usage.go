@@ -80,6 +80,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.LightPriorityFlag,
+			utils.UltraLightServersFlag,
+			utils.UltraLightFractionFlag,
 			utils.LightKDFFlag,
 		},
 	},
@@ -168,6 +171,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.BootnodesFlag,
 			utils.BootnodesV4Flag,
 			utils.BootnodesV5Flag,
+			utils.DNSDiscoveryFlag,
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
@@ -0,0 +1,112 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aaechain/go-aaechain/cmd/utils"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/common/hexutil"
+	"github.com/aaechain/go-aaechain/swarm/api"
+	swarm "github.com/aaechain/go-aaechain/swarm/api/client"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func accessNewPass(ctx *cli.Context) {
+	hash := accessArg(ctx, "swarm access new pass <hash>")
+	newAccess(ctx, hash, &api.AccessRequest{
+		Type:     api.AccessTypePass,
+		Password: accessPassword(ctx),
+	})
+}
+
+func accessNewPK(ctx *cli.Context) {
+	hash := accessArg(ctx, "swarm access new pk --grant-key <public key> <hash>")
+	key := ctx.String(SwarmAccessGrantKeyFlag.Name)
+	if key == "" {
+		utils.Fatalf("Missing --%s", SwarmAccessGrantKeyFlag.Name)
+	}
+	newAccess(ctx, hash, &api.AccessRequest{
+		Type:     api.AccessTypePK,
+		Grantees: []hexutil.Bytes{common.FromHex(key)},
+	})
+}
+
+func accessNewACT(ctx *cli.Context) {
+	hash := accessArg(ctx, "swarm access new act --grant-keys <file> [--with-password] <hash>")
+	newAccess(ctx, hash, actRequest(ctx))
+}
+
+func accessUpdate(ctx *cli.Context) {
+	root := accessArg(ctx, "swarm access update --grant-keys <file> [--with-password] <root hash>")
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	hash, err := client.UpdateAccess(root, actRequest(ctx))
+	if err != nil {
+		utils.Fatalf("Failed to update access root %s: %s", root, err)
+	}
+	fmt.Println(hash)
+}
+
+func newAccess(ctx *cli.Context, hash string, req *api.AccessRequest) {
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	root, err := client.NewAccess(hash, req)
+	if err != nil {
+		utils.Fatalf("Failed to create access root for %s: %s", hash, err)
+	}
+	fmt.Println(root)
+}
+
+func accessArg(ctx *cli.Context, usage string) string {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: %s", usage)
+	}
+	return args[0]
+}
+
+// actRequest builds the request of an ACT root from the grant keys file and
+// the optional password
+func actRequest(ctx *cli.Context) *api.AccessRequest {
+	req := &api.AccessRequest{Type: api.AccessTypeACT}
+	if path := ctx.String(SwarmAccessGrantKeysFlag.Name); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			utils.Fatalf("Failed to read grant keys: %s", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				req.Grantees = append(req.Grantees, common.FromHex(line))
+			}
+		}
+	}
+	if ctx.Bool(SwarmAccessWithPasswordFlag.Name) {
+		req.Password = accessPassword(ctx)
+	}
+	if len(req.Grantees) == 0 && req.Password == "" {
+		utils.Fatalf("Missing --%s or --%s", SwarmAccessGrantKeysFlag.Name, SwarmAccessWithPasswordFlag.Name)
+	}
+	return req
+}
+
+func accessPassword(ctx *cli.Context) string {
+	return getPassPhrase("Password for the access controlled root", 0, utils.MakePasswordList(ctx))
+}
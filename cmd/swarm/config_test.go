@@ -154,7 +154,7 @@ func TestFileOverrides(t *testing.T) {
 	defaultConf.ChunkerParams.Branches = 64
 	defaultConf.HiveParams.CallInterval = 6000000000
 	defaultConf.Swap.Params.Strategy.AutoCashInterval = 600 * time.Second
-	defaultConf.SyncParams.KeyBufferSize = 512
+	defaultConf.SyncParams.SyncBatchSize = 512
 	//create a TOML string
 	out, err := tomlSettings.Marshal(&defaultConf)
 	if err != nil {
@@ -239,8 +239,8 @@ func TestFileOverrides(t *testing.T) {
 		t.Fatalf("Expected SwapParams AutoCashInterval to be %ds, got %d", 600, info.Swap.Params.Strategy.AutoCashInterval)
 	}
 
-	if info.SyncParams.KeyBufferSize != 512 {
-		t.Fatalf("Expected info.SyncParams.KeyBufferSize to be %d, got %d", 512, info.SyncParams.KeyBufferSize)
+	if info.SyncParams.SyncBatchSize != 512 {
+		t.Fatalf("Expected info.SyncParams.SyncBatchSize to be %d, got %d", 512, info.SyncParams.SyncBatchSize)
 	}
 
 	node.Shutdown()
@@ -361,7 +361,7 @@ func TestCmdLineOverridesFile(t *testing.T) {
 	defaultConf.ChunkerParams.Branches = 64
 	defaultConf.HiveParams.CallInterval = 6000000000
 	defaultConf.Swap.Params.Strategy.AutoCashInterval = 600 * time.Second
-	defaultConf.SyncParams.KeyBufferSize = 512
+	defaultConf.SyncParams.SyncBatchSize = 512
 	//create a TOML file
 	out, err := tomlSettings.Marshal(&defaultConf)
 	if err != nil {
@@ -451,8 +451,8 @@ func TestCmdLineOverridesFile(t *testing.T) {
 		t.Fatalf("Expected SwapParams AutoCashInterval to be %ds, got %d", 600, info.Swap.Params.Strategy.AutoCashInterval)
 	}
 
-	if info.SyncParams.KeyBufferSize != 512 {
-		t.Fatalf("Expected info.SyncParams.KeyBufferSize to be %d, got %d", 512, info.SyncParams.KeyBufferSize)
+	if info.SyncParams.SyncBatchSize != 512 {
+		t.Fatalf("Expected info.SyncParams.SyncBatchSize to be %d, got %d", 512, info.SyncParams.SyncBatchSize)
 	}
 
 	node.Shutdown()
@@ -136,6 +136,30 @@ var (
 		Name:  "mime",
 		Usage: "force mime type",
 	}
+	SwarmEncryptedFlag = cli.BoolFlag{
+		Name:  "encrypt",
+		Usage: "encrypt the uploaded file without a manifest, printing a reference including the decryption key (single files only)",
+	}
+	SwarmRedundancyFlag = cli.IntFlag{
+		Name:  "redundancy",
+		Usage: "number of parity chunks added to every intermediate chunk of the upload, allowing as many missing chunks to be recovered (0-16)",
+	}
+	SwarmPinRawFlag = cli.BoolFlag{
+		Name:  "raw",
+		Usage: "pin raw content instead of a manifest and the content it refers to",
+	}
+	SwarmAccessGrantKeyFlag = cli.StringFlag{
+		Name:  "grant-key",
+		Usage: "public key (hex) of the grantee",
+	}
+	SwarmAccessGrantKeysFlag = cli.StringFlag{
+		Name:  "grant-keys",
+		Usage: "file with the public keys (hex) of the grantees, one per line",
+	}
+	SwarmAccessWithPasswordFlag = cli.BoolFlag{
+		Name:  "with-password",
+		Usage: "also grant access with a password (read from --password or prompted)",
+	}
 	CorsStringFlag = cli.StringFlag{
 		Name:   "corsdomain",
 		Usage:  "Domain on which to send Access-Control-Allow-Origin header (multiple domains can be supplied separated by a ',')",
@@ -195,6 +219,9 @@ The output of this command is supposed to be machine-readable.
 			ArgsUsage: " <file>",
 			Description: `
 "upload a file or directory to swarm using the HTTP API and prints the root hash",
+
+With --encrypt only a single file can be uploaded. It is stored raw, without a
+manifest, and the printed reference includes the decryption key.
 `,
 		},
 		{
@@ -248,6 +275,121 @@ Update the hash for an already existing path in the manifest
 					ArgsUsage: "<MANIFEST> <path>",
 					Description: `
 Removes a path from the manifest
+`,
+				},
+			},
+		},
+		{
+			Name:      "pin",
+			Usage:     "manage the content pinned on the local node",
+			ArgsUsage: "pin COMMAND",
+			Description: `
+Pins content on the local node, protecting its chunks from garbage collection.
+`,
+			Subcommands: []cli.Command{
+				{
+					Action:    pinAdd,
+					Name:      "add",
+					Usage:     "pin a manifest and all the content it refers to",
+					ArgsUsage: "<hash>",
+					Flags:     []cli.Flag{SwarmPinRawFlag},
+					Description: `
+Pins a manifest, its submanifests and all the content they refer to, retrieving
+the chunks from the network if needed. With --raw the hash is pinned as raw
+content instead.
+
+    swarm pin add <hash>
+`,
+				},
+				{
+					Action:    pinRemove,
+					Name:      "rm",
+					Usage:     "unpin content pinned with 'swarm pin add'",
+					ArgsUsage: "<hash>",
+					Description: `
+Unpins content, its chunks may be garbage collected unless pinned by another root.
+`,
+				},
+				{
+					Action:    pinList,
+					Name:      "ls",
+					Usage:     "list the pinned roots with their chunk counts and sizes",
+					ArgsUsage: " ",
+					Description: `
+Lists the pinned roots with the number and total size of the chunks they pin.
+`,
+				},
+			},
+		},
+		{
+			Name:      "access",
+			Usage:     "manage access control to content",
+			ArgsUsage: "access COMMAND",
+			Description: `
+Creates and updates access controlled roots wrapping the reference of content
+so that only the grantees can retrieve it.
+`,
+			Subcommands: []cli.Command{
+				{
+					Name:      "new",
+					Usage:     "create an access controlled root",
+					ArgsUsage: "new COMMAND",
+					Description: `
+Creates an access controlled root for the given hash, the node publishes it with
+its own key.
+`,
+					Subcommands: []cli.Command{
+						{
+							Action:    accessNewPass,
+							Name:      "pass",
+							Usage:     "grant access with a password",
+							ArgsUsage: "<hash>",
+							Description: `
+Grants access to anyone knowing the password, which is read from the file given
+with --password or prompted for.
+
+    swarm --password <file> access new pass <hash>
+`,
+						},
+						{
+							Action:    accessNewPK,
+							Name:      "pk",
+							Usage:     "grant access to a single public key",
+							ArgsUsage: "<hash>",
+							Flags:     []cli.Flag{SwarmAccessGrantKeyFlag},
+							Description: `
+Grants access to the node owning the given public key.
+
+    swarm access new pk --grant-key <public key> <hash>
+`,
+						},
+						{
+							Action:    accessNewACT,
+							Name:      "act",
+							Usage:     "grant access to a list of public keys",
+							ArgsUsage: "<hash>",
+							Flags:     []cli.Flag{SwarmAccessGrantKeysFlag, SwarmAccessWithPasswordFlag},
+							Description: `
+Grants access to the nodes owning the public keys listed in the given file and,
+with --with-password, to anyone knowing the password. The list can be changed
+later with 'swarm access update'.
+
+    swarm access new act --grant-keys <file> <hash>
+`,
+						},
+					},
+				},
+				{
+					Action:    accessUpdate,
+					Name:      "update",
+					Usage:     "replace the grantees of an access controlled root",
+					ArgsUsage: "<root hash>",
+					Flags:     []cli.Flag{SwarmAccessGrantKeysFlag, SwarmAccessWithPasswordFlag},
+					Description: `
+Replaces the grantees of a root created with 'swarm access new act' by this node,
+printing the hash of the updated root.
+
+    swarm access update --grant-keys <file> <root hash>
 `,
 				},
 			},
@@ -355,6 +497,8 @@ DEPRECATED: use 'swarm db clean'.
 		SwarmUploadDefaultPath,
 		SwarmUpFromStdinFlag,
 		SwarmUploadMimeType,
+		SwarmEncryptedFlag,
+		SwarmRedundancyFlag,
 		//deprecated flags
 		DeprecatedaaeAPIFlag,
 		DeprecatedEnsAddrFlag,
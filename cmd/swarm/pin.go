@@ -0,0 +1,72 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-aaeereum.
+//
+// go-aaeereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-aaeereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-aaeereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aaechain/go-aaechain/cmd/utils"
+	swarm "github.com/aaechain/go-aaechain/swarm/api/client"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func pinAdd(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm pin add [--raw] <hash>")
+	}
+
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	root, err := client.Pin(args[0], ctx.Bool(SwarmPinRawFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to pin %s: %s", args[0], err)
+	}
+	fmt.Printf("%s: %d chunks, %d bytes\n", root.Root, root.Chunks, root.Size)
+}
+
+func pinRemove(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm pin rm <hash>")
+	}
+
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	if err := client.Unpin(args[0]); err != nil {
+		utils.Fatalf("Failed to unpin %s: %s", args[0], err)
+	}
+}
+
+func pinList(ctx *cli.Context) {
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	roots, err := client.PinnedRoots()
+	if err != nil {
+		utils.Fatalf("Failed to list pinned content: %s", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "ROOT\tCHUNKS\tSIZE")
+	for _, root := range roots {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", root.Root, root.Chunks, root.Size)
+	}
+}
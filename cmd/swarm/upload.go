@@ -45,9 +45,12 @@ func upload(ctx *cli.Context) {
 		defaultPath  = ctx.GlobalString(SwarmUploadDefaultPath.Name)
 		fromStdin    = ctx.GlobalBool(SwarmUpFromStdinFlag.Name)
 		mimeType     = ctx.GlobalString(SwarmUploadMimeType.Name)
+		encrypt      = ctx.GlobalBool(SwarmEncryptedFlag.Name)
+		redundancy   = ctx.GlobalInt(SwarmRedundancyFlag.Name)
 		client       = swarm.NewClient(bzzapi)
 		file         string
 	)
+	client.Redundancy = redundancy
 
 	if len(args) != 1 {
 		if fromStdin {
@@ -70,13 +73,27 @@ func upload(ctx *cli.Context) {
 		file = expandPath(args[0])
 	}
 
-	if !wantManifest {
+	if encrypt {
+		// Encrypted content is uploaded raw as a single file, a manifest would
+		// reveal the file names and paths
+		if recursive || defaultPath != "" {
+			utils.Fatalf("--%s can't be combined with --%s or --%s, only single files can be uploaded encrypted", SwarmEncryptedFlag.Name, SwarmRecursiveUploadFlag.Name, SwarmUploadDefaultPath.Name)
+		}
+		if stat, err := os.Stat(file); err == nil && stat.IsDir() {
+			utils.Fatalf("--%s doesn't support directories, only single files can be uploaded encrypted", SwarmEncryptedFlag.Name)
+		}
+	}
+	if !wantManifest || encrypt {
 		f, err := swarm.Open(file)
 		if err != nil {
 			utils.Fatalf("Error opening file: %s", err)
 		}
 		defer f.Close()
-		hash, err := client.UploadRaw(f, f.Size)
+		uploadRaw := client.UploadRaw
+		if encrypt {
+			uploadRaw = client.UploadEncrypted
+		}
+		hash, err := uploadRaw(f, f.Size)
 		if err != nil {
 			utils.Fatalf("Upload failed: %s", err)
 		}
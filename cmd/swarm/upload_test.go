@@ -57,6 +57,22 @@ func TestCLISwarmUp(t *testing.T) {
 	}
 }
 
+// TestCLISwarmUpEncryptDir tests that 'swarm up --encrypt' rejects directories
+// with a usage error, as only single files can be uploaded encrypted
+func TestCLISwarmUpEncryptDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swarm-test")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	up := runSwarm(t, "--encrypt", "up", dir)
+	up.ExpectRegexp(`Fatal: --encrypt doesn't support directories.*\n`)
+	up.ExpectExit()
+
+	up = runSwarm(t, "--encrypt", "--recursive", "up", dir)
+	up.ExpectRegexp(`Fatal: --encrypt can't be combined with --recursive.*\n`)
+	up.ExpectExit()
+}
+
 func assertNil(t *testing.T, err error) {
 	if err != nil {
 		t.Fatal(err)
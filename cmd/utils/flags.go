@@ -185,6 +185,19 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: aae.DefaultConfig.LightPeers,
 	}
+	LightPriorityFlag = cli.StringFlag{
+		Name:  "lightpriority",
+		Usage: "Comma separated node IDs of LES clients with guaranteed capacity",
+	}
+	UltraLightServersFlag = cli.StringFlag{
+		Name:  "ultralight",
+		Usage: "Comma separated node IDs of trusted LES servers, enabling ultra-light client mode",
+	}
+	UltraLightFractionFlag = cli.IntFlag{
+		Name:  "ultralightfraction",
+		Usage: "Percentage of trusted LES servers required to agree on a new head in ultra-light mode (1-100)",
+		Value: aae.DefaultConfig.UltraLightFraction,
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -479,6 +492,11 @@ var (
 		Usage: "Comma separated enode URLs for P2P v5 discovery booaaerap (light server, light nodes)",
 		Value: "",
 	}
+	DNSDiscoveryFlag = cli.StringFlag{
+		Name:  "discovery.dns",
+		Usage: "Comma separated enrtree:// URLs of DNS node lists used as dial candidates",
+		Value: "",
+	}
 	NodeKeyFileFlag = cli.StringFlag{
 		Name:  "nodekey",
 		Usage: "P2P node key file",
@@ -647,6 +665,19 @@ func setBooaaerapNodesV5(ctx *cli.Context, cfg *p2p.Config) {
 	}
 }
 
+// setDNSDiscovery sets the DNS node list URLs from the command line flags.
+func setDNSDiscovery(ctx *cli.Context, cfg *p2p.Config) {
+	if !ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
+		return
+	}
+	cfg.DNSDiscovery = nil
+	for _, url := range strings.Split(ctx.GlobalString(DNSDiscoveryFlag.Name), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			cfg.DNSDiscovery = append(cfg.DNSDiscovery, url)
+		}
+	}
+}
+
 // setListenAddress creates a TCP listening address string from set command
 // line flags.
 func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
@@ -812,6 +843,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setListenAddress(ctx, cfg)
 	setBooaaerapNodes(ctx, cfg)
 	setBooaaerapNodesV5(ctx, cfg)
+	setDNSDiscovery(ctx, cfg)
 
 	lightClient := ctx.GlobalBool(LightModeFlag.Name) || ctx.GlobalString(SyncModeFlag.Name) == "light"
 	lightServer := ctx.GlobalInt(LightServFlag.Name) != 0
@@ -1039,6 +1071,27 @@ func SetaaeConfig(ctx *cli.Context, stack *node.Node, cfg *aae.Config) {
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(LightPriorityFlag.Name) {
+		for _, hex := range strings.Split(ctx.GlobalString(LightPriorityFlag.Name), ",") {
+			id, err := discover.HexID(strings.TrimSpace(hex))
+			if err != nil {
+				Fatalf("Option %q: %v", LightPriorityFlag.Name, err)
+			}
+			cfg.LightPriorityClients = append(cfg.LightPriorityClients, id)
+		}
+	}
+	if ctx.GlobalIsSet(UltraLightServersFlag.Name) {
+		for _, hex := range strings.Split(ctx.GlobalString(UltraLightServersFlag.Name), ",") {
+			id, err := discover.HexID(strings.TrimSpace(hex))
+			if err != nil {
+				Fatalf("Option %q: %v", UltraLightServersFlag.Name, err)
+			}
+			cfg.UltraLightServers = append(cfg.UltraLightServers, id)
+		}
+	}
+	if ctx.GlobalIsSet(UltraLightFractionFlag.Name) {
+		cfg.UltraLightFraction = ctx.GlobalInt(UltraLightFractionFlag.Name)
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
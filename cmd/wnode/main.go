@@ -81,6 +81,7 @@ var (
 var (
 	booaaerapMode  = flag.Bool("standalone", false, "boostrap node: don't initiate connection to peers, just wait for incoming connections")
 	forwarderMode  = flag.Bool("forwarder", false, "forwarder mode: only forward messages, neither encrypt nor decrypt messages")
+	lightMode      = flag.Bool("light", false, "light client mode: only receive messages with the topic of interest, don't forward any")
 	mailServerMode = flag.Bool("mailserver", false, "mail server mode: delivers expired messages on demand")
 	requestMail    = flag.Bool("mailclient", false, "request expired messages from the booaaerap server")
 	asymmetricMode = flag.Bool("asym", false, "use asymmetric encryption")
@@ -208,6 +209,7 @@ func initialize() {
 	cfg := &whisper.Config{
 		MaxMessageSize:     uint32(*argMaxSize),
 		MinimumAcceptedPOW: *argPoW,
+		LightClient:        *lightMode,
 	}
 
 	if *argPoW != whisper.DefaultMinimumPoW {
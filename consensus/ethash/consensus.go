@@ -281,6 +281,9 @@ func (ethash *aaeash) verifyHeader(chain consensus.ChainReader, header, parent *
 	if err := misc.VerifyForkHashes(chain.Config(), header, uncle); err != nil {
 		return err
 	}
+	if err := misc.VerifyBaseFee(chain.Config(), parent, header); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,87 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/common/math"
+	"github.com/aaechain/go-aaechain/core/types"
+	"github.com/aaechain/go-aaechain/params"
+)
+
+// VerifyBaseFee verifies that the header carries a base fee if and only if the
+// base fee fork is active, and that it was correctly derived from the parent.
+func VerifyBaseFee(config *params.ChainConfig, parent, header *types.Header) error {
+	if !config.IsBaseFee(header.Number) {
+		if header.BaseFee != nil {
+			return fmt.Errorf("invalid baseFee before fork: have %v, want <nil>", header.BaseFee)
+		}
+		return nil
+	}
+	if header.BaseFee == nil {
+		return fmt.Errorf("header is missing baseFee")
+	}
+	if expected := CalcBaseFee(config, parent); header.BaseFee.Cmp(expected) != 0 {
+		return fmt.Errorf("invalid baseFee: have %v, want %v, parentBaseFee %v, parentGasUsed %d",
+			header.BaseFee, expected, parent.BaseFee, parent.GasUsed)
+	}
+	return nil
+}
+
+// CalcBaseFee calculates the base fee of the header following the parent. The
+// base fee grows if the parent used more than its gas target, half of the gas
+// limit, and shrinks if it used less, by at most 1/BaseFeeChangeDenominator.
+func CalcBaseFee(config *params.ChainConfig, parent *types.Header) *big.Int {
+	// The first block of the fork starts at the initial base fee
+	if !config.IsBaseFee(parent.Number) {
+		return new(big.Int).SetUint64(params.InitialBaseFee)
+	}
+	var (
+		parentGasTarget          = parent.GasLimit / params.ElasticityMultiplier
+		parentGasTargetBig       = new(big.Int).SetUint64(parentGasTarget)
+		baseFeeChangeDenominator = new(big.Int).SetUint64(params.BaseFeeChangeDenominator)
+	)
+	// If the parent gasUsed is the same as the target, the baseFee remains unchanged
+	if parent.GasUsed == parentGasTarget || parentGasTarget == 0 {
+		return new(big.Int).Set(parent.BaseFee)
+	}
+	if parent.GasUsed > parentGasTarget {
+		// If the parent block used more gas than its target, the baseFee should increase
+		gasUsedDelta := new(big.Int).SetUint64(parent.GasUsed - parentGasTarget)
+		x := new(big.Int).Mul(parent.BaseFee, gasUsedDelta)
+		y := x.Div(x, parentGasTargetBig)
+		baseFeeDelta := math.BigMax(
+			x.Div(y, baseFeeChangeDenominator),
+			common.Big1,
+		)
+		return x.Add(parent.BaseFee, baseFeeDelta)
+	}
+	// Otherwise if the parent block used less gas than its target, the baseFee should decrease
+	gasUsedDelta := new(big.Int).SetUint64(parentGasTarget - parent.GasUsed)
+	x := new(big.Int).Mul(parent.BaseFee, gasUsedDelta)
+	y := x.Div(x, parentGasTargetBig)
+	baseFeeDelta := x.Div(y, baseFeeChangeDenominator)
+
+	baseFee := x.Sub(parent.BaseFee, baseFeeDelta)
+	if baseFee.Sign() < 0 {
+		baseFee.SetUint64(0)
+	}
+	return baseFee
+}
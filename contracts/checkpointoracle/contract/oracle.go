@@ -0,0 +1,411 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package contract
+
+import (
+	"math/big"
+	"strings"
+
+	aaeereum "github.com/aaechain/go-aaechain"
+	"github.com/aaechain/go-aaechain/accounts/abi"
+	"github.com/aaechain/go-aaechain/accounts/abi/bind"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/core/types"
+	"github.com/aaechain/go-aaechain/event"
+)
+
+// CheckpointOracleABI is the input ABI used to generate the binding from.
+const CheckpointOracleABI = "[{\"constant\":true,\"inputs\":[],\"name\":\"GetLatestCheckpoint\",\"outputs\":[{\"name\":\"\",\"type\":\"uint64\"},{\"name\":\"\",\"type\":\"bytes32\"},{\"name\":\"\",\"type\":\"bytes32\"},{\"name\":\"\",\"type\":\"bytes32\"},{\"name\":\"\",\"type\":\"uint256\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[],\"name\":\"GetSignatures\",\"outputs\":[{\"name\":\"\",\"type\":\"uint8[]\"},{\"name\":\"\",\"type\":\"bytes32[]\"},{\"name\":\"\",\"type\":\"bytes32[]\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[],\"name\":\"GetAllAdmin\",\"outputs\":[{\"name\":\"\",\"type\":\"address[]\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"name\":\"_sectionIndex\",\"type\":\"uint64\"},{\"name\":\"_sectionHead\",\"type\":\"bytes32\"},{\"name\":\"_chtRoot\",\"type\":\"bytes32\"},{\"name\":\"_bloomTrieRoot\",\"type\":\"bytes32\"},{\"name\":\"_v\",\"type\":\"uint8[]\"},{\"name\":\"_r\",\"type\":\"bytes32[]\"},{\"name\":\"_s\",\"type\":\"bytes32[]\"}],\"name\":\"SetCheckpoint\",\"outputs\":[{\"name\":\"\",\"type\":\"bool\"}],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"name\":\"_adminlist\",\"type\":\"address[]\"},{\"name\":\"_sectionSize\",\"type\":\"uint256\"},{\"name\":\"_processConfirms\",\"type\":\"uint256\"},{\"name\":\"_threshold\",\"type\":\"uint256\"}],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"name\":\"sectionIndex\",\"type\":\"uint64\"},{\"indexed\":false,\"name\":\"sectionHead\",\"type\":\"bytes32\"},{\"indexed\":false,\"name\":\"chtRoot\",\"type\":\"bytes32\"},{\"indexed\":false,\"name\":\"bloomTrieRoot\",\"type\":\"bytes32\"}],\"name\":\"NewCheckpoint\",\"type\":\"event\"}]"
+
+// CheckpointOracle is an auto generated Go binding around an Aaechain contract.
+type CheckpointOracle struct {
+	CheckpointOracleCaller     // Read-only binding to the contract
+	CheckpointOracleTransactor // Write-only binding to the contract
+	CheckpointOracleFilterer   // Log filterer for contract events
+}
+
+// CheckpointOracleCaller is an auto generated read-only Go binding around an Aaechain contract.
+type CheckpointOracleCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// CheckpointOracleTransactor is an auto generated write-only Go binding around an Aaechain contract.
+type CheckpointOracleTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// CheckpointOracleFilterer is an auto generated log filtering Go binding around an Aaechain contract events.
+type CheckpointOracleFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// CheckpointOracleSession is an auto generated Go binding around an Aaechain contract,
+// with pre-set call and transact options.
+type CheckpointOracleSession struct {
+	Contract     *CheckpointOracle // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// CheckpointOracleCallerSession is an auto generated read-only Go binding around an Aaechain contract,
+// with pre-set call options.
+type CheckpointOracleCallerSession struct {
+	Contract *CheckpointOracleCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts           // Call options to use throughout this session
+}
+
+// CheckpointOracleTransactorSession is an auto generated write-only Go binding around an Aaechain contract,
+// with pre-set transact options.
+type CheckpointOracleTransactorSession struct {
+	Contract     *CheckpointOracleTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts           // Transaction auth options to use throughout this session
+}
+
+// CheckpointOracleRaw is an auto generated low-level Go binding around an Aaechain contract.
+type CheckpointOracleRaw struct {
+	Contract *CheckpointOracle // Generic contract binding to access the raw methods on
+}
+
+// CheckpointOracleCallerRaw is an auto generated low-level read-only Go binding around an Aaechain contract.
+type CheckpointOracleCallerRaw struct {
+	Contract *CheckpointOracleCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// CheckpointOracleTransactorRaw is an auto generated low-level write-only Go binding around an Aaechain contract.
+type CheckpointOracleTransactorRaw struct {
+	Contract *CheckpointOracleTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewCheckpointOracle creates a new instance of CheckpointOracle, bound to a specific deployed contract.
+func NewCheckpointOracle(address common.Address, backend bind.ContractBackend) (*CheckpointOracle, error) {
+	contract, err := bindCheckpointOracle(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracle{CheckpointOracleCaller: CheckpointOracleCaller{contract: contract}, CheckpointOracleTransactor: CheckpointOracleTransactor{contract: contract}, CheckpointOracleFilterer: CheckpointOracleFilterer{contract: contract}}, nil
+}
+
+// NewCheckpointOracleCaller creates a new read-only instance of CheckpointOracle, bound to a specific deployed contract.
+func NewCheckpointOracleCaller(address common.Address, caller bind.ContractCaller) (*CheckpointOracleCaller, error) {
+	contract, err := bindCheckpointOracle(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracleCaller{contract: contract}, nil
+}
+
+// NewCheckpointOracleTransactor creates a new write-only instance of CheckpointOracle, bound to a specific deployed contract.
+func NewCheckpointOracleTransactor(address common.Address, transactor bind.ContractTransactor) (*CheckpointOracleTransactor, error) {
+	contract, err := bindCheckpointOracle(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracleTransactor{contract: contract}, nil
+}
+
+// NewCheckpointOracleFilterer creates a new log filterer instance of CheckpointOracle, bound to a specific deployed contract.
+func NewCheckpointOracleFilterer(address common.Address, filterer bind.ContractFilterer) (*CheckpointOracleFilterer, error) {
+	contract, err := bindCheckpointOracle(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracleFilterer{contract: contract}, nil
+}
+
+// bindCheckpointOracle binds a generic wrapper to an already deployed contract.
+func bindCheckpointOracle(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(CheckpointOracleABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_CheckpointOracle *CheckpointOracleRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _CheckpointOracle.Contract.CheckpointOracleCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_CheckpointOracle *CheckpointOracleRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.CheckpointOracleTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_CheckpointOracle *CheckpointOracleRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.CheckpointOracleTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_CheckpointOracle *CheckpointOracleCallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _CheckpointOracle.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_CheckpointOracle *CheckpointOracleTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_CheckpointOracle *CheckpointOracleTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.contract.Transact(opts, method, params...)
+}
+
+// GetAllAdmin is a free data retrieval call binding the contract method 0x45848dfc.
+//
+// Solidity: function GetAllAdmin() constant returns(address[])
+func (_CheckpointOracle *CheckpointOracleCaller) GetAllAdmin(opts *bind.CallOpts) ([]common.Address, error) {
+	var (
+		ret0 = new([]common.Address)
+	)
+	out := ret0
+	err := _CheckpointOracle.contract.Call(opts, out, "GetAllAdmin")
+	return *ret0, err
+}
+
+// GetAllAdmin is a free data retrieval call binding the contract method 0x45848dfc.
+//
+// Solidity: function GetAllAdmin() constant returns(address[])
+func (_CheckpointOracle *CheckpointOracleSession) GetAllAdmin() ([]common.Address, error) {
+	return _CheckpointOracle.Contract.GetAllAdmin(&_CheckpointOracle.CallOpts)
+}
+
+// GetAllAdmin is a free data retrieval call binding the contract method 0x45848dfc.
+//
+// Solidity: function GetAllAdmin() constant returns(address[])
+func (_CheckpointOracle *CheckpointOracleCallerSession) GetAllAdmin() ([]common.Address, error) {
+	return _CheckpointOracle.Contract.GetAllAdmin(&_CheckpointOracle.CallOpts)
+}
+
+// GetLatestCheckpoint is a free data retrieval call binding the contract method 0x4d6a304c.
+//
+// Solidity: function GetLatestCheckpoint() constant returns(uint64, bytes32, bytes32, bytes32, uint256)
+func (_CheckpointOracle *CheckpointOracleCaller) GetLatestCheckpoint(opts *bind.CallOpts) (uint64, [32]byte, [32]byte, [32]byte, *big.Int, error) {
+	var (
+		ret0 = new(uint64)
+		ret1 = new([32]byte)
+		ret2 = new([32]byte)
+		ret3 = new([32]byte)
+		ret4 = new(*big.Int)
+	)
+	out := &[]interface{}{
+		ret0,
+		ret1,
+		ret2,
+		ret3,
+		ret4,
+	}
+	err := _CheckpointOracle.contract.Call(opts, out, "GetLatestCheckpoint")
+	return *ret0, *ret1, *ret2, *ret3, *ret4, err
+}
+
+// GetLatestCheckpoint is a free data retrieval call binding the contract method 0x4d6a304c.
+//
+// Solidity: function GetLatestCheckpoint() constant returns(uint64, bytes32, bytes32, bytes32, uint256)
+func (_CheckpointOracle *CheckpointOracleSession) GetLatestCheckpoint() (uint64, [32]byte, [32]byte, [32]byte, *big.Int, error) {
+	return _CheckpointOracle.Contract.GetLatestCheckpoint(&_CheckpointOracle.CallOpts)
+}
+
+// GetLatestCheckpoint is a free data retrieval call binding the contract method 0x4d6a304c.
+//
+// Solidity: function GetLatestCheckpoint() constant returns(uint64, bytes32, bytes32, bytes32, uint256)
+func (_CheckpointOracle *CheckpointOracleCallerSession) GetLatestCheckpoint() (uint64, [32]byte, [32]byte, [32]byte, *big.Int, error) {
+	return _CheckpointOracle.Contract.GetLatestCheckpoint(&_CheckpointOracle.CallOpts)
+}
+
+// GetSignatures is a free data retrieval call binding the contract method 0xe2aec30a.
+//
+// Solidity: function GetSignatures() constant returns(uint8[], bytes32[], bytes32[])
+func (_CheckpointOracle *CheckpointOracleCaller) GetSignatures(opts *bind.CallOpts) ([]uint8, [][32]byte, [][32]byte, error) {
+	var (
+		ret0 = new([]uint8)
+		ret1 = new([][32]byte)
+		ret2 = new([][32]byte)
+	)
+	out := &[]interface{}{
+		ret0,
+		ret1,
+		ret2,
+	}
+	err := _CheckpointOracle.contract.Call(opts, out, "GetSignatures")
+	return *ret0, *ret1, *ret2, err
+}
+
+// GetSignatures is a free data retrieval call binding the contract method 0xe2aec30a.
+//
+// Solidity: function GetSignatures() constant returns(uint8[], bytes32[], bytes32[])
+func (_CheckpointOracle *CheckpointOracleSession) GetSignatures() ([]uint8, [][32]byte, [][32]byte, error) {
+	return _CheckpointOracle.Contract.GetSignatures(&_CheckpointOracle.CallOpts)
+}
+
+// GetSignatures is a free data retrieval call binding the contract method 0xe2aec30a.
+//
+// Solidity: function GetSignatures() constant returns(uint8[], bytes32[], bytes32[])
+func (_CheckpointOracle *CheckpointOracleCallerSession) GetSignatures() ([]uint8, [][32]byte, [][32]byte, error) {
+	return _CheckpointOracle.Contract.GetSignatures(&_CheckpointOracle.CallOpts)
+}
+
+// SetCheckpoint is a paid mutator transaction binding the contract method 0x6889d7b2.
+//
+// Solidity: function SetCheckpoint(_sectionIndex uint64, _sectionHead bytes32, _chtRoot bytes32, _bloomTrieRoot bytes32, _v uint8[], _r bytes32[], _s bytes32[]) returns(bool)
+func (_CheckpointOracle *CheckpointOracleTransactor) SetCheckpoint(opts *bind.TransactOpts, _sectionIndex uint64, _sectionHead [32]byte, _chtRoot [32]byte, _bloomTrieRoot [32]byte, _v []uint8, _r [][32]byte, _s [][32]byte) (*types.Transaction, error) {
+	return _CheckpointOracle.contract.Transact(opts, "SetCheckpoint", _sectionIndex, _sectionHead, _chtRoot, _bloomTrieRoot, _v, _r, _s)
+}
+
+// SetCheckpoint is a paid mutator transaction binding the contract method 0x6889d7b2.
+//
+// Solidity: function SetCheckpoint(_sectionIndex uint64, _sectionHead bytes32, _chtRoot bytes32, _bloomTrieRoot bytes32, _v uint8[], _r bytes32[], _s bytes32[]) returns(bool)
+func (_CheckpointOracle *CheckpointOracleSession) SetCheckpoint(_sectionIndex uint64, _sectionHead [32]byte, _chtRoot [32]byte, _bloomTrieRoot [32]byte, _v []uint8, _r [][32]byte, _s [][32]byte) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.SetCheckpoint(&_CheckpointOracle.TransactOpts, _sectionIndex, _sectionHead, _chtRoot, _bloomTrieRoot, _v, _r, _s)
+}
+
+// SetCheckpoint is a paid mutator transaction binding the contract method 0x6889d7b2.
+//
+// Solidity: function SetCheckpoint(_sectionIndex uint64, _sectionHead bytes32, _chtRoot bytes32, _bloomTrieRoot bytes32, _v uint8[], _r bytes32[], _s bytes32[]) returns(bool)
+func (_CheckpointOracle *CheckpointOracleTransactorSession) SetCheckpoint(_sectionIndex uint64, _sectionHead [32]byte, _chtRoot [32]byte, _bloomTrieRoot [32]byte, _v []uint8, _r [][32]byte, _s [][32]byte) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.SetCheckpoint(&_CheckpointOracle.TransactOpts, _sectionIndex, _sectionHead, _chtRoot, _bloomTrieRoot, _v, _r, _s)
+}
+
+// CheckpointOracleNewCheckpointIterator is returned from FilterNewCheckpoint and is used to iterate over the raw logs and unpacked data for NewCheckpoint events raised by the CheckpointOracle contract.
+type CheckpointOracleNewCheckpointIterator struct {
+	Event *CheckpointOracleNewCheckpoint // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  aaeereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whaaeer the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whaaeer there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *CheckpointOracleNewCheckpointIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(CheckpointOracleNewCheckpoint)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(CheckpointOracleNewCheckpoint)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *CheckpointOracleNewCheckpointIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *CheckpointOracleNewCheckpointIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// CheckpointOracleNewCheckpoint represents a NewCheckpoint event raised by the CheckpointOracle contract.
+type CheckpointOracleNewCheckpoint struct {
+	SectionIndex  uint64
+	SectionHead   [32]byte
+	ChtRoot       [32]byte
+	BloomTrieRoot [32]byte
+	Raw           types.Log // Blockchain specific contextual infos
+}
+
+// FilterNewCheckpoint is a free log retrieval operation binding the contract event 0x6ed8c268ab289f8222fafd7fe0ed48970ea6bb341d6e045f505807ef11175f08.
+//
+// Solidity: event NewCheckpoint(sectionIndex indexed uint64, sectionHead bytes32, chtRoot bytes32, bloomTrieRoot bytes32)
+func (_CheckpointOracle *CheckpointOracleFilterer) FilterNewCheckpoint(opts *bind.FilterOpts, sectionIndex []uint64) (*CheckpointOracleNewCheckpointIterator, error) {
+
+	var sectionIndexRule []interface{}
+	for _, sectionIndexItem := range sectionIndex {
+		sectionIndexRule = append(sectionIndexRule, sectionIndexItem)
+	}
+
+	logs, sub, err := _CheckpointOracle.contract.FilterLogs(opts, "NewCheckpoint", sectionIndexRule)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracleNewCheckpointIterator{contract: _CheckpointOracle.contract, event: "NewCheckpoint", logs: logs, sub: sub}, nil
+}
+
+// WatchNewCheckpoint is a free log subscription operation binding the contract event 0x6ed8c268ab289f8222fafd7fe0ed48970ea6bb341d6e045f505807ef11175f08.
+//
+// Solidity: event NewCheckpoint(sectionIndex indexed uint64, sectionHead bytes32, chtRoot bytes32, bloomTrieRoot bytes32)
+func (_CheckpointOracle *CheckpointOracleFilterer) WatchNewCheckpoint(opts *bind.WatchOpts, sink chan<- *CheckpointOracleNewCheckpoint, sectionIndex []uint64) (event.Subscription, error) {
+
+	var sectionIndexRule []interface{}
+	for _, sectionIndexItem := range sectionIndex {
+		sectionIndexRule = append(sectionIndexRule, sectionIndexItem)
+	}
+
+	logs, sub, err := _CheckpointOracle.contract.WatchLogs(opts, "NewCheckpoint", sectionIndexRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(CheckpointOracleNewCheckpoint)
+				if err := _CheckpointOracle.contract.UnpackLog(event, "NewCheckpoint", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
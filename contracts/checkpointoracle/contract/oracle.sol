@@ -0,0 +1,101 @@
+pragma solidity ^0.4.21;
+
+/**
+ * A checkpoint oracle registering the CHT and BloomTrie roots of finished
+ * sections for light clients. A checkpoint is accepted if it is signed by at
+ * least the threshold number of signers. The signatures are stored alongside,
+ * so that light clients can verify the checkpoint themselves.
+ */
+contract CheckpointOracle {
+    event NewCheckpoint(uint64 indexed sectionIndex, bytes32 sectionHead, bytes32 chtRoot, bytes32 bloomTrieRoot);
+
+    mapping(address => bool) admins;
+    address[] adminList;
+
+    uint sectionSize;     // Number of blocks in a section
+    uint processConfirms; // Number of confirmations before a section is processed
+    uint threshold;       // Number of signatures required to accept a checkpoint
+
+    // The latest registered checkpoint and the block number it was registered at
+    uint64 sectionIndex;
+    bytes32 sectionHead;
+    bytes32 chtRoot;
+    bytes32 bloomTrieRoot;
+    uint height;
+
+    // Signatures of the latest registered checkpoint, sorted by signer address
+    uint8[] v;
+    bytes32[] r;
+    bytes32[] s;
+
+    /**
+     * Constructor.
+     * @param _adminlist The addresses of the checkpoint signers.
+     * @param _sectionSize The number of blocks in a section.
+     * @param _processConfirms The number of confirmations before a section is processed.
+     * @param _threshold The number of signatures required to accept a checkpoint.
+     */
+    function CheckpointOracle(address[] _adminlist, uint _sectionSize, uint _processConfirms, uint _threshold) public {
+        for (uint i = 0; i < _adminlist.length; i++) {
+            admins[_adminlist[i]] = true;
+            adminList.push(_adminlist[i]);
+        }
+        sectionSize = _sectionSize;
+        processConfirms = _processConfirms;
+        threshold = _threshold;
+    }
+
+    /**
+     * Returns the latest registered checkpoint and the block number it was
+     * registered at, which is zero if there is no checkpoint yet.
+     */
+    function GetLatestCheckpoint() view public returns (uint64, bytes32, bytes32, bytes32, uint) {
+        return (sectionIndex, sectionHead, chtRoot, bloomTrieRoot, height);
+    }
+
+    /**
+     * Returns the signatures of the latest registered checkpoint.
+     */
+    function GetSignatures() view public returns (uint8[], bytes32[], bytes32[]) {
+        return (v, r, s);
+    }
+
+    /**
+     * Returns the addresses of the checkpoint signers.
+     */
+    function GetAllAdmin() view public returns (address[]) {
+        return adminList;
+    }
+
+    /**
+     * Registers a new checkpoint. The section must be processed and newer than
+     * the registered one. The signatures are over
+     * keccak256(0x19, 0x00, oracle, sectionIndex, sectionHead, chtRoot, bloomTrieRoot)
+     * and must be sorted by signer address.
+     */
+    function SetCheckpoint(uint64 _sectionIndex, bytes32 _sectionHead, bytes32 _chtRoot, bytes32 _bloomTrieRoot, uint8[] _v, bytes32[] _r, bytes32[] _s) public returns (bool) {
+        require(admins[msg.sender]);
+        require(block.number >= (uint(_sectionIndex) + 1) * sectionSize + processConfirms);
+        require(height == 0 || _sectionIndex > sectionIndex);
+        require(_v.length == _r.length && _v.length == _s.length && _v.length >= threshold);
+
+        bytes32 hash = keccak256(byte(0x19), byte(0), this, _sectionIndex, _sectionHead, _chtRoot, _bloomTrieRoot);
+        address last = 0;
+        for (uint i = 0; i < _v.length; i++) {
+            address signer = ecrecover(hash, _v[i], _r[i], _s[i]);
+            require(admins[signer] && uint(signer) > uint(last));
+            last = signer;
+        }
+        sectionIndex = _sectionIndex;
+        sectionHead = _sectionHead;
+        chtRoot = _chtRoot;
+        bloomTrieRoot = _bloomTrieRoot;
+        height = block.number;
+        v = _v;
+        r = _r;
+        s = _s;
+
+        emit NewCheckpoint(_sectionIndex, _sectionHead, _chtRoot, _bloomTrieRoot);
+        return true;
+    }
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package checkpointoracle is a wrapper of the checkpoint oracle contract, which
+// registers the CHT and BloomTrie roots of finished sections for light clients.
+package checkpointoracle
+
+//go:generate abigen --sol contract/oracle.sol --pkg contract --out contract/oracle.go
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/aaechain/go-aaechain/accounts/abi/bind"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/contracts/checkpointoracle/contract"
+	"github.com/aaechain/go-aaechain/core/types"
+	"github.com/aaechain/go-aaechain/crypto"
+)
+
+var (
+	ErrNoCheckpoint          = errors.New("no checkpoint registered")
+	ErrNotEnoughSignatures   = errors.New("not enough valid signatures")
+	ErrInvalidSignatureCount = errors.New("invalid number of signature elements")
+)
+
+// Checkpoint is a set of post-processed trie roots (CHT and BloomTrie) of a
+// finished section, identified by its index and the hash of its last header.
+type Checkpoint struct {
+	SectionIndex  uint64      `json:"sectionIndex"`
+	SectionHead   common.Hash `json:"sectionHead"`
+	ChtRoot       common.Hash `json:"chtRoot"`
+	BloomTrieRoot common.Hash `json:"bloomTrieRoot"`
+}
+
+// SignHash returns the hash signed by the signers of the checkpoint when it is
+// registered in the oracle at the given address. It follows the version 0x00
+// format of EIP-191, the oracle being the intended validator.
+func (c *Checkpoint) SignHash(oracle common.Address) common.Hash {
+	var index [8]byte
+	binary.BigEndian.PutUint64(index[:], c.SectionIndex)
+	return crypto.Keccak256Hash([]byte{0x19, 0x00}, oracle.Bytes(), index[:], c.SectionHead.Bytes(), c.ChtRoot.Bytes(), c.BloomTrieRoot.Bytes())
+}
+
+// Sign signs the checkpoint to be registered in the oracle at the given address.
+func (c *Checkpoint) Sign(oracle common.Address, signer *ecdsa.PrivateKey) ([]byte, error) {
+	return crypto.Sign(c.SignHash(oracle).Bytes(), signer)
+}
+
+// Signers recovers the distinct signers of the checkpoint from the signatures.
+func (c *Checkpoint) Signers(oracle common.Address, sigs [][]byte) ([]common.Address, error) {
+	hash := c.SignHash(oracle)
+	seen := make(map[common.Address]bool)
+
+	var signers []common.Address
+	for _, sig := range sigs {
+		pubkey, err := crypto.SigToPub(hash.Bytes(), sig)
+		if err != nil {
+			return nil, err
+		}
+		if addr := crypto.PubkeyToAddress(*pubkey); !seen[addr] {
+			seen[addr] = true
+			signers = append(signers, addr)
+		}
+	}
+	return signers, nil
+}
+
+// CheckpointOracle is a Go wrapper around an on-chain checkpoint oracle contract.
+type CheckpointOracle struct {
+	address  common.Address
+	contract *contract.CheckpointOracle
+}
+
+// NewCheckpointOracle binds the checkpoint oracle contract at the given address.
+func NewCheckpointOracle(address common.Address, backend bind.ContractBackend) (*CheckpointOracle, error) {
+	c, err := contract.NewCheckpointOracle(address, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracle{address: address, contract: c}, nil
+}
+
+// NewCheckpointOracleCaller binds the checkpoint oracle contract at the given
+// address for reading only.
+func NewCheckpointOracleCaller(address common.Address, caller bind.ContractCaller) (*CheckpointOracle, error) {
+	c, err := contract.NewCheckpointOracleCaller(address, caller)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracle{address: address, contract: &contract.CheckpointOracle{CheckpointOracleCaller: *c}}, nil
+}
+
+// Contract returns the underlying contract binding.
+func (o *CheckpointOracle) Contract() *contract.CheckpointOracle {
+	return o.contract
+}
+
+// LatestCheckpoint returns the latest registered checkpoint along with the
+// block number it was registered at. The checkpoint is only returned if at
+// least threshold of the given signers signed it, so it doesn't need to be
+// read from a trusted state.
+func (o *CheckpointOracle) LatestCheckpoint(opts *bind.CallOpts, signers []common.Address, threshold int) (*Checkpoint, uint64, error) {
+	index, head, chtRoot, bloomTrieRoot, height, err := o.contract.GetLatestCheckpoint(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if height.Sign() == 0 {
+		return nil, 0, ErrNoCheckpoint
+	}
+	v, r, s, err := o.contract.GetSignatures(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(v) != len(r) || len(v) != len(s) {
+		return nil, 0, ErrInvalidSignatureCount
+	}
+	sigs := make([][]byte, len(v))
+	for i := range v {
+		sigs[i] = make([]byte, 65)
+		copy(sigs[i], r[i][:])
+		copy(sigs[i][32:], s[i][:])
+		sigs[i][64] = v[i] - 27
+	}
+	cp := &Checkpoint{SectionIndex: index, SectionHead: head, ChtRoot: chtRoot, BloomTrieRoot: bloomTrieRoot}
+	recovered, err := cp.Signers(o.address, sigs)
+	if err != nil {
+		return nil, 0, err
+	}
+	trusted := make(map[common.Address]bool)
+	for _, signer := range signers {
+		trusted[signer] = true
+	}
+	count := 0
+	for _, signer := range recovered {
+		if trusted[signer] {
+			count++
+		}
+	}
+	if count < threshold {
+		return nil, 0, ErrNotEnoughSignatures
+	}
+	return cp, height.Uint64(), nil
+}
+
+// SetCheckpoint registers a new checkpoint with the given signatures, which are
+// sorted by signer address as required by the contract.
+func (o *CheckpointOracle) SetCheckpoint(opts *bind.TransactOpts, cp *Checkpoint, sigs [][]byte) (*types.Transaction, error) {
+	hash := cp.SignHash(o.address)
+
+	type signature struct {
+		signer common.Address
+		sig    []byte
+	}
+	sorted := make([]signature, len(sigs))
+	for i, sig := range sigs {
+		pubkey, err := crypto.SigToPub(hash.Bytes(), sig)
+		if err != nil {
+			return nil, err
+		}
+		sorted[i] = signature{crypto.PubkeyToAddress(*pubkey), sig}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].signer.Bytes(), sorted[j].signer.Bytes()) < 0
+	})
+	var (
+		v    = make([]uint8, len(sorted))
+		r, s = make([][32]byte, len(sorted)), make([][32]byte, len(sorted))
+	)
+	for i, sig := range sorted {
+		copy(r[i][:], sig.sig[:32])
+		copy(s[i][:], sig.sig[32:64])
+		v[i] = sig.sig[64] + 27
+	}
+	return o.contract.SetCheckpoint(opts, cp.SectionIndex, cp.SectionHead, cp.ChtRoot, cp.BloomTrieRoot, v, r, s)
+}
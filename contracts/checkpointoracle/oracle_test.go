@@ -0,0 +1,208 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package checkpointoracle
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	aaeereum "github.com/aaechain/go-aaechain"
+	"github.com/aaechain/go-aaechain/accounts/abi"
+	"github.com/aaechain/go-aaechain/accounts/abi/bind"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/contracts/checkpointoracle/contract"
+	"github.com/aaechain/go-aaechain/core/types"
+	"github.com/aaechain/go-aaechain/crypto"
+)
+
+// testOracle emulates the storage of the oracle contract. It serves calls from
+// the fields and records the arguments of submitted checkpoints.
+type testOracle struct {
+	abi     abi.ABI
+	cp      Checkpoint
+	height  int64
+	v       []uint8
+	r, s    [][32]byte
+	signers []common.Address
+}
+
+func newTestOracle(t *testing.T) *testOracle {
+	parsed, err := abi.JSON(strings.NewReader(contract.CheckpointOracleABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testOracle{abi: parsed}
+}
+
+func (o *testOracle) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (o *testOracle) CallContract(ctx context.Context, call aaeereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	for name, method := range o.abi.Methods {
+		if !bytes.Equal(call.Data[:4], method.Id()) {
+			continue
+		}
+		switch name {
+		case "GetLatestCheckpoint":
+			return method.Outputs.Pack(o.cp.SectionIndex, [32]byte(o.cp.SectionHead), [32]byte(o.cp.ChtRoot), [32]byte(o.cp.BloomTrieRoot), big.NewInt(o.height))
+		case "GetSignatures":
+			return method.Outputs.Pack(o.v, o.r, o.s)
+		case "GetAllAdmin":
+			return method.Outputs.Pack(o.signers)
+		}
+	}
+	return nil, errors.New("unknown method")
+}
+
+func (o *testOracle) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (o *testOracle) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (o *testOracle) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (o *testOracle) EstimateGas(ctx context.Context, call aaeereum.CallMsg) (uint64, error) {
+	return 1000000, nil
+}
+
+func (o *testOracle) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	args, err := o.abi.Methods["SetCheckpoint"].Inputs.UnpackValues(tx.Data()[4:])
+	if err != nil {
+		return err
+	}
+	o.cp = Checkpoint{
+		SectionIndex:  args[0].(uint64),
+		SectionHead:   args[1].([32]byte),
+		ChtRoot:       args[2].([32]byte),
+		BloomTrieRoot: args[3].([32]byte),
+	}
+	o.v, o.r, o.s = args[4].([]uint8), args[5].([][32]byte), args[6].([][32]byte)
+	o.height++
+	return nil
+}
+
+func (o *testOracle) FilterLogs(ctx context.Context, query aaeereum.FilterQuery) ([]types.Log, error) {
+	return nil, nil
+}
+
+func (o *testOracle) SubscribeFilterLogs(ctx context.Context, query aaeereum.FilterQuery, ch chan<- types.Log) (aaeereum.Subscription, error) {
+	return nil, errors.New("not supported")
+}
+
+func newTestSigners(n int) ([]*ecdsa.PrivateKey, []common.Address) {
+	keys := make([]*ecdsa.PrivateKey, n)
+	addrs := make([]common.Address, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	return keys, addrs
+}
+
+// Tests that signed checkpoints can be submitted and read back, and that the
+// submitted signatures are sorted by signer.
+func TestCheckpointRoundtrip(t *testing.T) {
+	backend := newTestOracle(t)
+	address := common.HexToAddress("0x0100")
+	oracle, err := NewCheckpointOracle(address, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, signers := newTestSigners(3)
+
+	if _, _, err := oracle.LatestCheckpoint(nil, signers, 2); err != ErrNoCheckpoint {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNoCheckpoint)
+	}
+	cp := &Checkpoint{
+		SectionIndex:  3,
+		SectionHead:   common.HexToHash("0x01"),
+		ChtRoot:       common.HexToHash("0x02"),
+		BloomTrieRoot: common.HexToHash("0x03"),
+	}
+	var sigs [][]byte
+	for _, key := range keys {
+		sig, err := cp.Sign(address, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sig)
+	}
+	if _, err := oracle.SetCheckpoint(bind.NewKeyedTransactor(keys[0]), cp, sigs); err != nil {
+		t.Fatalf("failed to submit checkpoint: %v", err)
+	}
+	hash := cp.SignHash(address)
+	var last common.Address
+	for i := range backend.v {
+		sig := append(append(backend.r[i][:], backend.s[i][:]...), backend.v[i]-27)
+		pubkey, err := crypto.SigToPub(hash.Bytes(), sig)
+		if err != nil {
+			t.Fatalf("invalid submitted signature %d: %v", i, err)
+		}
+		signer := crypto.PubkeyToAddress(*pubkey)
+		if bytes.Compare(signer.Bytes(), last.Bytes()) <= 0 {
+			t.Fatalf("signatures not sorted by signer")
+		}
+		last = signer
+	}
+	have, height, err := oracle.LatestCheckpoint(nil, signers, 3)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	if *have != *cp || height != 1 {
+		t.Fatalf("checkpoint mismatch: have %+v at %d, want %+v at 1", have, height, cp)
+	}
+}
+
+// Tests that checkpoints without enough signatures of the trusted signers are
+// rejected.
+func TestCheckpointUntrusted(t *testing.T) {
+	backend := newTestOracle(t)
+	address := common.HexToAddress("0x0100")
+	oracle, _ := NewCheckpointOracle(address, backend)
+	keys, signers := newTestSigners(3)
+
+	cp := &Checkpoint{SectionIndex: 1, ChtRoot: common.HexToHash("0x02")}
+	sig1, _ := cp.Sign(address, keys[0])
+	sig2, _ := cp.Sign(address, keys[1])
+	if _, err := oracle.SetCheckpoint(bind.NewKeyedTransactor(keys[0]), cp, [][]byte{sig1, sig2, sig2}); err != nil {
+		t.Fatalf("failed to submit checkpoint: %v", err)
+	}
+	// Duplicate signatures don't count twice
+	if _, _, err := oracle.LatestCheckpoint(nil, signers, 3); err != ErrNotEnoughSignatures {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNotEnoughSignatures)
+	}
+	// Signatures of unknown signers don't count
+	if _, _, err := oracle.LatestCheckpoint(nil, signers[1:], 2); err != ErrNotEnoughSignatures {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNotEnoughSignatures)
+	}
+	// Checkpoints modified after signing are not signed by anyone
+	backend.cp.ChtRoot = common.HexToHash("0x04")
+	if _, _, err := oracle.LatestCheckpoint(nil, signers, 1); err != ErrNotEnoughSignatures {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNotEnoughSignatures)
+	}
+}
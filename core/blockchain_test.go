@@ -1338,3 +1338,134 @@ func TestLargeReorgTrieGC(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the gas of fee delegated transactions is paid by the fee payer,
+// while the sender provides the nonce and the value.
+func TestFeeDelegation(t *testing.T) {
+	var (
+		db, _       = aaedb.NewMemDatabase()
+		key, _      = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		payerKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		address     = crypto.PubkeyToAddress(key.PublicKey)
+		payer       = crypto.PubkeyToAddress(payerKey.PublicKey)
+		funds       = big.NewInt(1000000000)
+		theAddr     = common.Address{1}
+		gspec       = &Genesis{
+			Config: &params.ChainConfig{
+				ChainId:            big.NewInt(1),
+				HomesteadBlock:     new(big.Int),
+				EIP155Block:        new(big.Int),
+				EIP158Block:        new(big.Int),
+				TypedTxBlock:       new(big.Int),
+				FeeDelegationBlock: new(big.Int),
+			},
+			Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000)}, payer: {Balance: funds}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, block *BlockGen) {
+		signer := types.NewFeeDelegationSigner(gspec.Config.ChainId)
+		tx, err := types.SignTx(types.NewTx(&types.FeeDelegatedTx{
+			ChainID:  gspec.Config.ChainId,
+			Nonce:    block.TxNonce(address),
+			GasPrice: big.NewInt(1),
+			Gas:      21000,
+			To:       &theAddr,
+			Value:    big.NewInt(1000),
+		}), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx, err = types.SignFeePayer(tx, signer, payerKey); err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := blockchain.State()
+	if balance := st.GetBalance(address); balance.Sign() != 0 {
+		t.Errorf("sender balance mismatch: have %v, want 0", balance)
+	}
+	if nonce := st.GetNonce(address); nonce != 1 {
+		t.Errorf("sender nonce mismatch: have %d, want 1", nonce)
+	}
+	if nonce := st.GetNonce(payer); nonce != 0 {
+		t.Errorf("fee payer nonce mismatch: have %d, want 0", nonce)
+	}
+	if balance, want := st.GetBalance(payer), new(big.Int).Sub(funds, big.NewInt(21000)); balance.Cmp(want) != 0 {
+		t.Errorf("fee payer balance mismatch: have %v, want %v", balance, want)
+	}
+	if balance := st.GetBalance(theAddr); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want 1000", balance)
+	}
+}
+
+func TestBaseFee(t *testing.T) {
+	var (
+		db, _   = aaedb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = new(big.Int).Mul(new(big.Int).SetUint64(params.InitialBaseFee), big.NewInt(100000))
+		miner   = common.Address{0xaa}
+		theAddr = common.Address{1}
+		gspec   = &Genesis{
+			Config: &params.ChainConfig{
+				ChainId:        big.NewInt(1),
+				HomesteadBlock: new(big.Int),
+				EIP155Block:    new(big.Int),
+				EIP158Block:    new(big.Int),
+				TypedTxBlock:   new(big.Int),
+				BaseFeeBlock:   new(big.Int),
+			},
+			Alloc: GenesisAlloc{address: {Balance: funds}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	if genesis.BaseFee() == nil || genesis.BaseFee().Uint64() != params.InitialBaseFee {
+		t.Fatalf("genesis base fee mismatch: have %v, want %d", genesis.BaseFee(), params.InitialBaseFee)
+	}
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	tip := big.NewInt(2)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, block *BlockGen) {
+		block.SetCoinbase(miner)
+		tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   gspec.Config.ChainId,
+			Nonce:     block.TxNonce(address),
+			GasTipCap: tip,
+			GasFeeCap: new(big.Int).SetUint64(2 * params.InitialBaseFee),
+			Gas:       21000,
+			To:        &theAddr,
+			Value:     big.NewInt(1000),
+		}), types.NewDynamicFeeSigner(gspec.Config.ChainId), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	// The empty genesis block lowers the base fee of the first block
+	baseFee := blocks[0].BaseFee()
+	if baseFee == nil || baseFee.Cmp(new(big.Int).SetUint64(params.InitialBaseFee)) >= 0 {
+		t.Fatalf("block base fee mismatch: have %v, want below %d", baseFee, params.InitialBaseFee)
+	}
+	// The sender pays the base fee and the tip, the miner only receives the tip
+	st, _ := blockchain.State()
+	price := new(big.Int).Add(baseFee, tip)
+	want := new(big.Int).Sub(funds, new(big.Int).Add(big.NewInt(1000), new(big.Int).Mul(price, big.NewInt(21000))))
+	if balance := st.GetBalance(address); balance.Cmp(want) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", balance, want)
+	}
+	want = new(big.Int).Add(ethash.FrontierBlockReward, new(big.Int).Mul(tip, big.NewInt(21000)))
+	if balance := st.GetBalance(miner); balance.Cmp(want) != 0 {
+		t.Errorf("miner balance mismatch: have %v, want %v", balance, want)
+	}
+}
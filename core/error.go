@@ -32,4 +32,8 @@ var (
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrTipAboveFeeCap is returned if the priority fee of a transaction is
+	// higher than its fee cap.
+	ErrTipAboveFeeCap = errors.New("tip higher than fee cap")
 )
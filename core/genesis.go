@@ -254,6 +254,9 @@ func (g *Genesis) ToBlock(db aaedb.Database) *types.Block {
 	if g.Difficulty == nil {
 		head.Difficulty = params.GenesisDifficulty
 	}
+	if g.Config != nil && g.Config.IsBaseFee(head.Number) {
+		head.BaseFee = new(big.Int).SetUint64(params.InitialBaseFee)
+	}
 	statedb.Commit(false)
 	statedb.Database().TrieDB().Commit(root, true)
 
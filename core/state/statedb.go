@@ -74,6 +74,9 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+	// Per-transaction access list, see PrepareAccessList.
+	accessList *accessList
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        journal
@@ -96,6 +99,7 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
+		accessList:        newAccessList(),
 	}, nil
 }
 
@@ -126,6 +130,7 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
 	self.preimages = make(map[common.Hash][]byte)
+	self.accessList = newAccessList()
 	self.clearJournalAndRefund()
 	return nil
 }
@@ -485,6 +490,7 @@ func (self *StateDB) Copy() *StateDB {
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
+	state.accessList = self.accessList.Copy()
 	return state
 }
 
@@ -554,6 +560,62 @@ func (self *StateDB) Prepare(thash, bhash common.Hash, ti int) {
 	self.txIndex = ti
 }
 
+// PrepareAccessList resets the access list at the start of a transaction and
+// adds the sender, the destination, the precompiles and the entries of the
+// transaction's own access list to it.
+func (self *StateDB) PrepareAccessList(sender common.Address, dst *common.Address, precompiles []common.Address, list types.AccessList) {
+	self.accessList = newAccessList()
+
+	self.AddAddressToAccessList(sender)
+	if dst != nil {
+		self.AddAddressToAccessList(*dst)
+	}
+	for _, addr := range precompiles {
+		self.AddAddressToAccessList(addr)
+	}
+	for _, el := range list {
+		self.AddAddressToAccessList(el.Address)
+		for _, key := range el.StorageKeys {
+			self.AddSlotToAccessList(el.Address, key)
+		}
+	}
+}
+
+// AddAddressToAccessList adds the given address to the access list.
+func (self *StateDB) AddAddressToAccessList(addr common.Address) {
+	if self.accessList.AddAddress(addr) {
+		self.journal = append(self.journal, accessListAddAccountChange{&addr})
+	}
+}
+
+// AddSlotToAccessList adds the given (address, slot) to the access list.
+func (self *StateDB) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	addrMod, slotMod := self.accessList.AddSlot(addr, slot)
+	if addrMod {
+		// In practice, this should not happen, since there is no way to enter the
+		// scope of 'address' without having the 'address' become already added
+		// to the access list (via call-variant, create, etc).
+		// Better safe than sorry, though
+		self.journal = append(self.journal, accessListAddAccountChange{&addr})
+	}
+	if slotMod {
+		self.journal = append(self.journal, accessListAddSlotChange{
+			address: &addr,
+			slot:    &slot,
+		})
+	}
+}
+
+// AddressInAccessList returns whaaeer the address is in the access list.
+func (self *StateDB) AddressInAccessList(addr common.Address) bool {
+	return self.accessList.ContainsAddress(addr)
+}
+
+// SlotInAccessList reports whaaeer the address and the slot are in the access list.
+func (self *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addressOk bool, slotOk bool) {
+	return self.accessList.Contains(addr, slot)
+}
+
 // DeleteSuicides flags the suicided objects for deletion so that it
 // won't be referenced again when called / queried up on.
 //
@@ -421,3 +421,52 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+func TestStateDBAccessList(t *testing.T) {
+	db, _ := aaedb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	var (
+		addr1 = common.HexToAddress("0x01")
+		addr2 = common.HexToAddress("0x02")
+		slot1 = common.HexToHash("0x01")
+		slot2 = common.HexToHash("0x02")
+	)
+	verify := func(addr common.Address, slot common.Hash, wantAddr, wantSlot bool) {
+		t.Helper()
+		if have := state.AddressInAccessList(addr); have != wantAddr {
+			t.Errorf("address %x presence mismatch: have %v, want %v", addr, have, wantAddr)
+		}
+		haveAddr, haveSlot := state.SlotInAccessList(addr, slot)
+		if haveAddr != wantAddr || haveSlot != wantSlot {
+			t.Errorf("slot %x/%x presence mismatch: have %v/%v, want %v/%v", addr, slot, haveAddr, haveSlot, wantAddr, wantSlot)
+		}
+	}
+	state.PrepareAccessList(addr1, nil, nil, types.AccessList{{Address: addr2, StorageKeys: []common.Hash{slot1}}})
+	verify(addr1, slot1, true, false)
+	verify(addr2, slot1, true, true)
+
+	// Additions after a snapshot must be undone when reverting
+	snap := state.Snapshot()
+	state.AddSlotToAccessList(addr1, slot2)
+	state.AddSlotToAccessList(addr2, slot2)
+	state.AddAddressToAccessList(common.HexToAddress("0x03"))
+	verify(addr1, slot2, true, true)
+	verify(addr2, slot2, true, true)
+
+	copy := state.Copy()
+	state.RevertToSnapshot(snap)
+	verify(addr1, slot2, true, false)
+	verify(addr2, slot1, true, true)
+	verify(addr2, slot2, true, false)
+	verify(common.HexToAddress("0x03"), slot1, false, false)
+
+	// The copy must not be affected by the revert
+	if _, ok := copy.SlotInAccessList(addr2, slot2); !ok {
+		t.Error("copied access list modified by revert")
+	}
+	// A new transaction starts with a fresh access list
+	state.PrepareAccessList(addr2, nil, nil, nil)
+	verify(addr1, slot1, false, false)
+	verify(addr2, slot1, true, false)
+}
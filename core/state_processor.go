@@ -112,6 +112,7 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing waaeer the root touch-delete accounts.
 	receipt := types.NewReceipt(root, failed, *usedGas)
+	receipt.Type = tx.Type()
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = gas
 	// if the transaction created a contract, store the creation address in the receipt.
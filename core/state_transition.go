@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/core/types"
 	"github.com/aaechain/go-aaechain/core/vm"
 	"github.com/aaechain/go-aaechain/log"
 	"github.com/aaechain/go-aaechain/params"
@@ -67,16 +68,24 @@ type Message interface {
 	To() *common.Address
 
 	GasPrice() *big.Int
+	GasTipCap() *big.Int
+	GasFeeCap() *big.Int
 	Gas() uint64
 	Value() *big.Int
 
 	Nonce() uint64
 	CheckNonce() bool
 	Data() []byte
+	AccessList() types.AccessList
+
+	// FeePayer returns the account paying for the gas, the sender itself
+	// unless the message comes from a fee delegated transaction.
+	FeePayer() common.Address
 }
 
-// IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, contractCreation, homestead bool) (uint64, error) {
+// IntrinsicGas computes the 'intrinsic gas' for a message with the given data
+// and access list.
+func IntrinsicGas(data []byte, accessList types.AccessList, contractCreation, homestead bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if contractCreation && homestead {
@@ -105,16 +114,34 @@ func IntrinsicGas(data []byte, contractCreation, homestead bool) (uint64, error)
 		}
 		gas += z * params.TxDataZeroGas
 	}
+	if accessList != nil {
+		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
+		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+	}
 	return gas, nil
 }
 
+// EffectiveGasPrice returns the price per gas paid by a message included in a
+// block with the given base fee: the base fee plus the priority fee, capped to
+// the fee cap. A nil base fee returns the gas price of the message.
+func EffectiveGasPrice(msg Message, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return new(big.Int).Set(msg.GasPrice())
+	}
+	price := new(big.Int).Add(msg.GasTipCap(), baseFee)
+	if price.Cmp(msg.GasFeeCap()) > 0 {
+		price.Set(msg.GasFeeCap())
+	}
+	return price
+}
+
 // NewStateTransition initialises and returns a new state transition object.
 func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition {
 	return &StateTransition{
 		gp:       gp,
 		evm:      evm,
 		msg:      msg,
-		gasPrice: msg.GasPrice(),
+		gasPrice: EffectiveGasPrice(msg, evm.BaseFee),
 		value:    msg.Value(),
 		data:     msg.Data(),
 		state:    evm.StateDB,
@@ -140,6 +167,14 @@ func (st *StateTransition) from() vm.AccountRef {
 	return vm.AccountRef(f)
 }
 
+func (st *StateTransition) feePayer() vm.AccountRef {
+	f := st.msg.FeePayer()
+	if !st.state.Exist(f) {
+		st.state.CreateAccount(f)
+	}
+	return vm.AccountRef(f)
+}
+
 func (st *StateTransition) to() vm.AccountRef {
 	if st.msg == nil {
 		return vm.AccountRef{}
@@ -167,11 +202,14 @@ func (st *StateTransition) useGas(amount uint64) error {
 
 func (st *StateTransition) buyGas() error {
 	var (
-		state  = st.state
-		sender = st.from()
+		state = st.state
+		payer = st.feePayer()
 	)
+	// The payer must be able to cover the gas at the fee cap, even though only
+	// the effective gas price is charged
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
-	if state.GetBalance(sender.Address()).Cmp(mgval) < 0 {
+	balanceCheck := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.msg.GasFeeCap())
+	if state.GetBalance(payer.Address()).Cmp(balanceCheck) < 0 {
 		return errInsufficientBalanceForGas
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -180,7 +218,7 @@ func (st *StateTransition) buyGas() error {
 	st.gas += st.msg.Gas()
 
 	st.initialGas = st.msg.Gas()
-	state.SubBalance(sender.Address(), mgval)
+	state.SubBalance(payer.Address(), mgval)
 	return nil
 }
 
@@ -197,6 +235,15 @@ func (st *StateTransition) preCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	// Make sure the fee cap covers the base fee of the block
+	if baseFee := st.evm.BaseFee; baseFee != nil {
+		if msg.GasFeeCap().Cmp(msg.GasTipCap()) < 0 {
+			return ErrTipAboveFeeCap
+		}
+		if msg.GasFeeCap().Cmp(baseFee) < 0 {
+			return types.ErrGasFeeCapTooLow
+		}
+	}
 	return st.buyGas()
 }
 
@@ -214,7 +261,7 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 	contractCreation := msg.To() == nil
 
 	// Pay intrinsic gas
-	gas, err := IntrinsicGas(st.data, contractCreation, homestead)
+	gas, err := IntrinsicGas(st.data, msg.AccessList(), contractCreation, homestead)
 	if err != nil {
 		return nil, 0, false, err
 	}
@@ -229,6 +276,10 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		// error.
 		vmerr error
 	)
+	// Warm up the accounts and slots known to be accessed by the transaction
+	if rules := evm.ChainConfig().Rules(evm.BlockNumber); rules.IsAccessList {
+		st.state.PrepareAccessList(sender.Address(), msg.To(), vm.ActivePrecompiles(rules), msg.AccessList())
+	}
 	if contractCreation {
 		ret, _, st.gas, vmerr = evm.Create(sender, st.data, st.gas, st.value)
 	} else {
@@ -246,7 +297,13 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		}
 	}
 	st.refundGas()
-	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+
+	// The base fee is burned, only the priority fee is paid to the coinbase
+	tip := st.gasPrice
+	if st.evm.BaseFee != nil {
+		tip = new(big.Int).Sub(st.gasPrice, st.evm.BaseFee)
+	}
+	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), tip))
 
 	return ret, st.gasUsed(), vmerr != nil, err
 }
@@ -260,10 +317,10 @@ func (st *StateTransition) refundGas() {
 	st.gas += refund
 
 	// Return aae for remaining gas, exchanged at the original rate.
-	sender := st.from()
+	payer := st.feePayer()
 
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(sender.Address(), remaining)
+	st.state.AddBalance(payer.Address(), remaining)
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
@@ -248,21 +248,25 @@ func (l *txList) Overlaps(tx *types.Transaction) bool {
 //
 // If the new transaction is accepted into the list, the lists' cost and gas
 // thresholds are also potentially updated.
-func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
+//
+// Transactions are compared by the tip they pay to the miner at the given base
+// fee, which is nil before the base fee fork.
+func (l *txList) Add(tx *types.Transaction, priceBump uint64, baseFee *big.Int) (bool, *types.Transaction) {
 	// If there's an older better transaction, abort
 	old := l.txs.Get(tx.Nonce())
 	if old != nil {
-		threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
-		// Have to ensure that the new gas price is higher than the old gas
-		// price as well as checking the percentage threshold to ensure that
-		// this is accurate for low (Wei-level) gas price replacements
-		if old.GasPrice().Cmp(tx.GasPrice()) >= 0 || threshold.Cmp(tx.GasPrice()) > 0 {
+		oldTip, newTip := old.EffectiveGasTipValue(baseFee), tx.EffectiveGasTipValue(baseFee)
+		threshold := new(big.Int).Div(new(big.Int).Mul(oldTip, big.NewInt(100+int64(priceBump))), big.NewInt(100))
+		// Have to ensure that the new tip is higher than the old tip as well as
+		// checking the percentage threshold to ensure that this is accurate for
+		// low (Wei-level) tip replacements
+		if oldTip.Cmp(newTip) >= 0 || threshold.Cmp(newTip) > 0 {
 			return false, nil
 		}
 	}
 	// Otherwise overwrite the old transaction with the current one
 	l.txs.Put(tx)
-	if cost := tx.Cost(); l.costcap.Cmp(cost) < 0 {
+	if cost := senderCost(tx); l.costcap.Cmp(cost) < 0 {
 		l.costcap = cost
 	}
 	if gas := tx.Gas(); l.gascap < gas {
@@ -296,7 +300,7 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64) (types.Transactions
 	l.gascap = gasLimit
 
 	// Filter out all the transactions above the account's funds
-	removed := l.txs.Filter(func(tx *types.Transaction) bool { return tx.Cost().Cmp(costLimit) > 0 || tx.Gas() > gasLimit })
+	removed := l.txs.Filter(func(tx *types.Transaction) bool { return senderCost(tx).Cmp(costLimit) > 0 || tx.Gas() > gasLimit })
 
 	// If the list was strict, filter anything above the lowest nonce
 	var invalids types.Transactions
@@ -313,6 +317,25 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64) (types.Transactions
 	return removed, invalids
 }
 
+// Underpaid removes the lowest nonce transaction whose fee cap doesn't cover the
+// given base fee, along with all the transactions following it. The removed
+// transactions are returned for demotion.
+func (l *txList) Underpaid(baseFee *big.Int) types.Transactions {
+	var (
+		lowest uint64
+		found  bool
+	)
+	for nonce, tx := range l.txs.items {
+		if tx.GasFeeCap().Cmp(baseFee) < 0 && (!found || nonce < lowest) {
+			lowest, found = nonce, true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return l.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce() >= lowest })
+}
+
 // Cap places a hard limit on the number of items, returning all transactions
 // exceeding that limit.
 func (l *txList) Cap(threshold int) types.Transactions {
@@ -342,8 +365,23 @@ func (l *txList) Remove(tx *types.Transaction) (bool, types.Transactions) {
 // Note, all transactions with nonces lower than start will also be returned to
 // prevent getting into and invalid state. This is not somaaeing that should ever
 // happen but better to be self correcting than failing!
-func (l *txList) Ready(start uint64) types.Transactions {
-	return l.txs.Ready(start)
+//
+// If a base fee is given, the sequence stops before the first transaction whose
+// fee cap doesn't cover it.
+func (l *txList) Ready(start uint64, baseFee *big.Int) types.Transactions {
+	ready := l.txs.Ready(start)
+	if baseFee == nil {
+		return ready
+	}
+	for i, tx := range ready {
+		if tx.GasFeeCap().Cmp(baseFee) < 0 {
+			for _, tx := range ready[i:] {
+				l.txs.Put(tx)
+			}
+			return ready[:i]
+		}
+	}
+	return ready
 }
 
 // Len returns the length of the transaction list.
@@ -363,23 +401,40 @@ func (l *txList) Flatten() types.Transactions {
 	return l.txs.Flatten()
 }
 
+// senderCost returns the funds the sender of a transaction must hold to pay for
+// it: the full cost, or just the value for fee delegated transactions whose gas
+// is paid by the fee payer.
+func senderCost(tx *types.Transaction) *big.Int {
+	if tx.Type() == types.FeeDelegatedTxType {
+		return tx.Value()
+	}
+	return tx.Cost()
+}
+
 // priceHeap is a heap.Interface implementation over transactions for retrieving
-// price-sorted transactions to discard when the pool fills up.
-type priceHeap []*types.Transaction
+// price-sorted transactions to discard when the pool fills up. Transactions are
+// sorted by the tip they pay to the miner at the base fee of the next block.
+type priceHeap struct {
+	baseFee *big.Int // Base fee of the next block, nil before the base fee fork
+	list    []*types.Transaction
+}
 
-func (h priceHeap) Len() int           { return len(h) }
-func (h priceHeap) Less(i, j int) bool { return h[i].GasPrice().Cmp(h[j].GasPrice()) < 0 }
-func (h priceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *priceHeap) Len() int      { return len(h.list) }
+func (h *priceHeap) Swap(i, j int) { h.list[i], h.list[j] = h.list[j], h.list[i] }
+
+func (h *priceHeap) Less(i, j int) bool {
+	return h.list[i].EffectiveGasTipCmp(h.list[j], h.baseFee) < 0
+}
 
 func (h *priceHeap) Push(x interface{}) {
-	*h = append(*h, x.(*types.Transaction))
+	h.list = append(h.list, x.(*types.Transaction))
 }
 
 func (h *priceHeap) Pop() interface{} {
-	old := *h
+	old := h.list
 	n := len(old)
 	x := old[n-1]
-	*h = old[0 : n-1]
+	h.list = old[0 : n-1]
 	return x
 }
 
@@ -410,15 +465,33 @@ func (l *txPricedList) Put(tx *types.Transaction) {
 func (l *txPricedList) Removed() {
 	// Bump the stale counter, but exit if still too low (< 25%)
 	l.stales++
-	if l.stales <= len(*l.items)/4 {
+	if l.stales <= l.items.Len()/4 {
 		return
 	}
 	// Seems we've reached a critical number of stale transactions, reheap
-	reheap := make(priceHeap, 0, len(*l.all))
+	l.reheap()
+}
+
+// SetBaseFee updates the base fee the transactions are sorted by and rebuilds
+// the heap if it changed.
+func (l *txPricedList) SetBaseFee(baseFee *big.Int) {
+	old := l.items.baseFee
+	if old == baseFee || (old != nil && baseFee != nil && old.Cmp(baseFee) == 0) {
+		return
+	}
+	l.items.baseFee = baseFee
+	l.reheap()
+}
 
-	l.stales, l.items = 0, &reheap
+// reheap rebuilds the heap from the pool contents, dropping any stale entries.
+func (l *txPricedList) reheap() {
+	reheap := &priceHeap{
+		baseFee: l.items.baseFee,
+		list:    make([]*types.Transaction, 0, len(*l.all)),
+	}
+	l.stales, l.items = 0, reheap
 	for _, tx := range *l.all {
-		*l.items = append(*l.items, tx)
+		l.items.list = append(l.items.list, tx)
 	}
 	heap.Init(l.items)
 }
@@ -429,7 +502,7 @@ func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transact
 	drop := make(types.Transactions, 0, 128) // Remote underpriced transactions to drop
 	save := make(types.Transactions, 0, 64)  // Local underpriced transactions to keep
 
-	for len(*l.items) > 0 {
+	for l.items.Len() > 0 {
 		// Discard stale transactions if found during cleanup
 		tx := heap.Pop(l.items).(*types.Transaction)
 		if _, ok := (*l.all)[tx.Hash()]; !ok {
@@ -437,7 +510,7 @@ func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transact
 			continue
 		}
 		// Stop the discards if we've reached the threshold
-		if tx.GasPrice().Cmp(threshold) >= 0 {
+		if tx.EffectiveGasTipValue(l.items.baseFee).Cmp(threshold) >= 0 {
 			save = append(save, tx)
 			break
 		}
@@ -462,8 +535,8 @@ func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) boo
 		return false
 	}
 	// Discard stale price points if found at the heap start
-	for len(*l.items) > 0 {
-		head := []*types.Transaction(*l.items)[0]
+	for l.items.Len() > 0 {
+		head := l.items.list[0]
 		if _, ok := (*l.all)[head.Hash()]; !ok {
 			l.stales--
 			heap.Pop(l.items)
@@ -472,12 +545,12 @@ func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) boo
 		break
 	}
 	// Check if the transaction is underpriced or not
-	if len(*l.items) == 0 {
+	if l.items.Len() == 0 {
 		log.Error("Pricing query for empty pool") // This cannot happen, print to catch programming errors
 		return false
 	}
-	cheapest := []*types.Transaction(*l.items)[0]
-	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
+	cheapest := l.items.list[0]
+	return cheapest.EffectiveGasTipCmp(tx, l.items.baseFee) >= 0
 }
 
 // Discard finds a number of most underpriced transactions, removes them from the
@@ -486,7 +559,7 @@ func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions
 	drop := make(types.Transactions, 0, count) // Remote underpriced transactions to drop
 	save := make(types.Transactions, 0, 64)    // Local underpriced transactions to keep
 
-	for len(*l.items) > 0 && count > 0 {
+	for l.items.Len() > 0 && count > 0 {
 		// Discard stale transactions if found during cleanup
 		tx := heap.Pop(l.items).(*types.Transaction)
 		if _, ok := (*l.all)[tx.Hash()]; !ok {
@@ -37,7 +37,7 @@ func TesaaerictTxListAdd(t *testing.T) {
 	// Insert the transactions in a random order
 	list := newTxList(true)
 	for _, v := range rand.Perm(len(txs)) {
-		list.Add(txs[v], DefaultTxPoolConfig.PriceBump)
+		list.Add(txs[v], DefaultTxPoolConfig.PriceBump, nil)
 	}
 	// Verify internal state
 	if len(list.txs.items) != len(txs) {
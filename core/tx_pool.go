@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/consensus/misc"
 	"github.com/aaechain/go-aaechain/core/state"
 	"github.com/aaechain/go-aaechain/core/types"
 	"github.com/aaechain/go-aaechain/event"
@@ -78,6 +79,18 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrTxTypeNotSupported is returned if a typed transaction is received
+	// before the fork enabling it.
+	ErrTxTypeNotSupported = types.ErrTxTypeNotSupported
+
+	// ErrInvalidFeePayer is returned if the fee payer signature of a fee
+	// delegated transaction is invalid.
+	ErrInvalidFeePayer = errors.New("invalid fee payer")
+
+	// ErrFeePayerInsufficientFunds is returned if the fee payer of a fee
+	// delegated transaction can't pay for its gas.
+	ErrFeePayerInsufficientFunds = errors.New("insufficient funds of fee payer for gas * price")
 )
 
 var (
@@ -211,6 +224,11 @@ type TxPool struct {
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
+	typedTx   bool // Whaaeer the next block may contain typed transactions
+	feeDeleg  bool // Whaaeer the next block may contain fee delegated transactions
+	baseFee   bool // Whaaeer the next block may contain dynamic fee transactions
+
+	nextBaseFee *big.Int // Base fee of the next block, nil before the base fee fork
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -224,7 +242,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		signer:      types.NewEIP155Signer(chainconfig.ChainId),
+		signer:      types.LatestSigner(chainconfig),
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
@@ -411,6 +429,18 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
+	pool.typedTx = pool.chainconfig.IsTypedTx(next)
+	pool.feeDeleg = pool.chainconfig.IsFeeDelegation(next)
+	pool.baseFee = pool.chainconfig.IsBaseFee(next)
+
+	// Sort the transactions by the tips they would pay in the next block
+	pool.nextBaseFee = nil
+	if pool.baseFee {
+		pool.nextBaseFee = misc.CalcBaseFee(pool.chainconfig, newHead)
+	}
+	pool.priced.SetBaseFee(pool.nextBaseFee)
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	pool.addTxsLocked(reinject, false)
@@ -554,6 +584,16 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx checks whaaeer a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Reject typed transactions until the fork enabling them activates
+	if !pool.typedTx && tx.Type() != types.LegacyTxType {
+		return ErrTxTypeNotSupported
+	}
+	if !pool.feeDeleg && tx.Type() == types.FeeDelegatedTxType {
+		return ErrTxTypeNotSupported
+	}
+	if !pool.baseFee && tx.Type() == types.DynamicFeeTxType {
+		return ErrTxTypeNotSupported
+	}
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
@@ -567,14 +607,18 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentMaxGas < tx.Gas() {
 		return ErrGasLimit
 	}
+	// The priority fee can't exceed the fee cap
+	if tx.GasFeeCap().Cmp(tx.GasTipCap()) < 0 {
+		return ErrTipAboveFeeCap
+	}
 	// Make sure the transaction is signed properly
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
 		return ErrInvalidSender
 	}
-	// Drop non-local transactions under our own minimal accepted gas price
+	// Drop non-local transactions under our own minimal accepted gas price or tip
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
-	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
+	if !local && pool.gasPrice.Cmp(tx.GasTipCap()) > 0 {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
@@ -583,10 +627,24 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	}
 	// Transactor should have enough funds to cover the costs
 	// cost == V + GP * GL
-	if pool.currenaaeate.GetBalance(from).Cmp(tx.Cost()) < 0 {
+	if pool.currenaaeate.GetBalance(from).Cmp(senderCost(tx)) < 0 {
 		return ErrInsufficientFunds
 	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
+	// The fee payer of sponsored transactions covers GP * GL
+	if tx.Type() == types.FeeDelegatedTxType {
+		payer, err := types.FeePayer(pool.signer, tx)
+		if err != nil {
+			return ErrInvalidFeePayer
+		}
+		cost := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+		if payer == from {
+			cost.Add(cost, tx.Value())
+		}
+		if pool.currenaaeate.GetBalance(payer).Cmp(cost) < 0 {
+			return ErrFeePayerInsufficientFunds
+		}
+	}
+	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, pool.homestead)
 	if err != nil {
 		return err
 	}
@@ -637,7 +695,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
-		inserted, old := list.Add(tx, pool.config.PriceBump)
+		inserted, old := list.Add(tx, pool.config.PriceBump, pool.nextBaseFee)
 		if !inserted {
 			pendingDiscardCounter.Inc(1)
 			return false, ErrReplaceUnderpriced
@@ -683,7 +741,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	if pool.queue[from] == nil {
 		pool.queue[from] = newTxList(false)
 	}
-	inserted, old := pool.queue[from].Add(tx, pool.config.PriceBump)
+	inserted, old := pool.queue[from].Add(tx, pool.config.PriceBump, pool.nextBaseFee)
 	if !inserted {
 		// An older transaction was better, discard this
 		queuedDiscardCounter.Inc(1)
@@ -695,8 +753,11 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
 	}
-	pool.all[hash] = tx
-	pool.priced.Put(tx)
+	// Demoted transactions are already tracked
+	if pool.all[hash] == nil {
+		pool.all[hash] = tx
+		pool.priced.Put(tx)
+	}
 	return old != nil, nil
 }
 
@@ -722,7 +783,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	}
 	list := pool.pending[addr]
 
-	inserted, old := list.Add(tx, pool.config.PriceBump)
+	inserted, old := list.Add(tx, pool.config.PriceBump, pool.nextBaseFee)
 	if !inserted {
 		// An older transaction was better, discard this
 		delete(pool.all, hash)
@@ -937,7 +998,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			queuedNofundsCounter.Inc(1)
 		}
 		// Gather all executable transactions and promote them
-		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
+		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr), pool.nextBaseFee) {
 			hash := tx.Hash()
 			log.Trace("Promoting queued transaction", "hash", hash)
 			pool.promoteTx(addr, hash, tx)
@@ -1099,6 +1160,14 @@ func (pool *TxPool) demoteUnexecutables() {
 			log.Trace("Demoting pending transaction", "hash", hash)
 			pool.enqueueTx(hash, tx)
 		}
+		// Queue back any transactions whose fee cap no longer covers the base fee
+		if pool.nextBaseFee != nil {
+			for _, tx := range list.Underpaid(pool.nextBaseFee) {
+				hash := tx.Hash()
+				log.Trace("Demoting underpaid pending transaction", "hash", hash)
+				pool.enqueueTx(hash, tx)
+			}
+		}
 		// If there's a gap in front, warn (should never happen) and postpone all transactions
 		if list.Len() > 0 && list.txs.Get(nonce) == nil {
 			for _, tx := range list.Cap(0) {
@@ -329,6 +329,139 @@ func TestTransactionNegativeValue(t *testing.T) {
 	}
 }
 
+func TestTransactionTypedTxFork(t *testing.T) {
+	t.Parallel()
+
+	diskdb, _ := aaedb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	statedb.AddBalance(addr, big.NewInt(100000000000000))
+
+	tx, _ := types.SignTx(types.NewTx(&types.AccessListTx{
+		ChainID:  params.TestChainConfig.ChainId,
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+		To:       &common.Address{},
+		Value:    big.NewInt(100),
+		AccessList: types.AccessList{
+			{Address: common.Address{}, StorageKeys: []common.Hash{{}}},
+		},
+	}), types.NewEIP2718Signer(params.TestChainConfig.ChainId), key)
+
+	// Typed transactions must be rejected until the fork activates
+	config := *params.TestChainConfig
+	config.TypedTxBlock = nil
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	if err := pool.AddRemote(tx); err != ErrTxTypeNotSupported {
+		t.Error("expected", ErrTxTypeNotSupported, "got", err)
+	}
+	// After the fork they are accepted like any other transaction
+	forked := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer forked.Stop()
+
+	if err := forked.AddRemote(tx); err != nil {
+		t.Error("expected typed transaction to be accepted, got", err)
+	}
+}
+
+// Tests that the fee payer of fee delegated transactions must be able to pay for
+// the gas, while the sender only needs the value.
+func TestTransactionFeeDelegation(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	payerKey, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+
+	signer := types.NewFeeDelegationSigner(params.TestChainConfig.ChainId)
+	tx, _ := types.SignTx(types.NewTx(&types.FeeDelegatedTx{
+		ChainID:  params.TestChainConfig.ChainId,
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+		To:       &common.Address{},
+		Value:    big.NewInt(100),
+	}), signer, key)
+
+	pool.currenaaeate.AddBalance(from, big.NewInt(100))
+	if err := pool.AddRemote(tx); err != ErrInvalidFeePayer {
+		t.Error("expected", ErrInvalidFeePayer, "got", err)
+	}
+	tx, _ = types.SignFeePayer(tx, signer, payerKey)
+	if err := pool.AddRemote(tx); err != ErrFeePayerInsufficientFunds {
+		t.Error("expected", ErrFeePayerInsufficientFunds, "got", err)
+	}
+	pool.currenaaeate.AddBalance(payer, big.NewInt(100000))
+	if err := pool.AddRemote(tx); err != nil {
+		t.Error("expected fee delegated transaction to be accepted, got", err)
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Errorf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+}
+
+// Tests that pending transactions whose fee cap falls below a raised base fee
+// are demoted to the queue, and promoted back once the base fee drops.
+func TestTransactionBaseFeeDemotion(t *testing.T) {
+	t.Parallel()
+
+	diskdb, _ := aaedb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := *params.TestChainConfig
+	config.BaseFeeBlock = big.NewInt(1)
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000000000000))
+
+	signer := types.NewDynamicFeeSigner(config.ChainId)
+	for nonce, feeCap := range []int64{2000000000, 1100000000, 2000000000} {
+		tx, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   config.ChainId,
+			Nonce:     uint64(nonce),
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(feeCap),
+			Gas:       100000,
+			To:        &common.Address{},
+			Value:     big.NewInt(100),
+		}), signer, key)
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 3/0", pending, queued)
+	}
+	// A full block raises the base fee above the second fee cap
+	pool.lockedReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: 1000000, GasUsed: 1000000, BaseFee: big.NewInt(1000000000)})
+	if pending, queued := pool.Stats(); pending != 1 || queued != 2 {
+		t.Fatalf("pool stats mismatch after base fee rise: have %d/%d, want 1/2", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// A block at the gas target keeps the base fee, promoting them back
+	pool.lockedReset(nil, &types.Header{Number: big.NewInt(2), GasLimit: 1000000, GasUsed: 500000, BaseFee: big.NewInt(1000000000)})
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("pool stats mismatch after base fee drop: have %d/%d, want 3/0", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 func TestTransactionChainFork(t *testing.T) {
 	t.Parallel()
 
@@ -68,3 +68,41 @@ func TestBlockEncoding(t *testing.T) {
 		t.Errorf("encoded block mismatch:\ngot:  %x\nwant: %x", ourBlockEnc, blockEnc)
 	}
 }
+
+func TestHeaderBaseFeeEncoding(t *testing.T) {
+	header := &Header{
+		Difficulty: big.NewInt(131072),
+		Number:     big.NewInt(1),
+		GasLimit:   3141592,
+		Time:       big.NewInt(1426516743),
+		Extra:      []byte("test"),
+	}
+	legacy, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	// Headers without a base fee keep their original encoding
+	var dec Header
+	if err := rlp.DecodeBytes(legacy, &dec); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if dec.BaseFee != nil || dec.Hash() != header.Hash() {
+		t.Errorf("legacy header mismatch: have %v, want %v", dec.Hash(), header.Hash())
+	}
+	// Headers with a base fee append it to the encoding
+	header.BaseFee = big.NewInt(1000000000)
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	if len(enc) <= len(legacy) {
+		t.Errorf("base fee not encoded: have %d bytes, legacy %d bytes", len(enc), len(legacy))
+	}
+	dec = Header{}
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if dec.BaseFee == nil || dec.BaseFee.Cmp(header.BaseFee) != 0 || dec.Hash() != header.Hash() {
+		t.Errorf("base fee header mismatch: have %v, want %v", dec.BaseFee, header.BaseFee)
+	}
+}
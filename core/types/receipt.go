@@ -18,6 +18,7 @@ package types
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"unsafe"
@@ -34,6 +35,8 @@ var (
 	receipaaeatusSuccessfulRLP = []byte{0x01}
 )
 
+var errEmptyTypedReceipt = errors.New("empty typed receipt bytes")
+
 const (
 	// ReceipaaeatusFailed is the status code of a transaction if execution failed.
 	ReceipaaeatusFailed = uint(0)
@@ -45,6 +48,7 @@ const (
 // Receipt represents the results of a transaction.
 type Receipt struct {
 	// Consensus fields
+	Type              uint8  `json:"type,omitempty"`
 	Posaaeate         []byte `json:"root"`
 	Status            uint   `json:"status"`
 	CumulativeGasUsed uint64 `json:"cumulativeGasUsed" gencodec:"required"`
@@ -58,6 +62,7 @@ type Receipt struct {
 }
 
 type receiptMarshaling struct {
+	Type              hexutil.Uint64
 	Posaaeate         hexutil.Bytes
 	Status            hexutil.Uint
 	CumulativeGasUsed hexutil.Uint64
@@ -95,22 +100,100 @@ func NewReceipt(root []byte, failed bool, cumulativeGasUsed uint64) *Receipt {
 
 // EncodeRLP implements rlp.Encoder, and flattens the consensus fields of a receipt
 // into an RLP stream. If no post state is present, byzantium fork is assumed.
+// Receipts of typed transactions are encoded as an RLP string containing the
+// type byte followed by the RLP payload.
 func (r *Receipt) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs})
+	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs}
+	if r.Type == LegacyTxType {
+		return rlp.Encode(w, data)
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteByte(r.Type)
+	if err := rlp.Encode(buf, data); err != nil {
+		return err
+	}
+	return rlp.Encode(w, buf.Bytes())
+}
+
+// MarshalBinary returns the consensus encoding of the receipt, the RLP list for
+// legacy receipts and the bare envelope for typed ones.
+func (r *Receipt) MarshalBinary() ([]byte, error) {
+	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs}
+	if r.Type == LegacyTxType {
+		return rlp.EncodeToBytes(data)
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteByte(r.Type)
+	err := rlp.Encode(buf, data)
+	return buf.Bytes(), err
 }
 
 // DecodeRLP implements rlp.Decoder, and loads the consensus fields of a receipt
 // from an RLP stream.
 func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
-	var dec receiptRLP
-	if err := s.Decode(&dec); err != nil {
+	kind, _, err := s.Kind()
+	switch {
+	case err != nil:
 		return err
+	case kind == rlp.List:
+		// It's a legacy receipt.
+		var dec receiptRLP
+		if err := s.Decode(&dec); err != nil {
+			return err
+		}
+		r.Type = LegacyTxType
+		return r.setFromRLP(dec)
+	case kind == rlp.String:
+		// It's a typed receipt envelope.
+		b, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 {
+			return errEmptyTypedReceipt
+		}
+		if b[0] != AccessListTxType {
+			return ErrTxTypeNotSupported
+		}
+		var dec receiptRLP
+		if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+			return err
+		}
+		r.Type = b[0]
+		return r.setFromRLP(dec)
+	default:
+		return rlp.ErrExpectedList
 	}
-	if err := r.seaaeatus(dec.PosaaeateOrStatus); err != nil {
+}
+
+// UnmarshalBinary decodes the consensus encoding of receipts.
+func (r *Receipt) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		// It's a legacy receipt, decode the RLP list
+		var data receiptRLP
+		if err := rlp.DecodeBytes(b, &data); err != nil {
+			return err
+		}
+		r.Type = LegacyTxType
+		return r.setFromRLP(data)
+	}
+	if len(b) == 0 {
+		return errEmptyTypedReceipt
+	}
+	if b[0] != AccessListTxType {
+		return ErrTxTypeNotSupported
+	}
+	var data receiptRLP
+	if err := rlp.DecodeBytes(b[1:], &data); err != nil {
 		return err
 	}
-	r.CumulativeGasUsed, r.Bloom, r.Logs = dec.CumulativeGasUsed, dec.Bloom, dec.Logs
-	return nil
+	r.Type = b[0]
+	return r.setFromRLP(data)
+}
+
+func (r *Receipt) setFromRLP(data receiptRLP) error {
+	r.CumulativeGasUsed, r.Bloom, r.Logs = data.CumulativeGasUsed, data.Bloom, data.Logs
+	return r.seaaeatus(data.PosaaeateOrStatus)
 }
 
 func (r *Receipt) seaaeatus(posaaeateOrStatus []byte) error {
@@ -176,15 +259,44 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
 	}
-	return rlp.Encode(w, enc)
+	if r.Type == LegacyTxType {
+		return rlp.Encode(w, enc)
+	}
+	// Typed receipts are stored as a string holding the type and the fields
+	buf := new(bytes.Buffer)
+	buf.WriteByte(r.Type)
+	if err := rlp.Encode(buf, enc); err != nil {
+		return err
+	}
+	return rlp.Encode(w, buf.Bytes())
 }
 
 // DecodeRLP implements rlp.Decoder, and loads both consensus and implementation
 // fields of a receipt from an RLP stream.
 func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
 	var dec receipaaeorageRLP
-	if err := s.Decode(&dec); err != nil {
+
+	kind, _, err := s.Kind()
+	switch {
+	case err != nil:
 		return err
+	case kind == rlp.List:
+		if err := s.Decode(&dec); err != nil {
+			return err
+		}
+		r.Type = LegacyTxType
+	default:
+		b, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 {
+			return errEmptyTypedReceipt
+		}
+		if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+			return err
+		}
+		r.Type = b[0]
 	}
 	if err := (*Receipt)(r).seaaeatus(dec.PosaaeateOrStatus); err != nil {
 		return err
@@ -206,9 +318,10 @@ type Receipts []*Receipt
 // Len returns the number of receipts in this list.
 func (r Receipts) Len() int { return len(r) }
 
-// GetRlp returns the RLP encoding of one receipt from the list.
+// GetRlp returns the consensus encoding of one receipt from the list, the
+// envelope without the RLP string header for typed receipts.
 func (r Receipts) GetRlp(i int) []byte {
-	bytes, err := rlp.EncodeToBytes(r[i])
+	bytes, err := r[i].MarshalBinary()
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,93 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/rlp"
+)
+
+func TestTypedReceiptEncoding(t *testing.T) {
+	receipt := &Receipt{
+		Type:              AccessListTxType,
+		Status:            ReceipaaeatusSuccessful,
+		CumulativeGasUsed: 1,
+		Logs: []*Log{
+			{Address: common.BytesToAddress([]byte{0x11}), Topics: []common.Hash{{0x01}}, Data: []byte{0x01, 0x00, 0xff}},
+		},
+	}
+	// The consensus encoding is the type byte followed by the RLP payload
+	bin, err := receipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("binary encode error: %v", err)
+	}
+	if bin[0] != AccessListTxType {
+		t.Fatalf("envelope type mismatch, got %d", bin[0])
+	}
+	if !bytes.Equal(Receipts{receipt}.GetRlp(0), bin) {
+		t.Errorf("GetRlp mismatch")
+	}
+	dec := new(Receipt)
+	if err := dec.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("binary decode error: %v", err)
+	}
+	if dec.Type != receipt.Type || dec.Status != receipt.Status || len(dec.Logs) != 1 {
+		t.Errorf("binary roundtrip mismatch: have %v, want %v", dec, receipt)
+	}
+	// Inside RLP lists the envelope is wrapped into a string
+	legacy := &Receipt{Status: ReceipaaeatusFailed, CumulativeGasUsed: 2}
+	enc, err := rlp.EncodeToBytes(Receipts{receipt, legacy})
+	if err != nil {
+		t.Fatalf("rlp encode error: %v", err)
+	}
+	var receipts Receipts
+	if err := rlp.DecodeBytes(enc, &receipts); err != nil {
+		t.Fatalf("rlp decode error: %v", err)
+	}
+	if len(receipts) != 2 || receipts[0].Type != AccessListTxType || receipts[1].Type != LegacyTxType {
+		t.Fatalf("rlp roundtrip mismatch")
+	}
+	if receipts[1].Status != ReceipaaeatusFailed || receipts[1].CumulativeGasUsed != 2 {
+		t.Errorf("legacy receipt mismatch: have %v, want %v", receipts[1], legacy)
+	}
+}
+
+func TestTypedReceiptStorage(t *testing.T) {
+	receipt := &Receipt{
+		Type:              AccessListTxType,
+		Status:            ReceipaaeatusSuccessful,
+		CumulativeGasUsed: 1,
+		Logs:              []*Log{},
+		TxHash:            common.BytesToHash([]byte{0x11, 0x11}),
+		ContractAddress:   common.BytesToAddress([]byte{0x01, 0x11, 0x11}),
+		GasUsed:           111111,
+	}
+	enc, err := rlp.EncodeToBytes((*ReceiptForStorage)(receipt))
+	if err != nil {
+		t.Fatalf("storage encode error: %v", err)
+	}
+	dec := new(ReceiptForStorage)
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatalf("storage decode error: %v", err)
+	}
+	if dec.Type != receipt.Type || dec.TxHash != receipt.TxHash || dec.ContractAddress != receipt.ContractAddress || dec.GasUsed != receipt.GasUsed {
+		t.Errorf("storage roundtrip mismatch: have %v, want %v", (*Receipt)(dec), receipt)
+	}
+}
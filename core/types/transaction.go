@@ -17,6 +17,7 @@
 package types
 
 import (
+	"bytes"
 	"container/heap"
 	"errors"
 	"fmt"
@@ -25,16 +26,27 @@ import (
 	"sync/atomic"
 
 	"github.com/aaechain/go-aaechain/common"
-	"github.com/aaechain/go-aaechain/common/hexutil"
 	"github.com/aaechain/go-aaechain/crypto"
 	"github.com/aaechain/go-aaechain/rlp"
 )
 
-//go:generate gencodec -type txdata -field-override txdataMarshaling -out gen_tx_json.go
-
 var (
-	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
-	errNoSigner   = errors.New("missing signing methods")
+	ErrInvalidSig         = errors.New("invalid transaction v, r, s values")
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	errNoSigner           = errors.New("missing signing methods")
+	errEmptyTypedTx       = errors.New("empty typed transaction bytes")
+
+	// ErrGasFeeCapTooLow is returned if the fee cap of a transaction is below
+	// the base fee of the block.
+	ErrGasFeeCapTooLow = errors.New("fee cap less than block base fee")
+)
+
+// Transaction types.
+const (
+	LegacyTxType = iota
+	AccessListTxType
+	FeeDelegatedTxType
+	DynamicFeeTxType
 )
 
 // deriveSigner makes a *best* guess about which signer to use.
@@ -46,40 +58,42 @@ func deriveSigner(V *big.Int) Signer {
 	}
 }
 
+// Transaction is an aaechain transaction. Its content is one of the supported
+// transaction types: legacy transactions are encoded as an RLP list, typed
+// ones as an envelope made of the type byte followed by the RLP payload.
 type Transaction struct {
-	data txdata
+	data TxData
 	// caches
-	hash atomic.Value
-	size atomic.Value
-	from atomic.Value
+	hash  atomic.Value
+	size  atomic.Value
+	from  atomic.Value
+	payer atomic.Value
 }
 
-type txdata struct {
-	AccountNonce uint64          `json:"nonce"    gencodec:"required"`
-	Price        *big.Int        `json:"gasPrice" gencodec:"required"`
-	GasLimit     uint64          `json:"gas"      gencodec:"required"`
-	Recipient    *common.Address `json:"to"       rlp:"nil"` // nil means contract creation
-	Amount       *big.Int        `json:"value"    gencodec:"required"`
-	Payload      []byte          `json:"input"    gencodec:"required"`
+// TxData is the underlying data of a transaction, implemented by each
+// transaction type.
+type TxData interface {
+	txType() byte // returns the type ID
+	copy() TxData // creates a deep copy
 
-	// Signature values
-	V *big.Int `json:"v" gencodec:"required"`
-	R *big.Int `json:"r" gencodec:"required"`
-	S *big.Int `json:"s" gencodec:"required"`
+	chainID() *big.Int
+	accessList() AccessList
+	data() []byte
+	gas() uint64
+	gasPrice() *big.Int
+	gasTipCap() *big.Int
+	gasFeeCap() *big.Int
+	value() *big.Int
+	nonce() uint64
+	to() *common.Address
 
-	// This is only used when marshaling to JSON.
-	Hash *common.Hash `json:"hash" rlp:"-"`
+	rawSignatureValues() (v, r, s *big.Int)
+	setSignatureValues(v, r, s *big.Int)
 }
 
-type txdataMarshaling struct {
-	AccountNonce hexutil.Uint64
-	Price        *hexutil.Big
-	GasLimit     hexutil.Uint64
-	Amount       *hexutil.Big
-	Payload      hexutil.Bytes
-	V            *hexutil.Big
-	R            *hexutil.Big
-	S            *hexutil.Big
+// NewTx creates a new transaction of the type of inner.
+func NewTx(inner TxData) *Transaction {
+	return &Transaction{data: inner.copy()}
 }
 
 func NewTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
@@ -94,7 +108,7 @@ func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit
 	if len(data) > 0 {
 		data = common.CopyBytes(data)
 	}
-	d := txdata{
+	d := &LegacyTx{
 		AccountNonce: nonce,
 		Recipient:    to,
 		Payload:      data,
@@ -115,14 +129,22 @@ func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit
 	return &Transaction{data: d}
 }
 
+// Type returns the transaction type.
+func (tx *Transaction) Type() uint8 {
+	return tx.data.txType()
+}
+
 // ChainId returns which chain id this transaction was signed for (if at all)
 func (tx *Transaction) ChainId() *big.Int {
-	return deriveChainId(tx.data.V)
+	return tx.data.chainID()
 }
 
 // Protected returns whaaeer the transaction is protected from replay protection.
 func (tx *Transaction) Protected() bool {
-	return isProtectedV(tx.data.V)
+	if tx, ok := tx.data.(*LegacyTx); ok {
+		return tx.V != nil && isProtectedV(tx.V)
+	}
+	return true
 }
 
 func isProtectedV(V *big.Int) bool {
@@ -134,86 +156,223 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-// EncodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder. Typed transactions are encoded as an RLP
+// string containing the envelope.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.Type() == LegacyTxType {
+		return rlp.Encode(w, tx.data)
+	}
+	buf := new(bytes.Buffer)
+	if err := tx.encodeTyped(buf); err != nil {
+		return err
+	}
+	return rlp.Encode(w, buf.Bytes())
+}
+
+// encodeTyped writes the canonical encoding of a typed transaction to w.
+func (tx *Transaction) encodeTyped(w *bytes.Buffer) error {
+	w.WriteByte(tx.Type())
+	return rlp.Encode(w, tx.data)
+}
+
+// MarshalBinary returns the canonical encoding of the transaction. For legacy
+// transactions it is the RLP encoding, for typed ones the envelope.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.Type() == LegacyTxType {
+		return rlp.EncodeToBytes(tx.data)
+	}
+	var buf bytes.Buffer
+	err := tx.encodeTyped(&buf)
+	return buf.Bytes(), err
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
-		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+	kind, size, err := s.Kind()
+	switch {
+	case err != nil:
+		return err
+	case kind == rlp.List:
+		// It's a legacy transaction
+		var inner LegacyTx
+		if err := s.Decode(&inner); err != nil {
+			return err
+		}
+		tx.setDecoded(&inner, common.StorageSize(rlp.ListSize(size)))
+		return nil
+	case kind == rlp.String:
+		// It's a typed transaction envelope
+		b, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		inner, err := decodeTyped(b)
+		if err != nil {
+			return err
+		}
+		tx.setDecoded(inner, common.StorageSize(len(b)))
+		return nil
+	default:
+		return rlp.ErrExpectedList
 	}
-
-	return err
 }
 
-// MarshalJSON encodes the web3 RPC transaction format.
-func (tx *Transaction) MarshalJSON() ([]byte, error) {
-	hash := tx.Hash()
-	data := tx.data
-	data.Hash = &hash
-	return data.MarshalJSON()
+// UnmarshalBinary decodes the canonical encoding of transactions.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		// It's a legacy transaction
+		var inner LegacyTx
+		if err := rlp.DecodeBytes(b, &inner); err != nil {
+			return err
+		}
+		tx.setDecoded(&inner, common.StorageSize(len(b)))
+		return nil
+	}
+	inner, err := decodeTyped(b)
+	if err != nil {
+		return err
+	}
+	tx.setDecoded(inner, common.StorageSize(len(b)))
+	return nil
 }
 
-// UnmarshalJSON decodes the web3 RPC transaction format.
-func (tx *Transaction) UnmarshalJSON(input []byte) error {
-	var dec txdata
-	if err := dec.UnmarshalJSON(input); err != nil {
-		return err
+// decodeTyped decodes the envelope of a typed transaction.
+func decodeTyped(b []byte) (TxData, error) {
+	if len(b) == 0 {
+		return nil, errEmptyTypedTx
+	}
+	switch b[0] {
+	case AccessListTxType:
+		var inner AccessListTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	case FeeDelegatedTxType:
+		var inner FeeDelegatedTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	case DynamicFeeTxType:
+		var inner DynamicFeeTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	default:
+		return nil, ErrTxTypeNotSupported
 	}
-	var V byte
-	if isProtectedV(dec.V) {
-		chainID := deriveChainId(dec.V).Uint64()
-		V = byte(dec.V.Uint64() - 35 - 2*chainID)
+}
+
+// setDecoded sets the inner transaction and size after decoding.
+func (tx *Transaction) setDecoded(inner TxData, size common.StorageSize) {
+	tx.data = inner
+	tx.size.Store(size)
+}
+
+// sanityCheckSignature validates the signature values of a decoded transaction.
+func sanityCheckSignature(v *big.Int, r *big.Int, s *big.Int, maybeProtected bool) error {
+	var plainV byte
+	if maybeProtected && isProtectedV(v) {
+		chainID := deriveChainId(v).Uint64()
+		plainV = byte(v.Uint64() - 35 - 2*chainID)
+	} else if maybeProtected {
+		plainV = byte(v.Uint64() - 27)
 	} else {
-		V = byte(dec.V.Uint64() - 27)
+		// Typed transactions carry the plain y parity
+		if v.BitLen() > 8 {
+			return ErrInvalidSig
+		}
+		plainV = byte(v.Uint64())
 	}
-	if !crypto.ValidateSignatureValues(V, dec.R, dec.S, false) {
+	if !crypto.ValidateSignatureValues(plainV, r, s, false) {
 		return ErrInvalidSig
 	}
-	*tx = Transaction{data: dec}
 	return nil
 }
 
-func (tx *Transaction) Data() []byte       { return common.CopyBytes(tx.data.Payload) }
-func (tx *Transaction) Gas() uint64        { return tx.data.GasLimit }
-func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.data.Price) }
-func (tx *Transaction) Value() *big.Int    { return new(big.Int).Set(tx.data.Amount) }
-func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
+func (tx *Transaction) Data() []byte       { return common.CopyBytes(tx.data.data()) }
+func (tx *Transaction) Gas() uint64        { return tx.data.gas() }
+func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.data.gasPrice()) }
+func (tx *Transaction) Value() *big.Int    { return new(big.Int).Set(tx.data.value()) }
+func (tx *Transaction) Nonce() uint64      { return tx.data.nonce() }
 func (tx *Transaction) CheckNonce() bool   { return true }
 
+// AccessList returns the access list of the transaction, nil for legacy ones.
+func (tx *Transaction) AccessList() AccessList { return tx.data.accessList() }
+
+// GasTipCap returns the maximum priority fee per gas of the transaction. It is
+// the gas price for transactions without a dynamic fee.
+func (tx *Transaction) GasTipCap() *big.Int { return new(big.Int).Set(tx.data.gasTipCap()) }
+
+// GasFeeCap returns the maximum fee per gas of the transaction, base fee
+// included. It is the gas price for transactions without a dynamic fee.
+func (tx *Transaction) GasFeeCap() *big.Int { return new(big.Int).Set(tx.data.gasFeeCap()) }
+
+// EffectiveGasTip returns the priority fee per gas paid to the miner if the
+// transaction is included in a block with the given base fee. It returns
+// ErrGasFeeCapTooLow if the fee cap doesn't cover the base fee. A nil base fee
+// returns the tip cap.
+func (tx *Transaction) EffectiveGasTip(baseFee *big.Int) (*big.Int, error) {
+	tip := tx.EffectiveGasTipValue(baseFee)
+	if tip.Sign() < 0 {
+		return nil, ErrGasFeeCapTooLow
+	}
+	return tip, nil
+}
+
+// EffectiveGasTipValue is identical to EffectiveGasTip, but returns a negative
+// tip instead of an error if the fee cap doesn't cover the base fee.
+func (tx *Transaction) EffectiveGasTipValue(baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return tx.GasTipCap()
+	}
+	tip := new(big.Int).Sub(tx.data.gasFeeCap(), baseFee)
+	if tip.Cmp(tx.data.gasTipCap()) > 0 {
+		tip.Set(tx.data.gasTipCap())
+	}
+	return tip
+}
+
+// EffectiveGasTipCmp compares the effective tips of two transactions at the
+// given base fee.
+func (tx *Transaction) EffectiveGasTipCmp(other *Transaction, baseFee *big.Int) int {
+	return tx.EffectiveGasTipValue(baseFee).Cmp(other.EffectiveGasTipValue(baseFee))
+}
+
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
 func (tx *Transaction) To() *common.Address {
-	if tx.data.Recipient == nil {
+	if tx.data.to() == nil {
 		return nil
 	}
-	to := *tx.data.Recipient
+	to := *tx.data.to()
 	return &to
 }
 
-// Hash hashes the RLP encoding of tx.
+// Hash returns the transaction hash, the hash of the RLP encoding for legacy
+// transactions and of the envelope for typed ones.
 // It uniquely identifies the transaction.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
+	var v common.Hash
+	if tx.Type() == LegacyTxType {
+		v = rlpHash(tx.data)
+	} else {
+		v = prefixedRlpHash(tx.Type(), tx.data)
+	}
 	tx.hash.Store(v)
 	return v
 }
 
-// Size returns the true RLP encoded storage size of the transaction, either by
+// Size returns the true encoded storage size of the transaction, either by
 // encoding and returning it, or returning a previsouly cached value.
 func (tx *Transaction) Size() common.StorageSize {
 	if size := tx.size.Load(); size != nil {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
+	rlp.Encode(&c, tx.data)
+	if tx.Type() != LegacyTxType {
+		c++ // type byte of the envelope
+	}
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -225,17 +384,23 @@ func (tx *Transaction) Size() common.StorageSize {
 // XXX Rename message to somaaeing less arbitrary?
 func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 	msg := Message{
-		nonce:      tx.data.AccountNonce,
-		gasLimit:   tx.data.GasLimit,
-		gasPrice:   new(big.Int).Set(tx.data.Price),
-		to:         tx.data.Recipient,
-		amount:     tx.data.Amount,
-		data:       tx.data.Payload,
+		nonce:      tx.data.nonce(),
+		gasLimit:   tx.data.gas(),
+		gasPrice:   new(big.Int).Set(tx.data.gasPrice()),
+		gasTipCap:  new(big.Int).Set(tx.data.gasTipCap()),
+		gasFeeCap:  new(big.Int).Set(tx.data.gasFeeCap()),
+		to:         tx.data.to(),
+		amount:     tx.data.value(),
+		data:       tx.data.data(),
+		accessList: tx.data.accessList(),
 		checkNonce: true,
 	}
 
 	var err error
-	msg.from, err = Sender(s, tx)
+	if msg.from, err = Sender(s, tx); err != nil {
+		return msg, err
+	}
+	msg.feePayer, err = FeePayer(s, tx)
 	return msg, err
 }
 
@@ -246,28 +411,56 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	if err != nil {
 		return nil, err
 	}
-	cpy := &Transaction{data: tx.data}
-	cpy.data.R, cpy.data.S, cpy.data.V = r, s, v
-	return cpy, nil
+	cpy := tx.data.copy()
+	cpy.setSignatureValues(v, r, s)
+	return &Transaction{data: cpy}, nil
 }
 
 // Cost returns amount + gasprice * gaslimit.
 func (tx *Transaction) Cost() *big.Int {
-	total := new(big.Int).Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
-	total.Add(total, tx.data.Amount)
+	total := new(big.Int).Mul(tx.data.gasPrice(), new(big.Int).SetUint64(tx.data.gas()))
+	total.Add(total, tx.data.value())
 	return total
 }
 
 func (tx *Transaction) RawSignatureValues() (*big.Int, *big.Int, *big.Int) {
-	return tx.data.V, tx.data.R, tx.data.S
+	return tx.data.rawSignatureValues()
+}
+
+// RawFeePayerSignatureValues returns the fee payer signature of fee delegated
+// transactions, nil values otherwise.
+func (tx *Transaction) RawFeePayerSignatureValues() (*big.Int, *big.Int, *big.Int) {
+	if inner, ok := tx.data.(*FeeDelegatedTx); ok {
+		return inner.FeePayerV, inner.FeePayerR, inner.FeePayerS
+	}
+	return nil, nil, nil
+}
+
+// WithFeePayerSignature returns a new transaction with the given fee payer
+// signature, in the [R || S || V] format where V is 0 or 1.
+func (tx *Transaction) WithFeePayerSignature(signer FeeDelegationSigner, sig []byte) (*Transaction, error) {
+	inner, ok := tx.data.(*FeeDelegatedTx)
+	if !ok {
+		return nil, ErrTxTypeNotSupported
+	}
+	if inner.ChainID.Cmp(signer.chainId) != 0 {
+		return nil, ErrInvalidChainId
+	}
+	cpy := inner.copy().(*FeeDelegatedTx)
+	cpy.FeePayerR, cpy.FeePayerS, _ = decodeSignature(sig)
+	cpy.FeePayerV = big.NewInt(int64(sig[64]))
+	return &Transaction{data: cpy}, nil
 }
 
 func (tx *Transaction) String() string {
 	var from, to string
-	if tx.data.V != nil {
+	if v, _, _ := tx.data.rawSignatureValues(); v != nil {
 		// make a best guess about the signer and use that to derive
 		// the sender.
-		signer := deriveSigner(tx.data.V)
+		signer := deriveSigner(v)
+		if tx.Type() != LegacyTxType {
+			signer = NewEIP2718Signer(tx.ChainId())
+		}
 		if f, err := Sender(signer, tx); err != nil { // derive but don't cache
 			from = "[invalid sender: invalid sig]"
 		} else {
@@ -277,14 +470,16 @@ func (tx *Transaction) String() string {
 		from = "[invalid sender: nil V field]"
 	}
 
-	if tx.data.Recipient == nil {
+	if tx.data.to() == nil {
 		to = "[contract creation]"
 	} else {
-		to = fmt.Sprintf("%x", tx.data.Recipient[:])
+		to = fmt.Sprintf("%x", tx.data.to()[:])
 	}
-	enc, _ := rlp.EncodeToBytes(&tx.data)
+	enc, _ := tx.MarshalBinary()
+	v, r, s := tx.data.rawSignatureValues()
 	return fmt.Sprintf(`
 	TX(%x)
+	Type:     %d
 	Contract: %v
 	From:     %s
 	To:       %s
@@ -299,17 +494,18 @@ func (tx *Transaction) String() string {
 	Hex:      %x
 `,
 		tx.Hash(),
-		tx.data.Recipient == nil,
+		tx.Type(),
+		tx.data.to() == nil,
 		from,
 		to,
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Amount,
-		tx.data.Payload,
-		tx.data.V,
-		tx.data.R,
-		tx.data.S,
+		tx.data.nonce(),
+		tx.data.gasPrice(),
+		tx.data.gas(),
+		tx.data.value(),
+		tx.data.data(),
+		v,
+		r,
+		s,
 		enc,
 	)
 }
@@ -323,9 +519,10 @@ func (s Transactions) Len() int { return len(s) }
 // Swap swaps the i'th and the j'th element in s.
 func (s Transactions) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-// GetRlp implements Rlpable and returns the i'th element of s in rlp.
+// GetRlp implements Rlpable and returns the i'th element of s in its canonical
+// encoding, rlp for legacy transactions and the envelope for typed ones.
 func (s Transactions) GetRlp(i int) []byte {
-	enc, _ := rlp.EncodeToBytes(s[i])
+	enc, _ := s[i].MarshalBinary()
 	return enc
 }
 
@@ -353,19 +550,38 @@ func TxDifference(a, b Transactions) (keep Transactions) {
 type TxByNonce Transactions
 
 func (s TxByNonce) Len() int           { return len(s) }
-func (s TxByNonce) Less(i, j int) bool { return s[i].data.AccountNonce < s[j].data.AccountNonce }
+func (s TxByNonce) Less(i, j int) bool { return s[i].data.nonce() < s[j].data.nonce() }
 func (s TxByNonce) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
+// TxWithMinerFee wraps a transaction with its effective priority fee, the
+// part of the gas price paid to the miner.
+type TxWithMinerFee struct {
+	tx       *Transaction
+	minerFee *big.Int
+}
+
+// NewTxWithMinerFee creates a wrapped transaction, calculating the effective
+// miner fee if a base fee is provided. It returns an error if the fee cap of
+// the transaction doesn't cover the base fee.
+func NewTxWithMinerFee(tx *Transaction, baseFee *big.Int) (*TxWithMinerFee, error) {
+	minerFee, err := tx.EffectiveGasTip(baseFee)
+	if err != nil {
+		return nil, err
+	}
+	return &TxWithMinerFee{tx: tx, minerFee: minerFee}, nil
+}
+
 // TxByPrice implements both the sort and the heap interface, making it useful
 // for all at once sorting as well as individually adding and removing elements.
-type TxByPrice Transactions
+// Transactions are ordered by the miner fee they pay.
+type TxByPrice []*TxWithMinerFee
 
 func (s TxByPrice) Len() int           { return len(s) }
-func (s TxByPrice) Less(i, j int) bool { return s[i].data.Price.Cmp(s[j].data.Price) > 0 }
+func (s TxByPrice) Less(i, j int) bool { return s[i].minerFee.Cmp(s[j].minerFee) > 0 }
 func (s TxByPrice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 func (s *TxByPrice) Push(x interface{}) {
-	*s = append(*s, x.(*Transaction))
+	*s = append(*s, x.(*TxWithMinerFee))
 }
 
 func (s *TxByPrice) Pop() interface{} {
@@ -380,32 +596,42 @@ func (s *TxByPrice) Pop() interface{} {
 // transactions in a profit-maximizing sorted order, while supporting removing
 // entire batches of transactions for non-executable accounts.
 type TransactionsByPriceAndNonce struct {
-	txs    map[common.Address]Transactions // Per account nonce-sorted list of transactions
-	heads  TxByPrice                       // Next transaction for each unique account (price heap)
-	signer Signer                          // Signer for the set of transactions
+	txs     map[common.Address]Transactions // Per account nonce-sorted list of transactions
+	heads   TxByPrice                       // Next transaction for each unique account (price heap)
+	signer  Signer                          // Signer for the set of transactions
+	baseFee *big.Int                        // Current base fee, nil before the base fee fork
 }
 
 // NewTransactionsByPriceAndNonce creates a transaction set that can retrieve
 // price sorted transactions in a nonce-honouring way.
 //
+// Transactions are ordered by the miner fee they pay on top of the given base
+// fee. Accounts whose next transaction doesn't cover the base fee are skipped.
+//
 // Note, the input map is reowned so the caller should not interact any more with
 // if after providing it to the constructor.
-func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions) *TransactionsByPriceAndNonce {
+func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions, baseFee *big.Int) *TransactionsByPriceAndNonce {
 	// Initialize a price based heap with the head transactions
 	heads := make(TxByPrice, 0, len(txs))
-	for _, accTxs := range txs {
-		heads = append(heads, accTxs[0])
+	for from, accTxs := range txs {
 		// Ensure the sender address is from the signer
 		acc, _ := Sender(signer, accTxs[0])
+		wrapped, err := NewTxWithMinerFee(accTxs[0], baseFee)
+		if err != nil {
+			delete(txs, from)
+			continue
+		}
+		heads = append(heads, wrapped)
 		txs[acc] = accTxs[1:]
 	}
 	heap.Init(&heads)
 
 	// Assemble and return the transaction set
 	return &TransactionsByPriceAndNonce{
-		txs:    txs,
-		heads:  heads,
-		signer: signer,
+		txs:     txs,
+		heads:   heads,
+		signer:  signer,
+		baseFee: baseFee,
 	}
 }
 
@@ -414,18 +640,20 @@ func (t *TransactionsByPriceAndNonce) Peek() *Transaction {
 	if len(t.heads) == 0 {
 		return nil
 	}
-	return t.heads[0]
+	return t.heads[0].tx
 }
 
 // Shift replaces the current best head with the next one from the same account.
 func (t *TransactionsByPriceAndNonce) Shift() {
-	acc, _ := Sender(t.signer, t.heads[0])
+	acc, _ := Sender(t.signer, t.heads[0].tx)
 	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
-		t.heads[0], t.txs[acc] = txs[0], txs[1:]
-		heap.Fix(&t.heads, 0)
-	} else {
-		heap.Pop(&t.heads)
+		if wrapped, err := NewTxWithMinerFee(txs[0], t.baseFee); err == nil {
+			t.heads[0], t.txs[acc] = wrapped, txs[1:]
+			heap.Fix(&t.heads, 0)
+			return
+		}
 	}
+	heap.Pop(&t.heads)
 }
 
 // Pop removes the best transaction, *not* replacing it with the next one from
@@ -445,28 +673,40 @@ type Message struct {
 	amount     *big.Int
 	gasLimit   uint64
 	gasPrice   *big.Int
+	gasTipCap  *big.Int
+	gasFeeCap  *big.Int
 	data       []byte
+	accessList AccessList
+	feePayer   common.Address
 	checkNonce bool
 }
 
-func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, checkNonce bool) Message {
+func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, accessList AccessList, checkNonce bool) Message {
 	return Message{
 		from:       from,
+		feePayer:   from,
 		to:         to,
 		nonce:      nonce,
 		amount:     amount,
 		gasLimit:   gasLimit,
 		gasPrice:   gasPrice,
+		gasTipCap:  gasPrice,
+		gasFeeCap:  gasPrice,
 		data:       data,
+		accessList: accessList,
 		checkNonce: checkNonce,
 	}
 }
 
-func (m Message) From() common.Address { return m.from }
-func (m Message) To() *common.Address  { return m.to }
-func (m Message) GasPrice() *big.Int   { return m.gasPrice }
-func (m Message) Value() *big.Int      { return m.amount }
-func (m Message) Gas() uint64          { return m.gasLimit }
-func (m Message) Nonce() uint64        { return m.nonce }
-func (m Message) Data() []byte         { return m.data }
-func (m Message) CheckNonce() bool     { return m.checkNonce }
+func (m Message) From() common.Address     { return m.from }
+func (m Message) To() *common.Address      { return m.to }
+func (m Message) GasPrice() *big.Int       { return m.gasPrice }
+func (m Message) GasTipCap() *big.Int      { return m.gasTipCap }
+func (m Message) GasFeeCap() *big.Int      { return m.gasFeeCap }
+func (m Message) Value() *big.Int          { return m.amount }
+func (m Message) Gas() uint64              { return m.gasLimit }
+func (m Message) Nonce() uint64            { return m.nonce }
+func (m Message) Data() []byte             { return m.data }
+func (m Message) AccessList() AccessList   { return m.accessList }
+func (m Message) FeePayer() common.Address { return m.feePayer }
+func (m Message) CheckNonce() bool         { return m.checkNonce }
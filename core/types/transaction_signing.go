@@ -42,6 +42,12 @@ type sigCache struct {
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
+	case config.IsBaseFee(blockNumber):
+		signer = NewDynamicFeeSigner(config.ChainId)
+	case config.IsFeeDelegation(blockNumber):
+		signer = NewFeeDelegationSigner(config.ChainId)
+	case config.IsTypedTx(blockNumber):
+		signer = NewEIP2718Signer(config.ChainId)
 	case config.IsEIP155(blockNumber):
 		signer = NewEIP155Signer(config.ChainId)
 	case config.IsHomestead(blockNumber):
@@ -52,6 +58,38 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	return signer
 }
 
+// LatestSigner returns the most permissive Signer available for the given chain
+// configuration, accepting every transaction type the chain may ever enable.
+// Use it in code that handles transactions independently of the block number,
+// such as the transaction pool.
+func LatestSigner(config *params.ChainConfig) Signer {
+	if config.ChainId != nil {
+		if config.BaseFeeBlock != nil {
+			return NewDynamicFeeSigner(config.ChainId)
+		}
+		if config.FeeDelegationBlock != nil {
+			return NewFeeDelegationSigner(config.ChainId)
+		}
+		if config.TypedTxBlock != nil {
+			return NewEIP2718Signer(config.ChainId)
+		}
+		if config.EIP155Block != nil {
+			return NewEIP155Signer(config.ChainId)
+		}
+	}
+	return HomesteadSigner{}
+}
+
+// LatestSignerForChainID returns the most permissive Signer available for the
+// given chain id, or the homestead signer if the chain id is nil. Use it for
+// signing transactions when the chain configuration is not available.
+func LatestSignerForChainID(chainID *big.Int) Signer {
+	if chainID == nil {
+		return HomesteadSigner{}
+	}
+	return NewDynamicFeeSigner(chainID)
+}
+
 // SignTx signs the transaction using the given signer and private key
 func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
 	h := s.Hash(tx)
@@ -88,6 +126,45 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 	return addr, nil
 }
 
+// FeePayer returns the address paying for the gas of the transaction, derived
+// from the fee payer signature of fee delegated transactions and the sender
+// of any other transaction.
+//
+// Like Sender, FeePayer caches the address for the signer used to derive it.
+func FeePayer(signer Signer, tx *Transaction) (common.Address, error) {
+	if tx.Type() != FeeDelegatedTxType {
+		return Sender(signer, tx)
+	}
+	fs, ok := signer.(feePayerSigner)
+	if !ok {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	if sc := tx.payer.Load(); sc != nil {
+		sigCache := sc.(sigCache)
+		if sigCache.signer.Equal(signer) {
+			return sigCache.from, nil
+		}
+	}
+	addr, err := fs.FeePayer(tx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	tx.payer.Store(sigCache{signer: signer, from: addr})
+	return addr, nil
+}
+
+// SignFeePayer signs a fee delegated transaction as fee payer, using the given
+// signer and private key. The transaction should already be signed by its
+// sender.
+func SignFeePayer(tx *Transaction, s FeeDelegationSigner, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	h := s.FeePayerHash(tx)
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(s, sig)
+}
+
 // Signer encapsulates transaction signature handling. Note that this interface is not a
 // stable API and may change at any time to accommodate new protocol rules.
 type Signer interface {
@@ -102,6 +179,225 @@ type Signer interface {
 	Equal(Signer) bool
 }
 
+// feePayerSigner is implemented by the signers deriving the fee payer of fee
+// delegated transactions.
+type feePayerSigner interface {
+	FeePayer(tx *Transaction) (common.Address, error)
+}
+
+// DynamicFeeSigner implements Signer for dynamic fee transactions, delegating
+// any other transaction to the fee delegation rules.
+type DynamicFeeSigner struct{ FeeDelegationSigner }
+
+func NewDynamicFeeSigner(chainId *big.Int) DynamicFeeSigner {
+	return DynamicFeeSigner{NewFeeDelegationSigner(chainId)}
+}
+
+func (s DynamicFeeSigner) Equal(s2 Signer) bool {
+	x, ok := s2.(DynamicFeeSigner)
+	return ok && x.chainId.Cmp(s.chainId) == 0
+}
+
+func (s DynamicFeeSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != DynamicFeeTxType {
+		return s.FeeDelegationSigner.Sender(tx)
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V, R, S := tx.RawSignatureValues()
+	V = new(big.Int).Add(V, big.NewInt(27))
+	return recoverPlain(s.Hash(tx), R, S, V, true)
+}
+
+// SignatureValues returns signature values. This signature
+// needs to be in the [R || S || V] format where V is 0 or 1.
+func (s DynamicFeeSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != DynamicFeeTxType {
+		return s.FeeDelegationSigner.SignatureValues(tx, sig)
+	}
+	if tx.ChainId().Sign() != 0 && tx.ChainId().Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _ = decodeSignature(sig)
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s DynamicFeeSigner) Hash(tx *Transaction) common.Hash {
+	if tx.Type() != DynamicFeeTxType {
+		return s.FeeDelegationSigner.Hash(tx)
+	}
+	return prefixedRlpHash(tx.Type(), []interface{}{
+		s.chainId,
+		tx.Nonce(),
+		tx.data.gasTipCap(),
+		tx.data.gasFeeCap(),
+		tx.Gas(),
+		tx.To(),
+		tx.data.value(),
+		tx.data.data(),
+		tx.AccessList(),
+	})
+}
+
+// FeeDelegationSigner implements Signer for fee delegated transactions,
+// delegating any other transaction to the EIP2718 rules. The sender and the fee
+// payer of fee delegated transactions sign distinct hashes, see Hash and
+// FeePayerHash.
+type FeeDelegationSigner struct{ EIP2718Signer }
+
+func NewFeeDelegationSigner(chainId *big.Int) FeeDelegationSigner {
+	return FeeDelegationSigner{NewEIP2718Signer(chainId)}
+}
+
+func (s FeeDelegationSigner) Equal(s2 Signer) bool {
+	x, ok := s2.(FeeDelegationSigner)
+	return ok && x.chainId.Cmp(s.chainId) == 0
+}
+
+func (s FeeDelegationSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != FeeDelegatedTxType {
+		return s.EIP2718Signer.Sender(tx)
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V, R, S := tx.RawSignatureValues()
+	V = new(big.Int).Add(V, big.NewInt(27))
+	return recoverPlain(s.Hash(tx), R, S, V, true)
+}
+
+// FeePayer returns the fee payer address of a fee delegated transaction.
+func (s FeeDelegationSigner) FeePayer(tx *Transaction) (common.Address, error) {
+	if tx.Type() != FeeDelegatedTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V, R, S := tx.RawFeePayerSignatureValues()
+	V = new(big.Int).Add(V, big.NewInt(27))
+	return recoverPlain(s.FeePayerHash(tx), R, S, V, true)
+}
+
+// SignatureValues returns signature values. This signature
+// needs to be in the [R || S || V] format where V is 0 or 1.
+func (s FeeDelegationSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != FeeDelegatedTxType {
+		return s.EIP2718Signer.SignatureValues(tx, sig)
+	}
+	if tx.ChainId().Sign() != 0 && tx.ChainId().Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _ = decodeSignature(sig)
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s FeeDelegationSigner) Hash(tx *Transaction) common.Hash {
+	if tx.Type() != FeeDelegatedTxType {
+		return s.EIP2718Signer.Hash(tx)
+	}
+	return prefixedRlpHash(tx.Type(), []interface{}{
+		s.chainId,
+		tx.Nonce(),
+		tx.data.gasPrice(),
+		tx.Gas(),
+		tx.To(),
+		tx.data.value(),
+		tx.data.data(),
+		tx.AccessList(),
+	})
+}
+
+// FeePayerHash returns the hash to be signed by the fee payer, which commits
+// to the sender signature too.
+func (s FeeDelegationSigner) FeePayerHash(tx *Transaction) common.Hash {
+	v, r, ss := tx.RawSignatureValues()
+	return prefixedRlpHash(tx.Type(), []interface{}{
+		s.chainId,
+		tx.Nonce(),
+		tx.data.gasPrice(),
+		tx.Gas(),
+		tx.To(),
+		tx.data.value(),
+		tx.data.data(),
+		tx.AccessList(),
+		v, r, ss,
+	})
+}
+
+// EIP2718Signer implements Signer for typed transactions, delegating legacy
+// transactions to the EIP155 rules. Typed transactions carry their chain id
+// and the plain y parity of the signature as V.
+type EIP2718Signer struct{ EIP155Signer }
+
+func NewEIP2718Signer(chainId *big.Int) EIP2718Signer {
+	return EIP2718Signer{NewEIP155Signer(chainId)}
+}
+
+func (s EIP2718Signer) Equal(s2 Signer) bool {
+	x, ok := s2.(EIP2718Signer)
+	return ok && x.chainId.Cmp(s.chainId) == 0
+}
+
+func (s EIP2718Signer) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() == LegacyTxType {
+		return s.EIP155Signer.Sender(tx)
+	}
+	if tx.Type() != AccessListTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V, R, S := tx.RawSignatureValues()
+	// Typed transactions use the plain 0/1 parity, recoverPlain expects 27/28
+	V = new(big.Int).Add(V, big.NewInt(27))
+	return recoverPlain(s.Hash(tx), R, S, V, true)
+}
+
+// SignatureValues returns signature values. This signature
+// needs to be in the [R || S || V] format where V is 0 or 1.
+func (s EIP2718Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() == LegacyTxType {
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	}
+	if tx.Type() != AccessListTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+	// Check that chain ID of tx matches the signer.
+	if tx.ChainId().Sign() != 0 && tx.ChainId().Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _ = decodeSignature(sig)
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s EIP2718Signer) Hash(tx *Transaction) common.Hash {
+	if tx.Type() != AccessListTxType {
+		return s.EIP155Signer.Hash(tx)
+	}
+	return prefixedRlpHash(tx.Type(), []interface{}{
+		s.chainId,
+		tx.Nonce(),
+		tx.data.gasPrice(),
+		tx.Gas(),
+		tx.To(),
+		tx.data.value(),
+		tx.data.data(),
+		tx.AccessList(),
+	})
+}
+
 // EIP155Transaction implements Signer using the EIP155 rules.
 type EIP155Signer struct {
 	chainId, chainIdMul *big.Int
@@ -125,20 +421,27 @@ func (s EIP155Signer) Equal(s2 Signer) bool {
 var big8 = big.NewInt(8)
 
 func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	if !tx.Protected() {
 		return HomesteadSigner{}.Sender(tx)
 	}
 	if tx.ChainId().Cmp(s.chainId) != 0 {
 		return common.Address{}, ErrInvalidChainId
 	}
-	V := new(big.Int).Sub(tx.data.V, s.chainIdMul)
+	V, R, S := tx.RawSignatureValues()
+	V = new(big.Int).Sub(V, s.chainIdMul)
 	V.Sub(V, big8)
-	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+	return recoverPlain(s.Hash(tx), R, S, V, true)
 }
 
 // WithSignature returns a new transaction with the given signature. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
 	R, S, V, err = HomesteadSigner{}.SignatureValues(tx, sig)
 	if err != nil {
 		return nil, nil, nil, err
@@ -154,12 +457,12 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
 	return rlpHash([]interface{}{
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Recipient,
-		tx.data.Amount,
-		tx.data.Payload,
+		tx.data.nonce(),
+		tx.data.gasPrice(),
+		tx.data.gas(),
+		tx.data.to(),
+		tx.data.value(),
+		tx.data.data(),
 		s.chainId, uint(0), uint(0),
 	})
 }
@@ -180,7 +483,11 @@ func (hs HomesteadSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v
 }
 
 func (hs HomesteadSigner) Sender(tx *Transaction) (common.Address, error) {
-	return recoverPlain(hs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, true)
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	v, r, s := tx.RawSignatureValues()
+	return recoverPlain(hs.Hash(tx), r, s, v, true)
 }
 
 type FrontierSigner struct{}
@@ -193,30 +500,44 @@ func (s FrontierSigner) Equal(s2 Signer) bool {
 // SignatureValues returns signature values. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *big.Int, err error) {
+	if tx.Type() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+	r, s, v = decodeSignature(sig)
+	return r, s, v, nil
+}
+
+// decodeSignature splits a [R || S || V] signature into its values, with V
+// shifted to 27 or 28.
+func decodeSignature(sig []byte) (r, s, v *big.Int) {
 	if len(sig) != 65 {
 		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
 	}
 	r = new(big.Int).SetBytes(sig[:32])
 	s = new(big.Int).SetBytes(sig[32:64])
 	v = new(big.Int).SetBytes([]byte{sig[64] + 27})
-	return r, s, v, nil
+	return r, s, v
 }
 
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
 	return rlpHash([]interface{}{
-		tx.data.AccountNonce,
-		tx.data.Price,
-		tx.data.GasLimit,
-		tx.data.Recipient,
-		tx.data.Amount,
-		tx.data.Payload,
+		tx.data.nonce(),
+		tx.data.gasPrice(),
+		tx.data.gas(),
+		tx.data.to(),
+		tx.data.value(),
+		tx.data.data(),
 	})
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
-	return recoverPlain(fs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, false)
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	v, r, s := tx.RawSignatureValues()
+	return recoverPlain(fs.Hash(tx), r, s, v, false)
 }
 
 func recoverPlain(sighash common.Hash, R, S, Vb *big.Int, homestead bool) (common.Address, error) {
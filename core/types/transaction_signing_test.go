@@ -136,3 +136,120 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestEIP2718Signing(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer := NewEIP2718Signer(big.NewInt(1))
+	tx := accessListTx(t, signer, key)
+
+	if v, _, _ := tx.RawSignatureValues(); v.Uint64() > 1 {
+		t.Errorf("expected plain y parity as V, got %d", v)
+	}
+	from, err := Sender(signer, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != addr {
+		t.Errorf("exected from and address to be equal. Got %x want %x", from, addr)
+	}
+	// Legacy transactions are still signed with the EIP155 rules
+	legacy, err := SignTx(NewTransaction(0, addr, new(big.Int), 0, new(big.Int), nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !legacy.Protected() || legacy.ChainId().Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("expected EIP155 protected legacy transaction")
+	}
+	if from, err := Sender(NewEIP155Signer(big.NewInt(1)), legacy); err != nil || from != addr {
+		t.Errorf("legacy sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	// Typed transactions are bound to their chain id
+	if _, err := Sender(NewEIP2718Signer(big.NewInt(2)), tx); err != ErrInvalidChainId {
+		t.Errorf("expected error %v, got %v", ErrInvalidChainId, err)
+	}
+}
+
+func TestLegacySignersRejectTypedTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx := accessListTx(t, NewEIP2718Signer(big.NewInt(1)), key)
+
+	for _, signer := range []Signer{NewEIP155Signer(big.NewInt(1)), HomesteadSigner{}, FrontierSigner{}} {
+		if _, err := Sender(signer, tx); err != ErrTxTypeNotSupported {
+			t.Errorf("%T: expected error %v, got %v", signer, ErrTxTypeNotSupported, err)
+		}
+		if _, err := SignTx(tx, signer, key); err != ErrTxTypeNotSupported {
+			t.Errorf("%T: expected signing error %v, got %v", signer, ErrTxTypeNotSupported, err)
+		}
+	}
+}
+
+func TestFeeDelegationSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	payerKey, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+
+	signer := NewFeeDelegationSigner(big.NewInt(1))
+	tx, err := SignTx(NewTx(&FeeDelegatedTx{
+		ChainID:  big.NewInt(1),
+		Nonce:    1,
+		GasPrice: big.NewInt(1),
+		Gas:      21000,
+		To:       &common.Address{},
+		Value:    big.NewInt(10),
+	}), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err = SignFeePayer(tx, signer, payerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != addr {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if from, err := FeePayer(signer, tx); err != nil || from != payer {
+		t.Errorf("fee payer mismatch: have %x (%v), want %x", from, err, payer)
+	}
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From() != addr || msg.FeePayer() != payer {
+		t.Errorf("message accounts mismatch: have %x/%x, want %x/%x", msg.From(), msg.FeePayer(), addr, payer)
+	}
+	// Both signatures survive the encoding
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := new(Transaction)
+	if err := dec.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	if from, err := FeePayer(signer, dec); err != nil || from != payer {
+		t.Errorf("decoded fee payer mismatch: have %x (%v), want %x", from, err, payer)
+	}
+	// The fee payer signature commits to the sender signature
+	resigned, err := SignTx(tx, signer, payerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, _ := FeePayer(signer, resigned); from == payer {
+		t.Errorf("fee payer signature valid for a different sender signature")
+	}
+	// Fee delegated transactions are not valid before the fork
+	if _, err := Sender(NewEIP2718Signer(big.NewInt(1)), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("expected error %v, got %v", ErrTxTypeNotSupported, err)
+	}
+	// Other transactions are paid by their sender
+	legacy, err := SignTx(NewTransaction(0, addr, new(big.Int), 0, new(big.Int), nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := FeePayer(signer, legacy); err != nil || from != addr {
+		t.Errorf("legacy fee payer mismatch: have %x (%v), want %x", from, err, addr)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/aaechain/go-aaechain/common"
@@ -144,7 +145,7 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 		}
 	}
 	// Sort the transactions and cross check the nonce ordering
-	txset := NewTransactionsByPriceAndNonce(signer, groups)
+	txset := NewTransactionsByPriceAndNonce(signer, groups, nil)
 
 	txs := Transactions{}
 	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
@@ -233,3 +234,189 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+// accessListTx returns a signed access list transaction used by the typed
+// transaction tests.
+func accessListTx(t *testing.T, signer Signer, key *ecdsa.PrivateKey) *Transaction {
+	to := common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	tx, err := SignTx(NewTx(&AccessListTx{
+		ChainID:  big.NewInt(1),
+		Nonce:    3,
+		GasPrice: big.NewInt(1),
+		Gas:      25000,
+		To:       &to,
+		Value:    big.NewInt(10),
+		Data:     common.FromHex("5544"),
+		AccessList: AccessList{
+			{Address: to, StorageKeys: []common.Hash{{0x01}, {0x02}}},
+		},
+	}), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	return tx
+}
+
+func TestTypedTransactionEncoding(t *testing.T) {
+	key, _ := defaultTestKey()
+	tx := accessListTx(t, NewEIP2718Signer(big.NewInt(1)), key)
+
+	// The canonical encoding is the type byte followed by the RLP payload
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("binary encode error: %v", err)
+	}
+	if bin[0] != AccessListTxType {
+		t.Fatalf("envelope type mismatch, got %d", bin[0])
+	}
+	if hash := crypto.Keccak256Hash(bin); hash != tx.Hash() {
+		t.Errorf("hash mismatch, got %x want %x", tx.Hash(), hash)
+	}
+	if int(tx.Size()) != len(bin) {
+		t.Errorf("size mismatch, got %d want %d", int(tx.Size()), len(bin))
+	}
+	var dec Transaction
+	if err := dec.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("binary decode error: %v", err)
+	}
+	if dec.Hash() != tx.Hash() || dec.Type() != AccessListTxType || len(dec.AccessList()) != 1 {
+		t.Errorf("binary roundtrip mismatch: have %v, want %v", &dec, tx)
+	}
+	// Inside RLP lists the envelope is wrapped into a string
+	enc, err := rlp.EncodeToBytes(Transactions{tx, rightvrsTx})
+	if err != nil {
+		t.Fatalf("rlp encode error: %v", err)
+	}
+	var txs Transactions
+	if err := rlp.DecodeBytes(enc, &txs); err != nil {
+		t.Fatalf("rlp decode error: %v", err)
+	}
+	if len(txs) != 2 || txs[0].Hash() != tx.Hash() || txs[1].Hash() != rightvrsTx.Hash() {
+		t.Errorf("rlp roundtrip mismatch")
+	}
+	if !bytes.Equal(Transactions(txs).GetRlp(0), bin) {
+		t.Errorf("GetRlp mismatch, got %x want %x", Transactions(txs).GetRlp(0), bin)
+	}
+	// Unknown types must be rejected
+	if err := dec.UnmarshalBinary(append([]byte{0x7f}, bin[1:]...)); err != ErrTxTypeNotSupported {
+		t.Errorf("unknown type error mismatch, got %v want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+func TestTypedTransactionJSON(t *testing.T) {
+	key, _ := defaultTestKey()
+	tx := accessListTx(t, NewEIP2718Signer(big.NewInt(1)), key)
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var parsedTx *Transaction
+	if err := json.Unmarshal(data, &parsedTx); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if tx.Hash() != parsedTx.Hash() {
+		t.Errorf("parsed tx differs from original tx, want %v, got %v", tx, parsedTx)
+	}
+}
+
+func TestDynamicFeeTransaction(t *testing.T) {
+	key, _ := defaultTestKey()
+	to := common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	signer := NewDynamicFeeSigner(big.NewInt(1))
+	tx, err := SignTx(NewTx(&DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     3,
+		GasTipCap: big.NewInt(2),
+		GasFeeCap: big.NewInt(10),
+		Gas:       25000,
+		To:        &to,
+		Value:     big.NewInt(10),
+	}), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("sender mismatch: have %x, err %v", from, err)
+	}
+	// Binary and JSON roundtrips must preserve the fee fields
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("binary encode error: %v", err)
+	}
+	if bin[0] != DynamicFeeTxType {
+		t.Fatalf("envelope type mismatch, got %d", bin[0])
+	}
+	var dec Transaction
+	if err := dec.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("binary decode error: %v", err)
+	}
+	if dec.Hash() != tx.Hash() || dec.GasTipCap().Cmp(big.NewInt(2)) != 0 || dec.GasFeeCap().Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("binary roundtrip mismatch: have %v, want %v", &dec, tx)
+	}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var parsedTx *Transaction
+	if err := json.Unmarshal(data, &parsedTx); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if tx.Hash() != parsedTx.Hash() {
+		t.Errorf("parsed tx differs from original tx, want %v, got %v", tx, parsedTx)
+	}
+	// The effective tip is capped by both the tip and the fee cap
+	tests := []struct {
+		baseFee *big.Int
+		tip     *big.Int
+		err     error
+	}{
+		{nil, big.NewInt(2), nil},
+		{big.NewInt(5), big.NewInt(2), nil},
+		{big.NewInt(9), big.NewInt(1), nil},
+		{big.NewInt(10), big.NewInt(0), nil},
+		{big.NewInt(11), nil, ErrGasFeeCapTooLow},
+	}
+	for i, tt := range tests {
+		tip, err := tx.EffectiveGasTip(tt.baseFee)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if tt.tip != nil && tip.Cmp(tt.tip) != 0 {
+			t.Errorf("test %d: tip mismatch: have %v, want %v", i, tip, tt.tip)
+		}
+	}
+}
+
+func TestTransactionPriceSortBaseFee(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := NewDynamicFeeSigner(big.NewInt(1))
+
+	// With a base fee of 10, the effective tips are 5, 1 and none
+	caps := []struct{ tip, feeCap int64 }{{5, 20}, {8, 11}, {20, 9}}
+	groups := map[common.Address]Transactions{}
+	for i, key := range keys {
+		tx, _ := SignTx(NewTx(&DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			GasTipCap: big.NewInt(caps[i].tip),
+			GasFeeCap: big.NewInt(caps[i].feeCap),
+			Gas:       21000,
+			To:        &common.Address{},
+		}), signer, key)
+		groups[crypto.PubkeyToAddress(key.PublicKey)] = Transactions{tx}
+	}
+	txset := NewTransactionsByPriceAndNonce(signer, groups, big.NewInt(10))
+
+	var tips []int64
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		tips = append(tips, tx.GasTipCap().Int64())
+		txset.Shift()
+	}
+	if !reflect.DeepEqual(tips, []int64{5, 8}) {
+		t.Errorf("transaction order mismatch: have %v, want [5 8]", tips)
+	}
+}
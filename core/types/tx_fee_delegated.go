@@ -0,0 +1,117 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/aaechain/go-aaechain/common"
+)
+
+// FeeDelegatedTx is the data of a sponsored transaction. It is signed by the
+// sender, who provides the nonce and value, and then by a fee payer, who pays
+// for the gas. The fee payer signs over the sender signature too, so it only
+// sponsors this exact transaction.
+type FeeDelegatedTx struct {
+	ChainID    *big.Int        // destination chain ID
+	Nonce      uint64          // nonce of sender account
+	GasPrice   *big.Int        // wei per gas
+	Gas        uint64          // gas limit
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int        // wei amount
+	Data       []byte          // contract invocation input data
+	AccessList AccessList      // access list
+	V, R, S    *big.Int        // sender signature values
+
+	FeePayerV, FeePayerR, FeePayerS *big.Int // fee payer signature values
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *FeeDelegatedTx) copy() TxData {
+	cpy := &FeeDelegatedTx{
+		Nonce: tx.Nonce,
+		To:    copyAddressPtr(tx.To),
+		Data:  common.CopyBytes(tx.Data),
+		Gas:   tx.Gas,
+		// These are copied below.
+		AccessList: make(AccessList, len(tx.AccessList)),
+		Value:      new(big.Int),
+		ChainID:    new(big.Int),
+		GasPrice:   new(big.Int),
+		V:          new(big.Int),
+		R:          new(big.Int),
+		S:          new(big.Int),
+		FeePayerV:  new(big.Int),
+		FeePayerR:  new(big.Int),
+		FeePayerS:  new(big.Int),
+	}
+	for i, tuple := range tx.AccessList {
+		cpy.AccessList[i] = AccessTuple{
+			Address:     tuple.Address,
+			StorageKeys: append([]common.Hash(nil), tuple.StorageKeys...),
+		}
+	}
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasPrice != nil {
+		cpy.GasPrice.Set(tx.GasPrice)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	if tx.FeePayerV != nil {
+		cpy.FeePayerV.Set(tx.FeePayerV)
+	}
+	if tx.FeePayerR != nil {
+		cpy.FeePayerR.Set(tx.FeePayerR)
+	}
+	if tx.FeePayerS != nil {
+		cpy.FeePayerS.Set(tx.FeePayerS)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *FeeDelegatedTx) txType() byte           { return FeeDelegatedTxType }
+func (tx *FeeDelegatedTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *FeeDelegatedTx) accessList() AccessList { return tx.AccessList }
+func (tx *FeeDelegatedTx) data() []byte           { return tx.Data }
+func (tx *FeeDelegatedTx) gas() uint64            { return tx.Gas }
+func (tx *FeeDelegatedTx) gasPrice() *big.Int     { return tx.GasPrice }
+func (tx *FeeDelegatedTx) gasTipCap() *big.Int    { return tx.GasPrice }
+func (tx *FeeDelegatedTx) gasFeeCap() *big.Int    { return tx.GasPrice }
+func (tx *FeeDelegatedTx) value() *big.Int        { return tx.Value }
+func (tx *FeeDelegatedTx) nonce() uint64          { return tx.Nonce }
+func (tx *FeeDelegatedTx) to() *common.Address    { return tx.To }
+
+func (tx *FeeDelegatedTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *FeeDelegatedTx) setSignatureValues(v, r, s *big.Int) {
+	tx.V, tx.R, tx.S = v, r, s
+}
@@ -50,6 +50,7 @@ type Contract struct {
 	self          ContractRef
 
 	jumpdests destinations // result of JUMPDEST analysis.
+	container *Container   // parsed EOF container, nil for legacy code
 
 	Code     []byte
 	CodeHash common.Hash
@@ -102,8 +103,12 @@ func (c *Contract) GetOp(n uint64) OpCode {
 	return OpCode(c.GetByte(n))
 }
 
-// GetByte returns the n'th byte in the contract's byte array
+// GetByte returns the n'th byte in the contract's byte array. Bytes past the
+// code section of an EOF container are returned as STOP.
 func (c *Contract) GetByte(n uint64) byte {
+	if c.container != nil && n >= c.container.codeEnd() {
+		return 0
+	}
 	if n < uint64(len(c.Code)) {
 		return c.Code[n]
 	}
@@ -111,6 +116,15 @@ func (c *Contract) GetByte(n uint64) byte {
 	return 0
 }
 
+// validJumpdest checks whaaeer dest is a valid jump destination, using the
+// code validation of EOF containers instead of JUMPDEST analysis.
+func (c *Contract) validJumpdest(dest *big.Int) bool {
+	if c.container != nil {
+		return c.container.validJumpdest(dest)
+	}
+	return c.jumpdests.has(c.CodeHash, c.Code, dest)
+}
+
 // Caller returns the caller of the contract.
 //
 // Caller will recursively call caller when the contract is a delegate
@@ -59,6 +59,20 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{8}): &bn256Pairing{},
 }
 
+// ActivePrecompiles returns the addresses of the pre-compiled contracts active
+// under the given rules.
+func ActivePrecompiles(rules params.Rules) []common.Address {
+	precompiles := PrecompiledContractsHomestead
+	if rules.IsByzantium {
+		precompiles = PrecompiledContractsByzantium
+	}
+	addrs := make([]common.Address, 0, len(precompiles))
+	for addr := range precompiles {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -0,0 +1,212 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/aaechain/go-aaechain/common"
+)
+
+// EVM object format (EOF) containers start with the magic 0xef00 followed by a
+// version byte and a header listing the sizes of their sections:
+//
+//	magic | version | 0x01 code_size | [0x02 data_size] | 0x00 | code | [data]
+//
+// Sizes are 2-byte big endian, non-zero values. Containers are validated once
+// at deploy time: undefined opcodes and truncated PUSH immediates are rejected,
+// as are JUMPDEST bytes inside PUSH immediates, so that a jump destination is
+// valid iff it points to a JUMPDEST byte in the code section and no runtime
+// analysis is needed. After the fork any new code starting with 0xef must be a
+// valid container.
+//
+// Legacy code deployed before the fork may still start with the magic, so the
+// addresses of validated containers are recorded in the storage of a system
+// account and only code deployed at a recorded address is run as a container.
+const (
+	eofFormatByte = 0xef
+	eofMagicByte  = 0x00
+	eofVersion1   = 0x01
+
+	eofKindTerminator = 0x00
+	eofKindCode       = 0x01
+	eofKindData       = 0x02
+)
+
+var (
+	errInvalidMagic         = errors.New("invalid container magic")
+	errInvalidVersion       = errors.New("invalid container version")
+	errIncompleteHeader     = errors.New("incomplete container header")
+	errInvalidSectionKind   = errors.New("invalid container section kind")
+	errInvalidSectionSize   = errors.New("invalid container section size")
+	errMissingCodeSection   = errors.New("missing container code section")
+	errInvalidContainerSize = errors.New("container size mismatch")
+	errUndefinedInstruction = errors.New("undefined instruction")
+	errTruncatedImmediate   = errors.New("truncated PUSH immediate")
+	errJumpdestInImmediate  = errors.New("JUMPDEST inside PUSH immediate")
+	errInvalidCodePrefix    = errors.New("invalid code: must not begin with 0xef")
+)
+
+var (
+	// eofRegistryAddress is the system account recording the addresses of the
+	// containers validated on deployment.
+	eofRegistryAddress = common.HexToAddress("0x000000000000000000000000000000000000ef00")
+
+	// eofValidatedMarker is the registry value of validated container addresses.
+	eofValidatedMarker = common.BigToHash(big.NewInt(1))
+)
+
+// Container is a parsed EOF container, referencing the sections of the
+// original code.
+type Container struct {
+	Code []byte // Code section, executed starting from its first byte
+	Data []byte // Data section, never executed
+
+	codeOffset uint64 // Offset of the code section in the container
+}
+
+// HasEOFMagic returns whaaeer the code starts with the EOF magic.
+func HasEOFMagic(code []byte) bool {
+	return len(code) >= 2 && code[0] == eofFormatByte && code[1] == eofMagicByte
+}
+
+// ParseContainer parses the header of an EOF container and checks that the
+// section sizes add up to its length. The code itself isn't validated.
+func ParseContainer(b []byte) (*Container, error) {
+	if !HasEOFMagic(b) {
+		return nil, errInvalidMagic
+	}
+	if len(b) < 3 {
+		return nil, errIncompleteHeader
+	}
+	if b[2] != eofVersion1 {
+		return nil, errInvalidVersion
+	}
+	var (
+		codeSize, dataSize uint64
+		pos                = 3
+	)
+	for {
+		if pos >= len(b) {
+			return nil, errIncompleteHeader
+		}
+		kind := b[pos]
+		if kind == eofKindTerminator {
+			pos++
+			break
+		}
+		if pos+3 > len(b) {
+			return nil, errIncompleteHeader
+		}
+		size := uint64(binary.BigEndian.Uint16(b[pos+1:]))
+		if size == 0 {
+			return nil, errInvalidSectionSize
+		}
+		switch {
+		case kind == eofKindCode && codeSize == 0:
+			codeSize = size
+		case kind == eofKindData && codeSize != 0 && dataSize == 0:
+			dataSize = size
+		default:
+			return nil, errInvalidSectionKind
+		}
+		pos += 3
+	}
+	if codeSize == 0 {
+		return nil, errMissingCodeSection
+	}
+	start := uint64(pos)
+	if start+codeSize+dataSize != uint64(len(b)) {
+		return nil, errInvalidContainerSize
+	}
+	return &Container{
+		Code:       b[start : start+codeSize],
+		Data:       b[start+codeSize:],
+		codeOffset: start,
+	}, nil
+}
+
+// validateContainer parses an EOF container and validates its code section
+// against the given instruction set.
+func validateContainer(b []byte, jt *[256]operation) (*Container, error) {
+	c, err := ParseContainer(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCode(c.Code, jt); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// validateCode checks that the code only contains instructions defined in the
+// instruction set, with complete PUSH immediates not containing any JUMPDEST.
+func validateCode(code []byte, jt *[256]operation) error {
+	for pc := 0; pc < len(code); pc++ {
+		op := OpCode(code[pc])
+		if !jt[op].valid {
+			return errUndefinedInstruction
+		}
+		if op >= PUSH1 && op <= PUSH32 {
+			size := int(op - PUSH1 + 1)
+			if pc+size >= len(code) {
+				return errTruncatedImmediate
+			}
+			if bytes.IndexByte(code[pc+1:pc+1+size], byte(JUMPDEST)) >= 0 {
+				return errJumpdestInImmediate
+			}
+			pc += size
+		}
+	}
+	return nil
+}
+
+// markContainer records that the code deployed at addr is a validated container.
+func markContainer(db StateDB, addr common.Address) {
+	// Keep the registry non-empty so it isn't removed as an empty account
+	if db.GetNonce(eofRegistryAddress) == 0 {
+		db.SetNonce(eofRegistryAddress, 1)
+	}
+	db.Seaaeate(eofRegistryAddress, addr.Hash(), eofValidatedMarker)
+}
+
+// isMarkedContainer returns whaaeer the code deployed at addr was validated as
+// a container on deployment.
+func isMarkedContainer(db StateDB, addr common.Address) bool {
+	return db.Geaaeate(eofRegistryAddress, addr.Hash()) == eofValidatedMarker
+}
+
+// codeEnd returns the offset following the code section in the container.
+func (c *Container) codeEnd() uint64 {
+	return c.codeOffset + uint64(len(c.Code))
+}
+
+// validJumpdest checks whaaeer dest points to a JUMPDEST in the code section.
+// Validation guarantees such a byte can't be part of a PUSH immediate.
+func (c *Container) validJumpdest(dest *big.Int) bool {
+	if dest.BitLen() >= 63 {
+		return false
+	}
+	udest := dest.Uint64()
+	if udest < c.codeOffset || udest >= c.codeEnd() {
+		return false
+	}
+	return OpCode(c.Code[udest-c.codeOffset]) == JUMPDEST
+}
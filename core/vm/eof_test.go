@@ -0,0 +1,183 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aaechain/go-aaechain/aaedb"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/core/state"
+	"github.com/aaechain/go-aaechain/params"
+)
+
+// eofRuntime is a container whose code jumps over a STOP and stores 0x2a in
+// slot 0, followed by a single byte data section.
+var eofRuntime = common.FromHex("ef000101000b020001" + "00" + "600e5600" + "5b602a60005500" + "aa")
+
+func TestParseContainer(t *testing.T) {
+	tests := []struct {
+		code string
+		err  error
+	}{
+		{"ef00010100010000", nil},
+		{"ef00010100010200020000aabb", nil},
+		{"", errInvalidMagic},
+		{"ef01010100010000", errInvalidMagic},
+		{"ef00", errIncompleteHeader},
+		{"ef00020100010000", errInvalidVersion},
+		{"ef000101", errIncompleteHeader},
+		{"ef0001010001", errIncompleteHeader},
+		{"ef000101000000", errInvalidSectionSize},
+		{"ef00010200010000", errInvalidSectionKind},
+		{"ef000101000101000100", errInvalidSectionKind},
+		{"ef00010300010000", errInvalidSectionKind},
+		{"ef000100", errMissingCodeSection},
+		{"ef000101000200", errInvalidContainerSize},
+		{"ef0001010001000000", errInvalidContainerSize},
+	}
+	for i, tt := range tests {
+		if _, err := ParseContainer(common.FromHex(tt.code)); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	c, err := ParseContainer(eofRuntime)
+	if err != nil {
+		t.Fatalf("failed to parse container: %v", err)
+	}
+	if len(c.Code) != 11 || len(c.Data) != 1 || c.codeOffset != 10 {
+		t.Errorf("section mismatch: code %x, data %x, offset %d", c.Code, c.Data, c.codeOffset)
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	tests := []struct {
+		code  string
+		valid bool
+	}{
+		{"00", true},
+		{"6001600101", true},
+		{"7f" + "0000000000000000000000000000000000000000000000000000000000000000", true},
+		{"0c", false},         // undefined instruction
+		{"1b", false},         // undefined before constantinople
+		{"61aa", false},       // truncated immediate
+		{"7f00", false},       // truncated immediate
+		{"605b56", false},     // JUMPDEST in immediate
+		{"62005b0056", false}, // JUMPDEST in immediate
+		{"5b60005600", true},  // regular JUMPDEST
+		{"600160020160005260206000f3", true},
+	}
+	for i, tt := range tests {
+		err := validateCode(common.FromHex(tt.code), &byzantiumInstructionSet)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v (%v), want %v", i, valid, err, tt.valid)
+		}
+	}
+}
+
+func TestEOFDeployment(t *testing.T) {
+	// deployer returns the code appended to it as contract code
+	deployer := func(code []byte) []byte {
+		return append([]byte{
+			byte(PUSH1), byte(len(code)), byte(DUP1), byte(PUSH1), 11, byte(PUSH1), 0, byte(CODECOPY),
+			byte(PUSH1), 0, byte(RETURN),
+		}, code...)
+	}
+	tests := []struct {
+		eofBlock *big.Int
+		code     []byte
+		err      error
+	}{
+		// Before the fork any code can be deployed
+		{nil, eofRuntime, nil},
+		{nil, common.FromHex("ef"), nil},
+		{nil, common.FromHex("ef0001010001000c"), nil},
+		// After the fork code starting with 0xef must be a valid container
+		{big.NewInt(0), eofRuntime, nil},
+		{big.NewInt(0), common.FromHex("6000"), nil},
+		{big.NewInt(0), common.FromHex("ef"), errInvalidCodePrefix},
+		{big.NewInt(0), common.FromHex("ef01"), errInvalidCodePrefix},
+		{big.NewInt(0), common.FromHex("ef00020100010000"), errInvalidVersion},
+		{big.NewInt(0), common.FromHex("ef0001010001000c"), errUndefinedInstruction},
+	}
+	for i, tt := range tests {
+		db, _ := aaedb.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+		config := *params.TestChainConfig
+		config.EOFBlock = tt.eofBlock
+		evm := NewEVM(Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: new(big.Int),
+		}, statedb, &config, Config{})
+
+		ret, addr, _, err := evm.Create(AccountRef(common.Address{1}), deployer(tt.code), 1000000, new(big.Int))
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err == nil && string(statedb.GetCode(addr)) != string(ret) {
+			t.Errorf("test %d: code mismatch: have %x, want %x", i, statedb.GetCode(addr), ret)
+		}
+		// Only containers validated on deployment are recorded
+		if marked, want := isMarkedContainer(statedb, addr), err == nil && tt.eofBlock != nil && HasEOFMagic(tt.code); marked != want {
+			t.Errorf("test %d: container marker mismatch: have %v, want %v", i, marked, want)
+		}
+	}
+}
+
+func TestEOFExecution(t *testing.T) {
+	db, _ := aaedb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	address := common.Address{0x0a}
+	statedb.SetCode(address, eofRuntime)
+	markContainer(statedb, address)
+
+	// The same code deployed before the fork is legacy code
+	legacy := common.Address{0x0b}
+	statedb.SetCode(legacy, eofRuntime)
+
+	config := *params.TestChainConfig
+	config.EOFBlock = new(big.Int)
+	evm := NewEVM(Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}, statedb, &config, Config{})
+
+	// Execution starts at the code section and jumps straight to the JUMPDEST
+	if _, _, err := evm.Call(AccountRef(common.Address{1}), address, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if val := statedb.Geaaeate(address, common.Hash{}); val != common.BigToHash(big.NewInt(0x2a)) {
+		t.Errorf("storage mismatch: have %x, want 2a", val)
+	}
+	// Unrecorded code hits the undefined 0xef opcode
+	if _, _, err := evm.Call(AccountRef(common.Address{1}), legacy, nil, 100000, new(big.Int)); err == nil {
+		t.Errorf("legacy code executed as container")
+	}
+	// Jumps outside the code section are invalid
+	contract := &Contract{Code: eofRuntime}
+	contract.container, _ = ParseContainer(eofRuntime)
+	for _, dest := range []int64{0, 4, 14, 21} {
+		if valid := contract.validJumpdest(big.NewInt(dest)); valid != (dest == 14) {
+			t.Errorf("jump destination %d validity mismatch: have %v", dest, valid)
+		}
+	}
+}
@@ -73,6 +73,7 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+	BaseFee     *big.Int       // Base fee of the block, nil before the base fee fork
 }
 
 // EVM is the aaechain Virtual Machine base object and provides
@@ -328,6 +329,10 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 
 	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	// The created address is warm regardless of whaaeer the creation succeeds.
+	if evm.chainRules.IsAccessList {
+		evm.StateDB.AddAddressToAccessList(contractAddr)
+	}
 	contractHash := evm.StateDB.GetCodeHash(contractAddr)
 	if evm.StateDB.GetNonce(contractAddr) != 0 || (contractHash != (common.Hash{}) && contractHash != emptyCodeHash) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
@@ -346,6 +351,16 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	contract := NewContract(caller, AccountRef(contractAddr), value, gas)
 	contract.SetCallCode(&contractAddr, crypto.Keccak256Hash(code), code)
 
+	// Reject malformed EOF init code before running it
+	if evm.chainRules.IsEOF && HasEOFMagic(code) {
+		c, err := validateContainer(code, &evm.interpreter.cfg.JumpTable)
+		if err != nil {
+			evm.StateDB.RevertToSnapshot(snapshot)
+			return nil, contractAddr, 0, err
+		}
+		contract.container = c
+	}
+
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, contractAddr, gas, nil
 	}
@@ -357,6 +372,17 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 
 	ret, err = run(evm, contract, nil)
 
+	// Deployed code starting with 0xef must be a valid EOF container
+	container := false
+	if err == nil && evm.chainRules.IsEOF && len(ret) > 0 && ret[0] == eofFormatByte {
+		if !HasEOFMagic(ret) {
+			err = errInvalidCodePrefix
+		} else if _, verr := validateContainer(ret, &evm.interpreter.cfg.JumpTable); verr != nil {
+			err = verr
+		} else {
+			container = true
+		}
+	}
 	// check whaaeer the max code size has been exceeded
 	maxCodeSizeExceeded := evm.ChainConfig().IsEIP158(evm.BlockNumber) && len(ret) > params.MaxCodeSize
 	// if the contract creation ran successfully and no errors were returned
@@ -367,6 +393,9 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 		createDataGas := uint64(len(ret)) * params.CreateDataGas
 		if contract.UseGas(createDataGas) {
 			evm.StateDB.SetCode(contractAddr, ret)
+			if container {
+				markContainer(evm.StateDB, contractAddr)
+			}
 		} else {
 			err = ErrCodeStoreOutOfGas
 		}
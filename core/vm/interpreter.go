@@ -75,6 +75,9 @@ func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 		default:
 			cfg.JumpTable = frontierInstructionSet
 		}
+		if evm.chainRules.IsAccessList {
+			enableAccessList(&cfg.JumpTable)
+		}
 	}
 
 	return &Interpreter{
@@ -137,6 +140,20 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 	)
 	contract.Input = input
 
+	// Execute EOF containers from the start of their code section. Containers
+	// were validated at deploy time, so only their header needs to be parsed.
+	// Init code is validated by Create, deployed code only if it was recorded
+	// as a container on deployment.
+	if contract.container == nil && in.evm.chainRules.IsEOF && HasEOFMagic(contract.Code) &&
+		contract.CodeAddr != nil && isMarkedContainer(in.evm.StateDB, *contract.CodeAddr) {
+		if c, err := ParseContainer(contract.Code); err == nil {
+			contract.container = c
+		}
+	}
+	if contract.container != nil {
+		pc = contract.container.codeOffset
+	}
+
 	if in.cfg.Debug {
 		defer func() {
 			if err != nil {
@@ -0,0 +1,146 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/common/math"
+	"github.com/aaechain/go-aaechain/params"
+)
+
+// enableAccessList switches the state accessing instructions of the jump
+// table to the access list gas accounting. The gas table of the access list
+// phase prices all accesses as warm, the functions below add the cold
+// surcharge on the first access to an account or slot in a transaction.
+func enableAccessList(jt *[256]operation) {
+	jt[SLOAD].gasCost = gasSLoadAccessList
+	jt[SSTORE].gasCost = gasSStoreAccessList
+	jt[BALANCE].gasCost = makeAccountAccessGas(gasBalance)
+	jt[EXTCODESIZE].gasCost = makeAccountAccessGas(gasExtCodeSize)
+	jt[EXTCODECOPY].gasCost = makeAccountAccessGas(gasExtCodeCopy)
+	jt[CALL].gasCost = makeCallAccessGas(gasCall)
+	jt[CALLCODE].gasCost = makeCallAccessGas(gasCallCode)
+	jt[SELFDESTRUCT].gasCost = gasSuicideAccessList
+	if jt[DELEGATECALL].valid {
+		jt[DELEGATECALL].gasCost = makeCallAccessGas(gasDelegateCall)
+	}
+	if jt[STATICCALL].valid {
+		jt[STATICCALL].gasCost = makeCallAccessGas(gasStaticCall)
+	}
+}
+
+// gasSLoadAccessList charges the warm read price for slots already accessed in
+// the transaction and the cold price otherwise, adding the slot to the access
+// list.
+func gasSLoadAccessList(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	slot := common.BigToHash(stack.Back(0))
+	if _, slotPresent := evm.StateDB.SlotInAccessList(contract.Address(), slot); !slotPresent {
+		evm.StateDB.AddSlotToAccessList(contract.Address(), slot)
+		return params.ColdSloadCost, nil
+	}
+	return gt.SLoad, nil
+}
+
+// gasSStoreAccessList charges the regular store price plus the cold read price
+// if the slot was not accessed yet in the transaction.
+func gasSStoreAccessList(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	var (
+		slot = common.BigToHash(stack.Back(0))
+		cost uint64
+	)
+	if _, slotPresent := evm.StateDB.SlotInAccessList(contract.Address(), slot); !slotPresent {
+		evm.StateDB.AddSlotToAccessList(contract.Address(), slot)
+		cost = params.ColdSloadCost
+	}
+	gas, err := gasSStore(gt, evm, contract, stack, mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	var overflow bool
+	if gas, overflow = math.SafeAdd(gas, cost); overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
+
+// makeAccountAccessGas wraps the gas function of an instruction reading the
+// account on top of the stack, adding the cold surcharge on its first access.
+func makeAccountAccessGas(gasFn gasFunc) gasFunc {
+	return func(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		addr := common.BigToAddress(stack.Back(0))
+		gas, err := gasFn(gt, evm, contract, stack, mem, memorySize)
+		if err != nil || evm.StateDB.AddressInAccessList(addr) {
+			return gas, err
+		}
+		evm.StateDB.AddAddressToAccessList(addr)
+
+		var overflow bool
+		if gas, overflow = math.SafeAdd(gas, params.ColdAccountAccessCost-params.WarmStorageReadCost); overflow {
+			return 0, errGasUintOverflow
+		}
+		return gas, nil
+	}
+}
+
+// makeCallAccessGas wraps the gas function of a call variant, adding the cold
+// surcharge on the first access to the callee. The surcharge is deducted before
+// the gas passed on to the callee is computed, so that it can't be forwarded.
+func makeCallAccessGas(gasFn gasFunc) gasFunc {
+	return func(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		var (
+			addr     = common.BigToAddress(stack.Back(1))
+			coldCost = params.ColdAccountAccessCost - params.WarmStorageReadCost
+			warm     = evm.StateDB.AddressInAccessList(addr)
+		)
+		if !warm {
+			evm.StateDB.AddAddressToAccessList(addr)
+			if !contract.UseGas(coldCost) {
+				return 0, ErrOutOfGas
+			}
+		}
+		gas, err := gasFn(gt, evm, contract, stack, mem, memorySize)
+		if warm || err != nil {
+			return gas, err
+		}
+		// The surcharge is given back here and charged by the interpreter as
+		// part of the dynamic cost, so that tracers report it correctly.
+		contract.Gas += coldCost
+
+		var overflow bool
+		if gas, overflow = math.SafeAdd(gas, coldCost); overflow {
+			return 0, errGasUintOverflow
+		}
+		return gas, nil
+	}
+}
+
+// gasSuicideAccessList charges the full cold account access price if the
+// beneficiary was not accessed yet in the transaction.
+func gasSuicideAccessList(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	addr := common.BigToAddress(stack.Back(0))
+	gas, err := gasSuicide(gt, evm, contract, stack, mem, memorySize)
+	if err != nil || evm.StateDB.AddressInAccessList(addr) {
+		return gas, err
+	}
+	evm.StateDB.AddAddressToAccessList(addr)
+
+	var overflow bool
+	if gas, overflow = math.SafeAdd(gas, params.ColdAccountAccessCost); overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/aaechain/go-aaechain/core/state"
 	"github.com/aaechain/go-aaechain/core/vm"
 	"github.com/aaechain/go-aaechain/aaedb"
+	"github.com/aaechain/go-aaechain/params"
 )
 
 func TestDefaults(t *testing.T) {
@@ -117,6 +118,47 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestAccessListGas(t *testing.T) {
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 0xff, byte(vm.BALANCE), byte(vm.POP),
+		byte(vm.PUSH1), 0xff, byte(vm.BALANCE), byte(vm.POP),
+		byte(vm.STOP),
+	}
+	tests := []struct {
+		accessListBlock *big.Int
+		gas             uint64
+	}{
+		// Flat EIP158 prices for every access
+		{nil, 4*3 + 2*200 + 2*400 + 4*2},
+		// Cold prices on the first access, warm on the second one
+		{big.NewInt(0), 4*3 + params.ColdSloadCost + params.ColdAccountAccessCost + 2*params.WarmStorageReadCost + 4*2},
+	}
+	for i, tt := range tests {
+		db, _ := aaedb.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		address := common.HexToAddress("0x0a")
+		statedb.SetCode(address, code)
+
+		config := &params.ChainConfig{
+			ChainId:         big.NewInt(1),
+			HomesteadBlock:  new(big.Int),
+			EIP150Block:     new(big.Int),
+			EIP155Block:     new(big.Int),
+			EIP158Block:     new(big.Int),
+			AccessListBlock: tt.accessListBlock,
+		}
+		_, leftOverGas, err := Call(address, nil, &Config{State: statedb, ChainConfig: config, GasLimit: 100000})
+		if err != nil {
+			t.Fatalf("test %d: call failed: %v", i, err)
+		}
+		if used := 100000 - leftOverGas; used != tt.gas {
+			t.Errorf("test %d: gas used mismatch: have %d, want %d", i, used, tt.gas)
+		}
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
@@ -371,6 +371,12 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// Sponsored transactions need the signature of the fee payer too
+	if args.FeePayer != nil {
+		if signed, err = signFeePayer(s.b, *args.FeePayer, signed); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	return submitTransaction(ctx, s.b, signed)
 }
 
@@ -384,7 +390,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if args.Gas == nil {
 		return nil, fmt.Errorf("gas not specified")
 	}
-	if args.GasPrice == nil {
+	if args.GasPrice == nil && args.MaxFeePerGas == nil {
 		return nil, fmt.Errorf("gasPrice not specified")
 	}
 	if args.Nonce == nil {
@@ -394,7 +400,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(signed)
+	data, err := signed.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -609,6 +615,22 @@ type CallArgs struct {
 	GasPrice hexutil.Big     `json:"gasPrice"`
 	Value    hexutil.Big     `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
+
+	AccessList *types.AccessList `json:"accessList"`
+}
+
+// callFrom returns the sender of a call, defaulting to the first account of the
+// first wallet if none is specified.
+func (s *PublicBlockChainAPI) callFrom(args CallArgs) common.Address {
+	if args.From != (common.Address{}) {
+		return args.From
+	}
+	if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+		if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+			return accounts[0].Address
+		}
+	}
+	return common.Address{}
 }
 
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
@@ -619,14 +641,8 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 		return nil, 0, false, err
 	}
 	// Set sender address or use a default if none specified
-	addr := args.From
-	if addr == (common.Address{}) {
-		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
-			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
-				addr = accounts[0].Address
-			}
-		}
-	}
+	addr := s.callFrom(args)
+
 	// Set default gas & gas price if none were set
 	gas, gasPrice := uint64(args.Gas), args.GasPrice.ToInt()
 	if gas == 0 {
@@ -634,10 +650,17 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	}
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
+		if header.BaseFee != nil && gasPrice.Cmp(header.BaseFee) < 0 {
+			gasPrice = new(big.Int).Set(header.BaseFee)
+		}
 	}
 
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, accessList, false)
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -680,6 +703,59 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr r
 	return (hexutil.Bytes)(result), err
 }
 
+// accessListResult is the result of eth_createAccessList.
+type accessListResult struct {
+	AccessList *types.AccessList `json:"accessList"`
+	Error      string            `json:"error,omitempty"`
+	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+}
+
+// CreateAccessList creates an access list for the given transaction, along with
+// the gas it uses when executed with that access list. The transaction is run
+// repeatedly until the accounts and storage slots it touches don't change.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (*accessListResult, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Determine the accounts that are warm regardless of the access list
+	args.From = s.callFrom(args)
+
+	var to common.Address
+	if args.To != nil {
+		to = *args.To
+	} else {
+		to = crypto.CreateAddress(args.From, state.GetNonce(args.From))
+	}
+	precompiles := vm.ActivePrecompiles(s.b.ChainConfig().Rules(header.Number))
+
+	var prevTracer *vm.AccessListTracer
+	if args.AccessList != nil {
+		prevTracer = vm.NewAccessListTracer(*args.AccessList, args.From, to, precompiles)
+	} else {
+		prevTracer = vm.NewAccessListTracer(nil, args.From, to, precompiles)
+	}
+	for {
+		// Run the transaction with the access list collected so far
+		accessList := prevTracer.AccessList()
+		args.AccessList = &accessList
+
+		tracer := vm.NewAccessListTracer(accessList, args.From, to, precompiles)
+		_, gas, failed, err := s.doCall(ctx, args, blockNr, vm.Config{Debug: true, Tracer: tracer}, 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if tracer.Equal(prevTracer) {
+			result := &accessListResult{AccessList: &accessList, GasUsed: hexutil.Uint64(gas)}
+			if failed {
+				result.Error = "execution failed"
+			}
+			return result, nil
+		}
+		prevTracer = tracer
+	}
+}
+
 // EstimateGas returns an estimate of the amount of gas needed to execute the
 // given transaction against the current pending block.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
@@ -815,6 +891,9 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 		"transactionsRoot": head.TxHash,
 		"receiptsRoot":     head.ReceiptHash,
 	}
+	if head.BaseFee != nil {
+		fields["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee)
+	}
 
 	if inclTx {
 		formatTx := func(tx *types.Transaction) (interface{}, error) {
@@ -850,20 +929,29 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
-	BlockHash        common.Hash     `json:"blockHash"`
-	BlockNumber      *hexutil.Big    `json:"blockNumber"`
-	From             common.Address  `json:"from"`
-	Gas              hexutil.Uint64  `json:"gas"`
-	GasPrice         *hexutil.Big    `json:"gasPrice"`
-	Hash             common.Hash     `json:"hash"`
-	Input            hexutil.Bytes   `json:"input"`
-	Nonce            hexutil.Uint64  `json:"nonce"`
-	To               *common.Address `json:"to"`
-	TransactionIndex hexutil.Uint    `json:"transactionIndex"`
-	Value            *hexutil.Big    `json:"value"`
-	V                *hexutil.Big    `json:"v"`
-	R                *hexutil.Big    `json:"r"`
-	S                *hexutil.Big    `json:"s"`
+	BlockHash        common.Hash       `json:"blockHash"`
+	BlockNumber      *hexutil.Big      `json:"blockNumber"`
+	From             common.Address    `json:"from"`
+	Gas              hexutil.Uint64    `json:"gas"`
+	GasPrice         *hexutil.Big      `json:"gasPrice"`
+	Hash             common.Hash       `json:"hash"`
+	Input            hexutil.Bytes     `json:"input"`
+	Nonce            hexutil.Uint64    `json:"nonce"`
+	To               *common.Address   `json:"to"`
+	TransactionIndex hexutil.Uint      `json:"transactionIndex"`
+	Value            *hexutil.Big      `json:"value"`
+	V                *hexutil.Big      `json:"v"`
+	R                *hexutil.Big      `json:"r"`
+	S                *hexutil.Big      `json:"s"`
+	Type             hexutil.Uint64    `json:"type"`
+	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	Accesses         *types.AccessList `json:"accessList,omitempty"`
+	GasFeeCap        *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	GasTipCap        *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	FeePayer         *common.Address   `json:"feePayer,omitempty"`
+	FeePayerV        *hexutil.Big      `json:"feePayerV,omitempty"`
+	FeePayerR        *hexutil.Big      `json:"feePayerR,omitempty"`
+	FeePayerS        *hexutil.Big      `json:"feePayerS,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -871,7 +959,7 @@ type RPCTransaction struct {
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.LatestSignerForChainID(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -888,6 +976,23 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		V:        (*hexutil.Big)(v),
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
+		Type:     hexutil.Uint64(tx.Type()),
+	}
+	if tx.Type() != types.LegacyTxType {
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap())
+		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap())
+	}
+	if tx.Type() == types.FeeDelegatedTxType {
+		payer, _ := types.FeePayer(signer, tx)
+		v, r, s := tx.RawFeePayerSignatureValues()
+
+		result.FeePayer = &payer
+		result.FeePayerV, result.FeePayerR, result.FeePayerS = (*hexutil.Big)(v), (*hexutil.Big)(r), (*hexutil.Big)(s)
 	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = blockHash
@@ -917,7 +1022,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -1027,8 +1132,8 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 			return nil, nil
 		}
 	}
-	// Serialize to RLP and return
-	return rlp.EncodeToBytes(tx)
+	// Serialize to the canonical encoding and return
+	return tx.MarshalBinary()
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
@@ -1048,7 +1153,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.LatestSignerForChainID(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
@@ -1064,6 +1169,17 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
+		"type":              hexutil.Uint(tx.Type()),
+	}
+	if tx.Type() == types.FeeDelegatedTxType {
+		fields["feePayer"], _ = types.FeePayer(signer, tx)
+	}
+	// Report the price actually paid by transactions included after the base fee fork
+	fields["effectiveGasPrice"] = (*hexutil.Big)(tx.GasPrice())
+	if block, err := s.b.GetBlock(ctx, blockHash); err == nil && block.BaseFee() != nil {
+		if tip, err := tx.EffectiveGasTip(block.BaseFee()); err == nil {
+			fields["effectiveGasPrice"] = (*hexutil.Big)(tip.Add(tip, block.BaseFee()))
+		}
 	}
 
 	// Assign receipt status or post state.
@@ -1111,6 +1227,18 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// Setting the fee payer creates a fee delegated transaction, whose gas
+	// is paid by the fee payer account.
+	FeePayer *common.Address `json:"feePayer"`
+	ChainID  *hexutil.Big    `json:"chainId"`
+
+	// Setting the access list creates an access list transaction.
+	AccessList *types.AccessList `json:"accessList"`
+
+	// Setting the fee cap or the tip creates a dynamic fee transaction.
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1119,7 +1247,11 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		args.Gas = new(hexutil.Uint64)
 		*(*uint64)(args.Gas) = 90000
 	}
-	if args.GasPrice == nil {
+	if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil {
+		if err := args.setFeeDefaults(ctx, b); err != nil {
+			return err
+		}
+	} else if args.GasPrice == nil {
 		price, err := b.SuggestPrice(ctx)
 		if err != nil {
 			return err
@@ -1151,6 +1283,43 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 			return errors.New(`contract creation without any data provided`)
 		}
 	}
+	if (args.FeePayer != nil || args.AccessList != nil || args.MaxFeePerGas != nil) && args.ChainID == nil {
+		args.ChainID = (*hexutil.Big)(b.ChainConfig().ChainId)
+	}
+	return nil
+}
+
+// setFeeDefaults fills in the fee cap and the tip of dynamic fee transactions.
+// A missing tip defaults to the suggested gas price and a missing fee cap to
+// the tip plus twice the base fee of the latest block.
+func (args *SendTxArgs) setFeeDefaults(ctx context.Context, b Backend) error {
+	if args.GasPrice != nil {
+		return errors.New(`both "gasPrice" and "maxFeePerGas" or "maxPriorityFeePerGas" specified`)
+	}
+	if args.FeePayer != nil {
+		return errors.New(`fee delegated transactions don't support "maxFeePerGas" or "maxPriorityFeePerGas"`)
+	}
+	if args.MaxPriorityFeePerGas == nil {
+		tip, err := b.SuggestPrice(ctx)
+		if err != nil {
+			return err
+		}
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tip)
+	}
+	if args.MaxFeePerGas == nil {
+		head, err := b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			return err
+		}
+		if head.BaseFee == nil {
+			return errors.New("base fee market not active")
+		}
+		feeCap := new(big.Int).Add(args.MaxPriorityFeePerGas.ToInt(), new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+		args.MaxFeePerGas = (*hexutil.Big)(feeCap)
+	}
+	if args.MaxFeePerGas.ToInt().Cmp(args.MaxPriorityFeePerGas.ToInt()) < 0 {
+		return fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", args.MaxFeePerGas, args.MaxPriorityFeePerGas)
+	}
 	return nil
 }
 
@@ -1161,12 +1330,73 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	} else if args.Input != nil {
 		input = *args.Input
 	}
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	if args.MaxFeePerGas != nil {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			GasTipCap:  (*big.Int)(args.MaxPriorityFeePerGas),
+			GasFeeCap:  (*big.Int)(args.MaxFeePerGas),
+			Gas:        uint64(*args.Gas),
+			To:         args.To,
+			Value:      (*big.Int)(args.Value),
+			Data:       input,
+			AccessList: accessList,
+		})
+	}
+	if args.FeePayer != nil {
+		return types.NewTx(&types.FeeDelegatedTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			GasPrice:   (*big.Int)(args.GasPrice),
+			Gas:        uint64(*args.Gas),
+			To:         args.To,
+			Value:      (*big.Int)(args.Value),
+			Data:       input,
+			AccessList: accessList,
+		})
+	}
+	if args.AccessList != nil {
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			GasPrice:   (*big.Int)(args.GasPrice),
+			Gas:        uint64(*args.Gas),
+			To:         args.To,
+			Value:      (*big.Int)(args.Value),
+			Data:       input,
+			AccessList: accessList,
+		})
+	}
 	if args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
 	}
 	return types.NewTransaction(uint64(*args.Nonce), *args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
 }
 
+// signFeePayer signs a fee delegated transaction as fee payer with the unlocked
+// account of a local wallet.
+func signFeePayer(b Backend, payer common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	if tx.Type() != types.FeeDelegatedTxType {
+		return nil, types.ErrTxTypeNotSupported
+	}
+	account := accounts.Account{Address: payer}
+
+	wallet, err := b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.NewFeeDelegationSigner(b.ChainConfig().ChainId)
+	sig, err := wallet.SignHash(account, signer.FeePayerHash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(signer, sig)
+}
+
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
 func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
 	if err := b.SendTx(ctx, tx); err != nil {
@@ -1220,6 +1450,12 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// Sponsored transactions need the signature of the fee payer too
+	if args.FeePayer != nil {
+		if signed, err = signFeePayer(s.b, *args.FeePayer, signed); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	return submitTransaction(ctx, s.b, signed)
 }
 
@@ -1227,7 +1463,7 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
 	return submitTransaction(ctx, s.b, tx)
@@ -1271,7 +1507,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if args.Gas == nil {
 		return nil, fmt.Errorf("gas not specified")
 	}
-	if args.GasPrice == nil {
+	if args.GasPrice == nil && args.MaxFeePerGas == nil {
 		return nil, fmt.Errorf("gasPrice not specified")
 	}
 	if args.Nonce == nil {
@@ -1284,13 +1520,32 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
 	return &SignTransactionResult{data, tx}, nil
 }
 
+// SignFeePayerTransaction signs a fee delegated transaction, already signed by
+// its sender, as the given fee payer. The node needs to have the private key of
+// the fee payer account and it needs to be unlocked.
+func (s *PublicTransactionPoolAPI) SignFeePayerTransaction(ctx context.Context, encodedTx hexutil.Bytes, feePayer common.Address) (*SignTransactionResult, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
+		return nil, err
+	}
+	signed, err := signFeePayer(s.b, feePayer, tx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{data, signed}, nil
+}
+
 // PendingTransactions returns the transactions that are in the transaction pool and have a from address that is one of
 // the accounts this node manages.
 func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, error) {
@@ -1303,7 +1558,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
+			signer = types.LatestSignerForChainID(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
@@ -1331,7 +1586,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	for _, p := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if p.Protected() {
-			signer = types.NewEIP155Signer(p.ChainId())
+			signer = types.LatestSignerForChainID(p.ChainId())
 		}
 		wantSigHash := signer.Hash(matchTx)
 
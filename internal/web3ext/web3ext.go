@@ -23,6 +23,7 @@ var Modules = map[string]string{
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
 	"aae":        aae_JS,
+	"les":        LES_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
@@ -123,6 +124,15 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'peerScores',
+			call: 'admin_peerScores'
+		}),
+		new web3._extend.Method({
+			name: 'resetPeerScore',
+			call: 'admin_resetPeerScore',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -412,6 +422,18 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'createAccessList',
+			call: 'eth_createAccessList',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'signFeePayerTransaction',
+			call: 'eth_signFeePayerTransaction',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'submitTransaction',
 			call: 'eth_submitTransaction',
@@ -449,6 +471,38 @@ web3._extend({
 });
 `
 
+const LES_JS = `
+web3._extend({
+	property: 'les',
+	methods: [
+		new web3._extend.Method({
+			name: 'addPriorityClient',
+			call: 'les_addPriorityClient',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'removePriorityClient',
+			call: 'les_removePriorityClient',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'priorityClients',
+			getter: 'les_priorityClients'
+		}),
+		new web3._extend.Property({
+			name: 'clientPool',
+			getter: 'les_clientPool'
+		}),
+		new web3._extend.Property({
+			name: 'latestCheckpoint',
+			getter: 'les_latestCheckpoint'
+		}),
+	]
+});
+`
+
 const Miner_JS = `
 web3._extend({
 	property: 'miner',
@@ -0,0 +1,81 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+
+	"github.com/aaechain/go-aaechain/contracts/checkpointoracle"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+)
+
+var errNoCheckpoint = errors.New("no finished section")
+
+// PrivateLightServerAPI provides an API to manage the light clients served by
+// a node.
+type PrivateLightServerAPI struct {
+	server *LesServer
+}
+
+// NewPrivateLightServerAPI creates a new light server API.
+func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
+	return &PrivateLightServerAPI{server: server}
+}
+
+// AddPriorityClient guarantees capacity to a light client, measured in the same
+// units as the recharge rate of free clients. The flow control parameters of
+// the client are scaled accordingly. A connected client is disconnected to
+// apply its new parameters.
+func (api *PrivateLightServerAPI) AddPriorityClient(id discover.NodeID, capacity uint64) error {
+	if capacity == 0 {
+		return errCapacityTooLow
+	}
+	return api.server.clientPool.setPriority(id, capacity)
+}
+
+// RemovePriorityClient turns a priority client back into a free client.
+func (api *PrivateLightServerAPI) RemovePriorityClient(id discover.NodeID) error {
+	return api.server.clientPool.setPriority(id, 0)
+}
+
+// PriorityClients returns the capacities assigned to priority clients.
+func (api *PrivateLightServerAPI) PriorityClients() map[discover.NodeID]uint64 {
+	return api.server.clientPool.priorityClients()
+}
+
+// ClientPool returns the capacity of the server, the capacity taken by
+// connected priority clients and the number of connected free clients.
+func (api *PrivateLightServerAPI) ClientPool() map[string]interface{} {
+	total, priority, free := api.server.clientPool.status()
+	return map[string]interface{}{
+		"totalCapacity":    total,
+		"priorityCapacity": priority,
+		"freeClients":      free,
+		"freeRecharge":     api.server.defParams.MinRecharge,
+	}
+}
+
+// LatestCheckpoint returns the CHT and BloomTrie roots of the latest section
+// processed by both indexers, to be signed and registered in the checkpoint
+// oracle.
+func (api *PrivateLightServerAPI) LatestCheckpoint() (*checkpointoracle.Checkpoint, error) {
+	cp := api.server.latestCheckpoint()
+	if cp == nil {
+		return nil, errNoCheckpoint
+	}
+	return cp, nil
+}
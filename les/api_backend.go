@@ -29,6 +29,7 @@ import (
 	"github.com/aaechain/go-aaechain/core/types"
 	"github.com/aaechain/go-aaechain/core/vm"
 	"github.com/aaechain/go-aaechain/aae/downloader"
+	"github.com/aaechain/go-aaechain/aae/filters"
 	"github.com/aaechain/go-aaechain/aae/gasprice"
 	"github.com/aaechain/go-aaechain/aaedb"
 	"github.com/aaechain/go-aaechain/event"
@@ -91,16 +92,76 @@ func (b *LesApiBackend) GetLogs(ctx context.Context, blockHash common.Hash) ([][
 	return light.GetBlockLogs(ctx, b.aae.odr, blockHash, core.GetBlockNumber(b.aae.chainDb, blockHash))
 }
 
+// FilterLogs retrieves the logs matching the filter criteria in the given range
+// from servers supporting server-side filtering (implementation of
+// filters.RangeFilterBackend). The returned logs are proven against the local
+// headers, but the serving peer is trusted for completeness and for the log
+// indices within the blocks.
+func (b *LesApiBackend) FilterLogs(ctx context.Context, begin, end uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+	// The logs are verified against the local headers, which are missing below
+	// the trusted checkpoint. Ultra-light clients can't match the logs locally
+	// either, as they don't index the chain.
+	if core.GetCanonicalHash(b.aae.chainDb, begin) == (common.Hash{}) {
+		if b.aae.protocolManager.ulc != nil {
+			return nil, errUltraLightLogs
+		}
+		return nil, filters.ErrRangeFilterUnavailable
+	}
+	var logs []*types.Log
+	for begin <= end {
+		to := end
+		if to-begin >= MaxLogsFetch {
+			to = begin + MaxLogsFetch - 1
+		}
+		found, last, err := light.GetFilteredLogs(ctx, b.aae.odr, begin, to, addresses, topics)
+		if err == ErrNoPeers {
+			return nil, filters.ErrRangeFilterUnavailable
+		}
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, found...)
+		begin = last + 1
+	}
+	return logs, nil
+}
+
 func (b *LesApiBackend) GetTd(blockHash common.Hash) *big.Int {
 	return b.aae.blockchain.GetTdByHash(blockHash)
 }
 
 func (b *LesApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	// Retrieve the state accessed by the call in a few batched requests,
+	// the actual execution will then find it in the local database.
+	if err := light.Prefetch(ctx, header, b.aae.odr, b.prefetchCall(ctx, msg, header)); err != nil {
+		return nil, nil, err
+	}
 	state.SetBalance(msg.From(), math.MaxBig256)
 	context := core.NewEVMContext(msg, header, b.aae.blockchain, nil)
 	return vm.NewEVM(context, state, b.aae.chainConfig, vmCfg), state.Error, nil
 }
 
+// prefetchCall returns a function executing msg on the state recording the
+// state entries missing for the call.
+func (b *LesApiBackend) prefetchCall(ctx context.Context, msg core.Message, header *types.Header) func(*state.StateDB) {
+	return func(statedb *state.StateDB) {
+		statedb.SetBalance(msg.From(), math.MaxBig256)
+		context := core.NewEVMContext(msg, header, b.aae.blockchain, nil)
+		evm := vm.NewEVM(context, statedb, b.aae.chainConfig, vm.Config{})
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	}
+}
+
 func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 	return b.aae.txPool.Add(ctx, signedTx)
 }
@@ -117,7 +117,14 @@ func New(ctx *node.ServiceContext, config *aae.Config) (*Lightaaechain, error) {
 	if laae.blockchain, err = light.NewLightChain(laae.odr, laae.chainConfig, laae.engine); err != nil {
 		return nil, err
 	}
-	laae.bloomIndexer.Start(laae.blockchain)
+	ulc := newULC(config.UltraLightServers, config.UltraLightFraction)
+	if ulc != nil {
+		// Old headers are not kept in ultra-light mode, so the chain can't be indexed
+		log.Info("Ultra-light client mode enabled", "servers", len(ulc.trusted), "fraction", ulc.fraction)
+		laae.blockchain.SetUltraLight(ulcHistory)
+	} else {
+		laae.bloomIndexer.Start(laae.blockchain)
+	}
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -129,6 +136,11 @@ func New(ctx *node.ServiceContext, config *aae.Config) (*Lightaaechain, error) {
 	if laae.protocolManager, err = NewProtocolManager(laae.chainConfig, true, ClientProtocolVersions, config.NetworkId, laae.eventMux, laae.engine, laae.peers, laae.blockchain, nil, chainDb, laae.odr, laae.relay, quitSync, &laae.wg); err != nil {
 		return nil, err
 	}
+	laae.protocolManager.ulc = ulc
+	if config.CheckpointOracle != nil {
+		log.Info("Checkpoint oracle enabled", "address", config.CheckpointOracle.Address, "signers", len(config.CheckpointOracle.Signers), "threshold", config.CheckpointOracle.Threshold)
+		laae.protocolManager.oracle = config.CheckpointOracle
+	}
 	laae.ApiBackend = &LesApiBackend{laae, nil}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
@@ -145,6 +157,8 @@ func lesTopic(genesisHash common.Hash, protocolVersion uint) discv5.Topic {
 		name = "LES"
 	case lpv2:
 		name = "LES2"
+	case lpv3:
+		name = "LES3"
 	default:
 		panic(nil)
 	}
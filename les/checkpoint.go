@@ -0,0 +1,148 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	aaeereum "github.com/aaechain/go-aaechain"
+	"github.com/aaechain/go-aaechain/accounts/abi/bind"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/contracts/checkpointoracle"
+	"github.com/aaechain/go-aaechain/core"
+	"github.com/aaechain/go-aaechain/core/state"
+	"github.com/aaechain/go-aaechain/core/types"
+	"github.com/aaechain/go-aaechain/core/vm"
+	"github.com/aaechain/go-aaechain/light"
+	"github.com/aaechain/go-aaechain/log"
+)
+
+// oracleCallGas is the gas allowance of the read-only oracle calls.
+const oracleCallGas = 50000000
+
+var errCallFailed = errors.New("contract call failed")
+
+// syncCheckpoint reads the latest checkpoint from the oracle contract in the
+// state of the peer's head and adds it to the light chain if it is signed by
+// enough trusted signers. The state itself is not trusted, the signatures are
+// verified locally.
+func (pm *ProtocolManager) syncCheckpoint(ctx context.Context, p *peer) {
+	head, err := pm.requestHeader(ctx, p, p.Head())
+	if err != nil {
+		p.Log().Debug("Failed to retrieve head for checkpoint", "err", err)
+		return
+	}
+	oracle, err := checkpointoracle.NewCheckpointOracleCaller(pm.oracle.Address, &stateCaller{pm: pm, header: head})
+	if err != nil {
+		return
+	}
+	cp, height, err := oracle.LatestCheckpoint(&bind.CallOpts{Context: ctx}, pm.oracle.Signers, pm.oracle.Threshold)
+	if err != nil {
+		p.Log().Debug("Failed to read checkpoint oracle", "err", err)
+		return
+	}
+	if pm.blockchain.(*light.LightChain).AddTrustedCheckpoint("oracle", cp.SectionIndex, cp.SectionHead, cp.ChtRoot, cp.BloomTrieRoot) {
+		log.Debug("Checkpoint retrieved from oracle", "section", cp.SectionIndex, "registered", height)
+	}
+}
+
+// requestHeader retrieves a single header by hash from the given peer.
+func (pm *ProtocolManager) requestHeader(ctx context.Context, p *peer, hash common.Hash) (*types.Header, error) {
+	var header *types.Header
+
+	reqID := genReqID()
+	rq := &distReq{
+		getCost: func(dp distPeer) uint64 {
+			return dp.(*peer).GetRequestCost(GetBlockHeadersMsg, 1)
+		},
+		canSend: func(dp distPeer) bool {
+			return dp.(*peer) == p
+		},
+		request: func(dp distPeer) func() {
+			cost := p.GetRequestCost(GetBlockHeadersMsg, 1)
+			p.fcServer.QueueRequest(reqID, cost)
+			return func() { p.RequestHeadersByHash(reqID, cost, hash, 1, 0, false) }
+		},
+	}
+	validate := func(dp distPeer, msg *Msg) error {
+		if msg.MsgType != MsgBlockHeaders {
+			return errInvalidMessageType
+		}
+		headers := msg.Obj.([]*types.Header)
+		if len(headers) != 1 {
+			return errInvalidEntryCount
+		}
+		if headers[0].Hash() != hash {
+			return errHeaderUnavailable
+		}
+		header = headers[0]
+		return nil
+	}
+	if err := pm.retriever.retrieve(ctx, reqID, rq, validate, pm.quitSync); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// stateCaller is a bind.ContractCaller executing read-only calls on the state
+// of a given header, retrieved on demand from the LES network.
+type stateCaller struct {
+	pm     *ProtocolManager
+	header *types.Header
+}
+
+// CodeAt implements bind.ContractCaller, returning the code of the given account.
+func (c *stateCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	statedb := light.NewState(ctx, c.header, c.pm.odr)
+	code := statedb.GetCode(contract)
+	return code, statedb.Error()
+}
+
+// CallContract implements bind.ContractCaller, executing the call without
+// creating a transaction.
+func (c *stateCaller) CallContract(ctx context.Context, call aaeereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	msg := types.NewMessage(call.From, call.To, 0, new(big.Int), oracleCallGas, new(big.Int), call.Data, nil, false)
+
+	var (
+		res    []byte
+		failed bool
+		err    error
+	)
+	run := func(statedb *state.StateDB) {
+		context := core.NewEVMContext(msg, c.header, c.pm.blockchain.(*light.LightChain), nil)
+		evm := vm.NewEVM(context, statedb, c.pm.chainConfig, vm.Config{})
+		res, _, failed, err = core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(oracleCallGas))
+	}
+	// Retrieve the accessed state in batches before the actual execution
+	if err := light.Prefetch(ctx, c.header, c.pm.odr, run); err != nil {
+		return nil, err
+	}
+	statedb := light.NewState(ctx, c.header, c.pm.odr)
+	run(statedb)
+	if statedb.Error() != nil {
+		return nil, statedb.Error()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if failed {
+		return nil, errCallFailed
+	}
+	return res, nil
+}
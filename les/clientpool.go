@@ -0,0 +1,299 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aaechain/go-aaechain/common/mclock"
+	"github.com/aaechain/go-aaechain/les/flowcontrol"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
+)
+
+const (
+	defaultPriorityCapacity = 4 // Capacity of whitelisted priority clients, in free client units
+
+	usageDecayTime  = 10 * time.Minute // Time constant of the exponential decay of client usage
+	usageForgetTime = time.Hour        // Time after which the usage of a disconnected client is forgotten
+)
+
+var (
+	errCapacityTooLow    = errors.New("capacity lower than that of free clients")
+	errCapacityExhausted = errors.New("not enough capacity left for priority client")
+)
+
+// poolPeer is the part of a client peer the client pool interacts with.
+type poolPeer interface {
+	ID() discover.NodeID
+	Disconnect(reason p2p.DiscReason)
+}
+
+// poolClient is a client connected to the pool.
+type poolClient struct {
+	peer     poolPeer
+	capacity uint64 // Capacity of a priority client, zero for free clients
+}
+
+// usageRecord tracks the recent usage of a free client. The usage is the sum
+// of the costs of served requests, decaying exponentially over time.
+type usageRecord struct {
+	usage float64
+	last  mclock.AbsTime
+}
+
+// value returns the usage at the given time.
+func (r *usageRecord) value(now mclock.AbsTime) float64 {
+	dt := time.Duration(now - r.last)
+	return r.usage * math.Exp(-float64(dt)/float64(usageDecayTime))
+}
+
+// clientPool decides which light clients a server accepts and with what flow
+// control parameters. The capacity of the server is measured in recharge rate
+// units and is sized to serve the configured number of free clients. Priority
+// clients are assigned a part of it and get flow control parameters scaled
+// accordingly. Their capacity is guaranteed: free clients are kicked out to make
+// room when a priority client connects. The remaining capacity is shared by
+// free clients. If the pool is full, a new free client replaces the connected
+// free client with the highest recent usage, provided that the newcomer used
+// less itself.
+type clientPool struct {
+	lock sync.Mutex
+
+	freeParams  *flowcontrol.ServerParams // Flow control parameters of free clients
+	totalCap    uint64                    // Total capacity of the server
+	priorityCap uint64                    // Capacity used by connected priority clients
+	freeCount   int                       // Number of connected free clients
+
+	priority  map[discover.NodeID]uint64       // Capacities assigned to priority clients
+	connected map[discover.NodeID]*poolClient  // Currently connected clients
+	usage     map[discover.NodeID]*usageRecord // Usage of connected and recent free clients
+
+	clock func() mclock.AbsTime // Time source, replaceable for testing
+}
+
+// newClientPool creates a pool for maxFree free clients with the given flow
+// control parameters. Whitelisted clients are given the default priority
+// capacity.
+func newClientPool(freeParams *flowcontrol.ServerParams, maxFree int, whitelist []discover.NodeID) *clientPool {
+	pool := &clientPool{
+		freeParams: freeParams,
+		totalCap:   freeParams.MinRecharge * uint64(maxFree),
+		priority:   make(map[discover.NodeID]uint64),
+		connected:  make(map[discover.NodeID]*poolClient),
+		usage:      make(map[discover.NodeID]*usageRecord),
+		clock:      mclock.Now,
+	}
+	for _, id := range whitelist {
+		if err := pool.setPriority(id, defaultPriorityCapacity*freeParams.MinRecharge); err != nil {
+			log.Warn("Failed to add priority light client", "id", id, "err", err)
+		}
+	}
+	return pool
+}
+
+// setPriority assigns capacity to a priority client. Zero capacity turns the
+// client back into a free client. A connected client is disconnected, so that
+// it reconnects with its new flow control parameters.
+func (pool *clientPool) setPriority(id discover.NodeID, capacity uint64) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if capacity != 0 && capacity < pool.freeParams.MinRecharge {
+		return errCapacityTooLow
+	}
+	var assigned uint64
+	for other, c := range pool.priority {
+		if other != id {
+			assigned += c
+		}
+	}
+	if assigned+capacity > pool.totalCap {
+		return errCapacityExhausted
+	}
+	if capacity == 0 {
+		delete(pool.priority, id)
+	} else {
+		pool.priority[id] = capacity
+	}
+	if c := pool.connected[id]; c != nil {
+		pool.remove(id, c)
+		c.peer.Disconnect(p2p.DiscRequested)
+	}
+	return nil
+}
+
+// priorityClients returns the capacities assigned to priority clients.
+func (pool *clientPool) priorityClients() map[discover.NodeID]uint64 {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	clients := make(map[discover.NodeID]uint64, len(pool.priority))
+	for id, capacity := range pool.priority {
+		clients[id] = capacity
+	}
+	return clients
+}
+
+// status returns the total capacity, the capacity used by priority clients and
+// the number of connected free clients.
+func (pool *clientPool) status() (total, priority uint64, free int) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	return pool.totalCap, pool.priorityCap, pool.freeCount
+}
+
+// connect admits a client to the pool and returns its flow control parameters.
+// Trusted peers are admitted as free clients even if the pool is full.
+func (pool *clientPool) connect(p poolPeer, trusted bool) (*flowcontrol.ServerParams, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	id := p.ID()
+	if pool.connected[id] != nil {
+		return nil, errAlreadyRegistered
+	}
+	now := pool.clock()
+	if capacity, ok := pool.priority[id]; ok {
+		// Kick out free clients until the guaranteed capacity is available.
+		if free := pool.freeCapacity(); free < capacity {
+			need := int((capacity - free + pool.freeParams.MinRecharge - 1) / pool.freeParams.MinRecharge)
+			pool.evict(need, now)
+		}
+		pool.connected[id] = &poolClient{peer: p, capacity: capacity}
+		pool.priorityCap += capacity
+		return &flowcontrol.ServerParams{
+			BufLimit:    pool.freeParams.BufLimit / pool.freeParams.MinRecharge * capacity,
+			MinRecharge: capacity,
+		}, nil
+	}
+	if pool.freeCapacity() < pool.freeParams.MinRecharge && !trusted {
+		// Replace the most active free client if the newcomer was less active.
+		worst, usage := pool.mostActive(now)
+		if worst == nil || usage <= pool.usageOf(id, now) {
+			return nil, p2p.DiscTooManyPeers
+		}
+		pool.evict(1, now)
+	}
+	pool.connected[id] = &poolClient{peer: p}
+	pool.freeCount++
+	if pool.usage[id] == nil {
+		pool.usage[id] = &usageRecord{last: now}
+	}
+	return pool.freeParams, nil
+}
+
+// disconnect removes a client from the pool.
+func (pool *clientPool) disconnect(p poolPeer) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	id := p.ID()
+	if c := pool.connected[id]; c != nil && c.peer == p {
+		pool.remove(id, c)
+	}
+	pool.forget(pool.clock())
+}
+
+// charge adds the cost of a served request to the usage of a free client.
+func (pool *clientPool) charge(id discover.NodeID, cost uint64) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if r := pool.usage[id]; r != nil {
+		now := pool.clock()
+		r.usage = r.value(now) + float64(cost)
+		r.last = now
+	}
+}
+
+// freeCapacity returns the capacity not used by connected clients.
+func (pool *clientPool) freeCapacity() uint64 {
+	used := pool.priorityCap + uint64(pool.freeCount)*pool.freeParams.MinRecharge
+	if used >= pool.totalCap {
+		return 0
+	}
+	return pool.totalCap - used
+}
+
+// usageOf returns the recent usage of a client.
+func (pool *clientPool) usageOf(id discover.NodeID, now mclock.AbsTime) float64 {
+	if r := pool.usage[id]; r != nil {
+		return r.value(now)
+	}
+	return 0
+}
+
+// mostActive returns the connected free client with the highest usage.
+func (pool *clientPool) mostActive(now mclock.AbsTime) (poolPeer, float64) {
+	var (
+		worst   poolPeer
+		highest = -1.0
+	)
+	for id, c := range pool.connected {
+		if c.capacity == 0 {
+			if usage := pool.usageOf(id, now); usage > highest {
+				worst, highest = c.peer, usage
+			}
+		}
+	}
+	return worst, highest
+}
+
+// evict disconnects up to n free clients, starting with the most active ones.
+func (pool *clientPool) evict(n int, now mclock.AbsTime) {
+	queue := prque.New()
+	for id, c := range pool.connected {
+		if c.capacity == 0 {
+			queue.Push(id, float32(pool.usageOf(id, now)))
+		}
+	}
+	for ; n > 0 && !queue.Empty(); n-- {
+		id := queue.PopItem().(discover.NodeID)
+		c := pool.connected[id]
+		pool.remove(id, c)
+		log.Debug("Evicting light client", "id", id, "usage", pool.usageOf(id, now))
+		c.peer.Disconnect(p2p.DiscTooManyPeers)
+	}
+}
+
+// remove drops a connected client from the accounting. The usage record is
+// kept to prevent clients from resetting it by reconnecting.
+func (pool *clientPool) remove(id discover.NodeID, c *poolClient) {
+	delete(pool.connected, id)
+	if c.capacity == 0 {
+		pool.freeCount--
+	} else {
+		pool.priorityCap -= c.capacity
+	}
+}
+
+// forget drops the usage records of disconnected clients which haven't been
+// active for a while.
+func (pool *clientPool) forget(now mclock.AbsTime) {
+	for id, r := range pool.usage {
+		if pool.connected[id] == nil && time.Duration(now-r.last) > usageForgetTime {
+			delete(pool.usage, id)
+		}
+	}
+}
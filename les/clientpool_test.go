@@ -0,0 +1,179 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"testing"
+
+	"github.com/aaechain/go-aaechain/common/mclock"
+	"github.com/aaechain/go-aaechain/les/flowcontrol"
+	"github.com/aaechain/go-aaechain/p2p"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+)
+
+type poolTestPeer struct {
+	id           discover.NodeID
+	disconnected bool
+}
+
+func newPoolTestPeer(i byte) *poolTestPeer {
+	return &poolTestPeer{id: discover.NodeID{i}}
+}
+
+func (p *poolTestPeer) ID() discover.NodeID              { return p.id }
+func (p *poolTestPeer) Disconnect(reason p2p.DiscReason) { p.disconnected = true }
+
+var poolTestParams = &flowcontrol.ServerParams{BufLimit: 1000, MinRecharge: 10}
+
+func newTestClientPool(maxFree int) (*clientPool, *mclock.AbsTime) {
+	pool := newClientPool(poolTestParams, maxFree, nil)
+	now := new(mclock.AbsTime)
+	pool.clock = func() mclock.AbsTime { return *now }
+	return pool, now
+}
+
+// Tests that free clients are admitted up to the capacity of the pool, after
+// which the most active client is replaced by less active newcomers.
+func TestClientPoolFree(t *testing.T) {
+	pool, _ := newTestClientPool(3)
+
+	peers := make([]*poolTestPeer, 5)
+	for i := range peers {
+		peers[i] = newPoolTestPeer(byte(i))
+	}
+	for i := 0; i < 3; i++ {
+		params, err := pool.connect(peers[i], false)
+		if err != nil {
+			t.Fatalf("client %d rejected: %v", i, err)
+		}
+		if *params != *poolTestParams {
+			t.Fatalf("client %d params mismatch: have %v, want %v", i, params, poolTestParams)
+		}
+	}
+	if _, err := pool.connect(peers[3], false); err != p2p.DiscTooManyPeers {
+		t.Fatalf("error mismatch for client beyond capacity: have %v, want %v", err, p2p.DiscTooManyPeers)
+	}
+	if _, err := pool.connect(peers[3], true); err != nil {
+		t.Fatalf("trusted client rejected: %v", err)
+	}
+	pool.disconnect(peers[3])
+
+	pool.charge(peers[1].id, 500)
+	pool.charge(peers[2].id, 100)
+	if _, err := pool.connect(peers[4], false); err != nil {
+		t.Fatalf("inactive client rejected: %v", err)
+	}
+	if !peers[1].disconnected || peers[0].disconnected || peers[2].disconnected {
+		t.Fatalf("wrong client evicted")
+	}
+	// The evicted client can't come back while it is more active than the others.
+	pool.disconnect(peers[1])
+	if _, err := pool.connect(peers[1], false); err != p2p.DiscTooManyPeers {
+		t.Fatalf("error mismatch for reconnecting active client: have %v, want %v", err, p2p.DiscTooManyPeers)
+	}
+}
+
+// Tests that priority clients get their guaranteed capacity, evicting free
+// clients if necessary.
+func TestClientPoolPriority(t *testing.T) {
+	pool, _ := newTestClientPool(4)
+
+	prio := newPoolTestPeer(0xff)
+	if err := pool.setPriority(prio.id, 25); err != nil {
+		t.Fatalf("failed to set priority: %v", err)
+	}
+	peers := make([]*poolTestPeer, 4)
+	for i := range peers {
+		peers[i] = newPoolTestPeer(byte(i))
+		if _, err := pool.connect(peers[i], false); err != nil {
+			t.Fatalf("client %d rejected: %v", i, err)
+		}
+		pool.charge(peers[i].id, uint64(i+1)*100)
+	}
+	params, err := pool.connect(prio, false)
+	if err != nil {
+		t.Fatalf("priority client rejected: %v", err)
+	}
+	if params.MinRecharge != 25 || params.BufLimit != 2500 {
+		t.Fatalf("priority params mismatch: have %+v", params)
+	}
+	// Three free clients had to make room, starting with the most active.
+	for i, p := range peers {
+		if want := i > 0; p.disconnected != want {
+			t.Errorf("client %d: disconnected %v, want %v", i, p.disconnected, want)
+		}
+	}
+	if _, priority, free := pool.status(); priority != 25 || free != 1 {
+		t.Fatalf("status mismatch: have priority %d, free %d; want 25, 1", priority, free)
+	}
+	// Removing the priority disconnects the client so it reconnects as free.
+	if err := pool.setPriority(prio.id, 0); err != nil {
+		t.Fatalf("failed to remove priority: %v", err)
+	}
+	if !prio.disconnected {
+		t.Fatalf("priority client not disconnected after removal")
+	}
+	if _, priority, _ := pool.status(); priority != 0 {
+		t.Fatalf("priority capacity not released: %d", priority)
+	}
+}
+
+// Tests that priority capacity can't exceed the capacity of the pool.
+func TestClientPoolPriorityLimits(t *testing.T) {
+	pool, _ := newTestClientPool(4)
+
+	if err := pool.setPriority(discover.NodeID{1}, 5); err != errCapacityTooLow {
+		t.Fatalf("error mismatch: have %v, want %v", err, errCapacityTooLow)
+	}
+	if err := pool.setPriority(discover.NodeID{1}, 30); err != nil {
+		t.Fatalf("failed to set priority: %v", err)
+	}
+	if err := pool.setPriority(discover.NodeID{2}, 20); err != errCapacityExhausted {
+		t.Fatalf("error mismatch: have %v, want %v", err, errCapacityExhausted)
+	}
+	// Changing the capacity of an existing client doesn't count it twice.
+	if err := pool.setPriority(discover.NodeID{1}, 40); err != nil {
+		t.Fatalf("failed to update priority: %v", err)
+	}
+	if clients := pool.priorityClients(); len(clients) != 1 || clients[discover.NodeID{1}] != 40 {
+		t.Fatalf("priority clients mismatch: %v", clients)
+	}
+}
+
+// Tests that client usage decays over time and is eventually forgotten.
+func TestClientPoolUsageDecay(t *testing.T) {
+	pool, now := newTestClientPool(1)
+
+	active, idle := newPoolTestPeer(1), newPoolTestPeer(2)
+	pool.connect(active, false)
+	pool.charge(active.id, 1000)
+	pool.disconnect(active)
+
+	*now += mclock.AbsTime(usageDecayTime)
+	if usage := pool.usageOf(active.id, *now); usage < 367 || usage > 368 {
+		t.Fatalf("decayed usage mismatch: have %f, want 1000/e", usage)
+	}
+	*now += mclock.AbsTime(usageForgetTime)
+	pool.connect(idle, false)
+	pool.disconnect(idle)
+	if _, ok := pool.usage[active.id]; ok {
+		t.Fatalf("usage of inactive client not forgotten")
+	}
+	if _, ok := pool.usage[idle.id]; !ok {
+		t.Fatalf("usage of recent client forgotten")
+	}
+}
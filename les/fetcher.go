@@ -401,6 +401,9 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 
 	for p, fp := range f.peers {
 		for hash, n := range fp.nodeByHash {
+			if f.pm.ulc != nil && !f.trustedAgreed(hash) {
+				continue
+			}
 			if !f.checkKnownNode(p, n) && !n.requested && (bestTd == nil || n.td.Cmp(bestTd) >= 0) {
 				amount := f.requestAmount(p, n)
 				if bestTd == nil || n.td.Cmp(bestTd) > 0 || amount < bestAmount {
@@ -430,6 +433,10 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 				f.lock.Lock()
 				defer f.lock.Unlock()
 
+				// Ultra-light clients only sync from trusted servers, the synced headers are not verified
+				if f.pm.ulc != nil && !p.isTrusted {
+					return false
+				}
 				fp := f.peers[p]
 				return fp != nil && fp.nodeByHash[bestHash] != nil
 			},
@@ -489,6 +496,18 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 	return rq, reqID
 }
 
+// trustedAgreed tells if enough trusted servers announced the given head for an
+// ultra-light client to accept it
+func (f *lightFetcher) trustedAgreed(hash common.Hash) bool {
+	count := 0
+	for p, fp := range f.peers {
+		if p.isTrusted && fp.nodeByHash[hash] != nil {
+			count++
+		}
+	}
+	return f.pm.ulc.agreed(count)
+}
+
 // deliverHeaders delivers header download request responses for processing
 func (f *lightFetcher) deliverHeaders(peer *peer, reqID uint64, headers []*types.Header) {
 	f.deliverChn <- fetchResponse{reqID: reqID, headers: headers, peer: peer}
@@ -58,6 +58,7 @@ const (
 	MaxHelperTrieProofsFetch = 64  // Amount of merkle proofs to be fetched per retrieval request
 	MaxTxSend                = 64  // Amount of transactions to be send per request
 	MaxTxStatus              = 256 // Amount of transactions to queried per request
+	MaxLogsFetch             = 512 // Amount of blocks to be filtered per logs request
 
 	disableClientRemovePeer = false
 )
@@ -108,6 +109,8 @@ type ProtocolManager struct {
 
 	downloader *downloader.Downloader
 	fetcher    *lightFetcher
+	ulc        *ulc                           // Trusted servers of an ultra-light client, nil if disabled
+	oracle     *params.CheckpointOracleConfig // Checkpoint oracle read at sync start, nil if disabled
 	peers      *peerSet
 	maxPeers   int
 
@@ -260,9 +263,23 @@ func (pm *ProtocolManager) newPeer(pv int, nv uint64, p *p2p.Peer, rw p2p.MsgRea
 // handle is the callback invoked to manage the life cycle of a les peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	// Ignore maxPeers if this is a trusted peer
-	if pm.peers.Len() >= pm.maxPeers && !p.Peer.Info().Network.Trusted {
-		return p2p.DiscTooManyPeers
+	// Admit clients through the client pool, ignore maxPeers if this is a trusted peer
+	trusted := p.Peer.Info().Network.Trusted
+	if pm.server != nil {
+		params, err := pm.server.clientPool.connect(p, trusted)
+		if err != nil {
+			return err
+		}
+		defer pm.server.clientPool.disconnect(p)
+		p.fcParams = params
+	} else {
+		// Trusted servers of an ultra-light client are always accepted and asked for signed announcements
+		if pm.ulc != nil {
+			p.isTrusted = pm.ulc.isTrusted(p.ID())
+		}
+		if pm.peers.Len() >= pm.maxPeers && !trusted && !p.isTrusted {
+			return p2p.DiscTooManyPeers
+		}
 	}
 
 	p.Log().Debug("Light aaechain peer connected", "name", p.Name())
@@ -330,7 +347,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
-var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg}
+var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg, GetLogsMsg}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
@@ -349,14 +366,15 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		bufValue, _ := p.fcClient.AcceptRequest()
 		cost := costs.baseCost + reqCnt*costs.reqCost
-		if cost > pm.server.defParams.BufLimit {
-			cost = pm.server.defParams.BufLimit
+		if cost > p.fcParams.BufLimit {
+			cost = p.fcParams.BufLimit
 		}
 		if cost > bufValue {
-			recharge := time.Duration((cost - bufValue) * 1000000 / pm.server.defParams.MinRecharge)
+			recharge := time.Duration((cost - bufValue) * 1000000 / p.fcParams.MinRecharge)
 			p.Log().Error("Request came too early", "recharge", common.PrettyDuration(recharge))
 			return true
 		}
+		pm.server.clientPool.charge(p.ID(), cost)
 		return false
 	}
 
@@ -497,6 +515,12 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 		if pm.fetcher != nil && pm.fetcher.requestedID(resp.ReqID) {
 			pm.fetcher.deliverHeaders(p, resp.ReqID, resp.Headers)
+		} else if pm.retriever != nil && pm.retriever.requested(resp.ReqID) {
+			deliverMsg = &Msg{
+				MsgType: MsgBlockHeaders,
+				ReqID:   resp.ReqID,
+				Obj:     resp.Headers,
+			}
 		} else {
 			err := pm.downloader.DeliverHeaders(p.id, resp.Headers)
 			if err != nil {
@@ -1076,6 +1100,55 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 
+	case GetLogsMsg:
+		if p.version < lpv3 {
+			return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+		}
+		p.Log().Trace("Received logs request")
+		// Decode the filter criteria and the block range
+		var req struct {
+			ReqID uint64
+			Query LogsReq
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if req.Query.ToBlock < req.Query.FromBlock {
+			return errResp(ErrRequestRejected, "")
+		}
+		reqCnt := req.Query.ToBlock - req.Query.FromBlock + 1
+		if reject(reqCnt, MaxLogsFetch) {
+			return errResp(ErrRequestRejected, "")
+		}
+		resp := pm.filterLogs(req.Query)
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + reqCnt*costs.reqCost)
+		pm.server.fcCosaaeats.update(msg.Code, reqCnt, rcost)
+
+		return p.SendLogs(req.ReqID, bv, resp)
+
+	case LogsMsg:
+		if p.version < lpv3 {
+			return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+		}
+		if pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		p.Log().Trace("Received logs response")
+		var resp struct {
+			ReqID, BV uint64
+			Data      LogsResp
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgLogs,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Data,
+		}
+
 	default:
 		p.Log().Trace("Received unknown message", "code", msg.Code)
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -489,6 +489,76 @@ func TestGetBloombitsProofs(t *testing.T) {
 	}
 }
 
+// Tests that logs filtered by the server can be retrieved and verified.
+func TestGetLogsLes3(t *testing.T) {
+	// Assemble the test environment
+	db, _ := aaedb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 4, testChainGen, nil, nil, db)
+	bc := pm.blockchain.(*core.BlockChain)
+	peer, _ := newTestPeer(t, "peer", 3, pm, true)
+	defer peer.close()
+
+	// Request the logs of the event emitter over the whole chain
+	query := LogsReq{ToBlock: bc.CurrentBlock().NumberU64(), Addresses: []common.Address{testEventEmitterAddr}}
+	cost := peer.GetRequestCost(GetLogsMsg, int(query.ToBlock+1))
+	sendRequest(peer.app, GetLogsMsg, 42, cost, query)
+
+	msg, err := peer.app.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if msg.Code != LogsMsg {
+		t.Fatalf("message code mismatch: have %d, want %d", msg.Code, LogsMsg)
+	}
+	var resp struct {
+		ReqID, BV uint64
+		Data      LogsResp
+	}
+	if err := msg.Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// Verify the response against the headers and check the derived logs
+	req := &LogsRequest{FromBlock: query.FromBlock, ToBlock: query.ToBlock, Addresses: query.Addresses}
+	if err := req.Validate(db, &Msg{MsgType: MsgLogs, ReqID: 42, Obj: resp.Data}); err != nil {
+		t.Fatalf("failed to validate response: %v", err)
+	}
+	if req.Last != query.ToBlock {
+		t.Errorf("last block mismatch: have %d, want %d", req.Last, query.ToBlock)
+	}
+	block := bc.GetBlockByNumber(2)
+	if len(req.Logs) != 1 {
+		t.Fatalf("log count mismatch: have %d, want 1", len(req.Logs))
+	}
+	if l := req.Logs[0]; l.Address != testEventEmitterAddr || l.BlockHash != block.Hash() || l.TxHash != block.Transactions()[3].Hash() || l.TxIndex != 3 || l.Index != 0 {
+		t.Errorf("log mismatch: %+v", l)
+	}
+	// Tampered responses must be rejected
+	resp.Data.Blocks[0].Receipts[0].Index = 2
+	if err := req.Validate(db, &Msg{MsgType: MsgLogs, ReqID: 42, Obj: resp.Data}); err == nil {
+		t.Errorf("tampered response accepted")
+	}
+}
+
+// Tests that log filtering requests are rejected on LES/2 connections.
+func TestGetLogsLes2(t *testing.T) {
+	db, _ := aaedb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 4, testChainGen, nil, nil, db)
+	peer, errc := newTestPeer(t, "peer", 2, pm, true)
+	defer peer.close()
+
+	query := LogsReq{ToBlock: 4, Addresses: []common.Address{testEventEmitterAddr}}
+	sendRequest(peer.app, GetLogsMsg, 42, peer.GetRequestCost(GetLogsMsg, 5), query)
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("peer exited without error")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("peer not dropped")
+	}
+}
+
 func TestTransactionStatusLes2(t *testing.T) {
 	db, _ := aaedb.NewMemDatabase()
 	pm := newTestProtocolManagerMust(t, false, 0, nil, nil, nil, db)
@@ -191,6 +191,7 @@ func newTestProtocolManager(lightSync bool, blocks int, generator func(int, *cor
 			MinRecharge: 1,
 		}
 
+		srv.clientPool = newClientPool(srv.defParams, 1000, nil)
 		srv.fcManager = flowcontrol.NewClientManager(50, 10, 1000000000)
 		srv.fcCosaaeats = newCosaaeats(nil)
 	}
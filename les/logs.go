@@ -0,0 +1,180 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/core"
+	"github.com/aaechain/go-aaechain/core/types"
+	"github.com/aaechain/go-aaechain/light"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/rlp"
+	"github.com/aaechain/go-aaechain/trie"
+)
+
+// LogsReq is a request for the receipts containing logs matching the given
+// criteria in a range of canonical blocks.
+type LogsReq struct {
+	FromBlock, ToBlock uint64
+	Addresses          []common.Address
+	Topics             [][]common.Hash
+}
+
+// LogsResp lists the matching receipts of the blocks up to Last. The receipts
+// and their transactions are contained in Proofs, which proves them against
+// the receipt and transaction roots of the headers.
+type LogsResp struct {
+	Last   uint64
+	Blocks []BlockLogs
+	Proofs light.NodeList
+}
+
+// BlockLogs lists the matching receipts of a block.
+type BlockLogs struct {
+	Number   uint64
+	Receipts []ReceiptLogs
+}
+
+// ReceiptLogs identifies a matching receipt by its transaction index, along with
+// the index of its first log in the block.
+type ReceiptLogs struct {
+	Index    uint64
+	LogIndex uint64
+}
+
+// filterLogs collects the receipts matching the filter criteria in the requested
+// range along with their proofs, until the response size limit is reached.
+func (pm *ProtocolManager) filterLogs(req LogsReq) LogsResp {
+	var (
+		resp  LogsResp
+		nodes = light.NewNodeSet()
+	)
+	for number := req.FromBlock; number <= req.ToBlock; number++ {
+		if number > req.FromBlock && nodes.DataSize() >= softResponseLimit {
+			break
+		}
+		resp.Last = number
+
+		hash := core.GetCanonicalHash(pm.chainDb, number)
+		header := core.GetHeader(pm.chainDb, hash, number)
+		if header == nil || !matchBloom(header.Bloom, req.Addresses, req.Topics) {
+			continue
+		}
+		receipts := core.GetBlockReceipts(pm.chainDb, hash, number)
+		body := core.GetBody(pm.chainDb, hash, number)
+		if body == nil || len(receipts) != len(body.Transactions) {
+			continue
+		}
+		// Rebuild the receipt and transaction tries to prove the matching entries
+		receiptTrie, txTrie := new(trie.Trie), new(trie.Trie)
+		for i := range receipts {
+			key, _ := rlp.EncodeToBytes(uint(i))
+			receiptTrie.Update(key, receipts.GetRlp(i))
+			txTrie.Update(key, types.Transactions(body.Transactions).GetRlp(i))
+		}
+		var (
+			block    = BlockLogs{Number: number}
+			logIndex uint64
+		)
+		for i, receipt := range receipts {
+			for _, l := range receipt.Logs {
+				if !matchLog(l, req.Addresses, req.Topics) {
+					continue
+				}
+				key, _ := rlp.EncodeToBytes(uint(i))
+				if err := receiptTrie.Prove(key, 0, nodes); err != nil {
+					log.Error("Failed to prove receipt", "number", number, "index", i, "err", err)
+					break
+				}
+				if err := txTrie.Prove(key, 0, nodes); err != nil {
+					log.Error("Failed to prove transaction", "number", number, "index", i, "err", err)
+					break
+				}
+				block.Receipts = append(block.Receipts, ReceiptLogs{Index: uint64(i), LogIndex: logIndex})
+				break
+			}
+			logIndex += uint64(len(receipt.Logs))
+		}
+		if len(block.Receipts) > 0 {
+			resp.Blocks = append(resp.Blocks, block)
+		}
+	}
+	resp.Proofs = nodes.NodeList()
+	return resp
+}
+
+// matchBloom tells if a block with the given bloom may contain logs matching the
+// filter criteria.
+func matchBloom(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		included := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLog tells if a log matches the filter criteria.
+func matchLog(l *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if l.Address == addr {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(topics) > len(l.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		included := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if l.Topics[i] == topic {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
@@ -77,6 +77,8 @@ const (
 	MsgProofsV2
 	MsgHeaderProofs
 	MsgHelperTrieProofs
+	MsgBlockHeaders
+	MsgLogs
 )
 
 // Msg encodes a LES message that delivers reply data for a request
@@ -45,6 +45,9 @@ var (
 	errCHTHashMismatch     = errors.New("cht hash mismatch")
 	errCHTNumberMismatch   = errors.New("cht number mismatch")
 	errUselessNodes        = errors.New("useless nodes in merkle proof nodeset")
+	errLogsRangeMismatch   = errors.New("logs range mismatch")
+	errUselessReceipt      = errors.New("receipt without matching logs")
+	errLogsBloomMismatch   = errors.New("logs not matching header bloom")
 )
 
 type LesOdrRequest interface {
@@ -64,10 +67,16 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*TrieRequest)(r)
 	case *light.CodeRequest:
 		return (*CodeRequest)(r)
+	case *light.TrieBatchRequest:
+		return (*TrieBatchRequest)(r)
+	case *light.CodeBatchRequest:
+		return (*CodeBatchRequest)(r)
 	case *light.ChtRequest:
 		return (*ChtRequest)(r)
 	case *light.BloomRequest:
 		return (*BloomRequest)(r)
+	case *light.LogsRequest:
+		return (*LogsRequest)(r)
 	default:
 		return nil
 	}
@@ -193,7 +202,7 @@ func (r *TrieRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetProofsV1Msg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetProofsV2Msg, 1)
 	default:
 		panic(nil)
@@ -309,6 +318,140 @@ func (r *CodeRequest) Validate(db aaedb.Database, msg *Msg) error {
 	return nil
 }
 
+// ODR request type for multiple state/storage trie entries of the same block,
+// see LesOdrRequest interface
+type TrieBatchRequest light.TrieBatchRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *TrieBatchRequest) GetCost(peer *peer) uint64 {
+	switch peer.version {
+	case lpv1:
+		return peer.GetRequestCost(GetProofsV1Msg, len(r.Keys))
+	case lpv2, lpv3:
+		return peer.GetRequestCost(GetProofsV2Msg, len(r.Keys))
+	default:
+		panic(nil)
+	}
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *TrieBatchRequest) CanSend(peer *peer) bool {
+	return peer.HasBlock(r.Ids[0].BlockHash, r.Ids[0].BlockNumber)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *TrieBatchRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting batch of trie proofs", "count", len(r.Keys))
+	reqs := make([]ProofReq, len(r.Keys))
+	for i, key := range r.Keys {
+		reqs[i] = ProofReq{
+			BHash:  r.Ids[i].BlockHash,
+			AccKey: r.Ids[i].AccKey,
+			Key:    key,
+		}
+	}
+	return peer.RequestProofs(reqID, r.GetCost(peer), reqs)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *TrieBatchRequest) Validate(db aaedb.Database, msg *Msg) error {
+	log.Debug("Validating batch of trie proofs", "count", len(r.Keys))
+
+	switch msg.MsgType {
+	case MsgProofsV1:
+		proofs := msg.Obj.([]light.NodeList)
+		if len(proofs) != len(r.Keys) {
+			return errInvalidEntryCount
+		}
+		nodeSet := light.NewNodeSet()
+		for i, proof := range proofs {
+			// Verify each proof and store if all check out
+			set := proof.NodeSet()
+			if _, err, _ := trie.VerifyProof(r.Ids[i].Root, r.Keys[i], set); err != nil {
+				return fmt.Errorf("merkle proof verification failed: %v", err)
+			}
+			set.Store(nodeSet)
+		}
+		r.Proof = nodeSet
+		return nil
+
+	case MsgProofsV2:
+		proofs := msg.Obj.(light.NodeList)
+		// Verify all proofs against the merged node set and store if they check out
+		nodeSet := proofs.NodeSet()
+		reads := &readTraceDB{db: nodeSet}
+		for i, key := range r.Keys {
+			if _, err, _ := trie.VerifyProof(r.Ids[i].Root, key, reads); err != nil {
+				return fmt.Errorf("merkle proof verification failed: %v", err)
+			}
+		}
+		// check if all nodes have been read by VerifyProof
+		if len(reads.reads) != nodeSet.KeyCount() {
+			return errUselessNodes
+		}
+		r.Proof = nodeSet
+		return nil
+
+	default:
+		return errInvalidMessageType
+	}
+}
+
+// ODR request type for multiple contract codes of the same block, see
+// LesOdrRequest interface
+type CodeBatchRequest light.CodeBatchRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *CodeBatchRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetCodeMsg, len(r.Hashes))
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *CodeBatchRequest) CanSend(peer *peer) bool {
+	return peer.HasBlock(r.Ids[0].BlockHash, r.Ids[0].BlockNumber)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *CodeBatchRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting batch of code data", "count", len(r.Hashes))
+	reqs := make([]CodeReq, len(r.Hashes))
+	for i, id := range r.Ids {
+		reqs[i] = CodeReq{
+			BHash:  id.BlockHash,
+			AccKey: id.AccKey,
+		}
+	}
+	return peer.RequestCode(reqID, r.GetCost(peer), reqs)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *CodeBatchRequest) Validate(db aaedb.Database, msg *Msg) error {
+	log.Debug("Validating batch of code data", "count", len(r.Hashes))
+
+	// Ensure we have a correct message with all code elements
+	if msg.MsgType != MsgCode {
+		return errInvalidMessageType
+	}
+	reply := msg.Obj.([][]byte)
+	if len(reply) != len(r.Hashes) {
+		return errInvalidEntryCount
+	}
+	// Verify the data and store if all check out
+	for i, data := range reply {
+		if hash := crypto.Keccak256Hash(data); r.Hashes[i] != hash {
+			return errDataHashMismatch
+		}
+	}
+	r.Data = reply
+	return nil
+}
+
 const (
 	// helper trie type constants
 	htCanonical = iota // Canonical hash trie
@@ -353,7 +496,7 @@ func (r *ChtRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetHeaderProofsMsg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
 	default:
 		panic(nil)
@@ -543,6 +686,108 @@ func (r *BloomRequest) Validate(db aaedb.Database, msg *Msg) error {
 	return nil
 }
 
+// LogsRequest is the ODR request type for logs filtered by the server, see
+// LesOdrRequest interface
+type LogsRequest light.LogsRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *LogsRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetLogsMsg, int(r.ToBlock-r.FromBlock+1))
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *LogsRequest) CanSend(peer *peer) bool {
+	return peer.servesLogs() && peer.headBlockInfo().Number >= r.ToBlock
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *LogsRequest) Request(reqID uint64, peer *peer) error {
+	return peer.RequestLogs(reqID, r.GetCost(peer), LogsReq{
+		FromBlock: r.FromBlock,
+		ToBlock:   r.ToBlock,
+		Addresses: r.Addresses,
+		Topics:    r.Topics,
+	})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *LogsRequest) Validate(db aaedb.Database, msg *Msg) error {
+	log.Debug("Validating filtered logs", "from", r.FromBlock, "to", r.ToBlock)
+
+	if msg.MsgType != MsgLogs {
+		return errInvalidMessageType
+	}
+	resp := msg.Obj.(LogsResp)
+	if resp.Last < r.FromBlock || resp.Last > r.ToBlock {
+		return errLogsRangeMismatch
+	}
+	nodeSet := resp.Proofs.NodeSet()
+	reads := &readTraceDB{db: nodeSet}
+
+	var logs []*types.Log
+	for i, block := range resp.Blocks {
+		if block.Number < r.FromBlock || block.Number > resp.Last || (i > 0 && block.Number <= resp.Blocks[i-1].Number) {
+			return errLogsRangeMismatch
+		}
+		// Retrieve our stored header and validate the receipts against it
+		hash := core.GetCanonicalHash(db, block.Number)
+		header := core.GetHeader(db, hash, block.Number)
+		if header == nil {
+			return errHeaderUnavailable
+		}
+		if !matchBloom(header.Bloom, r.Addresses, r.Topics) {
+			return errLogsBloomMismatch
+		}
+		for j, entry := range block.Receipts {
+			if j > 0 && entry.Index <= block.Receipts[j-1].Index {
+				return errLogsRangeMismatch
+			}
+			key, _ := rlp.EncodeToBytes(uint(entry.Index))
+			value, err, _ := trie.VerifyProof(header.ReceiptHash, key, reads)
+			if err != nil {
+				return fmt.Errorf("merkle proof verification failed: %v", err)
+			}
+			var receipt types.Receipt
+			if err := receipt.UnmarshalBinary(value); err != nil {
+				return err
+			}
+			if value, err, _ = trie.VerifyProof(header.TxHash, key, reads); err != nil {
+				return fmt.Errorf("merkle proof verification failed: %v", err)
+			}
+			var tx types.Transaction
+			if err := tx.UnmarshalBinary(value); err != nil {
+				return err
+			}
+			// Derive the log fields. The position of the first log in the block
+			// can't be proven without all the preceding receipts, so it is taken
+			// from the server as is.
+			matched := false
+			for k, l := range receipt.Logs {
+				l.BlockNumber = block.Number
+				l.BlockHash = hash
+				l.TxHash = tx.Hash()
+				l.TxIndex = uint(entry.Index)
+				l.Index = uint(entry.LogIndex) + uint(k)
+				if matchLog(l, r.Addresses, r.Topics) {
+					logs = append(logs, l)
+					matched = true
+				}
+			}
+			if !matched {
+				return errUselessReceipt
+			}
+		}
+	}
+	if len(reads.reads) != nodeSet.KeyCount() {
+		return errUselessNodes
+	}
+	r.Logs, r.Last = logs, resp.Last
+	return nil
+}
+
 // readTraceDB stores the keys of database reads. We use this to check that received node
 // sets contain only the trie nodes necessary to make proofs pass.
 type readTraceDB struct {
@@ -108,6 +108,30 @@ func TestOdrContractCallLes1(t *testing.T) { testOdr(t, 1, 2, odrContractCall) }
 
 func TestOdrContractCallLes2(t *testing.T) { testOdr(t, 2, 2, odrContractCall) }
 
+func TestOdrContractCallPrefetchLes1(t *testing.T) { testOdr(t, 1, 2, odrContractCallPrefetch) }
+
+func TestOdrContractCallPrefetchLes2(t *testing.T) { testOdr(t, 2, 2, odrContractCallPrefetch) }
+
+// odrContractCallPrefetch performs the contract calls of odrContractCall after
+// retrieving their state in batched requests.
+func odrContractCallPrefetch(ctx context.Context, db aaedb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
+	if lc != nil {
+		header := lc.GetHeaderByHash(bhash)
+		data := common.Hex2Bytes("60CD26850000000000000000000000000000000000000000000000000000000000000000")
+		for i := 0; i < 3; i++ {
+			data[35] = byte(i)
+			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, nil, false)}
+			light.Prefetch(ctx, header, lc.Odr(), func(statedb *state.StateDB) {
+				statedb.SetBalance(testBankAddress, math.MaxBig256)
+				context := core.NewEVMContext(msg, header, lc, nil)
+				vmenv := vm.NewEVM(context, statedb, config, vm.Config{})
+				core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(math.MaxUint64))
+			})
+		}
+	}
+	return odrContractCall(ctx, db, config, bc, lc, bhash)
+}
+
 type callmsg struct {
 	types.Message
 }
@@ -128,7 +152,7 @@ func odrContractCall(ctx context.Context, db aaedb.Database, config *params.Chai
 				from := statedb.GetOrNewStateObject(testBankAddress)
 				from.SetBalance(math.MaxBig256)
 
-				msg := callmsg{types.NewMessage(from.Address(), &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, false)}
+				msg := callmsg{types.NewMessage(from.Address(), &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, nil, false)}
 
 				context := core.NewEVMContext(msg, header, bc, nil)
 				vmenv := vm.NewEVM(context, statedb, config, vm.Config{})
@@ -142,7 +166,7 @@ func odrContractCall(ctx context.Context, db aaedb.Database, config *params.Chai
 			header := lc.GetHeaderByHash(bhash)
 			state := light.NewState(ctx, header, lc.Odr())
 			state.SetBalance(testBankAddress, math.MaxBig256)
-			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, false)}
+			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, nil, false)}
 			context := core.NewEVMContext(msg, header, lc, nil)
 			vmenv := vm.NewEVM(context, state, config, vm.Config{})
 			gp := new(core.GasPool).AddGas(math.MaxUint64)
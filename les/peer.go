@@ -59,6 +59,7 @@ type peer struct {
 	network uint64 // Network ID being on
 
 	announceType, requestAnnounceType uint64
+	isTrusted                         bool // Trusted server of an ultra-light client
 
 	id string
 
@@ -72,8 +73,9 @@ type peer struct {
 	hasBlock       func(common.Hash, uint64) bool
 	responseErrors int
 
-	fcClient       *flowcontrol.ClientNode // nil if the peer is server only
-	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
+	fcClient       *flowcontrol.ClientNode   // nil if the peer is server only
+	fcParams       *flowcontrol.ServerParams // Flow control parameters assigned to a client peer
+	fcServer       *flowcontrol.ServerNode   // nil if the peer is client only
 	fcServerParams *flowcontrol.ServerParams
 	fcCosts        requestCostTable
 }
@@ -231,6 +233,11 @@ func (p *peer) SendHelperTrieProofs(reqID, bv uint64, resp HelperTrieResps) erro
 	return sendResponse(p.rw, HelperTrieProofsMsg, reqID, bv, resp)
 }
 
+// SendLogs sends the receipts matching a log filter along with their proofs.
+func (p *peer) SendLogs(reqID, bv uint64, resp LogsResp) error {
+	return sendResponse(p.rw, LogsMsg, reqID, bv, resp)
+}
+
 // SendTxStatus sends a batch of transaction status records, corresponding to the ones requested.
 func (p *peer) SendTxStatus(reqID, bv uint64, stats []txStatus) error {
 	return sendResponse(p.rw, TxStatusMsg, reqID, bv, stats)
@@ -276,7 +283,7 @@ func (p *peer) RequestProofs(reqID, cost uint64, reqs []ProofReq) error {
 	switch p.version {
 	case lpv1:
 		return sendRequest(p.rw, GetProofsV1Msg, reqID, cost, reqs)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetProofsV2Msg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -298,7 +305,7 @@ func (p *peer) RequestHelperTrieProofs(reqID, cost uint64, reqs []HelperTrieReq)
 			reqsV1[i] = ChtReq{ChtNum: (req.TrieIdx + 1) * (light.CHTFrequencyClient / light.CHTFrequencyServer), BlockNum: blockNum, FromLevel: req.FromLevel}
 		}
 		return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqsV1)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetHelperTrieProofsMsg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -311,13 +318,29 @@ func (p *peer) RequestTxStatus(reqID, cost uint64, txHashes []common.Hash) error
 	return sendRequest(p.rw, GetTxStatusMsg, reqID, cost, txHashes)
 }
 
+// RequestLogs fetches the receipts matching a log filter in a block range from
+// a remote node.
+func (p *peer) RequestLogs(reqID, cost uint64, query LogsReq) error {
+	p.Log().Debug("Requesting filtered logs", "from", query.FromBlock, "to", query.ToBlock)
+	return sendRequest(p.rw, GetLogsMsg, reqID, cost, query)
+}
+
+// servesLogs tells if the remote node serves log filtering requests.
+func (p *peer) servesLogs() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.fcCosts[GetLogsMsg]
+	return ok && p.version >= lpv3
+}
+
 // SendTxStatus sends a batch of transactions to be added to the remote transaction pool.
 func (p *peer) SendTxs(reqID, cost uint64, txs types.Transactions) error {
 	p.Log().Debug("Fetching batch of transactions", "count", len(txs))
 	switch p.version {
 	case lpv1:
 		return p2p.Send(p.rw, SendTxMsg, txs) // old message format does not include reqID
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, SendTxV2Msg, reqID, cost, txs)
 	default:
 		panic(nil)
@@ -409,13 +432,16 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		send = send.add("serveChainSince", uint64(0))
 		send = send.add("serveStateSince", uint64(0))
 		send = send.add("txRelay", nil)
-		send = send.add("flowControl/BL", server.defParams.BufLimit)
-		send = send.add("flowControl/MRR", server.defParams.MinRecharge)
+		send = send.add("flowControl/BL", p.fcParams.BufLimit)
+		send = send.add("flowControl/MRR", p.fcParams.MinRecharge)
 		list := server.fcCosaaeats.getCurrentList()
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
 	} else {
-		p.requestAnnounceType = announceTypeSimple // set to default until "very light" client mode is implemented
+		p.requestAnnounceType = announceTypeSimple
+		if p.isTrusted {
+			p.requestAnnounceType = announceTypeSigned
+		}
 		send = send.add("announceType", p.requestAnnounceType)
 	}
 	recvList, err := p.sendReceiveHandshake(send)
@@ -464,7 +490,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if recv.get("announceType", &p.announceType) != nil {
 			p.announceType = announceTypeSimple
 		}
-		p.fcClient = flowcontrol.NewClientNode(server.fcManager, server.defParams)
+		p.fcClient = flowcontrol.NewClientNode(server.fcManager, p.fcParams)
 	} else {
 		if recv.get("serveChainSince", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot serve chain")
@@ -24,6 +24,7 @@ import (
 	"sync"
 
 	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/contracts/checkpointoracle"
 	"github.com/aaechain/go-aaechain/core"
 	"github.com/aaechain/go-aaechain/core/types"
 	"github.com/aaechain/go-aaechain/aae"
@@ -34,6 +35,7 @@ import (
 	"github.com/aaechain/go-aaechain/p2p"
 	"github.com/aaechain/go-aaechain/p2p/discv5"
 	"github.com/aaechain/go-aaechain/rlp"
+	"github.com/aaechain/go-aaechain/rpc"
 )
 
 type LesServer struct {
@@ -42,6 +44,7 @@ type LesServer struct {
 	fcManager       *flowcontrol.ClientManager // nil if our node is client only
 	fcCosaaeats     *requestCosaaeats
 	defParams       *flowcontrol.ServerParams
+	clientPool      *clientPool
 	lesTopics       []discv5.Topic
 	privateKey      *ecdsa.PrivateKey
 	quitSync        chan struct{}
@@ -97,15 +100,52 @@ func NewLesServer(aae *aae.aaechain, config *aae.Config) (*LesServer, error) {
 		BufLimit:    300000000,
 		MinRecharge: 50000,
 	}
+	srv.clientPool = newClientPool(srv.defParams, config.LightPeers, config.LightPriorityClients)
 	srv.fcManager = flowcontrol.NewClientManager(uint64(config.LightServ), 10, 1000000000)
 	srv.fcCosaaeats = newCosaaeats(aae.ChainDb())
 	return srv, nil
 }
 
+// latestCheckpoint returns the roots of the latest LES/2 section indexed by
+// both the CHT and the bloom trie indexer, or nil if there is none.
+func (s *LesServer) latestCheckpoint() *checkpointoracle.Checkpoint {
+	chtV1SectionCount, _, _ := s.chtIndexer.Sections()
+	sections := chtV1SectionCount / (light.CHTFrequencyClient / light.CHTFrequencyServer)
+	if bloomTrieSectionCount, _, _ := s.bloomTrieIndexer.Sections(); bloomTrieSectionCount < sections {
+		sections = bloomTrieSectionCount
+	}
+	if sections == 0 {
+		return nil
+	}
+	var (
+		index = sections - 1
+		db    = s.protocolManager.chainDb
+		head  = s.chtIndexer.SectionHead((index+1)*(light.CHTFrequencyClient/light.CHTFrequencyServer) - 1)
+	)
+	return &checkpointoracle.Checkpoint{
+		SectionIndex:  index,
+		SectionHead:   head,
+		ChtRoot:       light.GetChtV2Root(db, index, head),
+		BloomTrieRoot: light.GetBloomTrieRoot(db, index, head),
+	}
+}
+
 func (s *LesServer) Protocols() []p2p.Protocol {
 	return s.protocolManager.SubProtocols
 }
 
+// APIs returns the APIs of the light server.
+func (s *LesServer) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPrivateLightServerAPI(s),
+			Public:    false,
+		},
+	}
+}
+
 // Start starts the LES server
 func (s *LesServer) Start(srvr *p2p.Server) {
 	s.protocolManager.Start(s.config.LightPeers)
@@ -0,0 +1,72 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+
+	"github.com/aaechain/go-aaechain/aae"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+)
+
+// ulcHistory is the number of recent headers kept by an ultra-light client.
+const ulcHistory = 1024
+
+// errUltraLightLogs is returned for log queries reaching below the recent
+// headers of an ultra-light client, which neither indexes the chain nor keeps
+// the headers the logs could be verified against.
+var errUltraLightLogs = errors.New("log filtering below the recent headers is unsupported in ultra-light mode")
+
+// ulc holds the set of trusted servers of an ultra-light client. Such a client
+// accepts a new head without verifying the header chain once a large enough
+// fraction of the trusted servers announced it with a signed announcement.
+type ulc struct {
+	trusted  map[discover.NodeID]struct{}
+	fraction int // Percentage of trusted servers required to agree on a head
+}
+
+// newULC creates the ultra-light client configuration, or returns nil if no
+// trusted servers are configured.
+func newULC(servers []discover.NodeID, fraction int) *ulc {
+	if len(servers) == 0 {
+		return nil
+	}
+	if fraction <= 0 || fraction > 100 {
+		log.Warn("Invalid ultra-light trusted fraction, using default", "fraction", fraction, "default", aae.DefaultConfig.UltraLightFraction)
+		fraction = aae.DefaultConfig.UltraLightFraction
+	}
+	u := &ulc{
+		trusted:  make(map[discover.NodeID]struct{}),
+		fraction: fraction,
+	}
+	for _, id := range servers {
+		u.trusted[id] = struct{}{}
+	}
+	return u
+}
+
+// isTrusted tells if the given server is one of the trusted servers.
+func (u *ulc) isTrusted(id discover.NodeID) bool {
+	_, ok := u.trusted[id]
+	return ok
+}
+
+// agreed tells if the given number of trusted servers is enough to accept a head.
+func (u *ulc) agreed(count int) bool {
+	return count*100 >= u.fraction*len(u.trusted)
+}
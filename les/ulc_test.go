@@ -0,0 +1,94 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aaechain/go-aaechain/aae"
+	"github.com/aaechain/go-aaechain/aae/filters"
+	"github.com/aaechain/go-aaechain/aaedb"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+)
+
+// Tests the ultra-light client configuration.
+func TestULCConfig(t *testing.T) {
+	if newULC(nil, 50) != nil {
+		t.Fatalf("ultra-light mode enabled without trusted servers")
+	}
+	servers := []discover.NodeID{{1}, {2}, {3}, {4}}
+	if u := newULC(servers, 0); u.fraction != aae.DefaultConfig.UltraLightFraction {
+		t.Fatalf("invalid fraction not replaced: have %d, want %d", u.fraction, aae.DefaultConfig.UltraLightFraction)
+	}
+	u := newULC(servers, 50)
+	if !u.isTrusted(discover.NodeID{2}) || u.isTrusted(discover.NodeID{5}) {
+		t.Fatalf("trusted servers mismatch")
+	}
+	for count, want := range []bool{false, false, true, true, true} {
+		if u.agreed(count) != want {
+			t.Errorf("agreement of %d servers: have %v, want %v", count, !want, want)
+		}
+	}
+}
+
+// Tests that an ultra-light client only accepts heads announced by enough
+// trusted servers.
+func TestULCTrustedAgreed(t *testing.T) {
+	servers := []discover.NodeID{{1}, {2}, {3}}
+	f := &lightFetcher{
+		pm:    &ProtocolManager{ulc: newULC(servers, 60)},
+		peers: make(map[*peer]*fetcherPeerInfo),
+	}
+	head := common.Hash{0xff}
+	announce := func(trusted bool) {
+		fp := &fetcherPeerInfo{nodeByHash: make(map[common.Hash]*fetcherTreeNode)}
+		fp.nodeByHash[head] = &fetcherTreeNode{hash: head}
+		f.peers[&peer{isTrusted: trusted}] = fp
+	}
+	announce(true)
+	announce(false)
+	announce(false)
+	if f.trustedAgreed(head) {
+		t.Fatalf("head accepted with one trusted announcement")
+	}
+	announce(true)
+	if !f.trustedAgreed(head) {
+		t.Fatalf("head not accepted with two trusted announcements")
+	}
+	if f.trustedAgreed(common.Hash{0xee}) {
+		t.Fatalf("unannounced head accepted")
+	}
+}
+
+// Tests that an ultra-light client rejects log queries below its recent
+// headers instead of falling back to local matching.
+func TestULCFilterLogs(t *testing.T) {
+	db, _ := aaedb.NewMemDatabase()
+	b := &LesApiBackend{aae: &Lightaaechain{
+		chainDb:         db,
+		protocolManager: &ProtocolManager{ulc: newULC([]discover.NodeID{{1}}, 50)},
+	}}
+	if _, err := b.FilterLogs(context.Background(), 1, 10, nil, nil); err != errUltraLightLogs {
+		t.Fatalf("ultra-light filter error mismatch: have %v, want %v", err, errUltraLightLogs)
+	}
+	b.aae.protocolManager.ulc = nil
+	if _, err := b.FilterLogs(context.Background(), 1, 10, nil, nil); err != filters.ErrRangeFilterUnavailable {
+		t.Fatalf("light filter error mismatch: have %v, want %v", err, filters.ErrRangeFilterUnavailable)
+	}
+}
@@ -19,6 +19,7 @@ package light
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 	"sync"
 	"sync/atomic"
@@ -68,7 +69,8 @@ type LightChain struct {
 	procInterrupt int32 // interrupt signaler for block processing
 	wg            sync.WaitGroup
 
-	engine consensus.Engine
+	engine  consensus.Engine
+	history uint64 // Number of recent headers kept in ultra-light mode, zero if disabled
 }
 
 // NewLightChain returns a fully initialised light chain using information
@@ -130,6 +132,25 @@ func (self *LightChain) addTrustedCheckpoint(cp trustedCheckpoint) {
 	log.Info("Added trusted checkpoint", "chain", cp.name, "block", (cp.sectionIdx+1)*CHTFrequencyClient-1, "hash", cp.sectionHead)
 }
 
+// AddTrustedCheckpoint adds a checkpoint obtained at runtime (e.g. from an
+// on-chain oracle) to the blockchain. It returns false if the section is
+// already known by the CHT indexer.
+func (self *LightChain) AddTrustedCheckpoint(name string, sectionIdx uint64, sectionHead, chtRoot, bloomTrieRoot common.Hash) bool {
+	if indexer := self.odr.ChtIndexer(); indexer != nil {
+		if sections, _, _ := indexer.Sections(); sectionIdx < sections {
+			return false
+		}
+	}
+	self.addTrustedCheckpoint(trustedCheckpoint{
+		name:          name,
+		sectionIdx:    sectionIdx,
+		sectionHead:   sectionHead,
+		chtRoot:       chtRoot,
+		bloomTrieRoot: bloomTrieRoot,
+	})
+	return true
+}
+
 func (self *LightChain) getProcInterrupt() bool {
 	return atomic.LoadInt32(&self.procInterrupt) == 1
 }
@@ -344,7 +365,15 @@ func (self *LightChain) postChainEvents(events []interface{}) {
 // chain events when necessary.
 func (self *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (int, error) {
 	start := time.Now()
-	if i, err := self.hc.ValidateHeaderChain(chain, checkFreq); err != nil {
+	if atomic.LoadUint64(&self.history) != 0 {
+		// Headers are trusted in ultra-light mode, only check that they're linked
+		for i := 1; i < len(chain); i++ {
+			if chain[i].Number.Uint64() != chain[i-1].Number.Uint64()+1 || chain[i].ParentHash != chain[i-1].Hash() {
+				return i, fmt.Errorf("non contiguous insert: item %d is #%d [%x…], item %d is #%d [%x…] (parent [%x…])", i-1, chain[i-1].Number,
+					chain[i-1].Hash().Bytes()[:4], i, chain[i].Number, chain[i].Hash().Bytes()[:4], chain[i].ParentHash[:4])
+			}
+		}
+	} else if i, err := self.hc.ValidateHeaderChain(chain, checkFreq); err != nil {
 		return i, err
 	}
 
@@ -377,10 +406,46 @@ func (self *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 		return err
 	}
 	i, err := self.hc.InsertHeaderChain(chain, whFunc, start)
+	if history := atomic.LoadUint64(&self.history); history != 0 {
+		self.pruneHeaders(history)
+	}
 	self.postChainEvents(events)
 	return i, err
 }
 
+// SetUltraLight switches the chain to ultra-light mode, in which the inserted
+// headers are trusted without verifying them and only the given number of
+// recent canonical headers is kept. Older ones are retrieved on demand.
+func (self *LightChain) SetUltraLight(history uint64) {
+	atomic.StoreUint64(&self.history, history)
+
+	self.chainmu.Lock()
+	defer self.chainmu.Unlock()
+	self.pruneHeaders(history)
+}
+
+// pruneHeaders deletes the canonical headers older than the given number of
+// recent ones, keeping the genesis. Deletion proceeds backwards until a header
+// which has already been deleted is found.
+func (self *LightChain) pruneHeaders(history uint64) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	head := self.hc.CurrentHeader().Number.Uint64()
+	if head <= history {
+		return
+	}
+	for number := head - history; number > 0; number-- {
+		hash := core.GetCanonicalHash(self.chainDb, number)
+		if hash == (common.Hash{}) {
+			break
+		}
+		core.DeleteCanonicalHash(self.chainDb, number)
+		core.DeleteHeader(self.chainDb, hash, number)
+		core.DeleteTd(self.chainDb, hash, number)
+	}
+}
+
 // CurrentHeader retrieves the current head header of the canonical chain. The
 // header is retrieved from the HeaderChain's internal cache.
 func (self *LightChain) CurrentHeader() *types.Header {
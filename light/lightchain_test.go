@@ -241,6 +241,43 @@ func TestBrokenHeaderChain(t *testing.T) {
 	}
 }
 
+// Tests that an ultra-light chain accepts headers without verifying them but
+// keeps only the recent ones.
+func TestUltraLightHeaders(t *testing.T) {
+	db, _ := aaedb.NewMemDatabase()
+	gspec := core.Genesis{Config: params.TestChainConfig}
+	genesis := gspec.MustCommit(db)
+	lightchain, _ := NewLightChain(&dummyOdr{db: db}, gspec.Config, ethash.NewFakeFailer(5))
+
+	headers := makeHeaderChain(genesis.Header(), 20, db, canonicalSeed)
+	if _, err := lightchain.InsertHeaderChain(headers[:10], 1); err == nil {
+		t.Fatalf("invalid header accepted")
+	}
+	lightchain.SetUltraLight(8)
+	if _, err := lightchain.InsertHeaderChain(headers[:10], 1); err != nil {
+		t.Fatalf("failed to insert trusted headers: %v", err)
+	}
+	if _, err := lightchain.InsertHeaderChain([]*types.Header{headers[11], headers[10]}, 1); err == nil {
+		t.Fatalf("non contiguous headers accepted")
+	}
+	if _, err := lightchain.InsertHeaderChain(headers[10:], 1); err != nil {
+		t.Fatalf("failed to insert trusted headers: %v", err)
+	}
+	if head := lightchain.CurrentHeader().Hash(); head != headers[19].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head, headers[19].Hash())
+	}
+	for i, header := range headers {
+		number := header.Number.Uint64()
+		kept := core.GetCanonicalHash(db, number) != (common.Hash{})
+		if want := number > 12; kept != want {
+			t.Errorf("header %d: kept %v, want %v", i, kept, want)
+		}
+	}
+	if core.GetCanonicalHash(db, 0) != genesis.Hash() {
+		t.Errorf("genesis header pruned")
+	}
+}
+
 func makeHeaderChainWithDiff(genesis *types.Block, d []int, seed byte) []*types.Header {
 	var chain []*types.Header
 	for i, difficulty := range d {
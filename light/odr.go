@@ -102,6 +102,36 @@ func (req *CodeRequest) StoreResult(db aaedb.Database) {
 	db.Put(req.Hash[:], req.Data)
 }
 
+// TrieBatchRequest is the ODR request type for retrieving multiple state and
+// storage trie entries of the same block at once
+type TrieBatchRequest struct {
+	OdrRequest
+	Ids   []*TrieID
+	Keys  [][]byte
+	Proof *NodeSet
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *TrieBatchRequest) StoreResult(db aaedb.Database) {
+	req.Proof.Store(db)
+}
+
+// CodeBatchRequest is the ODR request type for retrieving multiple contract
+// codes of the same block at once
+type CodeBatchRequest struct {
+	OdrRequest
+	Ids    []*TrieID // reference storage tries of the accounts
+	Hashes []common.Hash
+	Data   [][]byte
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *CodeBatchRequest) StoreResult(db aaedb.Database) {
+	for i, hash := range req.Hashes {
+		db.Put(hash[:], req.Data[i])
+	}
+}
+
 // BlockRequest is the ODR request type for retrieving block bodies
 type BlockRequest struct {
 	OdrRequest
@@ -128,6 +158,21 @@ func (req *ReceiptsRequest) StoreResult(db aaedb.Database) {
 	core.WriteBlockReceipts(db, req.Hash, req.Number, req.Receipts)
 }
 
+// LogsRequest is the ODR request type for retrieving the logs matching a filter
+// in a range of canonical blocks. The server may cover only the beginning of
+// the range, Last is the number of the last block the result is complete for.
+type LogsRequest struct {
+	OdrRequest
+	FromBlock, ToBlock uint64
+	Addresses          []common.Address
+	Topics             [][]common.Hash
+	Logs               []*types.Log
+	Last               uint64
+}
+
+// StoreResult implements OdrRequest, filtered logs are not stored locally
+func (req *LogsRequest) StoreResult(db aaedb.Database) {}
+
 // ChtRequest is the ODR request type for state/storage trie entries
 type ChtRequest struct {
 	OdrRequest
@@ -80,6 +80,18 @@ func (odr *testOdr) Retrieve(ctx context.Context, req OdrRequest) error {
 		req.Proof = nodes
 	case *CodeRequest:
 		req.Data, _ = odr.sdb.Get(req.Hash[:])
+	case *TrieBatchRequest:
+		nodes := NewNodeSet()
+		for i, key := range req.Keys {
+			t, _ := trie.New(req.Ids[i].Root, trie.NewDatabase(odr.sdb))
+			t.Prove(key, 0, nodes)
+		}
+		req.Proof = nodes
+	case *CodeBatchRequest:
+		req.Data = make([][]byte, len(req.Hashes))
+		for i, hash := range req.Hashes {
+			req.Data[i], _ = odr.sdb.Get(hash[:])
+		}
 	}
 	req.StoreResult(odr.ldb)
 	return nil
@@ -176,7 +188,7 @@ func odrContractCall(ctx context.Context, db aaedb.Database, bc *core.BlockChain
 
 		// Perform read-only call.
 		st.SetBalance(testBankAddress, math.MaxBig256)
-		msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 1000000, new(big.Int), data, false)}
+		msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 1000000, new(big.Int), data, nil, false)}
 		context := core.NewEVMContext(msg, header, chain, nil)
 		vmenv := vm.NewEVM(context, st, config, vm.Config{})
 		gp := new(core.GasPool).AddGas(math.MaxUint64)
@@ -189,6 +201,53 @@ func odrContractCall(ctx context.Context, db aaedb.Database, bc *core.BlockChain
 	return res, nil
 }
 
+func TestOdrContractCallPrefetchLes1(t *testing.T) { testChainOdr(t, 1, odrContractCallPrefetch) }
+
+// prefetchedOdr fails all single trie entry and code retrievals, ensuring that
+// the state of a call is available after prefetching it.
+type prefetchedOdr struct {
+	OdrBackend
+}
+
+func (odr prefetchedOdr) Retrieve(ctx context.Context, req OdrRequest) error {
+	switch req.(type) {
+	case *TrieRequest, *CodeRequest:
+		return ErrOdrDisabled
+	}
+	return odr.OdrBackend.Retrieve(ctx, req)
+}
+
+func odrContractCallPrefetch(ctx context.Context, db aaedb.Database, bc *core.BlockChain, lc *LightChain, bhash common.Hash) ([]byte, error) {
+	if bc != nil {
+		return odrContractCall(ctx, db, bc, lc, bhash)
+	}
+	data := common.Hex2Bytes("60CD26850000000000000000000000000000000000000000000000000000000000000000")
+	header := lc.GetHeaderByHash(bhash)
+
+	var res []byte
+	for i := 0; i < 3; i++ {
+		data[35] = byte(i)
+		msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 1000000, new(big.Int), data, nil, false)}
+		call := func(st *state.StateDB) []byte {
+			st.SetBalance(testBankAddress, math.MaxBig256)
+			context := core.NewEVMContext(msg, header, lc, nil)
+			vmenv := vm.NewEVM(context, st, params.TestChainConfig, vm.Config{})
+			gp := new(core.GasPool).AddGas(math.MaxUint64)
+			ret, _, _, _ := core.ApplyMessage(vmenv, msg, gp)
+			return ret
+		}
+		if err := Prefetch(ctx, header, lc.Odr(), func(st *state.StateDB) { call(st) }); err != nil {
+			return res, err
+		}
+		st := NewState(ctx, header, prefetchedOdr{lc.Odr()})
+		res = append(res, call(st)...)
+		if st.Error() != nil {
+			return res, st.Error()
+		}
+	}
+	return res, nil
+}
+
 func testChainGen(i int, block *core.BlockGen) {
 	signer := types.HomesteadSigner{}
 	switch i {
@@ -170,6 +170,21 @@ func GetBlockLogs(ctx context.Context, odr OdrBackend, hash common.Hash, number
 	return logs, nil
 }
 
+// GetFilteredLogs retrieves the logs matching the given criteria in a range of
+// canonical blocks, filtered by the server. It returns the number of the last
+// block covered by the result, which may be lower than to.
+//
+// The receipts and transactions the logs are derived from are verified against
+// the local headers, however the server is trusted not to omit matching logs
+// and to report the correct log index (Log.Index) within each block.
+func GetFilteredLogs(ctx context.Context, odr OdrBackend, from, to uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, uint64, error) {
+	r := &LogsRequest{FromBlock: from, ToBlock: to, Addresses: addresses, Topics: topics}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, 0, err
+	}
+	return r.Logs, r.Last, nil
+}
+
 // GetBloomBits retrieves a batch of compressed bloomBits vectors belonging to the given bit index and section indexes
 func GetBloomBits(ctx context.Context, odr OdrBackend, bitIdx uint, sectionIdxList []uint64) ([][]byte, error) {
 	db := odr.Database()
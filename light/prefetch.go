@@ -0,0 +1,125 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/core/state"
+	"github.com/aaechain/go-aaechain/core/types"
+)
+
+const (
+	maxPrefetchRounds = 4  // Maximum number of times the accessed state is discovered and retrieved
+	maxPrefetchBatch  = 64 // Maximum number of trie entries or codes retrieved in a single request
+)
+
+// missingState collects the trie entries and contract codes which were accessed
+// but are not available locally.
+type missingState struct {
+	ids     []*TrieID
+	keys    [][]byte
+	codeIds []*TrieID
+	codes   []common.Hash
+	seen    map[string]struct{}
+}
+
+func newMissingState() *missingState {
+	return &missingState{seen: make(map[string]struct{})}
+}
+
+func (m *missingState) addEntry(id *TrieID, key []byte) {
+	ref := string(id.AccKey) + "/" + string(key)
+	if _, ok := m.seen[ref]; ok {
+		return
+	}
+	m.seen[ref] = struct{}{}
+	m.ids = append(m.ids, id)
+	m.keys = append(m.keys, key)
+}
+
+func (m *missingState) addCode(id *TrieID, hash common.Hash) {
+	ref := string(hash[:])
+	if _, ok := m.seen[ref]; ok {
+		return
+	}
+	m.seen[ref] = struct{}{}
+	m.codeIds = append(m.codeIds, id)
+	m.codes = append(m.codes, hash)
+}
+
+// requests splits the missing state into batched retrieval requests.
+func (m *missingState) requests() []OdrRequest {
+	var reqs []OdrRequest
+	for len(m.keys) > 0 {
+		n := len(m.keys)
+		if n > maxPrefetchBatch {
+			n = maxPrefetchBatch
+		}
+		reqs = append(reqs, &TrieBatchRequest{Ids: m.ids[:n], Keys: m.keys[:n]})
+		m.ids, m.keys = m.ids[n:], m.keys[n:]
+	}
+	for len(m.codes) > 0 {
+		n := len(m.codes)
+		if n > maxPrefetchBatch {
+			n = maxPrefetchBatch
+		}
+		reqs = append(reqs, &CodeBatchRequest{Ids: m.codeIds[:n], Hashes: m.codes[:n]})
+		m.codeIds, m.codes = m.codeIds[n:], m.codes[n:]
+	}
+	return reqs
+}
+
+// Prefetch retrieves the state accessed by fn at the given header in a few
+// batched requests, instead of one request per trie entry and contract code.
+// fn is run on a state which records the missing entries and codes rather than
+// retrieving them. They are then retrieved concurrently, stored in the local
+// database, and fn is run again to discover state accessed depending on them.
+// This is repeated until no more state is missing, at most maxPrefetchRounds
+// times. fn should be free of side effects and its errors are ignored, since
+// it runs on incomplete state.
+func Prefetch(ctx context.Context, head *types.Header, odr OdrBackend, fn func(*state.StateDB)) error {
+	for i := 0; i < maxPrefetchRounds; i++ {
+		missing := newMissingState()
+		statedb, err := state.New(head.Root, &odrDatabase{ctx: ctx, id: StateTrieID(head), backend: odr, missing: missing})
+		if err != nil {
+			return err
+		}
+		fn(statedb)
+
+		reqs := missing.requests()
+		if len(reqs) == 0 {
+			return nil
+		}
+		errc := make(chan error, len(reqs))
+		for _, req := range reqs {
+			go func(req OdrRequest) {
+				errc <- odr.Retrieve(ctx, req)
+			}(req)
+		}
+		for range reqs {
+			if e := <-errc; e != nil && err == nil {
+				err = e
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
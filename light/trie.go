@@ -35,13 +35,14 @@ func NewState(ctx context.Context, head *types.Header, odr OdrBackend) *state.St
 }
 
 func NewStateDatabase(ctx context.Context, head *types.Header, odr OdrBackend) state.Database {
-	return &odrDatabase{ctx, StateTrieID(head), odr}
+	return &odrDatabase{ctx: ctx, id: StateTrieID(head), backend: odr}
 }
 
 type odrDatabase struct {
 	ctx     context.Context
 	id      *TrieID
 	backend OdrBackend
+	missing *missingState // Records missing state instead of retrieving it, if set
 }
 
 func (db *odrDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
@@ -75,6 +76,10 @@ func (db *odrDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, err
 	}
 	id := *db.id
 	id.AccKey = addrHash[:]
+	if db.missing != nil {
+		db.missing.addCode(&id, codeHash)
+		return nil, nil
+	}
 	req := &CodeRequest{Id: &id, Hash: codeHash}
 	err := db.backend.Retrieve(db.ctx, req)
 	return req.Data, err
@@ -159,6 +164,10 @@ func (t *odrTrie) do(key []byte, fn func() error) error {
 		if _, ok := err.(*trie.MissingNodeError); !ok {
 			return err
 		}
+		if t.db.missing != nil {
+			t.db.missing.addEntry(t.id, key)
+			return nil
+		}
 		r := &TrieRequest{Id: t.id, Key: key}
 		if err := t.db.backend.Retrieve(t.db.ctx, r); err != nil {
 			return err
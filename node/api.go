@@ -75,6 +75,32 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// PeerScores returns the reputation scores of the nodes which have been
+// scored since the node was started.
+func (api *PrivateAdminAPI) PeerScores() ([]*p2p.PeerScore, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeerScores(), nil
+}
+
+// ResetPeerScore clears the reputation score of a remote node and lifts any
+// ban on it.
+func (api *PrivateAdminAPI) ResetPeerScore(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.ResetPeerScore(node.ID)
+	return true, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -26,6 +26,7 @@ import (
 
 	"github.com/aaechain/go-aaechain/log"
 	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/p2p/netutil"
 )
 
@@ -77,6 +78,7 @@ type dialstate struct {
 	dialing       map[discover.NodeID]connFlag
 	lookupBuf     []*discover.Node // current discovery lookup results
 	randomNodes   []*discover.Node // filled from Table
+	sources       []nodeSource     // additional dial candidate sources
 	static        map[discover.NodeID]*dialTask
 	hist          *dialHistory
 
@@ -90,6 +92,7 @@ type discoverTable interface {
 	Resolve(target discover.NodeID) *discover.Node
 	Lookup(target discover.NodeID) []*discover.Node
 	ReadRandomNodes([]*discover.Node) int
+	RequestENR(*discover.Node) (*enr.Record, error)
 }
 
 // the dial history remembers recent dials.
@@ -101,6 +104,11 @@ type pastDial struct {
 	exp time.Time
 }
 
+// nodeSource provides dial candidates in addition to the discovery table.
+type nodeSource interface {
+	ReadRandomNodes([]*discover.Node) int
+}
+
 type task interface {
 	Do(*Server)
 }
@@ -145,6 +153,11 @@ func newDialState(static []*discover.Node, bootnodes []*discover.Node, ntab disc
 	return s
 }
 
+// addSource adds a source of dynamic dial candidates.
+func (s *dialstate) addSource(src nodeSource) {
+	s.sources = append(s.sources, src)
+}
+
 func (s *dialstate) addStatic(n *discover.Node) {
 	// This overwites the task instead of updating an existing
 	// entry, giving users the opportunity to force a resolve operation.
@@ -218,7 +231,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 	// Use random nodes from the table for half of the necessary
 	// dynamic dials.
 	randomCandidates := needDynDials / 2
-	if randomCandidates > 0 {
+	if randomCandidates > 0 && s.ntab != nil {
 		n := s.ntab.ReadRandomNodes(s.randomNodes)
 		for i := 0; i < randomCandidates && i < n; i++ {
 			if addDial(dynDialedConn, s.randomNodes[i]) {
@@ -226,6 +239,23 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 			}
 		}
 	}
+	// Take nodes from the other sources. Without a discovery table,
+	// they are the only source of dynamic dial candidates.
+	for _, src := range s.sources {
+		limit := needDynDials / 2
+		if s.ntab == nil {
+			limit = needDynDials
+		}
+		if limit == 0 {
+			break
+		}
+		n := src.ReadRandomNodes(s.randomNodes)
+		for i := 0; i < limit && i < n; i++ {
+			if addDial(dynDialedConn, s.randomNodes[i]) {
+				needDynDials--
+			}
+		}
+	}
 	// Create dynamic dials from random lookup results, removing tried
 	// items from the result buffer.
 	i := 0
@@ -236,7 +266,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 	}
 	s.lookupBuf = s.lookupBuf[:copy(s.lookupBuf, s.lookupBuf[i:])]
 	// Launch a discovery lookup if more candidates are needed.
-	if len(s.lookupBuf) < needDynDials && !s.lookupRunning {
+	if len(s.lookupBuf) < needDynDials && !s.lookupRunning && s.ntab != nil {
 		s.lookupRunning = true
 		newtasks = append(newtasks, &discoverTask{})
 	}
@@ -258,6 +288,8 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errRecordRejected   = errors.New("node record rejected")
+	errBanned           = errors.New("banned for low reputation")
 )
 
 func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer) error {
@@ -294,6 +326,13 @@ func (t *dialTask) Do(srv *Server) {
 			return
 		}
 	}
+	if t.flags&dynDialedConn != 0 && srv.rep.banned(t.dest.ID) {
+		log.Trace("Skipping dial candidate", "id", t.dest.ID, "addr", &net.TCPAddr{IP: t.dest.IP, Port: int(t.dest.TCP)}, "err", errBanned)
+		return
+	}
+	if t.flags&dynDialedConn != 0 && !t.checkRecord(srv) {
+		return
+	}
 	err := t.dial(srv, t.dest)
 	if err != nil {
 		log.Trace("Dial error", "task", t, "err", err)
@@ -306,6 +345,25 @@ func (t *dialTask) Do(srv *Server) {
 	}
 }
 
+// checkRecord retrieves the node record of a dynamic dial candidate and runs
+// it through the record filters of the server. Candidates whose record can't
+// be retrieved, e.g. because they predate node records, are dialed anyway.
+func (t *dialTask) checkRecord(srv *Server) bool {
+	if srv.ntab == nil || len(srv.recordFilters) == 0 {
+		return true
+	}
+	rec, err := srv.ntab.RequestENR(t.dest)
+	if err != nil {
+		log.Trace("Can't retrieve node record", "id", t.dest.ID, "err", err)
+		return true
+	}
+	if !srv.acceptRecord(rec) {
+		log.Trace("Skipping dial candidate", "id", t.dest.ID, "addr", &net.TCPAddr{IP: t.dest.IP, Port: int(t.dest.TCP)}, "err", errRecordRejected)
+		return false
+	}
+	return true
+}
+
 // resolve attempts to find the current endpoint for the destination
 // using discovery.
 //
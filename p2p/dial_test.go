@@ -18,13 +18,16 @@ package p2p
 
 import (
 	"encoding/binary"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/aaechain/go-aaechain/crypto"
 	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/p2p/netutil"
 )
 
@@ -83,6 +86,9 @@ func (t fakeTable) Close()                                   {}
 func (t fakeTable) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t fakeTable) Resolve(discover.NodeID) *discover.Node   { return nil }
 func (t fakeTable) ReadRandomNodes(buf []*discover.Node) int { return copy(buf, t) }
+func (t fakeTable) RequestENR(*discover.Node) (*enr.Record, error) {
+	return nil, errors.New("no record")
+}
 
 // This test checks that dynamic dials are launched from discovery results.
 func TestDialStateDynDial(t *testing.T) {
@@ -402,6 +408,43 @@ func TestDialStateDynDialFromTable(t *testing.T) {
 	})
 }
 
+// This test checks that dynamic dials are launched from additional node
+// sources when there is no discovery table.
+func TestDialStateDynDialFromSource(t *testing.T) {
+	dialer := newDialState(nil, nil, nil, 4, nil)
+	dialer.addSource(fakeTable{
+		{ID: uintID(1)},
+		{ID: uintID(2)},
+		{ID: uintID(3)},
+	})
+	runDialTest(t, dialtest{
+		init: dialer,
+		rounds: []round{
+			// The first two nodes are dialed, no lookup is launched.
+			{
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
+				},
+			},
+			// Node 1 connects, node 2 is not dialed again because
+			// it was dialed recently.
+			{
+				peers: []*Peer{
+					{rw: &conn{flags: dynDialedConn, id: uintID(1)}},
+				},
+				done: []task{
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(1)}},
+					&dialTask{flags: dynDialedConn, dest: &discover.Node{ID: uintID(2)}},
+				},
+				new: []task{
+					&waitExpireTask{Duration: 30 * time.Second},
+				},
+			},
+		},
+	})
+}
+
 // This test checks that candidates that do not match the netrestrict list are not dialed.
 func TestDialStateNetRestrict(t *testing.T) {
 	// This table always returns the same random nodes
@@ -655,6 +698,59 @@ func TestDialResolve(t *testing.T) {
 	}
 }
 
+func TestDialRecordFilter(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var rec enr.Record
+	rec.Set(enr.WithEntry("net", uint(1)))
+	if err := rec.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	table := &recordMock{record: &rec}
+	dest := discover.NewNode(discover.PubkeyID(&key.PublicKey), net.IP{127, 0, 55, 234}, 3333, 4444)
+
+	for _, want := range []uint{1, 2} {
+		want := want
+		dialer := new(countingDialer)
+		srv := &Server{ntab: table, Config: Config{Dialer: dialer}}
+		srv.recordFilters = []func(*enr.Record) bool{func(r *enr.Record) bool {
+			var id uint
+			return r.Load(enr.WithEntry("net", &id)) == nil && id == want
+		}}
+		(&dialTask{flags: dynDialedConn, dest: dest}).Do(srv)
+		if accept := want == 1; dialer.dials != 0 != accept {
+			t.Errorf("filter for net %d: got %d dials, want dial %t", want, dialer.dials, accept)
+		}
+	}
+	// Static dials are not subject to the record filters.
+	dialer := new(countingDialer)
+	srv := &Server{ntab: table, Config: Config{Dialer: dialer}}
+	srv.recordFilters = []func(*enr.Record) bool{func(*enr.Record) bool { return false }}
+	(&dialTask{flags: staticDialedConn, dest: dest}).Do(srv)
+	if dialer.dials != 1 {
+		t.Errorf("static dial: got %d dials, want 1", dialer.dials)
+	}
+}
+
+// countingDialer counts dial attempts and fails all of them.
+type countingDialer struct {
+	dials int
+}
+
+func (d *countingDialer) Dial(*discover.Node) (net.Conn, error) {
+	d.dials++
+	return nil, errors.New("dial refused")
+}
+
+// implements discoverTable for TestDialRecordFilter
+type recordMock struct {
+	fakeTable
+	record *enr.Record
+}
+
+func (t *recordMock) RequestENR(*discover.Node) (*enr.Record, error) {
+	return t.record, nil
+}
+
 // compares task lists but doesn't care about the order.
 func sametasks(a, b []task) bool {
 	if len(a) != len(b) {
@@ -694,3 +790,6 @@ func (t *resolveMock) Close()                                   {}
 func (t *resolveMock) Booaaerap([]*discover.Node)               {}
 func (t *resolveMock) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t *resolveMock) ReadRandomNodes(buf []*discover.Node) int { return 0 }
+func (t *resolveMock) RequestENR(*discover.Node) (*enr.Record, error) {
+	return nil, errors.New("no record")
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/aaechain/go-aaechain/crypto"
 	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/rlp"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
@@ -61,6 +62,15 @@ var (
 	nodeDBDiscoverPing      = nodeDBDiscoverRoot + ":lastping"
 	nodeDBDiscoverPong      = nodeDBDiscoverRoot + ":lastpong"
 	nodeDBDiscoverFindFails = nodeDBDiscoverRoot + ":findfail"
+	nodeDBDiscoverENR       = nodeDBDiscoverRoot + ":enr"
+	nodeDBDiscoverENRTime   = nodeDBDiscoverRoot + ":enrtime"
+
+	nodeDBPeerRoot   = ":p2p"
+	nodeDBPeerScore  = nodeDBPeerRoot + ":score"
+	nodeDBPeerBanned = nodeDBPeerRoot + ":banned"
+
+	nodeDBLocalSeq  = "local:seq"
+	nodeDBLocalHash = "local:hash"
 )
 
 // newNodeDB creates a new node database for storing and retrieving infos about
@@ -303,6 +313,79 @@ func (db *nodeDB) updateFindFails(id NodeID, fails int) error {
 	return db.storeInt64(makeKey(id, nodeDBDiscoverFindFails), int64(fails))
 }
 
+// record retrieves the cached node record of a remote node along with the
+// time it was retrieved.
+func (db *nodeDB) record(id NodeID) (*enr.Record, time.Time) {
+	blob, err := db.lvl.Get(makeKey(id, nodeDBDiscoverENR), nil)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	rec := new(enr.Record)
+	if err := rlp.DecodeBytes(blob, rec); err != nil {
+		log.Error("Failed to decode node record RLP", "err", err)
+		return nil, time.Time{}
+	}
+	return rec, time.Unix(db.fetchInt64(makeKey(id, nodeDBDiscoverENRTime)), 0)
+}
+
+// updateRecord caches the node record of a remote node.
+func (db *nodeDB) updateRecord(id NodeID, rec *enr.Record) error {
+	blob, err := rlp.EncodeToBytes(rec)
+	if err != nil {
+		return err
+	}
+	if err := db.lvl.Put(makeKey(id, nodeDBDiscoverENR), blob, nil); err != nil {
+		return err
+	}
+	return db.storeInt64(makeKey(id, nodeDBDiscoverENRTime), time.Now().Unix())
+}
+
+// peerScore retrieves the reputation score of a peer and the time until
+// which it is banned.
+func (db *nodeDB) peerScore(id NodeID) (int, time.Time) {
+	score := int(db.fetchInt64(makeKey(id, nodeDBPeerScore)))
+	banned := db.fetchInt64(makeKey(id, nodeDBPeerBanned))
+	if banned == 0 {
+		return score, time.Time{}
+	}
+	return score, time.Unix(banned, 0)
+}
+
+// updatePeerScore updates the reputation score and ban time of a peer.
+func (db *nodeDB) updatePeerScore(id NodeID, score int, bannedUntil time.Time) error {
+	var banned int64
+	if !bannedUntil.IsZero() {
+		banned = bannedUntil.Unix()
+	}
+	if err := db.storeInt64(makeKey(id, nodeDBPeerScore), int64(score)); err != nil {
+		return err
+	}
+	return db.storeInt64(makeKey(id, nodeDBPeerBanned), banned)
+}
+
+// localSeq retrieves the sequence number of the local node record.
+func (db *nodeDB) localSeq() uint64 {
+	return uint64(db.fetchInt64(makeKey(nodeDBNilNodeID, nodeDBLocalSeq)))
+}
+
+// localRecordHash retrieves the content hash of the last signed local record.
+func (db *nodeDB) localRecordHash() []byte {
+	blob, err := db.lvl.Get(makeKey(nodeDBNilNodeID, nodeDBLocalHash), nil)
+	if err != nil {
+		return nil
+	}
+	return blob
+}
+
+// storeLocalRecord stores the sequence number and content hash of the local
+// node record.
+func (db *nodeDB) storeLocalRecord(seq uint64, hash []byte) error {
+	if err := db.storeInt64(makeKey(nodeDBNilNodeID, nodeDBLocalSeq), int64(seq)); err != nil {
+		return err
+	}
+	return db.lvl.Put(makeKey(nodeDBNilNodeID, nodeDBLocalHash), hash, nil)
+}
+
 // querySeeds retrieves random nodes to be used as potential seed nodes
 // for booaaerapping.
 func (db *nodeDB) querySeeds(n int, maxAge time.Duration) []*Node {
@@ -25,6 +25,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/aaechain/go-aaechain/p2p/enr"
 )
 
 var nodeDBKeyTests = []struct {
@@ -378,3 +380,74 @@ func TestNodeDBSelfExpiration(t *testing.T) {
 		t.Errorf("self not evacuated")
 	}
 }
+
+func TestNodeDBLocalRecord(t *testing.T) {
+	db, _ := newNodeDB("", Version, NodeID{})
+	defer db.close()
+
+	if seq := db.localSeq(); seq != 0 {
+		t.Fatalf("initial seq mismatch: have %d, want 0", seq)
+	}
+	if hash := db.localRecordHash(); hash != nil {
+		t.Fatalf("initial hash mismatch: have %x, want nil", hash)
+	}
+	hash := []byte{1, 2, 3}
+	if err := db.storeLocalRecord(42, hash); err != nil {
+		t.Fatalf("failed to store local record: %v", err)
+	}
+	if seq := db.localSeq(); seq != 42 {
+		t.Errorf("seq mismatch: have %d, want 42", seq)
+	}
+	if stored := db.localRecordHash(); !bytes.Equal(stored, hash) {
+		t.Errorf("hash mismatch: have %x, want %x", stored, hash)
+	}
+}
+
+func TestNodeDBPeerScore(t *testing.T) {
+	db, _ := newNodeDB("", Version, NodeID{})
+	defer db.close()
+
+	id := MustHexID("0x1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439")
+	if score, banned := db.peerScore(id); score != 0 || !banned.IsZero() {
+		t.Fatalf("initial score mismatch: have %d/%v, want 0/zero", score, banned)
+	}
+	until := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	if err := db.updatePeerScore(id, -60, until); err != nil {
+		t.Fatalf("failed to update score: %v", err)
+	}
+	if score, banned := db.peerScore(id); score != -60 || !banned.Equal(until) {
+		t.Errorf("score mismatch: have %d/%v, want -60/%v", score, banned, until)
+	}
+	if err := db.updatePeerScore(id, 5, time.Time{}); err != nil {
+		t.Fatalf("failed to update score: %v", err)
+	}
+	if score, banned := db.peerScore(id); score != 5 || !banned.IsZero() {
+		t.Errorf("score mismatch: have %d/%v, want 5/zero", score, banned)
+	}
+}
+
+func TestNodeDBLocalSeqRestart(t *testing.T) {
+	root, err := ioutil.TempDir("", "nodedb-")
+	if err != nil {
+		t.Fatalf("failed to create temporary data folder: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	key := newkey()
+	start := func(entries ...enr.Entry) uint64 {
+		cfg := Config{PrivateKey: key, NodeDBPath: filepath.Join(root, "database"), Entries: entries}
+		tab, udp, err := newUDP(newpipe(), cfg)
+		if err != nil {
+			t.Fatalf("failed to start UDP transport: %v", err)
+		}
+		defer tab.Close()
+		return udp.localRecord().Seq()
+	}
+	first := start(enr.WithEntry("foo", uint(1)))
+	if seq := start(enr.WithEntry("foo", uint(1))); seq != first {
+		t.Errorf("seq changed on restart without changes: have %d, want %d", seq, first)
+	}
+	if seq := start(enr.WithEntry("foo", uint(2))); seq != first+1 {
+		t.Errorf("seq not increased after changing the record: have %d, want %d", seq, first+1)
+	}
+}
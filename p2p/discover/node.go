@@ -34,6 +34,7 @@ import (
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/crypto"
 	"github.com/aaechain/go-aaechain/crypto/secp256k1"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 )
 
 const NodeIDBits = 512
@@ -71,6 +72,48 @@ func NewNode(id NodeID, ip net.IP, udpPort, tcpPort uint16) *Node {
 	}
 }
 
+// NodeFromRecord creates a node from a signed node record. The record must
+// contain an IP address and a TCP port. If the record has no UDP port, the TCP
+// port is assumed.
+func NodeFromRecord(rec *enr.Record) (*Node, error) {
+	if !rec.Signed() {
+		return nil, errors.New("unsigned node record")
+	}
+	id, err := recordID(rec)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		ip4 enr.IP4
+		ip6 enr.IP6
+		ip  net.IP
+		tcp enr.TCP
+		udp enr.UDP
+	)
+	switch {
+	case rec.Load(&ip4) == nil:
+		ip = net.IP(ip4)
+	case rec.Load(&ip6) == nil:
+		ip = net.IP(ip6)
+	default:
+		return nil, errors.New("node record has no IP address")
+	}
+	if err := rec.Load(&tcp); err != nil {
+		return nil, err
+	}
+	if err := rec.Load(&udp); err != nil {
+		if !enr.IsNotFound(err) {
+			return nil, err
+		}
+		udp = enr.UDP(tcp)
+	}
+	n := NewNode(id, ip, uint16(udp), uint16(tcp))
+	if err := n.validateComplete(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
 func (n *Node) addr() *net.UDPAddr {
 	return &net.UDPAddr{IP: n.IP, Port: int(n.UDP)}
 }
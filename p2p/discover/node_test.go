@@ -30,6 +30,7 @@ import (
 
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 )
 
 func ExampleNewNode() {
@@ -180,6 +181,38 @@ func TestNodeString(t *testing.T) {
 	}
 }
 
+func TestNodeFromRecord(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var r enr.Record
+	r.Set(enr.IP4{10, 3, 58, 6})
+	r.Set(enr.TCP(30303))
+	if _, err := NodeFromRecord(&r); err == nil {
+		t.Fatal("expected error for unsigned record")
+	}
+	if err := r.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	n, err := NodeFromRecord(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewNode(PubkeyID(&key.PublicKey), net.IP{10, 3, 58, 6}, 30303, 30303)
+	if !reflect.DeepEqual(n, want) {
+		t.Errorf("wrong node:\ngot  %v\nwant %v", n, want)
+	}
+
+	// Records without a TCP port can't be dialed.
+	var r2 enr.Record
+	r2.Set(enr.IP4{10, 3, 58, 6})
+	r2.Set(enr.UDP(30301))
+	if err := r2.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NodeFromRecord(&r2); err == nil {
+		t.Error("expected error for record without TCP port")
+	}
+}
+
 func TestHexID(t *testing.T) {
 	ref := NodeID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 128, 106, 217, 182, 31, 165, 174, 1, 67, 7, 235, 220, 150, 66, 83, 173, 205, 159, 44, 10, 57, 42, 161, 26, 188}
 	id1 := MustHexID("0x000000000000000000000000000000000000000000000000000000000000000000000000000000806ad9b61fa5ae014307ebdc964253adcd9f2c0a392aa11abc")
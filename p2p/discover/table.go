@@ -36,6 +36,7 @@ import (
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/crypto"
 	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/p2p/netutil"
 )
 
@@ -63,6 +64,7 @@ const (
 	seedMinTableTime   = 5 * time.Minute
 	seedCount          = 30
 	seedMaxAge         = 5 * 24 * time.Hour
+	recordMaxAge       = time.Hour
 )
 
 type Table struct {
@@ -101,6 +103,10 @@ type transport interface {
 	ping(NodeID, *net.UDPAddr) error
 	waitping(NodeID) error
 	findnode(toid NodeID, addr *net.UDPAddr, target NodeID) ([]*Node, error)
+	requestENR(toid NodeID, addr *net.UDPAddr) (*enr.Record, error)
+	localRecord() *enr.Record
+	setRecordEntry(enr.Entry) error
+	setEndpoint(*net.UDPAddr) error
 	close()
 }
 
@@ -167,6 +173,48 @@ func (tab *Table) Self() *Node {
 	return tab.self
 }
 
+// Record returns the signed node record of the local node.
+func (tab *Table) Record() *enr.Record {
+	return tab.net.localRecord()
+}
+
+// SetRecordEntry adds or updates an entry of the local node record. The record
+// is signed again with an incremented sequence number.
+func (tab *Table) SetRecordEntry(e enr.Entry) error {
+	return tab.net.setRecordEntry(e)
+}
+
+// SetEndpoint updates the externally visible address of the local node, e.g.
+// after the external IP reported by a NAT device has changed.
+func (tab *Table) SetEndpoint(addr *net.UDPAddr) error {
+	return tab.net.setEndpoint(addr)
+}
+
+// PeerScore returns the reputation score of the given node as stored in the
+// node database, along with the time until which the node is banned.
+func (tab *Table) PeerScore(id NodeID) (int, time.Time) {
+	return tab.db.peerScore(id)
+}
+
+// SetPeerScore stores the reputation score and ban time of the given node.
+func (tab *Table) SetPeerScore(id NodeID, score int, bannedUntil time.Time) error {
+	return tab.db.updatePeerScore(id, score, bannedUntil)
+}
+
+// RequestENR returns the node record of n. Records are cached in the node
+// database for a while, after which they are requested from the node again.
+func (tab *Table) RequestENR(n *Node) (*enr.Record, error) {
+	if rec, fetched := tab.db.record(n.ID); rec != nil && time.Since(fetched) < recordMaxAge {
+		return rec, nil
+	}
+	rec, err := tab.net.requestENR(n.ID, n.addr())
+	if err != nil {
+		return nil, err
+	}
+	tab.db.updateRecord(n.ID, rec)
+	return rec, nil
+}
+
 // ReadRandomNodes fills the given slice with random nodes from the
 // table. It will not write the same node more than once. The nodes in
 // the slice are copies and can be modified by the caller.
@@ -30,6 +30,7 @@ import (
 
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 )
 
 func TestTable_pingReplace(t *testing.T) {
@@ -206,7 +207,13 @@ func newPingRecorder() *pingRecorder {
 func (t *pingRecorder) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node, error) {
 	return nil, nil
 }
-func (t *pingRecorder) close() {}
+func (t *pingRecorder) requestENR(toid NodeID, toaddr *net.UDPAddr) (*enr.Record, error) {
+	return nil, errTimeout
+}
+func (t *pingRecorder) localRecord() *enr.Record       { return nil }
+func (t *pingRecorder) setRecordEntry(enr.Entry) error { return nil }
+func (t *pingRecorder) setEndpoint(*net.UDPAddr) error { return nil }
+func (t *pingRecorder) close()                         {}
 func (t *pingRecorder) waitping(from NodeID) error {
 	return nil // remote always pings
 }
@@ -579,6 +586,12 @@ func (tn *preminedTestnet) findnode(toid NodeID, toaddr *net.UDPAddr, target Nod
 func (*preminedTestnet) close()                                      {}
 func (*preminedTestnet) waitping(from NodeID) error                  { return nil }
 func (*preminedTestnet) ping(toid NodeID, toaddr *net.UDPAddr) error { return nil }
+func (*preminedTestnet) localRecord() *enr.Record                    { return nil }
+func (*preminedTestnet) setRecordEntry(enr.Entry) error              { return nil }
+func (*preminedTestnet) setEndpoint(*net.UDPAddr) error              { return nil }
+func (*preminedTestnet) requestENR(NodeID, *net.UDPAddr) (*enr.Record, error) {
+	return nil, errTimeout
+}
 
 // mine generates a testnet struct literal with nodes at
 // various distances to the given target.
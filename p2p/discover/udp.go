@@ -23,10 +23,13 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/aaechain/go-aaechain/crypto"
 	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/p2p/nat"
 	"github.com/aaechain/go-aaechain/p2p/netutil"
 	"github.com/aaechain/go-aaechain/rlp"
@@ -44,6 +47,7 @@ var (
 	errTimeout          = errors.New("RPC timeout")
 	errClockWarp        = errors.New("reply deadline too far in the future")
 	errClosed           = errors.New("socket closed")
+	errRecordMismatch   = errors.New("node record does not belong to node")
 )
 
 // Timeouts
@@ -63,6 +67,8 @@ const (
 	pongPacket
 	findnodePacket
 	neighborsPacket
+	enrRequestPacket
+	enrResponsePacket
 )
 
 // RPC request structures
@@ -104,6 +110,21 @@ type (
 		Rest []rlp.RawValue `rlp:"tail"`
 	}
 
+	// enrRequest queries for the remote node's record.
+	enrRequest struct {
+		Expiration uint64
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
+	// enrResponse is the reply to enrRequest.
+	enrResponse struct {
+		ReplyTok []byte // Hash of the enrRequest packet.
+		Record   enr.Record
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
 	rpcNode struct {
 		IP  net.IP // len 4 for IPv4 or 16 for IPv6
 		UDP uint16 // for discovery protocol
@@ -162,7 +183,12 @@ type udp struct {
 	conn        conn
 	netrestrict *netutil.Netlist
 	priv        *ecdsa.PrivateKey
-	ourEndpoint rpcEndpoint
+
+	mu           sync.Mutex  // protects ourEndpoint and the local record
+	ourEndpoint  rpcEndpoint // endpoint announced in ping packets
+	tcpPort      uint16      // RLPx listening port, zero if unknown
+	record       *enr.Record // signed record of the local node
+	recordExtras []enr.Entry // additional entries of the local record
 
 	addpending chan *pending
 	gotreply   chan reply
@@ -223,10 +249,12 @@ type Config struct {
 
 	// These settings are optional:
 	AnnounceAddr *net.UDPAddr      // local address announced in the DHT
+	TCPPort      int               // RLPx listening port announced in the node record
 	NodeDBPath   string            // if set, the node database is stored at this filesystem location
 	NetRestrict  *netutil.Netlist  // network whitelist
 	Bootnodes    []*Node           // list of booaaerap nodes
 	Unhandled    chan<- ReadPacket // unhandled packets are sent on this channel
+	Entries      []enr.Entry       // additional entries of the local node record
 }
 
 // ListenUDP returns a new table that listens for UDP packets on laddr.
@@ -241,25 +269,38 @@ func ListenUDP(c conn, cfg Config) (*Table, error) {
 
 func newUDP(c conn, cfg Config) (*Table, *udp, error) {
 	udp := &udp{
-		conn:        c,
-		priv:        cfg.PrivateKey,
-		netrestrict: cfg.NetRestrict,
-		closing:     make(chan struct{}),
-		gotreply:    make(chan reply),
-		addpending:  make(chan *pending),
+		conn:         c,
+		priv:         cfg.PrivateKey,
+		netrestrict:  cfg.NetRestrict,
+		closing:      make(chan struct{}),
+		gotreply:     make(chan reply),
+		addpending:   make(chan *pending),
+		tcpPort:      uint16(cfg.TCPPort),
+		recordExtras: append([]enr.Entry{}, cfg.Entries...),
 	}
 	realaddr := c.LocalAddr().(*net.UDPAddr)
 	if cfg.AnnounceAddr != nil {
 		realaddr = cfg.AnnounceAddr
 	}
-	// TODO: separate TCP port
-	udp.ourEndpoint = makeEndpoint(realaddr, uint16(realaddr.Port))
+	tcpPort := udp.tcpPort
+	if tcpPort == 0 {
+		tcpPort = uint16(realaddr.Port)
+	}
+	udp.ourEndpoint = makeEndpoint(realaddr, tcpPort)
 	tab, err := newTable(udp, PubkeyID(&cfg.PrivateKey.PublicKey), realaddr, cfg.NodeDBPath, cfg.Bootnodes)
 	if err != nil {
 		return nil, nil, err
 	}
 	udp.Table = tab
 
+	udp.mu.Lock()
+	err = udp.updateRecord()
+	udp.mu.Unlock()
+	if err != nil {
+		tab.Close()
+		return nil, nil, err
+	}
+
 	go udp.loop()
 	go udp.readLoop(cfg.Unhandled)
 	return udp.Table, udp, nil
@@ -273,9 +314,13 @@ func (t *udp) close() {
 
 // ping sends a ping message to the given node and waits for a reply.
 func (t *udp) ping(toid NodeID, toaddr *net.UDPAddr) error {
+	t.mu.Lock()
+	from := t.ourEndpoint
+	t.mu.Unlock()
+
 	req := &ping{
 		Version:    Version,
-		From:       t.ourEndpoint,
+		From:       from,
 		To:         makeEndpoint(toaddr, 0), // TODO: maybe use known TCP port from DB
 		Expiration: uint64(time.Now().Add(expiration).Unix()),
 	}
@@ -320,6 +365,151 @@ func (t *udp) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node
 	return nodes, err
 }
 
+// requestENR sends an enrRequest to the given node and waits for its record.
+// The returned record is verified to be signed by toid.
+func (t *udp) requestENR(toid NodeID, toaddr *net.UDPAddr) (*enr.Record, error) {
+	req := &enrRequest{
+		Expiration: uint64(time.Now().Add(expiration).Unix()),
+	}
+	packet, hash, err := encodePacket(t.priv, enrRequestPacket, req)
+	if err != nil {
+		return nil, err
+	}
+	var rec *enr.Record
+	errc := t.pending(toid, enrResponsePacket, func(r interface{}) bool {
+		reply := r.(*enrResponse)
+		if !bytes.Equal(reply.ReplyTok, hash) {
+			return false
+		}
+		rec = &reply.Record
+		return true
+	})
+	t.write(toaddr, req.name(), packet)
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	if id, err := recordID(rec); err != nil {
+		return nil, err
+	} else if id != toid {
+		return nil, errRecordMismatch
+	}
+	return rec, nil
+}
+
+// localRecord returns the signed record of the local node.
+func (t *udp) localRecord() *enr.Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.record
+}
+
+// setRecordEntry adds or updates an entry of the local node record,
+// signing it again with an incremented sequence number.
+func (t *udp) setRecordEntry(e enr.Entry) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	extras := make([]enr.Entry, 0, len(t.recordExtras)+1)
+	for _, extra := range t.recordExtras {
+		if extra.ENRKey() != e.ENRKey() {
+			extras = append(extras, extra)
+		}
+	}
+	t.recordExtras = append(extras, e)
+	return t.updateRecord()
+}
+
+// setEndpoint changes the externally visible address of the local node,
+// signing the local record again if the address differs.
+func (t *udp) setEndpoint(addr *net.UDPAddr) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tcpPort := t.tcpPort
+	if tcpPort == 0 {
+		tcpPort = uint16(addr.Port)
+	}
+	endpoint := makeEndpoint(addr, tcpPort)
+	if endpoint.IP.Equal(t.ourEndpoint.IP) && endpoint.UDP == t.ourEndpoint.UDP {
+		return nil
+	}
+	t.ourEndpoint = endpoint
+	return t.updateRecord()
+}
+
+// updateRecord assembles and signs the local node record. The sequence number
+// is persisted in the node database and only increases if the content of the
+// record changed since it was last signed. The caller must hold t.mu.
+func (t *udp) updateRecord() error {
+	entries := []enr.Entry{enr.UDP(t.ourEndpoint.UDP)}
+	if ip := t.ourEndpoint.IP; !ip.IsUnspecified() && !ip.IsLoopback() {
+		if ip4 := ip.To4(); ip4 != nil {
+			entries = append(entries, enr.IP4(ip4))
+		} else {
+			entries = append(entries, enr.IP6(ip))
+		}
+	}
+	if t.tcpPort != 0 {
+		entries = append(entries, enr.TCP(t.tcpPort))
+	}
+	entries = append(entries, t.recordExtras...)
+
+	hash, err := entriesHash(entries)
+	if err != nil {
+		return err
+	}
+	seq := t.db.localSeq()
+	if seq > 0 && bytes.Equal(hash, t.db.localRecordHash()) {
+		// Nothing changed, sign again under the same sequence number.
+		seq--
+	}
+	var rec enr.Record
+	for _, e := range entries {
+		rec.Set(e)
+	}
+	rec.SetSeq(seq)
+	if err := rec.Sign(t.priv); err != nil {
+		return err
+	}
+	if err := t.db.storeLocalRecord(rec.Seq(), hash); err != nil {
+		return err
+	}
+	t.record = &rec
+	return nil
+}
+
+// entriesHash hashes the given record entries independent of their order. It
+// is used to detect whaaeer the content of the local record has changed.
+func entriesHash(entries []enr.Entry) ([]byte, error) {
+	type kv struct {
+		K string
+		V []byte
+	}
+	list := make([]kv, len(entries))
+	for i, e := range entries {
+		blob, err := rlp.EncodeToBytes(e)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = kv{e.ENRKey(), blob}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].K < list[j].K })
+	blob, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(blob), nil
+}
+
+// recordID returns the node ID of the key that signed the given record.
+func recordID(rec *enr.Record) (NodeID, error) {
+	var pubkey enr.Secp256k1
+	if err := rec.Load(&pubkey); err != nil {
+		return NodeID{}, err
+	}
+	return PubkeyID((*ecdsa.PublicKey)(&pubkey)), nil
+}
+
 // pending adds a reply callback to the pending reply queue.
 // see the documentation of type pending for a detailed explanation.
 func (t *udp) pending(id NodeID, ptype byte, callback func(interface{}) bool) <-chan error {
@@ -571,6 +761,10 @@ func decodePacket(buf []byte) (packet, NodeID, []byte, error) {
 		req = new(findnode)
 	case neighborsPacket:
 		req = new(neighbors)
+	case enrRequestPacket:
+		req = new(enrRequest)
+	case enrResponsePacket:
+		req = new(enrResponse)
 	default:
 		return nil, fromID, hash, fmt.Errorf("unknown type: %d", ptype)
 	}
@@ -662,6 +856,33 @@ func (req *neighbors) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byt
 
 func (req *neighbors) name() string { return "NEIGHBORS/v4" }
 
+func (req *enrRequest) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if expired(req.Expiration) {
+		return errExpired
+	}
+	if !t.db.hasBond(fromID) {
+		// The record is bigger than the request, only reply to bonded
+		// nodes for the same reason as in findnode.
+		return errUnknownNode
+	}
+	t.send(from, enrResponsePacket, &enrResponse{
+		ReplyTok: mac,
+		Record:   *t.localRecord(),
+	})
+	return nil
+}
+
+func (req *enrRequest) name() string { return "ENRREQUEST/v4" }
+
+func (req *enrResponse) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if !t.handleReply(fromID, enrResponsePacket, req) {
+		return errUnsolicitedReply
+	}
+	return nil
+}
+
+func (req *enrResponse) name() string { return "ENRRESPONSE/v4" }
+
 func expired(ts uint64) bool {
 	return time.Unix(int64(ts), 0).Before(time.Now())
 }
@@ -36,6 +36,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/rlp"
 )
 
@@ -386,6 +387,65 @@ func TestUDP_successfulPing(t *testing.T) {
 	}
 }
 
+func TestUDP_enrRequestUnbonded(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	test.packetIn(errUnknownNode, enrRequestPacket, &enrRequest{Expiration: futureExp})
+}
+
+func TestUDP_enrRequest(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	// ensure there's a bond with the test node,
+	// enrRequest won't be accepted otherwise.
+	remoteID := PubkeyID(&test.remotekey.PublicKey)
+	test.table.db.updateBondTime(remoteID, time.Now())
+
+	test.packetIn(nil, enrRequestPacket, &enrRequest{Expiration: futureExp})
+	test.waitPacketOut(func(p *enrResponse) {
+		reqhash := test.sent[0][:macSize]
+		if !bytes.Equal(p.ReplyTok, reqhash) {
+			t.Errorf("got enrResponse.ReplyTok %x, want %x", p.ReplyTok, reqhash)
+		}
+		id, err := recordID(&p.Record)
+		if err != nil {
+			t.Fatalf("invalid record in response: %v", err)
+		}
+		if want := PubkeyID(&test.localkey.PublicKey); id != want {
+			t.Errorf("record signed by wrong node: got %v, want %v", id, want)
+		}
+		if p.Record.Seq() != test.udp.localRecord().Seq() {
+			t.Errorf("got record seq %d, want %d", p.Record.Seq(), test.udp.localRecord().Seq())
+		}
+	})
+}
+
+func TestUDP_requestENRMismatch(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	remoteID := PubkeyID(&test.remotekey.PublicKey)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := test.udp.requestENR(remoteID, test.remoteaddr)
+		errc <- err
+	}()
+
+	// Reply with a record that was signed by some other node.
+	var rec enr.Record
+	if err := rec.Sign(newkey()); err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := test.waitPacketOut(func(p *enrRequest) {})
+	test.packetIn(nil, enrResponsePacket, &enrResponse{ReplyTok: hash, Record: rec})
+
+	if err := <-errc; err != errRecordMismatch {
+		t.Errorf("got error %v, want %v", err, errRecordMismatch)
+	}
+}
+
 var testPackets = []struct {
 	input      string
 	wantPacket interface{}
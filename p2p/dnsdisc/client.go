@@ -0,0 +1,326 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dnsdisc implements node discovery via DNS. Node lists are published
+// as a merkle tree of TXT records. The leaves of the tree are signed node
+// records or links to other trees, and the root of the tree is signed by the
+// publisher of the list. Trees are referenced by URLs of the form
+//
+//	enrtree://<base32 public key>@<domain>
+package dnsdisc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/enr"
+)
+
+var errTreeTooLarge = errors.New("tree exceeds entry limit")
+
+const (
+	defaultTimeout         = 5 * time.Second
+	defaultRecheckInterval = 30 * time.Minute
+	defaultMaxTreeEntries  = 10000
+
+	// maxLinkDepth limits how far links between trees are followed.
+	maxLinkDepth = 5
+)
+
+// Resolver is a DNS resolver that can query TXT records.
+type Resolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// Config holds Client options.
+type Config struct {
+	Timeout         time.Duration          // timeout used for DNS lookups (default 5s)
+	RecheckInterval time.Duration          // time between tree root update checks (default 30min)
+	Resolver        Resolver               // the DNS resolver to use (defaults to system DNS)
+	Filter          func(*enr.Record) bool // if set, only records accepted by the filter are used
+	MaxTreeEntries  int                    // maximum number of entries fetched per tree (default 10000)
+	Logger          log.Logger             // a logger (defaults to log.Root())
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RecheckInterval == 0 {
+		cfg.RecheckInterval = defaultRecheckInterval
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = new(net.Resolver)
+	}
+	if cfg.MaxTreeEntries == 0 {
+		cfg.MaxTreeEntries = defaultMaxTreeEntries
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Root()
+	}
+	return cfg
+}
+
+// Client resolves node trees from DNS.
+type Client struct {
+	cfg Config
+}
+
+// NewClient creates a client.
+func NewClient(cfg Config) *Client {
+	return &Client{cfg.withDefaults()}
+}
+
+// SyncTree downloads the complete tree at the given URL and verifies it.
+func (c *Client) SyncTree(url string) (*Tree, error) {
+	link, err := parseLink(url)
+	if err != nil {
+		return nil, err
+	}
+	return c.syncTree(context.Background(), link, nil)
+}
+
+// syncTree downloads the tree referenced by link. If the root of prev matches
+// the current root, prev is returned without fetching any other entries.
+func (c *Client) syncTree(ctx context.Context, link *linkEntry, prev *Tree) (*Tree, error) {
+	root, err := c.resolveRoot(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && prev.root.eroot == root.eroot && prev.root.lroot == root.lroot && prev.root.seq == root.seq {
+		return prev, nil
+	}
+	t := &Tree{root: root, entries: make(map[string]entry)}
+	if err := c.resolveSubtree(ctx, t, link.domain, root.eroot, false); err != nil {
+		return nil, err
+	}
+	if err := c.resolveSubtree(ctx, t, link.domain, root.lroot, true); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// resolveRoot retrieves the root entry of a tree and verifies its signature.
+func (c *Client) resolveRoot(ctx context.Context, link *linkEntry) (*rootEntry, error) {
+	txt, err := c.lookupTXT(ctx, link.domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range txt {
+		if !strings.HasPrefix(r, rootPrefix) {
+			continue
+		}
+		root, err := parseRoot(r)
+		if err != nil {
+			return nil, err
+		}
+		if !root.verifySignature(link.pubkey) {
+			return nil, entryError{"root", errInvalidSig}
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("no tree root found at %s", link.domain)
+}
+
+// resolveSubtree fetches the entry with the given hash and all entries below it.
+// It fails with errTreeTooLarge once the tree holds MaxTreeEntries entries, so
+// a publisher can't make the client issue an unbounded number of queries.
+func (c *Client) resolveSubtree(ctx context.Context, t *Tree, domain, hash string, links bool) error {
+	if _, ok := t.entries[hash]; ok {
+		return nil
+	}
+	if len(t.entries) >= c.cfg.MaxTreeEntries {
+		return errTreeTooLarge
+	}
+	e, err := c.resolveEntry(ctx, domain, hash, links)
+	if err != nil {
+		return err
+	}
+	t.entries[hash] = e
+	if b, ok := e.(*branchEntry); ok {
+		for _, child := range b.children {
+			if err := c.resolveSubtree(ctx, t, domain, child, links); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveEntry retrieves a single entry and verifies that its content matches hash.
+func (c *Client) resolveEntry(ctx context.Context, domain, hash string, links bool) (entry, error) {
+	name := hash + "." + domain
+	txt, err := c.lookupTXT(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range txt {
+		if h := crypto.Keccak256([]byte(r)); b32format.EncodeToString(h[:16]) != hash {
+			continue
+		}
+		return parseEntry(r, links)
+	}
+	return nil, fmt.Errorf("no entry matching hash %s found at %s", hash, name)
+}
+
+// lookupTXT performs a single TXT query. The resolver is expected to join the
+// strings of multi-string records.
+func (c *Client) lookupTXT(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	return c.cfg.Resolver.LookupTXT(ctx, name)
+}
+
+// Source provides nodes from a set of trees. It follows links between trees
+// and checks the trees for updates periodically. Source implements the
+// ReadRandomNodes method of discover.Table and can be used as a source of
+// dial candidates by p2p.Server.
+type Source struct {
+	client *Client
+	urls   []string
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	trees map[string]*Tree
+	nodes []*discover.Node
+}
+
+// NewSource creates a source for the trees at the given URLs and starts
+// syncing them in the background.
+func (c *Client) NewSource(urls ...string) (*Source, error) {
+	for _, url := range urls {
+		if _, err := parseLink(url); err != nil {
+			return nil, fmt.Errorf("invalid tree URL %q: %v", url, err)
+		}
+	}
+	s := &Source{
+		client: c,
+		urls:   append([]string{}, urls...),
+		trees:  make(map[string]*Tree),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.wg.Add(1)
+	go s.loop()
+	return s, nil
+}
+
+// Close stops background syncing.
+func (s *Source) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// ReadRandomNodes fills the given slice with random nodes from the trees.
+// It returns the number of nodes written.
+func (s *Source) ReadRandomNodes(buf []*discover.Node) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, i := range rand.Perm(len(s.nodes)) {
+		if n == len(buf) {
+			break
+		}
+		buf[n] = s.nodes[i]
+		n++
+	}
+	return n
+}
+
+func (s *Source) loop() {
+	defer s.wg.Done()
+
+	recheck := time.NewTicker(s.client.cfg.RecheckInterval)
+	defer recheck.Stop()
+	for {
+		s.sync()
+		select {
+		case <-recheck.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// sync updates all trees and recomputes the node set. Trees which can't be
+// updated keep their previous content.
+func (s *Source) sync() {
+	s.mu.Lock()
+	prev := s.trees
+	s.mu.Unlock()
+
+	var (
+		logger = s.client.cfg.Logger
+		trees  = make(map[string]*Tree)
+		queue  = s.urls
+	)
+	for depth := 0; len(queue) > 0 && depth <= maxLinkDepth; depth++ {
+		var next []string
+		for _, url := range queue {
+			if _, ok := trees[url]; ok {
+				continue
+			}
+			link, _ := parseLink(url)
+			t, err := s.client.syncTree(s.ctx, link, prev[url])
+			if err != nil {
+				if s.ctx.Err() != nil {
+					return
+				}
+				logger.Debug("Can't sync DNS node tree", "url", url, "err", err)
+				if t = prev[url]; t == nil {
+					continue
+				}
+			}
+			trees[url] = t
+			next = append(next, t.Links()...)
+		}
+		queue = next
+	}
+
+	var (
+		nodes []*discover.Node
+		seen  = make(map[discover.NodeID]bool)
+	)
+	for _, t := range trees {
+		for _, rec := range t.Nodes() {
+			if s.client.cfg.Filter != nil && !s.client.cfg.Filter(rec) {
+				continue
+			}
+			n, err := discover.NodeFromRecord(rec)
+			if err != nil || seen[n.ID] {
+				continue
+			}
+			seen[n.ID] = true
+			nodes = append(nodes, n)
+		}
+	}
+	logger.Trace("Synced DNS node trees", "trees", len(trees), "nodes", len(nodes))
+
+	s.mu.Lock()
+	s.trees, s.nodes = trees, nodes
+	s.mu.Unlock()
+}
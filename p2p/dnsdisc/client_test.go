@@ -0,0 +1,175 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/enr"
+)
+
+// mapResolver is a Resolver serving TXT records from a map.
+type mapResolver map[string]string
+
+func (mr mapResolver) add(records map[string]string) {
+	for name, txt := range records {
+		mr[name] = txt
+	}
+}
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if txt, ok := mr[name]; ok {
+		return []string{txt}, nil
+	}
+	return nil, fmt.Errorf("%s: no such host", name)
+}
+
+func signedTree(t *testing.T, seq uint, records []*enr.Record, links []string, domain string) (*Tree, string) {
+	tree, err := MakeTree(seq, records, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(testKey, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, url
+}
+
+func TestClientSyncTree(t *testing.T) {
+	records := testRecords(t, 40)
+	tree, url := signedTree(t, 1, records, []string{testLink}, "n")
+	r := mapResolver{}
+	r.add(tree.ToTXT("n"))
+
+	c := NewClient(Config{Resolver: r})
+	synced, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatal("sync error:", err)
+	}
+	if !reflect.DeepEqual(synced.ToTXT("n"), tree.ToTXT("n")) {
+		t.Error("synced tree does not match published tree")
+	}
+	if !reflect.DeepEqual(synced.Links(), []string{testLink}) {
+		t.Errorf("wrong links: %v", synced.Links())
+	}
+	if len(synced.Nodes()) != len(records) {
+		t.Errorf("wrong number of nodes: got %d, want %d", len(synced.Nodes()), len(records))
+	}
+}
+
+func TestClientSyncTreeBadSignature(t *testing.T) {
+	tree, _ := signedTree(t, 1, testRecords(t, 3), nil, "n")
+	r := mapResolver{}
+	r.add(tree.ToTXT("n"))
+
+	// Use a URL with a different key.
+	otherKey, _ := crypto.GenerateKey()
+	url := linkPrefix + b32format.EncodeToString(crypto.CompressPubkey(&otherKey.PublicKey)) + "@n"
+	c := NewClient(Config{Resolver: r})
+	if _, err := c.SyncTree(url); err != (entryError{"root", errInvalidSig}) {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestClientSyncTreeEntryLimit(t *testing.T) {
+	tree, url := signedTree(t, 1, testRecords(t, 40), nil, "n")
+	r := mapResolver{}
+	r.add(tree.ToTXT("n"))
+
+	c := NewClient(Config{Resolver: r, MaxTreeEntries: 20})
+	if _, err := c.SyncTree(url); err != errTreeTooLarge {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestClientSyncTreeHashMismatch(t *testing.T) {
+	tree, url := signedTree(t, 1, testRecords(t, 3), nil, "n")
+	r := mapResolver{}
+	r.add(tree.ToTXT("n"))
+
+	// Swap the content of two leaves.
+	var names []string
+	for name, txt := range r {
+		if name != "n" && txt[:len(enrPrefix)] == enrPrefix {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	r[names[0]], r[names[1]] = r[names[1]], r[names[0]]
+
+	c := NewClient(Config{Resolver: r})
+	if _, err := c.SyncTree(url); err == nil {
+		t.Error("expected error for tampered tree")
+	}
+}
+
+func TestSourceFollowsLinks(t *testing.T) {
+	var (
+		r           = mapResolver{}
+		ip          = net.IP{10, 1, 0, 1}
+		key1, _     = crypto.GenerateKey()
+		key2, _     = crypto.GenerateKey()
+		key3, _     = crypto.GenerateKey()
+		rec1        = testRecord(t, key1, ip, 30303)
+		rec2        = testRecord(t, key2, ip, 30304)
+		rec3        = testRecord(t, key3, ip, 30305)
+		tree2, url2 = signedTree(t, 1, []*enr.Record{rec2, rec3}, nil, "b")
+		tree1, url1 = signedTree(t, 1, []*enr.Record{rec1}, []string{url2}, "a")
+	)
+	r.add(tree1.ToTXT("a"))
+	r.add(tree2.ToTXT("b"))
+
+	// Filter out rec3.
+	filter := func(rec *enr.Record) bool {
+		var tcp enr.TCP
+		return rec.Load(&tcp) == nil && tcp != 30305
+	}
+	c := NewClient(Config{Resolver: r, Filter: filter})
+	s := &Source{client: c, urls: []string{url1}, ctx: context.Background(), trees: make(map[string]*Tree)}
+	s.sync()
+
+	buf := make([]*discover.Node, 10)
+	n := s.ReadRandomNodes(buf)
+	if n != 2 {
+		t.Fatalf("wrong number of nodes: got %d, want 2", n)
+	}
+	var ports []int
+	for _, node := range buf[:n] {
+		ports = append(ports, int(node.TCP))
+	}
+	sort.Ints(ports)
+	if !reflect.DeepEqual(ports, []int{30303, 30304}) {
+		t.Errorf("wrong nodes returned: ports %v", ports)
+	}
+
+	// Nodes remain available when the trees can't be fetched.
+	for name := range r {
+		delete(r, name)
+	}
+	s.sync()
+	if n := s.ReadRandomNodes(buf); n != 2 {
+		t.Errorf("wrong number of nodes after failed sync: got %d, want 2", n)
+	}
+}
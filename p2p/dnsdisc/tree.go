@@ -0,0 +1,383 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/p2p/enr"
+	"github.com/aaechain/go-aaechain/rlp"
+)
+
+const (
+	rootPrefix   = "enrtree-root:v1"
+	linkPrefix   = "enrtree://"
+	branchPrefix = "enrtree-branch:"
+	enrPrefix    = "enr:"
+
+	// maxChildren is the maximum number of hashes in a branch entry.
+	maxChildren = 13
+	// maxTXTLength is the maximum length of a single string in a TXT record.
+	maxTXTLength = 255
+)
+
+var (
+	errUnknownEntry = errors.New("unknown entry type")
+	errNoPubkey     = errors.New("missing public key")
+	errBadPubkey    = errors.New("invalid public key")
+	errInvalidENR   = errors.New("invalid node record")
+	errInvalidChild = errors.New("invalid child hash")
+	errInvalidSig   = errors.New("invalid root signature")
+	errSyntax       = errors.New("invalid syntax")
+)
+
+var b32format = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Tree is a merkle tree of node records and links to other trees. The root of
+// the tree is signed by the key of the tree publisher.
+type Tree struct {
+	root    *rootEntry
+	entries map[string]entry
+}
+
+// MakeTree creates a tree containing the given node records and links. The
+// tree is unsigned, call Sign to sign its root.
+func MakeTree(seq uint, records []*enr.Record, links []string) (*Tree, error) {
+	enrEntries := make([]entry, len(records))
+	for i, r := range records {
+		if !r.Signed() {
+			return nil, errInvalidENR
+		}
+		enrEntries[i] = &enrEntry{r}
+	}
+	linkEntries := make([]entry, len(links))
+	for i, l := range links {
+		le, err := parseLink(l)
+		if err != nil {
+			return nil, err
+		}
+		linkEntries[i] = le
+	}
+	// Sort entries by their content so the tree does not depend on
+	// the input order.
+	sortEntries(enrEntries)
+	sortEntries(linkEntries)
+
+	t := &Tree{entries: make(map[string]entry)}
+	eroot := t.build(enrEntries)
+	t.entries[subdomain(eroot)] = eroot
+	lroot := t.build(linkEntries)
+	t.entries[subdomain(lroot)] = lroot
+	t.root = &rootEntry{seq: seq, eroot: subdomain(eroot), lroot: subdomain(lroot)}
+	return t, nil
+}
+
+// build adds the given entries to the tree, creating branch entries as
+// needed. It returns the topmost branch, which is not added to the tree.
+func (t *Tree) build(entries []entry) entry {
+	if len(entries) == 1 {
+		return entries[0]
+	}
+	if len(entries) <= maxChildren {
+		hashes := make([]string, len(entries))
+		for i, e := range entries {
+			hashes[i] = subdomain(e)
+			t.entries[hashes[i]] = e
+		}
+		return &branchEntry{hashes}
+	}
+	var subtrees []entry
+	for len(entries) > 0 {
+		n := maxChildren
+		if len(entries) < n {
+			n = len(entries)
+		}
+		sub := t.build(entries[:n])
+		entries = entries[n:]
+		subtrees = append(subtrees, sub)
+		t.entries[subdomain(sub)] = sub
+	}
+	return t.build(subtrees)
+}
+
+// Sign signs the tree root with the given key. It returns the URL at which
+// the tree can be found when published at the given domain.
+func (t *Tree) Sign(key *ecdsa.PrivateKey, domain string) (string, error) {
+	root := *t.root
+	sig, err := crypto.Sign(root.sigHash(), key)
+	if err != nil {
+		return "", err
+	}
+	root.sig = sig
+	t.root = &root
+	link := &linkEntry{domain: domain, pubkey: &key.PublicKey}
+	return link.String(), nil
+}
+
+// Seq returns the sequence number of the tree.
+func (t *Tree) Seq() uint {
+	return t.root.seq
+}
+
+// Signature returns the root signature of the tree.
+func (t *Tree) Signature() string {
+	return base64.RawURLEncoding.EncodeToString(t.root.sig)
+}
+
+// Nodes returns all node records contained in the tree.
+func (t *Tree) Nodes() []*enr.Record {
+	var nodes []*enr.Record
+	for _, e := range t.entries {
+		if ee, ok := e.(*enrEntry); ok {
+			nodes = append(nodes, ee.node)
+		}
+	}
+	return nodes
+}
+
+// Links returns the URLs of all trees linked from the tree.
+func (t *Tree) Links() []string {
+	var links []string
+	for _, e := range t.entries {
+		if le, ok := e.(*linkEntry); ok {
+			links = append(links, le.String())
+		}
+	}
+	sort.Strings(links)
+	return links
+}
+
+// ToTXT returns all TXT records of the tree, keyed by their DNS name.
+func (t *Tree) ToTXT(domain string) map[string]string {
+	records := map[string]string{domain: t.root.String()}
+	for hash, e := range t.entries {
+		name := hash
+		if domain != "" {
+			name = hash + "." + domain
+		}
+		records[name] = e.String()
+	}
+	return records
+}
+
+// WriteZone writes the tree as a DNS zone file for the given domain.
+func (t *Tree) WriteZone(w io.Writer, domain string) error {
+	records := t.ToTXT(domain)
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s.\t%d\tIN\tTXT\t%s\n", name, defaultTTL, splitTXT(records[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultTTL is the time to live of records in generated zone files.
+const defaultTTL = 3600
+
+// splitTXT splits a TXT record value into quoted strings of the
+// maximum allowed length.
+func splitTXT(value string) string {
+	var parts []string
+	for len(value) > maxTXTLength {
+		parts = append(parts, `"`+value[:maxTXTLength]+`"`)
+		value = value[maxTXTLength:]
+	}
+	parts = append(parts, `"`+value+`"`)
+	return strings.Join(parts, " ")
+}
+
+// Entry types.
+
+type entry interface {
+	fmt.Stringer
+}
+
+type (
+	rootEntry struct {
+		eroot string
+		lroot string
+		seq   uint
+		sig   []byte
+	}
+	branchEntry struct {
+		children []string
+	}
+	enrEntry struct {
+		node *enr.Record
+	}
+	linkEntry struct {
+		domain string
+		pubkey *ecdsa.PublicKey
+	}
+)
+
+func (e *rootEntry) sigHash() []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("%s e=%s l=%s seq=%d", rootPrefix, e.eroot, e.lroot, e.seq)))
+}
+
+func (e *rootEntry) verifySignature(pubkey *ecdsa.PublicKey) bool {
+	if len(e.sig) != 65 {
+		return false
+	}
+	return crypto.VerifySignature(crypto.FromECDSAPub(pubkey), e.sigHash(), e.sig[:64])
+}
+
+func (e *rootEntry) String() string {
+	return fmt.Sprintf("%s e=%s l=%s seq=%d sig=%s", rootPrefix, e.eroot, e.lroot, e.seq, base64.RawURLEncoding.EncodeToString(e.sig))
+}
+
+func (e *branchEntry) String() string {
+	return branchPrefix + strings.Join(e.children, ",")
+}
+
+func (e *enrEntry) String() string {
+	enc, _ := rlp.EncodeToBytes(e.node)
+	return enrPrefix + base64.RawURLEncoding.EncodeToString(enc)
+}
+
+func (e *linkEntry) String() string {
+	return linkPrefix + b32format.EncodeToString(crypto.CompressPubkey(e.pubkey)) + "@" + e.domain
+}
+
+// subdomain returns the DNS label at which the given entry is published.
+func subdomain(e entry) string {
+	h := crypto.Keccak256([]byte(e.String()))
+	return b32format.EncodeToString(h[:16])
+}
+
+func sortEntries(entries []entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].String() < entries[j].String()
+	})
+}
+
+// Entry parsing.
+
+func parseRoot(e string) (*rootEntry, error) {
+	var (
+		eroot, lroot, sig string
+		seq               uint
+	)
+	if _, err := fmt.Sscanf(e, rootPrefix+" e=%s l=%s seq=%d sig=%s", &eroot, &lroot, &seq, &sig); err != nil {
+		return nil, entryError{"root", errSyntax}
+	}
+	if !isValidHash(eroot) || !isValidHash(lroot) {
+		return nil, entryError{"root", errInvalidChild}
+	}
+	sigb, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || len(sigb) != 65 {
+		return nil, entryError{"root", errInvalidSig}
+	}
+	return &rootEntry{eroot, lroot, seq, sigb}, nil
+}
+
+// parseEntry parses a non-root entry. If links is false, link entries are
+// not accepted.
+func parseEntry(e string, links bool) (entry, error) {
+	switch {
+	case strings.HasPrefix(e, linkPrefix) && links:
+		return parseLink(e)
+	case strings.HasPrefix(e, branchPrefix):
+		return parseBranch(e[len(branchPrefix):])
+	case strings.HasPrefix(e, enrPrefix):
+		return parseENR(e[len(enrPrefix):])
+	default:
+		return nil, errUnknownEntry
+	}
+}
+
+func parseLink(e string) (*linkEntry, error) {
+	if !strings.HasPrefix(e, linkPrefix) {
+		return nil, fmt.Errorf("wrong/missing scheme 'enrtree' in URL")
+	}
+	e = e[len(linkPrefix):]
+	pos := strings.IndexByte(e, '@')
+	if pos == -1 {
+		return nil, entryError{"link", errNoPubkey}
+	}
+	keystring, domain := e[:pos], e[pos+1:]
+	if domain == "" {
+		return nil, entryError{"link", errSyntax}
+	}
+	keybytes, err := b32format.DecodeString(keystring)
+	if err != nil {
+		return nil, entryError{"link", errBadPubkey}
+	}
+	key, err := crypto.DecompressPubkey(keybytes)
+	if err != nil {
+		return nil, entryError{"link", errBadPubkey}
+	}
+	return &linkEntry{domain, key}, nil
+}
+
+func parseBranch(e string) (entry, error) {
+	if e == "" {
+		return &branchEntry{}, nil
+	}
+	hashes := strings.Split(e, ",")
+	for _, h := range hashes {
+		if !isValidHash(h) {
+			return nil, entryError{"branch", errInvalidChild}
+		}
+	}
+	return &branchEntry{hashes}, nil
+}
+
+func parseENR(e string) (entry, error) {
+	enc, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, entryError{"enr", errInvalidENR}
+	}
+	var rec enr.Record
+	if err := rlp.Decode(bytes.NewReader(enc), &rec); err != nil {
+		return nil, entryError{"enr", err}
+	}
+	return &enrEntry{&rec}, nil
+}
+
+func isValidHash(s string) bool {
+	dlen := b32format.DecodedLen(len(s))
+	if dlen < 12 || dlen > 32 {
+		return false
+	}
+	_, err := b32format.DecodeString(s)
+	return err == nil
+}
+
+// entryError wraps an error that occurred while parsing an entry.
+type entryError struct {
+	typ string
+	err error
+}
+
+func (err entryError) Error() string {
+	return fmt.Sprintf("invalid %s entry: %v", err.typ, err.err)
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/p2p/enr"
+)
+
+var (
+	testKey, _ = crypto.HexToECDSA("45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8")
+	testLink   = "enrtree://" + b32format.EncodeToString(crypto.CompressPubkey(&testKey.PublicKey)) + "@nodes.example.org"
+)
+
+func testRecords(t *testing.T, n int) []*enr.Record {
+	records := make([]*enr.Record, n)
+	for i := range records {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records[i] = testRecord(t, key, net.IP{10, 0, byte(i >> 8), byte(i)}, 30303)
+	}
+	return records
+}
+
+func testRecord(t *testing.T, key *ecdsa.PrivateKey, ip net.IP, port int) *enr.Record {
+	var r enr.Record
+	r.Set(enr.IP4(ip))
+	r.Set(enr.TCP(port))
+	r.Set(enr.UDP(port))
+	if err := r.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	return &r
+}
+
+func TestTreeToTXT(t *testing.T) {
+	records := testRecords(t, 30)
+	tree, err := MakeTree(3, records, []string{testLink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(testKey, "nodes.example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != testLink {
+		t.Errorf("wrong tree URL: got %q, want %q", url, testLink)
+	}
+	if tree.Seq() != 3 {
+		t.Errorf("wrong seq: got %d, want 3", tree.Seq())
+	}
+	if len(tree.Nodes()) != len(records) {
+		t.Errorf("wrong number of nodes: got %d, want %d", len(tree.Nodes()), len(records))
+	}
+
+	txt := tree.ToTXT("nodes.example.org")
+	root, err := parseRoot(txt["nodes.example.org"])
+	if err != nil {
+		t.Fatal("can't parse root:", err)
+	}
+	if !root.verifySignature(&testKey.PublicKey) {
+		t.Error("root signature does not verify")
+	}
+	for name, content := range txt {
+		if name == "nodes.example.org" {
+			continue
+		}
+		e, err := parseEntry(content, true)
+		if err != nil {
+			t.Errorf("can't parse entry %s: %v", name, err)
+			continue
+		}
+		if want := subdomain(e) + ".nodes.example.org"; name != want {
+			t.Errorf("entry published at %s, want %s", name, want)
+		}
+		if b, ok := e.(*branchEntry); ok && len(b.children) > maxChildren {
+			t.Errorf("branch %s has %d children", name, len(b.children))
+		}
+	}
+}
+
+func TestTreeWriteZone(t *testing.T) {
+	tree, err := MakeTree(1, testRecords(t, 20), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Sign(testKey, "nodes.example.org"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tree.WriteZone(&buf, "nodes.example.org"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(tree.ToTXT("nodes.example.org")) {
+		t.Fatalf("wrong number of zone records: got %d, want %d", len(lines), len(tree.ToTXT("nodes.example.org")))
+	}
+	for _, line := range lines {
+		for _, s := range strings.Split(line, `"`) {
+			if len(s) > maxTXTLength {
+				t.Errorf("TXT string longer than %d bytes: %q", maxTXTLength, s)
+			}
+		}
+	}
+}
+
+func TestMakeTreeUnsigned(t *testing.T) {
+	var r enr.Record
+	r.Set(enr.IP4{127, 0, 0, 1})
+	if _, err := MakeTree(1, []*enr.Record{&r}, nil); err != errInvalidENR {
+		t.Errorf("wrong error for unsigned record: %v", err)
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		input string
+		links bool
+		err   error
+	}{
+		{input: "enrtree-branch:", links: false},
+		{input: "enrtree-branch:AAAAAAAAAAAAAAAAAAAA", links: false},
+		{input: "enrtree-branch:AAAAAAAAAAAAAAAAAAAA,AAAAAAAAAAAAAAAAAAAB", links: false},
+		{input: "enrtree-branch:AAAA", err: entryError{"branch", errInvalidChild}},
+		{input: "enr:-----", err: entryError{"enr", errInvalidENR}},
+		{input: testLink, links: true},
+		{input: testLink, links: false, err: errUnknownEntry},
+		{input: "enrtree://nodes.example.org", links: true, err: entryError{"link", errNoPubkey}},
+		{input: "enrtree://AAAA@nodes.example.org", links: true, err: entryError{"link", errBadPubkey}},
+		{input: "foo", err: errUnknownEntry},
+	}
+	for _, test := range tests {
+		_, err := parseEntry(test.input, test.links)
+		if err != test.err {
+			t.Errorf("parseEntry(%q): got error %v, want %v", test.input, err, test.err)
+		}
+	}
+}
+
+func TestParseRoot(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{input: "enrtree-root:v1 e=AAAAAAAAAAAAAAAAAAAA l=AAAAAAAAAAAAAAAAAAAA seq=3", err: entryError{"root", errSyntax}},
+		{input: "enrtree-root:v1 e=AAAA l=AAAAAAAAAAAAAAAAAAAA seq=3 sig=AAAA", err: entryError{"root", errInvalidChild}},
+		{input: "enrtree-root:v1 e=AAAAAAAAAAAAAAAAAAAA l=AAAAAAAAAAAAAAAAAAAA seq=3 sig=AAAA", err: entryError{"root", errInvalidSig}},
+	}
+	for _, test := range tests {
+		_, err := parseRoot(test.input)
+		if err != test.err {
+			t.Errorf("parseRoot(%q): got error %v, want %v", test.input, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/crypto/ecies"
+	"github.com/aaechain/go-aaechain/crypto/sha3"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/rlp"
+	"github.com/golang/snappy"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Transports which can be selected through Config.Transport.
+const (
+	TransportRLPx  = "rlpx"
+	TransportNoise = "noise"
+)
+
+// noiseProtocolName identifies the handshake pattern and primitives. It is
+// mixed into the handshake hash, so both ends must agree on it.
+const noiseProtocolName = "Noise_IK_secp256k1_ChaChaPoly_Keccak256"
+
+const (
+	noiseTagLen = 16 // Poly1305 authentication tag
+
+	noiseInitMsgLen = pubLen + pubLen + noiseTagLen + noiseTagLen // e, encrypted s, empty payload
+	noiseRespMsgLen = pubLen + noiseTagLen                        // e, empty payload
+
+	noiseHeaderLen = 3 // frame size as uint24
+)
+
+var (
+	errNoiseDecrypt       = errors.New("noise: message authentication failed")
+	errNoiseFrameTooSmall = errors.New("noise: frame smaller than tag")
+)
+
+// noise is an alternative to the rlpx transport for networks where all nodes
+// are known to support it. The encryption handshake follows the Noise IK
+// pattern with secp256k1 node keys as static keys. Messages are sent in
+// ChaCha20-Poly1305 frames.
+type noise struct {
+	fd net.Conn
+
+	rmu, wmu sync.Mutex
+	rw       *noiseFrameRW
+}
+
+func newNoise(fd net.Conn) transport {
+	fd.SetDeadline(time.Now().Add(handshakeTimeout))
+	return &noise{fd: fd}
+}
+
+func (t *noise) ReadMsg() (Msg, error) {
+	t.rmu.Lock()
+	defer t.rmu.Unlock()
+	t.fd.SetReadDeadline(time.Now().Add(frameReadTimeout))
+	return t.rw.ReadMsg()
+}
+
+func (t *noise) WriteMsg(msg Msg) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	t.fd.SetWriteDeadline(time.Now().Add(frameWriteTimeout))
+	return t.rw.WriteMsg(msg)
+}
+
+func (t *noise) close(err error) {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	// Tell the remote end why we're disconnecting if possible.
+	// See the comment in rlpx.close about net.Pipe.
+	if t.rw != nil {
+		if r, ok := err.(DiscReason); ok && r != DiscNetworkError {
+			if err := t.fd.SetWriteDeadline(time.Now().Add(discWriteTimeout)); err == nil {
+				SendItems(t.rw, discMsg, r)
+			}
+		}
+	}
+	t.fd.Close()
+}
+
+func (t *noise) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err error) {
+	if their, err = exchangeProtoHandshake(t.rw, our); err != nil {
+		return nil, err
+	}
+	t.rw.snappy = their.Version >= snappyProtocolVersion
+	return their, nil
+}
+
+func (t *noise) doEncHandshake(prv *ecdsa.PrivateKey, dial *discover.Node) (discover.NodeID, error) {
+	var (
+		sec noiseSecrets
+		err error
+	)
+	if dial == nil {
+		sec, err = noiseReceiverHandshake(t.fd, prv)
+	} else {
+		sec, err = noiseInitiatorHandshake(t.fd, prv, dial.ID)
+	}
+	if err != nil {
+		return discover.NodeID{}, err
+	}
+	t.wmu.Lock()
+	t.rw = newNoiseFrameRW(t.fd, sec)
+	t.wmu.Unlock()
+	return sec.RemoteID, nil
+}
+
+// noiseSecrets are the session keys produced by the noise handshake.
+type noiseSecrets struct {
+	RemoteID discover.NodeID
+	Egress   []byte
+	Ingress  []byte
+}
+
+// noiseState is the symmetric state of the handshake, as defined in
+// section 5.2 of the Noise specification.
+type noiseState struct {
+	h, ck []byte
+	aead  cipher.AEAD
+	n     uint64
+}
+
+func newNoiseState() *noiseState {
+	h := crypto.Keccak256([]byte(noiseProtocolName))
+	s := &noiseState{h: h, ck: h}
+	s.mixHash(nil) // empty prologue
+	return s
+}
+
+func (s *noiseState) mixHash(data []byte) {
+	s.h = crypto.Keccak256(s.h, data)
+}
+
+func (s *noiseState) mixKey(ikm []byte) {
+	var k []byte
+	s.ck, k = noiseHKDF(s.ck, ikm)
+	s.aead, _ = chacha20poly1305.New(k)
+	s.n = 0
+}
+
+func (s *noiseState) encryptAndHash(plaintext []byte) []byte {
+	ct := s.aead.Seal(nil, noiseNonce(s.n), plaintext, s.h)
+	s.n++
+	s.mixHash(ct)
+	return ct
+}
+
+func (s *noiseState) decryptAndHash(ciphertext []byte) ([]byte, error) {
+	pt, err := s.aead.Open(nil, noiseNonce(s.n), ciphertext, s.h)
+	if err != nil {
+		return nil, errNoiseDecrypt
+	}
+	s.n++
+	s.mixHash(ciphertext)
+	return pt, nil
+}
+
+// split derives the transport keys of the initiator and recipient.
+func (s *noiseState) split(initiator bool, remoteID discover.NodeID) noiseSecrets {
+	k1, k2 := noiseHKDF(s.ck, nil)
+	sec := noiseSecrets{RemoteID: remoteID, Egress: k1, Ingress: k2}
+	if !initiator {
+		sec.Egress, sec.Ingress = k2, k1
+	}
+	return sec
+}
+
+// mixDH mixes the result of key agreement between prv and pub into the key.
+func (s *noiseState) mixDH(prv *ecdsa.PrivateKey, pub *ecies.PublicKey) error {
+	shared, err := ecies.ImportECDSA(prv).GenerateShared(pub, sskLen, sskLen)
+	if err != nil {
+		return err
+	}
+	s.mixKey(shared)
+	return nil
+}
+
+// noiseInitiatorHandshake runs the handshake on the dialing side of the
+// connection. The static key of the recipient is known in advance.
+func noiseInitiatorHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, remoteID discover.NodeID) (noiseSecrets, error) {
+	rpub, err := importPublicKey(remoteID[:])
+	if err != nil {
+		return noiseSecrets{}, err
+	}
+	eph, err := ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+	if err != nil {
+		return noiseSecrets{}, err
+	}
+	s := newNoiseState()
+	s.mixHash(remoteID[:])
+
+	// -> e, es, s, ss
+	msg := make([]byte, 0, noiseInitMsgLen)
+	epub := exportPubkey(&eph.PublicKey)
+	msg = append(msg, epub...)
+	s.mixHash(epub)
+	if err := s.mixDH(eph.ExportECDSA(), rpub); err != nil {
+		return noiseSecrets{}, err
+	}
+	msg = append(msg, s.encryptAndHash(crypto.FromECDSAPub(&prv.PublicKey)[1:])...)
+	if err := s.mixDH(prv, rpub); err != nil {
+		return noiseSecrets{}, err
+	}
+	msg = append(msg, s.encryptAndHash(nil)...)
+	if _, err := conn.Write(msg); err != nil {
+		return noiseSecrets{}, err
+	}
+
+	// <- e, ee, se
+	resp := make([]byte, noiseRespMsgLen)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return noiseSecrets{}, err
+	}
+	repub, err := importPublicKey(resp[:pubLen])
+	if err != nil {
+		return noiseSecrets{}, err
+	}
+	s.mixHash(resp[:pubLen])
+	if err := s.mixDH(eph.ExportECDSA(), repub); err != nil {
+		return noiseSecrets{}, err
+	}
+	if err := s.mixDH(prv, repub); err != nil {
+		return noiseSecrets{}, err
+	}
+	if _, err := s.decryptAndHash(resp[pubLen:]); err != nil {
+		return noiseSecrets{}, err
+	}
+	return s.split(true, remoteID), nil
+}
+
+// noiseReceiverHandshake runs the handshake on the listening side of the
+// connection. The static key of the initiator is learned from its message.
+func noiseReceiverHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey) (noiseSecrets, error) {
+	s := newNoiseState()
+	s.mixHash(crypto.FromECDSAPub(&prv.PublicKey)[1:])
+
+	// -> e, es, s, ss
+	msg := make([]byte, noiseInitMsgLen)
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return noiseSecrets{}, err
+	}
+	repub, err := importPublicKey(msg[:pubLen])
+	if err != nil {
+		return noiseSecrets{}, err
+	}
+	s.mixHash(msg[:pubLen])
+	if err := s.mixDH(prv, repub); err != nil {
+		return noiseSecrets{}, err
+	}
+	rs, err := s.decryptAndHash(msg[pubLen : 2*pubLen+noiseTagLen])
+	if err != nil {
+		return noiseSecrets{}, err
+	}
+	rpub, err := importPublicKey(rs)
+	if err != nil {
+		return noiseSecrets{}, err
+	}
+	if err := s.mixDH(prv, rpub); err != nil {
+		return noiseSecrets{}, err
+	}
+	if _, err := s.decryptAndHash(msg[2*pubLen+noiseTagLen:]); err != nil {
+		return noiseSecrets{}, err
+	}
+
+	// <- e, ee, se
+	eph, err := ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+	if err != nil {
+		return noiseSecrets{}, err
+	}
+	resp := make([]byte, 0, noiseRespMsgLen)
+	epub := exportPubkey(&eph.PublicKey)
+	resp = append(resp, epub...)
+	s.mixHash(epub)
+	if err := s.mixDH(eph.ExportECDSA(), repub); err != nil {
+		return noiseSecrets{}, err
+	}
+	if err := s.mixDH(eph.ExportECDSA(), rpub); err != nil {
+		return noiseSecrets{}, err
+	}
+	resp = append(resp, s.encryptAndHash(nil)...)
+	if _, err := conn.Write(resp); err != nil {
+		return noiseSecrets{}, err
+	}
+
+	var remoteID discover.NodeID
+	copy(remoteID[:], rs)
+	return s.split(false, remoteID), nil
+}
+
+// noiseHKDF derives two keys from the chaining key and input key material.
+func noiseHKDF(ck, ikm []byte) ([]byte, []byte) {
+	mac := hmac.New(sha3.NewKeccak256, ck)
+	mac.Write(ikm)
+	prk := mac.Sum(nil)
+
+	mac = hmac.New(sha3.NewKeccak256, prk)
+	mac.Write([]byte{1})
+	out1 := mac.Sum(nil)
+
+	mac.Reset()
+	mac.Write(out1)
+	mac.Write([]byte{2})
+	return out1, mac.Sum(nil)
+}
+
+func noiseNonce(n uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint64(nonce[4:], n)
+	return nonce
+}
+
+// noiseFrameRW encrypts each message into a single frame, which consists of
+// the uint24 size of the ciphertext followed by the ciphertext. The size is
+// authenticated as additional data.
+//
+// noiseFrameRW is not safe for concurrent use from multiple goroutines.
+type noiseFrameRW struct {
+	conn     io.ReadWriter
+	enc, dec cipher.AEAD
+	wn, rn   uint64
+
+	snappy bool
+}
+
+func newNoiseFrameRW(conn io.ReadWriter, s noiseSecrets) *noiseFrameRW {
+	enc, err := chacha20poly1305.New(s.Egress)
+	if err != nil {
+		panic("invalid egress secret: " + err.Error())
+	}
+	dec, err := chacha20poly1305.New(s.Ingress)
+	if err != nil {
+		panic("invalid ingress secret: " + err.Error())
+	}
+	return &noiseFrameRW{conn: conn, enc: enc, dec: dec}
+}
+
+func (rw *noiseFrameRW) WriteMsg(msg Msg) error {
+	ptype, _ := rlp.EncodeToBytes(msg.Code)
+
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	if rw.snappy {
+		if msg.Size > maxUint24 {
+			return errPlainMessageTooLarge
+		}
+		payload = snappy.Encode(nil, payload)
+	}
+	fsize := uint32(len(ptype) + len(payload) + noiseTagLen)
+	if fsize > maxUint24 {
+		return errors.New("message size overflows uint24")
+	}
+	frame := make([]byte, noiseHeaderLen, noiseHeaderLen+int(fsize))
+	putInt24(fsize, frame)
+	plain := append(ptype, payload...)
+	frame = rw.enc.Seal(frame, noiseNonce(rw.wn), plain, frame[:noiseHeaderLen])
+	rw.wn++
+	_, err = rw.conn.Write(frame)
+	return err
+}
+
+func (rw *noiseFrameRW) ReadMsg() (msg Msg, err error) {
+	header := make([]byte, noiseHeaderLen)
+	if _, err := io.ReadFull(rw.conn, header); err != nil {
+		return msg, err
+	}
+	fsize := readInt24(header)
+	if fsize < noiseTagLen {
+		return msg, errNoiseFrameTooSmall
+	}
+	frame := make([]byte, fsize)
+	if _, err := io.ReadFull(rw.conn, frame); err != nil {
+		return msg, err
+	}
+	plain, err := rw.dec.Open(frame[:0], noiseNonce(rw.rn), frame, header)
+	if err != nil {
+		return msg, errNoiseDecrypt
+	}
+	rw.rn++
+
+	// decode message code
+	content := bytes.NewReader(plain)
+	if err := rlp.Decode(content, &msg.Code); err != nil {
+		return msg, err
+	}
+	msg.Size = uint32(content.Len())
+	msg.Payload = content
+
+	// if snappy is enabled, verify and decompress message
+	if rw.snappy {
+		payload, err := ioutil.ReadAll(msg.Payload)
+		if err != nil {
+			return msg, err
+		}
+		size, err := snappy.DecodedLen(payload)
+		if err != nil {
+			return msg, err
+		}
+		if size > int(maxUint24) {
+			return msg, errPlainMessageTooLarge
+		}
+		payload, err = snappy.Decode(nil, payload)
+		if err != nil {
+			return msg, err
+		}
+		msg.Size, msg.Payload = uint32(size), bytes.NewReader(payload)
+	}
+	return msg, nil
+}
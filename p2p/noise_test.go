@@ -0,0 +1,151 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/rlp"
+)
+
+func TestNoiseHandshake(t *testing.T) {
+	prv0, _ := crypto.GenerateKey()
+	prv1, _ := crypto.GenerateKey()
+	id0 := discover.PubkeyID(&prv0.PublicKey)
+	id1 := discover.PubkeyID(&prv1.PublicKey)
+
+	fd0, fd1 := net.Pipe()
+	type result struct {
+		sec noiseSecrets
+		err error
+	}
+	output := make(chan result)
+	go func() {
+		sec, err := noiseInitiatorHandshake(fd0, prv0, id1)
+		output <- result{sec, err}
+	}()
+	go func() {
+		sec, err := noiseReceiverHandshake(fd1, prv1)
+		output <- result{sec, err}
+	}()
+	r1, r2 := <-output, <-output
+	if r1.err != nil || r2.err != nil {
+		t.Fatalf("handshake failed: %v, %v", r1.err, r2.err)
+	}
+	// Results arrive in any order, put the initiator first.
+	if r1.sec.RemoteID != id1 {
+		r1, r2 = r2, r1
+	}
+	if r1.sec.RemoteID != id1 || r2.sec.RemoteID != id0 {
+		t.Errorf("remote ID mismatch: got %x, %x", r1.sec.RemoteID[:8], r2.sec.RemoteID[:8])
+	}
+	if !bytes.Equal(r1.sec.Egress, r2.sec.Ingress) || !bytes.Equal(r1.sec.Ingress, r2.sec.Egress) {
+		t.Errorf("session keys don't match:\n initiator: %x %x\n recipient: %x %x",
+			r1.sec.Egress, r1.sec.Ingress, r2.sec.Egress, r2.sec.Ingress)
+	}
+	if bytes.Equal(r1.sec.Egress, r1.sec.Ingress) {
+		t.Error("egress and ingress keys are equal")
+	}
+}
+
+func TestNoiseHandshakeWrongKey(t *testing.T) {
+	prv0, _ := crypto.GenerateKey()
+	prv1, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	// The initiator expects a different recipient key, so the recipient
+	// can't authenticate the first message.
+	fd0, fd1 := net.Pipe()
+	go func() {
+		noiseInitiatorHandshake(fd0, prv0, discover.PubkeyID(&other.PublicKey))
+		fd0.Close()
+	}()
+	if _, err := noiseReceiverHandshake(fd1, prv1); err != errNoiseDecrypt {
+		t.Errorf("wrong error: got %v, want %v", err, errNoiseDecrypt)
+	}
+	fd1.Close()
+}
+
+func TestNoiseFrameRW(t *testing.T) {
+	var (
+		k1   = make([]byte, 32)
+		k2   = make([]byte, 32)
+		conn = new(bytes.Buffer)
+	)
+	rand.Read(k1)
+	rand.Read(k2)
+	rw1 := newNoiseFrameRW(conn, noiseSecrets{Egress: k1, Ingress: k2})
+	rw2 := newNoiseFrameRW(conn, noiseSecrets{Egress: k2, Ingress: k1})
+
+	for i := 0; i < 10; i++ {
+		rw1.snappy = i >= 5
+		rw2.snappy = i >= 5
+		wmsg := []interface{}{"foo", "bar", strings.Repeat("test", i)}
+		if err := Send(rw1, uint64(i), wmsg); err != nil {
+			t.Fatalf("WriteMsg error (i=%d): %v", i, err)
+		}
+		msg, err := rw2.ReadMsg()
+		if err != nil {
+			t.Fatalf("ReadMsg error (i=%d): %v", i, err)
+		}
+		if msg.Code != uint64(i) {
+			t.Fatalf("msg code mismatch: got %d, want %d", msg.Code, i)
+		}
+		payload, _ := ioutil.ReadAll(msg.Payload)
+		wantPayload, _ := rlp.EncodeToBytes(wmsg)
+		if !bytes.Equal(payload, wantPayload) {
+			t.Fatalf("msg payload mismatch:\ngot  %x\nwant %x", payload, wantPayload)
+		}
+	}
+
+	// Modified frames are rejected.
+	if err := Send(rw1, 1, []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+	conn.Bytes()[noiseHeaderLen] ^= 1
+	if _, err := rw2.ReadMsg(); err != errNoiseDecrypt {
+		t.Errorf("wrong error for modified frame: got %v, want %v", err, errNoiseDecrypt)
+	}
+}
+
+func TestNoiseFrameRWReplay(t *testing.T) {
+	var (
+		k    = make([]byte, 32)
+		conn = new(bytes.Buffer)
+	)
+	rand.Read(k)
+	rw1 := newNoiseFrameRW(conn, noiseSecrets{Egress: k, Ingress: k})
+	rw2 := newNoiseFrameRW(conn, noiseSecrets{Egress: k, Ingress: k})
+
+	Send(rw1, 1, []string{"foo"})
+	frame := append([]byte(nil), conn.Bytes()...)
+	if _, err := rw2.ReadMsg(); err != nil {
+		t.Fatal(err)
+	}
+	// Sending the same frame again must fail because the nonce has advanced.
+	conn.Write(frame)
+	if _, err := rw2.ReadMsg(); err != errNoiseDecrypt {
+		t.Errorf("wrong error for replayed frame: got %v, want %v", err, errNoiseDecrypt)
+	}
+}
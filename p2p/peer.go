@@ -109,6 +109,9 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// rep tracks the reputation of the peer, if set
+	rep *reputation
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -179,6 +182,21 @@ func newPeer(conn *conn, protocols []Protocol) *Peer {
 	return p
 }
 
+// AdjustScore changes the reputation score of the peer by delta. Protocols
+// should use the Score* constants. A peer whose score falls too low is
+// disconnected and temporarily banned. Trusted and static peers are not scored.
+func (p *Peer) AdjustScore(delta int, reason string) {
+	if p.rep == nil || p.rw.is(trustedConn|staticDialedConn) {
+		return
+	}
+	score, banned := p.rep.adjust(p.ID(), delta)
+	p.log.Trace("Adjusted peer score", "delta", delta, "score", score, "reason", reason)
+	if banned {
+		p.log.Debug("Banning low-scored peer", "score", score, "reason", reason, "duration", banDuration)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
 func (p *Peer) Log() log.Logger {
 	return p.log
 }
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 )
 
 // Protocol represents a P2P subprotocol implementation.
@@ -52,6 +53,15 @@ type Protocol struct {
 	// about a certain peer in the network. If an info retrieval function is set,
 	// but returns nil, it is assumed that the protocol handshake is still running.
 	PeerInfo func(id discover.NodeID) interface{}
+
+	// Attributes contains protocol specific entries for the node record of
+	// the local node.
+	Attributes []enr.Entry
+
+	// RecordFilter is an optional helper method to decide whaaeer a node should
+	// be dialed based on its node record. Nodes rejected by the filter of any
+	// protocol are not dialed.
+	RecordFilter func(*enr.Record) bool
 }
 
 func (p Protocol) cap() Cap {
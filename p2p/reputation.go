@@ -0,0 +1,190 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+)
+
+// Score adjustments reported by protocols through Peer.AdjustScore.
+const (
+	ScoreUseful     = 1   // the peer delivered useful data
+	ScoreUseless    = -10 // the peer failed to deliver requested data in time
+	ScoreMisbehaved = -40 // the peer sent invalid data
+)
+
+const (
+	maxScore     = 100
+	minScore     = -100
+	banThreshold = -50              // peers at or below this score are banned
+	banDuration  = 30 * time.Minute // how long low scorers are banned
+)
+
+// PeerScore is the reputation of a node.
+type PeerScore struct {
+	ID          string     `json:"id"`
+	Score       int        `json:"score"`
+	BannedUntil *time.Time `json:"bannedUntil,omitempty"`
+}
+
+// scoreStore is implemented by the discovery table, which persists scores
+// in the node database.
+type scoreStore interface {
+	PeerScore(id discover.NodeID) (int, time.Time)
+	SetPeerScore(id discover.NodeID, score int, bannedUntil time.Time) error
+}
+
+// reputation tracks the scores of remote nodes. Scores are kept in memory and
+// written through to the store, if there is one. All methods are safe to call
+// on a nil reputation, which reports a neutral score for all nodes.
+type reputation struct {
+	mu    sync.Mutex
+	db    scoreStore
+	nodes map[discover.NodeID]*nodeScore
+	now   func() time.Time
+}
+
+type nodeScore struct {
+	score       int
+	bannedUntil time.Time
+}
+
+func newReputation(db scoreStore) *reputation {
+	return &reputation{db: db, nodes: make(map[discover.NodeID]*nodeScore), now: time.Now}
+}
+
+// get returns the score entry of id, loading it from the store if necessary.
+// Loaded entries are only cached if track is true, so looking up nodes which
+// never get scored doesn't grow the cache. The caller must hold r.mu.
+func (r *reputation) get(id discover.NodeID, track bool) *nodeScore {
+	if s := r.nodes[id]; s != nil {
+		return s
+	}
+	s := new(nodeScore)
+	if r.db != nil {
+		s.score, s.bannedUntil = r.db.PeerScore(id)
+	}
+	if track {
+		r.nodes[id] = s
+	}
+	return s
+}
+
+// store writes the score entry of id to the store. The caller must hold r.mu.
+func (r *reputation) store(id discover.NodeID, s *nodeScore) {
+	if r.db == nil {
+		return
+	}
+	if err := r.db.SetPeerScore(id, s.score, s.bannedUntil); err != nil {
+		log.Warn("Failed to store peer score", "id", id, "err", err)
+	}
+}
+
+// score returns the current score of id.
+func (r *reputation) score(id discover.NodeID) int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.get(id, false).score
+}
+
+// banned reports whaaeer id is currently banned.
+func (r *reputation) banned(id discover.NodeID) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.now().Before(r.get(id, false).bannedUntil)
+}
+
+// adjust adds delta to the score of id. If the score drops to the ban
+// threshold, the node is banned. It returns the new score and whaaeer the
+// node got banned.
+func (r *reputation) adjust(id discover.NodeID, delta int) (int, bool) {
+	if r == nil {
+		return 0, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.get(id, true)
+	s.score += delta
+	if s.score > maxScore {
+		s.score = maxScore
+	}
+	if s.score < minScore {
+		s.score = minScore
+	}
+	now := r.now()
+	ban := delta < 0 && s.score <= banThreshold && !now.Before(s.bannedUntil)
+	if ban {
+		s.bannedUntil = now.Add(banDuration)
+	}
+	r.store(id, s)
+	return s.score, ban
+}
+
+// reset clears the score and ban of id.
+func (r *reputation) reset(id discover.NodeID) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.get(id, true)
+	s.score, s.bannedUntil = 0, time.Time{}
+	r.store(id, s)
+}
+
+// list returns the scores of all nodes which have been scored or reset since
+// startup, sorted by score.
+func (r *reputation) list() []*PeerScore {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		now    = r.now()
+		scores = make([]*PeerScore, 0, len(r.nodes))
+	)
+	for id, s := range r.nodes {
+		ps := &PeerScore{ID: id.String(), Score: s.score}
+		if now.Before(s.bannedUntil) {
+			until := s.bannedUntil
+			ps.BannedUntil = &until
+		}
+		scores = append(scores, ps)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score < scores[j].Score
+		}
+		return scores[i].ID < scores[j].ID
+	})
+	return scores
+}
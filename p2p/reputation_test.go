@@ -0,0 +1,97 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aaechain/go-aaechain/p2p/discover"
+)
+
+// mapScoreStore is a scoreStore backed by a map.
+type mapScoreStore map[discover.NodeID]nodeScore
+
+func (s mapScoreStore) PeerScore(id discover.NodeID) (int, time.Time) {
+	return s[id].score, s[id].bannedUntil
+}
+
+func (s mapScoreStore) SetPeerScore(id discover.NodeID, score int, bannedUntil time.Time) error {
+	s[id] = nodeScore{score, bannedUntil}
+	return nil
+}
+
+func TestReputationBan(t *testing.T) {
+	var (
+		db  = make(mapScoreStore)
+		rep = newReputation(db)
+		now = time.Unix(1000, 0)
+		id  = randomID()
+	)
+	rep.now = func() time.Time { return now }
+
+	if score, banned := rep.adjust(id, ScoreMisbehaved); score != ScoreMisbehaved || banned {
+		t.Fatalf("wrong result after first penalty: score %d, banned %t", score, banned)
+	}
+	if score, banned := rep.adjust(id, ScoreMisbehaved); score != 2*ScoreMisbehaved || !banned {
+		t.Fatalf("wrong result after second penalty: score %d, banned %t", score, banned)
+	}
+	if !rep.banned(id) {
+		t.Fatal("node not banned")
+	}
+	// Further penalties don't extend the ban.
+	if _, banned := rep.adjust(id, ScoreMisbehaved); banned {
+		t.Error("ban reported twice")
+	}
+	if score := rep.score(id); score != minScore {
+		t.Errorf("score not clamped: %d", score)
+	}
+	// The ban expires.
+	now = now.Add(banDuration)
+	if rep.banned(id) {
+		t.Error("ban did not expire")
+	}
+	// Scores are persisted.
+	rep2 := newReputation(db)
+	rep2.now = rep.now
+	if score := rep2.score(id); score != minScore {
+		t.Errorf("wrong score after reload: %d", score)
+	}
+	rep2.reset(id)
+	if score, banned := db.PeerScore(id); score != 0 || !banned.IsZero() {
+		t.Errorf("reset not persisted: score %d, banned until %v", score, banned)
+	}
+}
+
+func TestReputationList(t *testing.T) {
+	rep := newReputation(nil)
+	a, b, c := randomID(), randomID(), randomID()
+	rep.adjust(a, ScoreUseful)
+	rep.adjust(b, 2*ScoreMisbehaved)
+	rep.score(c) // lookups are not tracked
+
+	list := rep.list()
+	if len(list) != 2 {
+		t.Fatalf("wrong number of entries: %d", len(list))
+	}
+	if list[0].ID != b.String() || list[0].BannedUntil == nil {
+		t.Errorf("wrong first entry: %+v", list[0])
+	}
+	if list[1].ID != a.String() || list[1].Score != ScoreUseful || list[1].BannedUntil != nil {
+		t.Errorf("wrong second entry: %+v", list[1])
+	}
+}
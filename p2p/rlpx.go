@@ -122,22 +122,31 @@ func (t *rlpx) close(err error) {
 }
 
 func (t *rlpx) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err error) {
+	if their, err = exchangeProtoHandshake(t.rw, our); err != nil {
+		return nil, err
+	}
+	// If the protocol version supports Snappy encoding, upgrade immediately
+	t.rw.snappy = their.Version >= snappyProtocolVersion
+
+	return their, nil
+}
+
+// exchangeProtoHandshake sends our protocol handshake and reads the
+// handshake of the remote side.
+func exchangeProtoHandshake(rw MsgReadWriter, our *protoHandshake) (their *protoHandshake, err error) {
 	// Writing our handshake happens concurrently, we prefer
 	// returning the handshake read error. If the remote side
 	// disconnects us early with a valid reason, we should return it
 	// as the error so it can be tracked elsewhere.
 	werr := make(chan error, 1)
-	go func() { werr <- Send(t.rw, handshakeMsg, our) }()
-	if their, err = readProtocolHandshake(t.rw, our); err != nil {
+	go func() { werr <- Send(rw, handshakeMsg, our) }()
+	if their, err = readProtocolHandshake(rw, our); err != nil {
 		<-werr // make sure the write terminates too
 		return nil, err
 	}
 	if err := <-werr; err != nil {
 		return nil, fmt.Errorf("write error: %v", err)
 	}
-	// If the protocol version supports Snappy encoding, upgrade immediately
-	t.rw.snappy = their.Version >= snappyProtocolVersion
-
 	return their, nil
 }
 
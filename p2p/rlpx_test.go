@@ -146,7 +146,23 @@ func testEncHandshake(token []byte) error {
 	return nil
 }
 
+// testTransports are the transports which can be selected through
+// Config.Transport.
+var testTransports = []struct {
+	name string
+	new  func(net.Conn) transport
+}{
+	{TransportRLPx, newRLPX},
+	{TransportNoise, newNoise},
+}
+
 func TestProtocolHandshake(t *testing.T) {
+	for _, tt := range testTransports {
+		t.Run(tt.name, func(t *testing.T) { testProtocolHandshake(t, tt.new) })
+	}
+}
+
+func testProtocolHandshake(t *testing.T, newTransport func(net.Conn) transport) {
 	var (
 		prv0, _ = crypto.GenerateKey()
 		node0   = &discover.Node{ID: discover.PubkeyID(&prv0.PublicKey), IP: net.IP{1, 2, 3, 4}, TCP: 33}
@@ -168,8 +184,8 @@ func TestProtocolHandshake(t *testing.T) {
 	go func() {
 		defer wg.Done()
 		defer fd0.Close()
-		rlpx := newRLPX(fd0)
-		remid, err := rlpx.doEncHandshake(prv0, node1)
+		tr := newTransport(fd0)
+		remid, err := tr.doEncHandshake(prv0, node1)
 		if err != nil {
 			t.Errorf("dial side enc handshake failed: %v", err)
 			return
@@ -179,7 +195,7 @@ func TestProtocolHandshake(t *testing.T) {
 			return
 		}
 
-		phs, err := rlpx.doProtoHandshake(hs0)
+		phs, err := tr.doProtoHandshake(hs0)
 		if err != nil {
 			t.Errorf("dial side proto handshake error: %v", err)
 			return
@@ -189,13 +205,13 @@ func TestProtocolHandshake(t *testing.T) {
 			t.Errorf("dial side proto handshake mismatch:\ngot: %s\nwant: %s\n", spew.Sdump(phs), spew.Sdump(hs1))
 			return
 		}
-		rlpx.close(DiscQuitting)
+		tr.close(DiscQuitting)
 	}()
 	go func() {
 		defer wg.Done()
 		defer fd1.Close()
-		rlpx := newRLPX(fd1)
-		remid, err := rlpx.doEncHandshake(prv1, nil)
+		tr := newTransport(fd1)
+		remid, err := tr.doEncHandshake(prv1, nil)
 		if err != nil {
 			t.Errorf("listen side enc handshake failed: %v", err)
 			return
@@ -205,7 +221,7 @@ func TestProtocolHandshake(t *testing.T) {
 			return
 		}
 
-		phs, err := rlpx.doProtoHandshake(hs1)
+		phs, err := tr.doProtoHandshake(hs1)
 		if err != nil {
 			t.Errorf("listen side proto handshake error: %v", err)
 			return
@@ -216,7 +232,7 @@ func TestProtocolHandshake(t *testing.T) {
 			return
 		}
 
-		if err := ExpectMsg(rlpx, discMsg, []DiscReason{DiscQuitting}); err != nil {
+		if err := ExpectMsg(tr, discMsg, []DiscReason{DiscQuitting}); err != nil {
 			t.Errorf("error receiving disconnect: %v", err)
 		}
 	}()
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -31,6 +32,8 @@ import (
 	"github.com/aaechain/go-aaechain/log"
 	"github.com/aaechain/go-aaechain/p2p/discover"
 	"github.com/aaechain/go-aaechain/p2p/discv5"
+	"github.com/aaechain/go-aaechain/p2p/dnsdisc"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/p2p/nat"
 	"github.com/aaechain/go-aaechain/p2p/netutil"
 )
@@ -49,6 +52,9 @@ const (
 
 	// Maximum amount of time allowed for writing a complete message.
 	frameWriteTimeout = 20 * time.Second
+
+	// Interval at which the external IP is queried from the NAT device.
+	externalIPRefreshInterval = 5 * time.Minute
 )
 
 var errServerStopped = errors.New("server stopped")
@@ -110,6 +116,18 @@ type Config struct {
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
 
+	// DNSDiscovery is a list of enrtree:// URLs of DNS node lists. Nodes
+	// contained in the lists are used as dial candidates.
+	DNSDiscovery []string `toml:",omitempty"`
+
+	// RecordEntries are added to the node record of the local node, which
+	// is served to other nodes through discovery.
+	RecordEntries []enr.Entry `toml:"-"`
+
+	// RecordFilter, if set, is applied to the node record of dynamically
+	// dialed candidates. Candidates whose record is rejected are not dialed.
+	RecordFilter func(*enr.Record) bool `toml:"-"`
+
 	// Protocols should contain the protocols supported
 	// by the server. Matching protocols are launched for
 	// each peer.
@@ -135,6 +153,11 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// Transport selects the encryption handshake and framing used for
+	// peer connections. It can be TransportRLPx (the default) or
+	// TransportNoise. Nodes using different transports cannot connect.
+	Transport string `toml:",omitempty"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
@@ -156,11 +179,14 @@ type Server struct {
 	lock    sync.Mutex // protects running
 	running bool
 
-	ntab         discoverTable
-	listener     net.Listener
-	ourHandshake *protoHandshake
-	lastLookup   time.Time
-	DiscV5       *discv5.Network
+	ntab          discoverTable
+	listener      net.Listener
+	ourHandshake  *protoHandshake
+	lastLookup    time.Time
+	recordFilters []func(*enr.Record) bool
+	dnsSource     *dnsdisc.Source
+	rep           *reputation
+	DiscV5        *discv5.Network
 
 	// These are for Peers, PeerCount (and nothing else).
 	peerOp     chan peerOpFunc
@@ -399,7 +425,14 @@ func (srv *Server) Start() (err error) {
 		return fmt.Errorf("Server.PrivateKey must be set to a non-nil key")
 	}
 	if srv.newTransport == nil {
-		srv.newTransport = newRLPX
+		switch srv.Transport {
+		case "", TransportRLPx:
+			srv.newTransport = newRLPX
+		case TransportNoise:
+			srv.newTransport = newNoise
+		default:
+			return fmt.Errorf("unknown transport %q", srv.Transport)
+		}
 	}
 	if srv.Dialer == nil {
 		srv.Dialer = TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}
@@ -447,20 +480,38 @@ func (srv *Server) Start() (err error) {
 	}
 
 	// node table
+	srv.recordFilters = nil
+	if srv.RecordFilter != nil {
+		srv.recordFilters = append(srv.recordFilters, srv.RecordFilter)
+	}
+	for _, p := range srv.Protocols {
+		if p.RecordFilter != nil {
+			srv.recordFilters = append(srv.recordFilters, p.RecordFilter)
+		}
+	}
 	if !srv.NoDiscovery {
 		cfg := discover.Config{
 			PrivateKey:   srv.PrivateKey,
 			AnnounceAddr: realaddr,
+			TCPPort:      srv.listenPort(),
 			NodeDBPath:   srv.NodeDatabase,
 			NetRestrict:  srv.NetRestrict,
 			Bootnodes:    srv.BooaaerapNodes,
 			Unhandled:    unhandled,
+			Entries:      append([]enr.Entry{}, srv.RecordEntries...),
+		}
+		for _, p := range srv.Protocols {
+			cfg.Entries = append(cfg.Entries, p.Attributes...)
 		}
 		ntab, err := discover.ListenUDP(conn, cfg)
 		if err != nil {
 			return err
 		}
 		srv.ntab = ntab
+		if srv.NAT != nil && !realaddr.IP.IsLoopback() {
+			srv.loopWG.Add(1)
+			go srv.trackExternalIP(ntab, realaddr.Port)
+		}
 	}
 
 	if srv.DiscoveryV5 {
@@ -482,9 +533,31 @@ func (srv *Server) Start() (err error) {
 		srv.DiscV5 = ntab
 	}
 
+	// peer reputation, persisted in the node database if discovery is enabled
+	if tab, ok := srv.ntab.(scoreStore); ok {
+		srv.rep = newReputation(tab)
+	} else {
+		srv.rep = newReputation(nil)
+	}
+
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.StaticNodes, srv.BooaaerapNodes, srv.ntab, dynPeers, srv.NetRestrict)
 
+	// DNS node lists
+	srv.dnsSource = nil
+	if len(srv.DNSDiscovery) > 0 {
+		cfg := dnsdisc.Config{Logger: srv.log}
+		if len(srv.recordFilters) > 0 {
+			cfg.Filter = srv.acceptRecord
+		}
+		src, err := dnsdisc.NewClient(cfg).NewSource(srv.DNSDiscovery...)
+		if err != nil {
+			return err
+		}
+		srv.dnsSource = src
+		dialer.addSource(src)
+	}
+
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
 	for _, p := range srv.Protocols {
@@ -506,6 +579,54 @@ func (srv *Server) Start() (err error) {
 	return nil
 }
 
+// listenPort returns the TCP port configured in ListenAddr, or zero if it is
+// not known before the listener is started.
+func (srv *Server) listenPort() int {
+	_, port, err := net.SplitHostPort(srv.ListenAddr)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// trackExternalIP periodically queries the NAT device for the external IP and
+// updates the endpoint announced in the local node record when it changes.
+func (srv *Server) trackExternalIP(ntab *discover.Table, port int) {
+	defer srv.loopWG.Done()
+
+	refresh := time.NewTicker(externalIPRefreshInterval)
+	defer refresh.Stop()
+	for {
+		select {
+		case <-refresh.C:
+			ip, err := srv.NAT.ExternalIP()
+			if err != nil {
+				srv.log.Debug("Couldn't get external IP", "err", err)
+				continue
+			}
+			if err := ntab.SetEndpoint(&net.UDPAddr{IP: ip, Port: port}); err != nil {
+				srv.log.Debug("Couldn't update node record", "err", err)
+			}
+		case <-srv.quit:
+			return
+		}
+	}
+}
+
+// acceptRecord reports whaaeer the given node record passes all record filters.
+func (srv *Server) acceptRecord(rec *enr.Record) bool {
+	for _, filter := range srv.recordFilters {
+		if !filter(rec) {
+			return false
+		}
+	}
+	return true
+}
+
 func (srv *Server) startListening() error {
 	// Launch the TCP listener.
 	listener, err := net.Listen("tcp", srv.ListenAddr)
@@ -540,6 +661,7 @@ func (srv *Server) run(dialstate dialer) {
 	var (
 		peers        = make(map[discover.NodeID]*Peer)
 		inboundCount = 0
+		evicting     = 0 // evicted peers which haven't shut down yet
 		trusted      = make(map[discover.NodeID]bool, len(srv.TrustedNodes))
 		taskdone     = make(chan task, maxActiveDialTasks)
 		runningTasks []task
@@ -633,9 +755,23 @@ running:
 			// At this point the connection is past the protocol handshake.
 			// Its capabilities are known and the remote identity is verified.
 			err := srv.protoHandshakeChecks(peers, inboundCount, c)
+			if err == nil && srv.isFull(peers, inboundCount, c) {
+				// The checks passed because there is a peer with a lower score.
+				// Evict it to make room. It is removed from the peer set right
+				// away so it doesn't count against the limits anymore.
+				victim := srv.evictionCandidate(peers, inboundCount, c)
+				victim.log.Debug("Evicting low-scored peer", "score", srv.rep.score(victim.ID()), "for", c.id)
+				victim.Disconnect(DiscTooManyPeers)
+				delete(peers, victim.ID())
+				if victim.Inbound() {
+					inboundCount--
+				}
+				evicting++
+			}
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(c, srv.Protocols)
+				p.rep = srv.rep
 				// If message events are enabled, pass the peerFeed
 				// to the peer
 				if srv.EnableMsgEvents {
@@ -660,6 +796,12 @@ running:
 		case pd := <-srv.delpeer:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
+			if peers[pd.ID()] != pd.Peer {
+				// The peer was evicted and is already gone from the peer set.
+				pd.log.Debug("Removing evicted p2p peer", "duration", d, "req", pd.requested, "err", pd.err)
+				evicting--
+				break
+			}
 			pd.log.Debug("Removing p2p peer", "duration", d, "peers", len(peers)-1, "req", pd.requested, "err", pd.err)
 			delete(peers, pd.ID())
 			if pd.Inbound() {
@@ -674,6 +816,9 @@ running:
 	if srv.ntab != nil {
 		srv.ntab.Close()
 	}
+	if srv.dnsSource != nil {
+		srv.dnsSource.Close()
+	}
 	if srv.DiscV5 != nil {
 		srv.DiscV5.Close()
 	}
@@ -684,10 +829,14 @@ running:
 	// Wait for peers to shut down. Pending connections and tasks are
 	// not handled here and will terminate soon-ish because srv.quit
 	// is closed.
-	for len(peers) > 0 {
+	for len(peers) > 0 || evicting > 0 {
 		p := <-srv.delpeer
 		p.log.Trace("<-delpeer (spindown)", "remainingTasks", len(runningTasks))
-		delete(peers, p.ID())
+		if peers[p.ID()] == p.Peer {
+			delete(peers, p.ID())
+		} else {
+			evicting--
+		}
 	}
 }
 
@@ -703,9 +852,9 @@ func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, inbound
 
 func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, inboundCount int, c *conn) error {
 	switch {
-	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
-		return DiscTooManyPeers
-	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
+	case !c.is(trustedConn|staticDialedConn) && srv.rep.banned(c.id):
+		return DiscUselessPeer
+	case srv.isFull(peers, inboundCount, c) && srv.evictionCandidate(peers, inboundCount, c) == nil:
 		return DiscTooManyPeers
 	case peers[c.id] != nil:
 		return DiscAlreadyConnected
@@ -716,12 +865,57 @@ func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, inboundCo
 	}
 }
 
+// isFull reports whaaeer the peer limits prevent c from being added.
+func (srv *Server) isFull(peers map[discover.NodeID]*Peer, inboundCount int, c *conn) bool {
+	switch {
+	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
+		return true
+	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
+		return true
+	default:
+		return false
+	}
+}
+
+// evictionCandidate returns the connected peer with the lowest score if it
+// can be evicted to make room for c, i.e. if its score is lower than the score
+// of c. Trusted and static peers are never evicted. If c is inbound and the
+// inbound slots are full, only inbound peers are considered.
+func (srv *Server) evictionCandidate(peers map[discover.NodeID]*Peer, inboundCount int, c *conn) *Peer {
+	var (
+		needInbound = c.is(inboundConn) && inboundCount >= srv.maxInboundConns()
+		cscore      = srv.rep.score(c.id)
+		victim      *Peer
+		vscore      int
+	)
+	for _, p := range peers {
+		if p.rw.is(trustedConn|staticDialedConn) || (needInbound && !p.Inbound()) {
+			continue
+		}
+		if score := srv.rep.score(p.ID()); score < cscore && (victim == nil || score < vscore) {
+			victim, vscore = p, score
+		}
+	}
+	return victim
+}
+
+// PeerScores returns the reputation of all nodes which have been scored
+// since the server was started.
+func (srv *Server) PeerScores() []*PeerScore {
+	return srv.rep.list()
+}
+
+// ResetPeerScore clears the reputation score and ban of the given node.
+func (srv *Server) ResetPeerScore(id discover.NodeID) {
+	srv.rep.reset(id)
+}
+
 func (srv *Server) maxInboundConns() int {
 	return srv.MaxPeers - srv.maxDialedConns()
 }
 
 func (srv *Server) maxDialedConns() int {
-	if srv.NoDiscovery || srv.NoDial {
+	if srv.NoDial || (srv.NoDiscovery && len(srv.DNSDiscovery) == 0) {
 		return 0
 	}
 	r := srv.DialRatio
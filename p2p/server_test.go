@@ -366,6 +366,60 @@ func TestServerAtCap(t *testing.T) {
 
 }
 
+func TestServerEviction(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			PrivateKey: newkey(),
+			MaxPeers:   3,
+			NoDial:     true,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id discover.NodeID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(id, fd)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, id: id, cont: make(chan error)}
+	}
+	// Fill up the peer set. The first peer has a bad score.
+	ids := []discover.NodeID{randomID(), randomID(), randomID()}
+	srv.rep.adjust(ids[0], ScoreMisbehaved)
+	for i, id := range ids {
+		if err := srv.checkpoint(newconn(id), srv.addpeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	// A connection with a worse score is rejected.
+	worse := randomID()
+	srv.rep.adjust(worse, 2*ScoreMisbehaved+ScoreUseless)
+	if err := srv.checkpoint(newconn(worse), srv.posthandshake); err != DiscUselessPeer {
+		t.Errorf("wrong error for banned conn: %v", err)
+	}
+	// A connection with a neutral score evicts the bad peer.
+	better := randomID()
+	c := newconn(better)
+	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
+		t.Fatalf("unexpected error @posthandshake: %v", err)
+	}
+	if err := srv.checkpoint(c, srv.addpeer); err != nil {
+		t.Fatalf("unexpected error @addpeer: %v", err)
+	}
+	connected := make(map[discover.NodeID]bool)
+	for _, p := range srv.Peers() {
+		connected[p.ID()] = true
+	}
+	if len(connected) != 3 || connected[ids[0]] || !connected[better] {
+		t.Errorf("wrong peer set after eviction: %v", connected)
+	}
+	// Another neutral connection can't evict anyone.
+	if err := srv.checkpoint(newconn(randomID()), srv.posthandshake); err != DiscTooManyPeers {
+		t.Errorf("wrong error for insert: %v", err)
+	}
+}
+
 func TestServerSetupConn(t *testing.T) {
 	id := randomID()
 	srvkey := newkey()
@@ -502,3 +556,67 @@ func randomID() (id discover.NodeID) {
 	}
 	return id
 }
+
+func TestServerTransports(t *testing.T) {
+	for _, tt := range testTransports {
+		t.Run(tt.name, func(t *testing.T) { testServerTransport(t, tt.name) })
+	}
+}
+
+// testServerTransport connects two servers using the given transport.
+func testServerTransport(t *testing.T, transport string) {
+	newServer := func() *Server {
+		srv := &Server{Config: Config{
+			Name:        "test",
+			MaxPeers:    10,
+			ListenAddr:  "127.0.0.1:0",
+			PrivateKey:  newkey(),
+			NoDiscovery: true,
+			Transport:   transport,
+		}}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("could not start server: %v", err)
+		}
+		return srv
+	}
+	srv1, srv2 := newServer(), newServer()
+	defer srv1.Stop()
+	defer srv2.Stop()
+
+	// Wait for the peer to be added on both sides.
+	events1, events2 := make(chan *PeerEvent, 1), make(chan *PeerEvent, 1)
+	sub1, sub2 := srv1.SubscribeEvents(events1), srv2.SubscribeEvents(events2)
+	defer sub1.Unsubscribe()
+	defer sub2.Unsubscribe()
+
+	addr := srv2.listener.Addr().(*net.TCPAddr)
+	srv1.AddPeer(discover.NewNode(srv2.Self().ID, addr.IP, 0, uint16(addr.Port)))
+	timeout := time.After(5 * time.Second)
+	for _, want := range []struct {
+		events chan *PeerEvent
+		id     discover.NodeID
+	}{{events1, srv2.Self().ID}, {events2, srv1.Self().ID}} {
+	wait:
+		for {
+			select {
+			case ev := <-want.events:
+				if ev.Type != PeerEventTypeAdd {
+					continue
+				}
+				if ev.Peer != want.id {
+					t.Errorf("wrong peer added: got %v, want %v", ev.Peer, want.id)
+				}
+				break wait
+			case <-timeout:
+				t.Fatal("timed out waiting for peer connection")
+			}
+		}
+	}
+}
+
+func TestServerUnknownTransport(t *testing.T) {
+	srv := &Server{Config: Config{PrivateKey: newkey(), Transport: "foo"}}
+	if err := srv.Start(); err == nil {
+		t.Fatal("expected error for unknown transport")
+	}
+}
@@ -79,19 +79,21 @@ var (
 
 	// AllaaeashProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the aaechain core developers into the aaeash consensus.
+	// The opt-in base fee market and EVM object format are left disabled.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllaaeashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(aaeashConfig), nil}
+	AllaaeashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(aaeashConfig), nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the aaechain core developers into the Clique consensus.
+	// The opt-in base fee market and EVM object format are left disabled.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(aaeashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(aaeashConfig), nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -117,6 +119,11 @@ type ChainConfig struct {
 
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	TypedTxBlock        *big.Int `json:"typedTxBlock,omitempty"`        // Typed transaction envelope switch block (nil = no fork, 0 = already activated)
+	FeeDelegationBlock  *big.Int `json:"feeDelegationBlock,omitempty"`  // Fee delegation switch block (nil = no fork, 0 = already activated)
+	AccessListBlock     *big.Int `json:"accessListBlock,omitempty"`     // Access list gas accounting switch block (nil = no fork, 0 = already activated)
+	BaseFeeBlock        *big.Int `json:"baseFeeBlock,omitempty"`        // Base fee market switch block (nil = no fork, 0 = already activated)
+	EOFBlock            *big.Int `json:"eofBlock,omitempty"`            // EVM object format switch block (nil = no fork, 0 = already activated)
 
 	// Various consensus engines
 	aaeash *aaeashConfig `json:"ethash,omitempty"`
@@ -142,6 +149,15 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// CheckpointOracleConfig is the configuration of the checkpoint oracle contract
+// from which light clients read the latest checkpoint. A checkpoint is trusted
+// if it is signed by at least Threshold of the Signers.
+type CheckpointOracleConfig struct {
+	Address   common.Address   `json:"address"`
+	Signers   []common.Address `json:"signers"`
+	Threshold int              `json:"threshold"`
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -153,7 +169,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v TypedTx: %v FeeDelegation: %v AccessList: %v BaseFee: %v EOF: %v Engine: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -163,6 +179,11 @@ func (c *ChainConfig) String() string {
 		c.EIP158Block,
 		c.ByzantiumBlock,
 		c.ConstantinopleBlock,
+		c.TypedTxBlock,
+		c.FeeDelegationBlock,
+		c.AccessListBlock,
+		c.BaseFeeBlock,
+		c.EOFBlock,
 		engine,
 	)
 }
@@ -197,6 +218,36 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsTypedTx returns whaaeer num is either equal to the typed transaction
+// envelope fork block or greater.
+func (c *ChainConfig) IsTypedTx(num *big.Int) bool {
+	return isForked(c.TypedTxBlock, num)
+}
+
+// IsFeeDelegation returns whaaeer num is either equal to the fee delegation
+// fork block or greater.
+func (c *ChainConfig) IsFeeDelegation(num *big.Int) bool {
+	return isForked(c.FeeDelegationBlock, num)
+}
+
+// IsAccessList returns whaaeer num is either equal to the access list fork
+// block or greater, pricing state accesses by whaaeer they are warm or cold.
+func (c *ChainConfig) IsAccessList(num *big.Int) bool {
+	return isForked(c.AccessListBlock, num)
+}
+
+// IsBaseFee returns whaaeer num is either equal to the base fee fork block or
+// greater, from which on block headers carry a base fee burnt by transactions.
+func (c *ChainConfig) IsBaseFee(num *big.Int) bool {
+	return isForked(c.BaseFeeBlock, num)
+}
+
+// IsEOF returns whaaeer num is either equal to the EVM object format fork block
+// or greater, from which on new contracts may be deployed as validated containers.
+func (c *ChainConfig) IsEOF(num *big.Int) bool {
+	return isForked(c.EOFBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -205,6 +256,8 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 		return GasTableHomestead
 	}
 	switch {
+	case c.IsAccessList(num):
+		return GasTableAccessList
 	case c.IsEIP158(num):
 		return GasTableEIP158
 	case c.IsEIP150(num):
@@ -260,6 +313,21 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.TypedTxBlock, newcfg.TypedTxBlock, head) {
+		return newCompatError("typed transaction fork block", c.TypedTxBlock, newcfg.TypedTxBlock)
+	}
+	if isForkIncompatible(c.FeeDelegationBlock, newcfg.FeeDelegationBlock, head) {
+		return newCompatError("fee delegation fork block", c.FeeDelegationBlock, newcfg.FeeDelegationBlock)
+	}
+	if isForkIncompatible(c.AccessListBlock, newcfg.AccessListBlock, head) {
+		return newCompatError("access list fork block", c.AccessListBlock, newcfg.AccessListBlock)
+	}
+	if isForkIncompatible(c.BaseFeeBlock, newcfg.BaseFeeBlock, head) {
+		return newCompatError("base fee fork block", c.BaseFeeBlock, newcfg.BaseFeeBlock)
+	}
+	if isForkIncompatible(c.EOFBlock, newcfg.EOFBlock, head) {
+		return newCompatError("EVM object format fork block", c.EOFBlock, newcfg.EOFBlock)
+	}
 	return nil
 }
 
@@ -326,7 +394,7 @@ func (err *ConfigCompatError) Error() string {
 type Rules struct {
 	ChainId                                   *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
-	IsByzantium                               bool
+	IsByzantium, IsAccessList, IsEOF          bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -334,5 +402,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainId == nil {
 		chainId = new(big.Int)
 	}
-	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num)}
+	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num), IsAccessList: c.IsAccessList(num), IsEOF: c.IsEOF(num)}
 }
@@ -72,4 +72,19 @@ var (
 
 		CreateBySuicide: 25000,
 	}
+
+	// GasTableAccessList contains the warm access prices of the access list
+	// phase. The first access to an account or slot within a transaction is
+	// charged the cold surcharge on top of these.
+	GasTableAccessList = GasTable{
+		ExtcodeSize: WarmStorageReadCost,
+		ExtcodeCopy: WarmStorageReadCost,
+		Balance:     WarmStorageReadCost,
+		SLoad:       WarmStorageReadCost,
+		Calls:       WarmStorageReadCost,
+		Suicide:     5000,
+		ExpByte:     50,
+
+		CreateBySuicide: 25000,
+	}
 )
@@ -61,6 +61,17 @@ const (
 	MemoryGas        uint64 = 3     // Times the address of the (highest referenced byte in memory + 1). NOTE: referencing happens on read, write and in instructions such as RETURN and CALL.
 	TxDataNonZeroGas uint64 = 68    // Per byte of data attached to a transaction that is not equal to zero. NOTE: Not payable on data of calls between transactions.
 
+	TxAccessListAddressGas    uint64 = 2400 // Per address specified in the access list of a typed transaction.
+	TxAccessListStorageKeyGas uint64 = 1900 // Per storage key specified in the access list of a typed transaction.
+
+	ColdAccountAccessCost uint64 = 2600 // Cost of the first access to an account within a transaction.
+	ColdSloadCost         uint64 = 2100 // Cost of the first read of a storage slot within a transaction.
+	WarmStorageReadCost   uint64 = 100  // Cost of reading an account or storage slot already accessed in the transaction.
+
+	BaseFeeChangeDenominator uint64 = 8          // Bounds the amount the base fee can change between blocks.
+	ElasticityMultiplier     uint64 = 2          // Bounds the maximum gas limit a block may have relative to its gas target.
+	InitialBaseFee           uint64 = 1000000000 // Initial base fee of the base fee fork block.
+
 	MaxCodeSize = 24576 // Maximum bytecode to permit for a contract
 
 	// Precompiled contract gas prices
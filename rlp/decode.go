@@ -64,12 +64,16 @@ type Decoder interface {
 // error if there are too few or too many elements.
 //
 // The decoding of struct fields honours certain struct tags, "tail",
-// "nil" and "-".
+// "optional", "nil" and "-".
 //
 // The "-" tag ignores fields.
 //
 // For an explanation of "tail", see the example.
 //
+// The "optional" tag allows the input list to end before the field. Missing
+// optional fields are set to their zero value. All fields following an
+// optional field must be optional too.
+//
 // The "nil" tag applies to pointer-typed fields and changes the decoding
 // rules for the field such that input values of size zero decode as a nil
 // pointer. This tag can be useful when decoding recursive types.
@@ -422,9 +426,16 @@ func makeStructDecoder(typ reflect.Type) (decoder, error) {
 		if _, err := s.List(); err != nil {
 			return wrapStreamError(err, typ)
 		}
-		for _, f := range fields {
+		for i, f := range fields {
 			err := f.info.decoder(s, val.Field(f.index))
 			if err == EOL {
+				if f.optional {
+					// The field is optional, so reaching the end of the list before
+					// reaching the last field is acceptable. All remaining undecoded
+					// fields are zeroed.
+					zeroFields(val, fields[i:])
+					break
+				}
 				return &decodeError{msg: "too few elements", typ: typ}
 			} else if err != nil {
 				return addErrorContext(err, "."+typ.Field(f.index).Name)
@@ -435,6 +446,13 @@ func makeStructDecoder(typ reflect.Type) (decoder, error) {
 	return dec, nil
 }
 
+func zeroFields(structval reflect.Value, fields []field) {
+	for _, f := range fields {
+		fv := structval.Field(f.index)
+		fv.Set(reflect.Zero(fv.Type()))
+	}
+}
+
 // makePtrDecoder creates a decoder that decodes into
 // the pointer's element type.
 func makePtrDecoder(typ reflect.Type) (decoder, error) {
@@ -347,6 +347,28 @@ type tailUint struct {
 	Tail []uint `rlp:"tail"`
 }
 
+type optionalFields struct {
+	A uint
+	B uint `rlp:"optional"`
+	C uint `rlp:"optional"`
+}
+
+type optionalPtrField struct {
+	A uint
+	B *[3]byte `rlp:"optional"`
+}
+
+type nonOptionalPtrField struct {
+	A uint
+	B *[3]byte
+	C *[3]byte `rlp:"optional"`
+}
+
+type invalidOptional struct {
+	A uint `rlp:"optional"`
+	B uint
+}
+
 var (
 	veryBigInt = big.NewInt(0).Add(
 		big.NewInt(0).Lsh(big.NewInt(0xFFFFFFFFFFFFFF), 16),
@@ -511,6 +533,48 @@ var decodeTests = []decodeTest{
 		value: tailRaw{A: 1, Tail: []RawValue{}},
 	},
 
+	// struct tag "optional"
+	{
+		input: "C101",
+		ptr:   new(optionalFields),
+		value: optionalFields{1, 0, 0},
+	},
+	{
+		input: "C20102",
+		ptr:   new(optionalFields),
+		value: optionalFields{1, 2, 0},
+	},
+	{
+		input: "C3010203",
+		ptr:   new(optionalFields),
+		value: optionalFields{1, 2, 3},
+	},
+	{
+		input: "C401020304",
+		ptr:   new(optionalFields),
+		error: "rlp: input list has too many elements for rlp.optionalFields",
+	},
+	{
+		input: "C101",
+		ptr:   new(optionalPtrField),
+		value: optionalPtrField{A: 1},
+	},
+	{
+		input: "C50183010203",
+		ptr:   new(optionalPtrField),
+		value: optionalPtrField{A: 1, B: &[3]byte{1, 2, 3}},
+	},
+	{
+		input: "C101",
+		ptr:   new(nonOptionalPtrField),
+		error: "rlp: too few elements for rlp.nonOptionalPtrField",
+	},
+	{
+		input: "C101",
+		ptr:   new(invalidOptional),
+		error: `rlp: struct field rlp.invalidOptional.B needs "optional" tag`,
+	},
+
 	// struct tag "-"
 	{
 		input: "C20102",
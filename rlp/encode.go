@@ -531,9 +531,18 @@ func makeStructWriter(typ reflect.Type) (writer, error) {
 	if err != nil {
 		return nil, err
 	}
+	firstOptional := firstOptionalField(fields)
 	writer := func(val reflect.Value, w *encbuf) error {
+		// Trailing optional fields are omitted if they, and all fields after
+		// them, hold the zero value.
+		last := len(fields) - 1
+		for ; last >= firstOptional; last-- {
+			if !isZero(val.Field(fields[last].index)) {
+				break
+			}
+		}
 		lh := w.list()
-		for _, f := range fields {
+		for _, f := range fields[:last+1] {
 			if err := f.info.writer(val.Field(f.index), w); err != nil {
 				return err
 			}
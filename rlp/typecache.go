@@ -41,6 +41,9 @@ type tags struct {
 	// elements. It can only be set for the last field, which must be
 	// of slice type.
 	tail bool
+	// rlp:"optional" allows for a field to be missing in the input list.
+	// If this is set, all subsequent fields must also be optional.
+	optional bool
 	// rlp:"-" ignores fields.
 	ignored bool
 }
@@ -91,11 +94,13 @@ func cachedTypeInfo1(typ reflect.Type, tags tags) (*typeinfo, error) {
 }
 
 type field struct {
-	index int
-	info  *typeinfo
+	index    int
+	info     *typeinfo
+	optional bool
 }
 
 func structFields(typ reflect.Type) (fields []field, err error) {
+	var anyOptional bool
 	for i := 0; i < typ.NumField(); i++ {
 		if f := typ.Field(i); f.PkgPath == "" { // exported
 			tags, err := parseStructTag(typ, i)
@@ -105,16 +110,32 @@ func structFields(typ reflect.Type) (fields []field, err error) {
 			if tags.ignored {
 				continue
 			}
+			// Once a field is optional, all subsequent ones must be too
+			if tags.optional || tags.tail {
+				anyOptional = true
+			} else if anyOptional {
+				return nil, fmt.Errorf(`rlp: struct field %v.%s needs "optional" tag`, typ, f.Name)
+			}
 			info, err := cachedTypeInfo1(f.Type, tags)
 			if err != nil {
 				return nil, err
 			}
-			fields = append(fields, field{i, info})
+			fields = append(fields, field{i, info, tags.optional})
 		}
 	}
 	return fields, nil
 }
 
+// firstOptionalField returns the index of the first field with "optional" tag.
+func firstOptionalField(fields []field) int {
+	for i, f := range fields {
+		if f.optional {
+			return i
+		}
+	}
+	return len(fields)
+}
+
 func parseStructTag(typ reflect.Type, fi int) (tags, error) {
 	f := typ.Field(fi)
 	var ts tags
@@ -125,8 +146,16 @@ func parseStructTag(typ reflect.Type, fi int) (tags, error) {
 			ts.ignored = true
 		case "nil":
 			ts.nilOK = true
+		case "optional":
+			ts.optional = true
+			if ts.tail {
+				return ts, fmt.Errorf(`rlp: invalid struct tag "optional" for %v.%s (also has "tail" tag)`, typ, f.Name)
+			}
 		case "tail":
 			ts.tail = true
+			if ts.optional {
+				return ts, fmt.Errorf(`rlp: invalid struct tag "tail" for %v.%s (also has "optional" tag)`, typ, f.Name)
+			}
 			if fi != typ.NumField()-1 {
 				return ts, fmt.Errorf(`rlp: invalid struct tag "tail" for %v.%s (must be on last field)`, typ, f.Name)
 			}
@@ -154,3 +183,12 @@ func genTypeInfo(typ reflect.Type, tags tags) (info *typeinfo, err error) {
 func isUint(k reflect.Kind) bool {
 	return k >= reflect.Uint && k <= reflect.Uintptr
 }
+
+// isZero reports whaaeer v holds the zero value of its type.
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
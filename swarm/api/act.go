@@ -0,0 +1,393 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/common/hexutil"
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+Access controlled roots are manifests with a single entry holding the
+reference of the protected content encrypted with a key only the grantees
+can derive. The access entry describes how the key is derived:
+
+	pass: key = scrypt(password, salt)
+	pk:   key = keccak256(ECDH(publisher, grantee) || salt)
+	act:  key = random access key, stored once per grantee in the access
+	      control trie (ACT), a manifest mapping the lookup key of each
+	      grantee to the access key encrypted for it
+
+The session key of an ACT grantee is derived as the key of the pass or pk
+types, its entry is found at hex(keccak256(session key || 0)) and holds the
+access key XORed with keccak256(session key || 1). The publisher is always
+granted access to the ACT so it can update the list of grantees.
+*/
+
+type AccessType string
+
+const (
+	AccessTypePass = AccessType("pass")
+	AccessTypePK   = AccessType("pk")
+	AccessTypeACT  = AccessType("act")
+)
+
+var (
+	ErrNoCredentials = errors.New("credentials required")
+	ErrAccessDenied  = errors.New("access denied")
+)
+
+// AccessEntry describes how the reference of an access controlled root is
+// encrypted
+type AccessEntry struct {
+	Type      AccessType    `json:"type"`
+	Publisher string        `json:"publisher,omitempty"`
+	Salt      hexutil.Bytes `json:"salt"`
+	Act       string        `json:"act,omitempty"`
+	KdfParams *KdfParams    `json:"kdf_params,omitempty"`
+}
+
+// KdfParams are the scrypt parameters used to derive keys from passwords
+type KdfParams struct {
+	N int `json:"n"`
+	P int `json:"p"`
+	R int `json:"r"`
+}
+
+var DefaultKdfParams = KdfParams{
+	N: 262144,
+	P: 1,
+	R: 8,
+}
+
+// AccessRequest lists the grantees of an access controlled root
+type AccessRequest struct {
+	Type     AccessType      `json:"type"`
+	Password string          `json:"password,omitempty"`
+	Grantees []hexutil.Bytes `json:"grantees,omitempty"` // public keys
+}
+
+// NewAccess stores an access controlled root wrapping the given reference
+func (self *Api) NewAccess(ref storage.Key, req *AccessRequest) (storage.Key, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	entry := &AccessEntry{
+		Type: req.Type,
+		Salt: salt,
+	}
+
+	var key []byte
+	switch req.Type {
+	case AccessTypePass:
+		if req.Password == "" {
+			return nil, fmt.Errorf("missing password")
+		}
+		kdf := DefaultKdfParams
+		entry.KdfParams = &kdf
+		sessionKey, err := passwordSessionKey(req.Password, salt, &kdf)
+		if err != nil {
+			return nil, err
+		}
+		key = sessionKey
+
+	case AccessTypePK:
+		if len(req.Grantees) != 1 {
+			return nil, fmt.Errorf("expected a single grantee, got %d", len(req.Grantees))
+		}
+		if self.privateKey == nil {
+			return nil, fmt.Errorf("no publisher key")
+		}
+		grantee := crypto.ToECDSAPub(req.Grantees[0])
+		if grantee == nil {
+			return nil, fmt.Errorf("invalid grantee public key")
+		}
+		entry.Publisher = hex.EncodeToString(crypto.FromECDSAPub(&self.privateKey.PublicKey))
+		key = ecdhSessionKey(self.privateKey, grantee, salt)
+
+	case AccessTypeACT:
+		accessKey, err := storage.NewEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := self.storeAct(entry, accessKey, req); err != nil {
+			return nil, err
+		}
+		key = accessKey
+
+	default:
+		return nil, fmt.Errorf("unknown access type %q", req.Type)
+	}
+
+	return self.storeAccessRoot(entry, xorRef(key, ref))
+}
+
+// UpdateAccess replaces the grantees of an ACT root published by this node,
+// returning the new root. The reference is encrypted with a fresh access key
+// granted to the listed grantees only, so removed grantees can't resolve the
+// new root. They can still read the content they resolved before.
+func (self *Api) UpdateAccess(root storage.Key, req *AccessRequest) (storage.Key, error) {
+	entry, encRef, err := self.accessEntry(root)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.Type != AccessTypeACT {
+		return nil, fmt.Errorf("%s is not an ACT root", root)
+	}
+	if self.privateKey == nil || entry.Publisher != hex.EncodeToString(crypto.FromECDSAPub(&self.privateKey.PublicKey)) {
+		return nil, fmt.Errorf("%s is not published by this node", root)
+	}
+	oldKey, err := self.actAccessKey(entry, "")
+	if err != nil {
+		return nil, err
+	}
+	ref := xorRef(oldKey, encRef)
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	updated := &AccessEntry{
+		Type: AccessTypeACT,
+		Salt: salt,
+	}
+	accessKey, err := storage.NewEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := self.storeAct(updated, accessKey, req); err != nil {
+		return nil, err
+	}
+	return self.storeAccessRoot(updated, xorRef(accessKey, ref))
+}
+
+// ResolveAccess returns the reference protected by an access controlled
+// root, using the node key and, if not empty, the password. Keys of other
+// content are returned as they are.
+func (self *Api) ResolveAccess(key storage.Key, password string) (storage.Key, error) {
+	entry, encRef, err := self.accessEntry(key)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return key, nil
+	}
+
+	switch entry.Type {
+	case AccessTypePass:
+		if password == "" {
+			return nil, ErrNoCredentials
+		}
+		sessionKey, err := passwordSessionKey(password, entry.Salt, entry.KdfParams)
+		if err != nil {
+			return nil, err
+		}
+		return xorRef(sessionKey, encRef), nil
+
+	case AccessTypePK:
+		publisher, err := entry.publisherKey()
+		if err != nil {
+			return nil, err
+		}
+		if self.privateKey == nil {
+			return nil, ErrAccessDenied
+		}
+		return xorRef(ecdhSessionKey(self.privateKey, publisher, entry.Salt), encRef), nil
+
+	case AccessTypeACT:
+		accessKey, err := self.actAccessKey(entry, password)
+		if err != nil {
+			return nil, err
+		}
+		return xorRef(accessKey, encRef), nil
+	}
+	return nil, fmt.Errorf("unknown access type %q", entry.Type)
+}
+
+// accessEntry returns the access entry and the encrypted reference of an
+// access controlled root, or a nil entry if the key refers to other content
+func (self *Api) accessEntry(key storage.Key) (*AccessEntry, []byte, error) {
+	// access controlled roots are small, do not read large content
+	reader := self.dpa.Retrieve(key)
+	size, err := reader.Size(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if size > storage.DefaultBranches*int64(len(key)) {
+		return nil, nil, nil
+	}
+	data := make([]byte, size)
+	if n, err := reader.ReadAt(data, 0); int64(n) < size {
+		return nil, nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, nil
+	}
+	if len(manifest.Entries) != 1 || manifest.Entries[0].Access == nil {
+		return nil, nil, nil
+	}
+	return manifest.Entries[0].Access, common.Hex2Bytes(manifest.Entries[0].Hash), nil
+}
+
+func (self *Api) storeAccessRoot(entry *AccessEntry, encRef []byte) (storage.Key, error) {
+	trie := &manifestTrie{dpa: self.dpa}
+	trie.addEntry(newManifestTrieEntry(&ManifestEntry{
+		Hash:        hex.EncodeToString(encRef),
+		ContentType: ManifestType,
+		Access:      entry,
+	}, nil), nil)
+	if err := trie.recalcAndStore(); err != nil {
+		return nil, err
+	}
+	return trie.hash, nil
+}
+
+// storeAct stores the ACT granting the access key to the requested grantees
+// and the publisher, and records it in the access entry
+func (self *Api) storeAct(entry *AccessEntry, accessKey []byte, req *AccessRequest) error {
+	if self.privateKey == nil {
+		return fmt.Errorf("no publisher key")
+	}
+	entry.Publisher = hex.EncodeToString(crypto.FromECDSAPub(&self.privateKey.PublicKey))
+
+	var sessionKeys [][]byte
+	for _, pub := range append([]hexutil.Bytes{crypto.FromECDSAPub(&self.privateKey.PublicKey)}, req.Grantees...) {
+		grantee := crypto.ToECDSAPub(pub)
+		if grantee == nil {
+			return fmt.Errorf("invalid grantee public key %s", pub)
+		}
+		sessionKeys = append(sessionKeys, ecdhSessionKey(self.privateKey, grantee, entry.Salt))
+	}
+	if req.Password != "" {
+		kdf := DefaultKdfParams
+		entry.KdfParams = &kdf
+		sessionKey, err := passwordSessionKey(req.Password, entry.Salt, &kdf)
+		if err != nil {
+			return err
+		}
+		sessionKeys = append(sessionKeys, sessionKey)
+	}
+
+	act := &manifestTrie{dpa: self.dpa}
+	for _, sessionKey := range sessionKeys {
+		lookupKey, keyKey := actKeys(sessionKey)
+		act.addEntry(newManifestTrieEntry(&ManifestEntry{
+			Path: hex.EncodeToString(lookupKey),
+			Hash: hex.EncodeToString(xorRef(keyKey, accessKey)),
+		}, nil), nil)
+	}
+	if err := act.recalcAndStore(); err != nil {
+		return err
+	}
+	entry.Act = act.hash.String()
+	return nil
+}
+
+// actAccessKey looks up the access key in the ACT with the session key of
+// the node and then with the one derived from the password
+func (self *Api) actAccessKey(entry *AccessEntry, password string) ([]byte, error) {
+	var sessionKeys [][]byte
+	if self.privateKey != nil {
+		publisher, err := entry.publisherKey()
+		if err != nil {
+			return nil, err
+		}
+		sessionKeys = append(sessionKeys, ecdhSessionKey(self.privateKey, publisher, entry.Salt))
+	}
+	if password != "" && entry.KdfParams != nil {
+		sessionKey, err := passwordSessionKey(password, entry.Salt, entry.KdfParams)
+		if err != nil {
+			return nil, err
+		}
+		sessionKeys = append(sessionKeys, sessionKey)
+	}
+
+	trie, err := loadManifest(self.dpa, common.Hex2Bytes(entry.Act), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error loading ACT %s: %v", entry.Act, err)
+	}
+	for _, sessionKey := range sessionKeys {
+		lookupKey, keyKey := actKeys(sessionKey)
+		path := hex.EncodeToString(lookupKey)
+		if e, fullpath := trie.getEntry(path); e != nil && fullpath == path {
+			return xorRef(keyKey, common.Hex2Bytes(e.Hash)), nil
+		}
+	}
+	if password == "" {
+		return nil, ErrNoCredentials
+	}
+	return nil, ErrAccessDenied
+}
+
+func (self *AccessEntry) publisherKey() (*ecdsa.PublicKey, error) {
+	publisher := crypto.ToECDSAPub(common.FromHex(self.Publisher))
+	if publisher == nil {
+		return nil, fmt.Errorf("invalid publisher key %q", self.Publisher)
+	}
+	return publisher, nil
+}
+
+// ecdhSessionKey derives the session key shared by the owner of the private
+// key and the owner of the public key
+func ecdhSessionKey(prvKey *ecdsa.PrivateKey, pubKey *ecdsa.PublicKey, salt []byte) []byte {
+	x, _ := crypto.S256().ScalarMult(pubKey.X, pubKey.Y, prvKey.D.Bytes())
+	return crypto.Keccak256(common.LeftPadBytes(x.Bytes(), 32), salt)
+}
+
+// passwordSessionKey derives the session key from the password, the kdf
+// params come from the manifest and only the defaults are accepted so that
+// a published root can't make resolving it arbitrarily expensive
+func passwordSessionKey(password string, salt []byte, params *KdfParams) ([]byte, error) {
+	if params == nil {
+		return nil, fmt.Errorf("missing kdf params")
+	}
+	if *params != DefaultKdfParams {
+		return nil, fmt.Errorf("unsupported kdf params n=%d r=%d p=%d", params.N, params.R, params.P)
+	}
+	return scrypt.Key([]byte(password), salt, params.N, params.R, params.P, 32)
+}
+
+// actKeys returns the lookup key and the key decrypting the access key of a
+// grantee in the ACT
+func actKeys(sessionKey []byte) (lookupKey, keyKey []byte) {
+	return crypto.Keccak256(sessionKey, []byte{0}), crypto.Keccak256(sessionKey, []byte{1})
+}
+
+// xorRef encrypts or decrypts a reference with a keystream built from the key
+func xorRef(key, ref []byte) []byte {
+	out := make([]byte, len(ref))
+	var segment []byte
+	for i := range ref {
+		if i%32 == 0 {
+			segment = crypto.Keccak256(key, []byte{byte(i / 32)})
+		}
+		out[i] = ref[i] ^ segment[i%32]
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aaechain/go-aaechain/common/hexutil"
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+)
+
+// lightKdfParams keep the password derivation fast in tests
+var lightKdfParams = KdfParams{N: 1 << 10, P: 1, R: 8}
+
+// testAccessApis returns the apis of a publisher, a grantee and an outsider
+// node sharing the same store
+func testAccessApis(t *testing.T, api *Api) (publisher, grantee, outsider *Api) {
+	newApi := func() *Api {
+		prvKey, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := NewApi(api.dpa, nil)
+		a.SetPrivateKey(prvKey)
+		return a
+	}
+	return newApi(), newApi(), newApi()
+}
+
+func testAccessRef(t *testing.T, api *Api) storage.Key {
+	content := "hello access"
+	ref, err := api.Put(content, "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestAccessPass(t *testing.T) {
+	defer func(params KdfParams) { DefaultKdfParams = params }(DefaultKdfParams)
+	DefaultKdfParams = lightKdfParams
+
+	testApi(t, func(api *Api) {
+		ref := testAccessRef(t, api)
+		root, err := api.NewAccess(ref, &AccessRequest{Type: AccessTypePass, Password: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.ResolveAccess(root, ""); err != ErrNoCredentials {
+			t.Fatalf("expected %v, got %v", ErrNoCredentials, err)
+		}
+		resolved, err := api.ResolveAccess(root, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(resolved, ref) {
+			t.Fatalf("expected %s, got %s", ref, resolved)
+		}
+		resolved, err = api.ResolveAccess(root, "wrong")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(resolved, ref) {
+			t.Fatal("resolved reference with the wrong password")
+		}
+
+		// content without access control resolves to itself
+		resolved, err = api.ResolveAccess(ref, "")
+		if err != nil || !bytes.Equal(resolved, ref) {
+			t.Fatalf("expected %s, got %s (%v)", ref, resolved, err)
+		}
+
+		// kdf params other than the defaults are refused
+		costly, err := api.storeAccessRoot(&AccessEntry{
+			Type:      AccessTypePass,
+			Salt:      make([]byte, 32),
+			KdfParams: &KdfParams{N: 1 << 30, P: 1, R: 8},
+		}, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.ResolveAccess(costly, "secret"); err == nil || !strings.Contains(err.Error(), "unsupported kdf params") {
+			t.Fatalf("expected unsupported kdf params error, got %v", err)
+		}
+
+		// keys which can't be retrieved are not returned unchecked
+		if _, err := api.ResolveAccess(storage.ZeroKey, ""); err == nil {
+			t.Fatal("expected error resolving missing content")
+		}
+	})
+}
+
+func TestAccessPK(t *testing.T) {
+	testApi(t, func(api *Api) {
+		publisher, grantee, outsider := testAccessApis(t, api)
+		ref := testAccessRef(t, api)
+		root, err := publisher.NewAccess(ref, &AccessRequest{
+			Type:     AccessTypePK,
+			Grantees: []hexutil.Bytes{crypto.FromECDSAPub(&grantee.privateKey.PublicKey)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolved, err := grantee.ResolveAccess(root, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(resolved, ref) {
+			t.Fatalf("expected %s, got %s", ref, resolved)
+		}
+		if resolved, _ := outsider.ResolveAccess(root, ""); bytes.Equal(resolved, ref) {
+			t.Fatal("outsider resolved the reference")
+		}
+	})
+}
+
+func TestAccessACT(t *testing.T) {
+	defer func(params KdfParams) { DefaultKdfParams = params }(DefaultKdfParams)
+	DefaultKdfParams = lightKdfParams
+
+	testApi(t, func(api *Api) {
+		publisher, grantee, outsider := testAccessApis(t, api)
+		ref := testAccessRef(t, api)
+		root, err := publisher.NewAccess(ref, &AccessRequest{
+			Type:     AccessTypeACT,
+			Password: "secret",
+			Grantees: []hexutil.Bytes{crypto.FromECDSAPub(&grantee.privateKey.PublicKey)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		check := func(a *Api, root storage.Key, password string, expErr error) {
+			resolved, err := a.ResolveAccess(root, password)
+			if err != expErr {
+				t.Fatalf("expected error %v, got %v", expErr, err)
+			}
+			if err == nil && !bytes.Equal(resolved, ref) {
+				t.Fatalf("expected %s, got %s", ref, resolved)
+			}
+		}
+		check(publisher, root, "", nil)
+		check(grantee, root, "", nil)
+		check(outsider, root, "", ErrNoCredentials)
+		check(outsider, root, "secret", nil)
+		check(outsider, root, "wrong", ErrAccessDenied)
+
+		// only the publisher can update the grantees
+		req := &AccessRequest{
+			Type:     AccessTypeACT,
+			Grantees: []hexutil.Bytes{crypto.FromECDSAPub(&outsider.privateKey.PublicKey)},
+		}
+		if _, err := grantee.UpdateAccess(root, req); err == nil || !strings.Contains(err.Error(), "not published") {
+			t.Fatalf("expected update by grantee to fail, got %v", err)
+		}
+		updated, err := publisher.UpdateAccess(root, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(publisher, updated, "", nil)
+		check(outsider, updated, "", nil)
+		check(grantee, updated, "", ErrNoCredentials)
+		check(grantee, updated, "secret", ErrAccessDenied)
+
+		// the access key of the removed grantee doesn't decrypt the new root
+		entry, _, err := grantee.accessEntry(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldKey, err := grantee.actAccessKey(entry, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, encRef, err := grantee.accessEntry(updated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(xorRef(oldKey, encRef), ref) {
+			t.Fatal("revoked access key decrypts the updated root")
+		}
+
+		if _, err := publisher.UpdateAccess(ref, req); err == nil {
+			t.Fatal("expected updating content without access control to fail")
+		}
+	})
+}
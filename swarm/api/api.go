@@ -17,6 +17,7 @@
 package api
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"io"
 	"net/http"
@@ -142,19 +143,28 @@ on top of the dpa
 it is the public interface of the dpa which is included in the aaeereum stack
 */
 type Api struct {
-	dpa *storage.DPA
-	dns Resolver
+	dpa        *storage.DPA
+	dns        Resolver
+	feeds      *storage.FeedHandler
+	privateKey *ecdsa.PrivateKey // node key used for access control
 }
 
 //the api constructor initialises
 func NewApi(dpa *storage.DPA, dns Resolver) (self *Api) {
 	self = &Api{
-		dpa: dpa,
-		dns: dns,
+		dpa:   dpa,
+		dns:   dns,
+		feeds: storage.NewFeedHandler(dpa),
 	}
 	return
 }
 
+// SetPrivateKey sets the key access controlled content is published with
+// and decrypted for
+func (self *Api) SetPrivateKey(prvKey *ecdsa.PrivateKey) {
+	self.privateKey = prvKey
+}
+
 // to be used only in TEST
 func (self *Api) Upload(uploadDir, index string) (hash string, err error) {
 	fs := NewFileSystem(self)
@@ -171,6 +181,65 @@ func (self *Api) Store(data io.Reader, size int64, wg *sync.WaitGroup) (key stor
 	return self.dpa.Store(data, size, wg, nil)
 }
 
+// StoreEncrypted stores the data encrypted, returning a reference which
+// includes the decryption key
+func (self *Api) StoreEncrypted(data io.Reader, size int64, wg *sync.WaitGroup) (key storage.Key, err error) {
+	return self.dpa.StoreEncrypted(data, size, wg, nil)
+}
+
+// StoreRedundant stores the data with the given level of erasure coded
+// redundancy, encrypted if encrypt is set
+func (self *Api) StoreRedundant(data io.Reader, size int64, level int, encrypt bool, wg *sync.WaitGroup) (key storage.Key, err error) {
+	return self.dpa.StoreRedundant(data, size, level, encrypt, wg, nil)
+}
+
+// UpdateFeed publishes a signed feed update, returning its address
+func (self *Api) UpdateFeed(update *storage.FeedUpdate) (storage.Key, error) {
+	return self.feeds.Update(update)
+}
+
+// LookupFeed retrieves the update of the feed for the given epoch, or the
+// latest update if epoch is 0
+func (self *Api) LookupFeed(feed *storage.Feed, epoch uint64) (*storage.FeedUpdate, error) {
+	if epoch == 0 {
+		return self.feeds.LookupLatest(feed)
+	}
+	return self.feeds.Lookup(feed, epoch)
+}
+
+// Pin protects the content under key from garbage collection in the local
+// store. Unless raw is set, key refers to a manifest and the manifest itself,
+// its submanifests and all the content they refer to are pinned.
+func (self *Api) Pin(key storage.Key, raw bool) (*storage.PinnedRoot, error) {
+	content := []storage.Key{key}
+	if !raw {
+		walker, err := self.NewManifestWalker(key, nil)
+		if err != nil {
+			return nil, err
+		}
+		err = walker.Walk(func(entry *ManifestEntry) error {
+			if entry.Hash != "" {
+				content = append(content, common.Hex2Bytes(entry.Hash))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return self.dpa.Pin(key, content)
+}
+
+// Unpin releases the content pinned under key
+func (self *Api) Unpin(key storage.Key) error {
+	return self.dpa.Unpin(key)
+}
+
+// PinnedRoots lists the pinned content roots
+func (self *Api) PinnedRoots() ([]*storage.PinnedRoot, error) {
+	return self.dpa.PinnedRoots()
+}
+
 type ErrResolve error
 
 // DNS Resolver
@@ -247,6 +316,25 @@ func (self *Api) Get(key storage.Key, path string) (reader storage.LazySectionRe
 
 	if entry != nil {
 		key = common.Hex2Bytes(entry.Hash)
+		if entry.Feed != nil {
+			// the entry refers to the content the latest feed update points to
+			var update *storage.FeedUpdate
+			update, err = self.feeds.LookupLatest(entry.Feed)
+			if err != nil {
+				apiGetNotFound.Inc(1)
+				status = http.StatusNotFound
+				log.Warn(fmt.Sprintf("feed lookup error: %v", err))
+				return
+			}
+			if len(update.Data) != common.HashLength {
+				apiGetNotFound.Inc(1)
+				status = http.StatusNotFound
+				err = fmt.Errorf("invalid feed reference %x", update.Data)
+				log.Warn(fmt.Sprintf("feed lookup error: %v", err))
+				return
+			}
+			key = storage.Key(update.Data)
+		}
 		status = entry.Status
 		if status == http.StatusMultipleChoices {
 			apiGetHttp300.Inc(1)
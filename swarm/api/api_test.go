@@ -22,9 +22,11 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/crypto"
 	"github.com/aaechain/go-aaechain/log"
 	"github.com/aaechain/go-aaechain/swarm/storage"
 )
@@ -119,6 +121,46 @@ func TestApiPut(t *testing.T) {
 	})
 }
 
+// TestApiFeedManifest tests manifest entries resolving through a feed follow
+// the feed's updates
+func TestApiFeedManifest(t *testing.T) {
+	testApi(t, func(api *Api) {
+		prvkey, _ := crypto.GenerateKey()
+		feed := &storage.Feed{
+			Topic: crypto.Keccak256Hash([]byte("index")),
+			User:  crypto.PubkeyToAddress(prvkey.PublicKey),
+		}
+		mkey, err := api.NewManifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mw, err := api.NewManifestWriter(mkey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mw.AddFeedEntry(&ManifestEntry{Path: "index.html", ContentType: "text/html"}, feed)
+		if mkey, err = mw.Store(); err != nil {
+			t.Fatal(err)
+		}
+
+		for epoch, content := range []string{"hello", "hello world"} {
+			key, err := api.Store(strings.NewReader(content), int64(len(content)), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			update := &storage.FeedUpdate{Feed: *feed, Epoch: uint64(epoch + 1), Data: []byte(key)}
+			if err := update.Sign(prvkey); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := api.UpdateFeed(update); err != nil {
+				t.Fatal(err)
+			}
+			resp := testGet(t, api, mkey.String(), "index.html")
+			checkResponse(t, resp, expResponse(content, "text/html", 0))
+		}
+	})
+}
+
 // testResolver implements the Resolver interface and either returns the given
 // hash if it is set, or returns a "name not found" error
 type testResolver struct {
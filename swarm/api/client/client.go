@@ -28,12 +28,14 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/aaechain/go-aaechain/swarm/api"
+	"github.com/aaechain/go-aaechain/swarm/storage"
 )
 
 var (
@@ -49,15 +51,26 @@ func NewClient(gateway string) *Client {
 
 // Client wraps interaction with a swarm HTTP gateway.
 type Client struct {
-	Gateway string
+	Gateway    string
+	Redundancy int // level of erasure coded redundancy uploads are stored with
 }
 
 // UploadRaw uploads raw data to swarm and returns the resulting hash
 func (c *Client) UploadRaw(r io.Reader, size int64) (string, error) {
+	return c.uploadRaw("bzz-raw", r, size)
+}
+
+// UploadEncrypted uploads raw data to swarm with its chunks encrypted and
+// returns the resulting reference, which includes the decryption key
+func (c *Client) UploadEncrypted(r io.Reader, size int64) (string, error) {
+	return c.uploadRaw("bzz-encrypted", r, size)
+}
+
+func (c *Client) uploadRaw(scheme string, r io.Reader, size int64) (string, error) {
 	if size <= 0 {
 		return "", errors.New("data size must be greater than zero")
 	}
-	req, err := http.NewRequest("POST", c.Gateway+"/bzz-raw:/", r)
+	req, err := http.NewRequest("POST", c.Gateway+"/"+scheme+":/"+c.uploadQuery(), r)
 	if err != nil {
 		return "", err
 	}
@@ -77,9 +90,27 @@ func (c *Client) UploadRaw(r io.Reader, size int64) (string, error) {
 	return string(data), nil
 }
 
+// uploadQuery returns the query string of upload requests
+func (c *Client) uploadQuery() string {
+	if c.Redundancy > 0 {
+		return "?redundancy=" + strconv.Itoa(c.Redundancy)
+	}
+	return ""
+}
+
 // DownloadRaw downloads raw data from swarm
 func (c *Client) DownloadRaw(hash string) (io.ReadCloser, error) {
-	uri := c.Gateway + "/bzz-raw:/" + hash
+	return c.downloadRaw("bzz-raw", hash)
+}
+
+// DownloadEncrypted downloads and decrypts raw data uploaded with
+// UploadEncrypted from swarm
+func (c *Client) DownloadEncrypted(ref string) (io.ReadCloser, error) {
+	return c.downloadRaw("bzz-encrypted", ref)
+}
+
+func (c *Client) downloadRaw(scheme, hash string) (io.ReadCloser, error) {
+	uri := c.Gateway + "/" + scheme + ":/" + hash
 	res, err := http.DefaultClient.Get(uri)
 	if err != nil {
 		return nil, err
@@ -284,6 +315,183 @@ func (c *Client) List(hash, prefix string) (*api.ManifestList, error) {
 	return &list, nil
 }
 
+// Diff returns the paths which were added, removed or modified in the
+// manifest with hash newHash compared to the one with hash oldHash
+func (c *Client) Diff(oldHash, newHash string) (*api.ManifestDiff, error) {
+	res, err := http.DefaultClient.Get(c.Gateway + "/bzz-diff:/" + oldHash + "/" + newHash)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	var diff api.ManifestDiff
+	if err := json.NewDecoder(res.Body).Decode(&diff); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// UpdateFeed posts a signed feed update to swarm and returns the resulting hash
+func (c *Client) UpdateFeed(update *storage.FeedUpdate) (string, error) {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return "", err
+	}
+	res, err := http.DefaultClient.Post(c.feedURL(&update.Feed), "application/json", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	hash, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CreateFeedManifest creates a manifest resolving to the content the latest
+// update of the given feed points to and returns the manifest hash
+func (c *Client) CreateFeedManifest(feed *storage.Feed, contentType string) (string, error) {
+	uri := c.feedURL(feed) + "?manifest=true"
+	if contentType != "" {
+		uri += "&content_type=" + url.QueryEscape(contentType)
+	}
+	res, err := http.DefaultClient.Post(uri, "", nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	hash, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// LookupFeed retrieves the update of a feed for the given epoch, or the
+// latest update if epoch is 0
+func (c *Client) LookupFeed(feed *storage.Feed, epoch uint64) (*storage.FeedUpdate, error) {
+	uri := c.feedURL(feed)
+	if epoch != 0 {
+		uri += "?epoch=" + strconv.FormatUint(epoch, 10)
+	}
+	res, err := http.DefaultClient.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	var update storage.FeedUpdate
+	if err := json.NewDecoder(res.Body).Decode(&update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+func (c *Client) feedURL(feed *storage.Feed) string {
+	return fmt.Sprintf("%s/bzz-feed:/%x/%x", c.Gateway, feed.User, feed.Topic)
+}
+
+// Pin pins the manifest with the given hash and all the content it refers to
+// (or only the raw content with the given hash if raw is set) on the swarm
+// node, protecting it from garbage collection
+func (c *Client) Pin(hash string, raw bool) (*storage.PinnedRoot, error) {
+	uri := c.Gateway + "/bzz-pin:/" + hash
+	if raw {
+		uri += "?raw=true"
+	}
+	res, err := http.DefaultClient.Post(uri, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	var root storage.PinnedRoot
+	if err := json.NewDecoder(res.Body).Decode(&root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// Unpin releases the content pinned under the given hash
+func (c *Client) Unpin(hash string) error {
+	req, err := http.NewRequest("DELETE", c.Gateway+"/bzz-pin:/"+hash, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	return nil
+}
+
+// PinnedRoots lists the content roots pinned on the swarm node together with
+// the number and total size of their chunks
+func (c *Client) PinnedRoots() ([]*storage.PinnedRoot, error) {
+	res, err := http.DefaultClient.Get(c.Gateway + "/bzz-pin:/")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	var roots []*storage.PinnedRoot
+	if err := json.NewDecoder(res.Body).Decode(&roots); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// NewAccess creates an access controlled root wrapping the content with the
+// given hash and returns the hash of the root
+func (c *Client) NewAccess(hash string, req *api.AccessRequest) (string, error) {
+	return c.postAccess(c.Gateway+"/bzz-access:/"+hash, req)
+}
+
+// UpdateAccess replaces the grantees of the ACT root with the given hash and
+// returns the hash of the updated root
+func (c *Client) UpdateAccess(root string, req *api.AccessRequest) (string, error) {
+	return c.postAccess(c.Gateway+"/bzz-access:/"+root+"?update=true", req)
+}
+
+func (c *Client) postAccess(uri string, req *api.AccessRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	res, err := http.DefaultClient.Post(uri, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	hash, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
 // Uploader uploads files to swarm using a provided UploadFn
 type Uploader interface {
 	Upload(UploadFn) error
@@ -353,7 +561,7 @@ type UploadFn func(file *File) error
 func (c *Client) TarUpload(hash string, uploader Uploader) (string, error) {
 	reqR, reqW := io.Pipe()
 	defer reqR.Close()
-	req, err := http.NewRequest("POST", c.Gateway+"/bzz:/"+hash, reqR)
+	req, err := http.NewRequest("POST", c.Gateway+"/bzz:/"+hash+c.uploadQuery(), reqR)
 	if err != nil {
 		return "", err
 	}
@@ -413,7 +621,7 @@ func (c *Client) TarUpload(hash string, uploader Uploader) (string, error) {
 func (c *Client) MultipartUpload(hash string, uploader Uploader) (string, error) {
 	reqR, reqW := io.Pipe()
 	defer reqR.Close()
-	req, err := http.NewRequest("POST", c.Gateway+"/bzz:/"+hash, reqR)
+	req, err := http.NewRequest("POST", c.Gateway+"/bzz:/"+hash+c.uploadQuery(), reqR)
 	if err != nil {
 		return "", err
 	}
@@ -18,14 +18,20 @@ package client
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/crypto"
 	"github.com/aaechain/go-aaechain/swarm/api"
+	"github.com/aaechain/go-aaechain/swarm/storage"
 	"github.com/aaechain/go-aaechain/swarm/testutil"
 )
 
@@ -58,6 +64,222 @@ func TestClientUploadDownloadRaw(t *testing.T) {
 	}
 }
 
+// TestClientUploadDownloadEncrypted tests uploading and downloading encrypted
+// raw data to swarm
+func TestClientUploadDownloadEncrypted(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	// upload some raw data encrypted, the reference includes the key
+	data := []byte("foo123")
+	ref, err := client.UploadEncrypted(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ref) != 128 {
+		t.Fatalf("expected a 128 character reference, got %q", ref)
+	}
+
+	// check we can download the same data
+	res, err := client.DownloadEncrypted(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	gotData, err := ioutil.ReadAll(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotData, data) {
+		t.Fatalf("expected downloaded data to be %q, got %q", data, gotData)
+	}
+
+	// check the stored chunk isn't readable without the key
+	res, err = client.DownloadRaw(ref[:64])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if gotData, _ = ioutil.ReadAll(res); bytes.Equal(gotData, data) {
+		t.Fatal("expected encrypted chunk to differ from the uploaded data")
+	}
+}
+
+// TestClientUploadDownloadRedundant tests uploading and downloading raw data
+// and files stored with erasure coded redundancy
+func TestClientUploadDownloadRedundant(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	client.Redundancy = 2
+
+	// upload raw data spanning several chunks and check we can download it
+	data := make([]byte, 3*4096+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	hash, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.DownloadRaw(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	gotData, err := ioutil.ReadAll(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotData, data) {
+		t.Fatal("downloaded data differs from the uploaded data")
+	}
+
+	// upload a file and check we can download it
+	file := &File{
+		ReadCloser: ioutil.NopCloser(bytes.NewReader(data)),
+		ManifestEntry: api.ManifestEntry{
+			Path:        "foo.bin",
+			ContentType: "application/octet-stream",
+			Size:        int64(len(data)),
+		},
+	}
+	hash, err = client.Upload(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotFile, err := client.Download(hash, "foo.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gotFile.Close()
+	if gotData, err = ioutil.ReadAll(gotFile); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotData, data) {
+		t.Fatal("downloaded file differs from the uploaded file")
+	}
+
+	// check an invalid redundancy level is rejected
+	client.Redundancy = 100
+	if _, err := client.UploadRaw(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Fatal("expected upload with an invalid redundancy level to fail")
+	}
+}
+
+// TestClientFeed tests publishing and looking up feed updates
+func TestClientFeed(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	prvkey, _ := crypto.GenerateKey()
+	feed := &storage.Feed{
+		Topic: crypto.Keccak256Hash([]byte("foo")),
+		User:  crypto.PubkeyToAddress(prvkey.PublicKey),
+	}
+	if _, err := client.LookupFeed(feed, 0); err == nil {
+		t.Fatal("expected an error looking up an empty feed")
+	}
+	for epoch := uint64(1); epoch <= 3; epoch++ {
+		update := &storage.FeedUpdate{Feed: *feed, Epoch: epoch, Data: []byte(fmt.Sprintf("foo%d", epoch))}
+		if err := update.Sign(prvkey); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := client.UpdateFeed(update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != feed.UpdateKey(epoch).String() {
+			t.Fatalf("expected update hash %s, got %s", feed.UpdateKey(epoch), hash)
+		}
+		latest, err := client.LookupFeed(feed, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latest.Epoch != epoch || !bytes.Equal(latest.Data, update.Data) {
+			t.Fatalf("expected latest update %d %q, got %d %q", epoch, update.Data, latest.Epoch, latest.Data)
+		}
+	}
+
+	// check earlier updates can be looked up by epoch
+	update, err := client.LookupFeed(feed, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(update.Data) != "foo2" {
+		t.Fatalf("expected update data %q, got %q", "foo2", update.Data)
+	}
+
+	// check unsigned updates are refused
+	forged := &storage.FeedUpdate{Feed: *feed, Epoch: 4, Data: []byte("forged")}
+	if _, err := client.UpdateFeed(forged); err == nil {
+		t.Fatal("expected an error posting an unsigned update")
+	}
+}
+
+// TestClientFeedManifest tests that a manifest created for a feed serves the
+// content the latest update of the feed points to
+func TestClientFeedManifest(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	prvkey, _ := crypto.GenerateKey()
+	feed := &storage.Feed{
+		Topic: crypto.Keccak256Hash([]byte("foo")),
+		User:  crypto.PubkeyToAddress(prvkey.PublicKey),
+	}
+	manifest, err := client.CreateFeedManifest(feed, "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(epoch uint64, data []byte) {
+		update := &storage.FeedUpdate{Feed: *feed, Epoch: epoch, Data: data}
+		if err := update.Sign(prvkey); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.UpdateFeed(update); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for epoch, content := range []string{"foo", "bar"} {
+		hash, err := client.UploadRaw(strings.NewReader(content), int64(len(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		publish(uint64(epoch+1), common.Hex2Bytes(hash))
+
+		file, err := client.Download(manifest, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Fatalf("expected downloaded content %q, got %q", content, data)
+		}
+		if file.ContentType != "text/plain" {
+			t.Fatalf("expected content type %q, got %q", "text/plain", file.ContentType)
+		}
+	}
+
+	// check an update which isn't a swarm reference isn't resolved
+	publish(3, []byte("not a reference"))
+	if _, err := client.Download(manifest, ""); err == nil {
+		t.Fatal("expected an error downloading an invalid feed reference")
+	}
+}
+
 // TestClientUploadDownloadFiles test uploading and downloading files to swarm
 // manifests
 func TestClientUploadDownloadFiles(t *testing.T) {
@@ -213,6 +435,231 @@ func TestClientUploadDownloadDirectory(t *testing.T) {
 			t.Fatalf("expected data to be %q, got %q", file, data)
 		}
 	}
+
+	// check we can download a subdirectory
+	sub, err := ioutil.TempDir("", "swarm-client-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sub)
+	if err := client.DownloadDirectory(hash, "dir2/", sub); err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	err = filepath.Walk(sub, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(sub, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"dir3/file6.txt", "dir4/file7.txt", "dir4/file8.txt", "file5.txt"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected subdirectory files %v, got %v", expected, files)
+	}
+
+	// check a path which is only a string prefix of the directories
+	// doesn't match any files
+	none, err := ioutil.TempDir("", "swarm-client-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(none)
+	err = client.DownloadDirectory(hash, "dir", none)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a 404 error downloading a non-existent directory, got %v", err)
+	}
+}
+
+// TestClientDiff tests comparing the paths of two manifests
+func TestClientDiff(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	client := NewClient(srv.URL)
+	oldHash, err := client.UploadDirectory(dir, "", "")
+	if err != nil {
+		t.Fatalf("error uploading directory: %s", err)
+	}
+
+	// add, remove and modify files
+	if err := os.Remove(filepath.Join(dir, "dir1/file3.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "dir2/dir4/file7.txt"), []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "dir1/file9.txt"), []byte("added"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newHash, err := client.UploadDirectory(dir, "", "")
+	if err != nil {
+		t.Fatalf("error uploading directory: %s", err)
+	}
+
+	diff, err := client.Diff(oldHash, newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &api.ManifestDiff{
+		Added:    []string{"dir1/file9.txt"},
+		Removed:  []string{"dir1/file3.txt"},
+		Modified: []string{"dir2/dir4/file7.txt"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("expected diff %+v, got %+v", expected, diff)
+	}
+
+	// check comparing a manifest with itself returns an empty diff
+	diff, err = client.Diff(newHash, newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff, &api.ManifestDiff{}) {
+		t.Fatalf("expected empty diff, got %+v", diff)
+	}
+}
+
+// TestClientPin tests pinning and unpinning content on the swarm node
+func TestClientPin(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	client := NewClient(srv.URL)
+	hash, err := client.UploadDirectory(dir, "", "")
+	if err != nil {
+		t.Fatalf("error uploading directory: %s", err)
+	}
+	data := []byte("foo123")
+	rawHash, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pin the manifest, which covers at least the manifest and the files
+	root, err := client.Pin(hash, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Root.String() != hash || root.Chunks <= uint64(len(testDirFiles)) {
+		t.Fatalf("unexpected pinned root %s with %d chunks", root.Root, root.Chunks)
+	}
+	if _, err := client.Pin(hash, false); err == nil {
+		t.Fatal("expected an error pinning twice")
+	}
+
+	// pin the raw content, a single chunk
+	rawRoot, err := client.Pin(rawHash, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rawRoot.Chunks != 1 || rawRoot.Size != uint64(8+len(data)) {
+		t.Fatalf("expected 1 chunk of %d bytes pinned, got %d chunks of %d bytes", 8+len(data), rawRoot.Chunks, rawRoot.Size)
+	}
+
+	roots, err := client.PinnedRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 2 {
+		t.Fatalf("expected 2 pinned roots, got %d", len(roots))
+	}
+
+	// check unpinning
+	if err := client.Unpin(hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Unpin(hash); err == nil {
+		t.Fatal("expected an error unpinning twice")
+	}
+	roots, err = client.PinnedRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || roots[0].Root.String() != rawHash {
+		t.Fatalf("expected only %s to be pinned, got %v", rawHash, roots)
+	}
+}
+
+// TestClientAccess tests creating a password protected root and retrieving
+// the content through it with basic authentication
+func TestClientAccess(t *testing.T) {
+	defer func(params api.KdfParams) { api.DefaultKdfParams = params }(api.DefaultKdfParams)
+	api.DefaultKdfParams = api.KdfParams{N: 1 << 10, P: 1, R: 8}
+
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	data := []byte("foo123")
+	hash, err := client.Upload(&File{
+		ReadCloser: ioutil.NopCloser(bytes.NewReader(data)),
+		ManifestEntry: api.ManifestEntry{
+			Path:        "file.txt",
+			ContentType: "text/plain",
+			Size:        int64(len(data)),
+		},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := client.NewAccess(hash, &api.AccessRequest{Type: api.AccessTypePass, Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(password string) *http.Response {
+		req, err := http.NewRequest("GET", srv.URL+"/bzz:/"+root+"/file.txt", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if password != "" {
+			req.SetBasicAuth("", password)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	// without credentials the server asks for them
+	res := get("")
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized || res.Header.Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected HTTP %d with an authentication challenge, got %s", http.StatusUnauthorized, res.Status)
+	}
+
+	res = get("secret")
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected HTTP status: %s", res.Status)
+	}
+	gotData, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotData, data) {
+		t.Fatalf("expected %q, got %q", data, gotData)
+	}
+
+	// only ACT roots can be updated
+	if _, err := client.UpdateAccess(root, &api.AccessRequest{Type: api.AccessTypeACT, Password: "other"}); err == nil {
+		t.Fatal("expected an error updating a password root")
+	}
 }
 
 // TestClientFileList tests listing files in a swarm manifest
@@ -28,6 +28,7 @@ import (
 	"github.com/aaechain/go-aaechain/log"
 	"github.com/aaechain/go-aaechain/node"
 	"github.com/aaechain/go-aaechain/swarm/network"
+	"github.com/aaechain/go-aaechain/swarm/network/stream"
 	"github.com/aaechain/go-aaechain/swarm/services/swap"
 	"github.com/aaechain/go-aaechain/swarm/storage"
 )
@@ -45,7 +46,7 @@ type Config struct {
 	*storage.ChunkerParams
 	*network.HiveParams
 	Swap *swap.SwapParams
-	*network.SyncParams
+	*stream.SyncParams
 	Contract    common.Address
 	EnsRoot     common.Address
 	EnsAPIs     []string
@@ -70,7 +71,7 @@ func NewDefaultConfig() (self *Config) {
 		StoreParams:   storage.NewDefaulaaeoreParams(),
 		ChunkerParams: storage.NewChunkerParams(),
 		HiveParams:    network.NewDefaultHiveParams(),
-		SyncParams:    network.NewDefaultSyncParams(),
+		SyncParams:    stream.NewDefaultSyncParams(),
 		Swap:          swap.NewDefaultSwapParams(),
 		ListenAddr:    DefaultHTTPListenAddr,
 		Port:          DefaultHTTPPort,
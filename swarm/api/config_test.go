@@ -55,7 +55,7 @@ func TestConfig(t *testing.T) {
 		t.Fatal("Failed to correctly initialize SwapParams")
 	}
 
-	if one.SyncParams.RequestDbPath == one.Path {
+	if one.SyncParams.CursorDbPath == one.Path {
 		t.Fatal("Failed to correctly initialize SyncParams")
 	}
 
@@ -21,6 +21,7 @@ package http
 
 import (
 	"archive/tar"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -60,6 +61,18 @@ var (
 	getFilesFail     = metrics.NewRegisteredCounter("api.http.get.files.fail", nil)
 	getListCount     = metrics.NewRegisteredCounter("api.http.get.list.count", nil)
 	getListFail      = metrics.NewRegisteredCounter("api.http.get.list.fail", nil)
+	getDiffCount     = metrics.NewRegisteredCounter("api.http.get.diff.count", nil)
+	getDiffFail      = metrics.NewRegisteredCounter("api.http.get.diff.fail", nil)
+	postFeedCount    = metrics.NewRegisteredCounter("api.http.post.feed.count", nil)
+	postFeedFail     = metrics.NewRegisteredCounter("api.http.post.feed.fail", nil)
+	getFeedCount     = metrics.NewRegisteredCounter("api.http.get.feed.count", nil)
+	getFeedFail      = metrics.NewRegisteredCounter("api.http.get.feed.fail", nil)
+	pinCount         = metrics.NewRegisteredCounter("api.http.pin.count", nil)
+	pinFail          = metrics.NewRegisteredCounter("api.http.pin.fail", nil)
+	unpinCount       = metrics.NewRegisteredCounter("api.http.unpin.count", nil)
+	unpinFail        = metrics.NewRegisteredCounter("api.http.unpin.fail", nil)
+	postAccessCount  = metrics.NewRegisteredCounter("api.http.post.access.count", nil)
+	postAccessFail   = metrics.NewRegisteredCounter("api.http.post.access.fail", nil)
 	requestCount     = metrics.NewRegisteredCounter("http.request.count", nil)
 	htmlRequestCount = metrics.NewRegisteredCounter("http.request.html.count", nil)
 	jsonRequestCount = metrics.NewRegisteredCounter("http.request.json.count", nil)
@@ -111,7 +124,10 @@ type Request struct {
 }
 
 // HandlePostRaw handles a POST request to a raw bzz-raw:/ URI, stores the request
-// body in swarm and returns the resulting storage key as a text/plain response
+// body in swarm and returns the resulting storage key as a text/plain response.
+// Requests to bzz-encrypted:/ store the body encrypted and return the reference
+// including the decryption key. The redundancy query parameter sets the level
+// of erasure coded redundancy the body is stored with
 func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 	postRawCount.Inc(1)
 	if r.uri.Path != "" {
@@ -126,7 +142,22 @@ func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 		return
 	}
 
-	key, err := s.api.Store(r.Body, r.ContentLength, nil)
+	level, err := redundancyLevel(r)
+	if err != nil {
+		postRawFail.Inc(1)
+		s.BadRequest(w, r, err.Error())
+		return
+	}
+
+	var key storage.Key
+	switch {
+	case level > 0:
+		key, err = s.api.StoreRedundant(r.Body, r.ContentLength, level, r.uri.Encrypted(), nil)
+	case r.uri.Encrypted():
+		key, err = s.api.StoreEncrypted(r.Body, r.ContentLength, nil)
+	default:
+		key, err = s.api.Store(r.Body, r.ContentLength, nil)
+	}
 	if err != nil {
 		postRawFail.Inc(1)
 		s.Error(w, r, err)
@@ -143,7 +174,8 @@ func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 // bzz:/<hash>/<path> which contains either a single file or multiple files
 // (either a tar archive or multipart form), adds those files either to an
 // existing manifest or to a new manifest under <path> and returns the
-// resulting manifest hash as a text/plain response
+// resulting manifest hash as a text/plain response. The redundancy query
+// parameter sets the level of erasure coded redundancy the files are stored with
 func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	postFilesCount.Inc(1)
 	contentType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
@@ -153,6 +185,13 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 		return
 	}
 
+	level, err := redundancyLevel(r)
+	if err != nil {
+		postFilesFail.Inc(1)
+		s.BadRequest(w, r, err.Error())
+		return
+	}
+
 	var key storage.Key
 	if r.uri.Addr != "" {
 		key, err = s.api.Resolve(r.uri)
@@ -171,6 +210,7 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	}
 
 	newKey, err := s.updateManifest(key, func(mw *api.ManifestWriter) error {
+		mw.SetRedundancy(level)
 		switch contentType {
 
 		case "application/x-tar":
@@ -194,6 +234,20 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	fmt.Fprint(w, newKey)
 }
 
+// redundancyLevel returns the level of erasure coded redundancy requested by
+// the redundancy query parameter of an upload
+func redundancyLevel(r *Request) (int, error) {
+	v := r.URL.Query().Get("redundancy")
+	if v == "" {
+		return 0, nil
+	}
+	level, err := strconv.Atoi(v)
+	if err != nil || level < 0 || level > storage.MaxRedundancyLevel {
+		return 0, fmt.Errorf("invalid redundancy level %q", v)
+	}
+	return level, nil
+}
+
 func (s *Server) handleTarUpload(req *Request, mw *api.ManifestWriter) error {
 	tr := tar.NewReader(req.Body)
 	for {
@@ -390,7 +444,7 @@ func (s *Server) HandleGet(w http.ResponseWriter, r *Request) {
 	}
 
 	switch {
-	case r.uri.Raw() || r.uri.DeprecatedRaw():
+	case r.uri.Raw() || r.uri.Encrypted() || r.uri.DeprecatedRaw():
 		// allow the request to overwrite the content type using a query
 		// parameter
 		contentType := "application/octet-stream"
@@ -407,16 +461,13 @@ func (s *Server) HandleGet(w http.ResponseWriter, r *Request) {
 	}
 }
 
-// HandleGetFiles handles a GET request to bzz:/<manifest> with an Accept
-// header of "application/x-tar" and returns a tar stream of all files
-// contained in the manifest
+// HandleGetFiles handles a GET request to bzz:/<manifest>/<path> with an
+// Accept header of "application/x-tar" and returns a tar stream of all files
+// contained in the manifest at or below <path>, responding with 404 if there
+// are none
 func (s *Server) HandleGetFiles(w http.ResponseWriter, r *Request) {
 	getFilesCount.Inc(1)
-	if r.uri.Path != "" {
-		getFilesFail.Inc(1)
-		s.BadRequest(w, r, "files request cannot contain a path")
-		return
-	}
+	prefix := r.uri.Path
 
 	key, err := s.api.Resolve(r.uri)
 	if err != nil {
@@ -424,6 +475,11 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *Request) {
 		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
 		return
 	}
+	key, ok := s.resolveAccess(w, r, key)
+	if !ok {
+		getFilesFail.Inc(1)
+		return
+	}
 
 	walker, err := s.api.NewManifestWalker(key, nil)
 	if err != nil {
@@ -432,14 +488,28 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *Request) {
 		return
 	}
 
-	tw := tar.NewWriter(w)
-	defer tw.Close()
-	w.Header().Set("Content-Type", "application/x-tar")
-	w.WriteHeader(http.StatusOK)
+	// the response header is only written once the first file is found
+	// so that a 404 can be returned if there are no files below the path
+	var tw *tar.Writer
+	defer func() {
+		if tw != nil {
+			tw.Close()
+		}
+	}()
 
 	err = walker.Walk(func(entry *api.ManifestEntry) error {
-		// ignore manifests (walk will recurse into them)
+		// recurse into manifests which can contain paths with the
+		// prefix and skip the others
 		if entry.ContentType == api.ManifestType {
+			if strings.HasPrefix(prefix, entry.Path) || strings.HasPrefix(entry.Path, prefix) {
+				return nil
+			}
+			return api.SkipManifest
+		}
+
+		// ignore files outside the path and feed entries, which
+		// don't refer to fixed content
+		if !underPath(entry.Path, prefix) || entry.Feed != nil {
 			return nil
 		}
 
@@ -450,6 +520,12 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *Request) {
 			return err
 		}
 
+		if tw == nil {
+			tw = tar.NewWriter(w)
+			w.Header().Set("Content-Type", "application/x-tar")
+			w.WriteHeader(http.StatusOK)
+		}
+
 		// write a tar header for the entry
 		hdr := &tar.Header{
 			Name:    entry.Path,
@@ -476,8 +552,28 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *Request) {
 	})
 	if err != nil {
 		getFilesFail.Inc(1)
+		if tw == nil {
+			s.Error(w, r, err)
+			return
+		}
 		s.logError("error generating tar stream: %s", err)
+		return
+	}
+	if tw == nil {
+		getFilesFail.Inc(1)
+		s.NotFound(w, r, fmt.Errorf("no files found below %s", r.uri))
+	}
+}
+
+// underPath returns whaaeer the manifest path is the given path itself or
+// a path within it, matching only on whole path components (so that "dir"
+// doesn't match "dir2/file.txt")
+func underPath(path, dir string) bool {
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" {
+		return true
 	}
+	return path == dir || strings.HasPrefix(path, dir+"/")
 }
 
 // HandleGetList handles a GET request to bzz-list:/<manifest>/<path> and returns
@@ -497,6 +593,11 @@ func (s *Server) HandleGetList(w http.ResponseWriter, r *Request) {
 		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
 		return
 	}
+	key, ok := s.resolveAccess(w, r, key)
+	if !ok {
+		getListFail.Inc(1)
+		return
+	}
 
 	list, err := s.getManifestList(key, r.uri.Path)
 
@@ -529,6 +630,295 @@ func (s *Server) HandleGetList(w http.ResponseWriter, r *Request) {
 	json.NewEncoder(w).Encode(&list)
 }
 
+// HandleGetDiff handles a GET request to bzz-diff:/<old>/<new> and returns
+// the paths which were added, removed or modified in manifest <new> compared
+// to manifest <old> as a JSON response
+func (s *Server) HandleGetDiff(w http.ResponseWriter, r *Request) {
+	getDiffCount.Inc(1)
+	if r.uri.Path == "" || strings.Contains(r.uri.Path, "/") {
+		getDiffFail.Inc(1)
+		s.BadRequest(w, r, "diff request must contain exactly two manifests")
+		return
+	}
+
+	oldKey, ok := s.resolveManifest(w, r, r.uri.Addr)
+	if !ok {
+		getDiffFail.Inc(1)
+		return
+	}
+	newKey, ok := s.resolveManifest(w, r, r.uri.Path)
+	if !ok {
+		getDiffFail.Inc(1)
+		return
+	}
+
+	diff, err := s.api.Diff(oldKey, newKey)
+	if err != nil {
+		getDiffFail.Inc(1)
+		s.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// resolveManifest resolves addr to the key of a manifest, replacing access
+// controlled roots with the reference they protect
+func (s *Server) resolveManifest(w http.ResponseWriter, r *Request, addr string) (storage.Key, bool) {
+	key, err := s.api.Resolve(&api.URI{Scheme: r.uri.Scheme, Addr: addr})
+	if err != nil {
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", addr, err))
+		return nil, false
+	}
+	return s.resolveAccess(w, r, key)
+}
+
+// HandlePostFeed handles a POST request to bzz-feed:/<user>/<topic> whose body
+// is a JSON encoded feed update signed by <user>, stores the update in swarm
+// and returns its storage key as a text/plain response
+//
+// If the manifest query parameter is set to true, the body is ignored and a
+// manifest resolving to the latest update of the feed is created instead,
+// with the entry served using the content_type query parameter if given.
+// The manifest hash is returned as a text/plain response
+func (s *Server) HandlePostFeed(w http.ResponseWriter, r *Request) {
+	postFeedCount.Inc(1)
+	feed, err := parseFeedURI(r.uri)
+	if err != nil {
+		postFeedFail.Inc(1)
+		s.BadRequest(w, r, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("manifest") == "true" {
+		key, err := s.api.NewFeedManifest(feed, r.URL.Query().Get("content_type"))
+		if err != nil {
+			postFeedFail.Inc(1)
+			s.Error(w, r, fmt.Errorf("error creating feed manifest: %s", err))
+			return
+		}
+		s.logDebug("feed manifest stored at %s", key.Log())
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, key)
+		return
+	}
+
+	var update storage.FeedUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		postFeedFail.Inc(1)
+		s.BadRequest(w, r, fmt.Sprintf("invalid feed update: %s", err))
+		return
+	}
+	update.Feed = *feed
+
+	key, err := s.api.UpdateFeed(&update)
+	if err != nil {
+		postFeedFail.Inc(1)
+		s.BadRequest(w, r, fmt.Sprintf("invalid feed update: %s", err))
+		return
+	}
+	s.logDebug("feed update for epoch %d stored at %s", update.Epoch, key.Log())
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, key)
+}
+
+// HandleGetFeed handles a GET request to bzz-feed:/<user>/<topic> and returns
+// the latest update of the feed as JSON, or the update of a given epoch if the
+// epoch query parameter is set
+func (s *Server) HandleGetFeed(w http.ResponseWriter, r *Request) {
+	getFeedCount.Inc(1)
+	feed, err := parseFeedURI(r.uri)
+	if err != nil {
+		getFeedFail.Inc(1)
+		s.BadRequest(w, r, err.Error())
+		return
+	}
+
+	var epoch uint64
+	if e := r.URL.Query().Get("epoch"); e != "" {
+		epoch, err = strconv.ParseUint(e, 10, 64)
+		if err != nil || epoch == 0 {
+			getFeedFail.Inc(1)
+			s.BadRequest(w, r, fmt.Sprintf("invalid epoch %q", e))
+			return
+		}
+	}
+
+	update, err := s.api.LookupFeed(feed, epoch)
+	if err != nil {
+		getFeedFail.Inc(1)
+		s.NotFound(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(update)
+}
+
+// HandlePin handles a POST request to bzz-pin:/<hash>, pins the manifest
+// <hash> and the content it refers to (or the raw content <hash> if the raw
+// query parameter is set) in the local store and returns the pinned root as
+// JSON
+func (s *Server) HandlePin(w http.ResponseWriter, r *Request) {
+	pinCount.Inc(1)
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		pinFail.Inc(1)
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+
+	root, err := s.api.Pin(key, r.URL.Query().Get("raw") == "true")
+	if err == storage.ErrAlreadyPinned {
+		pinFail.Inc(1)
+		ShowError(w, r, fmt.Sprintf("%s is already pinned", key), http.StatusConflict)
+		return
+	} else if err == storage.ErrPinQuotaExceeded {
+		pinFail.Inc(1)
+		ShowError(w, r, fmt.Sprintf("pinning %s exceeds the pin quota", key), http.StatusInsufficientStorage)
+		return
+	} else if err != nil {
+		pinFail.Inc(1)
+		s.Error(w, r, err)
+		return
+	}
+	s.logDebug("pinned %s: %d chunks, %d bytes", key.Log(), root.Chunks, root.Size)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(root)
+}
+
+// HandlePostAccess handles a POST request to bzz-access:/<hash> with a JSON
+// encoded access request body, stores an access controlled root wrapping
+// <hash> (or replaces the grantees of the ACT root <hash> if the update query
+// parameter is set) and returns the resulting root hash
+func (s *Server) HandlePostAccess(w http.ResponseWriter, r *Request) {
+	postAccessCount.Inc(1)
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		postAccessFail.Inc(1)
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+
+	var req api.AccessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		postAccessFail.Inc(1)
+		s.BadRequest(w, r, fmt.Sprintf("invalid access request: %s", err))
+		return
+	}
+
+	var root storage.Key
+	if r.URL.Query().Get("update") == "true" {
+		root, err = s.api.UpdateAccess(key, &req)
+	} else {
+		root, err = s.api.NewAccess(key, &req)
+	}
+	if err != nil {
+		postAccessFail.Inc(1)
+		s.BadRequest(w, r, err.Error())
+		return
+	}
+	s.logDebug("access root %s for %s", root.Log(), key.Log())
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, root)
+}
+
+// resolveAccess replaces an access controlled root with the reference it
+// protects, asking for a password with basic authentication if the node key
+// does not grant access
+func (s *Server) resolveAccess(w http.ResponseWriter, r *Request, key storage.Key) (storage.Key, bool) {
+	_, password, _ := r.BasicAuth()
+	ref, err := s.api.ResolveAccess(key, password)
+	switch err {
+	case nil:
+		return ref, true
+	case api.ErrNoCredentials, api.ErrAccessDenied:
+		w.Header().Set("WWW-Authenticate", `Basic realm="swarm"`)
+		ShowError(w, r, err.Error(), http.StatusUnauthorized)
+	default:
+		s.NotFound(w, r, fmt.Errorf("error resolving access to %s: %s", key, err))
+	}
+	return nil, false
+}
+
+// HandleUnpin handles a DELETE request to bzz-pin:/<hash> and releases the
+// content pinned under <hash>
+func (s *Server) HandleUnpin(w http.ResponseWriter, r *Request) {
+	unpinCount.Inc(1)
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		unpinFail.Inc(1)
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+
+	if err := s.api.Unpin(key); err == storage.ErrNotPinned {
+		unpinFail.Inc(1)
+		s.NotFound(w, r, err)
+		return
+	} else if err != nil {
+		unpinFail.Inc(1)
+		s.Error(w, r, err)
+		return
+	}
+	s.logDebug("unpinned %s", key.Log())
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleGetPins handles a GET request to bzz-pin:/ and returns the pinned roots
+// with the number and total size of their chunks as JSON, or only the given
+// root for bzz-pin:/<hash>
+func (s *Server) HandleGetPins(w http.ResponseWriter, r *Request) {
+	roots, err := s.api.PinnedRoots()
+	if err != nil {
+		s.Error(w, r, err)
+		return
+	}
+	if r.uri.Addr == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(roots)
+		return
+	}
+
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+	for _, root := range roots {
+		if bytes.Equal(root.Root, key) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(root)
+			return
+		}
+	}
+	s.NotFound(w, r, storage.ErrNotPinned)
+}
+
+// parseFeedURI returns the feed identified by a bzz-feed:/<user>/<topic> URI
+func parseFeedURI(uri *api.URI) (*storage.Feed, error) {
+	if !common.IsHexAddress(uri.Addr) {
+		return nil, fmt.Errorf("invalid feed user %q", uri.Addr)
+	}
+	topic := common.FromHex(uri.Path)
+	if len(topic) != common.HashLength {
+		return nil, fmt.Errorf("invalid feed topic %q", uri.Path)
+	}
+	return &storage.Feed{
+		Topic: common.BytesToHash(topic),
+		User:  common.HexToAddress(uri.Addr),
+	}, nil
+}
+
 func (s *Server) getManifestList(key storage.Key, prefix string) (list api.ManifestList, err error) {
 	walker, err := s.api.NewManifestWalker(key, nil)
 	if err != nil {
@@ -601,6 +991,11 @@ func (s *Server) HandleGetFile(w http.ResponseWriter, r *Request) {
 		s.NotFound(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
 		return
 	}
+	key, ok := s.resolveAccess(w, r, key)
+	if !ok {
+		getFileFail.Inc(1)
+		return
+	}
 
 	reader, contentType, status, err := s.api.Get(key, r.uri.Path)
 	if err != nil {
@@ -680,8 +1075,16 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "POST":
-		if uri.Raw() || uri.DeprecatedRaw() {
+		if uri.Raw() || uri.Encrypted() || uri.DeprecatedRaw() {
 			s.HandlePostRaw(w, req)
+		} else if uri.Feed() {
+			s.HandlePostFeed(w, req)
+		} else if uri.Pin() {
+			s.HandlePin(w, req)
+		} else if uri.Access() {
+			s.HandlePostAccess(w, req)
+		} else if uri.Diff() {
+			ShowError(w, req, fmt.Sprintf("No POST to %s allowed.", uri), http.StatusBadRequest)
 		} else {
 			s.HandlePostFiles(w, req)
 		}
@@ -692,7 +1095,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		//   new manifest leaving the existing one intact, so it isn't
 		//   strictly a traditional PUT request which replaces content
 		//   at a URI, and POST is more ubiquitous)
-		if uri.Raw() || uri.DeprecatedRaw() {
+		if uri.Raw() || uri.Encrypted() || uri.Feed() || uri.Pin() || uri.Access() || uri.Diff() || uri.DeprecatedRaw() {
 			ShowError(w, req, fmt.Sprintf("No PUT to %s allowed.", uri), http.StatusBadRequest)
 			return
 		} else {
@@ -700,14 +1103,18 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case "DELETE":
-		if uri.Raw() || uri.DeprecatedRaw() {
+		if uri.Raw() || uri.Encrypted() || uri.Feed() || uri.Access() || uri.Diff() || uri.DeprecatedRaw() {
 			ShowError(w, req, fmt.Sprintf("No DELETE to %s allowed.", uri), http.StatusBadRequest)
 			return
 		}
+		if uri.Pin() {
+			s.HandleUnpin(w, req)
+			return
+		}
 		s.HandleDelete(w, req)
 
 	case "GET":
-		if uri.Raw() || uri.Hash() || uri.DeprecatedRaw() {
+		if uri.Raw() || uri.Hash() || uri.Encrypted() || uri.DeprecatedRaw() {
 			s.HandleGet(w, req)
 			return
 		}
@@ -717,6 +1124,26 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if uri.Feed() {
+			s.HandleGetFeed(w, req)
+			return
+		}
+
+		if uri.Pin() {
+			s.HandleGetPins(w, req)
+			return
+		}
+
+		if uri.Diff() {
+			s.HandleGetDiff(w, req)
+			return
+		}
+
+		if uri.Access() {
+			ShowError(w, req, fmt.Sprintf("No GET to %s allowed.", uri), http.StatusBadRequest)
+			return
+		}
+
 		if r.Header.Get("Accept") == "application/x-tar" {
 			s.HandleGetFiles(w, req)
 			return
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -50,6 +51,14 @@ type ManifestEntry struct {
 	Size        int64     `json:"size,omitempty"`
 	ModTime     time.Time `json:"mod_time,omitempty"`
 	Status      int       `json:"status,omitempty"`
+
+	// Feed is set for entries referring to the content the latest update
+	// of the feed points to, in which case Hash is empty
+	Feed *storage.Feed `json:"feed,omitempty"`
+
+	// Access is set on the single entry of an access controlled root, in
+	// which case Hash is the encrypted reference of the protected content
+	Access *AccessEntry `json:"access,omitempty"`
 }
 
 // ManifestList represents the result of listing files in a manifest
@@ -68,11 +77,27 @@ func (a *Api) NewManifest() (storage.Key, error) {
 	return a.Store(bytes.NewReader(data), int64(len(data)), &sync.WaitGroup{})
 }
 
+// NewFeedManifest creates and stores a manifest whose root entry resolves to
+// the content the latest update of the given feed points to
+func (a *Api) NewFeedManifest(feed *storage.Feed, contentType string) (storage.Key, error) {
+	key, err := a.NewManifest()
+	if err != nil {
+		return nil, err
+	}
+	mw, err := a.NewManifestWriter(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	mw.AddFeedEntry(&ManifestEntry{ContentType: contentType}, feed)
+	return mw.Store()
+}
+
 // ManifestWriter is used to add and remove entries from an underlying manifest
 type ManifestWriter struct {
-	api   *Api
-	trie  *manifestTrie
-	quitC chan bool
+	api        *Api
+	trie       *manifestTrie
+	quitC      chan bool
+	redundancy int // redundancy level of the entries added
 }
 
 func (a *Api) NewManifestWriter(key storage.Key, quitC chan bool) (*ManifestWriter, error) {
@@ -80,12 +105,24 @@ func (a *Api) NewManifestWriter(key storage.Key, quitC chan bool) (*ManifestWrit
 	if err != nil {
 		return nil, fmt.Errorf("error loading manifest %s: %s", key, err)
 	}
-	return &ManifestWriter{a, trie, quitC}, nil
+	return &ManifestWriter{api: a, trie: trie, quitC: quitC}, nil
+}
+
+// SetRedundancy sets the level of erasure coded redundancy the data of the
+// entries added is stored with
+func (m *ManifestWriter) SetRedundancy(level int) {
+	m.redundancy = level
 }
 
 // AddEntry stores the given data and adds the resulting key to the manifest
 func (m *ManifestWriter) AddEntry(data io.Reader, e *ManifestEntry) (storage.Key, error) {
-	key, err := m.api.Store(data, e.Size, nil)
+	var key storage.Key
+	var err error
+	if m.redundancy > 0 {
+		key, err = m.api.StoreRedundant(data, e.Size, m.redundancy, false, nil)
+	} else {
+		key, err = m.api.Store(data, e.Size, nil)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +132,15 @@ func (m *ManifestWriter) AddEntry(data io.Reader, e *ManifestEntry) (storage.Key
 	return key, nil
 }
 
+// AddFeedEntry adds an entry to the manifest which resolves to the content
+// the latest update of the given feed points to
+func (m *ManifestWriter) AddFeedEntry(e *ManifestEntry, feed *storage.Feed) {
+	entry := newManifestTrieEntry(e, nil)
+	entry.Hash = ""
+	entry.Feed = feed
+	m.trie.addEntry(entry, m.quitC)
+}
+
 // RemoveEntry removes the given path from the manifest
 func (m *ManifestWriter) RemoveEntry(path string) error {
 	m.trie.deleteEntry(path, m.quitC)
@@ -162,6 +208,75 @@ func (m *ManifestWalker) walk(trie *manifestTrie, prefix string, walkFn WalkFn)
 	return nil
 }
 
+// ManifestDiff represents the paths which differ between two manifests
+type ManifestDiff struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+// Diff walks the manifests at oldKey and newKey, including their
+// submanifests, and returns the paths which were added, removed or which
+// refer to different content in the new manifest
+func (a *Api) Diff(oldKey, newKey storage.Key) (*ManifestDiff, error) {
+	oldEntries, err := a.manifestEntries(oldKey)
+	if err != nil {
+		return nil, err
+	}
+	newEntries, err := a.manifestEntries(newKey)
+	if err != nil {
+		return nil, err
+	}
+	diff := &ManifestDiff{}
+	for path, entry := range newEntries {
+		old, ok := oldEntries[path]
+		if !ok {
+			diff.Added = append(diff.Added, path)
+		} else if !sameContent(old, entry) {
+			diff.Modified = append(diff.Modified, path)
+		}
+	}
+	for path := range oldEntries {
+		if _, ok := newEntries[path]; !ok {
+			diff.Removed = append(diff.Removed, path)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+	return diff, nil
+}
+
+// manifestEntries returns the non-manifest entries of the manifest at key
+// and all of its submanifests indexed by path
+func (a *Api) manifestEntries(key storage.Key) (map[string]*ManifestEntry, error) {
+	walker, err := a.NewManifestWalker(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*ManifestEntry)
+	err = walker.Walk(func(entry *ManifestEntry) error {
+		if entry.ContentType != ManifestType {
+			entries[entry.Path] = entry
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func sameContent(a, b *ManifestEntry) bool {
+	if a.Hash != b.Hash || a.ContentType != b.ContentType || a.Mode != b.Mode {
+		return false
+	}
+	if a.Feed == nil || b.Feed == nil {
+		return a.Feed == b.Feed
+	}
+	return *a.Feed == *b.Feed
+}
+
 type manifestTrie struct {
 	dpa     *storage.DPA
 	entries [257]*manifestTrieEntry // indexed by first character of basePath, entries[256] is the empty basePath entry
@@ -333,7 +448,7 @@ func (self *manifestTrie) recalcAndStore() error {
 	list := &Manifest{}
 	for _, entry := range self.entries {
 		if entry != nil {
-			if entry.Hash == "" { // TODO: paralellize
+			if entry.Hash == "" && entry.Feed == nil { // TODO: paralellize
 				err := entry.subtrie.recalcAndStore()
 				if err != nil {
 					return err
@@ -31,6 +31,14 @@ type URI struct {
 	// * bzz-immutable - immutable URI of an entry in a swarm manifest
 	//                   (address is not resolved)
 	// * bzz-list      -  list of all files contained in a swarm manifest
+	// * bzz-encrypted - raw swarm content stored encrypted, the address being
+	//                   the reference including the decryption key
+	// * bzz-feed      - updates of a feed, the address being the user and the
+	//                   path the topic of the feed
+	// * bzz-pin       - pinned content roots of the local store
+	// * bzz-access    - access controlled roots wrapping the given content
+	// * bzz-diff      - differences between two manifests, the address being
+	//                   the old manifest and the path the new one
 	//
 	// Deprecated Schemes:
 	// * bzzr - raw swarm content
@@ -58,7 +66,8 @@ type URI struct {
 // * <scheme>://<addr>
 // * <scheme>://<addr>/<path>
 //
-// with scheme one of bzz, bzz-raw, bzz-immutable, bzz-list or bzz-hash
+// with scheme one of bzz, bzz-raw, bzz-immutable, bzz-list, bzz-hash, bzz-encrypted,
+// bzz-feed, bzz-pin, bzz-access or bzz-diff
 // or deprecated ones bzzr and bzzi
 func Parse(rawuri string) (*URI, error) {
 	u, err := url.Parse(rawuri)
@@ -69,7 +78,7 @@ func Parse(rawuri string) (*URI, error) {
 
 	// check the scheme is valid
 	switch uri.Scheme {
-	case "bzz", "bzz-raw", "bzz-immutable", "bzz-list", "bzz-hash", "bzzr", "bzzi":
+	case "bzz", "bzz-raw", "bzz-immutable", "bzz-list", "bzz-hash", "bzz-encrypted", "bzz-feed", "bzz-pin", "bzz-access", "bzz-diff", "bzzr", "bzzi":
 	default:
 		return nil, fmt.Errorf("unknown scheme %q", u.Scheme)
 	}
@@ -104,6 +113,26 @@ func (u *URI) List() bool {
 	return u.Scheme == "bzz-list"
 }
 
+func (u *URI) Encrypted() bool {
+	return u.Scheme == "bzz-encrypted"
+}
+
+func (u *URI) Feed() bool {
+	return u.Scheme == "bzz-feed"
+}
+
+func (u *URI) Pin() bool {
+	return u.Scheme == "bzz-pin"
+}
+
+func (u *URI) Access() bool {
+	return u.Scheme == "bzz-access"
+}
+
+func (u *URI) Diff() bool {
+	return u.Scheme == "bzz-diff"
+}
+
 func (u *URI) DeprecatedRaw() bool {
 	return u.Scheme == "bzzr"
 }
@@ -30,6 +30,11 @@ func TestParseURI(t *testing.T) {
 		expectImmutable           bool
 		expectList                bool
 		expectHash                bool
+		expectEncrypted           bool
+		expectFeed                bool
+		expectPin                 bool
+		expectAccess              bool
+		expectDiff                bool
 		expectDeprecatedRaw       bool
 		expectDeprecatedImmutable bool
 	}
@@ -60,6 +65,36 @@ func TestParseURI(t *testing.T) {
 			expectURI: &URI{Scheme: "bzz-raw"},
 			expectRaw: true,
 		},
+		{
+			uri:             "bzz-encrypted:",
+			expectURI:       &URI{Scheme: "bzz-encrypted"},
+			expectEncrypted: true,
+		},
+		{
+			uri:             "bzz-encrypted:/abc123",
+			expectURI:       &URI{Scheme: "bzz-encrypted", Addr: "abc123"},
+			expectEncrypted: true,
+		},
+		{
+			uri:        "bzz-feed:/abc123/def456",
+			expectURI:  &URI{Scheme: "bzz-feed", Addr: "abc123", Path: "def456"},
+			expectFeed: true,
+		},
+		{
+			uri:       "bzz-pin:/abc123",
+			expectURI: &URI{Scheme: "bzz-pin", Addr: "abc123"},
+			expectPin: true,
+		},
+		{
+			uri:          "bzz-access:/abc123",
+			expectURI:    &URI{Scheme: "bzz-access", Addr: "abc123"},
+			expectAccess: true,
+		},
+		{
+			uri:        "bzz-diff:/abc123/def456",
+			expectURI:  &URI{Scheme: "bzz-diff", Addr: "abc123", Path: "def456"},
+			expectDiff: true,
+		},
 		{
 			uri:       "bzz:/",
 			expectURI: &URI{Scheme: "bzz"},
@@ -166,6 +201,21 @@ func TestParseURI(t *testing.T) {
 		if actual.Hash() != x.expectHash {
 			t.Fatalf("expected %s hash to be %t, got %t", x.uri, x.expectHash, actual.Hash())
 		}
+		if actual.Encrypted() != x.expectEncrypted {
+			t.Fatalf("expected %s encrypted to be %t, got %t", x.uri, x.expectEncrypted, actual.Encrypted())
+		}
+		if actual.Feed() != x.expectFeed {
+			t.Fatalf("expected %s feed to be %t, got %t", x.uri, x.expectFeed, actual.Feed())
+		}
+		if actual.Pin() != x.expectPin {
+			t.Fatalf("expected %s pin to be %t, got %t", x.uri, x.expectPin, actual.Pin())
+		}
+		if actual.Access() != x.expectAccess {
+			t.Fatalf("expected %s access to be %t, got %t", x.uri, x.expectAccess, actual.Access())
+		}
+		if actual.Diff() != x.expectDiff {
+			t.Fatalf("expected %s diff to be %t, got %t", x.uri, x.expectDiff, actual.Diff())
+		}
 		if actual.DeprecatedRaw() != x.expectDeprecatedRaw {
 			t.Fatalf("expected %s deprecated raw to be %t, got %t", x.uri, x.expectDeprecatedRaw, actual.DeprecatedRaw())
 		}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/aaechain/go-aaechain/common"
@@ -28,6 +29,7 @@ import (
 	"github.com/aaechain/go-aaechain/p2p/discover"
 	"github.com/aaechain/go-aaechain/p2p/netutil"
 	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	"github.com/aaechain/go-aaechain/swarm/services/swap/swap"
 	"github.com/aaechain/go-aaechain/swarm/storage"
 )
 
@@ -57,6 +59,14 @@ type Hive struct {
 	toggle       chan bool
 	more         chan bool
 
+	pssHandler   PssHandler   // delivers pss messages addressed to us
+	pssValidator PssValidator // checks pss messages received from peers
+	pssLock      sync.Mutex   // guards the pss fields
+	pssSeen      *pssCache    // pss messages already relayed
+
+	swaps    map[discover.NodeID]*swap.Swap // swap instances of the connected peers
+	swapLock sync.RWMutex                   // guards swaps
+
 	// for testing only
 	swapEnabled bool
 	syncEnabled bool
@@ -105,6 +115,8 @@ func NewHive(addr common.Hash, params *HiveParams, swapEnabled, syncEnabled bool
 		path:         params.KadDbPath,
 		swapEnabled:  swapEnabled,
 		syncEnabled:  syncEnabled,
+		pssSeen:      newPssCache(),
+		swaps:        make(map[discover.NodeID]*swap.Swap),
 	}
 }
 
@@ -129,6 +141,34 @@ func (self *Hive) Addr() kademlia.Address {
 	return self.addr
 }
 
+// Depth returns the proximity order of the nearest neighbourhood
+func (self *Hive) Depth() int {
+	return self.kad.ProxLimit()
+}
+
+// MaxProx returns the proximity order of the deepest bin of the kademlia table
+func (self *Hive) MaxProx() int {
+	return self.kad.MaxProx
+}
+
+// Syncing returns true if syncronisation is enabled
+func (self *Hive) Syncing() bool {
+	return self.syncEnabled
+}
+
+// Account records units of service provided to (units > 0) or used from
+// (units < 0) the peer on its swap balance, failing if the balance does not
+// allow it. Peers without swap are not accounted.
+func (self *Hive) Account(id discover.NodeID, units int) error {
+	self.swapLock.RLock()
+	s := self.swaps[id]
+	self.swapLock.RUnlock()
+	if s == nil {
+		return nil
+	}
+	return s.Add(units)
+}
+
 // Start receives network info only at startup
 // listedAddr is a function to retrieve listening address to advertise to peers
 // connectPeer is a function to connect to a peer based on its NodeID or enode URL
@@ -226,7 +266,7 @@ func (self *Hive) keepAlive() {
 func (self *Hive) Stop() error {
 	// closing toggle channel quits the updateloop
 	close(self.quit)
-	return self.kad.Save(self.path, saveSync)
+	return self.kad.Save(self.path, nil)
 }
 
 // called at the end of a successful protocol handshake
@@ -239,10 +279,15 @@ func (self *Hive) addPeer(p *peer) error {
 		}
 	}()
 	log.Trace(fmt.Sprintf("hi new bee %v", p))
-	err := self.kad.On(p, loadSync)
+	err := self.kad.On(p, nil)
 	if err != nil {
 		return err
 	}
+	if p.swap != nil {
+		self.swapLock.Lock()
+		self.swaps[p.peer.ID()] = p.swap
+		self.swapLock.Unlock()
+	}
 	// self lookup (can be encoded as nil/zero key since peers addr known) + no id ()
 	// the most common way of saying hi in bzz is initiation of gossip
 	// let me know about anyone new from my hood , here is the storageradius
@@ -258,7 +303,12 @@ func (self *Hive) addPeer(p *peer) error {
 func (self *Hive) removePeer(p *peer) {
 	removePeerCounter.Inc(1)
 	log.Debug(fmt.Sprintf("bee %v removed", p))
-	self.kad.Off(p, saveSync)
+	self.kad.Off(p, nil)
+	self.swapLock.Lock()
+	if s, ok := self.swaps[p.peer.ID()]; ok && s == p.swap {
+		delete(self.swaps, p.peer.ID())
+	}
+	self.swapLock.Unlock()
 	select {
 	case self.more <- true:
 	default:
@@ -333,40 +383,6 @@ func (self *peer) LastActive() time.Time {
 	return self.lastActive
 }
 
-// reads the serialised form of sync state persisted as the 'Meta' attribute
-// and sets the decoded syncState on the online node
-func loadSync(record *kademlia.NodeRecord, node kademlia.Node) error {
-	p, ok := node.(*peer)
-	if !ok {
-		return fmt.Errorf("invalid type")
-	}
-	if record.Meta == nil {
-		log.Debug(fmt.Sprintf("no sync state for node record %v setting default", record))
-		p.syncState = &syncState{DbSyncState: &storage.DbSyncState{}}
-		return nil
-	}
-	state, err := decodeSync(record.Meta)
-	if err != nil {
-		return fmt.Errorf("error decoding kddb record meta info into a sync state: %v", err)
-	}
-	log.Trace(fmt.Sprintf("sync state for node record %v read from Meta: %s", record, string(*(record.Meta))))
-	p.syncState = state
-	return err
-}
-
-// callback when saving a sync state
-func saveSync(record *kademlia.NodeRecord, node kademlia.Node) {
-	if p, ok := node.(*peer); ok {
-		meta, err := encodeSync(p.syncState)
-		if err != nil {
-			log.Warn(fmt.Sprintf("error saving sync state for %v: %v", node, err))
-			return
-		}
-		log.Trace(fmt.Sprintf("saved sync state for %v: %s", node, string(*meta)))
-		record.Meta = meta
-	}
-}
-
 // the immediate response to a retrieve request,
 // sends relevant peer data given by the kademlia hive to the requester
 // TODO: remember peers sent for duration of the session, only new peers sent
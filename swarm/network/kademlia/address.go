@@ -75,6 +75,11 @@ func proximity(one, other Address) (ret int) {
 	return len(one) * 8
 }
 
+// Proximity returns the proximity order of the two addresses
+func Proximity(one, other Address) int {
+	return proximity(one, other)
+}
+
 // Address.ProxCmp compares the distances a->target and b->target.
 // Returns -1 if a is closer to target, 1 if b is closer to target
 // and 0 if they are equal.
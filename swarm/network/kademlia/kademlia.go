@@ -120,6 +120,13 @@ func (self *Kademlia) Count() int {
 	return self.count
 }
 
+// accessor for KAD proximity limit, the PO of the nearest neighbourhood
+func (self *Kademlia) ProxLimit() int {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.proxLimit
+}
+
 // accessor for KAD active node count
 func (self *Kademlia) DBCount() int {
 	return self.db.count()
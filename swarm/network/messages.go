@@ -34,14 +34,11 @@ BZZ protocol Message Types and Message Data Types
 
 // bzz protocol message codes
 const (
-	statusMsg          = iota // 0x01
-	storeRequestMsg           // 0x02
-	retrieveRequestMsg        // 0x03
-	peersMsg                  // 0x04
-	syncRequestMsg            // 0x05
-	deliveryRequestMsg        // 0x06
-	unsyncedKeysMsg           // 0x07
-	paymentMsg                // 0x08
+	statusMsg          = iota // 0x00
+	retrieveRequestMsg        // 0x01
+	peersMsg                  // 0x02
+	paymentMsg                // 0x03
+	pssMsg                    // 0x04
 )
 
 /*
@@ -53,7 +50,6 @@ const (
 * Swap: info for the swarm accounting protocol
 * NetworkID: 8 byte integer network identifier
 * Caps: swarm-specific capabilities, format identical to devp2p
-* SyncState: syncronisation state (db iterator key and address space etc) persisted about the peer
 
 */
 type statusMsgData struct {
@@ -68,41 +64,12 @@ func (self *statusMsgData) String() string {
 	return fmt.Sprintf("Status: Version: %v, ID: %v, Addr: %v, Swap: %v, NetworkId: %v", self.Version, self.ID, self.Addr, self.Swap, self.NetworkId)
 }
 
-/*
- store requests are forwarded to the peers in their kademlia proximity bin
- if they are distant
- if they are within our storage radius or have any incentive to store it
- then attach your nodeID to the metadata
- if the storage request is sufficiently close (within our proxLimit, i. e., the
- last row of the routing table)
-*/
-type storeRequestMsgData struct {
-	Key   storage.Key // hash of datasize | data
-	SData []byte      // the actual chunk Data
-	// optional
-	Id             uint64     // request ID. if delivery, the ID is retrieve request ID
-	requestTimeout *time.Time // expiry for forwarding - [not serialised][not currently used]
-	storageTimeout *time.Time // expiry of content - [not serialised][not currently used]
-	from           *peer      // [not serialised] protocol registers the requester
-}
-
-func (self storeRequestMsgData) String() string {
-	var from string
-	if self.from == nil {
-		from = "self"
-	} else {
-		from = self.from.Addr().String()
-	}
-	end := len(self.SData)
-	if len(self.SData) > 10 {
-		end = 10
-	}
-	return fmt.Sprintf("from: %v, Key: %v; ID: %v, requestTimeout: %v, storageTimeout: %v, SData %x", from, self.Key, self.Id, self.requestTimeout, self.storageTimeout, self.SData[:end])
-}
-
 /*
 Retrieve request
 
+Chunks are retrieved via the stream protocol (see package stream), over bzz
+retrieve requests serve as lookups prompting a peers message response.
+
 Timeout in milliseconds. Note that zero timeout retrieval requests do not request forwarding, but prompt for a peers message response. therefore they serve also
 as messages to retrieve peers.
 
@@ -236,61 +203,6 @@ func (self *peersMsgData) setTimeout(t *time.Time) {
 	}
 }
 
-/*
-syncRequest
-
-is sent after the handshake to initiate syncing
-the syncState of the remote node is persisted in kaddb and set on the
-peer/protocol instance when the node is registered by hive as online{
-*/
-
-type syncRequestMsgData struct {
-	SyncState *syncState `rlp:"nil"`
-}
-
-func (self *syncRequestMsgData) String() string {
-	return fmt.Sprintf("%v", self.SyncState)
-}
-
-/*
-deliveryRequest
-
-is sent once a batch of sync keys is filtered. The ones not found are
-sent as a list of syncReuest (hash, priority) in the Deliver field.
-When the source receives the sync request it continues to iterate
-and fetch at most N items as yet unsynced.
-At the same time responds with deliveries of the items.
-*/
-type deliveryRequestMsgData struct {
-	Deliver []*syncRequest
-}
-
-func (self *deliveryRequestMsgData) String() string {
-	return fmt.Sprintf("sync request for new chunks\ndelivery request for %v chunks", len(self.Deliver))
-}
-
-/*
-unsyncedKeys
-
-is sent first after the handshake if SyncState iterator brings up hundreds, thousands?
-and subsequently sent as a response to deliveryRequestMsgData.
-
-Syncing is the iterative process of exchanging unsyncedKeys and deliveryRequestMsgs
-both ways.
-
-State contains the sync state sent by the source. When the source receives the
-sync state it continues to iterate and fetch at most N items as yet unsynced.
-At the same time responds with deliveries of the items.
-*/
-type unsyncedKeysMsgData struct {
-	Unsynced []*syncRequest
-	State    *syncState
-}
-
-func (self *unsyncedKeysMsgData) String() string {
-	return fmt.Sprintf("sync: keys of %d new chunks (state %v) => synced: %v", len(self.Unsynced), self.State, self.State.Synced)
-}
-
 /*
 payment
 
@@ -306,3 +218,21 @@ type paymentMsgData struct {
 func (self *paymentMsgData) String() string {
 	return fmt.Sprintf("payment for %d units: %v", self.Units, self.Promise)
 }
+
+/*
+pss
+
+is a message routed through the kademlia towards the nodes closest to
+the destination overlay address To. To may be a prefix of the full address
+in which case every node in the matching neighbourhood receives the message.
+The payload is opaque to the bzz protocol
+*/
+
+type PssMsg struct {
+	To      []byte // (partial) overlay address of the recipient
+	Payload []byte // serialised envelope
+}
+
+func (self *PssMsg) String() string {
+	return fmt.Sprintf("pss to %x: %d bytes", self.To, len(self.Payload))
+}
@@ -25,9 +25,12 @@ The bzz protocol component speaks the bzz protocol
 * handle the protocol handshake
 * register peers in the KΛÐΞMLIΛ table via the hive logistic manager
 * dispatch to hive for handling the DHT logic
-* encode and decode requests for storage and retrieval
-* handle sync protocol messages via the syncer
-* talks the SWAP payment protocol (swap accounting is done within Neaaeore)
+* answer lookups with peers
+* talks the SWAP payment protocol
+* relays pss messages
+
+Storage, retrieval and syncronisation of chunks are done with the stream
+protocol running alongside (see package stream).
 */
 
 import (
@@ -43,25 +46,21 @@ import (
 	"github.com/aaechain/go-aaechain/p2p"
 	bzzswap "github.com/aaechain/go-aaechain/swarm/services/swap"
 	"github.com/aaechain/go-aaechain/swarm/services/swap/swap"
-	"github.com/aaechain/go-aaechain/swarm/storage"
 )
 
 //metrics variables
 var (
-	storeRequestMsgCounter    = metrics.NewRegisteredCounter("network.protocol.msg.storerequest.count", nil)
 	retrieveRequestMsgCounter = metrics.NewRegisteredCounter("network.protocol.msg.retrieverequest.count", nil)
 	peersMsgCounter           = metrics.NewRegisteredCounter("network.protocol.msg.peers.count", nil)
-	syncRequestMsgCounter     = metrics.NewRegisteredCounter("network.protocol.msg.syncrequest.count", nil)
-	unsyncedKeysMsgCounter    = metrics.NewRegisteredCounter("network.protocol.msg.unsyncedkeys.count", nil)
-	deliverRequestMsgCounter  = metrics.NewRegisteredCounter("network.protocol.msg.deliverrequest.count", nil)
 	paymentMsgCounter         = metrics.NewRegisteredCounter("network.protocol.msg.payment.count", nil)
+	pssMsgCounter             = metrics.NewRegisteredCounter("network.protocol.msg.pss.count", nil)
 	invalidMsgCounter         = metrics.NewRegisteredCounter("network.protocol.msg.invalid.count", nil)
 	handleStatusMsgCounter    = metrics.NewRegisteredCounter("network.protocol.msg.handlestatus.count", nil)
 )
 
 const (
-	Version            = 0
-	ProtocolLength     = uint64(8)
+	Version            = 1
+	ProtocolLength     = uint64(5)
 	ProtocolMaxMsgSize = 10 * 1024 * 1024
 	NetworkId          = 3
 )
@@ -69,13 +68,10 @@ const (
 // bzz represents the swarm wire protocol
 // an instance is running on each peer
 type bzz struct {
-	storage    StorageHandler       // handler storage/retrieval related requests coming via the bzz wire protocol
-	hive       *Hive                // the logistic manager, peerPool, routing service and peer handler
-	dbAccess   *DbAccess            // access to db storage counter and iterator for syncing
-	requestDb  *storage.LDBDatabase // db to persist backlog of deliveries to aid syncing
-	remoteAddr *peerAddr            // remote peers address
-	peer       *p2p.Peer            // the p2p peer object
-	rw         p2p.MsgReadWriter    // messageReadWriter to send messages to
+	hive       *Hive             // the logistic manager, peerPool, routing service and peer handler
+	remoteAddr *peerAddr         // remote peers address
+	peer       *p2p.Peer         // the p2p peer object
+	rw         p2p.MsgReadWriter // messageReadWriter to send messages to
 	backend    chequebook.Backend
 	lastActive time.Time
 	NetworkId  uint64
@@ -83,20 +79,6 @@ type bzz struct {
 	swap        *swap.Swap          // swap instance for the peer connection
 	swapParams  *bzzswap.SwapParams // swap settings both local and remote
 	swapEnabled bool                // flag to enable SWAP (will be set via Caps in handshake)
-	syncEnabled bool                // flag to enable SYNC (will be set via Caps in handshake)
-	syncer      *syncer             // syncer instance for the peer connection
-	syncParams  *SyncParams         // syncer params
-	syncState   *syncState          // outgoing syncronisation state (contains reference to remote peers db counter)
-}
-
-// interface type for handler of storage/retrieval related requests coming
-// via the bzz wire protocol
-// messages: UnsyncedKeys, DeliveryRequest, StoreRequest, RetrieveRequest
-type StorageHandler interface {
-	HandleUnsyncedKeysMsg(req *unsyncedKeysMsgData, p *peer) error
-	HandleDeliveryRequestMsg(req *deliveryRequestMsgData, p *peer) error
-	HandleStoreRequestMsg(req *storeRequestMsgData, p *peer)
-	HandleRetrieveRequestMsg(req *retrieveRequestMsgData, p *peer)
 }
 
 /*
@@ -110,14 +92,7 @@ on each peer connection
 The Run function of the Bzz protocol class creates a bzz instance
 which will represent the peer for the swarm hive and all peer-aware components
 */
-func Bzz(cloud StorageHandler, backend chequebook.Backend, hive *Hive, dbaccess *DbAccess, sp *bzzswap.SwapParams, sy *SyncParams, networkId uint64) (p2p.Protocol, error) {
-
-	// a single global request db is created for all peer connections
-	// this is to persist delivery backlog and aid syncronisation
-	requestDb, err := storage.NewLDBDatabase(sy.RequestDbPath)
-	if err != nil {
-		return p2p.Protocol{}, fmt.Errorf("error setting up request db: %v", err)
-	}
+func Bzz(backend chequebook.Backend, hive *Hive, sp *bzzswap.SwapParams, networkId uint64) (p2p.Protocol, error) {
 	if networkId == 0 {
 		networkId = NetworkId
 	}
@@ -126,7 +101,7 @@ func Bzz(cloud StorageHandler, backend chequebook.Backend, hive *Hive, dbaccess
 		Version: Version,
 		Length:  ProtocolLength,
 		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
-			return run(requestDb, cloud, backend, hive, dbaccess, sp, sy, networkId, p, rw)
+			return run(backend, hive, sp, networkId, p, rw)
 		},
 	}, nil
 }
@@ -136,27 +111,22 @@ the main protocol loop that
  * does the handshake by exchanging statusMsg
  * if peer is valid and accepted, registers with the hive
  * then enters into a forever loop handling incoming messages
- * storage and retrieval related queries coming via bzz are dispatched to StorageHandler
+ * lookups are answered with peers
  * peer-related messages are dispatched to the hive
  * payment related messages are relayed to SWAP service
  * on disconnect, unregister the peer in the hive (note RemovePeer in the post-disconnect hook)
  * whenever the loop terminates, the peer will disconnect with Subprotocol error
  * whenever handlers return an error the loop terminates
 */
-func run(requestDb *storage.LDBDatabase, depo StorageHandler, backend chequebook.Backend, hive *Hive, dbaccess *DbAccess, sp *bzzswap.SwapParams, sy *SyncParams, networkId uint64, p *p2p.Peer, rw p2p.MsgReadWriter) (err error) {
+func run(backend chequebook.Backend, hive *Hive, sp *bzzswap.SwapParams, networkId uint64, p *p2p.Peer, rw p2p.MsgReadWriter) (err error) {
 
 	self := &bzz{
-		storage:     depo,
 		backend:     backend,
 		hive:        hive,
-		dbAccess:    dbaccess,
-		requestDb:   requestDb,
 		peer:        p,
 		rw:          rw,
 		swapParams:  sp,
-		syncParams:  sy,
 		swapEnabled: hive.swapEnabled,
-		syncEnabled: true,
 		NetworkId:   networkId,
 	}
 
@@ -169,9 +139,6 @@ func run(requestDb *storage.LDBDatabase, depo StorageHandler, backend chequebook
 		// if the handler loop exits, the peer is disconnecting
 		// deregister the peer in the hive
 		self.hive.removePeer(&peer{bzz: self})
-		if self.syncer != nil {
-			self.syncer.stop() // quits request db and delivery loops, save requests
-		}
 		if self.swap != nil {
 			self.swap.Stop() // quits chequebox autocash etc
 		}
@@ -218,40 +185,15 @@ func (self *bzz) handle() error {
 		log.Debug(fmt.Sprintf("Status message: %v", msg))
 		return errors.New("extra status message")
 
-	case storeRequestMsg:
-		// store requests are dispatched to neaaeore
-		storeRequestMsgCounter.Inc(1)
-		var req storeRequestMsgData
-		if err := msg.Decode(&req); err != nil {
-			return fmt.Errorf("<- %v: %v", msg, err)
-		}
-		if n := len(req.SData); n < 9 {
-			return fmt.Errorf("<- %v: Data too short (%v)", msg, n)
-		}
-		// last Active time is set only when receiving chunks
-		self.lastActive = time.Now()
-		log.Trace(fmt.Sprintf("incoming store request: %s", req.String()))
-		// swap accounting is done within forwarding
-		self.storage.HandleStoreRequestMsg(&req, &peer{bzz: self})
-
 	case retrieveRequestMsg:
-		// retrieve Requests are dispatched to neaaeore
+		// retrieve requests are lookups, chunks are retrieved via the stream protocol
 		retrieveRequestMsgCounter.Inc(1)
 		var req retrieveRequestMsgData
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("<- %v: %v", msg, err)
 		}
 		req.from = &peer{bzz: self}
-		// if request is lookup and not to be delivered
-		if req.isLookup() {
-			log.Trace(fmt.Sprintf("self lookup for %v: responding with peers only...", req.from))
-		} else if req.Key == nil {
-			return fmt.Errorf("protocol handler: req.Key == nil || req.Timeout == nil")
-		} else {
-			// swap accounting is done within neaaeore
-			self.storage.HandleRetrieveRequestMsg(&req, &peer{bzz: self})
-		}
-		// direct response with peers, TODO: sort this out
+		log.Trace(fmt.Sprintf("lookup for %v: responding with peers", req.from))
 		self.hive.peers(&req)
 
 	case peersMsg:
@@ -266,45 +208,6 @@ func (self *bzz) handle() error {
 		log.Trace(fmt.Sprintf("<- peer addresses: %v", req))
 		self.hive.HandlePeersMsg(&req, &peer{bzz: self})
 
-	case syncRequestMsg:
-		syncRequestMsgCounter.Inc(1)
-		var req syncRequestMsgData
-		if err := msg.Decode(&req); err != nil {
-			return fmt.Errorf("<- %v: %v", msg, err)
-		}
-		log.Debug(fmt.Sprintf("<- sync request: %v", req))
-		self.lastActive = time.Now()
-		self.sync(req.SyncState)
-
-	case unsyncedKeysMsg:
-		// coming from parent node offering
-		unsyncedKeysMsgCounter.Inc(1)
-		var req unsyncedKeysMsgData
-		if err := msg.Decode(&req); err != nil {
-			return fmt.Errorf("<- %v: %v", msg, err)
-		}
-		log.Debug(fmt.Sprintf("<- unsynced keys : %s", req.String()))
-		err := self.storage.HandleUnsyncedKeysMsg(&req, &peer{bzz: self})
-		self.lastActive = time.Now()
-		if err != nil {
-			return fmt.Errorf("<- %v: %v", msg, err)
-		}
-
-	case deliveryRequestMsg:
-		// response to syncKeysMsg hashes filtered not existing in db
-		// also relays the last synced state to the source
-		deliverRequestMsgCounter.Inc(1)
-		var req deliveryRequestMsgData
-		if err := msg.Decode(&req); err != nil {
-			return fmt.Errorf("<-msg %v: %v", msg, err)
-		}
-		log.Debug(fmt.Sprintf("<- delivery request: %s", req.String()))
-		err := self.storage.HandleDeliveryRequestMsg(&req, &peer{bzz: self})
-		self.lastActive = time.Now()
-		if err != nil {
-			return fmt.Errorf("<- %v: %v", msg, err)
-		}
-
 	case paymentMsg:
 		// swap protocol message for payment, Units paid for, Cheque paid with
 		paymentMsgCounter.Inc(1)
@@ -317,6 +220,16 @@ func (self *bzz) handle() error {
 			self.swap.Receive(int(req.Units), req.Promise)
 		}
 
+	case pssMsg:
+		// routed messages are relayed to the hive
+		pssMsgCounter.Inc(1)
+		var req PssMsg
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("<- %v: %v", msg, err)
+		}
+		log.Trace(fmt.Sprintf("<- %s", req.String()))
+		self.hive.HandlePssMsg(&req, &peer{bzz: self})
+
 	default:
 		// no other message is allowed
 		invalidMsgCounter.Inc(1)
@@ -385,55 +298,7 @@ func (self *bzz) handleStatus() (err error) {
 	}
 
 	log.Info(fmt.Sprintf("Peer %08x is capable (%d/%d)", self.remoteAddr.Addr[:4], status.Version, status.NetworkId))
-	err = self.hive.addPeer(&peer{bzz: self})
-	if err != nil {
-		return err
-	}
-
-	// hive sets syncstate so sync should start after node added
-	log.Info(fmt.Sprintf("syncronisation request sent with %v", self.syncState))
-	self.syncRequest()
-
-	return nil
-}
-
-func (self *bzz) sync(state *syncState) error {
-	// syncer setup
-	if self.syncer != nil {
-		return errors.New("sync request can only be sent once")
-	}
-
-	cnt := self.dbAccess.counter()
-	remoteaddr := self.remoteAddr.Addr
-	start, stop := self.hive.kad.KeyRange(remoteaddr)
-
-	// an explicitly received nil syncstate disables syncronisation
-	if state == nil {
-		self.syncEnabled = false
-		log.Warn(fmt.Sprintf("syncronisation disabled for peer %v", self))
-		state = &syncState{DbSyncState: &storage.DbSyncState{}, Synced: true}
-	} else {
-		state.synced = make(chan bool)
-		state.SessionAt = cnt
-		if storage.IsZeroKey(state.Stop) && state.Synced {
-			state.Start = storage.Key(start[:])
-			state.Stop = storage.Key(stop[:])
-		}
-		log.Debug(fmt.Sprintf("syncronisation requested by peer %v at state %v", self, state))
-	}
-	var err error
-	self.syncer, err = newSyncer(
-		self.requestDb,
-		storage.Key(remoteaddr[:]),
-		self.dbAccess,
-		self.unsyncedKeys, self.store,
-		self.syncParams, state, func() bool { return self.syncEnabled },
-	)
-	if err != nil {
-		return nil
-	}
-	log.Trace(fmt.Sprintf("syncer set for peer %v", self))
-	return nil
+	return self.hive.addPeer(&peer{bzz: self})
 }
 
 func (self *bzz) String() string {
@@ -471,40 +336,6 @@ func (self *bzz) retrieve(req *retrieveRequestMsgData) error {
 	return self.send(retrieveRequestMsg, req)
 }
 
-// send storeRequestMsg
-func (self *bzz) store(req *storeRequestMsgData) error {
-	return self.send(storeRequestMsg, req)
-}
-
-func (self *bzz) syncRequest() error {
-	req := &syncRequestMsgData{}
-	if self.hive.syncEnabled {
-		log.Debug(fmt.Sprintf("syncronisation request to peer %v at state %v", self, self.syncState))
-		req.SyncState = self.syncState
-	}
-	if self.syncState == nil {
-		log.Warn(fmt.Sprintf("syncronisation disabled for peer %v at state %v", self, self.syncState))
-	}
-	return self.send(syncRequestMsg, req)
-}
-
-// queue storeRequestMsg in request db
-func (self *bzz) deliveryRequest(reqs []*syncRequest) error {
-	req := &deliveryRequestMsgData{
-		Deliver: reqs,
-	}
-	return self.send(deliveryRequestMsg, req)
-}
-
-// batch of syncRequests to send off
-func (self *bzz) unsyncedKeys(reqs []*syncRequest, state *syncState) error {
-	req := &unsyncedKeysMsgData{
-		Unsynced: reqs,
-		State:    state,
-	}
-	return self.send(unsyncedKeysMsg, req)
-}
-
 // send paymentMsg
 func (self *bzz) Pay(units int, promise swap.Promise) {
 	req := &paymentMsgData{uint(units), promise.(*chequebook.Cheque)}
@@ -516,6 +347,11 @@ func (self *bzz) payment(req *paymentMsgData) error {
 	return self.send(paymentMsg, req)
 }
 
+// sends pssMsg
+func (self *bzz) pss(req *PssMsg) error {
+	return self.send(pssMsg, req)
+}
+
 // sends peersMsg
 func (self *bzz) peers(req *peersMsgData) error {
 	return self.send(peersMsg, req)
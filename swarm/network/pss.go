@@ -0,0 +1,212 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+)
+
+const (
+	pssForwardPeers   = 3                // number of closer peers a pss message is relayed to
+	pssSeenTTL        = 10 * time.Minute // how long relayed pss messages are remembered
+	pssSeenBuckets    = 10               // number of time buckets the relay cache is split into
+	pssSeenBucketSize = 10000            // maximum number of messages remembered per bucket
+)
+
+// PssHandler is called with the pss messages addressed to this node
+type PssHandler func(msg *PssMsg) error
+
+// PssValidator checks a pss message received from a peer before it is
+// delivered or relayed, messages failing the check are dropped
+type PssValidator func(msg *PssMsg) error
+
+// SetPssHandler sets the handler receiving the pss messages addressed to us
+func (self *Hive) SetPssHandler(handler PssHandler) {
+	self.pssLock.Lock()
+	defer self.pssLock.Unlock()
+	self.pssHandler = handler
+}
+
+// SetPssValidator sets the check the pss messages received from peers have
+// to pass before they are delivered or relayed
+func (self *Hive) SetPssValidator(validator PssValidator) {
+	self.pssLock.Lock()
+	defer self.pssLock.Unlock()
+	self.pssValidator = validator
+}
+
+// SendPss routes a pss message originating from this node towards its
+// destination
+func (self *Hive) SendPss(msg *PssMsg) error {
+	if len(msg.To) > len(self.addr) {
+		return fmt.Errorf("pss destination address too long: %d bytes", len(msg.To))
+	}
+	if !self.pssFirstSeen(msg) {
+		return fmt.Errorf("pss message already sent")
+	}
+	self.routePss(msg, nil)
+	return nil
+}
+
+// HandlePssMsg is called by the protocol when receiving a pss message from
+// a peer. The message is delivered locally if we are a recipient and
+// relayed towards the destination otherwise
+func (self *Hive) HandlePssMsg(msg *PssMsg, from *peer) {
+	if len(msg.To) > len(self.addr) {
+		log.Debug(fmt.Sprintf("dropping pss message from %v: destination address too long", from))
+		return
+	}
+	if !self.pssFirstSeen(msg) {
+		log.Trace(fmt.Sprintf("dropping pss message from %v: already seen", from))
+		return
+	}
+	self.pssLock.Lock()
+	validator := self.pssValidator
+	self.pssLock.Unlock()
+	if validator != nil {
+		if err := validator(msg); err != nil {
+			log.Debug(fmt.Sprintf("dropping pss message from %v: %v", from, err))
+			return
+		}
+	}
+	self.routePss(msg, from)
+}
+
+// routePss delivers the message if our address matches the destination and
+// forwards it to the connected peers that are closer to the destination
+// than we are. Within the matching neighbourhood of a partial address the
+// message is passed on to every matching peer.
+func (self *Hive) routePss(msg *PssMsg, from *peer) {
+	var to kademlia.Address
+	copy(to[:], msg.To)
+
+	local := bytes.HasPrefix(self.addr[:], msg.To)
+	if local {
+		self.pssLock.Lock()
+		handler := self.pssHandler
+		self.pssLock.Unlock()
+		if handler != nil {
+			if err := handler(msg); err != nil {
+				log.Debug(fmt.Sprintf("pss handler: %v", err))
+			}
+		}
+		if len(msg.To) == len(self.addr) {
+			return
+		}
+	}
+
+	max := pssForwardPeers
+	if local {
+		// all matching peers are candidates in our own neighbourhood
+		max = self.kad.Count()
+	}
+	var sent int
+	for _, p := range self.getPeers(storage.Key(to[:]), max) {
+		if from != nil && p.Addr() == from.Addr() {
+			continue
+		}
+		addr := p.Addr()
+		if local {
+			if !bytes.HasPrefix(addr[:], msg.To) {
+				continue
+			}
+		} else if to.ProxCmp(addr, self.addr) >= 0 && !bytes.HasPrefix(addr[:], msg.To) {
+			continue
+		}
+		if err := p.pss(msg); err != nil {
+			log.Debug(fmt.Sprintf("pss forward to %v: %v", p, err))
+			continue
+		}
+		sent++
+	}
+	log.Trace(fmt.Sprintf("pss message to %x relayed to %d peers", msg.To, sent))
+}
+
+// pssFirstSeen records the message in the relay cache and reports whaaeer
+// it was not seen before
+func (self *Hive) pssFirstSeen(msg *PssMsg) bool {
+	hash := crypto.Keccak256Hash(msg.To, msg.Payload)
+
+	self.pssLock.Lock()
+	defer self.pssLock.Unlock()
+	return self.pssSeen.add(hash, time.Now())
+}
+
+// pssCache remembers the hashes of relayed pss messages in buckets covering
+// consecutive periods of pssSeenTTL/pssSeenBuckets. Whole buckets expire at
+// once, so the cache is never swept entry by entry. A bucket holds at most
+// pssSeenBucketSize hashes, a full bucket is closed early which shortens the
+// time messages are remembered under load rather than growing the cache.
+type pssCache struct {
+	buckets [pssSeenBuckets]map[common.Hash]struct{} // newest first
+	start   time.Time                                // start of the newest bucket
+}
+
+func newPssCache() *pssCache {
+	c := &pssCache{}
+	for i := range c.buckets {
+		c.buckets[i] = make(map[common.Hash]struct{})
+	}
+	return c
+}
+
+// add records hash at time now and reports whaaeer it was not in the cache
+func (self *pssCache) add(hash common.Hash, now time.Time) bool {
+	if expired := int(now.Sub(self.start) / (pssSeenTTL / pssSeenBuckets)); expired > 0 {
+		self.rotate(expired)
+		self.start = now
+	}
+	for _, bucket := range self.buckets {
+		if _, ok := bucket[hash]; ok {
+			return false
+		}
+	}
+	if len(self.buckets[0]) >= pssSeenBucketSize {
+		self.rotate(1)
+		self.start = now
+	}
+	self.buckets[0][hash] = struct{}{}
+	return true
+}
+
+// rotate drops the n oldest buckets and opens as many new ones
+func (self *pssCache) rotate(n int) {
+	if n > pssSeenBuckets {
+		n = pssSeenBuckets
+	}
+	copy(self.buckets[n:], self.buckets[:pssSeenBuckets-n])
+	for i := 0; i < n; i++ {
+		self.buckets[i] = make(map[common.Hash]struct{})
+	}
+}
+
+// len returns the number of hashes in the cache
+func (self *pssCache) len() int {
+	var n int
+	for _, bucket := range self.buckets {
+		n += len(bucket)
+	}
+	return n
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aaechain/go-aaechain/common"
+)
+
+func TestPssCache(t *testing.T) {
+	cache := newPssCache()
+	now := time.Now()
+	span := pssSeenTTL / pssSeenBuckets
+
+	a, b := common.Hash{1}, common.Hash{2}
+	if !cache.add(a, now) {
+		t.Fatal("new message reported as seen")
+	}
+	if cache.add(a, now.Add(span)) {
+		t.Fatal("message not remembered")
+	}
+	cache.add(b, now.Add(pssSeenTTL-span))
+	if cache.add(a, now.Add(pssSeenTTL-span)) {
+		t.Fatal("message forgotten before its ttl")
+	}
+
+	// whole buckets expire
+	if !cache.add(a, now.Add(pssSeenTTL+span)) {
+		t.Fatal("message remembered after its ttl")
+	}
+	if cache.add(b, now.Add(pssSeenTTL+span)) {
+		t.Fatal("later message forgotten with the earlier bucket")
+	}
+	if !cache.add(common.Hash{3}, now.Add(3*pssSeenTTL)) || cache.len() != 1 {
+		t.Fatalf("cache not emptied after its ttl: %d entries", cache.len())
+	}
+
+	// the size of the cache is bounded
+	now = now.Add(3 * pssSeenTTL)
+	for i := 0; i < 2*pssSeenBuckets*pssSeenBucketSize; i++ {
+		var hash common.Hash
+		hash[0], hash[1], hash[2] = byte(i), byte(i>>8), byte(i>>16)
+		cache.add(hash, now)
+	}
+	if max := pssSeenBuckets * pssSeenBucketSize; cache.len() > max {
+		t.Fatalf("cache size %d exceeds %d", cache.len(), max)
+	}
+}
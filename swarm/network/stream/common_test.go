@@ -0,0 +1,231 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/node"
+	"github.com/aaechain/go-aaechain/p2p"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/simulations"
+	"github.com/aaechain/go-aaechain/p2p/simulations/adapters"
+	"github.com/aaechain/go-aaechain/rpc"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+)
+
+const testMaxProx = 8
+
+// testOverlay is a static overlay, the depth given as testMaxProx+1 keeps
+// nodes out of each other's nearest neighbourhood
+type testOverlay struct {
+	addr    kademlia.Address
+	depth   int
+	maxProx int
+	syncing bool
+
+	lock     sync.Mutex
+	balances map[discover.NodeID]int // swap balances of the peers
+}
+
+func (self *testOverlay) Addr() kademlia.Address { return self.addr }
+func (self *testOverlay) Depth() int             { return self.depth }
+func (self *testOverlay) MaxProx() int           { return self.maxProx }
+func (self *testOverlay) Syncing() bool          { return self.syncing }
+
+func (self *testOverlay) Account(id discover.NodeID, units int) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.balances == nil {
+		self.balances = make(map[discover.NodeID]int)
+	}
+	self.balances[id] += units
+	return nil
+}
+
+func (self *testOverlay) balance(id discover.NodeID) int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.balances[id]
+}
+
+// testNode runs the stream protocol on a temporary store
+type testNode struct {
+	dir        string
+	overlay    *testOverlay
+	localStore *storage.LocalStore
+	netStore   *storage.Neaaeore
+	registry   *Registry
+}
+
+func newTestNode(overlay *testOverlay) (*testNode, error) {
+	dir, err := ioutil.TempDir("", "swarm-stream")
+	if err != nil {
+		return nil, err
+	}
+	hash := storage.MakeHashFunc("SHA3")
+	storeParams := storage.NewDefaulaaeoreParams()
+	storeParams.Init(dir)
+	localStore, err := storage.NewLocalStore(hash, storeParams)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	params := NewDefaultSyncParams()
+	params.Init(dir)
+	params.SyncInterval = uint64(50 * time.Millisecond)
+	registry, err := NewRegistry(overlay, localStore, hash, params)
+	if err != nil {
+		localStore.DbStore.Close()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	netStore := storage.NewNeaaeore(hash, localStore, registry, storeParams)
+	registry.SetNetStore(netStore)
+	return &testNode{
+		dir:        dir,
+		overlay:    overlay,
+		localStore: localStore,
+		netStore:   netStore,
+		registry:   registry,
+	}, nil
+}
+
+func (self *testNode) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{self.registry.Protocol()}
+}
+
+func (self *testNode) APIs() []rpc.API {
+	return nil
+}
+
+func (self *testNode) Start(server *p2p.Server) error {
+	return nil
+}
+
+func (self *testNode) Stop() error {
+	self.registry.Close()
+	self.localStore.DbStore.Close()
+	return os.RemoveAll(self.dir)
+}
+
+// has returns true if the chunk is found in the local store of the node
+func (self *testNode) has(key storage.Key) bool {
+	chunk, err := self.localStore.Get(key)
+	return err == nil && chunk.SData != nil
+}
+
+func (self *testNode) peerCount() int {
+	self.registry.peersLock.RLock()
+	defer self.registry.peersLock.RUnlock()
+	return len(self.registry.peers)
+}
+
+// testNetwork is a simulation network of testNodes
+type testNetwork struct {
+	*simulations.Network
+	lock  sync.Mutex
+	nodes map[discover.NodeID]*testNode
+}
+
+// newTestNetwork starts a simulation network of n nodes with overlay
+// addresses derived from their node ids
+func newTestNetwork(t *testing.T, n int, depth int, syncing bool) (*testNetwork, []discover.NodeID) {
+	net := &testNetwork{nodes: make(map[discover.NodeID]*testNode)}
+	adapter := adapters.NewSimAdapter(adapters.Services{
+		"stream": func(ctx *adapters.ServiceContext) (node.Service, error) {
+			id := ctx.Config.ID
+			n, err := newTestNode(&testOverlay{
+				addr:    kademlia.Address(crypto.Keccak256Hash(id[:])),
+				depth:   depth,
+				maxProx: testMaxProx,
+				syncing: syncing,
+			})
+			if err != nil {
+				return nil, err
+			}
+			net.lock.Lock()
+			net.nodes[id] = n
+			net.lock.Unlock()
+			return n, nil
+		},
+	})
+	net.Network = simulations.NewNetwork(adapter, &simulations.NetworkConfig{
+		DefaultService: "stream",
+	})
+	ids := make([]discover.NodeID, n)
+	for i := 0; i < n; i++ {
+		node, err := net.NewNode()
+		if err != nil {
+			net.Shutdown()
+			t.Fatalf("error creating node: %v", err)
+		}
+		if err := net.Start(node.ID()); err != nil {
+			net.Shutdown()
+			t.Fatalf("error starting node: %v", err)
+		}
+		ids[i] = node.ID()
+	}
+	return net, ids
+}
+
+func (self *testNetwork) node(id discover.NodeID) *testNode {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.nodes[id]
+}
+
+// storeRandomChunks stores n chunks of random data in the db store
+func storeRandomChunks(t *testing.T, localStore *storage.LocalStore, n int) []storage.Key {
+	hash := storage.MakeHashFunc("SHA3")
+	keys := make([]storage.Key, n)
+	for i := 0; i < n; i++ {
+		data := make([]byte, 8+64)
+		binary.LittleEndian.PutUint64(data, 64)
+		if _, err := rand.Read(data[8:]); err != nil {
+			t.Fatal(err)
+		}
+		hasher := hash()
+		hasher.Write(data)
+		chunk := storage.NewChunk(hasher.Sum(nil), nil)
+		chunk.SData = data
+		chunk.Size = 64
+		localStore.DbStore.Put(chunk)
+		keys[i] = chunk.Key
+	}
+	return keys
+}
+
+// waitFor polls the condition until it holds or the timeout expires
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return cond()
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"fmt"
+
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/metrics"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+)
+
+// metrics variables
+var (
+	retrieveRequestCounter  = metrics.NewRegisteredCounter("network.stream.retrieve.count", nil)
+	chunkDeliveryCounter    = metrics.NewRegisteredCounter("network.stream.delivery.count", nil)
+	invalidChunkCounter     = metrics.NewRegisteredCounter("network.stream.delivery.invalid", nil)
+	unsolicitedChunkCounter = metrics.NewRegisteredCounter("network.stream.delivery.unsolicited", nil)
+)
+
+// Store is a noop, chunks are spread by the peers syncing the bins of their
+// neighbourhood
+func (self *Registry) Store(chunk *storage.Chunk) {
+}
+
+// Retrieve sends a retrieve request for the chunk to the peer closest to it
+// among the peers not requesting it themselves and whose SWAP balance allows
+// the request to be paid for
+func (self *Registry) Retrieve(chunk *storage.Chunk) {
+	skip := make(map[*Peer]bool)
+	for _, p := range requesters(chunk) {
+		skip[p] = true
+	}
+	for {
+		p := self.closestPeer(chunk.Key, func(p *Peer) bool { return skip[p] })
+		if p == nil {
+			log.Trace(fmt.Sprintf("stream: no peer to retrieve %v from", chunk.Key.Log()))
+			return
+		}
+		if err := self.overlay.Account(p.ID(), -1); err != nil {
+			log.Warn(fmt.Sprintf("stream: unable to send retrieve request for %v to %v: %v", chunk.Key.Log(), p, err))
+			skip[p] = true
+			continue
+		}
+		log.Trace(fmt.Sprintf("stream: retrieving %v from %v", chunk.Key.Log(), p))
+		if err := p.Send(&RetrieveRequestMsg{Key: chunk.Key}); err != nil {
+			log.Warn(fmt.Sprintf("stream: retrieve request for %v to %v failed: %v", chunk.Key.Log(), p, err))
+		}
+		return
+	}
+}
+
+// Deliver delivers the retrieved chunk to the peers requesting it
+func (self *Registry) Deliver(chunk *storage.Chunk) {
+	for _, p := range requesters(chunk) {
+		log.Trace(fmt.Sprintf("stream: delivering %v to %v", chunk.Key.Log(), p))
+		if err := p.Send(&ChunkDeliveryMsg{Key: chunk.Key, SData: chunk.SData}); err != nil {
+			log.Warn(fmt.Sprintf("stream: delivery of %v to %v failed: %v", chunk.Key.Log(), p, err))
+		}
+	}
+}
+
+// requesters returns the peers requesting the chunk
+func requesters(chunk *storage.Chunk) (peers []*Peer) {
+	for _, req := range chunk.Req.RequesterList() {
+		if p, ok := req.(*Peer); ok {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// handleRetrieveRequestMsg credits the SWAP balance of the peer and delivers
+// the requested chunk if found locally, otherwise the peer is registered as
+// requester of the chunk retrieved from the swarm by the net store
+func (self *Registry) handleRetrieveRequestMsg(req *RetrieveRequestMsg, p *Peer) error {
+	retrieveRequestCounter.Inc(1)
+	if err := self.overlay.Account(p.ID(), 1); err != nil {
+		log.Warn(fmt.Sprintf("stream: cannot process retrieve request for %v from %v: %v", req.Key.Log(), p, err))
+		return nil
+	}
+	self.reqLock.Lock()
+	chunk, err := self.netStore.GetFor(req.Key, p)
+	if err != nil {
+		self.reqLock.Unlock()
+		return nil
+	}
+	if chunk.SData == nil {
+		self.reqLock.Unlock()
+		log.Trace(fmt.Sprintf("stream: %v requested by %v not found locally, retrieving", req.Key.Log(), p))
+		return nil
+	}
+	self.reqLock.Unlock()
+	p.send(&ChunkDeliveryMsg{Key: chunk.Key, SData: chunk.SData})
+	return nil
+}
+
+// handleChunkDeliveryMsg stores a valid chunk delivered by the peer, either
+// wanted while syncing or requested, completing the pending retrieval. Peers
+// delivering invalid chunks are dropped, chunks neither wanted nor requested
+// are ignored
+func (self *Registry) handleChunkDeliveryMsg(req *ChunkDeliveryMsg, p *Peer) error {
+	chunkDeliveryCounter.Inc(1)
+	if !storage.ValidChunk(self.hashfunc, req.Key, req.SData) {
+		invalidChunkCounter.Inc(1)
+		return fmt.Errorf("invalid chunk %v delivered", req.Key.Log())
+	}
+	wanted := p.wants(req.Key)
+	defer p.delivered(req.Key)
+
+	chunk, err := self.localStore.Get(req.Key)
+	self.reqLock.Lock()
+	if err == nil && chunk.SData != nil {
+		// found locally or delivered by another peer already
+		self.reqLock.Unlock()
+		return nil
+	}
+	if err != nil || chunk.Req == nil {
+		if !wanted {
+			self.reqLock.Unlock()
+			unsolicitedChunkCounter.Inc(1)
+			log.Debug(fmt.Sprintf("stream: ignoring chunk %v delivered by %v: neither wanted nor requested", req.Key.Log(), p))
+			return nil
+		}
+		chunk = storage.NewChunk(req.Key, nil)
+	}
+	chunk.SData = req.SData
+	chunk.Size = storage.ChunkSpan(req.SData)
+	chunk.Source = p
+	self.reqLock.Unlock()
+	self.netStore.Put(chunk)
+	return nil
+}
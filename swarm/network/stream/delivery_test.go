@@ -0,0 +1,183 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aaechain/go-aaechain/p2p/simulations/adapters"
+	p2ptest "github.com/aaechain/go-aaechain/p2p/testing"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+)
+
+// TestRetrieveSimulation retrieves a chunk stored at the end of a chain of
+// nodes not syncing with each other
+func TestRetrieveSimulation(t *testing.T) {
+	net, ids := newTestNetwork(t, 3, 0, false)
+	defer net.Shutdown()
+	for i := 0; i < len(ids)-1; i++ {
+		if err := net.Connect(ids[i], ids[i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := net.node(ids[0])
+	last := net.node(ids[len(ids)-1])
+	keys := storeRandomChunks(t, last.localStore, 1)
+
+	// wait for the stream protocol to run on both connections
+	ok := waitFor(5*time.Second, func() bool {
+		return first.peerCount() == 1 && net.node(ids[1]).peerCount() == 2
+	})
+	if !ok {
+		t.Fatal("peers not connected")
+	}
+	chunk, err := first.netStore.Get(keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.Req != nil {
+		select {
+		case <-chunk.Req.C:
+		case <-time.After(5 * time.Second):
+			t.Fatal("chunk not retrieved")
+		}
+	}
+	if !first.has(keys[0]) {
+		t.Fatal("retrieved chunk not stored")
+	}
+
+	// the retrieve requests are accounted on both connections
+	if b := first.overlay.balance(ids[1]); b != -1 {
+		t.Fatalf("expected balance -1 of the first node with the middle one, got %d", b)
+	}
+	if b := last.overlay.balance(ids[1]); b != 1 {
+		t.Fatalf("expected balance 1 of the last node with the middle one, got %d", b)
+	}
+}
+
+// TestRetrieveRequest checks that a requested chunk found locally is
+// delivered
+func TestRetrieveRequest(t *testing.T) {
+	overlay := &testOverlay{addr: kademlia.RandomAddress()}
+	pivot, err := newTestNode(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pivot.Stop()
+	keys := storeRandomChunks(t, pivot.localStore, 1)
+	chunk, err := pivot.localStore.Get(keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := p2ptest.NewProtocolTester(t, adapters.RandomNodeConfig().ID, 1, pivot.registry.run)
+	defer s.Stop()
+	id := s.IDs[0]
+	err = s.TestExchanges(
+		p2ptest.Exchange{
+			Label: "handshake",
+			Expects: []p2ptest.Expect{
+				{Code: 0, Msg: &HandshakeMsg{Addr: overlay.addr}, Peer: id},
+			},
+		},
+		p2ptest.Exchange{
+			Label: "retrieve",
+			Triggers: []p2ptest.Trigger{
+				{Code: 0, Msg: &HandshakeMsg{Addr: kademlia.RandomAddress()}, Peer: id},
+				{Code: 5, Msg: &RetrieveRequestMsg{Key: chunk.Key}, Peer: id},
+			},
+			Expects: []p2ptest.Expect{
+				{Code: 4, Msg: &ChunkDeliveryMsg{Key: chunk.Key, SData: chunk.SData}, Peer: id},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := overlay.balance(id); b != 1 {
+		t.Fatalf("expected balance 1 after serving the request, got %d", b)
+	}
+}
+
+// TestChunkDelivery checks that unsolicited chunks are ignored and that peers
+// delivering invalid chunks are dropped
+func TestChunkDelivery(t *testing.T) {
+	overlay := &testOverlay{addr: kademlia.RandomAddress()}
+	pivot, err := newTestNode(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pivot.Stop()
+
+	// a valid chunk is generated on another store
+	other, err := newTestNode(&testOverlay{addr: kademlia.RandomAddress()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Stop()
+	keys := storeRandomChunks(t, other.localStore, 1)
+	chunk, err := other.localStore.Get(keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := p2ptest.NewProtocolTester(t, adapters.RandomNodeConfig().ID, 1, pivot.registry.run)
+	defer s.Stop()
+	id := s.IDs[0]
+	err = s.TestExchanges(
+		p2ptest.Exchange{
+			Label: "handshake",
+			Expects: []p2ptest.Expect{
+				{Code: 0, Msg: &HandshakeMsg{Addr: overlay.addr}, Peer: id},
+			},
+		},
+		p2ptest.Exchange{
+			Label: "unsolicited delivery",
+			Triggers: []p2ptest.Trigger{
+				{Code: 0, Msg: &HandshakeMsg{Addr: kademlia.RandomAddress()}, Peer: id},
+				{Code: 4, Msg: &ChunkDeliveryMsg{Key: chunk.Key, SData: chunk.SData}, Peer: id},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waitFor(500*time.Millisecond, func() bool { return pivot.has(chunk.Key) }) {
+		t.Fatal("unsolicited chunk stored")
+	}
+
+	invalid := append([]byte{}, chunk.SData...)
+	invalid[len(invalid)-1]++
+	err = s.TestExchanges(p2ptest.Exchange{
+		Label: "invalid delivery",
+		Triggers: []p2ptest.Trigger{
+			{Code: 4, Msg: &ChunkDeliveryMsg{Key: chunk.Key, SData: invalid}, Peer: id},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.TestDisconnected(&p2ptest.Disconnect{
+		Peer:  id,
+		Error: fmt.Errorf("Message handler error: (msg code 4): invalid chunk %v delivered", chunk.Key.Log()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"fmt"
+
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+)
+
+// names of the streams served by peers
+const (
+	SyncStream = "SYNC" // chunks stored by the peer in a proximity bin
+)
+
+// Stream identifies a stream served by a peer, Key selects the proximity bin
+// of SYNC streams
+type Stream struct {
+	Name string
+	Key  uint8
+}
+
+func (self Stream) String() string {
+	return fmt.Sprintf("%s|%d", self.Name, self.Key)
+}
+
+// HandshakeMsg is exchanged by peers on connection, advertising their overlay
+// address
+type HandshakeMsg struct {
+	Addr kademlia.Address
+}
+
+func (self *HandshakeMsg) String() string {
+	return fmt.Sprintf("Handshake: Addr: %v", self.Addr)
+}
+
+// SubscribeMsg requests a stream from the peer, starting at the cursor
+// persisted from an earlier session, i.e. the storage index of the peer up to
+// which the stream was synced
+type SubscribeMsg struct {
+	Stream Stream
+	Cursor uint64
+}
+
+func (self *SubscribeMsg) String() string {
+	return fmt.Sprintf("Subscribe: Stream: %v, Cursor: %v", self.Stream, self.Cursor)
+}
+
+// OfferedHashesMsg offers a batch of the hashes of the chunks of a stream
+// stored with an index in the interval [From, To). Last marks the last batch
+// of the interval, after which To is the cursor of the stream
+type OfferedHashesMsg struct {
+	Stream   Stream
+	From, To uint64
+	Hashes   []byte
+	Last     bool
+}
+
+func (self *OfferedHashesMsg) String() string {
+	return fmt.Sprintf("OfferedHashes: Stream: %v, From: %v, To: %v, Hashes: %v, Last: %v", self.Stream, self.From, self.To, len(self.Hashes)/HashSize, self.Last)
+}
+
+// WantedHashesMsg answers the last offer of a stream with a bit vector of the
+// hashes the peer wants delivered
+type WantedHashesMsg struct {
+	Stream Stream
+	Want   []byte
+}
+
+func (self *WantedHashesMsg) String() string {
+	return fmt.Sprintf("WantedHashes: Stream: %v, Want: %x", self.Stream, self.Want)
+}
+
+// ChunkDeliveryMsg delivers a chunk wanted or requested by the peer
+type ChunkDeliveryMsg struct {
+	Key   storage.Key
+	SData []byte
+}
+
+func (self *ChunkDeliveryMsg) String() string {
+	return fmt.Sprintf("ChunkDelivery: Key: %v, Size: %v", self.Key.Log(), len(self.SData))
+}
+
+// RetrieveRequestMsg requests a chunk from the peer, which delivers it once
+// found locally or retrieved from the swarm
+type RetrieveRequestMsg struct {
+	Key storage.Key
+}
+
+func (self *RetrieveRequestMsg) String() string {
+	return fmt.Sprintf("RetrieveRequest: Key: %v", self.Key.Log())
+}
@@ -0,0 +1,122 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p/protocols"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+)
+
+// Peer is a connected peer running the stream protocol, it serves the
+// streams the peer subscribed to and receives the streams subscribed from it
+type Peer struct {
+	*protocols.Peer
+	registry *Registry
+	addr     kademlia.Address // overlay address of the peer
+	quit     chan struct{}    // closed on disconnect, quits the servers
+
+	lock    sync.Mutex
+	servers map[Stream]*server // streams served to the peer
+	clients map[Stream]*client // streams subscribed from the peer
+}
+
+func newPeer(registry *Registry, pp *protocols.Peer, addr kademlia.Address) *Peer {
+	return &Peer{
+		Peer:     pp,
+		registry: registry,
+		addr:     addr,
+		quit:     make(chan struct{}),
+		servers:  make(map[Stream]*server),
+		clients:  make(map[Stream]*client),
+	}
+}
+
+func (self *Peer) String() string {
+	return fmt.Sprintf("%v (%v)", self.ID(), self.addr)
+}
+
+// handleMsg dispatches the messages received from the peer
+func (self *Peer) handleMsg(msg interface{}) error {
+	log.Trace(fmt.Sprintf("stream: <- %v from %v", msg, self))
+	switch msg := msg.(type) {
+	case *SubscribeMsg:
+		return self.handleSubscribeMsg(msg)
+
+	case *OfferedHashesMsg:
+		return self.handleOfferedHashesMsg(msg)
+
+	case *WantedHashesMsg:
+		return self.handleWantedHashesMsg(msg)
+
+	case *ChunkDeliveryMsg:
+		return self.registry.handleChunkDeliveryMsg(msg, self)
+
+	case *RetrieveRequestMsg:
+		return self.registry.handleRetrieveRequestMsg(msg, self)
+
+	default:
+		return fmt.Errorf("unexpected message type: %T", msg)
+	}
+}
+
+// send sends the message without blocking the message loop: two peers
+// sending to each other from their message loops would wait on each other
+func (self *Peer) send(msg interface{}) {
+	go func() {
+		if err := self.Send(msg); err != nil {
+			log.Debug(fmt.Sprintf("stream: sending %v to %v failed: %v", msg, self, err))
+			self.Drop(err)
+		}
+	}()
+}
+
+// subscribeSync subscribes to the SYNC streams of the proximity bins of the
+// peer holding the chunks the node is responsible for: the bin the node falls
+// in and, if the peer is in the nearest neighbourhood, all the deeper bins
+func (self *Peer) subscribeSync() error {
+	overlay := self.registry.overlay
+	maxProx := overlay.MaxProx()
+	po := kademlia.Proximity(overlay.Addr(), self.addr)
+	if po > maxProx {
+		po = maxProx
+	}
+	last := po
+	if po >= overlay.Depth() {
+		last = maxProx
+	}
+	for bin := po; bin <= last; bin++ {
+		if err := self.subscribe(Stream{Name: SyncStream, Key: uint8(bin)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribe requests the stream from the peer starting at the cursor
+// persisted in an earlier session
+func (self *Peer) subscribe(stream Stream) error {
+	cursor := self.registry.cursor(self.addr, stream)
+	self.lock.Lock()
+	self.clients[stream] = newClient(stream)
+	self.lock.Unlock()
+	log.Debug(fmt.Sprintf("stream: subscribing to %v of %v at cursor %v", stream, self, cursor))
+	return self.Send(&SubscribeMsg{Stream: stream, Cursor: cursor})
+}
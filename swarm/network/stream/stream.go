@@ -0,0 +1,251 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package stream implements the swarm stream protocol, which replicates chunks
+between peers and serves retrieve requests.
+
+Peers serve their local chunk store as streams, one SYNC stream for each
+proximity bin of their kademlia table. Upon connection a peer subscribes to
+the streams of the bins holding the chunks it is responsible for: the bin the
+peer itself falls in and, if it is in the nearest neighbourhood, all deeper
+bins. The server offers the hashes of the chunks of the stream in batches, the
+subscriber answers with the hashes it wants and the server delivers the
+corresponding chunks before offering the next batch.
+
+Offers cover intervals of the storage index of the server. Once all the
+wanted chunks of an interval have been delivered, the subscriber persists the
+end of the interval as its cursor for the stream of the peer, so a later
+session resumes syncing where the last one stopped.
+
+Chunks are retrieved by sending retrieve requests to the peer closest to
+them, which delivers the chunk once found in its local store or retrieved
+from its own peers. Retrieve requests are accounted on the SWAP balance of the
+peers, the requested peer is credited and refuses requests from peers without
+the balance to pay for them. Chunks delivered by a peer are only stored if
+they were wanted from its streams or requested.
+*/
+package stream
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/protocols"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+)
+
+const (
+	// HashSize is the length of the chunk hashes offered by peers
+	HashSize = 32
+
+	syncBatchSize    = 128                     // maximum number of hashes offered in a batch
+	syncInterval     = 1000000000              // interval at which new chunks are looked for
+	handshakeTimeout = 3000 * time.Millisecond // timeout of the protocol handshake
+)
+
+// Spec is the specification of the stream protocol
+var Spec = &protocols.Spec{
+	Name:       "stream",
+	Version:    1,
+	MaxMsgSize: 10 * 1024 * 1024,
+	Messages: []interface{}{
+		HandshakeMsg{},
+		SubscribeMsg{},
+		OfferedHashesMsg{},
+		WantedHashesMsg{},
+		ChunkDeliveryMsg{},
+		RetrieveRequestMsg{},
+	},
+}
+
+// Overlay is the view of the kademlia topology the registry relies on,
+// implemented by network.Hive
+type Overlay interface {
+	Addr() kademlia.Address // base address of the node
+	Depth() int             // proximity order of the nearest neighbourhood
+	MaxProx() int           // proximity order of the deepest bin
+	Syncing() bool          // true if the node syncs with its peers
+
+	// Account records units of service provided to (units > 0) or used
+	// from (units < 0) the peer on its SWAP balance, failing if the balance
+	// does not allow it
+	Account(id discover.NodeID, units int) error
+}
+
+// SyncParams are the parameters of the stream protocol
+type SyncParams struct {
+	CursorDbPath  string // path of the db persisting the cursors of the streams of peers
+	SyncBatchSize uint   // maximum number of hashes offered in a batch
+	SyncInterval  uint64 // interval in nanoseconds at which new chunks are looked for
+}
+
+// NewDefaultSyncParams creates params with default values
+func NewDefaultSyncParams() *SyncParams {
+	return &SyncParams{
+		SyncBatchSize: syncBatchSize,
+		SyncInterval:  syncInterval,
+	}
+}
+
+// Init sets the path of the cursor db, called once all config options (file,
+// cmd line, env vars) have been evaluated
+func (self *SyncParams) Init(path string) {
+	self.CursorDbPath = filepath.Join(path, "cursors")
+}
+
+// Registry runs the stream protocol on the peer connections of the node. It
+// implements storage.CloudStore, retrieving chunks from its peers
+type Registry struct {
+	overlay    Overlay
+	localStore *storage.LocalStore
+	dbStore    *storage.DbStore
+	netStore   *storage.Neaaeore
+	hashfunc   storage.SwarmHasher
+	cursors    *storage.LDBDatabase
+	params     *SyncParams
+
+	peers     map[discover.NodeID]*Peer
+	peersLock sync.RWMutex
+	reqLock   sync.Mutex // guards the data of chunks pending retrieval
+}
+
+// NewRegistry creates the registry serving the local store of the node
+func NewRegistry(overlay Overlay, localStore *storage.LocalStore, hash storage.SwarmHasher, params *SyncParams) (*Registry, error) {
+	cursors, err := storage.NewLDBDatabase(params.CursorDbPath)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up cursor db: %v", err)
+	}
+	return &Registry{
+		overlay:    overlay,
+		localStore: localStore,
+		dbStore:    localStore.DbStore.(*storage.DbStore),
+		hashfunc:   hash,
+		cursors:    cursors,
+		params:     params,
+		peers:      make(map[discover.NodeID]*Peer),
+	}, nil
+}
+
+// SetNetStore sets the store delivered chunks are put to, which needs to be
+// the net store using the registry as its cloud store so retrievals complete
+func (self *Registry) SetNetStore(netStore *storage.Neaaeore) {
+	self.netStore = netStore
+}
+
+// Close closes the cursor db
+func (self *Registry) Close() {
+	self.cursors.Close()
+}
+
+// Protocol returns the stream protocol to run on the p2p server
+func (self *Registry) Protocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    Spec.Name,
+		Version: Spec.Version,
+		Length:  Spec.Length(),
+		Run:     self.run,
+	}
+}
+
+// run is the protocol loop of a peer connection: after the handshake it
+// subscribes to the relevant streams of the peer and handles its messages
+func (self *Registry) run(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	pp := protocols.NewPeer(p, rw, Spec)
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	hs, err := pp.Handshake(ctx, &HandshakeMsg{Addr: self.overlay.Addr()}, nil)
+	if err != nil {
+		return err
+	}
+	peer := newPeer(self, pp, hs.(*HandshakeMsg).Addr)
+	self.addPeer(peer)
+	defer self.removePeer(peer)
+
+	if self.overlay.Syncing() {
+		// subscribe while the message loop runs, the peer may be
+		// subscribing at the same time
+		go func() {
+			if err := peer.subscribeSync(); err != nil {
+				log.Warn(fmt.Sprintf("stream: subscribing to %v failed: %v", peer, err))
+				pp.Drop(err)
+			}
+		}()
+	}
+	return pp.Run(peer.handleMsg)
+}
+
+func (self *Registry) addPeer(p *Peer) {
+	self.peersLock.Lock()
+	defer self.peersLock.Unlock()
+	self.peers[p.ID()] = p
+	log.Debug(fmt.Sprintf("stream: peer %v connected", p))
+}
+
+func (self *Registry) removePeer(p *Peer) {
+	self.peersLock.Lock()
+	defer self.peersLock.Unlock()
+	delete(self.peers, p.ID())
+	close(p.quit)
+	log.Debug(fmt.Sprintf("stream: peer %v disconnected", p))
+}
+
+// closestPeer returns the connected peer closest to the key, skipping the
+// peers for which skip returns true
+func (self *Registry) closestPeer(key storage.Key, skip func(*Peer) bool) (closest *Peer) {
+	var target kademlia.Address
+	copy(target[:], key)
+	self.peersLock.RLock()
+	defer self.peersLock.RUnlock()
+	for _, p := range self.peers {
+		if skip(p) {
+			continue
+		}
+		if closest == nil || target.ProxCmp(p.addr, closest.addr) < 0 {
+			closest = p
+		}
+	}
+	return closest
+}
+
+// cursorKey is the key of the cursor of the stream of the peer in the cursor db
+func cursorKey(addr kademlia.Address, stream Stream) []byte {
+	return append(append(addr[:], stream.Name...), stream.Key)
+}
+
+// cursor returns the persisted cursor of the stream of the peer
+func (self *Registry) cursor(addr kademlia.Address, stream Stream) uint64 {
+	data, err := self.cursors.Get(cursorKey(addr, stream))
+	if err != nil || len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// setCursor persists the cursor of the stream of the peer
+func (self *Registry) setCursor(addr kademlia.Address, stream Stream, cursor uint64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, cursor)
+	self.cursors.Put(cursorKey(addr, stream), data)
+}
@@ -0,0 +1,302 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/metrics"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+)
+
+// metrics variables
+var (
+	offeredHashesCounter = metrics.NewRegisteredCounter("network.stream.sync.offered.count", nil)
+	wantedHashesCounter  = metrics.NewRegisteredCounter("network.stream.sync.wanted.count", nil)
+	syncDeliveryCounter  = metrics.NewRegisteredCounter("network.stream.sync.delivered.count", nil)
+)
+
+var errQuit = errors.New("peer disconnected")
+
+// server serves a SYNC stream to a subscribed peer: it offers the hashes of
+// the chunks of the proximity bin in the storage index order and delivers the
+// wanted ones
+type server struct {
+	peer        *Peer
+	stream      Stream
+	start, stop storage.Key // address range of the proximity bin
+	cursor      uint64      // storage index up to which the stream is offered
+	wantC       chan []byte // want bit vector answering the pending offer
+}
+
+// handleSubscribeMsg starts serving the requested stream to the peer
+func (self *Peer) handleSubscribeMsg(req *SubscribeMsg) error {
+	overlay := self.registry.overlay
+	maxProx := overlay.MaxProx()
+	if req.Stream.Name != SyncStream || int(req.Stream.Key) > maxProx {
+		return fmt.Errorf("unknown stream %v", req.Stream)
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, ok := self.servers[req.Stream]; ok {
+		return fmt.Errorf("stream %v already subscribed", req.Stream)
+	}
+	start, stop := binRange(overlay.Addr(), int(req.Stream.Key), maxProx)
+	s := &server{
+		peer:   self,
+		stream: req.Stream,
+		start:  start,
+		stop:   stop,
+		cursor: req.Cursor,
+		wantC:  make(chan []byte, 1),
+	}
+	self.servers[req.Stream] = s
+	log.Debug(fmt.Sprintf("stream: serving %v to %v from cursor %v", req.Stream, self, req.Cursor))
+	go s.run()
+	return nil
+}
+
+// handleWantedHashesMsg passes the want bit vector to the server waiting for
+// the answer to its offer
+func (self *Peer) handleWantedHashesMsg(req *WantedHashesMsg) error {
+	self.lock.Lock()
+	s, ok := self.servers[req.Stream]
+	self.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("stream %v not subscribed", req.Stream)
+	}
+	select {
+	case s.wantC <- req.Want:
+		return nil
+	default:
+		return fmt.Errorf("stream %v: unexpected wanted hashes", req.Stream)
+	}
+}
+
+// run offers the chunks stored in the bin since the cursor, then checks for
+// new chunks every sync interval until the peer disconnects
+func (self *server) run() {
+	registry := self.peer.registry
+	interval := time.Duration(registry.params.SyncInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		last := registry.localStore.DbCounter()
+		if self.cursor > last {
+			// the cursor is beyond the storage index, the store was reset
+			self.cursor = 0
+		}
+		if self.cursor < last {
+			if err := self.offer(self.cursor, last); err != nil {
+				log.Debug(fmt.Sprintf("stream: serving %v to %v stopped: %v", self.stream, self.peer, err))
+				return
+			}
+			self.cursor = last
+		}
+		select {
+		case <-ticker.C:
+		case <-self.peer.quit:
+			return
+		}
+	}
+}
+
+// offer offers the hashes of the chunks of the bin with storage index in the
+// interval [from, to) in batches, delivering the wanted chunks of each batch
+// before offering the next one
+func (self *server) offer(from, to uint64) error {
+	registry := self.peer.registry
+	it, err := registry.dbStore.NewSyncIterator(storage.DbSyncState{
+		Start: self.start,
+		Stop:  self.stop,
+		First: from,
+		Last:  to,
+	})
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	batchSize := int(registry.params.SyncBatchSize)
+	key := it.Next()
+	for key != nil {
+		var hashes []byte
+		for n := 0; key != nil && n < batchSize; n++ {
+			hashes = append(hashes, key...)
+			key = it.Next()
+		}
+		offeredHashesCounter.Inc(int64(len(hashes) / HashSize))
+		err := self.peer.Send(&OfferedHashesMsg{
+			Stream: self.stream,
+			From:   from,
+			To:     to,
+			Hashes: hashes,
+			Last:   key == nil,
+		})
+		if err != nil {
+			return err
+		}
+		var want []byte
+		select {
+		case want = <-self.wantC:
+		case <-self.peer.quit:
+			return errQuit
+		}
+		for i := 0; i < len(hashes)/HashSize; i++ {
+			if i/8 >= len(want) || want[i/8]&(1<<uint(i%8)) == 0 {
+				continue
+			}
+			chunk, err := registry.localStore.Get(storage.Key(hashes[i*HashSize : (i+1)*HashSize]))
+			if err != nil || chunk.SData == nil {
+				// the chunk was garbage collected since the offer
+				continue
+			}
+			syncDeliveryCounter.Inc(1)
+			if err := self.peer.Send(&ChunkDeliveryMsg{Key: chunk.Key, SData: chunk.SData}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// client receives a SYNC stream subscribed from a peer
+type client struct {
+	stream  Stream
+	pending map[string]bool // wanted hashes of the last offer not yet delivered
+	next    uint64          // cursor to persist once the pending chunks are delivered
+}
+
+func newClient(stream Stream) *client {
+	return &client{
+		stream:  stream,
+		pending: make(map[string]bool),
+	}
+}
+
+// handleOfferedHashesMsg answers an offer with the hashes of the chunks
+// missing from the local store. By the time the next offer of the stream
+// arrives the server delivered the chunks it could of the previous one
+func (self *Peer) handleOfferedHashesMsg(req *OfferedHashesMsg) error {
+	if len(req.Hashes)%HashSize != 0 {
+		return fmt.Errorf("invalid hashes length (len: %v)", len(req.Hashes))
+	}
+	self.lock.Lock()
+	c, ok := self.clients[req.Stream]
+	self.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("stream %v not subscribed", req.Stream)
+	}
+
+	registry := self.registry
+	self.commit(c, true)
+	n := len(req.Hashes) / HashSize
+	want := make([]byte, (n+7)/8)
+	wanted := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		hash := req.Hashes[i*HashSize : (i+1)*HashSize]
+		chunk, err := registry.localStore.Get(storage.Key(hash))
+		if err == nil && chunk.SData != nil {
+			continue
+		}
+		want[i/8] |= 1 << uint(i%8)
+		wanted[string(hash)] = true
+	}
+	wantedHashesCounter.Inc(int64(len(wanted)))
+	log.Trace(fmt.Sprintf("stream: %v of %v offered %v hashes, %v wanted", req.Stream, self, n, len(wanted)))
+
+	self.lock.Lock()
+	c.pending = wanted
+	if req.Last {
+		c.next = req.To
+	}
+	self.lock.Unlock()
+	self.commit(c, false)
+	self.send(&WantedHashesMsg{Stream: req.Stream, Want: want})
+	return nil
+}
+
+// wants reports whaaeer the chunk is pending delivery on a stream subscribed
+// from the peer
+func (self *Peer) wants(key storage.Key) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, c := range self.clients {
+		if c.pending[string(key)] {
+			return true
+		}
+	}
+	return false
+}
+
+// delivered marks the chunk as delivered for the clients waiting for it
+func (self *Peer) delivered(key storage.Key) {
+	self.lock.Lock()
+	var done []*client
+	for _, c := range self.clients {
+		if c.pending[string(key)] {
+			delete(c.pending, string(key))
+			if len(c.pending) == 0 {
+				done = append(done, c)
+			}
+		}
+	}
+	self.lock.Unlock()
+	for _, c := range done {
+		self.commit(c, false)
+	}
+}
+
+// commit persists the cursor of the last offered interval once all the
+// wanted chunks are delivered, or regardless if force is set
+func (self *Peer) commit(c *client, force bool) {
+	self.lock.Lock()
+	next := c.next
+	if next == 0 || !force && len(c.pending) > 0 {
+		self.lock.Unlock()
+		return
+	}
+	c.next = 0
+	self.lock.Unlock()
+	log.Trace(fmt.Sprintf("stream: %v of %v synced up to %v", c.stream, self, next))
+	self.registry.setCursor(self.addr, c.stream, next)
+}
+
+// binRange returns the address range of the chunks in the proximity bin of
+// the base address, the last bin holding all the chunks at least as close.
+// As the range is iterated by the db sync iterator, start is exclusive
+func binRange(base kademlia.Address, bin, maxBin int) (start, stop storage.Key) {
+	start = make(storage.Key, len(base))
+	stop = make(storage.Key, len(base))
+	copy(start, base[:])
+	copy(stop, base[:])
+	prefix := bin
+	if bin < maxBin {
+		start[bin/8] ^= 0x80 >> uint(bin%8)
+		stop[bin/8] ^= 0x80 >> uint(bin%8)
+		prefix++
+	}
+	for i := prefix; i < len(base)*8; i++ {
+		start[i/8] &^= 0x80 >> uint(i%8)
+		stop[i/8] |= 0x80 >> uint(i%8)
+	}
+	return start, stop
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aaechain/go-aaechain/p2p/simulations/adapters"
+	p2ptest "github.com/aaechain/go-aaechain/p2p/testing"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	"github.com/aaechain/go-aaechain/swarm/storage"
+)
+
+func bin(base kademlia.Address, key []byte, maxProx int) int {
+	var addr kademlia.Address
+	copy(addr[:], key)
+	po := kademlia.Proximity(base, addr)
+	if po > maxProx {
+		po = maxProx
+	}
+	return po
+}
+
+// TestSyncerSimulation connects nodes to a pivot holding chunks and checks
+// that each node syncs exactly the chunks of the proximity bin of the pivot
+// it falls in
+func TestSyncerSimulation(t *testing.T) {
+	net, ids := newTestNetwork(t, 6, testMaxProx+1, true)
+	defer net.Shutdown()
+	pivot := net.node(ids[0])
+	keys := storeRandomChunks(t, pivot.localStore, 100)
+
+	for _, id := range ids[1:] {
+		if err := net.Connect(ids[0], id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	synced := func(n *testNode, key storage.Key) bool {
+		return bin(pivot.overlay.addr, key, testMaxProx) == bin(pivot.overlay.addr, n.overlay.addr[:], testMaxProx)
+	}
+	for _, id := range ids[1:] {
+		n := net.node(id)
+		ok := waitFor(10*time.Second, func() bool {
+			for _, key := range keys {
+				if synced(n, key) && !n.has(key) {
+					return false
+				}
+			}
+			return true
+		})
+		if !ok {
+			t.Fatalf("node %v did not sync the chunks of its bin", id)
+		}
+	}
+	for _, id := range ids[1:] {
+		n := net.node(id)
+		for _, key := range keys {
+			if !synced(n, key) && n.has(key) {
+				t.Fatalf("node %v synced chunk %v of another bin", id, key.Log())
+			}
+		}
+	}
+}
+
+// TestSyncerCursors checks that the cursors of the synced streams are
+// persisted and syncing resumes on reconnection
+func TestSyncerCursors(t *testing.T) {
+	net, ids := newTestNetwork(t, 2, 0, true)
+	defer net.Shutdown()
+	pivot := net.node(ids[0])
+	n := net.node(ids[1])
+	po := bin(pivot.overlay.addr, n.overlay.addr[:], testMaxProx)
+
+	// checks that the chunks falling in the subscribed bins are synced and
+	// the cursors of the bins holding any are set to the storage index
+	check := func(keys []storage.Key, cursor uint64) bool {
+		for _, key := range keys {
+			b := bin(pivot.overlay.addr, key, testMaxProx)
+			if b < po {
+				continue
+			}
+			if !n.has(key) || n.registry.cursor(pivot.overlay.addr, Stream{Name: SyncStream, Key: uint8(b)}) != cursor {
+				return false
+			}
+		}
+		return true
+	}
+
+	keys := storeRandomChunks(t, pivot.localStore, 20)
+	if err := net.Connect(ids[0], ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(10*time.Second, func() bool { return check(keys, 20) }) {
+		t.Fatal("chunks not synced")
+	}
+	if err := net.Disconnect(ids[0], ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	keys = storeRandomChunks(t, pivot.localStore, 20)
+	// the connection is only reestablished once the disconnection is reported
+	if !waitFor(5*time.Second, func() bool { return net.Connect(ids[0], ids[1]) == nil }) {
+		t.Fatal("nodes not reconnected")
+	}
+	if !waitFor(10*time.Second, func() bool { return check(keys, 40) }) {
+		t.Fatal("chunks not synced after reconnection")
+	}
+}
+
+// TestSyncerOffersFromCursor checks that a subscribed stream is offered from
+// the cursor and the wanted chunks are delivered
+func TestSyncerOffersFromCursor(t *testing.T) {
+	overlay := &testOverlay{addr: kademlia.RandomAddress()}
+	pivot, err := newTestNode(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pivot.Stop()
+	keys := storeRandomChunks(t, pivot.localStore, 10)
+
+	// the only bin holds all chunks, offered in address order
+	offered := keys[4:]
+	sort.Slice(offered, func(i, j int) bool { return bytes.Compare(offered[i], offered[j]) < 0 })
+	var hashes []byte
+	for _, key := range offered {
+		hashes = append(hashes, key...)
+	}
+	chunk, err := pivot.localStore.Get(offered[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := p2ptest.NewProtocolTester(t, adapters.RandomNodeConfig().ID, 1, pivot.registry.run)
+	defer s.Stop()
+	id := s.IDs[0]
+	stream := Stream{Name: SyncStream, Key: 0}
+	err = s.TestExchanges(
+		p2ptest.Exchange{
+			Label: "handshake",
+			Expects: []p2ptest.Expect{
+				{Code: 0, Msg: &HandshakeMsg{Addr: overlay.addr}, Peer: id},
+			},
+		},
+		p2ptest.Exchange{
+			Label: "subscribe",
+			Triggers: []p2ptest.Trigger{
+				{Code: 0, Msg: &HandshakeMsg{Addr: kademlia.RandomAddress()}, Peer: id},
+				{Code: 1, Msg: &SubscribeMsg{Stream: stream, Cursor: 4}, Peer: id},
+			},
+			Expects: []p2ptest.Expect{
+				{Code: 2, Msg: &OfferedHashesMsg{Stream: stream, From: 4, To: 10, Hashes: hashes, Last: true}, Peer: id},
+			},
+		},
+		p2ptest.Exchange{
+			Label: "want",
+			Triggers: []p2ptest.Trigger{
+				{Code: 3, Msg: &WantedHashesMsg{Stream: stream, Want: []byte{0x01}}, Peer: id},
+			},
+			Expects: []p2ptest.Expect{
+				{Code: 4, Msg: &ChunkDeliveryMsg{Key: chunk.Key, SData: chunk.SData}, Peer: id},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pss
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaechain/go-aaechain/common/hexutil"
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/rpc"
+	whisper "github.com/aaechain/go-aaechain/whisper/whisperv6"
+)
+
+// APIMsg is the notification sent to RPC subscribers for every message
+// received on their topic
+type APIMsg struct {
+	Msg        hexutil.Bytes `json:"msg"`
+	Key        hexutil.Bytes `json:"key,omitempty"` // public key of the sender
+	Asymmetric bool          `json:"asymmetric"`
+}
+
+// API is the RPC interface of pss
+type API struct {
+	ps *Pss
+}
+
+func NewAPI(ps *Pss) *API {
+	return &API{ps: ps}
+}
+
+// Receive subscribes to the messages received on the topic
+func (self *API) Receive(ctx context.Context, topic whisper.TopicType) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	deregister := self.ps.Register(topic, func(msg *Message) error {
+		apimsg := &APIMsg{
+			Msg:        hexutil.Bytes(msg.Payload),
+			Asymmetric: msg.Asymmetric,
+		}
+		if msg.Src != nil {
+			apimsg.Key = crypto.FromECDSAPub(msg.Src)
+		}
+		if err := notifier.Notify(sub.ID, apimsg); err != nil {
+			log.Warn(fmt.Sprintf("pss: notify subscription %v: %v", sub.ID, err))
+		}
+		return nil
+	})
+	go func() {
+		defer deregister()
+		select {
+		case <-sub.Err():
+		case <-notifier.Closed():
+		}
+	}()
+	return sub, nil
+}
+
+// BaseAddr returns the overlay address of the node
+func (self *API) BaseAddr() hexutil.Bytes {
+	return hexutil.Bytes(self.ps.BaseAddr())
+}
+
+// GetPublicKey returns the public key messages to the node are encrypted for
+func (self *API) GetPublicKey() hexutil.Bytes {
+	return hexutil.Bytes(crypto.FromECDSAPub(self.ps.PublicKey()))
+}
+
+// SendAsym sends a message encrypted for the public key to the (partial)
+// overlay address to
+func (self *API) SendAsym(to hexutil.Bytes, pubKey hexutil.Bytes, topic whisper.TopicType, msg hexutil.Bytes) error {
+	key := crypto.ToECDSAPub(pubKey)
+	if key == nil {
+		return fmt.Errorf("invalid public key")
+	}
+	return self.ps.SendAsym(to, key, topic, msg)
+}
+
+// SendSym sends a message encrypted with the symmetric key of the given id
+// to the (partial) overlay address to
+func (self *API) SendSym(to hexutil.Bytes, keyID string, topic whisper.TopicType, msg hexutil.Bytes) error {
+	return self.ps.SendSym(to, keyID, topic, msg)
+}
+
+// AddSymKey stores a symmetric key used on the topic and returns its id
+func (self *API) AddSymKey(topic whisper.TopicType, key hexutil.Bytes) (string, error) {
+	return self.ps.AddSymmetricKey(topic, key)
+}
+
+// NewSymKey generates a symmetric key used on the topic and returns its id
+func (self *API) NewSymKey(topic whisper.TopicType) (string, error) {
+	return self.ps.GenerateSymmetricKey(topic)
+}
+
+// GetSymKey returns the symmetric key with the given id
+func (self *API) GetSymKey(keyID string) (hexutil.Bytes, error) {
+	key, err := self.ps.GetSymmetricKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(key), nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pss implements postal-service messaging over the swarm kademlia.
+//
+// Messages are wrapped in whisper envelopes, encrypted either for the
+// public key of the recipient or with a symmetric key shared on a topic,
+// and routed by the bzz protocol towards the (partial) overlay address of
+// the recipient. Received messages are decrypted and dispatched to the
+// handlers registered on their topic.
+package pss
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/metrics"
+	"github.com/aaechain/go-aaechain/rlp"
+	"github.com/aaechain/go-aaechain/swarm/network"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	whisper "github.com/aaechain/go-aaechain/whisper/whisperv6"
+)
+
+var (
+	sendCounter    = metrics.NewRegisteredCounter("pss.send.count", nil)
+	receiveCounter = metrics.NewRegisteredCounter("pss.receive.count", nil)
+)
+
+const (
+	// MinPoW is the proof of work envelopes need to be relayed
+	MinPoW = whisper.DefaultMinimumPoW
+	// powTime is the time in seconds allowed for the proof of work of an envelope
+	powTime = 5
+)
+
+var (
+	ErrExpired         = errors.New("pss message expired")
+	ErrUndecryptable   = errors.New("pss message could not be decrypted")
+	ErrInsufficientPoW = errors.New("pss message proof of work insufficient")
+)
+
+// Overlay routes pss messages through the network, it is implemented by
+// the swarm hive
+type Overlay interface {
+	Addr() kademlia.Address
+	SendPss(msg *network.PssMsg) error
+	SetPssHandler(handler network.PssHandler)
+	SetPssValidator(validator network.PssValidator)
+}
+
+// Message is a decrypted pss message as passed to the topic handlers
+type Message struct {
+	Topic      whisper.TopicType
+	Payload    []byte
+	Src        *ecdsa.PublicKey // sender, recovered from the signature
+	Asymmetric bool             // true if encrypted for our public key
+}
+
+// Handler is called with the messages received on a topic
+type Handler func(msg *Message) error
+
+// Pss is the postal service of a swarm node
+type Pss struct {
+	overlay Overlay
+	keys    *whisper.Whisper // key store, the whisper protocol itself is not run
+	baseKey *ecdsa.PrivateKey
+
+	lock     sync.RWMutex
+	handlers map[whisper.TopicType]map[uint64]Handler
+	symKeys  map[whisper.TopicType][]string // ids of the symmetric keys tried on a topic
+	nextID   uint64
+}
+
+// NewPss creates the postal service for the node with the given private key
+// and registers it as the pss message handler of the overlay
+func NewPss(overlay Overlay, prvKey *ecdsa.PrivateKey) (*Pss, error) {
+	if prvKey == nil {
+		return nil, errors.New("pss requires a private key")
+	}
+	keys := whisper.New(nil)
+	if _, err := keys.AddKeyPair(prvKey); err != nil {
+		return nil, err
+	}
+	self := &Pss{
+		overlay:  overlay,
+		keys:     keys,
+		baseKey:  prvKey,
+		handlers: make(map[whisper.TopicType]map[uint64]Handler),
+		symKeys:  make(map[whisper.TopicType][]string),
+	}
+	overlay.SetPssHandler(self.handle)
+	overlay.SetPssValidator(self.validate)
+	return self, nil
+}
+
+// BaseAddr returns the overlay address of the node
+func (self *Pss) BaseAddr() []byte {
+	addr := self.overlay.Addr()
+	return addr[:]
+}
+
+// PublicKey returns the public key messages to this node are encrypted for
+func (self *Pss) PublicKey() *ecdsa.PublicKey {
+	return &self.baseKey.PublicKey
+}
+
+// Register adds a handler for the messages received on the topic
+// the returned function removes the handler
+func (self *Pss) Register(topic whisper.TopicType, handler Handler) func() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	id := self.nextID
+	self.nextID++
+	if self.handlers[topic] == nil {
+		self.handlers[topic] = make(map[uint64]Handler)
+	}
+	self.handlers[topic][id] = handler
+	return func() {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+		delete(self.handlers[topic], id)
+		if len(self.handlers[topic]) == 0 {
+			delete(self.handlers, topic)
+		}
+	}
+}
+
+// AddSymmetricKey stores the symmetric key and uses it to decrypt the
+// messages received on the topic, it returns the id of the key
+func (self *Pss) AddSymmetricKey(topic whisper.TopicType, key []byte) (string, error) {
+	id, err := self.keys.AddSymKeyDirect(key)
+	if err != nil {
+		return "", err
+	}
+	self.addSymKeyID(topic, id)
+	return id, nil
+}
+
+// GenerateSymmetricKey creates a random symmetric key used on the topic,
+// it returns the id of the key
+func (self *Pss) GenerateSymmetricKey(topic whisper.TopicType) (string, error) {
+	id, err := self.keys.GenerateSymKey()
+	if err != nil {
+		return "", err
+	}
+	self.addSymKeyID(topic, id)
+	return id, nil
+}
+
+// GetSymmetricKey returns the symmetric key with the given id
+func (self *Pss) GetSymmetricKey(id string) ([]byte, error) {
+	return self.keys.GetSymKey(id)
+}
+
+func (self *Pss) addSymKeyID(topic whisper.TopicType, id string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.symKeys[topic] = append(self.symKeys[topic], id)
+}
+
+// SendAsym sends a message encrypted for the public key of the recipient
+// to the (partial) overlay address to
+func (self *Pss) SendAsym(to []byte, pubKey *ecdsa.PublicKey, topic whisper.TopicType, payload []byte) error {
+	if !whisper.ValidatePublicKey(pubKey) {
+		return fmt.Errorf("invalid public key")
+	}
+	return self.send(to, &whisper.MessageParams{
+		Dst:     pubKey,
+		Topic:   topic,
+		Payload: payload,
+	})
+}
+
+// SendSym sends a message encrypted with the symmetric key of the given id
+// to the (partial) overlay address to
+func (self *Pss) SendSym(to []byte, keyID string, topic whisper.TopicType, payload []byte) error {
+	key, err := self.keys.GetSymKey(keyID)
+	if err != nil {
+		return err
+	}
+	return self.send(to, &whisper.MessageParams{
+		KeySym:  key,
+		Topic:   topic,
+		Payload: payload,
+	})
+}
+
+// send signs and wraps the message in an envelope sealed with the proof of
+// work required to relay it and hands it to the overlay for routing
+func (self *Pss) send(to []byte, params *whisper.MessageParams) error {
+	params.Src = self.baseKey
+	params.PoW = MinPoW
+	params.WorkTime = powTime
+	msg, err := whisper.NewSentMessage(params)
+	if err != nil {
+		return err
+	}
+	env, err := msg.Wrap(params)
+	if err != nil {
+		return err
+	}
+	data, err := rlp.EncodeToBytes(env)
+	if err != nil {
+		return err
+	}
+	sendCounter.Inc(1)
+	return self.overlay.SendPss(&network.PssMsg{To: to, Payload: data})
+}
+
+// decodeEnvelope decodes the envelope of a pss message, rejecting expired
+// ones and ones with an expiry beyond their time to live
+func decodeEnvelope(msg *network.PssMsg) (*whisper.Envelope, error) {
+	var env whisper.Envelope
+	if err := rlp.DecodeBytes(msg.Payload, &env); err != nil {
+		return nil, err
+	}
+	now := uint32(time.Now().Unix())
+	if env.Expiry < now {
+		return nil, ErrExpired
+	}
+	if env.Expiry > now+env.TTL+whisper.DefaultSyncAllowance {
+		return nil, fmt.Errorf("pss message expiry %d too far in the future", env.Expiry)
+	}
+	return &env, nil
+}
+
+// validate is called by the overlay with the messages received from peers
+// before delivering or relaying them, it checks the proof of work of the
+// envelope so that relaying messages costs their senders
+func (self *Pss) validate(msg *network.PssMsg) error {
+	env, err := decodeEnvelope(msg)
+	if err != nil {
+		return err
+	}
+	if env.PoW() < MinPoW {
+		return ErrInsufficientPoW
+	}
+	return nil
+}
+
+// handle is called by the overlay with the messages addressed to us
+func (self *Pss) handle(msg *network.PssMsg) error {
+	receiveCounter.Inc(1)
+	env, err := decodeEnvelope(msg)
+	if err != nil {
+		return err
+	}
+
+	self.lock.RLock()
+	var handlers []Handler
+	for _, h := range self.handlers[env.Topic] {
+		handlers = append(handlers, h)
+	}
+	keyIDs := self.symKeys[env.Topic]
+	self.lock.RUnlock()
+	if len(handlers) == 0 {
+		log.Trace(fmt.Sprintf("pss: no handler for topic %x", env.Topic))
+		return nil
+	}
+
+	recv, asym := self.open(env, keyIDs)
+	if recv == nil {
+		return ErrUndecryptable
+	}
+	m := &Message{
+		Topic:      env.Topic,
+		Payload:    recv.Payload,
+		Src:        recv.Src,
+		Asymmetric: asym,
+	}
+	for _, h := range handlers {
+		if err := h(m); err != nil {
+			log.Debug(fmt.Sprintf("pss handler for topic %x: %v", env.Topic, err))
+		}
+	}
+	return nil
+}
+
+// open tries to decrypt the envelope with our private key first and then
+// with the symmetric keys registered on the topic
+func (self *Pss) open(env *whisper.Envelope, keyIDs []string) (*whisper.ReceivedMessage, bool) {
+	if recv, err := env.OpenAsymmetric(self.baseKey); err == nil && recv.ValidateAndParse() {
+		return recv, true
+	}
+	for _, id := range keyIDs {
+		key, err := self.keys.GetSymKey(id)
+		if err != nil {
+			continue
+		}
+		if recv, err := env.OpenSymmetric(key); err == nil && recv.ValidateAndParse() {
+			return recv, false
+		}
+	}
+	return nil, false
+}
+
+// ToTopic derives a topic from an arbitrary name
+func ToTopic(name string) whisper.TopicType {
+	return whisper.BytesToTopic(crypto.Keccak256([]byte(name)))
+}
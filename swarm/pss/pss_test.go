@@ -0,0 +1,255 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pss
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/rlp"
+	"github.com/aaechain/go-aaechain/swarm/network"
+	"github.com/aaechain/go-aaechain/swarm/network/kademlia"
+	whisper "github.com/aaechain/go-aaechain/whisper/whisperv6"
+)
+
+// testNetwork delivers pss messages to every node whose address matches
+// the destination
+type testNetwork struct {
+	nodes []*testOverlay
+}
+
+type testOverlay struct {
+	net       *testNetwork
+	addr      kademlia.Address
+	handler   network.PssHandler
+	validator network.PssValidator
+}
+
+func (self *testOverlay) Addr() kademlia.Address {
+	return self.addr
+}
+
+func (self *testOverlay) SetPssHandler(handler network.PssHandler) {
+	self.handler = handler
+}
+
+func (self *testOverlay) SetPssValidator(validator network.PssValidator) {
+	self.validator = validator
+}
+
+// SendPss delivers the message to the matching nodes, the nodes check it as
+// if it had been relayed to them by a peer
+func (self *testOverlay) SendPss(msg *network.PssMsg) error {
+	for _, n := range self.net.nodes {
+		if !bytes.HasPrefix(n.addr[:], msg.To) || n.handler == nil {
+			continue
+		}
+		if n != self && n.validator != nil && n.validator(msg) != nil {
+			continue
+		}
+		n.handler(msg)
+	}
+	return nil
+}
+
+func newTestPss(t *testing.T, net *testNetwork, addr byte) *Pss {
+	prvKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay := &testOverlay{net: net}
+	overlay.addr[0] = addr
+	net.nodes = append(net.nodes, overlay)
+	ps, err := NewPss(overlay, prvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ps
+}
+
+func receive(t *testing.T, msgC chan *Message) *Message {
+	select {
+	case msg := <-msgC:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	return nil
+}
+
+func TestPssAsymmetric(t *testing.T) {
+	net := &testNetwork{}
+	alice := newTestPss(t, net, 0x10)
+	bob := newTestPss(t, net, 0x20)
+	topic := ToTopic("test")
+
+	msgC := make(chan *Message, 1)
+	deregister := bob.Register(topic, func(msg *Message) error {
+		msgC <- msg
+		return nil
+	})
+	if err := alice.SendAsym(bob.BaseAddr(), bob.PublicKey(), topic, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	msg := receive(t, msgC)
+	if !bytes.Equal(msg.Payload, []byte("hello")) {
+		t.Fatalf("expected payload %q, got %q", "hello", msg.Payload)
+	}
+	if !msg.Asymmetric {
+		t.Fatal("expected asymmetric message")
+	}
+	if !bytes.Equal(crypto.FromECDSAPub(msg.Src), crypto.FromECDSAPub(alice.PublicKey())) {
+		t.Fatal("sender key mismatch")
+	}
+
+	// messages encrypted for somebody else are not delivered
+	if err := bob.handle(&network.PssMsg{}); err == nil {
+		t.Fatal("expected error handling empty payload")
+	}
+	if err := alice.SendAsym(bob.BaseAddr(), alice.PublicKey(), topic, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-msgC:
+		t.Fatal("received message encrypted for another key")
+	default:
+	}
+
+	deregister()
+	if err := alice.SendAsym(bob.BaseAddr(), bob.PublicKey(), topic, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-msgC:
+		t.Fatal("received message after deregistering")
+	default:
+	}
+}
+
+func TestPssSymmetric(t *testing.T) {
+	net := &testNetwork{}
+	alice := newTestPss(t, net, 0x10)
+	bob := newTestPss(t, net, 0x11)
+	carol := newTestPss(t, net, 0x20)
+	topic := ToTopic("test")
+
+	id, err := alice.GenerateSymmetricKey(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := alice.GetSymmetricKey(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.AddSymmetricKey(topic, key); err != nil {
+		t.Fatal(err)
+	}
+
+	bobC := make(chan *Message, 1)
+	bob.Register(topic, func(msg *Message) error {
+		bobC <- msg
+		return nil
+	})
+	carolC := make(chan *Message, 1)
+	carol.Register(topic, func(msg *Message) error {
+		carolC <- msg
+		return nil
+	})
+
+	// an empty destination reaches every node, only key holders can read it
+	if err := alice.SendSym(nil, id, topic, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	msg := receive(t, bobC)
+	if !bytes.Equal(msg.Payload, []byte("hello")) {
+		t.Fatalf("expected payload %q, got %q", "hello", msg.Payload)
+	}
+	if msg.Asymmetric {
+		t.Fatal("expected symmetric message")
+	}
+	select {
+	case <-carolC:
+		t.Fatal("message decrypted without the key")
+	default:
+	}
+
+	// messages on other topics are not dispatched
+	if err := alice.SendSym(nil, id, ToTopic("other"), []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-bobC:
+		t.Fatal("received message on unregistered topic")
+	default:
+	}
+}
+
+func TestPssPoW(t *testing.T) {
+	net := &testNetwork{}
+	alice := newTestPss(t, net, 0x10)
+	bob := newTestPss(t, net, 0x20)
+	topic := ToTopic("test")
+
+	msgC := make(chan *Message, 1)
+	bob.Register(topic, func(msg *Message) error {
+		msgC <- msg
+		return nil
+	})
+
+	// envelopes sent through pss carry the required proof of work
+	if err := alice.SendAsym(bob.BaseAddr(), bob.PublicKey(), topic, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, msgC)
+
+	// envelopes without it are not relayed
+	params := &whisper.MessageParams{
+		Src:     alice.baseKey,
+		Dst:     bob.PublicKey(),
+		Topic:   topic,
+		Payload: []byte("hello"),
+	}
+	sent, err := whisper.NewSentMessage(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := sent.Wrap(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.PoW() >= MinPoW {
+		t.Skip("envelope reached the proof of work by chance")
+	}
+	data, err := rlp.EncodeToBytes(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &network.PssMsg{To: bob.BaseAddr(), Payload: data}
+	if err := bob.validate(msg); err != ErrInsufficientPoW {
+		t.Fatalf("wrong validation error: have %v, want %v", err, ErrInsufficientPoW)
+	}
+	if err := alice.overlay.SendPss(msg); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-msgC:
+		t.Fatal("received message without proof of work")
+	default:
+	}
+}
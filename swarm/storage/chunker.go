@@ -17,6 +17,7 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -104,7 +105,9 @@ type hashJob struct {
 	key      Key
 	chunk    []byte
 	size     int64
+	encKey   []byte // chunk encryption key, nil for plaintext chunks
 	parentWg *sync.WaitGroup
+	stored   *[]byte // receives the chunk data as stored, nil unless needed for parity
 }
 
 func (self *TreeChunker) incrementWorkerCount() {
@@ -126,6 +129,47 @@ func (self *TreeChunker) decrementWorkerCount() {
 }
 
 func (self *TreeChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, swg, wwg *sync.WaitGroup) (Key, error) {
+	return self.splitTree(data, size, chunkC, swg, wwg, nil, 0)
+}
+
+// SplitEncrypted splits the data like Split, encrypting every chunk with a key
+// derived from a random root key. The returned reference is the key of the root
+// chunk followed by the root encryption key.
+func (self *TreeChunker) SplitEncrypted(data io.Reader, size int64, chunkC chan *Chunk, swg, wwg *sync.WaitGroup) (Key, error) {
+	encKey, err := NewEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	key, err := self.splitTree(data, size, chunkC, swg, wwg, encKey, 0)
+	if err != nil {
+		return nil, err
+	}
+	return append(key, encKey...), nil
+}
+
+// SplitRedundant splits the data like Split, adding level parity chunks to
+// every intermediate chunk so that up to level missing children of each of
+// them can be reconstructed by the joiner. The content is encrypted like with
+// SplitEncrypted if encrypt is set.
+func (self *TreeChunker) SplitRedundant(data io.Reader, size int64, level int, encrypt bool, chunkC chan *Chunk, swg, wwg *sync.WaitGroup) (Key, error) {
+	if level < 0 || level > MaxRedundancyLevel || int64(level) > self.branches-2 {
+		return nil, fmt.Errorf("invalid redundancy level %d", level)
+	}
+	var encKey []byte
+	if encrypt {
+		var err error
+		if encKey, err = NewEncryptionKey(); err != nil {
+			return nil, err
+		}
+	}
+	key, err := self.splitTree(data, size, chunkC, swg, wwg, encKey, int64(level))
+	if err != nil {
+		return nil, err
+	}
+	return append(key, encKey...), nil
+}
+
+func (self *TreeChunker) splitTree(data io.Reader, size int64, chunkC chan *Chunk, swg, wwg *sync.WaitGroup, encKey []byte, level int64) (Key, error) {
 	if self.chunkSize <= 0 {
 		panic("chunker must be initialised")
 	}
@@ -145,10 +189,12 @@ func (self *TreeChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, s
 
 	depth := 0
 	treeSize := self.chunkSize
+	// intermediate chunks of redundant content reserve level references for parities
+	fanout := self.branches - level
 
 	// takes lowest depth such that chunksize*HashCount^(depth+1) > size
 	// power series, will find the order of magnitude of the data size in base hashCount or numbers of levels of branching in the resulting tree.
-	for ; treeSize < size; treeSize *= self.branches {
+	for ; treeSize < size; treeSize *= fanout {
 		depth++
 	}
 
@@ -156,7 +202,7 @@ func (self *TreeChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, s
 	// this waitgroup member is released after the root hash is calculated
 	wg.Add(1)
 	//launch actual recursive function passing the waitgroups
-	go self.split(depth, treeSize/self.branches, key, data, size, jobC, chunkC, errC, quitC, wg, swg, wwg)
+	go self.split(depth, treeSize/fanout, key, encKey, level, data, size, jobC, chunkC, errC, quitC, wg, swg, wwg, nil)
 
 	// closes internal error channel if all subprocesses in the workgroup finished
 	go func() {
@@ -182,12 +228,13 @@ func (self *TreeChunker) Split(data io.Reader, size int64, chunkC chan *Chunk, s
 	return key, nil
 }
 
-func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reader, size int64, jobC chan *hashJob, chunkC chan *Chunk, errC chan error, quitC chan bool, parentWg, swg, wwg *sync.WaitGroup) {
+func (self *TreeChunker) split(depth int, treeSize int64, key Key, encKey []byte, level int64, data io.Reader, size int64, jobC chan *hashJob, chunkC chan *Chunk, errC chan error, quitC chan bool, parentWg, swg, wwg *sync.WaitGroup, stored *[]byte) {
 
 	//
+	fanout := self.branches - level
 
 	for depth > 0 && size < treeSize {
-		treeSize /= self.branches
+		treeSize /= fanout
 		depth--
 	}
 
@@ -205,7 +252,7 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 			}
 		}
 		select {
-		case jobC <- &hashJob{key, chunkData, size, parentWg}:
+		case jobC <- &hashJob{key, chunkData, size, encKey, parentWg, stored}:
 		case <-quitC:
 		}
 		return
@@ -214,10 +261,18 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 	// intermediate chunk containing child nodes hashes
 	branchCnt := (size + treeSize - 1) / treeSize
 
-	var chunk = make([]byte, branchCnt*self.hashSize+8)
+	var chunk = make([]byte, (branchCnt+level)*self.hashSize+8)
 	var pos, i int64
 
 	binary.LittleEndian.PutUint64(chunk[0:8], uint64(size))
+	// the redundancy level is recorded in the top byte of the span
+	chunk[7] = byte(level)
+
+	// children as stored, collected for the parities
+	var children [][]byte
+	if level > 0 {
+		children = make([][]byte, branchCnt)
+	}
 
 	childrenWg := &sync.WaitGroup{}
 	var secSize int64
@@ -231,8 +286,17 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 		// the hash of that data
 		subTreeKey := chunk[8+i*self.hashSize : 8+(i+1)*self.hashSize]
 
+		// the encryption key of the child
+		var subTreeEncKey []byte
+		if encKey != nil {
+			subTreeEncKey = deriveChunkKey(encKey, i)
+		}
+		var childStored *[]byte
+		if level > 0 {
+			childStored = &children[i]
+		}
 		childrenWg.Add(1)
-		self.split(depth-1, treeSize/self.branches, subTreeKey, data, secSize, jobC, chunkC, errC, quitC, childrenWg, swg, wwg)
+		self.split(depth-1, treeSize/fanout, subTreeKey, subTreeEncKey, level, data, secSize, jobC, chunkC, errC, quitC, childrenWg, swg, wwg, childStored)
 
 		i++
 		pos += treeSize
@@ -242,6 +306,10 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 	// go func() {
 	childrenWg.Wait()
 
+	if level > 0 && !self.splitParities(chunk[8+branchCnt*self.hashSize:], children, level, jobC, quitC) {
+		return
+	}
+
 	worker := self.getWorkerCount()
 	if int64(len(jobC)) > worker && worker < ChunkProcessors {
 		if wwg != nil {
@@ -252,11 +320,39 @@ func (self *TreeChunker) split(depth int, treeSize int64, key Key, data io.Reade
 
 	}
 	select {
-	case jobC <- &hashJob{key, chunk, size, parentWg}:
+	case jobC <- &hashJob{key, chunk, size, encKey, parentWg, stored}:
 	case <-quitC:
 	}
 }
 
+// splitParities computes the Reed-Solomon parities of the children of an
+// intermediate chunk and stores them as chunks, writing their keys to refs.
+// It returns false if the split was aborted.
+func (self *TreeChunker) splitParities(refs []byte, children [][]byte, level int64, jobC chan *hashJob, quitC chan bool) bool {
+	var shardSize int
+	for _, child := range children {
+		if len(child) > shardSize {
+			shardSize = len(child)
+		}
+	}
+	shards := make([][]byte, len(children))
+	for i, child := range children {
+		shards[i] = make([]byte, shardSize)
+		copy(shards[i], child)
+	}
+	paritiesWg := &sync.WaitGroup{}
+	for i, parity := range erasureEncode(shards, int(level)) {
+		paritiesWg.Add(1)
+		select {
+		case jobC <- &hashJob{key: refs[int64(i)*self.hashSize : int64(i+1)*self.hashSize], chunk: parity, size: int64(len(parity)), parentWg: paritiesWg}:
+		case <-quitC:
+			return false
+		}
+	}
+	paritiesWg.Wait()
+	return true
+}
+
 func (self *TreeChunker) hashWorker(jobC chan *hashJob, chunkC chan *Chunk, errC chan error, quitC chan bool, swg, wwg *sync.WaitGroup) {
 	defer self.decrementWorkerCount()
 
@@ -282,20 +378,28 @@ func (self *TreeChunker) hashWorker(jobC chan *hashJob, chunkC chan *Chunk, errC
 // The treeChunkers own Hash hashes togaaeer
 // - the size (of the subtree encoded in the Chunk)
 // - the Chunk, ie. the contents read from the input reader
+// Encrypted chunks are hashed and stored in their encrypted form.
 func (self *TreeChunker) hashChunk(hasher SwarmHash, job *hashJob, chunkC chan *Chunk, swg *sync.WaitGroup) {
-	hasher.ResetWithLength(job.chunk[:8]) // 8 bytes of length
-	hasher.Write(job.chunk[8:])           // minus 8 []byte length
+	data := job.chunk
+	if job.encKey != nil {
+		data = transformChunkData(job.encKey, job.chunk)
+	}
+	hasher.ResetWithLength(data[:8]) // 8 bytes of length
+	hasher.Write(data[8:])           // minus 8 []byte length
 	h := hasher.Sum(nil)
 
 	newChunk := &Chunk{
 		Key:   h,
-		SData: job.chunk,
+		SData: data,
 		Size:  job.size,
 		wg:    swg,
 	}
 
 	// report hash of this chunk one level up (keys corresponds to the proper subslice of the parent chunk)
 	copy(job.key, h)
+	if job.stored != nil {
+		*job.stored = data
+	}
 	// send off new chunk to storage
 	if chunkC != nil {
 		if swg != nil {
@@ -329,16 +433,27 @@ type LazyChunkReader struct {
 	chunkSize int64       // inherit from chunker
 	branches  int64       // inherit from chunker
 	hashSize  int64       // inherit from chunker
+	hashFunc  SwarmHasher // inherit from chunker, used to verify recovered chunks
+	encKey    []byte      // root encryption key, nil for plaintext content
+	level     int64       // redundancy level read from the root chunk
 }
 
 // implements the Joiner interface
+// References of encrypted content, twice the hash size long, are split into the
+// root key and the root encryption key.
 func (self *TreeChunker) Join(key Key, chunkC chan *Chunk) LazySectionReader {
+	var encKey []byte
+	if int64(len(key)) == 2*self.hashSize {
+		key, encKey = key[:self.hashSize], key[self.hashSize:]
+	}
 	return &LazyChunkReader{
 		key:       key,
+		encKey:    encKey,
 		chunkC:    chunkC,
 		chunkSize: self.chunkSize,
 		branches:  self.branches,
 		hashSize:  self.hashSize,
+		hashFunc:  self.hashFunc,
 	}
 }
 
@@ -356,7 +471,8 @@ func (self *LazyChunkReader) Size(quitC chan bool) (n int64, err error) {
 			return 0, fmt.Errorf("root chunk not found for %v", self.key.Hex())
 		}
 	}
-	self.chunk = chunk
+	self.chunk = decryptChunk(chunk, self.encKey)
+	self.level = redundancyLevel(self.chunk.SData)
 	return chunk.Size, nil
 }
 
@@ -381,12 +497,13 @@ func (self *LazyChunkReader) ReadAt(b []byte, off int64) (read int, err error) {
 	var depth int
 	// calculate depth and max treeSize
 	treeSize = self.chunkSize
-	for ; treeSize < size; treeSize *= self.branches {
+	fanout := self.branches - self.level
+	for ; treeSize < size; treeSize *= fanout {
 		depth++
 	}
 	wg := sync.WaitGroup{}
 	wg.Add(1)
-	go self.join(b, off, off+int64(len(b)), depth, treeSize/self.branches, self.chunk, &wg, errC, quitC)
+	go self.join(b, off, off+int64(len(b)), depth, treeSize/fanout, self.chunk, self.encKey, &wg, errC, quitC)
 	go func() {
 		wg.Wait()
 		close(errC)
@@ -404,15 +521,16 @@ func (self *LazyChunkReader) ReadAt(b []byte, off int64) (read int, err error) {
 	return len(b), nil
 }
 
-func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, treeSize int64, chunk *Chunk, parentWg *sync.WaitGroup, errC chan error, quitC chan bool) {
+func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, treeSize int64, chunk *Chunk, encKey []byte, parentWg *sync.WaitGroup, errC chan error, quitC chan bool) {
 	defer parentWg.Done()
 	// return NewDPA(&LocalStore{})
 
 	// chunk.Size = int64(binary.LittleEndian.Uint64(chunk.SData[0:8]))
 
 	// find appropriate block level
+	fanout := self.branches - self.level
 	for chunk.Size < treeSize && depth > 0 {
-		treeSize /= self.branches
+		treeSize /= fanout
 		depth--
 	}
 
@@ -450,7 +568,11 @@ func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, tr
 		wg.Add(1)
 		go func(j int64) {
 			childKey := chunk.SData[8+j*self.hashSize : 8+(j+1)*self.hashSize]
+			parent := chunk
 			chunk := retrieve(childKey, self.chunkC, quitC)
+			if chunk == nil && self.level > 0 {
+				chunk = self.recoverChild(parent, (parent.Size+treeSize-1)/treeSize, j, quitC)
+			}
 			if chunk == nil {
 				select {
 				case errC <- fmt.Errorf("chunk %v-%v not found", off, off+treeSize):
@@ -458,14 +580,153 @@ func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, tr
 				}
 				return
 			}
+			var childEncKey []byte
+			if encKey != nil {
+				childEncKey = deriveChunkKey(encKey, j)
+				chunk = decryptChunk(chunk, childEncKey)
+			}
 			if soff < off {
 				soff = off
 			}
-			self.join(b[soff-off:seoff-off], soff-roff, seoff-roff, depth-1, treeSize/self.branches, chunk, wg, errC, quitC)
+			self.join(b[soff-off:seoff-off], soff-roff, seoff-roff, depth-1, treeSize/fanout, chunk, childEncKey, wg, errC, quitC)
 		}(i)
 	} //for
 }
 
+// recoverChild reconstructs the missing child of an intermediate chunk with
+// the given number of children from its siblings and parities. The child is
+// returned as stored, or nil if too few of them could be retrieved.
+func (self *LazyChunkReader) recoverChild(parent *Chunk, children, missing int64, quitC chan bool) *Chunk {
+	refs := (int64(len(parent.SData)) - 8) / self.hashSize
+	if refs <= children {
+		return nil
+	}
+	shards := make([][]byte, refs)
+	var found int64
+	var shardSize int
+	for i := int64(0); i < refs && found < children; i++ {
+		if i == missing {
+			continue
+		}
+		shard := retrieve(parent.SData[8+i*self.hashSize:8+(i+1)*self.hashSize], self.chunkC, quitC)
+		if shard == nil {
+			continue
+		}
+		shards[i] = shard.SData
+		if i >= children {
+			shardSize = len(shard.SData)
+		}
+		found++
+	}
+	// a parity is always among the shards found as a data shard is missing
+	if found < children || shardSize == 0 {
+		return nil
+	}
+	for i := int64(0); i < children; i++ {
+		if shards[i] != nil && len(shards[i]) < shardSize {
+			shard := make([]byte, shardSize)
+			copy(shard, shards[i])
+			shards[i] = shard
+		}
+	}
+	data, err := erasureRecover(shards, int(children))
+	if err != nil {
+		return nil
+	}
+	key := Key(parent.SData[8+missing*self.hashSize : 8+(missing+1)*self.hashSize])
+	return self.verifyChild(key, data[missing])
+}
+
+// verifyChild finds the length of the recovered child zero padded by the
+// erasure coding by checking the candidate lengths against its key: the span
+// for content chunks and multiples of the hash size for intermediate chunks.
+func (self *LazyChunkReader) verifyChild(key Key, data []byte) *Chunk {
+	hasher := self.hashFunc()
+	check := func(n int64) *Chunk {
+		if n < 8 || n > int64(len(data)) {
+			return nil
+		}
+		hasher.ResetWithLength(data[:8])
+		hasher.Write(data[8:n])
+		if !bytes.Equal(hasher.Sum(nil), key) {
+			return nil
+		}
+		return &Chunk{Key: key, SData: data[:n], Size: ChunkSpan(data)}
+	}
+	if chunk := check(8 + ChunkSpan(data)); chunk != nil {
+		return chunk
+	}
+	for n := 8 + self.hashSize; n <= int64(len(data)); n += self.hashSize {
+		if chunk := check(n); chunk != nil {
+			return chunk
+		}
+	}
+	return nil
+}
+
+// Walk retrieves the chunks of the content tree under key one by one and calls
+// walkFn with each of them, as stored (i.e. encrypted if the content is).
+// Like Join, it accepts references to encrypted content.
+func (self *TreeChunker) Walk(key Key, chunkC chan *Chunk, walkFn func(*Chunk)) error {
+	var encKey []byte
+	if int64(len(key)) == 2*self.hashSize {
+		key, encKey = key[:self.hashSize], key[self.hashSize:]
+	}
+	chunk := retrieve(key, chunkC, nil)
+	if chunk == nil {
+		return fmt.Errorf("root chunk not found for %v", key.Hex())
+	}
+	treeSize := self.chunkSize
+	fanout := self.branches - redundancyLevel(decryptChunk(chunk, encKey).SData)
+	depth := 0
+	for ; treeSize < chunk.Size; treeSize *= fanout {
+		depth++
+	}
+	return self.walk(chunk, encKey, depth, treeSize/fanout, fanout, chunkC, walkFn)
+}
+
+func (self *TreeChunker) walk(chunk *Chunk, encKey []byte, depth int, treeSize, fanout int64, chunkC chan *Chunk, walkFn func(*Chunk)) error {
+	walkFn(chunk)
+	chunk = decryptChunk(chunk, encKey)
+
+	// find appropriate block level like join does
+	for chunk.Size < treeSize && depth > 0 {
+		treeSize /= fanout
+		depth--
+	}
+	if depth == 0 {
+		return nil
+	}
+	children := (chunk.Size + treeSize - 1) / treeSize
+	// the parities of redundant content follow the children
+	for i := children; i < (int64(len(chunk.SData))-8)/self.hashSize; i++ {
+		parityKey := chunk.SData[8+i*self.hashSize : 8+(i+1)*self.hashSize]
+		parity := retrieve(parityKey, chunkC, nil)
+		if parity == nil {
+			return fmt.Errorf("chunk %v not found", Key(parityKey).Log())
+		}
+		walkFn(parity)
+	}
+	for i := int64(0); i < children; i++ {
+		if int64(len(chunk.SData)) < 8+(i+1)*self.hashSize {
+			return fmt.Errorf("chunk %v is truncated", chunk.Key.Log())
+		}
+		childKey := chunk.SData[8+i*self.hashSize : 8+(i+1)*self.hashSize]
+		child := retrieve(childKey, chunkC, nil)
+		if child == nil {
+			return fmt.Errorf("chunk %v not found", Key(childKey).Log())
+		}
+		var childEncKey []byte
+		if encKey != nil {
+			childEncKey = deriveChunkKey(encKey, i)
+		}
+		if err := self.walk(child, childEncKey, depth-1, treeSize/fanout, fanout, chunkC, walkFn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // the helper method submits chunks for a key to a oueue (DPA) and
 // block until they time out or arrive
 // abort if quitC is readable
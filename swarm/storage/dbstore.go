@@ -53,8 +53,10 @@ const (
 	gcArrayFreeRatio = 0.1
 
 	// key prefixes for leveldb storage
-	kpIndex = 0
-	kpData  = 1
+	kpIndex   = 0
+	kpData    = 1
+	kpPin     = 6 // pin count of a chunk
+	kpPinRoot = 7 // pinned content root
 )
 
 var (
@@ -62,6 +64,7 @@ var (
 	keyEntryCnt  = []byte{3}
 	keyDataIdx   = []byte{4}
 	keyGCPos     = []byte{5}
+	keyPinnedCnt = []byte{8}
 )
 
 type gcItem struct {
@@ -76,6 +79,8 @@ type DbStore struct {
 	// this should be stored in db, accessed transactionally
 	entryCnt, accessCnt, dataIdx, capacity uint64
 
+	pinnedCnt, pinQuota uint64 // chunks with a pin count and their maximum
+
 	gcPos, gcStartPos []byte
 	gcArray           []*gcItem
 
@@ -110,6 +115,12 @@ func NewDbStore(path string, hash SwarmHasher, capacity uint64, radius int) (s *
 	if s.gcPos == nil {
 		s.gcPos = s.gcStartPos
 	}
+	if data, err := s.db.Get(keyPinnedCnt); err == nil {
+		s.pinnedCnt = BytesToU64(data)
+	} else {
+		// the store predates counting the pinned chunks
+		s.pinnedCnt = s.countPinned()
+	}
 	return
 }
 
@@ -171,7 +182,7 @@ func decodeIndex(data []byte, index *dpaDBIndex) {
 
 func decodeData(data []byte, chunk *Chunk) {
 	chunk.SData = data
-	chunk.Size = int64(binary.LittleEndian.Uint64(data[0:8]))
+	chunk.Size = ChunkSpan(data)
 }
 
 func gcListPartition(list []*gcItem, left int, right int, pivotIndex int) int {
@@ -211,7 +222,13 @@ func gcListSelect(list []*gcItem, left int, right int, n int) int {
 	}
 }
 
-func (s *DbStore) collectGarbage(ratio float32) {
+// collectGarbage evicts the least accessed ratio of a sample of the chunks,
+// never evicting pinned ones. It returns the number of chunks evicted.
+func (s *DbStore) collectGarbage(ratio float32) int {
+	if s.entryCnt <= s.pinnedCnt {
+		log.Debug(fmt.Sprintf("DbStore: no unpinned chunks to collect, %v entries of which %v pinned", s.entryCnt, s.pinnedCnt))
+		return 0
+	}
 	it := s.db.NewIterator()
 	it.Seek(s.gcPos)
 	if it.Valid() {
@@ -221,7 +238,7 @@ func (s *DbStore) collectGarbage(ratio float32) {
 	}
 	gcnt := 0
 
-	for (gcnt < gcArraySize) && (uint64(gcnt) < s.entryCnt) {
+	for visited := uint64(0); (gcnt < gcArraySize) && (visited < s.entryCnt); visited++ {
 
 		if (s.gcPos == nil) || (s.gcPos[0] != kpIndex) {
 			it.Seek(s.gcStartPos)
@@ -236,15 +253,18 @@ func (s *DbStore) collectGarbage(ratio float32) {
 			break
 		}
 
-		gci := new(gcItem)
-		gci.idxKey = s.gcPos
-		var index dpaDBIndex
-		decodeIndex(it.Value(), &index)
-		gci.idx = index.Idx
-		// the smaller, the more likely to be gc'd
-		gci.value = getIndexGCValue(&index)
-		s.gcArray[gcnt] = gci
-		gcnt++
+		if !s.isPinned(s.gcPos[1:]) {
+			gci := new(gcItem)
+			// the iterator reuses its key buffer
+			gci.idxKey = append([]byte{}, s.gcPos...)
+			var index dpaDBIndex
+			decodeIndex(it.Value(), &index)
+			gci.idx = index.Idx
+			// the smaller, the more likely to be gc'd
+			gci.value = getIndexGCValue(&index)
+			s.gcArray[gcnt] = gci
+			gcnt++
+		}
 		it.Next()
 		if it.Valid() {
 			s.gcPos = it.Key()
@@ -254,22 +274,29 @@ func (s *DbStore) collectGarbage(ratio float32) {
 	}
 	it.Release()
 
+	if gcnt == 0 {
+		log.Warn(fmt.Sprintf("DbStore: no unpinned chunks to collect, %v entries with capacity %v", s.entryCnt, s.capacity))
+		return 0
+	}
 	cutidx := gcListSelect(s.gcArray, 0, gcnt-1, int(float32(gcnt)*ratio))
 	cutval := s.gcArray[cutidx].value
 
 	// fmt.Print(gcnt, " ", s.entryCnt, " ")
 
 	// actual gc
+	collected := 0
 	for i := 0; i < gcnt; i++ {
 		if s.gcArray[i].value <= cutval {
 			gcCounter.Inc(1)
 			s.delete(s.gcArray[i].idx, s.gcArray[i].idxKey)
+			collected++
 		}
 	}
 
 	// fmt.Println(s.entryCnt)
 
 	s.db.Put(keyGCPos, s.gcPos)
+	return collected
 }
 
 // Export writes all chunks from the store to a tar archive, returning the
@@ -372,11 +399,8 @@ func (s *DbStore) Cleanup() {
 			s.delete(index.Idx, getIndexKey(key[1:]))
 			errorsFound++
 		} else {
-			hasher := s.hashfunc()
-			hasher.Write(data)
-			hash := hasher.Sum(nil)
-			if !bytes.Equal(hash, key[1:]) {
-				log.Warn(fmt.Sprintf("Found invalid chunk. Hash mismatch. hash=%x, key=%x", hash, key[:]))
+			if !ValidChunk(s.hashfunc, key[1:], data) {
+				log.Warn(fmt.Sprintf("Found invalid chunk. Hash mismatch. key=%x", key[:]))
 				s.delete(index.Idx, getIndexKey(key[1:]))
 				errorsFound++
 			}
@@ -485,10 +509,7 @@ func (s *DbStore) Get(key Key) (chunk *Chunk, err error) {
 			return
 		}
 
-		hasher := s.hashfunc()
-		hasher.Write(data)
-		hash := hasher.Sum(nil)
-		if !bytes.Equal(hash, key) {
+		if !ValidChunk(s.hashfunc, key, data) {
 			s.delete(index.Idx, getIndexKey(key))
 			log.Warn("Invalid Chunk in Database. Please repair with command: 'swarm cleandb'")
 		}
@@ -521,6 +542,7 @@ func (s *DbStore) setCapacity(c uint64) {
 	defer s.lock.Unlock()
 
 	s.capacity = c
+	s.pinQuota = uint64(float64(c) * pinQuotaRatio)
 
 	if s.entryCnt > c {
 		ratio := float32(1.01) - float32(c)/float32(s.entryCnt)
@@ -531,7 +553,9 @@ func (s *DbStore) setCapacity(c uint64) {
 			ratio = 1
 		}
 		for s.entryCnt > c {
-			s.collectGarbage(ratio)
+			if s.collectGarbage(ratio) == 0 {
+				break
+			}
 		}
 	}
 }
@@ -599,3 +623,9 @@ func (self *dbSyncIterator) Next() (key Key) {
 	self.it.Release()
 	return nil
 }
+
+// Release releases the db iterator, needed when the iteration is abandoned
+// before Next returns nil
+func (self *dbSyncIterator) Release() {
+	self.it.Release()
+}
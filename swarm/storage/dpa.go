@@ -48,7 +48,9 @@ const (
 )
 
 var (
-	notFound = errors.New("not found")
+	notFound                  = errors.New("not found")
+	errEncryptionNotSupported = errors.New("chunker doesn't support encryption")
+	errRedundancyNotSupported = errors.New("chunker doesn't support redundancy")
 )
 
 type DPA struct {
@@ -100,6 +102,27 @@ func (self *DPA) Store(data io.Reader, size int64, swg *sync.WaitGroup, wwg *syn
 	return self.Chunker.Split(data, size, self.storeC, swg, wwg)
 }
 
+// Public API. Main entry point for encrypted document storage. The returned
+// reference includes the decryption key and can be passed to Retrieve.
+func (self *DPA) StoreEncrypted(data io.Reader, size int64, swg *sync.WaitGroup, wwg *sync.WaitGroup) (key Key, err error) {
+	splitter, ok := self.Chunker.(EncryptingSplitter)
+	if !ok {
+		return nil, errEncryptionNotSupported
+	}
+	return splitter.SplitEncrypted(data, size, self.storeC, swg, wwg)
+}
+
+// Public API. Main entry point for storage with erasure coded redundancy,
+// encrypted if encrypt is set. Up to level missing children of every
+// intermediate chunk can be recovered on retrieval.
+func (self *DPA) StoreRedundant(data io.Reader, size int64, level int, encrypt bool, swg *sync.WaitGroup, wwg *sync.WaitGroup) (key Key, err error) {
+	splitter, ok := self.Chunker.(RedundantSplitter)
+	if !ok {
+		return nil, errRedundancyNotSupported
+	}
+	return splitter.SplitRedundant(data, size, level, encrypt, self.storeC, swg, wwg)
+}
+
 func (self *DPA) Start() {
 	self.lock.Lock()
 	defer self.lock.Unlock()
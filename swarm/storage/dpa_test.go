@@ -82,6 +82,57 @@ func TestDPArandom(t *testing.T) {
 	}
 }
 
+func TestDPAEncrypted(t *testing.T) {
+	dbStore := initDbStore(t)
+	dbStore.setCapacity(50000)
+	memStore := NewMemStore(dbStore, defaultCacheCapacity)
+	localStore := &LocalStore{
+		memStore,
+		dbStore,
+	}
+	chunker := NewTreeChunker(NewChunkerParams())
+	dpa := &DPA{
+		Chunker:    chunker,
+		ChunkStore: localStore,
+	}
+	dpa.Start()
+	defer dpa.Stop()
+	defer os.RemoveAll("/tmp/bzz")
+
+	for _, size := range []int{3, 4096, 0x100000} {
+		reader, slice := testDataReaderAndSlice(size)
+		wg := &sync.WaitGroup{}
+		key, err := dpa.StoreEncrypted(reader, int64(size), wg, nil)
+		if err != nil {
+			t.Fatalf("size %d: store error: %v", size, err)
+		}
+		wg.Wait()
+		if len(key) != 2*int(chunker.hashSize) {
+			t.Fatalf("size %d: reference length mismatch: have %d, want %d", size, len(key), 2*chunker.hashSize)
+		}
+		// The content can be retrieved with the decryption key
+		resultSlice := make([]byte, size)
+		if _, err := dpa.Retrieve(key).ReadAt(resultSlice, 0); err != io.EOF {
+			t.Fatalf("size %d: retrieve error: %v", size, err)
+		}
+		if !bytes.Equal(slice, resultSlice) {
+			t.Errorf("size %d: content mismatch", size)
+		}
+		// The stored root chunk doesn't reveal its contents
+		root, err := localStore.Get(key[:chunker.hashSize])
+		if err != nil {
+			t.Fatalf("size %d: root chunk not found: %v", size, err)
+		}
+		if size <= 4096 && bytes.Equal(root.SData[8:], slice) {
+			t.Errorf("size %d: root chunk stored in plaintext", size)
+		}
+		// Without the decryption key the content is garbled
+		if _, err := dpa.Retrieve(key[:chunker.hashSize]).ReadAt(resultSlice, 0); err == io.EOF && bytes.Equal(slice, resultSlice) {
+			t.Errorf("size %d: content retrieved without decryption key", size)
+		}
+	}
+}
+
 func TestDPA_capacity(t *testing.T) {
 	dbStore := initDbStore(t)
 	memStore := NewMemStore(dbStore, defaultCacheCapacity)
@@ -141,3 +192,90 @@ func TestDPA_capacity(t *testing.T) {
 		t.Errorf("Comparison error after clearing memStore.")
 	}
 }
+
+// lossyStore is a chunk store failing to retrieve the lost chunks
+type lossyStore struct {
+	ChunkStore
+	lost map[string]bool
+}
+
+func (self *lossyStore) Get(key Key) (*Chunk, error) {
+	if self.lost[string(key)] {
+		return nil, notFound
+	}
+	return self.ChunkStore.Get(key)
+}
+
+func TestDPARedundant(t *testing.T) {
+	dbStore := initDbStore(t)
+	dbStore.setCapacity(50000)
+	memStore := NewMemStore(dbStore, defaultCacheCapacity)
+	store := &lossyStore{
+		ChunkStore: &LocalStore{memStore, dbStore},
+		lost:       make(map[string]bool),
+	}
+	chunker := NewTreeChunker(NewChunkerParams())
+	dpa := &DPA{
+		Chunker:    chunker,
+		ChunkStore: store,
+	}
+	dpa.Start()
+	defer dpa.Stop()
+	defer os.RemoveAll("/tmp/bzz")
+
+	const level = 2
+	for _, encrypt := range []bool{false, true} {
+		for _, size := range []int{3 * 4096, 0x100000} {
+			reader, slice := testDataReaderAndSlice(size)
+			wg := &sync.WaitGroup{}
+			key, err := dpa.StoreRedundant(reader, int64(size), level, encrypt, wg, nil)
+			if err != nil {
+				t.Fatalf("size %d: store error: %v", size, err)
+			}
+			wg.Wait()
+			rootKey, encKey := key[:chunker.hashSize], []byte(nil)
+			if encrypt {
+				encKey = key[chunker.hashSize:]
+			}
+
+			// lose the first children of the root chunk as well as the
+			// first child of the first of them, up to the redundancy level
+			root, err := store.Get(rootKey)
+			if err != nil {
+				t.Fatalf("size %d: root chunk not found: %v", size, err)
+			}
+			root = decryptChunk(root, encKey)
+			if redundancyLevel(root.SData) != level {
+				t.Fatalf("size %d: redundancy level mismatch: have %d, want %d", size, redundancyLevel(root.SData), level)
+			}
+			first := Key(root.SData[8 : 8+chunker.hashSize])
+			if size > int(chunker.chunkSize)*int(chunker.branches-level) {
+				child, err := store.Get(first)
+				if err != nil {
+					t.Fatalf("size %d: child chunk not found: %v", size, err)
+				}
+				if encrypt {
+					child = decryptChunk(child, deriveChunkKey(encKey, 0))
+				}
+				store.lost[string(child.SData[8:8+chunker.hashSize])] = true
+			}
+			for i := int64(0); i < level; i++ {
+				store.lost[string(root.SData[8+i*chunker.hashSize:8+(i+1)*chunker.hashSize])] = true
+			}
+
+			resultSlice := make([]byte, size)
+			if _, err := dpa.Retrieve(key).ReadAt(resultSlice, 0); err != io.EOF {
+				t.Fatalf("size %d: retrieve error: %v", size, err)
+			}
+			if !bytes.Equal(slice, resultSlice) {
+				t.Fatalf("size %d: content mismatch", size)
+			}
+
+			// losing more children than the redundancy level fails
+			store.lost[string(root.SData[8+level*chunker.hashSize:8+(level+1)*chunker.hashSize])] = true
+			if _, err := dpa.Retrieve(key).ReadAt(resultSlice, 0); err == nil || err == io.EOF {
+				t.Fatalf("size %d: expected retrieve error", size)
+			}
+		}
+	}
+}
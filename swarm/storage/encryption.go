@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/aaechain/go-aaechain/crypto/sha3"
+)
+
+/*
+Encrypted uploads protect the content of the chunks from the nodes storing
+them. A random root key is generated for every upload, the key of each chunk is
+derived from the key of its parent chunk and its position among its siblings:
+
+	key(root)     = root key
+	key(child_i)  = keccak256(key(parent) || uint64(i))
+
+The payload of a chunk is XORed with a keystream built from its key, the 8 byte
+size prefix is left in the clear so the chunk stores can still account for the
+subtree sizes. Chunks are addressed by the hash of their encrypted form, the
+reference of the content is the address of the root chunk followed by the root
+key.
+*/
+
+// EncryptionKeyLength is the length of the chunk encryption keys.
+const EncryptionKeyLength = 32
+
+// NewEncryptionKey generates a random root key for an encrypted upload.
+func NewEncryptionKey() ([]byte, error) {
+	key := make([]byte, EncryptionKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// deriveChunkKey derives the encryption key of the i-th child of the chunk
+// encrypted with the given key.
+func deriveChunkKey(parent []byte, i int64) []byte {
+	var index [8]byte
+	binary.LittleEndian.PutUint64(index[:], uint64(i))
+
+	hasher := sha3.NewKeccak256()
+	hasher.Write(parent)
+	hasher.Write(index[:])
+	return hasher.Sum(nil)
+}
+
+// transformChunkData encrypts or decrypts the payload of the chunk data with
+// the given key, leaving the size prefix intact. The input isn't modified.
+func transformChunkData(key []byte, data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data[:8])
+
+	var (
+		counter [4]byte
+		hasher  = sha3.NewKeccak256()
+		segment []byte
+	)
+	for i := 8; i < len(data); i++ {
+		if (i-8)%EncryptionKeyLength == 0 {
+			binary.LittleEndian.PutUint32(counter[:], uint32((i-8)/EncryptionKeyLength))
+			hasher.Reset()
+			hasher.Write(key)
+			hasher.Write(counter[:])
+			segment = hasher.Sum(segment[:0])
+		}
+		out[i] = data[i] ^ segment[(i-8)%EncryptionKeyLength]
+	}
+	return out
+}
+
+// decryptChunk returns a copy of the retrieved chunk with its payload decrypted
+// with the given key, or the chunk itself if the key is nil.
+func decryptChunk(chunk *Chunk, key []byte) *Chunk {
+	if key == nil {
+		return chunk
+	}
+	return &Chunk{
+		Key:   chunk.Key,
+		SData: transformChunkData(key, chunk.SData),
+		Size:  chunk.Size,
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+/*
+Redundant uploads protect the content tree against the loss of chunks. With
+redundancy level L every intermediate chunk references at most branches-L
+children followed by L parity chunks, computed with a systematic Reed-Solomon
+code over the children as stored (zero padded to the longest of them):
+
+	| span | child_0 ... child_k-1 | parity_0 ... parity_L-1 |
+
+Any L missing children of an intermediate chunk can be reconstructed from its
+remaining children and parities. The level is recorded in the most significant
+byte of the span of intermediate chunks so the joiner knows the branching of
+the tree; spans are masked with spanMask when read.
+*/
+
+// MaxRedundancyLevel is the highest number of parity chunks per intermediate
+// chunk.
+const MaxRedundancyLevel = 16
+
+const spanMask = 1<<56 - 1
+
+var errTooFewShards = errors.New("too few shards to recover data")
+
+// ChunkSpan returns the size of the data covered by the chunk, ignoring the
+// redundancy level recorded in the span of intermediate chunks.
+func ChunkSpan(data []byte) int64 {
+	return int64(binary.LittleEndian.Uint64(data[0:8]) & spanMask)
+}
+
+// redundancyLevel returns the redundancy level recorded in the span of the
+// chunk data.
+func redundancyLevel(data []byte) int64 {
+	return int64(data[7])
+}
+
+// GF(2^8) arithmetic with the primitive polynomial x^8+x^4+x^3+x^2+1
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// erasureRow returns the row of the encoding matrix producing shard i out of
+// k data shards: a row of the identity for data shards and a row of a Cauchy
+// matrix for parity shards, so that any k rows are linearly independent.
+func erasureRow(i, k int) []byte {
+	row := make([]byte, k)
+	if i < k {
+		row[i] = 1
+		return row
+	}
+	for j := range row {
+		row[j] = gfInv(byte(i) ^ byte(j))
+	}
+	return row
+}
+
+// erasureEncode returns the parity shards of the data shards, which must
+// all have the same length.
+func erasureEncode(data [][]byte, parities int) [][]byte {
+	k := len(data)
+	shards := make([][]byte, parities)
+	for p := range shards {
+		row := erasureRow(k+p, k)
+		shard := make([]byte, len(data[0]))
+		for j, d := range data {
+			for b := range d {
+				shard[b] ^= gfMul(row[j], d[b])
+			}
+		}
+		shards[p] = shard
+	}
+	return shards
+}
+
+// erasureRecover reconstructs the k data shards from the shards, nil for the
+// missing ones, the first k being the data shards followed by the parities.
+func erasureRecover(shards [][]byte, k int) ([][]byte, error) {
+	var (
+		rows      [][]byte
+		available [][]byte
+	)
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		rows = append(rows, erasureRow(i, k))
+		available = append(available, shard)
+		if len(rows) == k {
+			break
+		}
+	}
+	if len(rows) < k {
+		return nil, errTooFewShards
+	}
+	inv, err := gfInvertMatrix(rows)
+	if err != nil {
+		return nil, err
+	}
+	data := make([][]byte, k)
+	for j := range data {
+		if j < len(shards) && shards[j] != nil {
+			data[j] = shards[j]
+			continue
+		}
+		shard := make([]byte, len(available[0]))
+		for r, a := range available {
+			for b := range a {
+				shard[b] ^= gfMul(inv[j][r], a[b])
+			}
+		}
+		data[j] = shard
+	}
+	return data, nil
+}
+
+// gfInvertMatrix inverts the square matrix with Gauss-Jordan elimination.
+func gfInvertMatrix(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	a := make([][]byte, n)
+	inv := make([][]byte, n)
+	for i := range m {
+		a[i] = append([]byte{}, m[i]...)
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && a[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, errors.New("singular matrix")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		scale := gfInv(a[col][col])
+		for j := 0; j < n; j++ {
+			a[col][j] = gfMul(a[col][j], scale)
+			inv[col][j] = gfMul(inv[col][j], scale)
+		}
+		for i := 0; i < n; i++ {
+			if i == col || a[i][col] == 0 {
+				continue
+			}
+			f := a[i][col]
+			for j := 0; j < n; j++ {
+				a[i][j] ^= gfMul(f, a[col][j])
+				inv[i][j] ^= gfMul(f, inv[col][j])
+			}
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestErasureRecover(t *testing.T) {
+	for _, k := range []int{1, 4, 126} {
+		for _, parities := range []int{1, 2, 16} {
+			data := make([][]byte, k)
+			for i := range data {
+				data[i] = make([]byte, 64)
+				rand.Read(data[i])
+			}
+			shards := append(append([][]byte{}, data...), erasureEncode(data, parities)...)
+
+			// lose as many shards as there are parities, data shards first
+			for i := 0; i < parities; i++ {
+				shards[i] = nil
+			}
+			recovered, err := erasureRecover(shards, k)
+			if err != nil {
+				t.Fatalf("k=%d parities=%d: %v", k, parities, err)
+			}
+			for i := range data {
+				if !bytes.Equal(recovered[i], data[i]) {
+					t.Fatalf("k=%d parities=%d: shard %d mismatch", k, parities, i)
+				}
+			}
+
+			// one more shard lost cannot be recovered
+			for i := range shards {
+				if shards[i] != nil {
+					shards[i] = nil
+					break
+				}
+			}
+			if _, err := erasureRecover(shards, k); err != errTooFewShards {
+				t.Fatalf("k=%d parities=%d: expected %v, got %v", k, parities, errTooFewShards, err)
+			}
+		}
+	}
+}
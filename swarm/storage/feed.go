@@ -0,0 +1,231 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+package storage
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/common/hexutil"
+	"github.com/aaechain/go-aaechain/crypto"
+)
+
+/*
+Feeds give swarm content a stable, mutable name without an ENS transaction per
+update. A feed is identified by its owner and a topic, the owner publishes
+updates as chunks addressed by
+
+	keccak256(topic || user || uint64(epoch))
+
+instead of the hash of their content. The chunk data carries the feed, the
+epoch, the owner's signature and the update payload:
+
+	| size (8, LE) | topic (32) | user (20) | epoch (8, BE) | signature (65) | data |
+
+Storers accept such chunks only if the address matches the fields and the
+signature was made by the feed's user. Epochs start at 1 and are expected to be
+published consecutively, so the latest update can be found with a logarithmic
+number of lookups.
+*/
+
+const (
+	feedChunkSize          = 4096
+	feedUpdateHeaderLength = common.HashLength + common.AddressLength + 8 + 65
+
+	// MaxFeedUpdateDataLength is the maximum size of a feed update's payload.
+	MaxFeedUpdateDataLength = feedChunkSize - 8 - feedUpdateHeaderLength
+)
+
+var (
+	ErrFeedNotFound       = errors.New("feed update not found")
+	errFeedUpdateTooLarge = fmt.Errorf("feed update data exceeds %d bytes", MaxFeedUpdateDataLength)
+	errInvalidFeedUpdate  = errors.New("invalid feed update chunk")
+	errInvalidFeedEpoch   = errors.New("feed epochs start at 1")
+	errFeedSignature      = errors.New("feed update not signed by the feed's user")
+)
+
+// Feed identifies a stream of updates published by a user on a topic.
+type Feed struct {
+	Topic common.Hash    `json:"topic"`
+	User  common.Address `json:"user"`
+}
+
+// UpdateKey returns the address of the feed's update for the given epoch.
+func (f *Feed) UpdateKey(epoch uint64) Key {
+	var e [8]byte
+	binary.BigEndian.PutUint64(e[:], epoch)
+	return Key(crypto.Keccak256(f.Topic[:], f.User[:], e[:]))
+}
+
+// FeedUpdate is a signed update of a feed.
+type FeedUpdate struct {
+	Feed
+	Epoch     uint64        `json:"epoch"`
+	Data      hexutil.Bytes `json:"data"`
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// digest returns the hash signed by the feed's user.
+func (u *FeedUpdate) digest() []byte {
+	var e [8]byte
+	binary.BigEndian.PutUint64(e[:], u.Epoch)
+	return crypto.Keccak256(u.Topic[:], u.User[:], e[:], u.Data)
+}
+
+// Sign sets the user of the update to the owner of the key and signs it.
+func (u *FeedUpdate) Sign(prv *ecdsa.PrivateKey) (err error) {
+	u.User = crypto.PubkeyToAddress(prv.PublicKey)
+	u.Signature, err = crypto.Sign(u.digest(), prv)
+	return err
+}
+
+// Verify checks the update is well formed and signed by the feed's user.
+func (u *FeedUpdate) Verify() error {
+	if u.Epoch == 0 {
+		return errInvalidFeedEpoch
+	}
+	if len(u.Data) > MaxFeedUpdateDataLength {
+		return errFeedUpdateTooLarge
+	}
+	if len(u.Signature) != 65 {
+		return errFeedSignature
+	}
+	pub, err := crypto.SigToPub(u.digest(), u.Signature)
+	if err != nil || crypto.PubkeyToAddress(*pub) != u.User {
+		return errFeedSignature
+	}
+	return nil
+}
+
+// Chunk serialises the update into the chunk stored at its address.
+func (u *FeedUpdate) Chunk() *Chunk {
+	data := make([]byte, 8+feedUpdateHeaderLength+len(u.Data))
+	binary.LittleEndian.PutUint64(data, uint64(len(u.Data)))
+	pos := 8
+	pos += copy(data[pos:], u.Topic[:])
+	pos += copy(data[pos:], u.User[:])
+	binary.BigEndian.PutUint64(data[pos:], u.Epoch)
+	pos += 8
+	pos += copy(data[pos:], u.Signature)
+	copy(data[pos:], u.Data)
+
+	return &Chunk{
+		Key:   u.UpdateKey(u.Epoch),
+		SData: data,
+		Size:  int64(len(u.Data)),
+	}
+}
+
+// ParseFeedUpdate decodes and verifies the feed update stored in a chunk.
+func ParseFeedUpdate(key Key, data []byte) (*FeedUpdate, error) {
+	if len(data) < 8+feedUpdateHeaderLength {
+		return nil, errInvalidFeedUpdate
+	}
+	size := binary.LittleEndian.Uint64(data)
+	if size != uint64(len(data)-8-feedUpdateHeaderLength) {
+		return nil, errInvalidFeedUpdate
+	}
+	u := new(FeedUpdate)
+	pos := 8
+	pos += copy(u.Topic[:], data[pos:])
+	pos += copy(u.User[:], data[pos:])
+	u.Epoch = binary.BigEndian.Uint64(data[pos:])
+	pos += 8
+	u.Signature = common.CopyBytes(data[pos : pos+65])
+	u.Data = common.CopyBytes(data[pos+65:])
+
+	if !bytes.Equal(u.UpdateKey(u.Epoch), key) {
+		return nil, errInvalidFeedUpdate
+	}
+	if err := u.Verify(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ValidChunk reports whaaeer data may be stored under key, either because key
+// is the hash of data or because data is a valid feed update for that address.
+func ValidChunk(hash SwarmHasher, key Key, data []byte) bool {
+	hasher := hash()
+	hasher.Write(data)
+	if bytes.Equal(hasher.Sum(nil), key) {
+		return true
+	}
+	_, err := ParseFeedUpdate(key, data)
+	return err == nil
+}
+
+// FeedHandler publishes and looks up feed updates in a chunk store.
+type FeedHandler struct {
+	store ChunkStore
+}
+
+func NewFeedHandler(store ChunkStore) *FeedHandler {
+	return &FeedHandler{store: store}
+}
+
+// Update verifies a signed update and stores it, returning its address.
+func (self *FeedHandler) Update(u *FeedUpdate) (Key, error) {
+	if err := u.Verify(); err != nil {
+		return nil, err
+	}
+	chunk := u.Chunk()
+	self.store.Put(chunk)
+	return chunk.Key, nil
+}
+
+// Lookup retrieves the update of the feed for the given epoch.
+func (self *FeedHandler) Lookup(feed *Feed, epoch uint64) (*FeedUpdate, error) {
+	key := feed.UpdateKey(epoch)
+	chunk, err := self.store.Get(key)
+	if err != nil || chunk.SData == nil {
+		return nil, ErrFeedNotFound
+	}
+	return ParseFeedUpdate(key, chunk.SData)
+}
+
+// LookupLatest retrieves the most recent update of the feed. The epochs are
+// probed with an exponential search followed by a binary search, relying on
+// updates being published for consecutive epochs.
+func (self *FeedHandler) LookupLatest(feed *Feed) (*FeedUpdate, error) {
+	latest, err := self.Lookup(feed, 1)
+	if err != nil {
+		return nil, err
+	}
+	// find an epoch without update, doubling the distance every time
+	lo, hi := uint64(1), uint64(2)
+	for {
+		u, err := self.Lookup(feed, hi)
+		if err != nil {
+			break
+		}
+		latest, lo, hi = u, hi, hi*2
+	}
+	// the latest update lies in [lo, hi)
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if u, err := self.Lookup(feed, mid); err == nil {
+			latest, lo = u, mid
+		} else {
+			hi = mid
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/crypto"
+)
+
+func newTestFeedUpdate(t *testing.T, topic string, epoch uint64, data []byte) *FeedUpdate {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	u := &FeedUpdate{
+		Feed:  Feed{Topic: crypto.Keccak256Hash([]byte(topic))},
+		Epoch: epoch,
+		Data:  data,
+	}
+	if err := u.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestFeedUpdateChunk(t *testing.T) {
+	u := newTestFeedUpdate(t, "foo", 3, []byte("bar"))
+	chunk := u.Chunk()
+	if !bytes.Equal(chunk.Key, u.UpdateKey(3)) {
+		t.Fatalf("chunk key mismatch: have %x, want %x", chunk.Key, u.UpdateKey(3))
+	}
+	if chunk.Size != 3 {
+		t.Fatalf("chunk size mismatch: have %d, want 3", chunk.Size)
+	}
+
+	parsed, err := ParseFeedUpdate(chunk.Key, chunk.SData)
+	if err != nil {
+		t.Fatalf("failed to parse update: %v", err)
+	}
+	if parsed.Feed != u.Feed || parsed.Epoch != u.Epoch || !bytes.Equal(parsed.Data, u.Data) {
+		t.Fatalf("parsed update mismatch: have %+v, want %+v", parsed, u)
+	}
+	hasher := MakeHashFunc(SHA3Hash)
+	if !ValidChunk(hasher, chunk.Key, chunk.SData) {
+		t.Fatal("feed update chunk not valid")
+	}
+
+	// an update stored under another epoch's address is rejected
+	if _, err := ParseFeedUpdate(u.UpdateKey(4), chunk.SData); err != errInvalidFeedUpdate {
+		t.Fatalf("wrong error for misplaced update: have %v, want %v", err, errInvalidFeedUpdate)
+	}
+	// as is an update with tampered data
+	tampered := common.CopyBytes(chunk.SData)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := ParseFeedUpdate(chunk.Key, tampered); err != errFeedSignature {
+		t.Fatalf("wrong error for tampered update: have %v, want %v", err, errFeedSignature)
+	}
+	if ValidChunk(hasher, chunk.Key, tampered) {
+		t.Fatal("tampered feed update chunk valid")
+	}
+	// and an update signed by someone else than the feed's user
+	u.User = common.HexToAddress("0x01")
+	if _, err := ParseFeedUpdate(u.UpdateKey(3), u.Chunk().SData); err != errFeedSignature {
+		t.Fatalf("wrong error for foreign update: have %v, want %v", err, errFeedSignature)
+	}
+}
+
+func TestFeedLookup(t *testing.T) {
+	dbStore := initDbStore(t)
+	defer dbStore.Close()
+	handler := NewFeedHandler(&LocalStore{
+		memStore: NewMemStore(dbStore, defaultCacheCapacity),
+		DbStore:  dbStore,
+	})
+
+	feed := &newTestFeedUpdate(t, "foo", 1, nil).Feed
+	if _, err := handler.LookupLatest(feed); err != ErrFeedNotFound {
+		t.Fatalf("wrong error for empty feed: have %v, want %v", err, ErrFeedNotFound)
+	}
+	for epoch := uint64(1); epoch <= 20; epoch++ {
+		data := []byte(fmt.Sprintf("update %d", epoch))
+		if _, err := handler.Update(newTestFeedUpdate(t, "foo", epoch, data)); err != nil {
+			t.Fatalf("epoch %d: failed to store update: %v", epoch, err)
+		}
+		latest, err := handler.LookupLatest(feed)
+		if err != nil {
+			t.Fatalf("epoch %d: failed to look up latest update: %v", epoch, err)
+		}
+		if latest.Epoch != epoch || !bytes.Equal(latest.Data, data) {
+			t.Fatalf("epoch %d: latest update mismatch: have epoch %d data %q", epoch, latest.Epoch, latest.Data)
+		}
+	}
+	u, err := handler.Lookup(feed, 7)
+	if err != nil {
+		t.Fatalf("failed to look up update: %v", err)
+	}
+	if want := []byte("update 7"); !bytes.Equal(u.Data, want) {
+		t.Fatalf("update data mismatch: have %q, want %q", u.Data, want)
+	}
+
+	// updates which aren't signed by the feed's user are refused
+	forged := newTestFeedUpdate(t, "foo", 21, []byte("forged"))
+	forged.Data = []byte("changed")
+	if _, err := handler.Update(forged); err != errFeedSignature {
+		t.Fatalf("wrong error for forged update: have %v, want %v", err, errFeedSignature)
+	}
+}
@@ -17,7 +17,6 @@
 package storage
 
 import (
-	"encoding/binary"
 
 	"github.com/aaechain/go-aaechain/metrics"
 )
@@ -84,7 +83,7 @@ func (self *LocalStore) Get(key Key) (chunk *Chunk, err error) {
 	if err != nil {
 		return
 	}
-	chunk.Size = int64(binary.LittleEndian.Uint64(chunk.SData[0:8]))
+	chunk.Size = ChunkSpan(chunk.SData)
 	self.memStore.Put(chunk)
 	return
 }
@@ -19,6 +19,7 @@ package storage
 import (
 	"fmt"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/aaechain/go-aaechain/log"
@@ -27,23 +28,24 @@ import (
 /*
 Neaaeore is a cloud storage access abstaction layer for swarm
 it contains the shared logic of network served chunk store/retrieval requests
-both local (coming from DPA api) and remote (coming from peers via bzz protocol)
+both local (coming from DPA api) and remote (coming from peers via stream protocol)
 it implements the ChunkStore interface and embeds LocalStore
 
-It is called by the bzz protocol instances via Depo (the store/retrieve request handler)
+It is called by the stream protocol instances handling deliveries and retrieve requests
 a protocol instance is running on each peer, so this is heavily parallelised.
 Neaaeore falls back to a backend (CloudStorage interface)
-implemented by bzz/network/forwarder. forwarder or IPFS or IPΞS
+implemented by bzz/network/stream registry or IPFS or IPΞS
 */
 type Neaaeore struct {
 	hashfunc   SwarmHasher
 	localStore *LocalStore
 	cloud      CloudStore
+	lock       sync.Mutex // serialises opening and expiring requests
 }
 
 // backend engine for cloud store
 // It can be aggregate dispatching to several parallel implementations:
-// bzz/network/forwarder. forwarder or IPFS or IPΞS
+// bzz/network/stream registry or IPFS or IPΞS
 type CloudStore interface {
 	Store(*Chunk)
 	Deliver(*Chunk)
@@ -118,24 +120,53 @@ func (self *Neaaeore) Put(entry *Chunk) {
 
 // retrieve logic common for local and network chunk retrieval requests
 func (self *Neaaeore) Get(key Key) (*Chunk, error) {
-	var err error
+	return self.GetFor(key, nil)
+}
+
+// GetFor retrieves the chunk like Get on behalf of a requester, which is
+// recorded on the request before the retrieval from the cloud store starts
+// so that the request is not forwarded back to it and it gets the delivery
+func (self *Neaaeore) GetFor(key Key, requester interface{}) (*Chunk, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
 	chunk, err := self.localStore.Get(key)
-	if err == nil {
-		if chunk.Req == nil {
-			log.Trace(fmt.Sprintf("Neaaeore.Get: %v found locally", key))
-		} else {
-			log.Trace(fmt.Sprintf("Neaaeore.Get: %v hit on an existing request", key))
-			// no need to launch again
+	if err == nil && chunk.SData != nil {
+		log.Trace(fmt.Sprintf("Neaaeore.Get: %v found locally", key))
+		return chunk, nil
+	}
+	if err == nil && chunk.Req != nil && !chunk.Req.expired {
+		log.Trace(fmt.Sprintf("Neaaeore.Get: %v hit on an existing request", key))
+		// no need to launch again
+		if requester != nil {
+			chunk.Req.AddRequester(requester)
 		}
-		return chunk, err
+		return chunk, nil
 	}
-	// no data and no request status
+	// no data and no pending request
 	log.Trace(fmt.Sprintf("Neaaeore.Get: %v not found locally. open new request", key))
 	chunk = NewChunk(key, newRequesaaeatus(key))
+	if requester != nil {
+		chunk.Req.AddRequester(requester)
+	}
 	self.localStore.memStore.Put(chunk)
 	go self.cloud.Retrieve(chunk)
+	go self.expire(chunk.Req)
 	return chunk, nil
 }
 
+// expire marks the request as timed out if the chunk is not delivered within
+// the search timeout, so that a later Get opens a new request
+func (self *Neaaeore) expire(req *Requesaaeatus) {
+	select {
+	case <-req.C:
+	case <-time.After(searchTimeout):
+		self.lock.Lock()
+		req.expired = true
+		self.lock.Unlock()
+		log.Trace(fmt.Sprintf("Neaaeore: request for %v timed out", req.Key.Log()))
+	}
+}
+
 // Close neaaeore
 func (self *Neaaeore) Close() {}
@@ -0,0 +1,89 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCloudStore records the retrieve requests and their requesters
+type testCloudStore struct {
+	lock       sync.Mutex
+	requesters [][]interface{}
+}
+
+func (self *testCloudStore) Store(*Chunk)   {}
+func (self *testCloudStore) Deliver(*Chunk) {}
+
+func (self *testCloudStore) Retrieve(chunk *Chunk) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.requesters = append(self.requesters, chunk.Req.RequesterList())
+}
+
+func (self *testCloudStore) retrieved() [][]interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.requesters
+}
+
+func TestNeaaeoreRequest(t *testing.T) {
+	defer func(timeout time.Duration) { searchTimeout = timeout }(searchTimeout)
+	searchTimeout = 100 * time.Millisecond
+
+	dbStore := initDbStore(t)
+	defer dbStore.Close()
+	localStore := &LocalStore{
+		memStore: NewMemStore(dbStore, defaultCacheCapacity),
+		DbStore:  dbStore,
+	}
+	cloud := &testCloudStore{}
+	netStore := NewNeaaeore(MakeHashFunc(SHA3Hash), localStore, cloud, nil)
+
+	// the requester is registered before the retrieval starts
+	chunk, err := netStore.GetFor(ZeroKey, "peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := netStore.Get(ZeroKey); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	retrieved := cloud.retrieved()
+	if len(retrieved) != 1 {
+		t.Fatalf("expected 1 retrieval of a pending request, got %d", len(retrieved))
+	}
+	if len(retrieved[0]) != 1 || retrieved[0][0] != "peer" {
+		t.Fatalf("expected requesters [peer] on retrieval, got %v", retrieved[0])
+	}
+
+	// once the search timed out a new request is opened
+	time.Sleep(2 * searchTimeout)
+	retry, err := netStore.Get(ZeroKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.Req == chunk.Req {
+		t.Fatal("timed out request reused")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(cloud.retrieved()); n != 2 {
+		t.Fatalf("expected 2 retrievals after the timeout, got %d", n)
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/rlp"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+/*
+Pinning protects content from the garbage collection of the local store. Pinning
+a root records it together with the content trees it covers and the number and
+total size of their chunks, and increments the pin count of each of these
+chunks. Unpinning walks the recorded trees again to reverse both. Chunks shared
+by several pinned roots stay protected until all of them are unpinned, the
+garbage collector of the DbStore skips chunks with a pin count.
+
+The chunks are counted as pinned before the DPA makes sure they are all in the
+DbStore, so a chunk evicted while the content was walked is stored again
+instead of being left unprotected. At most pinQuotaRatio of the capacity of
+the DbStore can be pinned, so that garbage collection always has chunks to
+evict.
+*/
+
+// pinQuotaRatio is the fraction of the DbStore capacity which can be pinned
+const pinQuotaRatio = 0.5
+
+var (
+	ErrAlreadyPinned       = errors.New("root already pinned")
+	ErrNotPinned           = errors.New("root not pinned")
+	ErrPinQuotaExceeded    = errors.New("pin quota exceeded")
+	errPinningNotSupported = errors.New("chunk store doesn't support pinning")
+	errWalkNotSupported    = errors.New("chunker doesn't support walking content")
+)
+
+// PinnedRoot reports a pinned content root together with the number and total
+// size of the chunks it keeps from being garbage collected.
+type PinnedRoot struct {
+	Root   Key    `json:"root"`
+	Chunks uint64 `json:"chunks"`
+	Size   uint64 `json:"size"`
+}
+
+type pinRootRecord struct {
+	Chunks  uint64
+	Size    uint64
+	Content []Key // roots of the content trees pinned under the root
+}
+
+func getPinKey(key Key) []byte {
+	return append([]byte{kpPin}, key...)
+}
+
+func getPinRootKey(root Key) []byte {
+	return append([]byte{kpPinRoot}, root...)
+}
+
+// isPinned reports whaaeer the chunk has a pin count, the caller holds the lock
+func (s *DbStore) isPinned(key Key) bool {
+	_, err := s.db.Get(getPinKey(key))
+	return err == nil
+}
+
+// countPinned counts the chunks with a pin count
+func (s *DbStore) countPinned() uint64 {
+	it := s.db.NewIterator()
+	defer it.Release()
+	var count uint64
+	for ok := it.Seek([]byte{kpPin}); ok && it.Key()[0] == kpPin; ok = it.Next() {
+		count++
+	}
+	return count
+}
+
+// Pin records root as pinned together with the roots of the content trees it
+// covers and increments the pin count of the given distinct chunks of these
+// trees. It fails with ErrPinQuotaExceeded if the chunks not yet pinned would
+// exceed the pin quota.
+func (s *DbStore) Pin(root Key, content []Key, chunks []Key, size uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, err := s.db.Get(getPinRootKey(root)); err == nil {
+		return ErrAlreadyPinned
+	}
+	batch := new(leveldb.Batch)
+	pinnedCnt := s.pinnedCnt
+	for _, key := range chunks {
+		data, err := s.db.Get(getPinKey(key))
+		if err != nil {
+			pinnedCnt++
+		}
+		batch.Put(getPinKey(key), U64ToBytes(BytesToU64(data)+1))
+	}
+	if pinnedCnt > s.pinQuota {
+		return ErrPinQuotaExceeded
+	}
+	batch.Put(keyPinnedCnt, U64ToBytes(pinnedCnt))
+	record, err := rlp.EncodeToBytes(&pinRootRecord{
+		Chunks:  uint64(len(chunks)),
+		Size:    size,
+		Content: content,
+	})
+	if err != nil {
+		return err
+	}
+	batch.Put(getPinRootKey(root), record)
+	if err := s.db.Write(batch); err != nil {
+		return err
+	}
+	s.pinnedCnt = pinnedCnt
+	return nil
+}
+
+// Unpin removes root from the pinned roots and decrements the pin count of the
+// given chunks, which should be the ones it was pinned with
+func (s *DbStore) Unpin(root Key, chunks []Key) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, err := s.db.Get(getPinRootKey(root)); err != nil {
+		return ErrNotPinned
+	}
+	batch := new(leveldb.Batch)
+	pinnedCnt := s.pinnedCnt
+	for _, key := range chunks {
+		data, err := s.db.Get(getPinKey(key))
+		if err != nil {
+			log.Warn(fmt.Sprintf("DbStore.Unpin: chunk %v of root %v not pinned", key.Log(), root.Log()))
+			continue
+		}
+		if count := BytesToU64(data); count > 1 {
+			batch.Put(getPinKey(key), U64ToBytes(count-1))
+		} else {
+			batch.Delete(getPinKey(key))
+			pinnedCnt--
+		}
+	}
+	batch.Put(keyPinnedCnt, U64ToBytes(pinnedCnt))
+	batch.Delete(getPinRootKey(root))
+	if err := s.db.Write(batch); err != nil {
+		return err
+	}
+	s.pinnedCnt = pinnedCnt
+	return nil
+}
+
+// missing returns the given chunks which are not in the store
+func (s *DbStore) missing(keys []Key) []Key {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var missing []Key
+	for _, key := range keys {
+		if _, err := s.db.Get(getIndexKey(key)); err != nil {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// pinnedContent returns the roots of the content trees pinned under root
+func (s *DbStore) pinnedContent(root Key) ([]Key, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data, err := s.db.Get(getPinRootKey(root))
+	if err != nil {
+		return nil, ErrNotPinned
+	}
+	var record pinRootRecord
+	if err := rlp.DecodeBytes(data, &record); err != nil {
+		return nil, err
+	}
+	return record.Content, nil
+}
+
+// PinnedRoots lists the pinned roots
+func (s *DbStore) PinnedRoots() ([]*PinnedRoot, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	it := s.db.NewIterator()
+	defer it.Release()
+	var roots []*PinnedRoot
+	for ok := it.Seek([]byte{kpPinRoot}); ok; ok = it.Next() {
+		key := it.Key()
+		if key[0] != kpPinRoot {
+			break
+		}
+		var record pinRootRecord
+		if err := rlp.DecodeBytes(it.Value(), &record); err != nil {
+			return nil, err
+		}
+		roots = append(roots, &PinnedRoot{
+			Root:   Key(append([]byte{}, key[1:]...)),
+			Chunks: record.Chunks,
+			Size:   record.Size,
+		})
+	}
+	return roots, nil
+}
+
+// dbStore finds the persistent store underlying the DPA's chunk store
+func (self *DPA) dbStore() (*DbStore, error) {
+	store := self.ChunkStore
+	for {
+		switch s := store.(type) {
+		case *DbStore:
+			return s, nil
+		case *dpaChunkStore:
+			store = s.localStore
+		case *LocalStore:
+			store = s.DbStore
+		default:
+			return nil, errPinningNotSupported
+		}
+	}
+}
+
+// chunkKeys retrieves the chunks of the content under the given keys,
+// returning the distinct chunk keys and their total size
+func (self *DPA) chunkKeys(keys []Key) ([]Key, uint64, error) {
+	walker, ok := self.Chunker.(ChunkWalker)
+	if !ok {
+		return nil, 0, errWalkNotSupported
+	}
+	var (
+		seen   = make(map[string]bool)
+		chunks []Key
+		size   uint64
+	)
+	for _, key := range keys {
+		err := walker.Walk(key, self.retrieveC, func(chunk *Chunk) {
+			if seen[string(chunk.Key)] {
+				return
+			}
+			seen[string(chunk.Key)] = true
+			chunks = append(chunks, chunk.Key)
+			size += uint64(len(chunk.SData))
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return chunks, size, nil
+}
+
+// Pin protects the chunks of the content under the given keys from garbage
+// collection, recording them under root. The chunks are retrieved from the
+// network if needed.
+func (self *DPA) Pin(root Key, content []Key) (*PinnedRoot, error) {
+	dbStore, err := self.dbStore()
+	if err != nil {
+		return nil, err
+	}
+	chunks, size, err := self.chunkKeys(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := dbStore.Pin(root, content, chunks, size); err != nil {
+		return nil, err
+	}
+	// chunks evicted since they were walked are now pinned, store them again
+	for _, key := range dbStore.missing(chunks) {
+		chunk, err := self.Get(key)
+		if err == nil && chunk.SData == nil {
+			err = notFound
+		}
+		if err != nil {
+			dbStore.Unpin(root, chunks)
+			return nil, fmt.Errorf("error retrieving chunk %v of %v: %v", key.Log(), root.Log(), err)
+		}
+		dbStore.Put(&Chunk{Key: key, SData: chunk.SData, Size: chunk.Size})
+	}
+	return &PinnedRoot{Root: root, Chunks: uint64(len(chunks)), Size: size}, nil
+}
+
+// Unpin releases the chunks pinned under root.
+func (self *DPA) Unpin(root Key) error {
+	dbStore, err := self.dbStore()
+	if err != nil {
+		return err
+	}
+	content, err := dbStore.pinnedContent(root)
+	if err != nil {
+		return err
+	}
+	chunks, _, err := self.chunkKeys(content)
+	if err != nil {
+		return err
+	}
+	return dbStore.Unpin(root, chunks)
+}
+
+// PinnedRoots lists the pinned roots
+func (self *DPA) PinnedRoots() ([]*PinnedRoot, error) {
+	dbStore, err := self.dbStore()
+	if err != nil {
+		return nil, err
+	}
+	return dbStore.PinnedRoots()
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+package storage
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestDPAPin(t *testing.T) {
+	dbStore := initDbStore(t)
+	defer dbStore.Close()
+	memStore := NewMemStore(dbStore, defaultCacheCapacity)
+	localStore := &LocalStore{
+		memStore,
+		dbStore,
+	}
+	dpa := &DPA{
+		Chunker:    NewTreeChunker(NewChunkerParams()),
+		ChunkStore: localStore,
+	}
+	dpa.Start()
+	defer dpa.Stop()
+
+	store := func(size int) (Key, []byte) {
+		reader, slice := testDataReaderAndSlice(size)
+		wg := &sync.WaitGroup{}
+		key, err := dpa.Store(reader, int64(size), wg, nil)
+		if err != nil {
+			t.Fatalf("store error: %v", err)
+		}
+		wg.Wait()
+		return key, slice
+	}
+	pinned, slice := store(0x10000)
+	unpinned, _ := store(0x10000)
+
+	// pinning beyond the quota is refused
+	dbStore.pinQuota = 16
+	if _, err := dpa.Pin(pinned, []Key{pinned}); err != ErrPinQuotaExceeded {
+		t.Fatalf("wrong error pinning beyond the quota: have %v, want %v", err, ErrPinQuotaExceeded)
+	}
+	if roots, _ := dpa.PinnedRoots(); len(roots) != 0 || dbStore.pinnedCnt != 0 {
+		t.Fatalf("pinned after exceeding the quota: %v, %d chunks", roots, dbStore.pinnedCnt)
+	}
+	dbStore.pinQuota = 17
+
+	// a chunk evicted from the db but still cached is stored again when pinned
+	data, err := dbStore.db.Get(getIndexKey(pinned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index dpaDBIndex
+	decodeIndex(data, &index)
+	dbStore.delete(index.Idx, getIndexKey(pinned))
+
+	// 16 data chunks and their parent
+	root, err := dpa.Pin(pinned, []Key{pinned})
+	if err != nil {
+		t.Fatalf("pin error: %v", err)
+	}
+	if _, err := dbStore.Get(pinned); err != nil {
+		t.Fatalf("evicted chunk not stored again when pinned: %v", err)
+	}
+	if dbStore.pinnedCnt != 17 {
+		t.Fatalf("pinned chunk count mismatch: have %d, want 17", dbStore.pinnedCnt)
+	}
+	if want := uint64(16*(8+4096) + 8 + 16*32); root.Chunks != 17 || root.Size != want {
+		t.Fatalf("pinned root mismatch: have %d chunks %d bytes, want 17 chunks %d bytes", root.Chunks, root.Size, want)
+	}
+	if _, err := dpa.Pin(pinned, []Key{pinned}); err != ErrAlreadyPinned {
+		t.Fatalf("wrong error pinning twice: have %v, want %v", err, ErrAlreadyPinned)
+	}
+	roots, err := dpa.PinnedRoots()
+	if err != nil {
+		t.Fatalf("error listing pinned roots: %v", err)
+	}
+	if len(roots) != 1 || !bytes.Equal(roots[0].Root, pinned) || roots[0].Chunks != root.Chunks || roots[0].Size != root.Size {
+		t.Fatalf("pinned roots mismatch: have %v, want [%v]", roots, root)
+	}
+
+	// garbage collection evicts everything but the pinned chunks
+	dbStore.setCapacity(1)
+	if dbStore.entryCnt != 17 {
+		t.Fatalf("entry count mismatch after gc: have %d, want 17", dbStore.entryCnt)
+	}
+	dpa.ChunkStore = dbStore
+	resultSlice := make([]byte, len(slice))
+	if _, err := dpa.Retrieve(pinned).ReadAt(resultSlice, 0); err != io.EOF {
+		t.Fatalf("error retrieving pinned content: %v", err)
+	}
+	if !bytes.Equal(slice, resultSlice) {
+		t.Fatal("pinned content mismatch")
+	}
+	if _, err := dbStore.Get(unpinned); err == nil {
+		t.Fatal("unpinned content survived gc")
+	}
+
+	// after unpinning the chunks can be collected
+	if err := dpa.Unpin(pinned); err != nil {
+		t.Fatalf("unpin error: %v", err)
+	}
+	if err := dpa.Unpin(pinned); err != ErrNotPinned {
+		t.Fatalf("wrong error unpinning twice: have %v, want %v", err, ErrNotPinned)
+	}
+	if roots, _ := dpa.PinnedRoots(); len(roots) != 0 || dbStore.pinnedCnt != 0 {
+		t.Fatalf("pinned left after unpinning: %v, %d chunks", roots, dbStore.pinnedCnt)
+	}
+	dbStore.setCapacity(1)
+	if dbStore.entryCnt > 1 {
+		t.Fatalf("entry count mismatch after unpinning: have %d, want at most 1", dbStore.entryCnt)
+	}
+}
@@ -133,6 +133,9 @@ type Requesaaeatus struct {
 	Source     Peer
 	C          chan bool
 	Requesters map[uint64][]interface{}
+
+	lock    sync.Mutex // guards Requesters
+	expired bool       // the search timed out, guarded by the lock of the Neaaeore
 }
 
 func newRequesaaeatus(key Key) *Requesaaeatus {
@@ -143,6 +146,24 @@ func newRequesaaeatus(key Key) *Requesaaeatus {
 	}
 }
 
+// AddRequester records a peer requesting the chunk
+func (self *Requesaaeatus) AddRequester(requester interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.Requesters[0] = append(self.Requesters[0], requester)
+}
+
+// RequesterList returns the peers requesting the chunk
+func (self *Requesaaeatus) RequesterList() []interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	var requesters []interface{}
+	for _, reqs := range self.Requesters {
+		requesters = append(requesters, reqs...)
+	}
+	return requesters
+}
+
 // Chunk also serves as a request object passed to ChunkStores
 // in case it is a retrieval request, Data is nil and Size is 0
 // Note that Size is not the size of the data chunk, which is Data.Size()
@@ -208,6 +229,25 @@ type Splitter interface {
 	Append(Key, io.Reader, chan *Chunk, *sync.WaitGroup, *sync.WaitGroup) (Key, error)
 }
 
+// EncryptingSplitter is implemented by splitters able to encrypt the chunks they
+// produce. The returned reference contains the key needed to decrypt the content
+// and is accepted by the Joiner like a plain root key.
+type EncryptingSplitter interface {
+	SplitEncrypted(io.Reader, int64, chan *Chunk, *sync.WaitGroup, *sync.WaitGroup) (Key, error)
+}
+
+// RedundantSplitter is implemented by splitters able to add erasure coded
+// parities to the intermediate chunks they produce, see SplitRedundant.
+type RedundantSplitter interface {
+	SplitRedundant(io.Reader, int64, int, bool, chan *Chunk, *sync.WaitGroup, *sync.WaitGroup) (Key, error)
+}
+
+// ChunkWalker is implemented by chunkers able to enumerate the chunks making
+// up the content under a root key, as used for pinning.
+type ChunkWalker interface {
+	Walk(Key, chan *Chunk, func(*Chunk)) error
+}
+
 type Joiner interface {
 	/*
 	   Join reconstructs original content based on a root key.
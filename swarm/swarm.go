@@ -44,6 +44,8 @@ import (
 	httpapi "github.com/aaechain/go-aaechain/swarm/api/http"
 	"github.com/aaechain/go-aaechain/swarm/fuse"
 	"github.com/aaechain/go-aaechain/swarm/network"
+	"github.com/aaechain/go-aaechain/swarm/network/stream"
+	"github.com/aaechain/go-aaechain/swarm/pss"
 	"github.com/aaechain/go-aaechain/swarm/storage"
 )
 
@@ -59,16 +61,16 @@ var (
 
 // the swarm stack
 type Swarm struct {
-	config      *api.Config            // swarm configuration
-	api         *api.Api               // high level api layer (fs/manifest)
-	dns         api.Resolver           // DNS registrar
-	dbAccess    *network.DbAccess      // access to local chunk db iterator and storage counter
-	storage     storage.ChunkStore     // internal access to storage, common interface to cloud storage backends
-	dpa         *storage.DPA           // distributed preimage archive, the local API to the storage with document level storage/retrieval support
-	depo        network.StorageHandler // remote request handler, interface between bzz protocol and the storage
-	cloud       storage.CloudStore     // procurement, cloud storage backend (can multi-cloud)
-	hive        *network.Hive          // the logistic manager
-	backend     chequebook.Backend     // simple blockchain Backend
+	config      *api.Config        // swarm configuration
+	api         *api.Api           // high level api layer (fs/manifest)
+	dns         api.Resolver       // DNS registrar
+	storage     storage.ChunkStore // internal access to storage, common interface to cloud storage backends
+	dpa         *storage.DPA       // distributed preimage archive, the local API to the storage with document level storage/retrieval support
+	cloud       storage.CloudStore // procurement, cloud storage backend (can multi-cloud)
+	streamer    *stream.Registry   // stream protocol, syncing and retrieval of chunks
+	hive        *network.Hive      // the logistic manager
+	pss         *pss.Pss           // postal service messaging routed by the hive
+	backend     chequebook.Backend // simple blockchain Backend
 	privateKey  *ecdsa.PrivateKey
 	corsString  string
 	swapEnabled bool
@@ -118,9 +120,6 @@ func NewSwarm(ctx *node.ServiceContext, backend chequebook.Backend, config *api.
 	// setup local store
 	log.Debug(fmt.Sprintf("Set up local storage"))
 
-	self.dbAccess = network.NewDbAccess(self.lstore)
-	log.Debug(fmt.Sprintf("Set up local db access (iterator/counter)"))
-
 	// set up the kademlia hive
 	self.hive = network.NewHive(
 		common.HexToHash(self.config.BzzKey), // key to hive (kademlia base address)
@@ -130,18 +129,27 @@ func NewSwarm(ctx *node.ServiceContext, backend chequebook.Backend, config *api.
 	)
 	log.Debug(fmt.Sprintf("Set up swarm network with Kademlia hive"))
 
+	// set up postal service messaging on top of the hive
+	self.pss, err = pss.NewPss(self.hive, self.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug(fmt.Sprintf("-> pss messaging over the kademlia"))
+
 	// setup cloud storage backend
-	self.cloud = network.NewForwarder(self.hive)
-	log.Debug(fmt.Sprintf("-> set swarm forwarder as cloud storage backend"))
+	self.streamer, err = stream.NewRegistry(self.hive, self.lstore, hash, config.SyncParams)
+	if err != nil {
+		return nil, err
+	}
+	self.cloud = self.streamer
+	log.Debug(fmt.Sprintf("-> set swarm stream registry as cloud storage backend"))
 
 	// setup cloud storage internal access layer
-	self.storage = storage.NewNeaaeore(hash, self.lstore, self.cloud, config.StoreParams)
+	netStore := storage.NewNeaaeore(hash, self.lstore, self.cloud, config.StoreParams)
+	self.streamer.SetNetStore(netStore)
+	self.storage = netStore
 	log.Debug(fmt.Sprintf("-> swarm net store shared access layer to Swarm Chunk Store"))
 
-	// set up Depo (storage handler = cloud storage access layer for incoming remote requests)
-	self.depo = network.NewDepo(hash, self.lstore, self.storage)
-	log.Debug(fmt.Sprintf("-> REmote Access to CHunks"))
-
 	// set up DPA, the cloud storage local access layer
 	dpaChunkStore := storage.NewDpaChunkStore(self.lstore, self.storage)
 	log.Debug(fmt.Sprintf("-> Local Access to Swarm"))
@@ -163,6 +171,7 @@ func NewSwarm(ctx *node.ServiceContext, backend chequebook.Backend, config *api.
 	}
 
 	self.api = api.NewApi(self.dpa, self.dns)
+	self.api.SetPrivateKey(self.privateKey)
 	// Manifests for Smart Hosting
 	log.Debug(fmt.Sprintf("-> Web3 virtual server API"))
 
@@ -351,6 +360,9 @@ func (self *Swarm) Stop() error {
 		ch.Save()
 	}
 
+	if self.streamer != nil {
+		self.streamer.Close()
+	}
 	if self.lstore != nil {
 		self.lstore.DbStore.Close()
 	}
@@ -361,11 +373,11 @@ func (self *Swarm) Stop() error {
 
 // implements the node.Service interface
 func (self *Swarm) Protocols() []p2p.Protocol {
-	proto, err := network.Bzz(self.depo, self.backend, self.hive, self.dbAccess, self.config.Swap, self.config.SyncParams, self.config.NetworkId)
+	proto, err := network.Bzz(self.backend, self.hive, self.config.Swap, self.config.NetworkId)
 	if err != nil {
 		return nil
 	}
-	return []p2p.Protocol{proto}
+	return []p2p.Protocol{proto, self.streamer.Protocol()}
 }
 
 // implements node.Service
@@ -398,6 +410,13 @@ func (self *Swarm) APIs() []rpc.API {
 			Service:   self.sfs,
 			Public:    false,
 		},
+		// messaging APIs
+		{
+			Namespace: "pss",
+			Version:   "0.1",
+			Service:   pss.NewAPI(self.pss),
+			Public:    true,
+		},
 		// storage APIs
 		// DEPRECATED: Use the HTTP API instead
 		{
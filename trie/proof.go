@@ -18,6 +18,7 @@ package trie
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/aaechain/go-aaechain/common"
@@ -114,7 +115,7 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb DatabaseReader) (valu
 		if err != nil {
 			return nil, fmt.Errorf("bad proof node %d: %v", i, err), i
 		}
-		keyrest, cld := get(n, key)
+		keyrest, cld := get(n, key, true)
 		switch cld := cld.(type) {
 		case nil:
 			// The trie doesn't contain the key.
@@ -128,7 +129,11 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb DatabaseReader) (valu
 	}
 }
 
-func get(tn node, key []byte) ([]byte, node) {
+// get returns the child of tn reached by key and the remaining key. If
+// skipResolved is true, it descends through all resolved nodes and stops at
+// the first hash node, value node or missing child. Otherwise it only takes
+// a single step.
+func get(tn node, key []byte, skipResolved bool) ([]byte, node) {
 	for {
 		switch n := tn.(type) {
 		case *shortNode:
@@ -137,9 +142,15 @@ func get(tn node, key []byte) ([]byte, node) {
 			}
 			tn = n.Val
 			key = key[len(n.Key):]
+			if !skipResolved {
+				return key, tn
+			}
 		case *fullNode:
 			tn = n.Children[key[0]]
 			key = key[1:]
+			if !skipResolved {
+				return key, tn
+			}
 		case hashNode:
 			return key, n
 		case nil:
@@ -151,3 +162,337 @@ func get(tn node, key []byte) ([]byte, node) {
 		}
 	}
 }
+
+// proofToPath resolves the nodes on the path to key from the proof and links
+// them into root, leaving all other children as hash nodes. If root is nil,
+// the root node is also taken from the proof. The value at key is returned
+// if the proof contains it. If allowNonExistent is set, proofs of absence are
+// accepted as well.
+func proofToPath(rootHash common.Hash, root node, key []byte, proofDb DatabaseReader, allowNonExistent bool) (node, []byte, error) {
+	resolve := func(hash common.Hash) (node, error) {
+		buf, _ := proofDb.Get(hash[:])
+		if buf == nil {
+			return nil, fmt.Errorf("proof node (hash %064x) missing", hash)
+		}
+		n, err := decodeNode(hash[:], buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node: %v", err)
+		}
+		return n, nil
+	}
+	if root == nil {
+		n, err := resolve(rootHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		root = n
+	}
+	var (
+		err     error
+		child   node
+		parent  = root
+		keyrest []byte
+		value   []byte
+	)
+	key = keybytesToHex(key)
+	for {
+		keyrest, child = get(parent, key, false)
+		switch cld := child.(type) {
+		case nil:
+			// The trie doesn't contain the key. All nodes resolved so far
+			// are proven correct, which is enough to prove a range.
+			if allowNonExistent {
+				return root, nil, nil
+			}
+			return nil, nil, errors.New("the node is not contained in trie")
+		case *shortNode, *fullNode:
+			// Embedded node, already resolved.
+			key, parent = keyrest, child
+			continue
+		case hashNode:
+			if child, err = resolve(common.BytesToHash(cld)); err != nil {
+				return nil, nil, err
+			}
+		case valueNode:
+			value = cld
+		}
+		// Link the resolved child into its parent.
+		switch pnode := parent.(type) {
+		case *shortNode:
+			pnode.Val = child
+		case *fullNode:
+			pnode.Children[key[0]] = child
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", pnode, pnode))
+		}
+		if value != nil {
+			return root, value, nil
+		}
+		key, parent = keyrest, child
+	}
+}
+
+// unsetInternal removes all references to nodes between the left and right
+// path from a trie built by proofToPath, so the range can be filled in by
+// inserting the leaves. The paths must be different, with left < right. It
+// reports whaaeer the whole trie lies inside the range, in which case the
+// caller should start from an empty trie.
+//
+// All nodes on the two paths are marked dirty because their content changes.
+// Some full nodes may temporarily have a single child; if the proof is valid,
+// the missing children are filled in by the leaves.
+func unsetInternal(n node, left []byte, right []byte) (bool, error) {
+	left, right = keybytesToHex(left), keybytesToHex(right)
+
+	// Step down to the fork point. It is either a short node whose key
+	// doesn't match one of the paths, or a full node where the paths go
+	// into different children.
+	var (
+		pos    = 0
+		parent node
+
+		// Comparison of the paths with the fork short node key:
+		// 0 means match, -1 means the path is less, 1 means it is greater.
+		shortForkLeft, shortForkRight int
+	)
+findFork:
+	for {
+		switch rn := n.(type) {
+		case *shortNode:
+			rn.flags = nodeFlag{dirty: true}
+			shortForkLeft = compareNibbles(left[pos:], rn.Key)
+			shortForkRight = compareNibbles(right[pos:], rn.Key)
+			if shortForkLeft != 0 || shortForkRight != 0 {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Val, pos+len(rn.Key)
+		case *fullNode:
+			rn.flags = nodeFlag{dirty: true}
+			leftnode, rightnode := rn.Children[left[pos]], rn.Children[right[pos]]
+			if leftnode == nil || rightnode == nil || leftnode != rightnode {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Children[left[pos]], pos+1
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+		}
+	}
+	switch rn := n.(type) {
+	case *shortNode:
+		// Both paths are on the same side of the short node: nothing of it
+		// can be inside the range.
+		if shortForkLeft == shortForkRight {
+			return false, errors.New("empty range")
+		}
+		// The short node lies between the paths, drop it entirely.
+		if shortForkLeft != 0 && shortForkRight != 0 {
+			if parent == nil {
+				return true, nil
+			}
+			parent.(*fullNode).Children[left[pos-1]] = nil
+			return false, nil
+		}
+		// One of the paths goes through the short node.
+		if _, ok := rn.Val.(valueNode); ok {
+			if parent == nil {
+				return true, nil
+			}
+			parent.(*fullNode).Children[left[pos-1]] = nil
+			return false, nil
+		}
+		if shortForkRight != 0 {
+			return false, unset(rn, rn.Val, left[pos:], len(rn.Key), false)
+		}
+		return false, unset(rn, rn.Val, right[pos:], len(rn.Key), true)
+	case *fullNode:
+		for i := left[pos] + 1; i < right[pos]; i++ {
+			rn.Children[i] = nil
+		}
+		if err := unset(rn, rn.Children[left[pos]], left[pos:], 1, false); err != nil {
+			return false, err
+		}
+		if err := unset(rn, rn.Children[right[pos]], right[pos:], 1, true); err != nil {
+			return false, err
+		}
+		return false, nil
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+	}
+}
+
+// unset removes all references on one side of the given path below the fork
+// point. If removeLeft is set, everything left of the path is removed (this
+// is the right edge of the range), otherwise everything right of it.
+func unset(parent node, child node, key []byte, pos int, removeLeft bool) error {
+	switch cld := child.(type) {
+	case *fullNode:
+		if removeLeft {
+			for i := 0; i < int(key[pos]); i++ {
+				cld.Children[i] = nil
+			}
+		} else {
+			for i := key[pos] + 1; i < 16; i++ {
+				cld.Children[i] = nil
+			}
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Children[key[pos]], key, pos+1, removeLeft)
+	case *shortNode:
+		if len(key[pos:]) < len(cld.Key) || !bytes.Equal(cld.Key, key[pos:pos+len(cld.Key)]) {
+			// The path doesn't exist in the trie. Drop the short node if it
+			// lies inside the range, keep it otherwise.
+			cmp := bytes.Compare(key[pos:], cld.Key)
+			if (removeLeft && cmp > 0) || (!removeLeft && cmp < 0) {
+				parent.(*fullNode).Children[key[pos-1]] = nil
+			}
+			return nil
+		}
+		if _, ok := cld.Val.(valueNode); ok {
+			// The edge leaf itself, it gets inserted again.
+			parent.(*fullNode).Children[key[pos-1]] = nil
+			return nil
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Val, key, pos+len(cld.Key), removeLeft)
+	case nil:
+		// The path ends in a missing child of the fork point.
+		return nil
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", child, child))
+	}
+}
+
+// compareNibbles compares the path with the key of a short node. Only the
+// first len(key) nibbles of the path are considered.
+func compareNibbles(path, key []byte) int {
+	if len(path) > len(key) {
+		path = path[:len(key)]
+	}
+	return bytes.Compare(path, key)
+}
+
+// hasRightElement reports whaaeer the trie contains keys greater than key.
+// The path to key must be resolved.
+func hasRightElement(node node, key []byte) bool {
+	pos, key := 0, keybytesToHex(key)
+	for node != nil {
+		switch rn := node.(type) {
+		case *fullNode:
+			for i := key[pos] + 1; i < 16; i++ {
+				if rn.Children[i] != nil {
+					return true
+				}
+			}
+			node, pos = rn.Children[key[pos]], pos+1
+		case *shortNode:
+			if len(key)-pos < len(rn.Key) || !bytes.Equal(rn.Key, key[pos:pos+len(rn.Key)]) {
+				return bytes.Compare(rn.Key, key[pos:]) > 0
+			}
+			node, pos = rn.Val, pos+len(rn.Key)
+		case valueNode:
+			return false
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", node, node))
+		}
+	}
+	return false
+}
+
+// VerifyRangeProof checks that keys and values are all leaves of the trie with
+// the given root hash from firstKey up to the last key, without gaps. The proof
+// must contain the nodes on the paths to firstKey and to the last key. firstKey
+// may be absent from the trie, in which case keys[0] is the first key after it.
+//
+// A nil proof means that keys and values are the complete content of the trie.
+// With no keys, the proof must show that the trie contains nothing after
+// firstKey.
+//
+// It returns whaaeer the trie contains more keys after the range.
+func VerifyRangeProof(rootHash common.Hash, firstKey []byte, keys [][]byte, values [][]byte, proof DatabaseReader) (bool, error) {
+	if len(keys) != len(values) {
+		return false, fmt.Errorf("inconsistent proof data, keys: %d, values: %d", len(keys), len(values))
+	}
+	for i := 0; i < len(keys); i++ {
+		if i < len(keys)-1 && bytes.Compare(keys[i], keys[i+1]) >= 0 {
+			return false, errors.New("range is not monotonically increasing")
+		}
+		if len(values[i]) == 0 {
+			return false, errors.New("range contains empty value")
+		}
+	}
+	// Without a proof, the range must be the whole trie.
+	if proof == nil {
+		tr := &Trie{db: newRangeProofDatabase()}
+		for i, key := range keys {
+			tr.Update(key, values[i])
+		}
+		if have := tr.Hash(); have != rootHash {
+			return false, fmt.Errorf("invalid proof, want hash %x, got %x", rootHash, have)
+		}
+		return false, nil
+	}
+	// Without keys, there must be nothing after firstKey.
+	if len(keys) == 0 {
+		root, _, err := proofToPath(rootHash, nil, firstKey, proof, true)
+		if err != nil {
+			return false, err
+		}
+		if hasRightElement(root, firstKey) {
+			return false, errors.New("more entries available")
+		}
+		return false, nil
+	}
+	lastKey := keys[len(keys)-1]
+	if bytes.Compare(firstKey, keys[0]) > 0 {
+		return false, errors.New("first key is after the range")
+	}
+	// A single leaf proven by one path.
+	if bytes.Equal(firstKey, lastKey) {
+		root, val, err := proofToPath(rootHash, nil, firstKey, proof, false)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(val, values[0]) {
+			return false, errors.New("correct proof but invalid data")
+		}
+		return hasRightElement(root, firstKey), nil
+	}
+	// Build the trie from the two edge paths, remove everything between them
+	// and fill it in from the leaves. The root hash only matches if no leaf
+	// was left out.
+	root, _, err := proofToPath(rootHash, nil, firstKey, proof, true)
+	if err != nil {
+		return false, err
+	}
+	root, _, err = proofToPath(rootHash, root, lastKey, proof, true)
+	if err != nil {
+		return false, err
+	}
+	empty, err := unsetInternal(root, firstKey, lastKey)
+	if err != nil {
+		return false, err
+	}
+	tr := &Trie{root: root, db: newRangeProofDatabase()}
+	if empty {
+		tr.root = nil
+	}
+	for i, key := range keys {
+		if err := tr.TryUpdate(key, values[i]); err != nil {
+			return false, err
+		}
+	}
+	if have := tr.Hash(); have != rootHash {
+		return false, fmt.Errorf("invalid proof, want hash %x, got %x", rootHash, have)
+	}
+	return hasRightElement(tr.root, lastKey), nil
+}
+
+// newRangeProofDatabase returns an empty database for tries built during range
+// proof verification. Any attempt to resolve a node outside the proven paths
+// fails with MissingNodeError.
+func newRangeProofDatabase() *Database {
+	db, _ := aaedb.NewMemDatabase()
+	return NewDatabase(db)
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	mrand "math/rand"
+	"sort"
 	"testing"
 	"time"
 
@@ -99,6 +100,206 @@ func mutateByte(b []byte) {
 	}
 }
 
+type entrySlice []*kv
+
+func (p entrySlice) Len() int           { return len(p) }
+func (p entrySlice) Less(i, j int) bool { return bytes.Compare(p[i].k, p[j].k) < 0 }
+func (p entrySlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// sortedEntries returns the entries of a random trie sorted by key.
+func sortedEntries(vals map[string]*kv) entrySlice {
+	var entries entrySlice
+	for _, kv := range vals {
+		entries = append(entries, kv)
+	}
+	sort.Sort(entries)
+	return entries
+}
+
+// rangeProof creates the edge proofs of a range.
+func rangeProof(t *testing.T, trie *Trie, first []byte, last []byte) *aaedb.MemDatabase {
+	proof, _ := aaedb.NewMemDatabase()
+	if err := trie.Prove(first, 0, proof); err != nil {
+		t.Fatalf("failed to prove the first node: %v", err)
+	}
+	if err := trie.Prove(last, 0, proof); err != nil {
+		t.Fatalf("failed to prove the last node: %v", err)
+	}
+	return proof
+}
+
+func rangeData(entries entrySlice) (keys, vals [][]byte) {
+	for _, e := range entries {
+		keys = append(keys, e.k)
+		vals = append(vals, e.v)
+	}
+	return keys, vals
+}
+
+func TestRangeProof(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	entries := sortedEntries(vals)
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries))
+		end := mrand.Intn(len(entries)-start) + start + 1
+
+		proof := rangeProof(t, trie, entries[start].k, entries[end-1].k)
+		keys, vals := rangeData(entries[start:end])
+		hasMore, err := VerifyRangeProof(trie.Hash(), keys[0], keys, vals, proof)
+		if err != nil {
+			t.Fatalf("case %d(%d->%d) expect no error, got %v", i, start, end-1, err)
+		}
+		if hasMore != (end < len(entries)) {
+			t.Fatalf("case %d(%d->%d) wrong hasMore: %v", i, start, end-1, hasMore)
+		}
+	}
+}
+
+// TestRangeProofNonExistentFirst tests ranges where the first key is not
+// contained in the trie.
+func TestRangeProofNonExistentFirst(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	entries := sortedEntries(vals)
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries)-1) + 1
+		end := mrand.Intn(len(entries)-start) + start + 1
+
+		first := decreaseKey(common.CopyBytes(entries[start].k))
+		if bytes.Equal(first, entries[start-1].k) {
+			continue
+		}
+		proof := rangeProof(t, trie, first, entries[end-1].k)
+		keys, vals := rangeData(entries[start:end])
+		if _, err := VerifyRangeProof(trie.Hash(), first, keys, vals, proof); err != nil {
+			t.Fatalf("case %d(%d->%d) expect no error, got %v", i, start, end-1, err)
+		}
+	}
+}
+
+// TestRangeProofBadData tests that modified, missing and extra leaves are
+// detected.
+func TestRangeProofBadData(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	entries := sortedEntries(vals)
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries))
+		end := mrand.Intn(len(entries)-start) + start + 1
+		if end-start < 3 {
+			continue
+		}
+		proof := rangeProof(t, trie, entries[start].k, entries[end-1].k)
+		keys, vals := rangeData(entries[start:end])
+
+		var (
+			index = mrand.Intn(end - start)
+			first = keys[0]
+		)
+		switch mrand.Intn(3) {
+		case 0:
+			// Modify a value.
+			vals[index] = randBytes(20)
+		case 1:
+			// Remove a leaf in the middle.
+			index = mrand.Intn(end-start-2) + 1
+			keys = append(keys[:index], keys[index+1:]...)
+			vals = append(vals[:index], vals[index+1:]...)
+		case 2:
+			// Modify a key in the middle.
+			index = mrand.Intn(end-start-2) + 1
+			keys[index] = increaseKey(common.CopyBytes(keys[index]))
+			if bytes.Compare(keys[index], keys[index+1]) >= 0 {
+				continue
+			}
+		}
+		if _, err := VerifyRangeProof(trie.Hash(), first, keys, vals, proof); err == nil {
+			t.Fatalf("case %d(%d->%d) expect error, got nil", i, start, end-1)
+		}
+	}
+}
+
+func TestRangeProofAllElements(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	entries := sortedEntries(vals)
+	keys, values := rangeData(entries)
+
+	// The whole trie without a proof.
+	hasMore, err := VerifyRangeProof(trie.Hash(), nil, keys, values, nil)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if hasMore {
+		t.Fatal("unexpected hasMore")
+	}
+	// Leaving out a leaf must fail.
+	if _, err := VerifyRangeProof(trie.Hash(), nil, keys[1:], values[1:], nil); err == nil {
+		t.Fatal("expect error for incomplete trie")
+	}
+	// The whole trie with edge proofs.
+	proof := rangeProof(t, trie, keys[0], keys[len(keys)-1])
+	if _, err := VerifyRangeProof(trie.Hash(), keys[0], keys, values, proof); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+}
+
+func TestRangeProofSingleElement(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	entries := sortedEntries(vals)
+	for _, pos := range []int{0, 1000, len(entries) - 1} {
+		key := entries[pos].k
+		proof := rangeProof(t, trie, key, key)
+		hasMore, err := VerifyRangeProof(trie.Hash(), key, [][]byte{key}, [][]byte{entries[pos].v}, proof)
+		if err != nil {
+			t.Fatalf("pos %d: expect no error, got %v", pos, err)
+		}
+		if hasMore != (pos < len(entries)-1) {
+			t.Fatalf("pos %d: wrong hasMore %v", pos, hasMore)
+		}
+		if _, err := VerifyRangeProof(trie.Hash(), key, [][]byte{key}, [][]byte{randBytes(20)}, proof); err == nil {
+			t.Fatalf("pos %d: expect error for wrong value", pos)
+		}
+	}
+}
+
+func TestRangeProofNoElements(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	entries := sortedEntries(vals)
+
+	// There is nothing after the last key.
+	last := increaseKey(common.CopyBytes(entries[len(entries)-1].k))
+	proof, _ := aaedb.NewMemDatabase()
+	trie.Prove(last, 0, proof)
+	if _, err := VerifyRangeProof(trie.Hash(), last, nil, nil, proof); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	// Claiming the same for a key inside the trie must fail.
+	first := decreaseKey(common.CopyBytes(entries[len(entries)-1].k))
+	proof, _ = aaedb.NewMemDatabase()
+	trie.Prove(first, 0, proof)
+	if _, err := VerifyRangeProof(trie.Hash(), first, nil, nil, proof); err == nil {
+		t.Fatal("expect error for omitted entries")
+	}
+}
+
+func increaseKey(key []byte) []byte {
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]++
+		if key[i] != 0x0 {
+			break
+		}
+	}
+	return key
+}
+
+func decreaseKey(key []byte) []byte {
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]--
+		if key[i] != 0xff {
+			break
+		}
+	}
+	return key
+}
+
 func BenchmarkProve(b *testing.B) {
 	trie, vals := randomTrie(100)
 	var keys []string
@@ -55,6 +55,7 @@ type LesServer interface {
 	Stop()
 	Protocols() []p2p.Protocol
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
+	APIs() []rpc.API
 }
 
 // aaechain implements the aaechain full node service.
@@ -249,6 +250,11 @@ func (s *aaechain) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the client management APIs of the light server, if running
+	if s.lesServer != nil {
+		apis = append(apis, s.lesServer.APIs()...)
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
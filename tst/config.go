@@ -30,6 +30,7 @@ import (
 	"github.com/aaechain/go-aaechain/core"
 	"github.com/aaechain/go-aaechain/aae/downloader"
 	"github.com/aaechain/go-aaechain/aae/gasprice"
+	"github.com/aaechain/go-aaechain/p2p/discover"
 	"github.com/aaechain/go-aaechain/params"
 )
 
@@ -43,12 +44,13 @@ var DefaultConfig = Config{
 		DatasetsInMem:  1,
 		DatasetsOnDisk: 2,
 	},
-	NetworkId:     1,
-	LightPeers:    100,
-	DatabaseCache: 768,
-	TrieCache:     256,
-	TrieTimeout:   5 * time.Minute,
-	GasPrice:      big.NewInt(18 * params.Shannon),
+	NetworkId:          1,
+	LightPeers:         100,
+	UltraLightFraction: 75,
+	DatabaseCache:      768,
+	TrieCache:          256,
+	TrieTimeout:        5 * time.Minute,
+	GasPrice:           big.NewInt(18 * params.Shannon),
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -87,6 +89,15 @@ type Config struct {
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	LightPriorityClients []discover.NodeID `toml:",omitempty"` // LES clients with guaranteed capacity
+
+	// Ultra-light client options
+	UltraLightServers  []discover.NodeID `toml:",omitempty"` // LES servers whose signed head announcements are trusted
+	UltraLightFraction int               `toml:",omitempty"` // Percentage of trusted servers required to agree on a head
+
+	// Checkpoint oracle read by light clients at sync start
+	CheckpointOracle *params.CheckpointOracleConfig `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
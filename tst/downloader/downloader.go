@@ -29,6 +29,7 @@ import (
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/core"
 	"github.com/aaechain/go-aaechain/core/types"
+	"github.com/aaechain/go-aaechain/aae/snap"
 	"github.com/aaechain/go-aaechain/aaedb"
 	"github.com/aaechain/go-aaechain/event"
 	"github.com/aaechain/go-aaechain/log"
@@ -100,6 +101,8 @@ type Downloader struct {
 	peers   *peerSet // Set of active peers from which download can proceed
 	stateDB aaedb.Database
 
+	snapSyncer *snap.Syncer // Range based state syncer, used if peers support snap
+
 	rttEstimate   uint64 // Round trip time to target for download requests
 	rttConfidence uint64 // Confidence in the estimated RTT (unit: millionths to allow atomic ops)
 
@@ -232,6 +235,12 @@ func New(mode SyncMode, stateDb aaedb.Database, mux *event.TypeMux, chain BlockC
 	return dl
 }
 
+// SetSnapSyncer sets the syncer used to download the state of fast syncs from
+// snap peers. It must be called before the first sync is started.
+func (d *Downloader) SetSnapSyncer(syncer *snap.Syncer) {
+	d.snapSyncer = syncer
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
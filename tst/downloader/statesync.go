@@ -26,6 +26,7 @@ import (
 	"github.com/aaechain/go-aaechain/core"
 	"github.com/aaechain/go-aaechain/core/state"
 	"github.com/aaechain/go-aaechain/crypto/sha3"
+	"github.com/aaechain/go-aaechain/aae/snap"
 	"github.com/aaechain/go-aaechain/aaedb"
 	"github.com/aaechain/go-aaechain/log"
 	"github.com/aaechain/go-aaechain/trie"
@@ -60,6 +61,7 @@ type stateSyncStats struct {
 // syncState starts downloading state with the given root hash.
 func (d *Downloader) syncState(root common.Hash) *stateSync {
 	s := newStateSync(d, root)
+	s.snap = d.snapSyncer != nil && d.snapSyncer.PeerCount() > 0
 	select {
 	case d.stateSyncStart <- s:
 	case <-d.quitCh:
@@ -89,6 +91,9 @@ func (d *Downloader) stateFetcher() {
 // runStateSync runs a state synchronisation until it completes or another root
 // hash is requested to be switched over to.
 func (d *Downloader) runStateSync(s *stateSync) *stateSync {
+	if s.snap {
+		return d.runSnapSync(s)
+	}
 	var (
 		active   = make(map[string]*stateReq) // Currently in-flight requests
 		finished []*stateReq                  // Completed or failed requests
@@ -209,10 +214,39 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 	}
 }
 
+// runSnapSync runs a state synchronisation through the snap protocol until it
+// completes or another root hash is requested to be switched over to.
+func (d *Downloader) runSnapSync(s *stateSync) *stateSync {
+	go func() {
+		err := d.snapSyncer.Sync(s.root, s.cancel)
+		if err == snap.ErrCancelled {
+			err = errCancelStateFetch
+		}
+		s.err = err
+		close(s.done)
+	}()
+	defer s.Cancel()
+
+	for {
+		select {
+		case next := <-d.stateSyncStart:
+			return next
+
+		case <-s.done:
+			return nil
+
+		case <-d.stateCh:
+			// Ignore node data responses, the state is downloaded in ranges.
+		}
+	}
+}
+
 // stateSync schedules requests for downloading a particular state trie defined
 // by a given state root.
 type stateSync struct {
-	d *Downloader // Downloader instance to access and manage current peerset
+	d    *Downloader // Downloader instance to access and manage current peerset
+	root common.Hash // State root being synced
+	snap bool        // Whaaeer the state is synced through the snap protocol
 
 	sched  *trie.TrieSync             // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
@@ -239,6 +273,7 @@ type stateTask struct {
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
 	return &stateSync{
 		d:       d,
+		root:    root,
 		sched:   state.NewStateSync(root, d.stateDB),
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
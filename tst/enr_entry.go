@@ -0,0 +1,59 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package aae
+
+import (
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/p2p/enr"
+	"github.com/aaechain/go-aaechain/rlp"
+)
+
+// aaeEntry is the "aae" node record entry, which advertises the chain a node
+// is on. It allows the dialer to skip nodes of other networks before the
+// RLPx handshake.
+type aaeEntry struct {
+	Genesis   common.Hash
+	NetworkId uint64
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e aaeEntry) ENRKey() string {
+	return "aae"
+}
+
+// currentENREntry constructs an `aae` node record entry for the local chain.
+func (pm *ProtocolManager) currentENREntry() *aaeEntry {
+	return &aaeEntry{
+		Genesis:   pm.blockchain.Genesis().Hash(),
+		NetworkId: pm.networkId,
+	}
+}
+
+// acceptRecord reports whaaeer a node should be dialed based on its record.
+// Nodes that don't advertise the aae entry are accepted since they might run
+// other protocols, nodes on a different chain are rejected.
+func (pm *ProtocolManager) acceptRecord(rec *enr.Record) bool {
+	var entry aaeEntry
+	if err := rec.Load(&entry); err != nil {
+		return enr.IsNotFound(err)
+	}
+	local := pm.currentENREntry()
+	return entry.Genesis == local.Genesis && entry.NetworkId == local.NetworkId
+}
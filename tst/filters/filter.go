@@ -18,6 +18,7 @@ package filters
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/aaechain/go-aaechain/common"
@@ -45,6 +46,17 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// ErrRangeFilterUnavailable is returned by a RangeFilterBackend if it can't
+// filter the requested range, in which case the logs are matched locally.
+var ErrRangeFilterUnavailable = errors.New("range filtering unavailable")
+
+// RangeFilterBackend is implemented by backends able to retrieve the logs
+// matching the filter criteria in a whole block range at once, like light
+// clients asking their servers to do the filtering.
+type RangeFilterBackend interface {
+	FilterLogs(ctx context.Context, begin, end uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error)
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -109,6 +121,16 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
+	// Let the backend filter the range if it can
+	if backend, ok := f.backend.(RangeFilterBackend); ok && uint64(f.begin) <= end {
+		logs, err := backend.FilterLogs(ctx, uint64(f.begin), end, f.addresses, f.topics)
+		if err != ErrRangeFilterUnavailable {
+			if err == nil {
+				f.begin = int64(end) + 1
+			}
+			return logs, err
+		}
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
@@ -33,11 +33,13 @@ import (
 	"github.com/aaechain/go-aaechain/core/types"
 	"github.com/aaechain/go-aaechain/aae/downloader"
 	"github.com/aaechain/go-aaechain/aae/fetcher"
+	"github.com/aaechain/go-aaechain/aae/snap"
 	"github.com/aaechain/go-aaechain/aaedb"
 	"github.com/aaechain/go-aaechain/event"
 	"github.com/aaechain/go-aaechain/log"
 	"github.com/aaechain/go-aaechain/p2p"
 	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/params"
 	"github.com/aaechain/go-aaechain/rlp"
 )
@@ -153,13 +155,23 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 				}
 				return nil
 			},
+			Attributes:   []enr.Entry{manager.currentENREntry()},
+			RecordFilter: manager.acceptRecord,
 		})
 	}
 	if len(manager.SubProtocols) == 0 {
 		return nil, errIncompatibleConfig
 	}
 	// Construct the different synchronisation mechanisms
-	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.dropSyncPeer)
+
+	// Serve the state in ranges to snap peers, and download it from them if fast syncing
+	var syncer *snap.Syncer
+	if mode == downloader.FastSync {
+		syncer = snap.NewSyncer(chaindb)
+		manager.downloader.SetSnapSyncer(syncer)
+	}
+	manager.SubProtocols = append(manager.SubProtocols, snap.MakeProtocols(blockchain.StateCache().TrieDB(), syncer)...)
 
 	validator := func(header *types.Header) error {
 		return engine.VerifyHeader(blockchain, header, true)
@@ -176,7 +188,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		return manager.blockchain.InsertChain(blocks)
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.dropBadBlockPeer)
 
 	return manager, nil
 }
@@ -200,6 +212,24 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+// dropSyncPeer is invoked by the downloader for peers which stall or deliver
+// an invalid chain. It lowers the reputation of the peer and removes it.
+func (pm *ProtocolManager) dropSyncPeer(id string) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.AdjustScore(p2p.ScoreUseless, "sync failure")
+	}
+	pm.removePeer(id)
+}
+
+// dropBadBlockPeer is invoked by the fetcher for peers which propagate invalid
+// blocks. It lowers the reputation of the peer and removes it.
+func (pm *ProtocolManager) dropBadBlockPeer(id string) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.AdjustScore(p2p.ScoreMisbehaved, "bad block")
+	}
+	pm.removePeer(id)
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -671,7 +701,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
-		pm.txpool.AddRemotes(txs)
+		pm.scoreTransactions(p, pm.txpool.AddRemotes(txs))
 
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -679,6 +709,26 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// scoreTransactions adjusts the reputation of a peer according to the pool
+// errors of the transactions it sent. Transactions which can never be valid
+// count as misbehaviour, errors which may be caused by a different view of
+// the chain state are ignored.
+func (pm *ProtocolManager) scoreTransactions(p *peer, errs []error) {
+	accepted := false
+	for _, err := range errs {
+		switch err {
+		case nil:
+			accepted = true
+		case core.ErrInvalidSender, core.ErrNegativeValue, core.ErrOversizedData, core.ErrIntrinsicGas, core.ErrGasLimit:
+			p.AdjustScore(p2p.ScoreMisbehaved, "invalid transaction")
+			return
+		}
+	}
+	if accepted {
+		p.AdjustScore(p2p.ScoreUseful, "new transactions")
+	}
+}
+
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
@@ -34,6 +34,7 @@ import (
 	"github.com/aaechain/go-aaechain/aaedb"
 	"github.com/aaechain/go-aaechain/event"
 	"github.com/aaechain/go-aaechain/p2p"
+	"github.com/aaechain/go-aaechain/p2p/enr"
 	"github.com/aaechain/go-aaechain/params"
 )
 
@@ -520,3 +521,35 @@ func testDAOChallenge(t *testing.T, localForked, remoteForked bool, timeout bool
 		}
 	}
 }
+
+// Tests that dial candidates are filtered by the chain advertised in their
+// node record.
+func TestAcceptRecord(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	local := pm.currentENREntry()
+	tests := []struct {
+		entry  enr.Entry
+		accept bool
+	}{
+		{entry: nil, accept: true},
+		{entry: local, accept: true},
+		{entry: &aaeEntry{Genesis: local.Genesis, NetworkId: local.NetworkId + 1}, accept: false},
+		{entry: &aaeEntry{Genesis: common.Hash{1}, NetworkId: local.NetworkId}, accept: false},
+		{entry: enr.WithEntry("aae", "garbage"), accept: false},
+	}
+	for i, tt := range tests {
+		var rec enr.Record
+		if tt.entry != nil {
+			rec.Set(tt.entry)
+		}
+		key, _ := crypto.GenerateKey()
+		if err := rec.Sign(key); err != nil {
+			t.Fatalf("test %d: failed to sign record: %v", i, err)
+		}
+		if accept := pm.acceptRecord(&rec); accept != tt.accept {
+			t.Errorf("test %d: accept mismatch: have %t, want %t", i, accept, tt.accept)
+		}
+	}
+}
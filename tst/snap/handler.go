@@ -0,0 +1,305 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aaechain/go-aaechain/aaedb"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/core/state"
+	"github.com/aaechain/go-aaechain/p2p"
+	"github.com/aaechain/go-aaechain/rlp"
+	"github.com/aaechain/go-aaechain/trie"
+)
+
+const (
+	softResponseLimit = 2 * 1024 * 1024 // Target maximum size of returned state data
+
+	maxCodeLookups     = 1024 // Maximum number of codes to serve in one request
+	maxTrieNodeLookups = 1024 // Maximum number of trie nodes to serve in one request
+)
+
+// MakeProtocols constructs the snap protocol for the supported versions. Peers
+// are served the state in db. If syncer is not nil, peers are also registered
+// with it to sync from.
+func MakeProtocols(db *trie.Database, syncer *Syncer) []p2p.Protocol {
+	protocols := make([]p2p.Protocol, 0, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		protocols = append(protocols, p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return handle(db, syncer, newPeer(int(version), p, rw))
+			},
+		})
+	}
+	return protocols
+}
+
+// handle is the callback invoked to manage the life cycle of a snap peer. When
+// this function terminates, the peer is disconnected.
+func handle(db *trie.Database, syncer *Syncer, peer *Peer) error {
+	peer.log.Debug("Snap peer connected", "name", peer.Name())
+	defer peer.log.Debug("Snap peer disconnected")
+
+	if syncer != nil {
+		syncer.Register(peer)
+		defer syncer.Unregister(peer.id)
+	}
+	for {
+		if err := handleMessage(db, syncer, peer); err != nil {
+			peer.log.Debug("Snap message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a
+// remote peer. The remote connection is torn down upon returning any error.
+func handleMessage(db *trie.Database, syncer *Syncer, peer *Peer) error {
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return fmt.Errorf("%v: %v > %v", errMsgTooLarge, msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req getAccountRangeData
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: msg %v: %v", errDecode, msg, err)
+		}
+		accounts, proof := serviceAccountRange(db, &req)
+		return p2p.Send(peer.rw, AccountRangeMsg, &accountRangeData{ID: req.ID, Accounts: accounts, Proof: proof})
+
+	case GetStorageRangesMsg:
+		var req getStorageRangesData
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: msg %v: %v", errDecode, msg, err)
+		}
+		slots, proof := serviceStorageRanges(db, &req)
+		return p2p.Send(peer.rw, StorageRangesMsg, &storageRangesData{ID: req.ID, Slots: slots, Proof: proof})
+
+	case GetByteCodesMsg:
+		var req getByteCodesData
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: msg %v: %v", errDecode, msg, err)
+		}
+		codes := serviceBlobs(db, req.Hashes, req.Bytes, maxCodeLookups)
+		return p2p.Send(peer.rw, ByteCodesMsg, &byteCodesData{ID: req.ID, Codes: codes})
+
+	case GetTrieNodesMsg:
+		var req getTrieNodesData
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: msg %v: %v", errDecode, msg, err)
+		}
+		nodes := serviceBlobs(db, req.Hashes, req.Bytes, maxTrieNodeLookups)
+		return p2p.Send(peer.rw, TrieNodesMsg, &trieNodesData{ID: req.ID, Nodes: nodes})
+
+	case AccountRangeMsg:
+		var res accountRangeData
+		if err := msg.Decode(&res); err != nil {
+			return fmt.Errorf("%v: msg %v: %v", errDecode, msg, err)
+		}
+		if syncer != nil {
+			syncer.deliver(&response{id: res.ID, peer: peer.id, accounts: res.Accounts, proof: res.Proof})
+		}
+
+	case StorageRangesMsg:
+		var res storageRangesData
+		if err := msg.Decode(&res); err != nil {
+			return fmt.Errorf("%v: msg %v: %v", errDecode, msg, err)
+		}
+		if syncer != nil {
+			syncer.deliver(&response{id: res.ID, peer: peer.id, slots: res.Slots, proof: res.Proof})
+		}
+
+	case ByteCodesMsg:
+		var res byteCodesData
+		if err := msg.Decode(&res); err != nil {
+			return fmt.Errorf("%v: msg %v: %v", errDecode, msg, err)
+		}
+		if syncer != nil {
+			syncer.deliver(&response{id: res.ID, peer: peer.id, blobs: res.Codes})
+		}
+
+	case TrieNodesMsg:
+		var res trieNodesData
+		if err := msg.Decode(&res); err != nil {
+			return fmt.Errorf("%v: msg %v: %v", errDecode, msg, err)
+		}
+		if syncer != nil {
+			syncer.deliver(&response{id: res.ID, peer: peer.id, blobs: res.Nodes})
+		}
+
+	default:
+		return fmt.Errorf("%v: %v", errInvalidMsgCode, msg.Code)
+	}
+	return nil
+}
+
+// responseLimit caps the soft size limit requested by a remote peer.
+func responseLimit(bytes uint64) uint64 {
+	if bytes > softResponseLimit {
+		return softResponseLimit
+	}
+	return bytes
+}
+
+// serviceAccountRange collects the requested range of accounts and the edge
+// proofs. Nothing is returned if the state is not available.
+func serviceAccountRange(db *trie.Database, req *getAccountRangeData) ([]*accountData, [][]byte) {
+	tr, err := trie.New(req.Root, db)
+	if err != nil {
+		return nil, nil
+	}
+	var (
+		accounts []*accountData
+		size     uint64
+		limit    = responseLimit(req.Bytes)
+		it       = trie.NewIterator(tr.NodeIterator(req.Origin[:]))
+	)
+	for it.Next() {
+		hash := common.BytesToHash(it.Key)
+		accounts = append(accounts, &accountData{Hash: hash, Body: common.CopyBytes(it.Value)})
+		size += uint64(common.HashLength + len(it.Value))
+		if bytes.Compare(hash[:], req.Limit[:]) >= 0 || size >= limit {
+			break
+		}
+	}
+	if it.Err != nil {
+		return nil, nil
+	}
+	last := req.Origin
+	if len(accounts) > 0 {
+		last = accounts[len(accounts)-1].Hash
+	}
+	proof, err := proveRange(tr, req.Origin, last)
+	if err != nil {
+		return nil, nil
+	}
+	return accounts, proof
+}
+
+// serviceStorageRanges collects the storage of the requested accounts. Only the
+// last storage range is allowed to be incomplete, in which case it is proven.
+func serviceStorageRanges(db *trie.Database, req *getStorageRangesData) ([][]*storageData, [][]byte) {
+	accTrie, err := trie.New(req.Root, db)
+	if err != nil {
+		return nil, nil
+	}
+	var (
+		slots [][]*storageData
+		proof [][]byte
+		size  uint64
+		limit = responseLimit(req.Bytes)
+	)
+	for i, account := range req.Accounts {
+		if size >= limit {
+			break
+		}
+		blob, err := accTrie.TryGet(account[:])
+		if err != nil || blob == nil {
+			break
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			break
+		}
+		stTrie, err := trie.New(acc.Root, db)
+		if err != nil {
+			break
+		}
+		var origin common.Hash
+		if i == 0 {
+			origin = req.Origin
+		}
+		var (
+			storage []*storageData
+			abort   bool
+			it      = trie.NewIterator(stTrie.NodeIterator(origin[:]))
+		)
+		for it.Next() {
+			if size >= limit && len(storage) > 0 {
+				abort = true
+				break
+			}
+			storage = append(storage, &storageData{Hash: common.BytesToHash(it.Key), Body: common.CopyBytes(it.Value)})
+			size += uint64(common.HashLength + len(it.Value))
+		}
+		if it.Err != nil {
+			break
+		}
+		slots = append(slots, storage)
+
+		// Prove the range if it doesn't cover the whole storage trie.
+		if abort || origin != (common.Hash{}) {
+			last := origin
+			if len(storage) > 0 {
+				last = storage[len(storage)-1].Hash
+			}
+			if proof, err = proveRange(stTrie, origin, last); err != nil {
+				return nil, nil
+			}
+			break
+		}
+	}
+	return slots, proof
+}
+
+// serviceBlobs retrieves trie nodes or contract codes by hash. Unknown items
+// are left empty.
+func serviceBlobs(db *trie.Database, hashes []common.Hash, bytes uint64, max int) [][]byte {
+	var (
+		blobs [][]byte
+		size  uint64
+		limit = responseLimit(bytes)
+	)
+	for _, hash := range hashes {
+		if size >= limit || len(blobs) >= max {
+			break
+		}
+		blob, _ := db.Node(hash)
+		blobs = append(blobs, blob)
+		size += uint64(len(blob))
+	}
+	return blobs
+}
+
+// proveRange creates the Merkle proofs of the first and last key of a range.
+func proveRange(tr *trie.Trie, first, last common.Hash) ([][]byte, error) {
+	db, _ := aaedb.NewMemDatabase()
+	if err := tr.Prove(first[:], 0, db); err != nil {
+		return nil, err
+	}
+	if err := tr.Prove(last[:], 0, db); err != nil {
+		return nil, err
+	}
+	proof := make([][]byte, 0, db.Len())
+	for _, key := range db.Keys() {
+		node, _ := db.Get(key)
+		proof = append(proof, node)
+	}
+	return proof, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"fmt"
+
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p"
+)
+
+// Peer is a remote node speaking the snap protocol.
+type Peer struct {
+	id string
+
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	version int // Protocol version negotiated
+	log     log.Logger
+}
+
+func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	id := p.ID()
+	return &Peer{
+		id:      fmt.Sprintf("%x", id[:8]),
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		log:     log.New("peer", fmt.Sprintf("%x", id[:8])),
+	}
+}
+
+// RequestAccountRange fetches a batch of accounts rooted in a specific account
+// trie, starting with the origin.
+func (p *Peer) RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error {
+	p.log.Trace("Fetching range of accounts", "reqid", id, "root", root, "origin", origin, "limit", limit, "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetAccountRangeMsg, &getAccountRangeData{
+		ID:     id,
+		Root:   root,
+		Origin: origin,
+		Limit:  limit,
+		Bytes:  bytes,
+	})
+}
+
+// RequestStorageRanges fetches the storage of a batch of accounts rooted in
+// a specific account trie. The origin only applies to the first account.
+func (p *Peer) RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin common.Hash, bytes uint64) error {
+	p.log.Trace("Fetching ranges of storage slots", "reqid", id, "root", root, "accounts", len(accounts), "origin", origin, "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetStorageRangesMsg, &getStorageRangesData{
+		ID:       id,
+		Root:     root,
+		Accounts: accounts,
+		Origin:   origin,
+		Bytes:    bytes,
+	})
+}
+
+// RequestByteCodes fetches a batch of contract codes by hash.
+func (p *Peer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	p.log.Trace("Fetching set of byte codes", "reqid", id, "hashes", len(hashes), "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetByteCodesMsg, &getByteCodesData{
+		ID:     id,
+		Hashes: hashes,
+		Bytes:  bytes,
+	})
+}
+
+// RequestTrieNodes fetches a batch of state trie nodes by hash.
+func (p *Peer) RequestTrieNodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	p.log.Trace("Fetching set of trie nodes", "reqid", id, "hashes", len(hashes), "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetTrieNodesMsg, &getTrieNodesData{
+		ID:     id,
+		Hashes: hashes,
+		Bytes:  bytes,
+	})
+}
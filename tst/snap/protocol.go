@@ -0,0 +1,144 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snap implements the snap sub-protocol, which serves contiguous
+// ranges of the state trie together with Merkle proofs of the range edges.
+// Syncing nodes download the state in ranges, rebuild the tries locally and
+// fetch the remaining trie nodes by hash to heal the result.
+package snap
+
+import (
+	"errors"
+
+	"github.com/aaechain/go-aaechain/common"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	snap1 = 1
+)
+
+// Official short name of the protocol used during capability negotiation.
+var ProtocolName = "snap"
+
+// Supported versions of the snap protocol (first is primary).
+var ProtocolVersions = []uint{snap1}
+
+// Number of implemented message corresponding to different protocol versions.
+var ProtocolLengths = []uint64{8}
+
+const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+
+// snap protocol message codes
+const (
+	GetAccountRangeMsg  = 0x00
+	AccountRangeMsg     = 0x01
+	GetStorageRangesMsg = 0x02
+	StorageRangesMsg    = 0x03
+	GetByteCodesMsg     = 0x04
+	ByteCodesMsg        = 0x05
+	GetTrieNodesMsg     = 0x06
+	TrieNodesMsg        = 0x07
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+	errBadRequest     = errors.New("bad request")
+)
+
+// getAccountRangeData is the network packet requesting the accounts of the
+// state trie with the given root from Origin. Serving stops at the first
+// account at or after Limit, or when the response reaches Bytes.
+type getAccountRangeData struct {
+	ID     uint64      // Request ID to match up the response with
+	Root   common.Hash // Root of the account trie to serve
+	Origin common.Hash // Hash of the first account to retrieve
+	Limit  common.Hash // Hash of the account after which to stop serving
+	Bytes  uint64      // Soft limit at which to stop returning data
+}
+
+// accountRangeData is the response to getAccountRangeData. The proof contains
+// the trie nodes on the paths to Origin and to the last returned account. If
+// the responder doesn't have the requested state, both lists are empty.
+type accountRangeData struct {
+	ID       uint64         // ID of the request this is a response for
+	Accounts []*accountData // List of consecutive accounts from the trie
+	Proof    [][]byte       // List of trie nodes proving the account range
+}
+
+// accountData is a single account of a range, with the hash of the address
+// as key and the RLP encoded account as body.
+type accountData struct {
+	Hash common.Hash
+	Body []byte
+}
+
+// getStorageRangesData is the network packet requesting the storage of the
+// given accounts. Origin only applies to the first account.
+type getStorageRangesData struct {
+	ID       uint64        // Request ID to match up the response with
+	Root     common.Hash   // Root of the account trie containing the accounts
+	Accounts []common.Hash // Account hashes of the storage tries to serve
+	Origin   common.Hash   // Hash of the first storage slot of the first account
+	Bytes    uint64        // Soft limit at which to stop returning data
+}
+
+// storageRangesData is the response to getStorageRangesData. Slots contains
+// the storage of a prefix of the requested accounts. All storage tries are
+// complete, except the last one if a proof is present.
+type storageRangesData struct {
+	ID    uint64           // ID of the request this is a response for
+	Slots [][]*storageData // Lists of consecutive storage slots per account
+	Proof [][]byte         // Edge proof of the last storage range, if incomplete
+}
+
+// storageData is a single storage slot, with the hash of the slot key as key
+// and the RLP encoded value as body.
+type storageData struct {
+	Hash common.Hash
+	Body []byte
+}
+
+// getByteCodesData is the network packet requesting contract code by hash.
+type getByteCodesData struct {
+	ID     uint64        // Request ID to match up the response with
+	Hashes []common.Hash // Code hashes to retrieve the code for
+	Bytes  uint64        // Soft limit at which to stop returning data
+}
+
+// byteCodesData is the response to getByteCodesData. Codes is a prefix of the
+// requested codes, missing codes are left empty.
+type byteCodesData struct {
+	ID    uint64
+	Codes [][]byte
+}
+
+// getTrieNodesData is the network packet requesting state trie nodes by hash,
+// used to heal the rebuilt tries.
+type getTrieNodesData struct {
+	ID     uint64
+	Hashes []common.Hash
+	Bytes  uint64
+}
+
+// trieNodesData is the response to getTrieNodesData. Nodes is a prefix of the
+// requested nodes, missing nodes are left empty.
+type trieNodesData struct {
+	ID    uint64
+	Nodes [][]byte
+}
@@ -0,0 +1,758 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/aaechain/go-aaechain/aaedb"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/core/state"
+	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/rlp"
+	"github.com/aaechain/go-aaechain/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+const (
+	accountConcurrency = 16 // Number of account trie chunks downloaded concurrently
+
+	maxRequestSize      = 512 * 1024 // Soft limit of the response size requested from peers
+	maxStorageAccounts  = 128        // Maximum number of storage tries requested at once
+	maxCodeRequestCount = 64         // Maximum number of codes requested at once
+	maxTrieRequestCount = 256        // Maximum number of trie nodes requested at once
+
+	requestTimeout = 10 * time.Second // Time allowance for a peer to answer a request
+)
+
+var (
+	// ErrCancelled is returned by Sync if the sync was aborted.
+	ErrCancelled = errors.New("sync cancelled")
+
+	errNoStatePeers = errors.New("no peer serves the requested state")
+)
+
+const (
+	accountRangeReq = iota
+	storageRangesReq
+	byteCodesReq
+	trieNodesReq
+)
+
+// request tracks a pending network request.
+type request struct {
+	id    uint64
+	peer  string
+	kind  int
+	timer *time.Timer
+
+	origin common.Hash    // Origin of account or storage ranges
+	task   *accountTask   // Account chunk of an account range request
+	stasks []*storageTask // Storage tries of a storage ranges request
+	hashes []common.Hash  // Requested code or trie node hashes
+}
+
+// response is a reply to a request, delivered by the protocol handler.
+type response struct {
+	id   uint64
+	peer string
+
+	accounts []*accountData
+	slots    [][]*storageData
+	proof    [][]byte
+	blobs    [][]byte
+}
+
+// accountTask is a chunk of the account trie. The accounts of the chunk are
+// inserted into a trie of their own, which is written to the database once
+// the storage and code of all its accounts are present. Nodes which only
+// cover accounts of the chunk then exist in the database together with
+// everything below them, which is what trie sync expects when healing.
+type accountTask struct {
+	next common.Hash // Next account to download
+	last common.Hash // Last account of the chunk
+
+	triedb *trie.Database
+	trie   *trie.Trie
+
+	pend      int  // Number of storage tries and codes still downloading
+	requested bool // Whaaeer an account range request is in flight
+	done      bool // Whaaeer all accounts of the chunk are downloaded
+	committed bool // Whaaeer the chunk is written to the database
+}
+
+// storageTask is the storage trie of a single account.
+type storageTask struct {
+	account common.Hash // Hash of the account owning the storage
+	root    common.Hash // Storage root of the account
+	next    common.Hash // Next slot to download
+
+	triedb *trie.Database
+	trie   *trie.Trie
+	owner  *accountTask
+}
+
+// Syncer downloads the state through the snap protocol. Accounts and storage
+// are downloaded in ranges and the tries are rebuilt locally. Trie nodes which
+// are still missing afterwards, e.g. because the sync target moved, are
+// fetched by hash.
+type Syncer struct {
+	db aaedb.Database
+
+	peers     map[string]*Peer
+	peersLock sync.RWMutex
+	update    chan struct{}  // Notifications about peers joining or leaving
+	responses chan *response // Responses delivered by the protocol handlers
+
+	// The fields below are only accessed by Sync.
+	root      common.Hash
+	tasks     []*accountTask
+	storage   []*storageTask                 // Storage tries waiting to be requested
+	codes     map[common.Hash][]*accountTask // Codes waiting for download, with the chunks needing them
+	heal      *trie.TrieSync                 // Trie sync scheduler used for healing
+	healRetry []common.Hash                  // Trie nodes to request again
+	pending   map[uint64]*request            // Requests in flight
+	busy      map[string]bool                // Peers with a request in flight
+	stateless map[string]bool                // Peers which don't serve the current root
+	reqID     uint64                         // Last request ID
+	timeout   chan *request                  // Timed out requests
+	codeReqs  map[common.Hash]bool           // Codes currently requested
+	healReqs  map[common.Hash]bool           // Trie nodes currently requested
+	stats     struct{ accounts, slots, codes, nodes uint64 }
+}
+
+// NewSyncer creates a syncer which writes the state into db.
+func NewSyncer(db aaedb.Database) *Syncer {
+	return &Syncer{
+		db:        db,
+		peers:     make(map[string]*Peer),
+		update:    make(chan struct{}, 1),
+		responses: make(chan *response, 64),
+	}
+}
+
+// Register adds a peer to sync from.
+func (s *Syncer) Register(p *Peer) {
+	s.peersLock.Lock()
+	s.peers[p.id] = p
+	s.peersLock.Unlock()
+	s.notify()
+}
+
+// Unregister removes a peer.
+func (s *Syncer) Unregister(id string) {
+	s.peersLock.Lock()
+	delete(s.peers, id)
+	s.peersLock.Unlock()
+	s.notify()
+}
+
+// PeerCount returns the number of peers to sync from.
+func (s *Syncer) PeerCount() int {
+	s.peersLock.RLock()
+	defer s.peersLock.RUnlock()
+	return len(s.peers)
+}
+
+func (s *Syncer) notify() {
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// deliver hands a response to the running sync. Responses which can't be
+// queued are dropped and the request times out.
+func (s *Syncer) deliver(res *response) {
+	select {
+	case s.responses <- res:
+	default:
+	}
+}
+
+// Sync downloads the state with the given root. If a previous call synced
+// towards a different root, the chunks written by it are kept and the
+// remaining differences are healed. Sync returns ErrCancelled when cancel
+// is closed.
+func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
+	if root == emptyRoot {
+		return nil
+	}
+	s.reset(root)
+	quit := make(chan struct{})
+	defer func() {
+		close(quit)
+		for _, req := range s.pending {
+			req.timer.Stop()
+			s.revert(req)
+		}
+	}()
+	log.Debug("Starting snap sync", "root", root)
+	for {
+		if s.healing() && s.heal.Pending() == 0 && len(s.pending) == 0 {
+			log.Info("Snap sync complete", "root", root, "accounts", s.stats.accounts, "slots", s.stats.slots, "codes", s.stats.codes, "nodes", s.stats.nodes)
+			return nil
+		}
+		if err := s.assign(quit); err != nil {
+			return err
+		}
+		if len(s.pending) == 0 && s.allStateless() {
+			return errNoStatePeers
+		}
+		select {
+		case <-s.update:
+			// Peers joined or left, revert the requests of dropped peers.
+			s.peersLock.RLock()
+			for _, req := range s.pending {
+				if s.peers[req.peer] == nil {
+					req.timer.Stop()
+					s.revert(req)
+				}
+			}
+			s.peersLock.RUnlock()
+
+		case res := <-s.responses:
+			req := s.pending[res.id]
+			if req == nil || req.peer != res.peer {
+				log.Debug("Unrequested snap response", "peer", res.peer, "reqid", res.id)
+				continue
+			}
+			req.timer.Stop()
+			delete(s.pending, req.id)
+			delete(s.busy, req.peer)
+			if err := s.process(req, res); err != nil {
+				return err
+			}
+
+		case req := <-s.timeout:
+			if s.pending[req.id] != req {
+				continue
+			}
+			log.Debug("Snap request timed out", "peer", req.peer, "reqid", req.id)
+			s.revert(req)
+
+		case <-cancel:
+			return ErrCancelled
+		}
+	}
+}
+
+// reset prepares the sync state for a new root. Chunks which are already
+// written stay done, everything else is restarted.
+func (s *Syncer) reset(root common.Hash) {
+	s.pending = make(map[uint64]*request)
+	s.busy = make(map[string]bool)
+	s.stateless = make(map[string]bool)
+	s.timeout = make(chan *request)
+	s.codeReqs = make(map[common.Hash]bool)
+	s.healReqs = make(map[common.Hash]bool)
+	s.heal, s.healRetry = nil, nil
+
+	if s.tasks == nil {
+		// Split the account hash space into equal chunks.
+		var (
+			next common.Hash
+			step = new(big.Int).Exp(common.Big2, big.NewInt(256), nil)
+		)
+		step.Div(step, big.NewInt(accountConcurrency))
+		for i := 0; i < accountConcurrency; i++ {
+			last := common.BigToHash(new(big.Int).Sub(new(big.Int).Mul(step, big.NewInt(int64(i+1))), common.Big1))
+			if i == accountConcurrency-1 {
+				last = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+			}
+			s.tasks = append(s.tasks, &accountTask{next: next, last: last})
+			next = incHash(last)
+		}
+	}
+	if s.root != root {
+		// Downloaded but unwritten data belongs to the old root.
+		for i, task := range s.tasks {
+			if task.committed {
+				continue
+			}
+			start := common.Hash{}
+			if i > 0 {
+				start = incHash(s.tasks[i-1].last)
+			}
+			*task = accountTask{next: start, last: task.last}
+		}
+		s.storage, s.codes = nil, make(map[common.Hash][]*accountTask)
+	}
+	for _, task := range s.tasks {
+		if task.trie == nil && !task.committed {
+			task.triedb = trie.NewDatabase(s.db)
+			task.trie, _ = trie.New(common.Hash{}, task.triedb)
+		}
+	}
+	s.root = root
+}
+
+// healing reports whaaeer all chunks are written and the sync is healing
+// the tries. It creates the healing scheduler when entering that phase.
+func (s *Syncer) healing() bool {
+	if s.heal != nil {
+		return true
+	}
+	for _, task := range s.tasks {
+		if !task.committed {
+			return false
+		}
+	}
+	s.heal = state.NewStateSync(s.root, s.db)
+	return true
+}
+
+// allStateless reports whaaeer there are peers, but none of them serves the
+// state being synced.
+func (s *Syncer) allStateless() bool {
+	s.peersLock.RLock()
+	defer s.peersLock.RUnlock()
+
+	for id := range s.peers {
+		if !s.stateless[id] {
+			return false
+		}
+	}
+	return len(s.peers) > 0
+}
+
+// idlePeers returns the peers which can be assigned a request.
+func (s *Syncer) idlePeers() []*Peer {
+	s.peersLock.RLock()
+	defer s.peersLock.RUnlock()
+
+	var idle []*Peer
+	for id, p := range s.peers {
+		if !s.busy[id] && !s.stateless[id] {
+			idle = append(idle, p)
+		}
+	}
+	return idle
+}
+
+// assign sends requests to idle peers. Healing requests are sent once all
+// ranges are done, otherwise code, storage and account range requests are
+// preferred in this order to keep the number of pending chunks small.
+func (s *Syncer) assign(quit chan struct{}) error {
+	for _, p := range s.idlePeers() {
+		req := &request{peer: p.id}
+		switch {
+		case s.healing():
+			req.kind, req.hashes = trieNodesReq, s.nextHealHashes()
+			if len(req.hashes) == 0 {
+				return nil
+			}
+		case len(s.codes) > len(s.codeReqs):
+			req.kind = byteCodesReq
+			for hash := range s.codes {
+				if len(req.hashes) == maxCodeRequestCount {
+					break
+				}
+				if !s.codeReqs[hash] {
+					s.codeReqs[hash] = true
+					req.hashes = append(req.hashes, hash)
+				}
+			}
+		case len(s.storage) > 0:
+			req.kind = storageRangesReq
+			if s.storage[0].next != (common.Hash{}) {
+				// Continue a large storage trie on its own.
+				req.origin, req.stasks, s.storage = s.storage[0].next, s.storage[:1:1], s.storage[1:]
+			} else {
+				n := 0
+				for n < len(s.storage) && n < maxStorageAccounts && s.storage[n].next == (common.Hash{}) {
+					n++
+				}
+				req.stasks, s.storage = s.storage[:n:n], s.storage[n:]
+			}
+		default:
+			for _, task := range s.tasks {
+				if !task.done && !task.requested {
+					req.kind, req.task, req.origin = accountRangeReq, task, task.next
+					task.requested = true
+					break
+				}
+			}
+			if req.task == nil {
+				return nil
+			}
+		}
+		if err := s.send(p, req, quit); err != nil {
+			p.log.Debug("Failed to send snap request", "err", err)
+			s.revert(req)
+		}
+	}
+	return nil
+}
+
+// nextHealHashes returns the next trie nodes to request during healing.
+func (s *Syncer) nextHealHashes() []common.Hash {
+	hashes := s.healRetry
+	if len(hashes) > maxTrieRequestCount {
+		hashes, s.healRetry = hashes[:maxTrieRequestCount], hashes[maxTrieRequestCount:]
+	} else {
+		s.healRetry = nil
+	}
+	if n := maxTrieRequestCount - len(hashes); n > 0 {
+		hashes = append(hashes, s.heal.Missing(n)...)
+	}
+	for _, hash := range hashes {
+		s.healReqs[hash] = true
+	}
+	return hashes
+}
+
+// send issues a request to a peer and starts its timeout timer.
+func (s *Syncer) send(p *Peer, req *request, quit chan struct{}) error {
+	s.reqID++
+	req.id = s.reqID
+	s.pending[req.id] = req
+	s.busy[p.id] = true
+	req.timer = time.AfterFunc(requestTimeout, func() {
+		select {
+		case s.timeout <- req:
+		case <-quit:
+		}
+	})
+	switch req.kind {
+	case accountRangeReq:
+		return p.RequestAccountRange(req.id, s.root, req.origin, req.task.last, maxRequestSize)
+	case storageRangesReq:
+		accounts := make([]common.Hash, len(req.stasks))
+		for i, st := range req.stasks {
+			accounts[i] = st.account
+		}
+		return p.RequestStorageRanges(req.id, s.root, accounts, req.origin, maxRequestSize)
+	case byteCodesReq:
+		return p.RequestByteCodes(req.id, req.hashes, maxRequestSize)
+	default:
+		return p.RequestTrieNodes(req.id, req.hashes, maxRequestSize)
+	}
+}
+
+// revert returns the tasks of a failed request to the queues.
+func (s *Syncer) revert(req *request) {
+	delete(s.pending, req.id)
+	delete(s.busy, req.peer)
+
+	switch req.kind {
+	case accountRangeReq:
+		req.task.requested = false
+	case storageRangesReq:
+		s.storage = append(req.stasks, s.storage...)
+	case byteCodesReq:
+		for _, hash := range req.hashes {
+			delete(s.codeReqs, hash)
+		}
+	case trieNodesReq:
+		s.retryHeal(req.hashes)
+	}
+}
+
+func (s *Syncer) retryHeal(hashes []common.Hash) {
+	for _, hash := range hashes {
+		if s.healReqs[hash] {
+			delete(s.healReqs, hash)
+			s.healRetry = append(s.healRetry, hash)
+		}
+	}
+}
+
+// process handles the response to a request. Invalid or empty responses
+// mark the peer as stateless, so it isn't asked again for this root.
+func (s *Syncer) process(req *request, res *response) error {
+	var err error
+	switch req.kind {
+	case accountRangeReq:
+		err = s.processAccounts(req, res)
+	case storageRangesReq:
+		err = s.processStorage(req, res)
+	case byteCodesReq:
+		err = s.processCodes(req, res)
+	case trieNodesReq:
+		err = s.processTrieNodes(req, res)
+	}
+	if err != nil {
+		log.Debug("Snap peer can't serve state", "peer", req.peer, "root", s.root, "err", err)
+		s.stateless[req.peer] = true
+		s.revert(req)
+	}
+	return nil
+}
+
+func (s *Syncer) processAccounts(req *request, res *response) error {
+	task := req.task
+	if len(res.accounts) == 0 && len(res.proof) == 0 {
+		return errBadRequest
+	}
+	keys := make([][]byte, len(res.accounts))
+	vals := make([][]byte, len(res.accounts))
+	for i, acc := range res.accounts {
+		keys[i], vals[i] = acc.Hash[:], acc.Body
+	}
+	hasMore, err := trie.VerifyRangeProof(s.root, req.origin[:], keys, vals, proofDB(res.proof))
+	if err != nil {
+		return err
+	}
+	// Decode all accounts first, so a bad account doesn't leave the chunk
+	// half updated.
+	accounts := make([]state.Account, 0, len(res.accounts))
+	for _, acc := range res.accounts {
+		if bytes.Compare(acc.Hash[:], task.last[:]) > 0 {
+			// Accounts beyond the chunk belong to the next one.
+			hasMore = false
+			break
+		}
+		var obj state.Account
+		if err := rlp.DecodeBytes(acc.Body, &obj); err != nil {
+			return err
+		}
+		accounts = append(accounts, obj)
+	}
+	task.requested = false
+	for i, obj := range accounts {
+		hash := res.accounts[i].Hash
+		task.trie.Update(hash[:], res.accounts[i].Body)
+
+		if codeHash := common.BytesToHash(obj.CodeHash); codeHash != emptyCode {
+			if ok, _ := s.db.Has(codeHash[:]); !ok {
+				s.codes[codeHash] = append(s.codes[codeHash], task)
+				task.pend++
+			}
+		}
+		if obj.Root != emptyRoot {
+			if ok, _ := s.db.Has(obj.Root[:]); !ok {
+				st := &storageTask{account: hash, root: obj.Root, owner: task, triedb: trie.NewDatabase(s.db)}
+				st.trie, _ = trie.New(common.Hash{}, st.triedb)
+				s.storage = append(s.storage, st)
+				task.pend++
+			}
+		}
+		task.next = incHash(hash)
+		if hash == task.last {
+			hasMore = false
+		}
+	}
+	s.stats.accounts += uint64(len(accounts))
+	if !hasMore || len(accounts) < len(res.accounts) {
+		task.done = true
+	}
+	return s.commitTask(task)
+}
+
+func (s *Syncer) processStorage(req *request, res *response) error {
+	if len(res.slots) == 0 {
+		return errBadRequest
+	}
+	if len(res.slots) > len(req.stasks) {
+		return errors.New("more storage ranges than requested")
+	}
+	// Verify all ranges before applying any of them.
+	type result struct {
+		keys, vals [][]byte
+		hasMore    bool
+	}
+	results := make([]result, len(res.slots))
+	for i, slots := range res.slots {
+		st := req.stasks[i]
+		r := &results[i]
+		for _, slot := range slots {
+			r.keys = append(r.keys, common.CopyBytes(slot.Hash[:]))
+			r.vals = append(r.vals, slot.Body)
+		}
+		var (
+			origin common.Hash
+			proof  trie.DatabaseReader
+		)
+		if i == 0 {
+			origin = req.origin
+		}
+		if i == len(res.slots)-1 && len(res.proof) > 0 {
+			proof = proofDB(res.proof)
+		} else if origin != (common.Hash{}) {
+			return errors.New("missing storage proof")
+		}
+		var err error
+		if r.hasMore, err = trie.VerifyRangeProof(st.root, origin[:], r.keys, r.vals, proof); err != nil {
+			return err
+		}
+	}
+	// Apply the ranges and requeue unfinished storage tries.
+	var requeue []*storageTask
+	for i, r := range results {
+		st := req.stasks[i]
+		for j, key := range r.keys {
+			st.trie.Update(key, r.vals[j])
+		}
+		s.stats.slots += uint64(len(r.keys))
+		if r.hasMore {
+			st.next = incHash(common.BytesToHash(r.keys[len(r.keys)-1]))
+			requeue = append(requeue, st)
+			continue
+		}
+		if err := s.commitStorage(st); err != nil {
+			return err
+		}
+	}
+	requeue = append(requeue, req.stasks[len(res.slots):]...)
+	s.storage = append(requeue, s.storage...)
+	return nil
+}
+
+func (s *Syncer) processCodes(req *request, res *response) error {
+	if len(res.blobs) > len(req.hashes) {
+		return errors.New("more codes than requested")
+	}
+	delivered := 0
+	for i, code := range res.blobs {
+		if len(code) == 0 {
+			continue
+		}
+		hash := req.hashes[i]
+		if crypto.Keccak256Hash(code) != hash {
+			return errors.New("code hash mismatch")
+		}
+		if err := s.db.Put(hash[:], code); err != nil {
+			return err
+		}
+		for _, task := range s.codes[hash] {
+			task.pend--
+			if err := s.commitTask(task); err != nil {
+				return err
+			}
+		}
+		delete(s.codes, hash)
+		delete(s.codeReqs, hash)
+		delivered++
+	}
+	s.stats.codes += uint64(delivered)
+	if delivered == 0 {
+		return errBadRequest
+	}
+	// Release the codes which weren't delivered.
+	for _, hash := range req.hashes {
+		delete(s.codeReqs, hash)
+	}
+	return nil
+}
+
+func (s *Syncer) processTrieNodes(req *request, res *response) error {
+	if len(res.blobs) > len(req.hashes) {
+		return errors.New("more trie nodes than requested")
+	}
+	var results []trie.SyncResult
+	for i, blob := range res.blobs {
+		if len(blob) == 0 {
+			continue
+		}
+		hash := req.hashes[i]
+		if crypto.Keccak256Hash(blob) != hash {
+			return errors.New("trie node hash mismatch")
+		}
+		results = append(results, trie.SyncResult{Hash: hash, Data: blob})
+	}
+	if len(results) == 0 {
+		return errBadRequest
+	}
+	for _, result := range results {
+		delete(s.healReqs, result.Hash)
+		if _, _, err := s.heal.Process([]trie.SyncResult{result}); err != nil && err != trie.ErrAlreadyProcessed && err != trie.ErrNotRequested {
+			return err
+		}
+	}
+	s.retryHeal(req.hashes)
+
+	batch := s.db.NewBatch()
+	n, err := s.heal.Commit(batch)
+	if err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	s.stats.nodes += uint64(n)
+	return nil
+}
+
+// commitStorage writes a completed storage trie and updates its chunk.
+func (s *Syncer) commitStorage(st *storageTask) error {
+	root, err := st.trie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if root != st.root {
+		return errors.New("storage root mismatch")
+	}
+	if err := st.triedb.Commit(root, false); err != nil {
+		return err
+	}
+	st.owner.pend--
+	return s.commitTask(st.owner)
+}
+
+// commitTask writes the account trie chunk once all its accounts are
+// downloaded together with their storage and code.
+func (s *Syncer) commitTask(task *accountTask) error {
+	if !task.done || task.pend > 0 || task.committed {
+		return nil
+	}
+	root, err := task.trie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if err := task.triedb.Commit(root, false); err != nil {
+		return err
+	}
+	task.committed, task.trie, task.triedb = true, nil, nil
+	return nil
+}
+
+// proofDB turns a list of proof nodes into a database for proof verification.
+// A nil database is returned for an empty list.
+func proofDB(proof [][]byte) trie.DatabaseReader {
+	if len(proof) == 0 {
+		return nil
+	}
+	db, _ := aaedb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// incHash returns the hash following h, wrapping around at the end.
+func incHash(h common.Hash) common.Hash {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			break
+		}
+	}
+	return h
+}
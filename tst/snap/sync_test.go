@@ -0,0 +1,198 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-aaeereum library.
+//
+// The go-aaeereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-aaeereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-aaeereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aaechain/go-aaechain/aaedb"
+	"github.com/aaechain/go-aaechain/common"
+	"github.com/aaechain/go-aaechain/core/state"
+	"github.com/aaechain/go-aaechain/p2p"
+	"github.com/aaechain/go-aaechain/p2p/discover"
+	"github.com/aaechain/go-aaechain/trie"
+)
+
+// makeTestState creates a state with accounts, contract code and storage,
+// including a storage trie too large to be served in a single response.
+func makeTestState(t *testing.T, db state.Database, root common.Hash, seed byte) common.Hash {
+	statedb, err := state.New(root, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		addr := common.BytesToAddress([]byte{seed, byte(i >> 8), byte(i)})
+		statedb.AddBalance(addr, big.NewInt(int64(i+1)))
+		statedb.SetNonce(addr, uint64(i))
+		if i%5 == 0 {
+			statedb.SetCode(addr, []byte{seed, byte(i), 0x60, 0x00})
+		}
+		if i%7 == 0 {
+			for j := 0; j < i%20+1; j++ {
+				statedb.Seaaeate(addr, common.BytesToHash([]byte{byte(j)}), common.BytesToHash([]byte{seed, byte(i), byte(j)}))
+			}
+		}
+	}
+	large := common.BytesToAddress([]byte{seed, 0xff, 0xff})
+	for j := 0; j < 20000; j++ {
+		statedb.Seaaeate(large, common.BytesToHash([]byte{byte(j >> 8), byte(j)}), common.BytesToHash([]byte{seed, byte(j >> 8), byte(j)}))
+	}
+	root, err = statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// connect links the syncer to a peer serving the state in db.
+func connect(syncer *Syncer, local, remote *trie.Database) func() {
+	app, net := p2p.MsgPipe()
+
+	var id1, id2 discover.NodeID
+	rand.Read(id1[:])
+	rand.Read(id2[:])
+
+	go handle(remote, nil, newPeer(snap1, p2p.NewPeer(id1, "server", nil), app))
+	go handle(local, syncer, newPeer(snap1, p2p.NewPeer(id2, "client", nil), net))
+
+	// Wait for the peer to be registered.
+	for i := 0; i < 100 && syncer.PeerCount() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return func() {
+		app.Close()
+		net.Close()
+	}
+}
+
+// checkState checks that the complete state with the given root is present
+// in db and equal to the source state.
+func checkState(t *testing.T, db aaedb.Database, src state.Database, root common.Hash) {
+	if err := checkStateConsistency(db, root); err != nil {
+		t.Fatalf("inconsistent state: %v", err)
+	}
+	want, _ := state.New(root, src)
+	have, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []common.Address{
+		common.BytesToAddress([]byte{1, 0, 0}),
+		common.BytesToAddress([]byte{1, 0, 35}),
+		common.BytesToAddress([]byte{2, 0, 35}),
+		common.BytesToAddress([]byte{1, 0xff, 0xff}),
+	} {
+		if have.GetBalance(addr).Cmp(want.GetBalance(addr)) != 0 {
+			t.Errorf("%x: balance mismatch: have %v, want %v", addr, have.GetBalance(addr), want.GetBalance(addr))
+		}
+		if !bytes.Equal(have.GetCode(addr), want.GetCode(addr)) {
+			t.Errorf("%x: code mismatch: have %x, want %x", addr, have.GetCode(addr), want.GetCode(addr))
+		}
+		if have.Geaaeate(addr, common.Hash{}) != want.Geaaeate(addr, common.Hash{}) {
+			t.Errorf("%x: storage mismatch", addr)
+		}
+	}
+}
+
+// checkStateConsistency checks that all data of a state root is present.
+func checkStateConsistency(db aaedb.Database, root common.Hash) error {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	return it.Error
+}
+
+// Tests that the state can be synced from a single peer.
+func TestSync(t *testing.T) {
+	src := state.NewDatabase(newMemDatabase())
+	root := makeTestState(t, src, common.Hash{}, 1)
+
+	db := newMemDatabase()
+	syncer := NewSyncer(db)
+	defer connect(syncer, trie.NewDatabase(db), src.TrieDB())()
+
+	if err := syncer.Sync(root, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	checkState(t, db, src, root)
+}
+
+// Tests that syncing to a new root after a finished sync only heals the
+// differences between the two states.
+func TestSyncMovedRoot(t *testing.T) {
+	src := state.NewDatabase(newMemDatabase())
+	root1 := makeTestState(t, src, common.Hash{}, 1)
+	root2 := makeTestState(t, src, root1, 2)
+
+	db := newMemDatabase()
+	syncer := NewSyncer(db)
+	defer connect(syncer, trie.NewDatabase(db), src.TrieDB())()
+
+	if err := syncer.Sync(root1, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	accounts := syncer.stats.accounts
+	if err := syncer.Sync(root2, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if syncer.stats.accounts != accounts {
+		t.Errorf("accounts downloaded again: have %d, want %d", syncer.stats.accounts, accounts)
+	}
+	if syncer.stats.nodes == 0 {
+		t.Errorf("no trie nodes healed")
+	}
+	checkState(t, db, src, root2)
+}
+
+// Tests that the sync fails if no peer has the requested state.
+func TestSyncMissingState(t *testing.T) {
+	src := state.NewDatabase(newMemDatabase())
+	root := makeTestState(t, src, common.Hash{}, 1)
+
+	db := newMemDatabase()
+	syncer := NewSyncer(db)
+	defer connect(syncer, trie.NewDatabase(db), trie.NewDatabase(newMemDatabase()))()
+
+	if err := syncer.Sync(root, make(chan struct{})); err != errNoStatePeers {
+		t.Fatalf("sync error mismatch: have %v, want %v", err, errNoStatePeers)
+	}
+}
+
+// Tests that a running sync can be cancelled.
+func TestSyncCancel(t *testing.T) {
+	db := newMemDatabase()
+	syncer := NewSyncer(db)
+
+	cancel := make(chan struct{})
+	close(cancel)
+	if err := syncer.Sync(common.HexToHash("0x01"), cancel); err != ErrCancelled {
+		t.Fatalf("sync error mismatch: have %v, want %v", err, ErrCancelled)
+	}
+}
+
+func newMemDatabase() *aaedb.MemDatabase {
+	db, _ := aaedb.NewMemDatabase()
+	return db
+}
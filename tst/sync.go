@@ -25,6 +25,7 @@ import (
 	"github.com/aaechain/go-aaechain/core/types"
 	"github.com/aaechain/go-aaechain/aae/downloader"
 	"github.com/aaechain/go-aaechain/log"
+	"github.com/aaechain/go-aaechain/p2p"
 	"github.com/aaechain/go-aaechain/p2p/discover"
 )
 
@@ -192,6 +193,8 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	if err := pm.downloader.Synchronise(peer.id, pHead, pTd, mode); err != nil {
 		return
 	}
+	peer.AdjustScore(p2p.ScoreUseful, "synced chain")
+
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
 		log.Info("Fast sync complete, auto disabling")
 		atomic.StoreUint32(&pm.fastSync, 0)
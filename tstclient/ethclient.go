@@ -28,7 +28,6 @@ import (
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/common/hexutil"
 	"github.com/aaechain/go-aaechain/core/types"
-	"github.com/aaechain/go-aaechain/rlp"
 	"github.com/aaechain/go-aaechain/rpc"
 )
 
@@ -469,13 +468,35 @@ func (ec *Client) EstimateGas(ctx context.Context, msg aaeereum.CallMsg) (uint64
 // If the transaction was a contract creation use the TransactionReceipt method to get the
 // contract address after the transaction has been mined.
 func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return err
 	}
 	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", common.ToHex(data))
 }
 
+// SignFeePayerTransaction requests the node to sign a fee delegated transaction,
+// already signed by its sender, as the given fee payer. The account of the fee
+// payer must be unlocked on the node. Fee payers holding their own keys can use
+// types.SignFeePayer instead.
+func (ec *Client) SignFeePayerTransaction(ctx context.Context, tx *types.Transaction, feePayer common.Address) (*types.Transaction, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := ec.c.CallContext(ctx, &res, "eth_signFeePayerTransaction", hexutil.Bytes(data), feePayer); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(res.Raw); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
 func toCallArg(msg aaeereum.CallMsg) interface{} {
 	arg := map[string]interface{}{
 		"from": msg.From,
@@ -17,8 +17,11 @@
 package mailserver
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
+	"sort"
 
 	"github.com/aaechain/go-aaechain/cmd/utils"
 	"github.com/aaechain/go-aaechain/common"
@@ -27,7 +30,7 @@ import (
 	"github.com/aaechain/go-aaechain/rlp"
 	whisper "github.com/aaechain/go-aaechain/whisper/whisperv6"
 	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
 type WMailServer struct {
@@ -37,23 +40,65 @@ type WMailServer struct {
 	key []byte
 }
 
+// DBKey is the key of an archived envelope. Envelopes are indexed by topic
+// first and by the time they were sent second, so that the envelopes of a
+// topic within a time range are stored contiguously.
 type DBKey struct {
+	topic     whisper.TopicType
 	timestamp uint32
 	hash      common.Hash
 	raw       []byte
 }
 
-func NewDbKey(t uint32, h common.Hash) *DBKey {
-	const sz = common.HashLength + 4
+// DBKeyLength is the length of the raw representation of a DBKey
+const DBKeyLength = whisper.TopicLength + 4 + common.HashLength
+
+// legacyDBKeyLength is the length of the keys envelopes were archived with
+// before they were indexed by topic, consisting of the time they were sent
+// followed by their hash
+const legacyDBKeyLength = 4 + common.HashLength
+
+// maxRequestLimit is the maximum number of envelopes delivered for a single
+// request, requests for more of them are continued with the cursor
+const maxRequestLimit = 1000
+
+func NewDbKey(topic whisper.TopicType, t uint32, h common.Hash) *DBKey {
 	var k DBKey
+	k.topic = topic
 	k.timestamp = t
 	k.hash = h
-	k.raw = make([]byte, sz)
-	binary.BigEndian.PutUint32(k.raw, k.timestamp)
-	copy(k.raw[4:], k.hash[:])
+	k.raw = make([]byte, DBKeyLength)
+	copy(k.raw, k.topic[:])
+	binary.BigEndian.PutUint32(k.raw[whisper.TopicLength:], k.timestamp)
+	copy(k.raw[whisper.TopicLength+4:], k.hash[:])
 	return &k
 }
 
+func parseDbKey(raw []byte) (*DBKey, error) {
+	if len(raw) != DBKeyLength {
+		return nil, fmt.Errorf("invalid DB key length %d", len(raw))
+	}
+	k := &DBKey{raw: raw}
+	copy(k.topic[:], raw)
+	k.timestamp = binary.BigEndian.Uint32(raw[whisper.TopicLength:])
+	copy(k.hash[:], raw[whisper.TopicLength+4:])
+	return k, nil
+}
+
+// MessagesRequest is the RLP encoded payload of a request to the mail server.
+// It asks for the envelopes sent within [Lower, Upper) with one of the given
+// Topics, or if no topics are given with a topic matching Bloom. At most
+// Limit envelopes are delivered (maxRequestLimit if zero or above it),
+// continuing after Cursor if it is set to the cursor of a previous response.
+type MessagesRequest struct {
+	Lower  uint32
+	Upper  uint32
+	Topics []whisper.TopicType
+	Bloom  []byte
+	Limit  uint32
+	Cursor []byte
+}
+
 func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, pow float64) {
 	var err error
 	if len(path) == 0 {
@@ -68,6 +113,9 @@ func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, p
 	if err != nil {
 		utils.Fatalf("Failed to open DB file: %s", err)
 	}
+	if err := s.migrate(); err != nil {
+		utils.Fatalf("Failed to migrate DB: %s", err)
+	}
 
 	s.w = shh
 	s.pow = pow
@@ -82,6 +130,48 @@ func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, p
 	}
 }
 
+// migrate moves the envelopes archived under legacy keys to the keys
+// indexing them by topic
+func (s *WMailServer) migrate() error {
+	i := s.db.NewIterator(nil, nil)
+	defer i.Release()
+
+	var (
+		batch leveldb.Batch
+		count int
+	)
+	for i.Next() {
+		if len(i.Key()) != legacyDBKeyLength {
+			continue
+		}
+		var envelope whisper.Envelope
+		if err := rlp.DecodeBytes(i.Value(), &envelope); err != nil {
+			log.Warn(fmt.Sprintf("Dropping undecodable archived envelope: %s", err))
+		} else {
+			key := NewDbKey(envelope.Topic, binary.BigEndian.Uint32(i.Key()), envelope.Hash())
+			batch.Put(key.raw, i.Value())
+		}
+		batch.Delete(i.Key())
+		count++
+		if batch.Len() >= 1000 {
+			if err := s.db.Write(&batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := i.Error(); err != nil {
+		return err
+	}
+	if err := s.db.Write(&batch, nil); err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Info(fmt.Sprintf("Migrated %d archived envelopes to topic indexed keys", count))
+	}
+	return nil
+}
+
 func (s *WMailServer) Close() {
 	if s.db != nil {
 		s.db.Close()
@@ -89,7 +179,7 @@ func (s *WMailServer) Close() {
 }
 
 func (s *WMailServer) Archive(env *whisper.Envelope) {
-	key := NewDbKey(env.Expiry-env.TTL, env.Hash())
+	key := NewDbKey(env.Topic, env.Expiry-env.TTL, env.Hash())
 	rawEnvelope, err := rlp.EncodeToBytes(env)
 	if err != nil {
 		log.Error(fmt.Sprintf("rlp.EncodeToBytes failed: %s", err))
@@ -107,60 +197,156 @@ func (s *WMailServer) DeliverMail(peer *whisper.Peer, request *whisper.Envelope)
 		return
 	}
 
-	ok, lower, upper, bloom := s.validateRequest(peer.ID(), request)
-	if ok {
-		s.processRequest(peer, lower, upper, bloom)
+	ok, req := s.validateRequest(peer.ID(), request)
+	if !ok {
+		return
+	}
+	_, cursor, err := s.processRequest(peer, req)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to process mail request: %s", err))
+		return
+	}
+	response := &whisper.MailServerResponse{
+		RequestID: request.Hash(),
+		Cursor:    cursor,
+	}
+	if err := s.w.SendHistoricMessageResponse(peer, response); err != nil {
+		log.Error(fmt.Sprintf("Failed to send mail server response to peer: %s", err))
 	}
 }
 
-func (s *WMailServer) processRequest(peer *whisper.Peer, lower, upper uint32, bloom []byte) []*whisper.Envelope {
+// processRequest delivers the archived envelopes matching the request to the
+// peer. If the limit of the request was reached before all of them were
+// delivered, it returns the cursor to continue from with the next request.
+func (s *WMailServer) processRequest(peer *whisper.Peer, req *MessagesRequest) ([]*whisper.Envelope, []byte, error) {
 	ret := make([]*whisper.Envelope, 0)
-	var err error
-	var zero common.Hash
-	kl := NewDbKey(lower, zero)
-	ku := NewDbKey(upper, zero)
-	i := s.db.NewIterator(&util.Range{Start: kl.raw, Limit: ku.raw}, nil)
+	q := newQuery(req)
+	i := s.db.NewIterator(nil, nil)
 	defer i.Release()
 
-	for i.Next() {
-		var envelope whisper.Envelope
-		err = rlp.DecodeBytes(i.Value(), &envelope)
+	var ok bool
+	if len(req.Cursor) > 0 {
+		// continue after the last envelope delivered for the previous request
+		if ok = i.Seek(req.Cursor); ok && bytes.Equal(i.Key(), req.Cursor) {
+			ok = i.Next()
+		}
+	} else {
+		ok = q.seek(i, whisper.TopicType{})
+	}
+
+	limit := req.Limit
+	if limit == 0 || limit > maxRequestLimit {
+		limit = maxRequestLimit
+	}
+
+	var (
+		count uint32
+		last  []byte
+	)
+	for ok {
+		key, err := parseDbKey(i.Key())
 		if err != nil {
-			log.Error(fmt.Sprintf("RLP decoding failed: %s", err))
+			return nil, nil, err
+		}
+		switch {
+		case !q.match(key.topic) || key.timestamp >= req.Upper:
+			ok = q.seekAfter(i, key.topic)
+			continue
+		case key.timestamp < req.Lower:
+			ok = q.seek(i, key.topic)
+			continue
+		case count == limit:
+			// there are more matching envelopes than the limit allows
+			return ret, last, i.Error()
 		}
 
-		if whisper.BloomFilterMatch(bloom, envelope.Bloom()) {
-			if peer == nil {
-				// used for test purposes
-				ret = append(ret, &envelope)
-			} else {
-				err = s.w.SendP2PDirect(peer, &envelope)
-				if err != nil {
-					log.Error(fmt.Sprintf("Failed to send direct message to peer: %s", err))
-					return nil
-				}
-			}
+		var envelope whisper.Envelope
+		if err := rlp.DecodeBytes(i.Value(), &envelope); err != nil {
+			log.Error(fmt.Sprintf("RLP decoding failed: %s", err))
+		} else if peer == nil {
+			// used for test purposes
+			ret = append(ret, &envelope)
+		} else if err := s.w.SendP2PDirect(peer, &envelope); err != nil {
+			return nil, nil, fmt.Errorf("failed to send direct message to peer: %s", err)
 		}
+		count++
+		last = common.CopyBytes(key.raw)
+		ok = i.Next()
+	}
+	if err := i.Error(); err != nil {
+		return nil, nil, fmt.Errorf("Level DB iterator error: %s", err)
 	}
+	return ret, nil, nil
+}
 
-	err = i.Error()
-	if err != nil {
-		log.Error(fmt.Sprintf("Level DB iterator error: %s", err))
+// query selects the archived envelopes matching a request by seeking over
+// the topics which can't match
+type query struct {
+	lower  uint32
+	topics []whisper.TopicType // sorted, nil if topics are matched by bloom
+	bloom  []byte
+}
+
+func newQuery(req *MessagesRequest) *query {
+	q := &query{lower: req.Lower, bloom: req.Bloom}
+	if len(req.Topics) > 0 {
+		q.topics = make([]whisper.TopicType, len(req.Topics))
+		copy(q.topics, req.Topics)
+		sort.Slice(q.topics, func(i, j int) bool {
+			return bytes.Compare(q.topics[i][:], q.topics[j][:]) < 0
+		})
 	}
+	return q
+}
 
-	return ret
+func (q *query) match(topic whisper.TopicType) bool {
+	if q.topics == nil {
+		return whisper.BloomFilterMatch(q.bloom, whisper.TopicToBloom(topic))
+	}
+	idx := q.search(topic)
+	return idx < len(q.topics) && q.topics[idx] == topic
 }
 
-func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope) (bool, uint32, uint32, []byte) {
+// search returns the index of the first requested topic not less than topic
+func (q *query) search(topic whisper.TopicType) int {
+	return sort.Search(len(q.topics), func(i int) bool {
+		return bytes.Compare(q.topics[i][:], topic[:]) >= 0
+	})
+}
+
+// seek moves the iterator to the first envelope sent after the lower bound
+// with the first topic not less than topic which can match
+func (q *query) seek(i iterator.Iterator, topic whisper.TopicType) bool {
+	if q.topics != nil {
+		idx := q.search(topic)
+		if idx == len(q.topics) {
+			return false
+		}
+		topic = q.topics[idx]
+	}
+	return i.Seek(NewDbKey(topic, q.lower, common.Hash{}).raw)
+}
+
+// seekAfter moves the iterator past all the envelopes with the given topic
+func (q *query) seekAfter(i iterator.Iterator, topic whisper.TopicType) bool {
+	n := binary.BigEndian.Uint32(topic[:])
+	if n == math.MaxUint32 {
+		return false
+	}
+	binary.BigEndian.PutUint32(topic[:], n+1)
+	return q.seek(i, topic)
+}
+
+func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope) (bool, *MessagesRequest) {
 	if s.pow > 0.0 && request.PoW() < s.pow {
-		return false, 0, 0, nil
+		return false, nil
 	}
 
 	f := whisper.Filter{KeySym: s.key}
 	decrypted := request.Open(&f)
 	if decrypted == nil {
 		log.Warn(fmt.Sprintf("Failed to decrypt p2p request"))
-		return false, 0, 0, nil
+		return false, nil
 	}
 
 	src := crypto.FromECDSAPub(decrypted.Src)
@@ -172,24 +358,38 @@ func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope)
 	// if !bytes.Equal(peerID, src) {
 	if src == nil {
 		log.Warn(fmt.Sprintf("Wrong signature of p2p request"))
-		return false, 0, 0, nil
-	}
-
-	var bloom []byte
-	payloadSize := len(decrypted.Payload)
-	if payloadSize < 8 {
-		log.Warn(fmt.Sprintf("Undersized p2p request"))
-		return false, 0, 0, nil
-	} else if payloadSize == 8 {
-		bloom = whisper.MakeFullNodeBloom()
-	} else if payloadSize < 8+whisper.BloomFilterSize {
-		log.Warn(fmt.Sprintf("Undersized bloom filter in p2p request"))
-		return false, 0, 0, nil
-	} else {
-		bloom = decrypted.Payload[8 : 8+whisper.BloomFilterSize]
+		return false, nil
+	}
+
+	var req MessagesRequest
+	if err := rlp.DecodeBytes(decrypted.Payload, &req); err != nil {
+		// not a MessagesRequest, so expect the legacy format of the
+		// lower and upper bounds followed by an optional bloom filter
+		payloadSize := len(decrypted.Payload)
+		if payloadSize < 8 {
+			log.Warn(fmt.Sprintf("Undersized p2p request"))
+			return false, nil
+		} else if payloadSize > 8 && payloadSize < 8+whisper.BloomFilterSize {
+			log.Warn(fmt.Sprintf("Undersized bloom filter in p2p request"))
+			return false, nil
+		} else if payloadSize > 8 {
+			req.Bloom = decrypted.Payload[8 : 8+whisper.BloomFilterSize]
+		}
+		req.Lower = binary.BigEndian.Uint32(decrypted.Payload[:4])
+		req.Upper = binary.BigEndian.Uint32(decrypted.Payload[4:8])
 	}
 
-	lower := binary.BigEndian.Uint32(decrypted.Payload[:4])
-	upper := binary.BigEndian.Uint32(decrypted.Payload[4:8])
-	return true, lower, upper, bloom
+	if len(req.Topics) == 0 {
+		if len(req.Bloom) == 0 {
+			req.Bloom = whisper.MakeFullNodeBloom()
+		} else if len(req.Bloom) != whisper.BloomFilterSize {
+			log.Warn(fmt.Sprintf("Wrong bloom filter size in p2p request"))
+			return false, nil
+		}
+	}
+	if len(req.Cursor) > 0 && len(req.Cursor) != DBKeyLength {
+		log.Warn(fmt.Sprintf("Wrong cursor length in p2p request"))
+		return false, nil
+	}
+	return true, &req
 }
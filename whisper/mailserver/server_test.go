@@ -21,12 +21,16 @@ import (
 	"crypto/ecdsa"
 	"encoding/binary"
 	"io/ioutil"
+	"math"
 	"math/rand"
+	"os"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/rlp"
 	whisper "github.com/aaechain/go-aaechain/whisper/whisperv6"
 )
 
@@ -51,19 +55,31 @@ func assert(statement bool, text string, t *testing.T) {
 
 func TestDBKey(t *testing.T) {
 	var h common.Hash
+	topic := whisper.TopicType{0x1F, 0x7E, 0xA1, 0x7F}
 	i := uint32(time.Now().Unix())
-	k := NewDbKey(i, h)
-	assert(len(k.raw) == common.HashLength+4, "wrong DB key length", t)
-	assert(byte(i%0x100) == k.raw[3], "raw representation should be big endian", t)
-	assert(byte(i/0x1000000) == k.raw[0], "big endian expected", t)
+	k := NewDbKey(topic, i, h)
+	assert(len(k.raw) == whisper.TopicLength+common.HashLength+4, "wrong DB key length", t)
+	assert(bytes.Equal(topic[:], k.raw[:whisper.TopicLength]), "topic should be the DB key prefix", t)
+	assert(byte(i%0x100) == k.raw[whisper.TopicLength+3], "raw representation should be big endian", t)
+	assert(byte(i/0x1000000) == k.raw[whisper.TopicLength], "big endian expected", t)
+
+	parsed, err := parseDbKey(k.raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(parsed.topic == topic && parsed.timestamp == i && parsed.hash == h, "wrong parsed DB key", t)
 }
 
 func generateEnvelope(t *testing.T) *whisper.Envelope {
+	return generateTopicEnvelope(t, whisper.TopicType{0x1F, 0x7E, 0xA1, 0x7F}, []byte("test payload"))
+}
+
+func generateTopicEnvelope(t *testing.T, topic whisper.TopicType, payload []byte) *whisper.Envelope {
 	h := crypto.Keccak256Hash([]byte("test sample data"))
 	params := &whisper.MessageParams{
 		KeySym:   h[:],
-		Topic:    whisper.TopicType{0x1F, 0x7E, 0xA1, 0x7F},
-		Payload:  []byte("test payload"),
+		Topic:    topic,
+		Payload:  payload,
 		PoW:      powRequirement,
 		WorkTime: 2,
 	}
@@ -79,7 +95,7 @@ func generateEnvelope(t *testing.T) *whisper.Envelope {
 	return env
 }
 
-func TestMailServer(t *testing.T) {
+func newTestServer(t *testing.T) (*WMailServer, func()) {
 	const password = "password_for_this_test"
 	const dbPath = "whisper-server-test"
 
@@ -88,22 +104,194 @@ func TestMailServer(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	var server WMailServer
+	server := &WMailServer{}
 	shh = whisper.New(&whisper.DefaultConfig)
-	shh.RegisterServer(&server)
+	shh.RegisterServer(server)
 
 	server.Init(shh, dir, password, powRequirement)
-	defer server.Close()
 
 	keyID, err = shh.AddSymKeyFromPassword(password)
 	if err != nil {
+		server.Close()
+		os.RemoveAll(dir)
 		t.Fatalf("Failed to create symmetric key for mail request: %s", err)
 	}
+	return server, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMailServer(t *testing.T) {
+	server, teardown := newTestServer(t)
+	defer teardown()
 
 	rand.Seed(seed)
 	env := generateEnvelope(t)
 	server.Archive(env)
-	deliverTest(t, &server, env)
+	deliverTest(t, server, env)
+}
+
+// TestMailServerTopicsPagination tests requesting exact topics and paging
+// through the result with the cursor
+func TestMailServerTopicsPagination(t *testing.T) {
+	server, teardown := newTestServer(t)
+	defer teardown()
+
+	topics := []whisper.TopicType{{0x01}, {0x02}, {0x03}}
+	expected := make(map[common.Hash]bool)
+	var lower, upper uint32 = math.MaxUint32, 0
+	for i := 0; i < 10; i++ {
+		topic := topics[i%len(topics)]
+		env := generateTopicEnvelope(t, topic, []byte{byte(i)})
+		server.Archive(env)
+		if topic != topics[1] {
+			expected[env.Hash()] = true
+		}
+		if sent := env.Expiry - env.TTL; sent < lower {
+			lower = sent
+		}
+		if sent := env.Expiry - env.TTL; sent+1 > upper {
+			upper = sent + 1
+		}
+	}
+
+	req := &MessagesRequest{
+		Lower:  lower,
+		Upper:  upper,
+		Topics: []whisper.TopicType{topics[2], topics[0]},
+		Limit:  3,
+	}
+	received := make(map[common.Hash]bool)
+	for pages := 1; ; pages++ {
+		mail, cursor, err := server.processRequest(nil, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uint32(len(mail)) > req.Limit {
+			t.Fatalf("expected at most %d envelopes, got %d", req.Limit, len(mail))
+		}
+		for _, env := range mail {
+			if !expected[env.Hash()] {
+				t.Fatalf("unexpected envelope with topic %x", env.Topic)
+			}
+			if received[env.Hash()] {
+				t.Fatalf("envelope %x delivered twice", env.Hash())
+			}
+			received[env.Hash()] = true
+		}
+		if cursor == nil {
+			if pages != 3 {
+				t.Fatalf("expected 3 pages, got %d", pages)
+			}
+			break
+		}
+		req.Cursor = cursor
+	}
+	if len(received) != len(expected) {
+		t.Fatalf("expected %d envelopes, got %d", len(expected), len(received))
+	}
+
+	// check the request is accepted by validateRequest
+	id, err := shh.NewKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := shh.GetPrivateKey(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := rlp.EncodeToBytes(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, decoded := server.validateRequest(crypto.FromECDSAPub(&key.PublicKey), createRequestEnvelope(t, topics[0], payload, key))
+	if !ok {
+		t.Fatal("request validation failed")
+	}
+	if decoded.Lower != req.Lower || decoded.Upper != req.Upper || decoded.Limit != req.Limit ||
+		!reflect.DeepEqual(decoded.Topics, req.Topics) || !bytes.Equal(decoded.Cursor, req.Cursor) {
+		t.Fatalf("expected decoded request %+v, got %+v", req, decoded)
+	}
+}
+
+// TestMailServerLimit tests the number of envelopes delivered for a single
+// request is capped, also if it doesn't set a limit
+func TestMailServerLimit(t *testing.T) {
+	server, teardown := newTestServer(t)
+	defer teardown()
+
+	env := generateEnvelope(t)
+	raw, err := rlp.EncodeToBytes(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := env.Expiry - env.TTL
+	for i := 0; i <= maxRequestLimit; i++ {
+		var h common.Hash
+		binary.BigEndian.PutUint32(h[:], uint32(i))
+		if err := server.db.Put(NewDbKey(env.Topic, sent, h).raw, raw, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, limit := range []uint32{0, maxRequestLimit + 1} {
+		req := &MessagesRequest{
+			Lower:  sent,
+			Upper:  sent + 1,
+			Topics: []whisper.TopicType{env.Topic},
+			Limit:  limit,
+		}
+		mail, cursor, err := server.processRequest(nil, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mail) != maxRequestLimit {
+			t.Fatalf("limit %d: expected %d envelopes, got %d", limit, maxRequestLimit, len(mail))
+		}
+		if cursor == nil {
+			t.Fatalf("limit %d: expected a cursor for the remaining envelope", limit)
+		}
+	}
+}
+
+// TestMailServerMigrate tests envelopes archived under legacy keys are
+// migrated to the keys indexed by topic
+func TestMailServerMigrate(t *testing.T) {
+	server, teardown := newTestServer(t)
+	defer teardown()
+
+	env := generateEnvelope(t)
+	raw, err := rlp.EncodeToBytes(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := env.Expiry - env.TTL
+	legacy := make([]byte, legacyDBKeyLength)
+	binary.BigEndian.PutUint32(legacy, sent)
+	hash := env.Hash()
+	copy(legacy[4:], hash[:])
+	if err := server.db.Put(legacy, raw, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := server.db.Has(legacy, nil); ok {
+		t.Fatal("expected the legacy key to be deleted")
+	}
+	mail, _, err := server.processRequest(nil, &MessagesRequest{
+		Lower:  sent,
+		Upper:  sent + 1,
+		Topics: []whisper.TopicType{env.Topic},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mail) != 1 || mail[0].Hash() != hash {
+		t.Fatalf("expected the migrated envelope to be delivered, got %d envelopes", len(mail))
+	}
 }
 
 func deliverTest(t *testing.T, server *WMailServer, env *whisper.Envelope) {
@@ -140,23 +328,26 @@ func deliverTest(t *testing.T, server *WMailServer, env *whisper.Envelope) {
 func singleRequest(t *testing.T, server *WMailServer, env *whisper.Envelope, p *ServerTestParams, expect bool) {
 	request := createRequest(t, p)
 	src := crypto.FromECDSAPub(&p.key.PublicKey)
-	ok, lower, upper, bloom := server.validateRequest(src, request)
+	ok, req := server.validateRequest(src, request)
 	if !ok {
 		t.Fatalf("request validation failed, seed: %d.", seed)
 	}
-	if lower != p.low {
+	if req.Lower != p.low {
 		t.Fatalf("request validation failed (lower bound), seed: %d.", seed)
 	}
-	if upper != p.upp {
+	if req.Upper != p.upp {
 		t.Fatalf("request validation failed (upper bound), seed: %d.", seed)
 	}
 	expectedBloom := whisper.TopicToBloom(p.topic)
-	if !bytes.Equal(bloom, expectedBloom) {
+	if !bytes.Equal(req.Bloom, expectedBloom) {
 		t.Fatalf("request validation failed (topic), seed: %d.", seed)
 	}
 
 	var exist bool
-	mail := server.processRequest(nil, p.low, p.upp, bloom)
+	mail, _, err := server.processRequest(nil, req)
+	if err != nil {
+		t.Fatalf("request processing failed, seed: %d: %s.", seed, err)
+	}
 	for _, msg := range mail {
 		if msg.Hash() == env.Hash() {
 			exist = true
@@ -169,10 +360,10 @@ func singleRequest(t *testing.T, server *WMailServer, env *whisper.Envelope, p *
 	}
 
 	src[0]++
-	ok, lower, upper, bloom = server.validateRequest(src, request)
+	ok, req = server.validateRequest(src, request)
 	if !ok {
 		// request should be valid regardless of signature
-		t.Fatalf("request validation false negative, seed: %d (lower: %d, upper: %d).", seed, lower, upper)
+		t.Fatalf("request validation false negative, seed: %d (lower: %d, upper: %d).", seed, p.low, p.upp)
 	}
 }
 
@@ -182,7 +373,10 @@ func createRequest(t *testing.T, p *ServerTestParams) *whisper.Envelope {
 	binary.BigEndian.PutUint32(data, p.low)
 	binary.BigEndian.PutUint32(data[4:], p.upp)
 	data = append(data, bloom...)
+	return createRequestEnvelope(t, p.topic, data, p.key)
+}
 
+func createRequestEnvelope(t *testing.T, topic whisper.TopicType, data []byte, src *ecdsa.PrivateKey) *whisper.Envelope {
 	key, err := shh.GetSymKey(keyID)
 	if err != nil {
 		t.Fatalf("failed to retrieve sym key with seed %d: %s.", seed, err)
@@ -190,11 +384,11 @@ func createRequest(t *testing.T, p *ServerTestParams) *whisper.Envelope {
 
 	params := &whisper.MessageParams{
 		KeySym:   key,
-		Topic:    p.topic,
+		Topic:    topic,
 		Payload:  data,
 		PoW:      powRequirement * 2,
 		WorkTime: 2,
-		Src:      p.key,
+		Src:      src,
 	}
 
 	msg, err := whisper.NewSentMessage(params)
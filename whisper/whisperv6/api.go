@@ -197,16 +197,17 @@ func (api *PublicWhisperAPI) DeleteSymKey(ctx context.Context, id string) bool {
 }
 
 // MakeLightClient turns the node into light client, which does not forward
-// any incoming messages, and sends only messages originated in this node.
+// any incoming messages, sends only messages originated in this node and
+// receives only messages with the topics of its filters.
 func (api *PublicWhisperAPI) MakeLightClient(ctx context.Context) bool {
-	api.w.lightClient = true
-	return api.w.lightClient
+	api.w.SetLightClientMode(true)
+	return api.w.LightClientMode()
 }
 
 // CancelLightClient cancels light client mode.
 func (api *PublicWhisperAPI) CancelLightClient(ctx context.Context) bool {
-	api.w.lightClient = false
-	return !api.w.lightClient
+	api.w.SetLightClientMode(false)
+	return !api.w.LightClientMode()
 }
 
 //go:generate gencodec -type NewMessage -field-override newMessageOverride -out gen_newmessage_json.go
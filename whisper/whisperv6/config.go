@@ -20,6 +20,7 @@ package whisperv6
 type Config struct {
 	MaxMessageSize     uint32  `toml:",omitempty"`
 	MinimumAcceptedPOW float64 `toml:",omitempty"`
+	LightClient        bool    `toml:",omitempty"` // announces the topics of interest and does not forward any messages
 }
 
 // DefaultConfig represents (shocker!) the default configuration.
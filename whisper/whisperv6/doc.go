@@ -35,6 +35,8 @@ package whisperv6
 import (
 	"fmt"
 	"time"
+
+	"github.com/aaechain/go-aaechain/common"
 )
 
 // Whisper protocol parameters
@@ -44,13 +46,15 @@ const (
 	ProtocolName       = "shh"     // Nickname of the protocol in gaae
 
 	// whisper protocol message codes, according to EIP-627
-	statusCode           = 0   // used by whisper protocol
-	messagesCode         = 1   // normal whisper message
-	powRequirementCode   = 2   // PoW requirement
-	bloomFilterExCode    = 3   // bloom filter exchange
-	p2pRequestCode       = 126 // peer-to-peer message, used by Dapp protocol
-	p2pMessageCode       = 127 // peer-to-peer message (to be consumed by the peer, but not forwarded any further)
-	NumberOfMessageCodes = 128
+	statusCode             = 0   // used by whisper protocol
+	messagesCode           = 1   // normal whisper message
+	powRequirementCode     = 2   // PoW requirement
+	bloomFilterExCode      = 3   // bloom filter exchange
+	topicInterestExCode    = 4   // topic interests exchange, used by light clients
+	p2pRequestCompleteCode = 125 // peer-to-peer message, reports the completion of a request to the mail server
+	p2pRequestCode         = 126 // peer-to-peer message, used by Dapp protocol
+	p2pMessageCode         = 127 // peer-to-peer message (to be consumed by the peer, but not forwarded any further)
+	NumberOfMessageCodes   = 128
 
 	SizeMask      = byte(3) // mask used to extract the size of payload size field from the flags
 	signatureFlag = byte(4)
@@ -72,6 +76,8 @@ const (
 	padSizeLimit      = 256 // just an arbitrary number, could be changed without breaking the protocol
 	messageQueueLimit = 1024
 
+	MaxTopicInterests = 1000 // maximum number of topics a light client may announce
+
 	expirationCycle   = time.Second
 	transmissionCycle = 300 * time.Millisecond
 
@@ -95,3 +101,12 @@ type MailServer interface {
 	Archive(env *Envelope)
 	DeliverMail(whisperPeer *Peer, request *Envelope)
 }
+
+// MailServerResponse is sent by a mail server with p2pRequestCompleteCode
+// once it has delivered the envelopes matching a request. Cursor is empty
+// if all the matching envelopes were delivered, otherwise it can be sent
+// with a subsequent request to continue the delivery.
+type MailServerResponse struct {
+	RequestID common.Hash // hash of the request envelope
+	Cursor    []byte
+}
@@ -132,6 +132,19 @@ func (fs *Filters) removeFromTopicMatchers(watcher *Filter) {
 	}
 }
 
+// Topics returns the topics the installed filters are interested in.
+func (fs *Filters) Topics() []TopicType {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	topics := make([]TopicType, 0, len(fs.topicMatcher))
+	for topic, watchers := range fs.topicMatcher {
+		if len(watchers) > 0 {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
 // getWatchersByTopic returns a slice containing the filters that
 // match a specific topic
 func (fs *Filters) getWatchersByTopic(topic TopicType) []*Filter {
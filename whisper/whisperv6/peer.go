@@ -37,9 +37,11 @@ type Peer struct {
 
 	trusted        bool
 	powRequirement float64
-	bloomMu        sync.Mutex
+	bloomMu        sync.Mutex // guards the bloom filter and the topic interests
 	bloomFilter    []byte
 	fullNode       bool
+	light          bool                   // is only sent the messages with the topics of interest
+	topics         map[TopicType]struct{} // topics of interest of a light peer
 
 	known *set.Set // Messages already known by the peer to avoid wasting bandwidth
 
@@ -83,7 +85,8 @@ func (peer *Peer) handshake() error {
 		pow := peer.host.MinPow()
 		powConverted := math.Float64bits(pow)
 		bloom := peer.host.BloomFilter()
-		errc <- p2p.SendItems(peer.ws, statusCode, ProtocolVersion, powConverted, bloom)
+		interests := peer.host.topicInterests()
+		errc <- p2p.SendItems(peer.ws, statusCode, ProtocolVersion, powConverted, bloom, interests)
 	}()
 
 	// Fetch the remote status packet and verify protocol match
@@ -124,6 +127,13 @@ func (peer *Peer) handshake() error {
 				return fmt.Errorf("peer [%x] sent bad status message: wrong bloom filter size %d", peer.ID(), sz)
 			}
 			peer.setBloomFilter(bloom)
+
+			var interests topicInterests
+			if err := s.Decode(&interests); err == nil {
+				if err := peer.setTopicInterests(&interests); err != nil {
+					return fmt.Errorf("peer [%x] sent bad status message: %v", peer.ID(), err)
+				}
+			}
 		}
 	}
 
@@ -190,7 +200,7 @@ func (peer *Peer) broadcast() error {
 	envelopes := peer.host.Envelopes()
 	bundle := make([]*Envelope, 0, len(envelopes))
 	for _, envelope := range envelopes {
-		if !peer.marked(envelope) && envelope.PoW() >= peer.powRequirement && peer.bloomMatch(envelope) {
+		if !peer.marked(envelope) && envelope.PoW() >= peer.powRequirement && peer.bloomMatch(envelope) && peer.topicMatch(envelope) {
 			bundle = append(bundle, envelope)
 		}
 	}
@@ -226,6 +236,10 @@ func (peer *Peer) notifyAboutBloomFilterChange(bloom []byte) error {
 	return p2p.Send(peer.ws, bloomFilterExCode, bloom)
 }
 
+func (peer *Peer) notifyAboutTopicInterestsChange(interests *topicInterests) error {
+	return p2p.Send(peer.ws, topicInterestExCode, interests)
+}
+
 func (peer *Peer) bloomMatch(env *Envelope) bool {
 	peer.bloomMu.Lock()
 	defer peer.bloomMu.Unlock()
@@ -242,6 +256,40 @@ func (peer *Peer) setBloomFilter(bloom []byte) {
 	}
 }
 
+func (peer *Peer) topicMatch(env *Envelope) bool {
+	peer.bloomMu.Lock()
+	defer peer.bloomMu.Unlock()
+	if !peer.light {
+		return true
+	}
+	_, ok := peer.topics[env.Topic]
+	return ok
+}
+
+// setTopicInterests sets the topics the peer is interested in, failing if it
+// announces more than MaxTopicInterests of them.
+func (peer *Peer) setTopicInterests(interests *topicInterests) error {
+	if len(interests.Topics) > MaxTopicInterests {
+		return fmt.Errorf("too many topic interests %d", len(interests.Topics))
+	}
+	peer.bloomMu.Lock()
+	defer peer.bloomMu.Unlock()
+	peer.light = interests.Light
+	peer.topics = make(map[TopicType]struct{}, len(interests.Topics))
+	for _, topic := range interests.Topics {
+		peer.topics[topic] = struct{}{}
+	}
+	return nil
+}
+
+// topicInterests is announced in the status message and with topicInterestExCode.
+// Light clients announce the exact topics they are interested in, in addition
+// to the bloom filter, and only expect the messages with these topics.
+type topicInterests struct {
+	Light  bool
+	Topics []TopicType
+}
+
 func MakeFullNodeBloom() []byte {
 	bloom := make([]byte, BloomFilterSize)
 	for i := 0; i < BloomFilterSize; i++ {
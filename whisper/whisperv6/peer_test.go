@@ -19,7 +19,9 @@ package whisperv6
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"fmt"
+	"math"
 	mrand "math/rand"
 	"net"
 	"sync"
@@ -421,6 +423,95 @@ func TestPeerBasic(t *testing.T) {
 	}
 }
 
+func TestPeerTopicInterests(t *testing.T) {
+	full := New(&DefaultConfig)
+	light := New(&Config{
+		MaxMessageSize:     DefaultMaxMessageSize,
+		MinimumAcceptedPOW: DefaultMinimumPoW,
+		LightClient:        true,
+	})
+	if _, err := light.Subscribe(&Filter{KeySym: sharedKey, Topics: [][]byte{sharedTopic[:]}}); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+
+	// connect the nodes, fullPeer being the full node as seen by the
+	// light node and lightPeer the light node as seen by the full node
+	rw1, rw2 := p2p.MsgPipe()
+	defer rw1.Close()
+	fullPeer := newPeer(light, p2p.NewPeer(discover.NodeID{1}, "full", nil), rw1)
+	lightPeer := newPeer(full, p2p.NewPeer(discover.NodeID{2}, "light", nil), rw2)
+	errc := make(chan error, 1)
+	go func() { errc <- fullPeer.handshake() }()
+	if err := lightPeer.handshake(); err != nil {
+		t.Fatalf("light peer handshake failed: %s", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("full peer handshake failed: %s", err)
+	}
+
+	expected := &Envelope{Topic: sharedTopic}
+	unexpected := &Envelope{Topic: wrongTopic}
+	if !lightPeer.topicMatch(expected) || lightPeer.topicMatch(unexpected) {
+		t.Fatalf("light peer should only match the announced topic")
+	}
+	if !fullPeer.topicMatch(expected) || !fullPeer.topicMatch(unexpected) {
+		t.Fatalf("full peer should match any topic")
+	}
+
+	// cancelling the light client mode should be announced to the full node
+	go full.runMessageLoop(lightPeer, rw2)
+	light.peerMu.Lock()
+	light.peers[fullPeer] = struct{}{}
+	light.peerMu.Unlock()
+	light.SetLightClientMode(false)
+	for i := 0; i < 100 && !lightPeer.topicMatch(unexpected); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !lightPeer.topicMatch(unexpected) {
+		t.Fatalf("peer should match any topic after cancelling light client mode")
+	}
+}
+
+func TestPeerTooManyTopicInterests(t *testing.T) {
+	full := New(&DefaultConfig)
+	interests := &topicInterests{Light: true, Topics: make([]TopicType, MaxTopicInterests+1)}
+	for i := range interests.Topics {
+		binary.BigEndian.PutUint32(interests.Topics[i][:], uint32(i))
+	}
+
+	// the handshake should fail if the status message announces too many topics
+	rw1, rw2 := p2p.MsgPipe()
+	defer rw1.Close()
+	lightPeer := newPeer(full, p2p.NewPeer(discover.NodeID{2}, "light", nil), rw1)
+	go func() {
+		if msg, err := rw2.ReadMsg(); err == nil {
+			msg.Discard()
+			p2p.SendItems(rw2, statusCode, ProtocolVersion, math.Float64bits(DefaultMinimumPoW), MakeFullNodeBloom(), interests)
+		}
+	}()
+	if err := lightPeer.handshake(); err == nil {
+		t.Fatalf("handshake should fail with %d topic interests", len(interests.Topics))
+	}
+
+	// and so should announcing them with the topic interests exchange
+	rw3, rw4 := p2p.MsgPipe()
+	defer rw3.Close()
+	lightPeer = newPeer(full, p2p.NewPeer(discover.NodeID{2}, "light", nil), rw3)
+	errc := make(chan error, 1)
+	go func() { errc <- full.runMessageLoop(lightPeer, rw3) }()
+	if err := p2p.Send(rw4, topicInterestExCode, interests); err != nil {
+		t.Fatalf("failed to send topic interests: %s", err)
+	}
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatalf("peer should be disconnected after announcing %d topic interests", len(interests.Topics))
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("peer wasn't disconnected after announcing %d topic interests", len(interests.Topics))
+	}
+}
+
 func checkPowExchangeForNodeZero(t *testing.T) {
 	const iterations = 200
 	for j := 0; j < iterations; j++ {
@@ -28,6 +28,7 @@ import (
 
 	"github.com/aaechain/go-aaechain/common"
 	"github.com/aaechain/go-aaechain/crypto"
+	"github.com/aaechain/go-aaechain/event"
 	"github.com/aaechain/go-aaechain/log"
 	"github.com/aaechain/go-aaechain/p2p"
 	"github.com/aaechain/go-aaechain/rlp"
@@ -55,6 +56,7 @@ const (
 	minPowToleranceIdx             // Minimal PoW tolerated by the whisper node for a limited time
 	bloomFilterIdx                 // Bloom filter for topics of interest for this node
 	bloomFilterToleranceIdx        // Bloom filter tolerated by the whisper node for a limited time
+	lightClientModeIdx             // Light client mode, announcing the topics of interest and not forwarding messages
 )
 
 // Whisper represents a dark communication interface through the aaechain
@@ -82,12 +84,11 @@ type Whisper struct {
 
 	syncAllowance int // maximum time in seconds allowed to process the whisper-related messages
 
-	lightClient bool // indicates is this node is pure light client (does not forward any messages)
-
 	statsMu sync.Mutex // guard stats
 	stats   Statistics // Statistics of whisper node
 
-	mailServer MailServer // MailServer interface
+	mailServer    MailServer // MailServer interface
+	mailResponses event.Feed // Feed of the responses received from mail servers
 }
 
 // New creates a Whisper client ready to communicate through the aaechain P2P network.
@@ -113,6 +114,7 @@ func New(cfg *Config) *Whisper {
 	whisper.settings.Store(minPowIdx, cfg.MinimumAcceptedPOW)
 	whisper.settings.Store(maxMsgSizeIdx, cfg.MaxMessageSize)
 	whisper.settings.Store(overflowIdx, false)
+	whisper.settings.Store(lightClientModeIdx, cfg.LightClient)
 
 	// p2p whisper sub protocol handler
 	whisper.protocol = p2p.Protocol{
@@ -181,6 +183,31 @@ func (whisper *Whisper) BloomFilterTolerance() []byte {
 	return val.([]byte)
 }
 
+// LightClientMode indicates this node is a light client, which does not forward
+// any incoming messages and announces its topics of interest to the peers, so
+// that they only send the messages with these topics.
+func (whisper *Whisper) LightClientMode() bool {
+	val, exist := whisper.settings.Load(lightClientModeIdx)
+	if !exist || val == nil {
+		return false
+	}
+	return val.(bool)
+}
+
+// topicInterests returns the topic interests announced to the peers. If there
+// are more topics than peers accept, only the bloom filter is announced.
+func (whisper *Whisper) topicInterests() *topicInterests {
+	if !whisper.LightClientMode() {
+		return &topicInterests{}
+	}
+	topics := whisper.filters.Topics()
+	if len(topics) > MaxTopicInterests {
+		log.Warn("too many topics to announce, relying on the bloom filter", "topics", len(topics))
+		return &topicInterests{}
+	}
+	return &topicInterests{Light: true, Topics: topics}
+}
+
 // MaxMessageSize returns the maximum accepted message size.
 func (whisper *Whisper) MaxMessageSize() uint32 {
 	val, _ := whisper.settings.Load(maxMsgSizeIdx)
@@ -251,6 +278,13 @@ func (whisper *Whisper) SetBloomFilter(bloom []byte) error {
 	return nil
 }
 
+// SetLightClientMode turns the light client mode on or off and informs the
+// peers about the resulting topic interests.
+func (whisper *Whisper) SetLightClientMode(v bool) {
+	whisper.settings.Store(lightClientModeIdx, v)
+	whisper.notifyPeersAboutTopicInterestsChange()
+}
+
 // SetMinimumPoW sets the minimal PoW required by this node
 func (whisper *Whisper) SetMinimumPoW(val float64) error {
 	if val < 0.0 {
@@ -276,6 +310,21 @@ func (whisper *Whisper) SetMinimumPowTest(val float64) {
 	whisper.settings.Store(minPowToleranceIdx, val)
 }
 
+func (whisper *Whisper) notifyPeersAboutTopicInterestsChange() {
+	interests := whisper.topicInterests()
+	arr := whisper.getPeers()
+	for _, p := range arr {
+		err := p.notifyAboutTopicInterestsChange(interests)
+		if err != nil {
+			// allow one retry
+			err = p.notifyAboutTopicInterestsChange(interests)
+		}
+		if err != nil {
+			log.Warn("failed to notify peer about new topic interests", "peer", p.ID(), "error", err)
+		}
+	}
+}
+
 func (whisper *Whisper) notifyPeersAboutPowRequirementChange(pow float64) {
 	arr := whisper.getPeers()
 	for _, p := range arr {
@@ -354,6 +403,18 @@ func (whisper *Whisper) RequestHistoricMessages(peerID []byte, envelope *Envelop
 	return p2p.Send(p.ws, p2pRequestCode, envelope)
 }
 
+// SendHistoricMessageResponse is used by a mail server to report to the peer
+// which requested historic messages that the request was processed.
+func (whisper *Whisper) SendHistoricMessageResponse(peer *Peer, response *MailServerResponse) error {
+	return p2p.Send(peer.ws, p2pRequestCompleteCode, response)
+}
+
+// SubscribeMailServerResponses subscribes to the responses of the mail servers
+// to the requests sent with RequestHistoricMessages.
+func (whisper *Whisper) SubscribeMailServerResponses(ch chan<- *MailServerResponse) event.Subscription {
+	return whisper.mailResponses.Subscribe(ch)
+}
+
 // SendP2PMessage sends a peer-to-peer message to a specific peer.
 func (whisper *Whisper) SendP2PMessage(peerID []byte, envelope *Envelope) error {
 	p, err := whisper.getPeer(peerID)
@@ -551,6 +612,9 @@ func (whisper *Whisper) Subscribe(f *Filter) (string, error) {
 	s, err := whisper.filters.Install(f)
 	if err == nil {
 		whisper.updateBloomFilter(f)
+		if whisper.LightClientMode() && len(f.Topics) > 0 {
+			whisper.notifyPeersAboutTopicInterestsChange()
+		}
 	}
 	return s, err
 }
@@ -583,6 +647,9 @@ func (whisper *Whisper) Unsubscribe(id string) error {
 	if !ok {
 		return fmt.Errorf("Unsubscribe: Invalid ID")
 	}
+	if whisper.LightClientMode() {
+		whisper.notifyPeersAboutTopicInterestsChange()
+	}
 	return nil
 }
 
@@ -672,7 +739,7 @@ func (whisper *Whisper) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 
 			trouble := false
 			for _, env := range envelopes {
-				cached, err := whisper.add(env, whisper.lightClient)
+				cached, err := whisper.add(env, whisper.LightClientMode())
 				if err != nil {
 					trouble = true
 					log.Error("bad envelope received, peer will be disconnected", "peer", p.peer.ID(), "err", err)
@@ -710,6 +777,16 @@ func (whisper *Whisper) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 				return errors.New("invalid bloom filter exchange message")
 			}
 			p.setBloomFilter(bloom)
+		case topicInterestExCode:
+			var interests topicInterests
+			err := packet.Decode(&interests)
+			if err == nil {
+				err = p.setTopicInterests(&interests)
+			}
+			if err != nil {
+				log.Warn("invalid topic interests exchange message, peer will be disconnected", "peer", p.peer.ID(), "err", err)
+				return errors.New("invalid topic interests exchange message")
+			}
 		case p2pMessageCode:
 			// peer-to-peer message, sent directly to peer bypassing PoW checks, etc.
 			// this message is not supposed to be forwarded to other peers, and
@@ -733,6 +810,17 @@ func (whisper *Whisper) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 				}
 				whisper.mailServer.DeliverMail(p, &request)
 			}
+		case p2pRequestCompleteCode:
+			// the response of a mail server, only accepted from the peers
+			// historic messages were requested from.
+			if p.trusted {
+				var response MailServerResponse
+				if err := packet.Decode(&response); err != nil {
+					log.Warn("failed to decode mail server response, peer will be disconnected", "peer", p.peer.ID(), "err", err)
+					return errors.New("invalid mail server response")
+				}
+				whisper.mailResponses.Send(&response)
+			}
 		default:
 			// New message types might be implemented in the future versions of Whisper.
 			// For forward compatibility, just ignore.